@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/internal/file"
+)
+
+// analyzeCommand is the subcommand that re-evaluates cached findings
+// against a new IOC without re-downloading any logs.
+const analyzeCommand = "analyze"
+
+// analyzeOptions is the parsed form of the analyze subcommand's
+// arguments.
+type analyzeOptions struct {
+	iocName        string
+	iocContent     string
+	iocContentFile string
+	iocPattern     string
+	iocFile        string
+	jsonOutput     string
+	csvOutput      string
+	cacheFiles     []string
+}
+
+// parseAnalyzeArgs parses the analyze subcommand's flags and positional
+// cache files. Usage errors are returned rather than fatal so the
+// parser is testable.
+func parseAnalyzeArgs(args []string, stderr io.Writer) (analyzeOptions, error) {
+	fs := flag.NewFlagSet(analyzeCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: ghscan analyze [-ioc-content a,b] [-ioc-content-file f] [-ioc-pattern re] [-json out.json] [-csv out.csv] cache1.json ...\n")
+		fs.PrintDefaults()
+	}
+
+	var opts analyzeOptions
+	fs.StringVar(&opts.iocName, "ioc-name", "", "Name recorded on matching results, or a predefined IOC to match")
+	fs.StringVar(&opts.iocContent, "ioc-content", "", "Comma-separated string(s) to search for in cached findings")
+	fs.StringVar(&opts.iocContentFile, "ioc-content-file", "", "Path to a file of newline-delimited strings to search for in cached findings")
+	fs.StringVar(&opts.iocPattern, "ioc-pattern", "", "Regex pattern to search cached findings with")
+	fs.StringVar(&opts.iocFile, "ioc-file", "", "Path to a JSON corpus file overriding the embedded IOC list")
+	fs.StringVar(&opts.jsonOutput, "json", "", "Path to filtered JSON output file")
+	fs.StringVar(&opts.csvOutput, "csv", "", "Path to filtered CSV output file")
+	if err := fs.Parse(args); err != nil {
+		return analyzeOptions{}, err
+	}
+	opts.cacheFiles = fs.Args()
+
+	if len(opts.cacheFiles) == 0 {
+		return analyzeOptions{}, errors.New("at least one cache file must be provided")
+	}
+	if opts.iocName == "" && opts.iocContent == "" && opts.iocContentFile == "" && opts.iocPattern == "" {
+		return analyzeOptions{}, errors.New("at least one of -ioc-name, -ioc-content, -ioc-content-file, or -ioc-pattern must be provided")
+	}
+	if opts.jsonOutput == "" && opts.csvOutput == "" {
+		return analyzeOptions{}, errors.New("at least one of -json or -csv must be provided")
+	}
+	return opts, nil
+}
+
+// runAnalyze implements `ghscan analyze` and returns the process exit
+// code, using the same contract as a scan: findings in the filtered
+// subset exit 2. Cache paths and outputs are resolved under results/.
+func runAnalyze(args []string) int {
+	opts, err := parseAnalyzeArgs(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitClean
+		}
+		logger.Errorf("Invalid analyze arguments: %v", err)
+		return exitScanFailed
+	}
+
+	findIOC, _, err := buildIOC(opts.iocName, opts.iocContent, opts.iocContentFile, opts.iocPattern, opts.iocFile)
+	if err != nil {
+		logger.Errorf("Failed to initialize IOC: %v", err)
+		return exitScanFailed
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cache, err := file.MergeCaches(ctx, logger, opts.cacheFiles)
+	if err != nil {
+		logger.Errorf("Failed to load caches: %v", err)
+		return exitScanFailed
+	}
+	cache.Results = action.Reanalyze(cache.Results, findIOC)
+	logger.Infof("%d cached results match %s", len(cache.Results), findIOC.GetName())
+
+	writeErr := file.WriteResults(ctx, logger, cache, "", opts.jsonOutput, opts.csvOutput)
+	if writeErr != nil {
+		logger.Errorf("Failed to write outputs: %v", writeErr)
+	}
+	return resolveExitCode(nil, writeErr, len(cache.Results))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// annotationLevel maps a finding's severity to a workflow command.
+// Critical and high findings, and unrated ones (direct IOC, YAML, and
+// history matches), are errors; medium and low leads are warnings.
+func annotationLevel(severity string) string {
+	switch severity {
+	case workflow.SeverityMedium, workflow.SeverityLow:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// formatAnnotations renders one GitHub Actions workflow command per
+// finding, in file.SortResults order, e.g.
+// "::error title=ghscan%3A tj-actions/changed-files::octo/repo ci.yml: ...".
+// The matched log content is deliberately left out: annotations are
+// visible to anyone who can read the scanning workflow's run, which
+// may be broader than who can read the scanned repositories.
+func formatAnnotations(results []ghscan.Result) []string {
+	lines := make([]string, 0, len(results))
+	for _, r := range file.SortResults(results) {
+		if r.IsEmpty() {
+			continue
+		}
+		title := "ghscan: " + cmp.Or(r.IOCName, r.KeyTypes, r.Source, "finding")
+
+		msg := r.Repository + " " + r.WorkflowFileName
+		if kind := strings.TrimSpace(r.Severity + " " + r.KeyTypes); kind != "" {
+			msg += " (" + kind + ")"
+		}
+		if r.Action != "" {
+			msg += " via " + r.Action
+		}
+		if url := cmp.Or(r.WorkflowRunURL, r.WorkflowURL); url != "" {
+			msg += " " + url
+		}
+		lines = append(lines, fmt.Sprintf("::%s title=%s::%s",
+			annotationLevel(r.Severity), escapeAnnotationProperty(title), escapeAnnotationData(msg)))
+	}
+	return lines
+}
+
+// writeAnnotations prints formatAnnotations(results) to w, one command
+// per line, for the Actions runner to pick up from stdout.
+func writeAnnotations(w io.Writer, results []ghscan.Result) error {
+	for _, line := range formatAnnotations(results) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return fmt.Errorf("writing annotation: %w", err)
+		}
+	}
+	return nil
+}
+
+// escapeAnnotationData escapes a workflow command message so a
+// newline cannot end the command early.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a workflow command property value,
+// which additionally may not contain the ':' and ',' delimiters.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/google/go-github/v86/github"
+)
+
+const (
+	// maxSARIFBytes is GitHub's limit on an uploaded SARIF file after
+	// gzip compression.
+	maxSARIFBytes = 10 << 20
+	// maxSARIFResults is GitHub's limit on the results of one run.
+	maxSARIFResults = 25000
+)
+
+// sarifPollInterval and sarifPollTimeout bound how long an upload is
+// watched while code scanning processes it. They are variables so
+// tests need not wait.
+var (
+	sarifPollInterval = 5 * time.Second
+	sarifPollTimeout  = 2 * time.Minute
+)
+
+// sarifTarget is a repository whose findings are uploaded to its code
+// scanning. branch is its default branch, or "" to look it up.
+type sarifTarget struct {
+	owner, repo, branch string
+}
+
+// sarifTargets returns every repository a finding names, once each, in
+// order, with the default branch of those that were scanned. With
+// includeEmpty, scanned repositories without findings are included too,
+// so their empty upload closes alerts a previous upload opened.
+func sarifTargets(repos []*github.Repository, results []ghscan.Result, includeEmpty bool) []sarifTarget {
+	var out []sarifTarget
+	seen := make(map[string]bool)
+	hasFindings := make(map[string]bool)
+	for _, r := range results {
+		hasFindings[r.Repository] = true
+	}
+	add := func(fullName, branch string) {
+		owner, repo, ok := strings.Cut(fullName, "/")
+		if !ok || owner == "" || repo == "" || seen[fullName] {
+			return
+		}
+		seen[fullName] = true
+		out = append(out, sarifTarget{owner: owner, repo: repo, branch: branch})
+	}
+	for _, r := range repos {
+		fullName := r.GetOwner().GetLogin() + "/" + r.GetName()
+		if includeEmpty || hasFindings[fullName] {
+			add(fullName, r.GetDefaultBranch())
+		}
+	}
+	for _, r := range results {
+		add(r.Repository, "")
+	}
+	return out
+}
+
+// encodeSARIF gzips and base64-encodes log as the code scanning API
+// requires, rejecting a log over GitHub's size and result limits.
+func encodeSARIF(log file.SARIFLog) (string, error) {
+	n := 0
+	for _, run := range log.Runs {
+		n += len(run.Results)
+	}
+	if n > maxSARIFResults {
+		return "", fmt.Errorf("%d results exceed code scanning's limit of %d per upload", n, maxSARIFResults)
+	}
+	data, err := json.Marshal(log)
+	if err != nil {
+		return "", fmt.Errorf("encoding SARIF: %w", err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return "", fmt.Errorf("compressing SARIF: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("compressing SARIF: %w", err)
+	}
+	if buf.Len() > maxSARIFBytes {
+		return "", fmt.Errorf("compressed SARIF is %d bytes, over code scanning's limit of %d", buf.Len(), maxSARIFBytes)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// uploadSARIF uploads each target's findings in results to its code
+// scanning, against the head of its default branch, and waits for
+// GitHub to process each upload. A failed repository does not stop the
+// others; the failures are returned joined.
+func uploadSARIF(ctx context.Context, logger *clog.Logger, gh *github.Client, targets []sarifTarget, results []ghscan.Result) error {
+	var errs error
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return errors.Join(errs, err)
+		}
+		fullName := t.owner + "/" + t.repo
+		repoResults := slices.DeleteFunc(slices.Clone(results), func(r ghscan.Result) bool { return r.Repository != fullName })
+		if err := uploadRepoSARIF(ctx, logger, gh, t, repoResults); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("uploading SARIF to %s: %w", fullName, err))
+		}
+	}
+	return errs
+}
+
+// uploadRepoSARIF uploads results as t's code scanning analysis.
+func uploadRepoSARIF(ctx context.Context, logger *clog.Logger, gh *github.Client, t sarifTarget, results []ghscan.Result) error {
+	sarif, err := encodeSARIF(file.BuildSARIF(results))
+	if err != nil {
+		return err
+	}
+
+	branch := t.branch
+	if branch == "" {
+		repo, _, err := gh.Repositories.Get(ctx, t.owner, t.repo)
+		if err != nil {
+			return fmt.Errorf("looking up default branch: %w", err)
+		}
+		branch = repo.GetDefaultBranch()
+	}
+	sha, _, err := gh.Repositories.GetCommitSHA1(ctx, t.owner, t.repo, branch, "")
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", branch, err)
+	}
+
+	id, _, err := gh.CodeScanning.UploadSarif(ctx, t.owner, t.repo, &github.SarifAnalysis{
+		CommitSHA: new(sha),
+		Ref:       new("refs/heads/" + branch),
+		Sarif:     new(sarif),
+		ToolName:  new("ghscan"),
+	})
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil &&
+			(ghErr.Response.StatusCode == http.StatusForbidden || ghErr.Response.StatusCode == http.StatusNotFound) {
+			return fmt.Errorf("%w (the token needs the security_events scope, or code scanning alerts write permission, and code scanning must be available for the repository)", err)
+		}
+		return err
+	}
+	logger.Infof("Uploaded %d findings to code scanning of %s/%s at %s", len(results), t.owner, t.repo, sha)
+	return waitForSARIF(ctx, logger, gh, t, id.GetID())
+}
+
+// waitForSARIF polls the processing status of upload id until GitHub
+// completes or rejects it. An upload still pending after
+// sarifPollTimeout is left to finish on its own.
+func waitForSARIF(ctx context.Context, logger *clog.Logger, gh *github.Client, t sarifTarget, id string) error {
+	if id == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, sarifPollTimeout)
+	defer cancel()
+	ticker := time.NewTicker(sarifPollInterval)
+	defer ticker.Stop()
+	for {
+		upload, _, err := gh.CodeScanning.GetSARIF(ctx, t.owner, t.repo, id)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			return fmt.Errorf("checking upload %s: %w", id, err)
+		case err != nil:
+			// The poll timed out, handled below.
+		case upload.GetProcessingStatus() == "complete":
+			logger.Infof("Code scanning processed the upload to %s/%s", t.owner, t.repo)
+			return nil
+		case upload.GetProcessingStatus() == "failed":
+			return fmt.Errorf("code scanning failed to process upload %s", id)
+		}
+		select {
+		case <-ctx.Done():
+			logger.Warnf("Code scanning is still processing the upload to %s/%s (%s); not waiting any longer", t.owner, t.repo, id)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
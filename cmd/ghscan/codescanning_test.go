@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestSARIFTargets asserts only repositories with findings are uploaded
+// to unless empty uploads are asked for, and repositories named only by
+// findings are added once.
+func TestSARIFTargets(t *testing.T) {
+	t.Parallel()
+
+	repos := []*github.Repository{
+		{Owner: &github.User{Login: new("o")}, Name: new("a"), DefaultBranch: new("main")},
+		{Owner: &github.User{Login: new("o")}, Name: new("b"), DefaultBranch: new("trunk")},
+	}
+	results := []ghscan.Result{{Repository: "o/b"}, {Repository: "o/c"}, {Repository: "o/c"}, {Repository: "bad"}}
+	cases := []struct {
+		name         string
+		includeEmpty bool
+		want         []sarifTarget
+	}{
+		{name: "findings only", want: []sarifTarget{{"o", "b", "trunk"}, {"o", "c", ""}}},
+		{name: "include empty", includeEmpty: true, want: []sarifTarget{{"o", "a", "main"}, {"o", "b", "trunk"}, {"o", "c", ""}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := sarifTargets(repos, results, tc.includeEmpty)
+			if len(got) != len(tc.want) {
+				t.Fatalf("sarifTargets = %+v, want %+v", got, tc.want)
+			}
+			for i := range tc.want {
+				if got[i] != tc.want[i] {
+					t.Errorf("target %d = %+v, want %+v", i, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestEncodeSARIF asserts the upload is gzipped base64 of the log.
+func TestEncodeSARIF(t *testing.T) {
+	t.Parallel()
+
+	log := file.BuildSARIF([]ghscan.Result{{Repository: "o/a", LineData: "x", IOCName: "ioc"}})
+	enc, err := encodeSARIF(log)
+	if err != nil {
+		t.Fatalf("encodeSARIF: %v", err)
+	}
+	gz, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		t.Fatalf("decoding base64: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatalf("opening gzip: %v", err)
+	}
+	var got file.SARIFLog
+	if err := json.NewDecoder(zr).Decode(&got); err != nil {
+		t.Fatalf("decoding SARIF: %v", err)
+	}
+	if len(got.Runs) != 1 || len(got.Runs[0].Results) != 1 {
+		t.Fatalf("round-tripped log = %+v, want one result", got)
+	}
+}
+
+// TestUploadSARIF asserts each target is uploaded against the head of
+// its default branch, the processing status is polled, and a rejected
+// upload is reported without stopping the others.
+func TestUploadSARIF(t *testing.T) {
+	// Not parallel: it shortens the package-level poll interval.
+	interval := sarifPollInterval
+	sarifPollInterval = time.Millisecond
+	t.Cleanup(func() { sarifPollInterval = interval })
+
+	var (
+		mu      sync.Mutex
+		uploads = make(map[string]map[string]any)
+		polls   = make(map[string]int)
+	)
+	gh := newEnterpriseTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/o/c":
+			_, _ = io.WriteString(w, `{"default_branch":"dev"}`)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/repos/o/") && strings.Contains(r.URL.Path, "/commits/"):
+			_, _ = io.WriteString(w, "sha-"+r.URL.Path[len("/repos/o/"):len("/repos/o/")+1])
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/code-scanning/sarifs"):
+			repo := strings.Split(r.URL.Path, "/")[3]
+			if repo == "b" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(w, `{"message":"Resource not accessible by integration"}`)
+				return
+			}
+			var body map[string]any
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decoding upload: %v", err)
+			}
+			mu.Lock()
+			uploads[repo] = body
+			mu.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = io.WriteString(w, `{"id":"up-`+repo+`"}`)
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/code-scanning/sarifs/"):
+			mu.Lock()
+			polls[r.URL.Path]++
+			n := polls[r.URL.Path]
+			mu.Unlock()
+			status := "pending"
+			if n > 1 {
+				status = "complete"
+			}
+			_, _ = io.WriteString(w, `{"processing_status":"`+status+`"}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	targets := []sarifTarget{{"o", "a", "main"}, {"o", "b", "main"}, {"o", "c", ""}}
+	results := []ghscan.Result{{Repository: "o/c", LineData: "x", IOCName: "ioc"}}
+	err := uploadSARIF(t.Context(), clog.New(slog.NewTextHandler(io.Discard, nil)), gh, targets, results)
+	if err == nil || !strings.Contains(err.Error(), "o/b") || !strings.Contains(err.Error(), "security_events") {
+		t.Fatalf("uploadSARIF err = %v, want the o/b failure with the scope hint", err)
+	}
+
+	if got := uploads["a"]; got["commit_sha"] != "sha-a" || got["ref"] != "refs/heads/main" || got["tool_name"] != "ghscan" {
+		t.Errorf("o/a upload = %v, want sha-a on refs/heads/main", got)
+	}
+	if got := uploads["c"]; got["commit_sha"] != "sha-c" || got["ref"] != "refs/heads/dev" {
+		t.Errorf("o/c upload = %v, want sha-c on the looked-up refs/heads/dev", got)
+	}
+	if polls["/repos/o/a/code-scanning/sarifs/up-a"] != 2 || polls["/repos/o/c/code-scanning/sarifs/up-c"] != 2 {
+		t.Errorf("polls = %v, want each upload polled until complete", polls)
+	}
+}
@@ -5,18 +5,187 @@
 //
 //	ghscan -target owner/repo -token $GITHUB_TOKEN \
 //	  -start 2025-01-01T00:00:00Z -end 2025-01-08T00:00:00Z \
-//	  [-cache results/cache.json] [-json out.json] [-csv out.csv] \
+//	  [-cache results/cache.json] [-no-cache] [-json out.json] [-csv out.csv] \
+//	  [-per-repo-output] [-summary summary.json] [-summary-only] [-sqlite findings.db] [-max-repos N] \
+//	  [-repo-language JavaScript,TypeScript] [-repo-topic production] [-visibility public] \
+//	  [-markdown report.md] [-json-nested] [-baseline accepted.json] [-github-annotations] [-upload-sarif [-upload-sarif-empty]] \
+//	  [-format json,csv,summary,markdown,sqlite,stix] [-output-dir ci] [-output-mode 0640] \
+//	  [-stix findings.stix.json] \
+//	  [-scan-history] [-scan-summaries] [-scan-attempts] [-correlate-secrets] [-repo-stagger 2s] \
+//	  [-repo-timeout 20m] [-scan-commit-messages] [-scan-pr-bodies] \
+//	  [-adaptive-concurrency] [-pause-on-rate-limit] [-best-effort] [-context-lines 3] \
+//	  [-min-confidence 50] [-explain] \
+//	  [-enable-detectors ioc,base64] [-disable-detectors pem] \
+//	  [-gitlab-project group/project -gitlab-url https://gitlab.example.com] \
+//	  [-runs-file runs.ndjson] [-end now -last 24h] \
+//	  [-logs-dir logs -log-extensions .txt,.log,.zip -logs-recursive=false] \
+//	  [-scan-actions] [-scan-actions-depth 2] [-resolve-tags] \
+//	  [-conclusions failure,!skipped] [-latest-only] [-all-runs] [-collapse-runs] [-org-workflow publish.yml] \
+//	  [-modified-only] \
+//	  [-search-query-template 'repo:{owner}/{repo} path:.github/workflows'] \
 //	  [-ioc-name tj-actions/changed-files] \
-//	  [-ioc-content "literal,strings"] [-ioc-pattern "regex"]
+//	  [-ioc-content "literal,strings"] [-ioc-content-file digests.txt] \
+//	  [-ioc-pattern "regex"]
 //
 // The target may be either an `owner/repository` pair (single repo) or
 // an organization name (every repository owned by the org is enumerated
-// and scanned). A GitHub personal access token must be supplied via
-// `-token` or the `GITHUB_TOKEN` environment variable.
+// and scanned). A github.com URL pasted from the browser names an org
+// or repository the same way, and a .../actions/runs/<ID> URL scans
+// only that run's logs. A malformed target is rejected before any API
+// call. -enterprise <slug> replaces -target and scans every
+// organization in a GitHub Enterprise; it requires an enterprise owner
+// token and fails before scanning when that access is missing.
+// -gitlab-project group/project also replaces -target and scans the
+// job logs of a GitLab project's pipelines (on -gitlab-url, with
+// -gitlab-token or `GITLAB_TOKEN`) with the same log detectors.
+// -runs-file also replaces -target: it names an NDJSON file (or - for
+// stdin) of {"owner", "repo", "run_id"} objects, read as the scan
+// proceeds, and only the logs of those runs are scanned. -logs-dir
+// also replaces -target and scans downloaded or extracted logs
+// offline: every file with one of -log-extensions, in subdirectories
+// too unless -logs-recursive=false, is one run, and binary files are
+// skipped. A
+// GitHub personal access token must otherwise be supplied via
+// `-token` or the `GITHUB_TOKEN` environment variable; when neither is
+// set, the token the gh CLI holds for github.com is used, from
+// `gh auth token --hostname github.com` or gh's hosts.yml. A gh login
+// to any other host is never used. The tokens are scrubbed from log
+// lines and from every output file, uploaded SARIF, and kept run log
+// unless -redact-token=false.
 //
 // Configuration not exposed as flags is read from `config.yaml` in the
-// current directory via viper. The cache, JSON, and CSV outputs are
-// written once the scan completes.
+// current directory via viper. Findings are appended to an NDJSON
+// journal beside the cache file and to the CSV output as each
+// repository finishes, and at least every -flush-interval (default
+// 1m), or every -flush-size pending results when set, for workflows
+// finished in a repository still being scanned;
+// once the scan completes, the cache, JSON, and
+// CSV outputs are rewritten in full and the journal is removed.
+// -no-cache skips all of that: findings stay in memory, no cache,
+// journal, or log cache is read or written, and only the requested
+// outputs are written when the scan ends. With
+// -per-repo-output, an owner__repo.json and owner__repo.csv pair is
+// also written for every repository that has findings. A per-repository
+// count of findings by IOC name is logged at the end of every run and,
+// with -summary, also written as JSON. -summary-only writes only that
+// summary, with totals, to -summary or summary.json and rejects the
+// detailed outputs, for dashboards over many repositories. -markdown
+// writes a Markdown report of the findings for pasting into issues.
+// -sqlite appends every finding, stamped with the scan time, to a
+// findings table for ad-hoc SQL across scheduled runs. -stix writes the
+// payloads, IOC digests, and destinations found as STIX 2.1 indicators
+// tied to the runs they were observed in. -format writes the listed
+// outputs under -output-dir with conventional file names (results.json,
+// results.csv, summary.json, report.md, findings.db,
+// findings.stix.json) unless the format's explicit path flag is set.
+// Outputs are written 0600 in 0750 directories; -output-mode sets
+// another octal file mode, such as 0640 for a group-readable artifact.
+// -max-repos N caps an organization scan to the first N repositories
+// listed, which is handy for sampling a large org before committing to
+// a full run.
+// -repo-language and -repo-topic keep only the listed repositories
+// whose primary language, or one of whose topics, is in the given
+// comma-separated list, before -max-repos applies. -visibility public
+// or -visibility private likewise keeps only the public or private
+// repositories; internal enterprise repositories count as private.
+// -org-workflow publish.yml scans only that workflow file in every
+// repository, without searching for workflow files, and skips
+// repositories that lack it.
+// -modified-only scans the runs of only the workflows whose file a
+// commit in the window modified, and reports those commits as
+// -scan-history does.
+// -pause-on-rate-limit holds new repositories, workflows, and runs
+// back until the core rate limit resets once it is all but spent.
+// -best-effort skips runs and workflows that fail rather than aborting
+// their repository, and reports them when the scan completes.
+// A failed scan exits 3, or 4 when every failure was a GitHub rate
+// limit and 5 when every failure was a token GitHub rejected or that
+// lacked access.
+// -keep-logs writes the extracted log of each run with findings to
+// results/logs/owner__repo/<run ID>.log; -keep-all-logs keeps every
+// scanned run. -detect-egress adds a detector for curl, wget, nc, and
+// Invoke-WebRequest calls to hosts outside -egress-allow, and
+// -detect-cache-poisoning reports actions/cache restores and keys that
+// warrant manual review. -detect-suspicious-git reports git clone,
+// remote add, and push commands against remotes on hosts outside
+// -git-remote-allow. -detect-mask-bypass flags steps that print a
+// masked secret after base64, hex, reversing, or character splitting.
+// -detect-base32 and -detect-base85 report base32, Ascii85, and RFC
+// 1924 base85 tokens that decode to printable text; base32 matches
+// uppercase identifiers too, so each is enabled on its own.
+// -enable-detectors runs only the named log detectors, turning on
+// opt-in ones it names, and -disable-detectors skips the named ones;
+// see Detectors in package workflow for the names.
+// -group-by-severity writes the JSON output and the Markdown report as
+// per-severity sections, most severe first, and sorts CSV rows by
+// severity. -json-nested
+// instead nests the JSON output's findings under repository and
+// workflow file, with counts at each level.
+// -allow-binary-decoded reports base64 blocks that decode to bytes
+// that are not UTF-8, escaping those bytes, instead of dropping them.
+// -context-lines N records up to N log lines on each side of a
+// matching line in the finding's context field.
+// -explain records in each finding's explanation field which detector
+// or rule reported it, what matched, and on which line; the Markdown
+// report shows it in a Why column.
+// -min-confidence N drops findings whose confidence score (0-100, set
+// per detector) is below N from the reports and the exit code; the
+// cache keeps them.
+// -baseline names a previous cache; findings already in it are
+// accepted, those that are not are logged with a NEW prefix, and only
+// new findings produce the findings exit code.
+// -github-annotations prints an ::error:: or ::warning:: workflow
+// command per finding (only new ones with -baseline) to stdout so a
+// scan run inside GitHub Actions surfaces them in the run's UI; it is
+// on by default when GITHUB_ACTIONS=true.
+// -upload-sarif uploads each scanned repository's findings to its
+// GitHub code scanning as SARIF and waits for GitHub to process them.
+// Repositories without findings are skipped unless -upload-sarif-empty
+// is set, which uploads an empty analysis to close their stale alerts.
+// -ioc-content-file adds one content string per line of a text file
+// (blank lines and # comments are skipped) to any -ioc-content values.
+// -api-version overrides the X-GitHub-Api-Version header sent by both
+// the go-github client and the raw log downloads.
+// -ca-cert adds the CA certificates in a PEM bundle to the roots both
+// clients trust, for GitHub Enterprise Server behind a private CA.
+// -scan-actions statically scans the manifest and bundled scripts of
+// each action a workflow references, at the ref it pins, following
+// composite actions up to -scan-actions-depth levels.
+// -resolve-tags resolves each action tag a workflow pins to the commit
+// it points at now and reports tags that point at a known-bad commit.
+// -log-cache-dir keeps downloaded run logs on disk and serves re-scans
+// of the same runs from it; -log-cache-ttl re-downloads older entries.
+// -conclusions restricts log scanning to runs whose conclusion (or
+// status, while unfinished) is listed, or excludes states prefixed
+// with "!". -latest-only scans only the newest run of each workflow
+// in the window, after the -conclusions filter. -all-runs scans every
+// run of a single repository's workflows, ignoring the window for
+// runs, at the cost of one API request per 100 runs listed.
+// -collapse-runs reports a finding repeated across a workflow's runs
+// once, against its oldest run, with every run listed in run_urls.
+// -end accepts now or latest for the scan's start time, and -last
+// <duration> replaces -start with that long before the end; the
+// GHSCAN_START, GHSCAN_END, and GHSCAN_LAST environment variables set
+// the same values. The resolved window is logged at startup.
+// -job-filter narrows each run's logs to jobs whose name matches one of
+// its regexps or whose conclusion is given as conclusion:<value>.
+//
+// The merge subcommand combines caches written by sharded scans into a
+// single JSON and/or CSV report:
+//
+//	ghscan merge [-json all.json] [-csv all.csv] shard1.json shard2.json ...
+//
+// The analyze subcommand filters existing caches against a new IOC
+// without touching the network, keeping only findings whose retained
+// text matches it:
+//
+//	ghscan analyze [-ioc-content a,b] [-ioc-content-file f] [-ioc-pattern re] [-json out.json] [-csv out.csv] cache.json ...
+//
+// -selftest scans a built-in fixture log with the embedded IOC corpus
+// and exits 3 unless the ioc, base64, and pem detectors all fire, so a
+// deployed binary can be checked without a target or token:
+//
+//	ghscan -selftest
 //
 // SIGINT and SIGTERM cancel the scan; in-flight HTTP and errgroup work
 // observes the cancellation and unwinds.
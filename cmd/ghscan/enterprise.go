@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v86/github"
+)
+
+// enterpriseScopes are the classic-token scopes that can read an
+// enterprise's organization list.
+var enterpriseScopes = []string{"read:enterprise", "admin:enterprise"}
+
+// enterpriseOrgsQuery pages through the organizations of an
+// enterprise. The REST API has no equivalent endpoint.
+const enterpriseOrgsQuery = `query($slug: String!, $after: String) {
+  enterprise(slug: $slug) {
+    organizations(first: 100, after: $after) {
+      nodes { login }
+      pageInfo { hasNextPage endCursor }
+    }
+  }
+}`
+
+type enterpriseOrgsResponse struct {
+	Data struct {
+		Enterprise *struct {
+			Organizations struct {
+				Nodes []struct {
+					Login string `json:"login"`
+				} `json:"nodes"`
+				PageInfo struct {
+					HasNextPage bool   `json:"hasNextPage"`
+					EndCursor   string `json:"endCursor"`
+				} `json:"pageInfo"`
+			} `json:"organizations"`
+		} `json:"enterprise"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// graphqlURL returns the GraphQL endpoint paired with gh's REST base:
+// https://api.github.com/graphql for github.com and
+// https://host/api/graphql for GitHub Enterprise Server.
+func graphqlURL(gh *github.Client) string {
+	base := *gh.BaseURL
+	if strings.HasSuffix(base.Path, "/api/v3/") {
+		base.Path = strings.TrimSuffix(base.Path, "v3/") + "graphql"
+		return base.String()
+	}
+	return base.JoinPath("graphql").String()
+}
+
+// listEnterpriseOrgs returns the login of every organization in the
+// enterprise identified by slug. It doubles as the enterprise preflight:
+// a classic token without an enterprise scope, or an enterprise the
+// token cannot see, fails with an error naming the missing access
+// rather than silently scanning nothing.
+func listEnterpriseOrgs(ctx context.Context, gh *github.Client, slug string) ([]string, error) {
+	var orgs []string
+	var after *string
+	for {
+		body := map[string]any{
+			"query":     enterpriseOrgsQuery,
+			"variables": map[string]any{"slug": slug, "after": after},
+		}
+		req, err := gh.NewRequest(ctx, http.MethodPost, graphqlURL(gh), body)
+		if err != nil {
+			return nil, fmt.Errorf("building enterprise query: %w", err)
+		}
+		var out enterpriseOrgsResponse
+		resp, err := gh.Do(req, &out)
+		// Classic tokens advertise their scopes; fine-grained and app
+		// tokens do not, and are judged by the query result instead.
+		if resp != nil {
+			if scopes := resp.Header.Get("X-OAuth-Scopes"); scopes != "" && !hasAnyScope(scopes, enterpriseScopes) {
+				return nil, fmt.Errorf("token scopes %q lack %s, which is required to list enterprise organizations", scopes, strings.Join(enterpriseScopes, " or "))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing organizations for enterprise %s: %w", slug, err)
+		}
+		if len(out.Errors) > 0 {
+			msgs := make([]string, 0, len(out.Errors))
+			for _, e := range out.Errors {
+				msgs = append(msgs, e.Message)
+			}
+			return nil, fmt.Errorf("listing organizations for enterprise %s: %w", slug, errors.New(strings.Join(msgs, "; ")))
+		}
+		ent := out.Data.Enterprise
+		if ent == nil {
+			return nil, fmt.Errorf("enterprise %s not found or not visible to this token; an enterprise owner token with %s is required", slug, strings.Join(enterpriseScopes, " or "))
+		}
+		for _, n := range ent.Organizations.Nodes {
+			if n.Login != "" {
+				orgs = append(orgs, n.Login)
+			}
+		}
+		if !ent.Organizations.PageInfo.HasNextPage {
+			return orgs, nil
+		}
+		after = new(ent.Organizations.PageInfo.EndCursor)
+	}
+}
+
+// hasAnyScope reports whether the comma-separated X-OAuth-Scopes value
+// grants any of want.
+func hasAnyScope(header string, want []string) bool {
+	for scope := range strings.SplitSeq(header, ",") {
+		if slices.Contains(want, strings.TrimSpace(scope)) {
+			return true
+		}
+	}
+	return false
+}
+
+// listOrgRepos returns every repository owned by org.
+func listOrgRepos(ctx context.Context, gh *github.Client, org string) ([]*github.Repository, error) {
+	opt := &github.RepositoryListByOrgOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var repos []*github.Repository
+	for {
+		orgRepos, resp, err := gh.Repositories.ListByOrg(ctx, org, opt)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, orgRepos...)
+		if resp.NextPage == 0 {
+			return repos, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
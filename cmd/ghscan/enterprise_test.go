@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v86/github"
+)
+
+func newEnterpriseTestClient(t *testing.T, h http.HandlerFunc) *github.Client {
+	t.Helper()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+	gh := github.NewClient(ts.Client())
+	u, err := url.Parse(ts.URL + "/")
+	if err != nil {
+		t.Fatalf("parse base URL: %v", err)
+	}
+	gh.BaseURL = u
+	return gh
+}
+
+// TestListEnterpriseOrgs covers pagination and the preflight failures:
+// a classic token without an enterprise scope and an enterprise the
+// token cannot see.
+func TestListEnterpriseOrgs(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"":   `{"data":{"enterprise":{"organizations":{"nodes":[{"login":"a"},{"login":"b"}],"pageInfo":{"hasNextPage":true,"endCursor":"c1"}}}}}`,
+		"c1": `{"data":{"enterprise":{"organizations":{"nodes":[{"login":"c"}],"pageInfo":{"hasNextPage":false}}}}}`,
+	}
+
+	cases := []struct {
+		name    string
+		scopes  string
+		body    string
+		want    []string
+		wantErr string
+	}{
+		{name: "paginates", scopes: "repo, read:enterprise", want: []string{"a", "b", "c"}},
+		{name: "fine-grained token", want: []string{"a", "b", "c"}},
+		{name: "missing scope", scopes: "repo, read:org", wantErr: "lack read:enterprise"},
+		{name: "enterprise not visible", body: `{"data":{"enterprise":null}}`, wantErr: "not found or not visible"},
+		{name: "graphql error", body: `{"data":{"enterprise":null},"errors":[{"message":"boom"}]}`, wantErr: "boom"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gh := newEnterpriseTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/graphql" || r.Method != http.MethodPost {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				var req struct {
+					Variables struct {
+						Slug  string  `json:"slug"`
+						After *string `json:"after"`
+					} `json:"variables"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				if req.Variables.Slug != "acme" {
+					t.Errorf("slug=%q, want acme", req.Variables.Slug)
+				}
+				if tc.scopes != "" {
+					w.Header().Set("X-OAuth-Scopes", tc.scopes)
+				}
+				body := tc.body
+				if body == "" {
+					after := ""
+					if req.Variables.After != nil {
+						after = *req.Variables.After
+					}
+					body = pages[after]
+				}
+				_, _ = w.Write([]byte(body))
+			})
+
+			got, err := listEnterpriseOrgs(t.Context(), gh, "acme")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("err=%v, want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("listEnterpriseOrgs: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("orgs=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGraphqlURL(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		base string
+		want string
+	}{
+		{base: "https://api.github.com/", want: "https://api.github.com/graphql"},
+		{base: "https://ghe.example.com/api/v3/", want: "https://ghe.example.com/api/graphql"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.base, func(t *testing.T) {
+			t.Parallel()
+			gh := github.NewClient(nil)
+			u, err := url.Parse(tc.base)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			gh.BaseURL = u
+			if got := graphqlURL(gh); got != tc.want {
+				t.Fatalf("graphqlURL(%s)=%s, want %s", tc.base, got, tc.want)
+			}
+		})
+	}
+}
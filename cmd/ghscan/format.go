@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// formatFiles maps each -format name to the conventional file name it
+// is written to under -output-dir.
+var formatFiles = map[string]string{
+	"json":     "results.json",
+	"csv":      "results.csv",
+	"summary":  "summary.json",
+	"markdown": "report.md",
+	"sqlite":   "findings.db",
+	"stix":     "findings.stix.json",
+}
+
+// outputPaths holds the per-format output paths, relative to
+// ghscan.ResultsDir, that the explicit path flags name.
+type outputPaths struct {
+	JSON, CSV, Summary, Markdown, SQLite, STIX string
+}
+
+// applyFormats fills in the path of each format listed in formats as
+// dir/<conventional name>. A path already set by its explicit flag is
+// kept, so -json and friends still win over -format. Unknown and
+// duplicate formats are rejected.
+func applyFormats(paths *outputPaths, formats []string, dir string) error {
+	seen := make(map[string]bool, len(formats))
+	for _, f := range formats {
+		name := strings.ToLower(f)
+		base, ok := formatFiles[name]
+		if !ok {
+			return fmt.Errorf("unknown format %q (want one of %s)", f, strings.Join(slices.Sorted(maps.Keys(formatFiles)), ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("format %q listed more than once", f)
+		}
+		seen[name] = true
+
+		var target *string
+		switch name {
+		case "json":
+			target = &paths.JSON
+		case "csv":
+			target = &paths.CSV
+		case "summary":
+			target = &paths.Summary
+		case "markdown":
+			target = &paths.Markdown
+		case "sqlite":
+			target = &paths.SQLite
+		case "stix":
+			target = &paths.STIX
+		}
+		if *target == "" {
+			*target = filepath.Join(dir, base)
+		}
+	}
+	return nil
+}
+
+// summaryOnlyConflicts returns the flags of the detailed outputs that
+// paths and perRepo request, none of which -summary-only writes.
+func summaryOnlyConflicts(paths outputPaths, perRepo bool) []string {
+	var conflicts []string
+	for _, o := range []struct {
+		flag string
+		set  bool
+	}{
+		{"-json", paths.JSON != ""},
+		{"-csv", paths.CSV != ""},
+		{"-markdown", paths.Markdown != ""},
+		{"-sqlite", paths.SQLite != ""},
+		{"-stix", paths.STIX != ""},
+		{"-per-repo-output", perRepo},
+	} {
+		if o.set {
+			conflicts = append(conflicts, o.flag)
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestApplyFormats pins the -format expansion: each listed format gets
+// its conventional file under -output-dir, explicit path flags win,
+// and unknown or repeated formats are rejected.
+func TestApplyFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		paths   outputPaths
+		formats []string
+		dir     string
+		want    outputPaths
+		wantErr bool
+	}{
+		{
+			name:    "conventional names under the output dir",
+			formats: []string{"json", "CSV", "sqlite"},
+			dir:     "ci",
+			want:    outputPaths{JSON: "ci/results.json", CSV: "ci/results.csv", SQLite: "ci/findings.db"},
+		},
+		{
+			name:    "no output dir writes to the results root",
+			formats: []string{"summary", "markdown", "stix"},
+			want:    outputPaths{Summary: "summary.json", Markdown: "report.md", STIX: "findings.stix.json"},
+		},
+		{
+			name:    "explicit path flags win",
+			paths:   outputPaths{JSON: "mine.json"},
+			formats: []string{"json", "csv"},
+			dir:     "ci",
+			want:    outputPaths{JSON: "mine.json", CSV: "ci/results.csv"},
+		},
+		{
+			name:  "no formats leave the paths alone",
+			paths: outputPaths{CSV: "out.csv"},
+			want:  outputPaths{CSV: "out.csv"},
+		},
+		{name: "unknown format", formats: []string{"sarif"}, wantErr: true},
+		{name: "repeated format", formats: []string{"json", "JSON"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := tc.paths
+			err := applyFormats(&got, tc.formats, tc.dir)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("applyFormats(%q) succeeded, want an error", tc.formats)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyFormats(%q): %v", tc.formats, err)
+			}
+			if got != tc.want {
+				t.Fatalf("applyFormats(%q)=%+v, want %+v", tc.formats, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestSummaryOnlyConflicts asserts -summary-only rejects every detailed
+// output but still allows the summary itself.
+func TestSummaryOnlyConflicts(t *testing.T) {
+	t.Parallel()
+
+	if got := summaryOnlyConflicts(outputPaths{Summary: "summary.json"}, false); len(got) != 0 {
+		t.Fatalf("summary alone conflicts with %q, want none", got)
+	}
+	got := summaryOnlyConflicts(outputPaths{JSON: "r.json", CSV: "r.csv", STIX: "s.json"}, true)
+	if want := []string{"-json", "-csv", "-stix", "-per-repo-output"}; !slices.Equal(got, want) {
+		t.Fatalf("conflicts=%q, want %q", got, want)
+	}
+}
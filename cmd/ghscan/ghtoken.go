@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// ghHost is the host ghscan's GitHub client talks to. The gh CLI
+// fallbacks only accept a token gh holds for this host, so a login to a
+// GitHub Enterprise Server is never sent to github.com.
+const ghHost = "github.com"
+
+// resolveGitHubToken returns the viper-resolved token when non-empty.
+// Otherwise it falls back to the gh CLI's login for ghHost: first
+// `gh auth token --hostname`, then, when gh is missing or fails, the
+// oauth_token stored in gh's hosts.yml (see ghHostsToken). The
+// fallbacks let users avoid exporting GITHUB_TOKEN when the gh CLI is
+// already authenticated. Errors never include the token value.
+func resolveGitHubToken(ctx context.Context, v *viper.Viper) (string, error) {
+	if t := strings.TrimSpace(v.GetString("token")); t != "" {
+		return t, nil
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", ghHost)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmdErr := cmd.Run()
+	if cmdErr == nil {
+		if tok := strings.TrimRight(stdout.String(), " \t\r\n"); tok != "" {
+			return tok, nil
+		}
+		cmdErr = fmt.Errorf("empty output")
+	}
+	tok, err := ghHostsToken(ghHost)
+	if err != nil {
+		return "", fmt.Errorf("GITHUB_TOKEN not set, 'gh auth token' failed (%w), and %v", cmdErr, err)
+	}
+	return tok, nil
+}
+
+// ghConfigDir returns the directory the gh CLI keeps its configuration
+// in: $GH_CONFIG_DIR, else $XDG_CONFIG_HOME/gh, else ~/.config/gh.
+func ghConfigDir() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh"), nil
+}
+
+// ghHostsToken returns the oauth_token gh's hosts.yml holds for host.
+// gh versions that keep the token in the system keyring leave it out of
+// hosts.yml; only `gh auth token` can read those.
+func ghHostsToken(host string) (string, error) {
+	dir, err := ghConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating the gh config directory: %w", err)
+	}
+	path := filepath.Join(dir, "hosts.yml")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+	tok := strings.TrimSpace(hosts[host].OAuthToken)
+	if tok == "" {
+		return "", fmt.Errorf("%s has no token for %s", path, host)
+	}
+	return tok, nil
+}
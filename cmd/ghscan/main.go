@@ -1,14 +1,21 @@
 package main
 
 import (
-	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
-	"os/exec"
 	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -16,9 +23,11 @@ import (
 	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/ghscan/internal/action"
 	"github.com/chainguard-dev/ghscan/internal/file"
+	"github.com/chainguard-dev/ghscan/internal/redact"
 	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
 	httpclient "github.com/chainguard-dev/ghscan/pkg/httpclient"
 	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
 	"github.com/google/go-github/v86/github"
 	"github.com/spf13/viper"
 	"golang.org/x/oauth2"
@@ -29,37 +38,20 @@ var logger *clog.Logger
 // Exit codes are part of the binary's public contract:
 //
 //	0 — clean run (zero IOC matches)
-//	2 — at least one IOC match
+//	2 — at least one IOC match (with -baseline, one not in the baseline)
 //	3 — scan pipeline failure (network, auth, IO, etc.)
+//	4 — scan failure caused only by GitHub's rate limits
+//	5 — scan failure caused only by a token GitHub rejects or that
+//	    lacks access
 const (
-	exitClean      = 0
-	exitFindings   = 2
-	exitScanFailed = 3
+	exitClean       = 0
+	exitFindings    = 2
+	exitScanFailed  = 3
+	exitRateLimited = 4
+	exitNoAccess    = 5
 )
 
-// resolveGitHubToken returns the viper-resolved token when non-empty,
-// otherwise falls back to invoking `gh auth token`. The fallback lets
-// users avoid exporting GITHUB_TOKEN when the gh CLI is already
-// authenticated. Errors never include the token value.
-func resolveGitHubToken(ctx context.Context, v *viper.Viper) (string, error) {
-	if t := strings.TrimSpace(v.GetString("token")); t != "" {
-		return t, nil
-	}
-	var stdout, stderr bytes.Buffer
-	cmd := exec.CommandContext(ctx, "gh", "auth", "token")
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("GITHUB_TOKEN not set and 'gh auth token' failed: %w", err)
-	}
-	tok := strings.TrimRight(stdout.String(), " \t\r\n")
-	if tok == "" {
-		return "", fmt.Errorf("GITHUB_TOKEN not set and 'gh auth token' returned empty output")
-	}
-	return tok, nil
-}
-
-// setDefaults seeds the supplied viper instance with every key main()
+// setDefaults seeds the supplied viper instance with every key a scan
 // reads. Keeping the list in one helper makes the binary safe to run
 // with no config.yaml present and lets tests assert the defaults
 // without driving the full flag pipeline. The duration values are
@@ -73,13 +65,67 @@ func resolveGitHubToken(ctx context.Context, v *viper.Viper) (string, error) {
 // safeguard.
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("token", os.Getenv("GITHUB_TOKEN"))
+	v.SetDefault("enterprise", "")
+	v.SetDefault("gitlab_project", "")
+	v.SetDefault("gitlab_url", workflow.DefaultGitLabURL)
+	v.SetDefault("gitlab_token", os.Getenv("GITLAB_TOKEN"))
+	v.SetDefault("redact_token", true)
+	v.SetDefault("logs_dir", "")
+	v.SetDefault("log_extensions", workflow.DefaultLogExtensions)
+	v.SetDefault("logs_recursive", true)
 	v.SetDefault("clean_cache", false)
+	v.SetDefault("no_cache", false)
+	v.SetDefault("per_repo_output", false)
+	v.SetDefault("group_by_severity", false)
+	v.SetDefault("json_nested", false)
+	v.SetDefault("summary_output", "")
+	v.SetDefault("summary_only", false)
+	v.SetDefault("sqlite_output", "")
+	v.SetDefault("markdown_output", "")
+	v.SetDefault("stix_output", "")
+	v.SetDefault("formats", []string{})
+	v.SetDefault("output_dir", "")
+	v.SetDefault("output_mode", "0600")
+	v.SetDefault("baseline", "")
+	v.SetDefault("github_annotations", false)
+	v.SetDefault("upload_sarif", false)
+	v.SetDefault("upload_sarif_empty", false)
+	// Empty keeps each client's built-in X-GitHub-Api-Version pin.
+	v.SetDefault("api_version", "")
+	v.SetDefault("ca_cert", "")
+	v.SetDefault("max_repos", 0)
+	v.SetDefault("repo_languages", []string{})
+	v.SetDefault("repo_topics", []string{})
+	v.SetDefault("visibility", visibilityAll)
+	v.SetDefault("org_workflow", "")
+	v.SetDefault("runs_file", "")
 	v.SetDefault("ioc.name", "tj-actions/changed-files")
+	v.SetDefault("ioc.content_file", "")
 	v.SetDefault("ioc_file", "")
+	// The scan window may come from GHSCAN_START, GHSCAN_END, and
+	// GHSCAN_LAST, so a scheduled job can set it without a config
+	// file. last=0s uses start_time.
+	bindWindowEnv(v)
+	v.SetDefault("last", "0s")
 	v.SetDefault("global_timeout", "3h")
 	v.SetDefault("operation_timeout", "30s")
 	v.SetDefault("max_retries", 3)
+	v.SetDefault("circuit_breaker_threshold", action.DefaultBreakerThreshold)
+	v.SetDefault("max_decode_depth", workflow.DefaultMaxDecodeDepth)
+	v.SetDefault("allow_binary_decoded", false)
+	v.SetDefault("context_lines", 0)
+	v.SetDefault("explain", false)
+	v.SetDefault("min_confidence", 0)
+	// fallback_concurrency bounds per-job log downloads when a run's
+	// log archive has expired; it can only lower the API fan-out.
+	v.SetDefault("fallback_concurrency", workflow.DefaultFallbackConcurrency)
 	v.SetDefault("max_concurrency", 32)
+	v.SetDefault("adaptive_concurrency", false)
+	v.SetDefault("pause_on_rate_limit", false)
+	v.SetDefault("best_effort", false)
+	v.SetDefault("repo_stagger", "0s")
+	v.SetDefault("flush_interval", action.DefaultFlushInterval.String())
+	v.SetDefault("flush_size", 0)
 	// Per-operation budgets derived from the legacy literal multipliers
 	// (req.Timeout*2, req.Timeout*1, operation_timeout*5) so the
 	// resulting wall-clock budgets are unchanged for callers that do
@@ -87,36 +133,325 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("workflow_fetch_budget", "60s")
 	v.SetDefault("run_scan_budget", "30s")
 	v.SetDefault("repo_enum_budget", "150s")
+	// Zero sizes each repository's budget to its workflows and runs,
+	// starting from repo_enum_budget.
+	v.SetDefault("repo_timeout", "0s")
 	// YAML and log scanning are complementary: YAML catches known-bad
 	// uses: refs before a step runs (preventing secret exfiltration),
 	// logs catch behavioral IOCs that surface only after execution.
 	// Both default on so existing users observe no behavior change.
 	v.SetDefault("scan_yaml", true)
 	v.SetDefault("scan_logs", true)
+	v.SetDefault("scan_history", false)
+	v.SetDefault("modified_only", false)
+	v.SetDefault("scan_summaries", false)
+	v.SetDefault("scan_attempts", false)
+	v.SetDefault("scan_commit_messages", false)
+	v.SetDefault("scan_pr_bodies", false)
+	v.SetDefault("correlate_secrets", false)
+	v.SetDefault("scan_actions", false)
+	v.SetDefault("scan_actions_depth", action.DefaultScanActionsDepth)
+	v.SetDefault("resolve_tags", false)
+	v.SetDefault("detect_egress", false)
+	v.SetDefault("detect_cache_poisoning", false)
+	v.SetDefault("detect_mask_bypass", false)
+	v.SetDefault("detect_suspicious_git", false)
+	v.SetDefault("detect_base32", false)
+	v.SetDefault("detect_base85", false)
+	v.SetDefault("git_remote_allowlist", workflow.DefaultGitRemoteAllowlist)
+	v.SetDefault("keep_logs", false)
+	v.SetDefault("keep_all_logs", false)
+	v.SetDefault("egress_allowlist", workflow.DefaultEgressAllowlist)
+	// Empty lists run every built-in and -detect-* detector.
+	v.SetDefault("enable_detectors", []string{})
+	v.SetDefault("disable_detectors", []string{})
+	v.SetDefault("conclusions", []string{})
+	v.SetDefault("latest_only", false)
+	v.SetDefault("all_runs", false)
+	v.SetDefault("collapse_runs", false)
+	v.SetDefault("log_cache_dir", "")
+	v.SetDefault("log_cache_ttl", "0s")
+	v.SetDefault("job_filter", []string{})
+	v.SetDefault("search_query_template", action.DefaultSearchQueryTemplate)
+}
+
+// buildIOC assembles the IOC described by the -ioc-* flags. content is
+// a comma-separated list, merged with the newline-delimited entries of
+// contentFile when set; iocFile, when set, overrides the embedded
+// corpus. The corpus is returned so the YAML path can consult it.
+func buildIOC(name, content, contentFile, pattern, iocFile string) (*ioc.IOC, *ioc.Corpus, error) {
+	contentParts := splitList(content)
+	if content != "" && len(contentParts) == 0 {
+		logger.Warn("ioc-content flag was provided but no valid content was parsed")
+	}
+	if strings.TrimSpace(contentFile) != "" {
+		fileParts, err := ioc.LoadContentFile(contentFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading IOC content: %w", err)
+		}
+		if len(fileParts) == 0 {
+			logger.Warnf("ioc-content-file %s contains no content", contentFile)
+		}
+		contentParts = mergeContent(contentParts, fileParts)
+	}
+
+	var corpus *ioc.Corpus
+	if strings.TrimSpace(iocFile) != "" {
+		c, err := ioc.LoadCorpusFile(iocFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("loading IOC corpus: %w", err)
+		}
+		corpus = c
+	}
+
+	findIOC, err := ioc.NewIOC(&ioc.Config{
+		Name:    name,
+		Content: contentParts,
+		Pattern: pattern,
+		Corpus:  corpus,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return findIOC, corpus, nil
+}
+
+// mergeContent appends extra to base, dropping entries already seen so
+// a digest listed both on the command line and in a file is matched
+// once.
+func mergeContent(base, extra []string) []string {
+	seen := make(map[string]struct{}, len(base)+len(extra))
+	out := make([]string, 0, len(base)+len(extra))
+	for _, c := range slices.Concat(base, extra) {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+	}
+	return out
+}
+
+// splitList splits a comma-separated flag value, trimming whitespace
+// and dropping empty entries.
+func splitList(s string) []string {
+	out := make([]string, 0)
+	for part := range strings.SplitSeq(s, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// GitHub account names are alphanumerics and hyphens, neither starting
+// nor ending with a hyphen, up to 39 characters. Repository names are
+// alphanumerics, '.', '_' and '-', up to 100 characters.
+var (
+	ownerNameRE = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,37}[A-Za-z0-9])?$`)
+	repoNameRE  = regexp.MustCompile(`^[A-Za-z0-9._-]{1,100}$`)
+)
+
+// scanTarget is a parsed -target: an organization (Repo empty), a
+// repository, or a single run of a repository when RunID is set.
+type scanTarget struct {
+	Owner string
+	Repo  string
+	RunID int64
+}
+
+// parseTarget parses a -target value. A github.com URL, as pasted from
+// the browser, names an organization (https://github.com/org or
+// .../orgs/org), a repository (https://github.com/owner/repo and any
+// page under it), or a single run (.../owner/repo/actions/runs/123).
+// Anything else is read as org or owner/repository; a trailing slash
+// after the repository is ignored, but a bare "owner/" is an error,
+// since it more likely lost its repository than meant the org.
+func parseTarget(target string) (scanTarget, error) {
+	t := strings.TrimSpace(target)
+	var (
+		segs  []string
+		runID int64
+	)
+	if isGitHubURL(t) {
+		if !strings.Contains(t, "://") {
+			t = "https://" + t
+		}
+		u, err := url.Parse(t)
+		if err != nil {
+			return scanTarget{}, fmt.Errorf("invalid target %q: %w", target, err)
+		}
+		if h := strings.ToLower(u.Hostname()); h != "github.com" && h != "www.github.com" {
+			return scanTarget{}, fmt.Errorf("invalid target %q: not a github.com URL", target)
+		}
+		segs = strings.Split(strings.Trim(u.Path, "/"), "/")
+		switch {
+		case segs[0] == "orgs" && len(segs) > 1:
+			segs = segs[1:2]
+		case len(segs) > 3 && segs[2] == "actions" && segs[3] == "runs":
+			if len(segs) < 5 {
+				return scanTarget{}, fmt.Errorf("invalid target %q: missing run ID after actions/runs", target)
+			}
+			if runID, err = strconv.ParseInt(segs[4], 10, 64); err != nil || runID <= 0 {
+				return scanTarget{}, fmt.Errorf("invalid target %q: %q is not a run ID", target, segs[4])
+			}
+			segs = segs[:2]
+		case len(segs) > 2:
+			// Any other page of a repository, e.g. /tree/main.
+			segs = segs[:2]
+		}
+		if len(segs) == 2 {
+			segs[1] = strings.TrimSuffix(segs[1], ".git")
+		}
+	} else {
+		if trimmed := strings.TrimRight(t, "/"); strings.Contains(trimmed, "/") {
+			t = trimmed
+		}
+		segs = strings.Split(t, "/")
+	}
+
+	owner := segs[0]
+	switch {
+	case owner == "":
+		return scanTarget{}, fmt.Errorf("invalid target %q: missing owner, expected org or owner/repository", target)
+	case !ownerNameRE.MatchString(owner):
+		return scanTarget{}, fmt.Errorf("invalid target %q: %q is not a valid GitHub owner name", target, owner)
+	case len(segs) == 1:
+		return scanTarget{Owner: owner}, nil
+	case len(segs) > 2:
+		return scanTarget{}, fmt.Errorf("invalid target %q: expected owner/repository, got extra path segments", target)
+	}
+	repo := segs[1]
+	switch {
+	case repo == "":
+		return scanTarget{}, fmt.Errorf("invalid target %q: missing repository after %q", target, owner+"/")
+	case repo == "." || repo == ".." || !repoNameRE.MatchString(repo):
+		return scanTarget{}, fmt.Errorf("invalid target %q: %q is not a valid repository name", target, repo)
+	}
+	return scanTarget{Owner: owner, Repo: repo, RunID: runID}, nil
+}
+
+// isGitHubURL reports whether a -target value is a URL rather than an
+// org or owner/repository, with or without its scheme.
+func isGitHubURL(t string) bool {
+	lower := strings.ToLower(t)
+	return strings.Contains(lower, "://") ||
+		strings.HasPrefix(lower, "github.com/") || strings.HasPrefix(lower, "www.github.com/")
+}
+
+// limitRepos caps repos to the first maxRepos entries in listing
+// order. A non-positive maxRepos leaves the slice untouched, which is
+// the default so full scans are never silently truncated.
+func limitRepos(repos []*github.Repository, maxRepos int) []*github.Repository {
+	if maxRepos <= 0 || len(repos) <= maxRepos {
+		return repos
+	}
+	return repos[:maxRepos]
+}
+
+// formatSummary renders one line per repository, sorted by repository
+// and then IOC name, e.g. "octo/repo: tj-actions/changed-files=2".
+func formatSummary(s ghscan.Summary) []string {
+	repos := make([]string, 0, len(s))
+	for repo := range s {
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+
+	lines := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		names := make([]string, 0, len(s[repo]))
+		for name := range s[repo] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, s[repo][name]))
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", repo, strings.Join(parts, ", ")))
+	}
+	return lines
+}
+
+// formatNewFindings renders one line per finding absent from the
+// baseline so a CI log shows exactly what regressed, e.g.
+// "NEW octo/repo ci.yml (high base64): <decoded data> <run URL>".
+func formatNewFindings(results []ghscan.Result) []string {
+	lines := make([]string, 0, len(results))
+	for _, r := range results {
+		line := fmt.Sprintf("NEW %s %s", r.Repository, r.WorkflowFileName)
+		if kind := strings.TrimSpace(r.Severity + " " + r.KeyTypes); kind != "" {
+			line += " (" + kind + ")"
+		}
+		line += ": " + cmp.Or(r.DecodedData, r.LineData, r.OffendingUsesLine)
+		if url := cmp.Or(r.WorkflowRunURL, r.WorkflowURL); url != "" {
+			line += " " + url
+		}
+		lines = append(lines, line)
+	}
+	return lines
 }
 
 // resolveExitCode maps the outcome of a scan to the binary's exit-code
 // contract. Pure function so it is trivially testable; the io paths
-// in main() route through it.
+// in runScan route through it.
 func resolveExitCode(scanErr, writeErr error, findings int) int {
-	if scanErr != nil || writeErr != nil {
+	if writeErr != nil {
 		return exitScanFailed
 	}
+	if scanErr != nil {
+		return scanFailureCode(scanErr)
+	}
 	if findings > 0 {
 		return exitFindings
 	}
 	return exitClean
 }
 
+// scanFailureCode returns exitRateLimited or exitNoAccess when every
+// failure in err is an [action.ScanError] of that category, so a
+// scheduler can retry later or fix the token; any other failure, or a
+// mix, is exitScanFailed.
+func scanFailureCode(err error) int {
+	scanErrs := action.ScanErrors(err)
+	if len(scanErrs) == 0 {
+		return exitScanFailed
+	}
+	category := scanErrs[0].Category
+	for _, e := range scanErrs[1:] {
+		if e.Category != category {
+			return exitScanFailed
+		}
+	}
+	switch category {
+	case action.ErrorRateLimit:
+		return exitRateLimited
+	case action.ErrorPermission:
+		return exitNoAccess
+	}
+	return exitScanFailed
+}
+
 func main() {
 	logger = clog.New(slog.Default().Handler())
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case mergeCommand:
+			os.Exit(runMerge(os.Args[2:]))
+		case analyzeCommand:
+			os.Exit(runAnalyze(os.Args[2:]))
+		}
+	}
+
 	// Use an explicit viper instance instead of the package singleton.
 	// This keeps the binary's config state self-contained and lets
 	// tests construct their own *viper.Viper without leaking globals.
 	// internal/action still reads max_retries / max_concurrency / per-op
-	// budgets off the global viper instance, so we mirror those keys
-	// from v below.
+	// budgets off the global viper instance, so mirrorGlobals copies
+	// those keys from v.
 	v := viper.New()
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
@@ -127,32 +462,81 @@ func main() {
 		logger.Info("No config file found; using defaults and flags")
 	}
 
-	targetFlag := flag.String("target", v.GetString("target"), "Organization name or owner/repository (e.g. octocat/Hello-World)")
-	tokenFlag := flag.String("token", v.GetString("token"), "GitHub Personal Access Token")
-	cacheFileFlag := flag.String("cache", v.GetString("cache_file"), "Path to JSON cache file")
-	cleanCacheFlag := flag.Bool("clean-cache", v.GetBool("clean_cache"), "Reset the findings cache")
-	jsonOutputFlag := flag.String("json", v.GetString("json_output"), "Path to final JSON output file")
-	csvOutputFlag := flag.String("csv", v.GetString("csv_output"), "Path to final CSV output file")
-	startTimeFlag := flag.String("start", v.GetString("start_time"), "Start time for workflow run filtering (RFC3339)")
-	endTimeFlag := flag.String("end", v.GetString("end_time"), "End time for workflow run filtering (RFC3339)")
-	iocNameFlag := flag.String("ioc-name", v.GetString("ioc.name"), "IOC Logs to scan for (e.g. tj-actions/changed-files")
-	iocContentFlag := flag.String("ioc-content", v.GetString("ioc.content"), "Comma-separated string(s) to search for in logs")
-	iocPatternFlag := flag.String("ioc-pattern", v.GetString("ioc.pattern"), "Regex pattern to search logs with")
-	iocFileFlag := flag.String("ioc-file", v.GetString("ioc_file"), "Path to a JSON corpus file overriding the embedded IOC list")
-	scanYAMLFlag := flag.Bool("scan-yaml", v.GetBool("scan_yaml"), "Scan workflow YAML for known-bad uses: refs before execution")
-	scanLogsFlag := flag.Bool("scan-logs", v.GetBool("scan_logs"), "Scan workflow run logs for behavioral IOCs after execution")
-	flag.Parse()
+	// flag.CommandLine exits on a parse error, so err is always nil.
+	opts, _ := parseScanArgs(v, flag.CommandLine, os.Args[1:])
 
-	if !*scanYAMLFlag && !*scanLogsFlag {
-		logger.Fatal("At least one of -scan-yaml or -scan-logs must be enabled")
+	if opts.selftest {
+		if err := runSelftest(logger); err != nil {
+			logger.Errorf("Self-test failed: %v", err)
+			os.Exit(exitScanFailed)
+		}
+		logger.Info("Self-test passed")
+		os.Exit(exitClean)
 	}
 
-	if *targetFlag == "" {
-		logger.Fatal("Target must be provided")
+	if err := opts.validate(); err != nil {
+		logger.Fatalf("Invalid flags: %v", err)
+	}
+	if exitCode := runScan(v, &opts); exitCode != exitClean {
+		os.Exit(exitCode)
+	}
+}
+
+// runScan runs the scan opts describes and writes its outputs,
+// returning the process exit code. It releases its contexts and files
+// before returning, so main can exit with the code.
+func runScan(v *viper.Viper, opts *scanOptions) int {
+	outputMode, err := file.ParseFileMode(opts.outputMode)
+	if err != nil {
+		logger.Fatalf("Invalid -output-mode: %v", err)
+	}
+	file.SetFileMode(outputMode)
+
+	// The runs file is opened up front so a bad path fails before any
+	// API call; it is read as the scan proceeds.
+	runsFile, err := openRunsFile(opts.runsFile)
+	if err != nil {
+		logger.Fatalf("Opening -runs-file: %v", err)
+	}
+	if runsFile != nil {
+		defer runsFile.Close()
+	}
+	if err := checkLogsDir(opts.logsDir); err != nil {
+		logger.Fatalf("%v", err)
 	}
 
-	globalTimeoutStr := v.GetString("global_timeout")
-	globalTimeout, err := time.ParseDuration(globalTimeoutStr)
+	// The window is resolved once, before any API call, so now and
+	// -last are pinned for the whole scan and logged for audit.
+	startTime, endTime, err := resolveWindow(opts.startTime, opts.endTime, opts.last, time.Now().UTC())
+	if err != nil {
+		logger.Fatalf("Invalid scan window: %v", err)
+	}
+	logger.Infof("Scan window: %s to %s (%s)", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), endTime.Sub(startTime))
+
+	target, err := opts.scanTarget()
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+	if err := opts.resolveOutputs(); err != nil {
+		logger.Fatalf("%v", err)
+	}
+	logOpts, err := opts.logOptions(v)
+	if err != nil {
+		logger.Fatalf("%v", err)
+	}
+
+	// tlsConfig is shared by the go-github transport and the raw HTTP
+	// client so log downloads trust the same roots as API calls.
+	var tlsConfig *tls.Config
+	if opts.caCert != "" {
+		roots, err := httpclient.LoadCertPool(opts.caCert)
+		if err != nil {
+			logger.Fatalf("Invalid -ca-cert: %v", err)
+		}
+		tlsConfig = httpclient.TLSConfig(roots)
+	}
+
+	globalTimeout, err := time.ParseDuration(v.GetString("global_timeout"))
 	if err != nil {
 		logger.Fatalf("Invalid global timeout: %v", err)
 	}
@@ -164,155 +548,474 @@ func main() {
 	defer cancel()
 	ctx = clog.WithLogger(ctx, logger)
 
-	v.Set("token", *tokenFlag)
-	token, err := resolveGitHubToken(ctx, v)
+	if opts.usesGitHub() {
+		v.Set("token", opts.token)
+		token, err := resolveGitHubToken(ctx, v)
+		if err != nil {
+			logger.Fatal("GITHUB_TOKEN not set, -token not provided, and no gh CLI token for github.com was found")
+		}
+		opts.token = token
+	}
+
+	// No code path should put a token in an error or URL; this is the
+	// safety net for the ones that would, since outputs get shared.
+	if opts.redactToken {
+		redactor := redact.New(opts.token, opts.gitlabToken)
+		logger = clog.New(redactor.Handler(logger.Handler()))
+		ctx = clog.WithLogger(ctx, logger)
+		file.SetRedactor(redactor)
+	}
+
+	mirrorGlobals(v, opts)
+
+	findIOC, corpus, err := buildIOC(opts.iocName, opts.iocContent, opts.iocContentFile, opts.iocPattern, opts.iocFile)
 	if err != nil {
-		logger.Fatal("GITHUB_TOKEN not set, -token not provided, and 'gh auth token' fallback failed")
+		logger.Fatalf("Failed to initialize IOC: %v", err)
 	}
-	*tokenFlag = token
 
-	// Mirror the keys consumed by package-level viper readers (e.g.
-	// internal/action.Scan) into the global instance so those call sites see
-	// the resolved values. This is the single point in the binary that
-	// touches the global instance.
-	gv := viper.GetViper()
-	gv.Set("max_retries", v.GetInt("max_retries"))
-	gv.Set("max_concurrency", v.GetInt("max_concurrency"))
-	gv.Set("operation_timeout", v.GetString("operation_timeout"))
-	gv.Set("workflow_fetch_budget", v.GetString("workflow_fetch_budget"))
-	gv.Set("run_scan_budget", v.GetString("run_scan_budget"))
-	gv.Set("repo_enum_budget", v.GetString("repo_enum_budget"))
-	gv.Set("scan_yaml", *scanYAMLFlag)
-	gv.Set("scan_logs", *scanLogsFlag)
-
-	contentParts := make([]string, 0)
-	if *iocContentFlag != "" {
-		for part := range strings.SplitSeq(*iocContentFlag, ",") {
-			trimmed := strings.TrimSpace(part)
-			if trimmed != "" {
-				contentParts = append(contentParts, trimmed)
-			}
+	logger.With(cmp.Or(opts.target, opts.enterprise, opts.gitlabProject, opts.runsFile, opts.logsDir))
+
+	client, hc := newGitHubClients(ctx, opts, tlsConfig)
+
+	var repos []*github.Repository
+	switch {
+	case opts.gitlabProject != "":
+		// The project's pipelines are listed by action.ScanSource.
+		logger.Infof("Scanning GitLab project %s at %s", opts.gitlabProject, opts.gitlabURL)
+	case opts.logsDir != "":
+		// The log files are listed by action.ScanSource.
+		logger.Infof("Scanning the logs in %s", opts.logsDir)
+	case runsFile != nil:
+		// The runs are read from the file by action.ScanRunRefs.
+		logger.Infof("Scanning the runs listed in %s", opts.runsFile)
+	default:
+		if repos, err = listRepos(ctx, client, opts, target); err != nil {
+			logger.Fatalf("%v", err)
 		}
+		logger.Infof("Found %d repositories to scan", len(repos))
+	}
 
-		if len(contentParts) == 0 {
-			logger.Warn("ioc-content flag was provided but no valid content was parsed")
+	// A run URL scans only that run, so the window is narrowed to the
+	// run's creation time and the request's RunID keeps only that run.
+	if target.RunID > 0 {
+		if startTime, endTime, err = runWindow(ctx, client, target); err != nil {
+			logger.Fatalf("%v", err)
 		}
 	}
 
-	var corpus *ioc.Corpus
-	if strings.TrimSpace(*iocFileFlag) != "" {
-		c, lerr := ioc.LoadCorpusFile(*iocFileFlag)
-		if lerr != nil {
-			logger.Fatalf("Failed to load IOC corpus: %v", lerr)
+	// The baseline is loaded before scanning so a bad path fails fast
+	// instead of after hours of API calls.
+	var baseline *ghscan.Cache
+	if opts.baseline != "" {
+		b, err := file.LoadBaseline(ctx, opts.baseline)
+		if err != nil {
+			logger.Fatalf("Failed to load baseline: %v", err)
 		}
-		corpus = c
+		logger.Infof("Loaded %d baseline results from %s", len(b.Results), opts.baseline)
+		baseline = &b
 	}
 
-	ic := &ioc.Config{
-		Name:    *iocNameFlag,
-		Content: contentParts,
-		Pattern: *iocPatternFlag,
-		Corpus:  corpus,
+	cache, appender := openCache(ctx, opts)
+
+	var logStore ghscan.LogStore
+	if opts.keepLogs || opts.keepAllLogs {
+		logStore = &file.LogKeeper{Logger: logger, All: opts.keepAllLogs}
+	}
+
+	// scanID labels this invocation's findings so outputs shared by
+	// successive scans can be grouped by the run that produced them.
+	scanID, scanStartedAt := ghscan.NewScanID(), time.Now().UTC()
+	logger.Infof("Starting scan %s", scanID)
+
+	cfg := ghscan.RequestConfig{
+		Cache:         cache,
+		CacheFile:     opts.cacheFile,
+		CachedResults: cachedKeys(cache),
+		Client:        client,
+		HTTPClient:    hc,
+		Corpus:        corpus,
+		EndTime:       endTime,
+		IOC:           findIOC,
+		Logs:          logStore,
+		LogOptions:    logOpts,
+		RunID:         target.RunID,
+		StartTime:     startTime,
+		Token:         opts.token,
+		ScanID:        scanID,
+		ScanStartedAt: scanStartedAt,
+	}
+	// A nil *file.Appender must not become a non-nil ResultSink.
+	if appender != nil {
+		cfg.Sink = appender
+	}
+	req := ghscan.NewRequest(cfg)
+
+	scanErr := scan(ctx, req, opts, runsFile, repos, tlsConfig)
+	if scanErr != nil {
+		logger.Errorf("Failed to scan Workflows in repos: %v", scanErr)
+	}
+	if err := appender.Close(); err != nil {
+		logger.Warnf("Failed to close incremental outputs: %v", err)
+	}
+
+	// Findings from the workflow, history, and source scans are not
+	// stamped as they are found, so fingerprint them all here.
+	ghscan.AddFingerprints(req.Cache.Results)
+
+	// The cache keeps every finding, so a later scan can apply another
+	// -min-confidence; only the reports and the exit code are filtered.
+	all := ghscan.Cache{ScanID: scanID, ScanStartedAt: scanStartedAt, Results: req.Cache.Results}
+	cr := all
+	cr.Results = ghscan.FilterConfidence(all.Results, opts.minConfidence)
+	if omitted := len(all.Results) - len(cr.Results); omitted > 0 {
+		logger.Infof("Omitted %d findings with confidence below %d from the reports", omitted, opts.minConfidence)
+	}
+	summary := ghscan.Summarize(cr.Results)
+	for _, line := range formatSummary(summary) {
+		logger.Info(line)
+	}
+	if n := req.Coverage.ExpiredRuns(); n > 0 {
+		logger.Warnf("%d runs could not be scanned because their logs have expired; findings older than GitHub's log retention window are not covered", n)
 	}
+	for _, skipped := range req.Coverage.SkippedRepos() {
+		logger.Warnf("Repository %s was not scanned: %s", skipped.Repository, skipped.Reason)
+	}
+
+	writeErr := writeOutputs(ctx, opts, all, cr, summary)
+	if opts.uploadSARIF {
+		writeErr = errors.Join(writeErr, uploadSARIF(ctx, logger, client, sarifTargets(repos, cr.Results, opts.uploadSARIFEmpty), cr.Results))
+	}
+	if writeErr != nil {
+		logger.Errorf("Failed to write outputs: %v", writeErr)
+	}
+	logger.Info("Processing complete")
+
+	return resolveExitCode(scanErr, writeErr, reportFindings(opts, cr.Results, baseline))
+}
+
+// reportFindings logs the findings in results that are not in
+// baseline, when there is one, and prints them as GitHub Actions
+// annotations with -github-annotations. It returns how many findings
+// count toward the exit code: those not in the baseline.
+func reportFindings(opts *scanOptions, results []ghscan.Result, baseline *ghscan.Cache) int {
+	reported := results
+	if baseline != nil {
+		reported = ghscan.NewSince(results, baseline.Results)
+		logger.Infof("%d of %d findings are new relative to baseline %s", len(reported), len(results), opts.baseline)
+		for _, line := range formatNewFindings(reported) {
+			logger.Warn(line)
+		}
+	}
+	if opts.githubAnnotations {
+		if err := writeAnnotations(os.Stdout, reported); err != nil {
+			logger.Errorf("Failed to write annotations: %v", err)
+		}
+	}
+	return len(reported)
+}
 
-	findIOC, err := ioc.NewIOC(ic)
+// openRunsFile opens the -runs-file at path, reading stdin for "-". It
+// returns nil when path is empty.
+func openRunsFile(path string) (io.ReadCloser, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// checkLogsDir reports a -logs-dir that is not a readable directory.
+func checkLogsDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	info, err := os.Stat(dir)
 	if err != nil {
-		logger.Fatalf("Failed to initialize IOC: %v", err)
+		return fmt.Errorf("opening -logs-dir: %w", err)
 	}
+	if !info.IsDir() {
+		return fmt.Errorf("-logs-dir %s is not a directory", dir)
+	}
+	return nil
+}
 
-	logger.With(*targetFlag)
+// scanTarget returns the parsed -target, which is empty for other
+// sources, and checks that -all-runs names a single repository.
+func (o *scanOptions) scanTarget() (scanTarget, error) {
+	var target scanTarget
+	if o.target != "" {
+		var err error
+		if target, err = parseTarget(o.target); err != nil {
+			return scanTarget{}, err
+		}
+	}
+	if o.allRuns {
+		if target.Repo == "" || target.RunID > 0 {
+			return scanTarget{}, errors.New("-all-runs requires -target naming a single repository")
+		}
+		logger.Warnf("-all-runs scans every run of every workflow in %s/%s, ignoring the scan window for runs: "+
+			"listing costs one API request per 100 runs and each run scanned costs at least one more, "+
+			"and runs older than the log retention window (90 days by default) have no logs left to scan",
+			target.Owner, target.Repo)
+	}
+	return target, nil
+}
 
-	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: *tokenFlag})
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+// mirrorGlobals copies the keys consumed by package-level viper readers
+// (e.g. internal/action.Scan) into the global instance so those call
+// sites see the resolved values. This is the single point in the
+// binary that touches the global instance.
+func mirrorGlobals(v *viper.Viper, opts *scanOptions) {
+	gv := viper.GetViper()
+	gv.Set("max_retries", v.GetInt("max_retries"))
+	gv.Set("circuit_breaker_threshold", v.GetInt("circuit_breaker_threshold"))
+	gv.Set("max_concurrency", v.GetInt("max_concurrency"))
+	gv.Set("adaptive_concurrency", opts.adaptiveConcurrency)
+	gv.Set("pause_on_rate_limit", opts.pauseOnRateLimit)
+	gv.Set("best_effort", opts.bestEffort)
+	gv.Set("repo_stagger", opts.repoStagger.String())
+	gv.Set("flush_interval", opts.flushInterval.String())
+	gv.Set("flush_size", opts.flushSize)
+	gv.Set("operation_timeout", v.GetString("operation_timeout"))
+	gv.Set("workflow_fetch_budget", v.GetString("workflow_fetch_budget"))
+	gv.Set("run_scan_budget", v.GetString("run_scan_budget"))
+	gv.Set("repo_enum_budget", v.GetString("repo_enum_budget"))
+	gv.Set("repo_timeout", opts.repoTimeout.String())
+	gv.Set("scan_yaml", opts.scanYAML)
+	gv.Set("scan_logs", opts.scanLogs)
+	gv.Set("scan_history", opts.scanHistory)
+	gv.Set("modified_only", opts.modifiedOnly)
+	gv.Set("scan_summaries", opts.scanSummaries)
+	gv.Set("scan_commit_messages", opts.scanCommitMessages)
+	gv.Set("scan_pr_bodies", opts.scanPRBodies)
+	gv.Set("explain", opts.explain)
+	gv.Set("scan_attempts", opts.scanAttempts)
+	gv.Set("correlate_secrets", opts.correlateSecrets)
+	gv.Set("scan_actions", opts.scanActions)
+	gv.Set("scan_actions_depth", opts.scanActionsDepth)
+	gv.Set("resolve_tags", opts.resolveTags)
+	gv.Set("search_query_template", opts.searchQueryTemplate)
+	gv.Set("org_workflow", opts.orgWorkflow)
+	gv.Set("conclusions", splitList(opts.conclusions))
+	gv.Set("latest_only", opts.latestOnly)
+	gv.Set("all_runs", opts.allRuns)
+	gv.Set("collapse_runs", opts.collapseRuns)
+}
+
+// newGitHubClients returns the go-github client and the shared HTTP
+// client the scan calls GitHub with, both trusting tlsConfig's roots
+// and sending -api-version.
+func newGitHubClients(ctx context.Context, opts *scanOptions, tlsConfig *tls.Config) (*github.Client, *httpclient.Client) {
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: opts.token})
+	oauthCtx := ctx
+	if tlsConfig != nil {
+		oauthCtx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: httpclient.NewTransport(tlsConfig)})
+	}
+	tc := oauth2.NewClient(oauthCtx, ts)
+	if opts.apiVersion != "" {
+		tc.Transport = &httpclient.APIVersionTransport{Version: opts.apiVersion, Base: tc.Transport}
+	}
+	// budget is shared by both clients because they spend the same
+	// token's quota; -adaptive-concurrency paces the scan by it and
+	// -pause-on-rate-limit waits for its reset.
+	var budget *httpclient.RateBudget
+	// Code search and core calls are throttled separately, so a
+	// throttled search holds back only the searches behind it.
+	tc.Transport = &httpclient.CategoryTransport{Limiter: new(httpclient.CategoryLimiter), Base: tc.Transport}
+	if opts.adaptiveConcurrency || opts.pauseOnRateLimit {
+		budget = new(httpclient.RateBudget)
+		tc.Transport = &httpclient.BudgetTransport{Budget: budget, Base: tc.Transport}
+	}
 
 	// Single shared HTTP client. Singleflight + ETag caching only
 	// dedupe correctly when the same instance is reused across all
 	// callers, so we construct exactly one and plumb it through
 	// ghscan.Request.
-	hc := httpclient.New()
+	hc := httpclient.New(httpclient.WithAPIVersion(opts.apiVersion), httpclient.WithRateBudget(budget), httpclient.WithTLSConfig(tlsConfig))
+	return github.NewClient(tc), hc
+}
 
+// listRepos returns the repositories a GitHub scan of target or
+// -enterprise covers, after the -repo-language, -repo-topic,
+// -visibility, and -max-repos filters.
+func listRepos(ctx context.Context, client *github.Client, opts *scanOptions, target scanTarget) ([]*github.Repository, error) {
 	var repos []*github.Repository
 	switch {
-	case strings.Contains(*targetFlag, "/"):
-		parts := strings.Split(*targetFlag, "/")
-		if len(parts) != 2 {
-			logger.Fatalf("Invalid repository format. Expected owner/repository, got: %s", *targetFlag)
-		}
-		owner, repoName := parts[0], parts[1]
-		repo, _, err := client.Repositories.Get(ctx, owner, repoName)
+	case opts.enterprise != "":
+		orgs, err := listEnterpriseOrgs(ctx, client, opts.enterprise)
 		if err != nil {
-			logger.Fatalf("Error retrieving repository: %v", err)
-		}
-		repos = append(repos, repo)
-	default:
-		org := *targetFlag
-		opt := &github.RepositoryListByOrgOptions{
-			ListOptions: github.ListOptions{PerPage: 100},
+			return nil, fmt.Errorf("enterprise preflight failed: %w", err)
 		}
-		for {
-			orgRepos, resp, err := client.Repositories.ListByOrg(ctx, org, opt)
+		logger.Infof("Found %d organizations in enterprise %s", len(orgs), opts.enterprise)
+		for i, org := range orgs {
+			orgRepos, err := listOrgRepos(ctx, client, org)
 			if err != nil {
-				logger.Fatalf("Error listing repos for org %s: %v", org, err)
+				return nil, fmt.Errorf("listing repos for org %s: %w", org, err)
 			}
+			logger.Infof("Listed %d repositories in org %s (%d/%d)", len(orgRepos), org, i+1, len(orgs))
 			repos = append(repos, orgRepos...)
-			if resp.NextPage == 0 {
-				break
-			}
-			opt.Page = resp.NextPage
 		}
+		// Every repository costs at least a code search plus one call
+		// per workflow and run, so an enterprise-wide scan can run for
+		// hours against the shared per-token rate limit.
+		if rl, _, err := client.RateLimit.Get(ctx); err == nil && rl.GetCore() != nil {
+			logger.Infof("Scanning %d repositories across %d organizations with %d of %d core API requests remaining",
+				len(repos), len(orgs), rl.GetCore().Remaining, rl.GetCore().Limit)
+		}
+	case target.Repo != "":
+		// A single named repository is never filtered.
+		repo, _, err := client.Repositories.Get(ctx, target.Owner, target.Repo)
+		if err != nil {
+			return nil, fmt.Errorf("retrieving repository: %w", err)
+		}
+		return []*github.Repository{repo}, nil
+	default:
+		orgRepos, err := listOrgRepos(ctx, client, target.Owner)
+		if err != nil {
+			return nil, fmt.Errorf("listing repos for org %s: %w", target.Owner, err)
+		}
+		repos = orgRepos
 	}
 
-	logger.Infof("Found %d repositories to scan", len(repos))
+	// Language and topic filters scope organization and enterprise
+	// listings.
+	if filter := newRepoFilter(splitList(opts.repoLanguage), splitList(opts.repoTopic)); !filter.empty() {
+		filtered, err := filterRepos(ctx, client, repos, filter)
+		if err != nil {
+			return nil, fmt.Errorf("filtering repositories: %w", err)
+		}
+		logger.Infof("%d of %d repositories passed the -repo-language/-repo-topic filter", len(filtered), len(repos))
+		repos = filtered
+	}
+	kept, public, private := filterVisibility(repos, opts.visibility)
+	logger.Infof("Listed %d public and %d private repositories", public, private)
+	if opts.visibility != visibilityAll {
+		logger.Infof("Scanning the %d %s repositories (-visibility %s)", len(kept), opts.visibility, opts.visibility)
+	}
+	repos = kept
 
-	startTime, err := time.Parse(time.RFC3339, *startTimeFlag)
-	if err != nil {
-		logger.Fatalf("Error parsing start time: %v", err)
+	if limited := limitRepos(repos, opts.maxRepos); len(limited) < len(repos) {
+		logger.Infof("Limiting scan to the first %d of %d repositories", len(limited), len(repos))
+		repos = limited
 	}
-	endTime, err := time.Parse(time.RFC3339, *endTimeFlag)
+	return repos, nil
+}
+
+// runWindow returns a scan window around the creation of the run
+// target names; the request's RunID then keeps only that run.
+func runWindow(ctx context.Context, client *github.Client, target scanTarget) (time.Time, time.Time, error) {
+	run, _, err := client.Actions.GetWorkflowRunByID(ctx, target.Owner, target.Repo, target.RunID)
 	if err != nil {
-		logger.Fatalf("Error parsing end time: %v", err)
+		return time.Time{}, time.Time{}, fmt.Errorf("retrieving run %d: %w", target.RunID, err)
 	}
+	created := run.GetCreatedAt().Time
+	logger.Infof("Scanning only run %d of %s (%s, created %s); -start and -end are ignored",
+		target.RunID, run.GetPath(), run.GetStatus(), created.Format(time.RFC3339))
+	return created.Add(-time.Minute), created.Add(time.Minute), nil
+}
 
-	cache := file.LoadCache(ctx, logger, *cacheFileFlag, *cleanCacheFlag)
-	cachedResults := make(map[string]bool)
-	for _, result := range cache.Results {
-		key := fmt.Sprintf("%s|%s", result.Repository, result.WorkflowFileName)
-		cachedResults[key] = true
+// openCache loads the findings cache and opens the appender that
+// flushes each repository's findings as it completes; writeOutputs
+// consolidates them into the canonical outputs. With -no-cache the
+// scan starts empty, findings stay in memory until the requested
+// outputs are written, and there is no appender. Failing to open the
+// appender only costs crash resilience, so the scan proceeds without
+// it.
+func openCache(ctx context.Context, opts *scanOptions) (ghscan.Cache, *file.Appender) {
+	if opts.noCache {
+		return ghscan.Cache{}, nil
 	}
-
-	req := ghscan.NewRequest(ghscan.RequestConfig{
-		Cache:         cache,
-		CacheFile:     *cacheFileFlag,
-		CachedResults: cachedResults,
-		Client:        client,
-		HTTPClient:    hc,
-		Corpus:        corpus,
-		EndTime:       endTime,
-		IOC:           findIOC,
-		StartTime:     startTime,
-		Token:         *tokenFlag,
+	cache := file.LoadCache(ctx, logger, opts.cacheFile, opts.cleanCache)
+	appender, err := file.NewAppender(ctx, logger, file.AppenderConfig{
+		CacheFile: opts.cacheFile,
+		CSVFile:   opts.csvOutput,
+		Seed:      cache.Results,
+		Clean:     opts.cleanCache,
 	})
+	if err != nil {
+		logger.Warnf("Incremental output disabled: %v", err)
+		return cache, nil
+	}
+	return cache, appender
+}
 
-	scanErr := action.Scan(ctx, logger, req, repos)
-	if scanErr != nil {
-		logger.Errorf("Failed to scan Workflows in repos: %v", scanErr)
+// cachedKeys returns the repository|workflow keys of the findings in
+// cache.
+func cachedKeys(cache ghscan.Cache) map[string]bool {
+	keys := make(map[string]bool)
+	for _, result := range cache.Results {
+		keys[fmt.Sprintf("%s|%s", result.Repository, result.WorkflowFileName)] = true
 	}
+	return keys
+}
 
-	cr := ghscan.Cache{Results: req.Cache.Results}
-	writeErr := file.WriteResults(ctx, logger, cr, *cacheFileFlag, *jsonOutputFlag, *csvOutputFlag)
-	if writeErr != nil {
-		logger.Errorf("Failed to write outputs: %v", writeErr)
+// scan runs req against the source opts names: the runs in runsFile,
+// a GitLab project, a logs directory, or repos.
+func scan(ctx context.Context, req *ghscan.Request, opts *scanOptions, runsFile io.Reader, repos []*github.Repository, tlsConfig *tls.Config) error {
+	switch {
+	case runsFile != nil:
+		return action.ScanRunRefs(ctx, logger, req, runsFile)
+	case opts.gitlabProject != "":
+		src := &workflow.GitLabSource{
+			Logger:  logger,
+			HTTP:    &http.Client{Transport: httpclient.NewTransport(tlsConfig)},
+			BaseURL: opts.gitlabURL,
+			Project: opts.gitlabProject,
+			Token:   opts.gitlabToken,
+		}
+		return action.ScanSource(ctx, logger, req, src, opts.gitlabProject)
+	case opts.logsDir != "":
+		src := &workflow.DirSource{
+			Dir:        opts.logsDir,
+			Extensions: splitList(opts.logExtensions),
+			Recursive:  opts.logsRecursive,
+		}
+		return action.ScanSource(ctx, logger, req, src, opts.logsDir)
+	default:
+		return action.Scan(ctx, logger, req, repos)
 	}
-	logger.Info("Processing complete")
+}
 
-	exitCode := resolveExitCode(scanErr, writeErr, len(req.Cache.Results))
-	if exitCode != exitClean {
-		// Release deferred cancel + signal handlers before os.Exit
-		// short-circuits the runtime; otherwise the timer goroutine
-		// outlives main.
-		cancel()
-		stop()
-		os.Exit(exitCode) //nolint:gocritic // cancel + stop are invoked above.
+// writeOutputs writes all, every finding, to the cache and cr, the
+// findings that passed -min-confidence, and its summary to the
+// requested outputs. It returns every write failure joined.
+func writeOutputs(ctx context.Context, opts *scanOptions, all, cr ghscan.Cache, summary ghscan.Summary) error {
+	var writeErr error
+	if !opts.noCache {
+		writeErr = file.WriteResults(ctx, logger, all, opts.cacheFile, "", "")
+	}
+	switch {
+	case opts.summaryOnly:
+		// -summary-only rejected every detailed output at startup.
+	case opts.groupBySeverity:
+		writeErr = errors.Join(writeErr, file.WriteSeverityReport(ctx, logger, cr.Results, opts.jsonOutput, opts.csvOutput))
+	case opts.jsonNested:
+		writeErr = errors.Join(writeErr,
+			file.WriteResults(ctx, logger, cr, "", "", opts.csvOutput),
+			file.WriteNestedReport(ctx, logger, cr, opts.jsonOutput),
+		)
+	default:
+		writeErr = errors.Join(writeErr, file.WriteResults(ctx, logger, cr, "", opts.jsonOutput, opts.csvOutput))
+	}
+	if opts.perRepoOutput {
+		writeErr = errors.Join(writeErr, file.WritePerRepoResults(ctx, logger, cr))
+	}
+	switch {
+	case opts.summaryOnly:
+		writeErr = errors.Join(writeErr, file.WriteSummaryReport(ctx, logger, summary.Report(), opts.summaryOutput))
+	case opts.summaryOutput != "":
+		writeErr = errors.Join(writeErr, file.WriteSummary(ctx, logger, summary, opts.summaryOutput))
+	}
+	if opts.markdownOutput != "" {
+		writeErr = errors.Join(writeErr, file.WriteMarkdown(ctx, logger, cr, opts.markdownOutput, opts.groupBySeverity))
+	}
+	if opts.stixOutput != "" {
+		writeErr = errors.Join(writeErr, file.WriteSTIX(ctx, logger, cr, opts.stixOutput))
+	}
+	if opts.sqliteOutput != "" {
+		writeErr = errors.Join(writeErr, file.WriteSQLite(ctx, logger, cr.Results, opts.sqliteOutput, time.Now()))
 	}
+	return writeErr
 }
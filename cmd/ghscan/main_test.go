@@ -3,13 +3,23 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
 	"github.com/spf13/viper"
 )
 
@@ -18,9 +28,14 @@ import (
 //	0 — clean run (no errors, zero findings)
 //	2 — at least one IOC finding (no errors)
 //	3 — any pipeline error, regardless of findings count
+//	4 — a scan whose every failure is a rate limit
+//	5 — a scan whose every failure is a rejected or unprivileged token
 func TestResolveExitCode(t *testing.T) {
 	t.Parallel()
 
+	rateLimited := &action.ScanError{Category: action.ErrorRateLimit, Op: "downloading logs", Err: errors.New("rate limited")}
+	noAccess := &action.ScanError{Category: action.ErrorPermission, Op: "listing runs", Err: errors.New("forbidden")}
+
 	cases := []struct {
 		name     string
 		scanErr  error
@@ -35,6 +50,10 @@ func TestResolveExitCode(t *testing.T) {
 		{name: "write error supersedes findings", writeErr: errors.New("disk full"), findings: 5, want: exitScanFailed},
 		{name: "both errors", scanErr: errors.New("a"), writeErr: errors.New("b"), findings: 0, want: exitScanFailed},
 		{name: "scan error alone, zero findings", scanErr: errors.New("boom"), want: exitScanFailed},
+		{name: "rate limited", scanErr: fmt.Errorf("scan: %w", rateLimited), findings: 5, want: exitRateLimited},
+		{name: "no access", scanErr: errors.Join(noAccess, noAccess), want: exitNoAccess},
+		{name: "mixed categories", scanErr: errors.Join(rateLimited, noAccess), want: exitScanFailed},
+		{name: "write error supersedes category", scanErr: rateLimited, writeErr: errors.New("disk full"), want: exitScanFailed},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -72,10 +91,25 @@ func TestSetDefaults_PopulatesMissingKeys(t *testing.T) {
 		{name: "operation_timeout falls back to 30s", key: "operation_timeout", wantStr: "30s"},
 		{name: "ioc name falls back to tj-actions", key: "ioc.name", wantStr: "tj-actions/changed-files"},
 		{name: "max_retries falls back to 3", key: "max_retries", wantInt: 3},
+		{name: "circuit_breaker_threshold falls back to 5", key: "circuit_breaker_threshold", wantInt: 5},
 		{name: "max_concurrency falls back to 32 to keep errgroup bounded", key: "max_concurrency", wantInt: 32},
+		{name: "max_repos falls back to 0 (no limit)", key: "max_repos", wantInt: 0},
+		{name: "flush_size falls back to 0 (disabled)", key: "flush_size", wantInt: 0},
+		{name: "max_decode_depth falls back to 3", key: "max_decode_depth", wantInt: 3},
+		{name: "context_lines defaults to off", key: "context_lines", wantInt: 0},
+		{name: "min_confidence defaults to reporting everything", key: "min_confidence", wantInt: 0},
+		{name: "fallback_concurrency falls back to 32", key: "fallback_concurrency", wantInt: 32},
 		{name: "workflow_fetch_budget falls back to 60s", key: "workflow_fetch_budget", wantStr: "60s"},
 		{name: "run_scan_budget falls back to 30s", key: "run_scan_budget", wantStr: "30s"},
 		{name: "repo_enum_budget falls back to 150s", key: "repo_enum_budget", wantStr: "150s"},
+		{name: "repo_stagger falls back to 0s (off)", key: "repo_stagger", wantStr: "0s"},
+		{name: "repo_timeout falls back to 0s (scaled)", key: "repo_timeout", wantStr: "0s"},
+		{name: "flush_interval falls back to 1m0s", key: "flush_interval", wantStr: "1m0s"},
+		{name: "log_cache_ttl falls back to 0s (keep forever)", key: "log_cache_ttl", wantStr: "0s"},
+		{name: "last falls back to 0s (use start_time)", key: "last", wantStr: "0s"},
+		{name: "search_query_template falls back to the workflow search", key: "search_query_template", wantStr: "repo:{owner}/{repo} path:.github/workflows language:YAML"},
+		{name: "gitlab_url falls back to gitlab.com", key: "gitlab_url", wantStr: "https://gitlab.com"},
+		{name: "output_mode falls back to owner-only 0600", key: "output_mode", wantStr: "0600"},
 	}
 
 	for _, tc := range cases {
@@ -150,6 +184,132 @@ func TestSetDefaults_ScanFlagsDefaultTrue(t *testing.T) {
 	if !v.GetBool("scan_logs") {
 		t.Fatal("scan_logs default=false, want true")
 	}
+	if v.GetBool("scan_history") {
+		t.Fatal("scan_history default=true, want false (opt-in, one API call per commit)")
+	}
+	if v.GetBool("no_cache") {
+		t.Fatal("no_cache default=true, want false (the cache makes reruns resumable)")
+	}
+	if v.GetBool("modified_only") {
+		t.Fatal("modified_only default=true, want false (narrows the scan to changed workflows)")
+	}
+	if v.GetBool("scan_summaries") {
+		t.Fatal("scan_summaries default=true, want false (opt-in, one API call per job)")
+	}
+	if !v.GetBool("redact_token") {
+		t.Fatal("redact_token default=false, want true (tokens are scrubbed unless disabled)")
+	}
+	if !v.GetBool("logs_recursive") {
+		t.Fatal("logs_recursive default=false, want true (extracted logs keep step files in job folders)")
+	}
+	if v.GetBool("scan_commit_messages") {
+		t.Fatal("scan_commit_messages default=true, want false (opt-in, a new finding source)")
+	}
+	if v.GetBool("scan_pr_bodies") {
+		t.Fatal("scan_pr_bodies default=true, want false (opt-in, one API call per pull request)")
+	}
+	if v.GetBool("scan_attempts") {
+		t.Fatal("scan_attempts default=true, want false (opt-in, one log download per attempt)")
+	}
+	if v.GetBool("explain") {
+		t.Fatal("explain default=true, want false (opt-in, adds text to every finding)")
+	}
+	if v.GetBool("allow_binary_decoded") {
+		t.Fatal("allow_binary_decoded default=true, want false (UTF-8 only)")
+	}
+	if v.GetBool("adaptive_concurrency") {
+		t.Fatal("adaptive_concurrency default=true, want false (opt-in)")
+	}
+	if v.GetBool("pause_on_rate_limit") {
+		t.Fatal("pause_on_rate_limit default=true, want false (opt-in, can stall a scan for up to an hour)")
+	}
+	if v.GetBool("best_effort") {
+		t.Fatal("best_effort default=true, want false (strict)")
+	}
+	if v.GetBool("correlate_secrets") {
+		t.Fatal("correlate_secrets default=true, want false (opt-in, one API call per commit with findings)")
+	}
+	if v.GetBool("scan_actions") {
+		t.Fatal("scan_actions default=true, want false (opt-in, fetches every referenced action)")
+	}
+	if v.GetBool("resolve_tags") {
+		t.Fatal("resolve_tags default=true, want false (opt-in, resolves every referenced tag)")
+	}
+	if v.GetBool("group_by_severity") {
+		t.Fatal("group_by_severity default=true, want false (changes the JSON output shape)")
+	}
+	if v.GetBool("json_nested") {
+		t.Fatal("json_nested default=true, want false (changes the JSON output shape)")
+	}
+	if v.GetBool("latest_only") {
+		t.Fatal("latest_only default=true, want false (would skip historical runs)")
+	}
+	if v.GetBool("all_runs") {
+		t.Fatal("all_runs default=true, want false (lists every run ever, one API request per 100)")
+	}
+	if v.GetBool("collapse_runs") {
+		t.Fatal("collapse_runs default=true, want false (folds rows other tools may count per run)")
+	}
+	if v.GetBool("github_annotations") {
+		t.Fatal("github_annotations default=true, want false (only auto-enabled under GITHUB_ACTIONS)")
+	}
+	if v.GetBool("upload_sarif") {
+		t.Fatal("upload_sarif default=true, want false (opt-in, writes to each repository's code scanning)")
+	}
+	if v.GetBool("upload_sarif_empty") {
+		t.Fatal("upload_sarif_empty default=true, want false (uploads to repositories without findings only when asked)")
+	}
+	if v.GetBool("keep_logs") || v.GetBool("keep_all_logs") {
+		t.Fatal("keep_logs/keep_all_logs default=true, want false (opt-in, disk heavy)")
+	}
+	if v.GetBool("detect_cache_poisoning") {
+		t.Fatal("detect_cache_poisoning default=true, want false (opt-in, heuristic)")
+	}
+	if v.GetBool("detect_suspicious_git") {
+		t.Fatal("detect_suspicious_git default=true, want false (opt-in, like detect_egress)")
+	}
+	if got := v.GetStringSlice("repo_languages"); len(got) != 0 {
+		t.Fatalf("repo_languages default=%q, want empty (scan every language)", got)
+	}
+	if got := v.GetStringSlice("repo_topics"); len(got) != 0 {
+		t.Fatalf("repo_topics default=%q, want empty (scan every topic)", got)
+	}
+	if got := v.GetString("visibility"); got != "all" {
+		t.Fatalf("visibility default=%q, want all (scan public and private repositories)", got)
+	}
+	if got := v.GetString("runs_file"); got != "" {
+		t.Fatalf("runs_file default=%q, want empty (scan -target)", got)
+	}
+	if got := v.GetString("logs_dir"); got != "" {
+		t.Fatalf("logs_dir default=%q, want empty (scan -target)", got)
+	}
+	if got := v.GetStringSlice("log_extensions"); !slices.Equal(got, []string{".txt", ".log", ".zip"}) {
+		t.Fatalf("log_extensions default=%q, want .txt, .log, and .zip", got)
+	}
+	if got := v.GetStringSlice("git_remote_allowlist"); !slices.Contains(got, "github.com") {
+		t.Fatalf("git_remote_allowlist default=%q, want it to include github.com", got)
+	}
+	if v.GetBool("summary_only") {
+		t.Fatal("summary_only default=true, want false (write the detailed outputs)")
+	}
+	if v.GetBool("detect_base32") || v.GetBool("detect_base85") {
+		t.Fatal("detect_base32/detect_base85 default=true, want false (opt-in, base32 matches uppercase IDs)")
+	}
+	if v.GetBool("detect_mask_bypass") {
+		t.Fatal("detect_mask_bypass default=true, want false (opt-in, heuristic)")
+	}
+	if v.GetBool("detect_egress") {
+		t.Fatal("detect_egress default=true, want false (opt-in, noisy on build logs)")
+	}
+	if got := v.GetStringSlice("egress_allowlist"); !slices.Contains(got, "github.com") {
+		t.Fatalf("egress_allowlist default=%q, want it to include github.com", got)
+	}
+	if got := v.GetStringSlice("enable_detectors"); len(got) != 0 {
+		t.Fatalf("enable_detectors default=%q, want empty (run every detector)", got)
+	}
+	if got := v.GetStringSlice("disable_detectors"); len(got) != 0 {
+		t.Fatalf("disable_detectors default=%q, want empty", got)
+	}
 }
 
 // TestSetDefaults_IocFile asserts the ioc_file key exists and defaults
@@ -165,6 +325,9 @@ func TestSetDefaults_IocFile(t *testing.T) {
 	if !v.IsSet("ioc_file") {
 		t.Fatal("ioc_file should be registered as a viper key")
 	}
+	if got := v.GetString("ioc.content_file"); got != "" {
+		t.Fatalf("ioc.content_file default=%q, want empty", got)
+	}
 }
 
 // TestSetDefaults_GlobalTimeoutParsesAsDuration ties the default
@@ -183,6 +346,9 @@ func TestSetDefaults_GlobalTimeoutParsesAsDuration(t *testing.T) {
 		"workflow_fetch_budget",
 		"run_scan_budget",
 		"repo_enum_budget",
+		"repo_timeout",
+		"repo_stagger",
+		"flush_interval",
 	} {
 		s := v.GetString(key)
 		if s == "" {
@@ -262,6 +428,7 @@ func TestResolveGitHubToken_GhFailureWraps(t *testing.T) {
 		t.Fatalf("write fake gh: %v", err)
 	}
 	t.Setenv("PATH", dir)
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
 
 	v := viper.New()
 	v.Set("token", "")
@@ -287,6 +454,7 @@ func TestResolveGitHubToken_GhMissingWraps(t *testing.T) {
 	// Empty PATH guarantees gh cannot be found.
 	dir := t.TempDir()
 	t.Setenv("PATH", dir)
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
 
 	v := viper.New()
 	v.Set("token", "")
@@ -302,3 +470,398 @@ func TestResolveGitHubToken_GhMissingWraps(t *testing.T) {
 		t.Fatalf("error %q does not mention gh auth token", err.Error())
 	}
 }
+
+// TestResolveGitHubToken_AsksGhForGitHubCom asserts that gh is asked
+// for its github.com token rather than the token of its default host,
+// which may be a GitHub Enterprise Server.
+func TestResolveGitHubToken_AsksGhForGitHubCom(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gh shim is a unix shell script")
+	}
+	dir := t.TempDir()
+	ghPath := filepath.Join(dir, "gh")
+	script := "#!/bin/sh\n[ \"$*\" = 'auth token --hostname github.com' ] || exit 1\necho ghp_dotcom\n"
+	if err := os.WriteFile(ghPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake gh: %v", err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+
+	v := viper.New()
+	got, err := resolveGitHubToken(context.Background(), v)
+	if err != nil {
+		t.Fatalf("resolveGitHubToken: %v", err)
+	}
+	if got != "ghp_dotcom" {
+		t.Fatalf("token=%q, want ghp_dotcom", got)
+	}
+}
+
+// TestResolveGitHubToken_HostsFile covers the fallback to gh's
+// hosts.yml when gh cannot be run: only a github.com token is used.
+func TestResolveGitHubToken_HostsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "github.com token",
+			hosts: "ghe.example.com:\n    oauth_token: gho_enterprise\ngithub.com:\n    oauth_token: gho_dotcom\n    user: octocat\n",
+			want:  "gho_dotcom",
+		},
+		{
+			name:    "only another host",
+			hosts:   "ghe.example.com:\n    oauth_token: gho_enterprise\n",
+			wantErr: true,
+		},
+		{
+			name:    "token kept in the keyring",
+			hosts:   "github.com:\n    user: octocat\n    git_protocol: https\n",
+			wantErr: true,
+		},
+		{
+			name:    "no hosts file",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PATH", t.TempDir())
+			cfg := t.TempDir()
+			t.Setenv("GH_CONFIG_DIR", cfg)
+			if tt.hosts != "" {
+				if err := os.WriteFile(filepath.Join(cfg, "hosts.yml"), []byte(tt.hosts), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := resolveGitHubToken(context.Background(), viper.New())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got token=%q", got)
+				}
+				if strings.Contains(err.Error(), "gho_") {
+					t.Fatalf("error %q leaks a token", err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveGitHubToken: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("token=%q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLimitRepos covers the -max-repos cap: non-positive values are a
+// no-op, and positive values keep the first N repositories in listing
+// order.
+func TestLimitRepos(t *testing.T) {
+	t.Parallel()
+
+	repos := []*github.Repository{
+		{Name: new("a")},
+		{Name: new("b")},
+		{Name: new("c")},
+	}
+	cases := []struct {
+		name     string
+		maxRepos int
+		want     []string
+	}{
+		{name: "zero means no limit", maxRepos: 0, want: []string{"a", "b", "c"}},
+		{name: "negative means no limit", maxRepos: -1, want: []string{"a", "b", "c"}},
+		{name: "cap below length", maxRepos: 2, want: []string{"a", "b"}},
+		{name: "cap at length", maxRepos: 3, want: []string{"a", "b", "c"}},
+		{name: "cap above length", maxRepos: 10, want: []string{"a", "b", "c"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := limitRepos(repos, tc.maxRepos)
+			if len(got) != len(tc.want) {
+				t.Fatalf("len=%d, want %d", len(got), len(tc.want))
+			}
+			for i, r := range got {
+				if r.GetName() != tc.want[i] {
+					t.Fatalf("got[%d]=%q, want %q", i, r.GetName(), tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: []string{}},
+		{in: "a", want: []string{"a"}},
+		{in: " a , ,b,", want: []string{"a", "b"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			if got := splitList(tc.in); strings.Join(got, "|") != strings.Join(tc.want, "|") || len(got) != len(tc.want) {
+				t.Fatalf("splitList(%q)=%q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestParseTarget pins the accepted -target forms, including pasted
+// github.com URLs, and that malformed values are rejected before any
+// API call.
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in      string
+		want    scanTarget
+		wantErr string
+	}{
+		{in: "octocat", want: scanTarget{Owner: "octocat"}},
+		{in: "octocat/Hello-World", want: scanTarget{Owner: "octocat", Repo: "Hello-World"}},
+		{in: " octocat/hello.world_x ", want: scanTarget{Owner: "octocat", Repo: "hello.world_x"}},
+		{in: "octocat/Hello-World/", want: scanTarget{Owner: "octocat", Repo: "Hello-World"}},
+		{in: "https://github.com/octocat/Hello-World/", want: scanTarget{Owner: "octocat", Repo: "Hello-World"}},
+		{in: "https://github.com/octocat/Hello-World.git", want: scanTarget{Owner: "octocat", Repo: "Hello-World"}},
+		{in: "https://www.github.com/octocat/Hello-World/tree/main?tab=readme", want: scanTarget{Owner: "octocat", Repo: "Hello-World"}},
+		{in: "github.com/chainguard-dev", want: scanTarget{Owner: "chainguard-dev"}},
+		{in: "https://github.com/chainguard-dev/", want: scanTarget{Owner: "chainguard-dev"}},
+		{in: "https://github.com/orgs/chainguard-dev/repositories", want: scanTarget{Owner: "chainguard-dev"}},
+		{in: "https://github.com/o/r/actions/runs/123", want: scanTarget{Owner: "o", Repo: "r", RunID: 123}},
+		{in: "https://github.com/o/r/actions/runs/123/job/456#step:2:1", want: scanTarget{Owner: "o", Repo: "r", RunID: 123}},
+		{in: "", wantErr: "missing owner"},
+		{in: "/repo", wantErr: "missing owner"},
+		{in: "https://github.com/", wantErr: "missing owner"},
+		{in: "owner/", wantErr: "missing repository"},
+		{in: "owner/repo/extra", wantErr: "extra path segments"},
+		{in: "https://gitlab.com/owner/repo", wantErr: "not a github.com URL"},
+		{in: "https://github.com/o/r/actions/runs", wantErr: "missing run ID"},
+		{in: "https://github.com/o/r/actions/runs/abc", wantErr: "not a run ID"},
+		{in: "-owner/repo", wantErr: "not a valid GitHub owner name"},
+		{in: "own er", wantErr: "not a valid GitHub owner name"},
+		{in: "owner/..", wantErr: "not a valid repository name"},
+		{in: "owner/re po", wantErr: "not a valid repository name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseTarget(tc.in)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) || !strings.Contains(err.Error(), "invalid target") {
+					t.Fatalf("parseTarget(%q) error=%v, want %q", tc.in, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("parseTarget(%q)=(%+v, %v), want %+v", tc.in, got, err, tc.want)
+			}
+		})
+	}
+}
+
+// TestMergeContent pins that -ioc-content entries come first and that
+// entries repeated in the content file are dropped.
+func TestMergeContent(t *testing.T) {
+	t.Parallel()
+
+	got := mergeContent([]string{"a", "b"}, []string{"b", "c", "a", "d"})
+	want := []string{"a", "b", "c", "d"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("mergeContent = %q, want %q", got, want)
+	}
+	if got := mergeContent([]string{}, []string{}); len(got) != 0 {
+		t.Fatalf("mergeContent of empty = %q, want empty", got)
+	}
+}
+
+// TestFormatSummary pins the end-of-run summary lines: one per
+// repository, with repositories and IOC names in sorted order.
+func TestFormatSummary(t *testing.T) {
+	t.Parallel()
+
+	got := formatSummary(ghscan.Summary{
+		"o/b": {"z/w": 1, "x/y": 3},
+		"o/a": {"x/y": 2},
+	})
+	want := []string{
+		"o/a: x/y=2",
+		"o/b: x/y=3, z/w=1",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("formatSummary = %q, want %q", got, want)
+	}
+}
+
+// TestFormatNewFindings pins the regression lines printed for findings
+// absent from the baseline: the decoded data is preferred over the raw
+// line, and the run URL over the workflow URL.
+func TestFormatNewFindings(t *testing.T) {
+	t.Parallel()
+
+	got := formatNewFindings([]ghscan.Result{
+		{
+			Repository:       "o/a",
+			WorkflowFileName: "ci.yml",
+			Severity:         "high",
+			KeyTypes:         "base64",
+			DecodedData:      "secret",
+			LineData:         "c2VjcmV0",
+			WorkflowRunURL:   "https://github.com/o/a/actions/runs/1",
+			WorkflowURL:      "https://github.com/o/a/actions/workflows/ci.yml",
+		},
+		{
+			Repository:        "o/b",
+			WorkflowFileName:  "release.yml",
+			OffendingUsesLine: "uses: x/y@bad",
+		},
+	})
+	want := []string{
+		"NEW o/a ci.yml (high base64): secret https://github.com/o/a/actions/runs/1",
+		"NEW o/b release.yml: uses: x/y@bad",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("formatNewFindings = %q, want %q", got, want)
+	}
+}
+
+// TestParseMergeArgs covers the merge subcommand's argument contract:
+// at least one input cache and at least one output are required.
+func TestParseMergeArgs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		args      []string
+		wantFiles []string
+		wantErr   bool
+	}{
+		{name: "json output", args: []string{"-json", "all.json", "a.json", "b.json"}, wantFiles: []string{"a.json", "b.json"}},
+		{name: "csv output", args: []string{"-csv", "all.csv", "a.json"}, wantFiles: []string{"a.json"}},
+		{name: "no inputs", args: []string{"-json", "all.json"}, wantErr: true},
+		{name: "no outputs", args: []string{"a.json"}, wantErr: true},
+		{name: "unknown flag", args: []string{"-bogus", "a.json"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			opts, err := parseMergeArgs(tc.args, io.Discard)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseMergeArgs(%q) err=%v, wantErr=%v", tc.args, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if strings.Join(opts.cacheFiles, ",") != strings.Join(tc.wantFiles, ",") {
+				t.Fatalf("cacheFiles=%q, want %q", opts.cacheFiles, tc.wantFiles)
+			}
+		})
+	}
+}
+
+func TestParseAnalyzeArgs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name      string
+		args      []string
+		wantFiles []string
+		wantErr   bool
+	}{
+		{name: "content and json", args: []string{"-ioc-content", "x", "-json", "out.json", "a.json", "b.json"}, wantFiles: []string{"a.json", "b.json"}},
+		{name: "pattern and csv", args: []string{"-ioc-pattern", "ev[i]l", "-csv", "out.csv", "a.json"}, wantFiles: []string{"a.json"}},
+		{name: "content file only", args: []string{"-ioc-content-file", "digests.txt", "-json", "out.json", "a.json"}, wantFiles: []string{"a.json"}},
+		{name: "no ioc", args: []string{"-json", "out.json", "a.json"}, wantErr: true},
+		{name: "no inputs", args: []string{"-ioc-content", "x", "-json", "out.json"}, wantErr: true},
+		{name: "no outputs", args: []string{"-ioc-content", "x", "a.json"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			opts, err := parseAnalyzeArgs(tc.args, io.Discard)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseAnalyzeArgs(%q) err=%v, wantErr=%v", tc.args, err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if strings.Join(opts.cacheFiles, ",") != strings.Join(tc.wantFiles, ",") {
+				t.Fatalf("cacheFiles=%q, want %q", opts.cacheFiles, tc.wantFiles)
+			}
+		})
+	}
+}
+
+// TestParseScanArgs asserts the scan flags default to the config
+// values and that validate rejects the flag combinations a scan cannot
+// run with.
+func TestParseScanArgs(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "target", args: []string{"-target", "octo/demo"}},
+		{name: "logs dir", args: []string{"-logs-dir", "logs"}},
+		{name: "no source", args: nil, wantErr: "target must be provided"},
+		{name: "two sources", args: []string{"-target", "octo", "-enterprise", "acme"}, wantErr: "only one of -target or -enterprise"},
+		{name: "runs file and target", args: []string{"-runs-file", "-", "-target", "octo"}, wantErr: "-runs-file cannot be combined"},
+		{name: "sarif offline", args: []string{"-logs-dir", "logs", "-upload-sarif"}, wantErr: "-upload-sarif uploads to GitHub"},
+		{name: "empty sarif alone", args: []string{"-target", "octo", "-upload-sarif-empty"}, wantErr: "-upload-sarif-empty requires -upload-sarif"},
+		{name: "no scans", args: []string{"-target", "octo", "-scan-yaml=false", "-scan-logs=false"}, wantErr: "at least one of -scan-yaml or -scan-logs"},
+		{name: "actions without yaml", args: []string{"-target", "octo", "-scan-yaml=false", "-scan-actions"}, wantErr: "-scan-actions requires -scan-yaml"},
+		{name: "confidence", args: []string{"-target", "octo", "-min-confidence", "101"}, wantErr: "-min-confidence must be between 0 and 100"},
+		{name: "visibility", args: []string{"-target", "octo", "-visibility", "internal"}, wantErr: "-visibility must be"},
+		{name: "conclusions", args: []string{"-target", "octo", "-conclusions", "bogus"}, wantErr: "invalid -conclusions"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			v := viper.New()
+			setDefaults(v)
+			fs := flag.NewFlagSet("ghscan", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			opts, err := parseScanArgs(v, fs, tc.args)
+			if err != nil {
+				t.Fatalf("parseScanArgs(%q): %v", tc.args, err)
+			}
+			if opts.cacheFile != v.GetString("cache_file") || opts.maxRepos != v.GetInt("max_repos") {
+				t.Fatalf("parseScanArgs(%q) did not default to the config values: %+v", tc.args, opts)
+			}
+			err = opts.validate()
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("validate(%q) = %v, want nil", tc.args, err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("validate(%q) = %v, want error containing %q", tc.args, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestRunSelftest asserts the built-in fixture trips every built-in
+// detector, and that a missing detection is named in the error.
+func TestRunSelftest(t *testing.T) {
+	if err := runSelftest(clog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatalf("runSelftest: %v", err)
+	}
+
+	err := checkSelftest([]workflow.Finding{{Decoded: selftestPayload}})
+	if err == nil {
+		t.Fatal("checkSelftest passed without IOC and PEM findings")
+	}
+	if got, want := err.Error(), "self-test detections missing: ioc, pem"; got != want {
+		t.Fatalf("error=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/internal/file"
+)
+
+// mergeCommand is the subcommand that combines the caches written by
+// sharded scans into one report.
+const mergeCommand = "merge"
+
+// mergeOptions is the parsed form of the merge subcommand's arguments.
+type mergeOptions struct {
+	jsonOutput string
+	csvOutput  string
+	cacheFiles []string
+}
+
+// parseMergeArgs parses the merge subcommand's flags and positional
+// cache files. Usage errors are returned rather than fatal so the
+// parser is testable.
+func parseMergeArgs(args []string, stderr io.Writer) (mergeOptions, error) {
+	fs := flag.NewFlagSet(mergeCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: ghscan merge [-json out.json] [-csv out.csv] cache1.json cache2.json ...\n")
+		fs.PrintDefaults()
+	}
+
+	var opts mergeOptions
+	fs.StringVar(&opts.jsonOutput, "json", "", "Path to combined JSON output file")
+	fs.StringVar(&opts.csvOutput, "csv", "", "Path to combined CSV output file")
+	if err := fs.Parse(args); err != nil {
+		return mergeOptions{}, err
+	}
+	opts.cacheFiles = fs.Args()
+
+	if len(opts.cacheFiles) == 0 {
+		return mergeOptions{}, errors.New("at least one cache file must be provided")
+	}
+	if opts.jsonOutput == "" && opts.csvOutput == "" {
+		return mergeOptions{}, errors.New("at least one of -json or -csv must be provided")
+	}
+	return opts, nil
+}
+
+// runMerge implements `ghscan merge` and returns the process exit
+// code. Cache paths and outputs are resolved under results/, the same
+// as -cache, -json, and -csv for a scan.
+func runMerge(args []string) int {
+	opts, err := parseMergeArgs(args, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitClean
+		}
+		logger.Errorf("Invalid merge arguments: %v", err)
+		return exitScanFailed
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cache, err := file.MergeCaches(ctx, logger, opts.cacheFiles)
+	if err != nil {
+		logger.Errorf("Failed to merge caches: %v", err)
+		return exitScanFailed
+	}
+	cache.Results = action.DedupResults(cache.Results)
+	logger.Infof("Merged %d results from %d caches", len(cache.Results), len(opts.cacheFiles))
+
+	writeErr := file.WriteResults(ctx, logger, cache, "", opts.jsonOutput, opts.csvOutput)
+	if writeErr != nil {
+		logger.Errorf("Failed to write outputs: %v", writeErr)
+	}
+	return resolveExitCode(nil, writeErr, len(cache.Results))
+}
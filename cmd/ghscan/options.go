@@ -0,0 +1,381 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/spf13/viper"
+)
+
+// scanOptions is the parsed form of a scan's flags. Each field holds
+// the flag of the same name; list-valued flags stay comma-separated
+// until they are used.
+type scanOptions struct {
+	// What to scan.
+	target        string
+	enterprise    string
+	runsFile      string
+	gitlabProject string
+	gitlabURL     string
+	logsDir       string
+	logExtensions string
+	logsRecursive bool
+	selftest      bool
+
+	// Credentials.
+	token       string
+	gitlabToken string
+	redactToken bool
+	apiVersion  string
+	caCert      string
+
+	// Cache and outputs.
+	cacheFile         string
+	cleanCache        bool
+	noCache           bool
+	jsonOutput        string
+	csvOutput         string
+	summaryOutput     string
+	summaryOnly       bool
+	markdownOutput    string
+	sqliteOutput      string
+	stixOutput        string
+	format            string
+	outputDir         string
+	outputMode        string
+	keepLogs          bool
+	keepAllLogs       bool
+	uploadSARIF       bool
+	uploadSARIFEmpty  bool
+	githubAnnotations bool
+	baseline          string
+	minConfidence     int
+	jsonNested        bool
+	groupBySeverity   bool
+	perRepoOutput     bool
+
+	// Scan window and IOC.
+	startTime      string
+	endTime        string
+	last           time.Duration
+	iocName        string
+	iocContent     string
+	iocContentFile string
+	iocPattern     string
+	iocFile        string
+
+	// Repository and run selection.
+	orgWorkflow         string
+	repoLanguage        string
+	repoTopic           string
+	visibility          string
+	maxRepos            int
+	conclusions         string
+	allRuns             bool
+	latestOnly          bool
+	collapseRuns        bool
+	searchQueryTemplate string
+
+	// Scans.
+	scanYAML           bool
+	scanLogs           bool
+	scanHistory        bool
+	modifiedOnly       bool
+	scanActions        bool
+	scanActionsDepth   int
+	resolveTags        bool
+	scanAttempts       bool
+	scanCommitMessages bool
+	scanPRBodies       bool
+	scanSummaries      bool
+	correlateSecrets   bool
+	explain            bool
+
+	// Log detectors.
+	allowBinaryDecoded   bool
+	contextLines         int
+	enableDetectors      string
+	disableDetectors     string
+	jobFilter            string
+	logCacheDir          string
+	logCacheTTL          time.Duration
+	detectEgress         bool
+	egressAllow          string
+	detectCachePoisoning bool
+	detectSuspiciousGit  bool
+	gitRemoteAllow       string
+	detectBase32         bool
+	detectBase85         bool
+	detectMaskBypass     bool
+
+	// Pacing.
+	pauseOnRateLimit    bool
+	adaptiveConcurrency bool
+	bestEffort          bool
+	repoTimeout         time.Duration
+	repoStagger         time.Duration
+	flushInterval       time.Duration
+	flushSize           int
+}
+
+// parseScanArgs defines a scan's flags on fs, defaulting to the values
+// in v, and parses args with them.
+func parseScanArgs(v *viper.Viper, fs *flag.FlagSet, args []string) (scanOptions, error) {
+	var o scanOptions
+	fs.StringVar(&o.target, "target", v.GetString("target"), "Organization name or owner/repository (e.g. octocat/Hello-World)")
+	fs.StringVar(&o.enterprise, "enterprise", v.GetString("enterprise"), "Enterprise slug; scan every repository of every organization in it (requires an enterprise owner token)")
+	fs.StringVar(&o.runsFile, "runs-file", v.GetString("runs_file"), "NDJSON file of {\"owner\", \"repo\", \"run_id\"} objects; scan exactly those runs' logs instead of a target (- reads stdin)")
+	fs.StringVar(&o.gitlabProject, "gitlab-project", v.GetString("gitlab_project"), "GitLab project path (e.g. group/project); scan its CI pipeline logs instead of a GitHub target")
+	fs.StringVar(&o.gitlabURL, "gitlab-url", v.GetString("gitlab_url"), "Base URL of the GitLab instance used with -gitlab-project")
+	fs.StringVar(&o.logsDir, "logs-dir", v.GetString("logs_dir"), "Directory of downloaded or extracted run logs; scan its files offline instead of a target")
+	fs.StringVar(&o.logExtensions, "log-extensions", strings.Join(v.GetStringSlice("log_extensions"), ","), "Comma-separated file extensions -logs-dir scans; .zip files are read as run log archives")
+	fs.BoolVar(&o.logsRecursive, "logs-recursive", v.GetBool("logs_recursive"), "Descend into the subdirectories of -logs-dir")
+	fs.BoolVar(&o.redactToken, "redact-token", v.GetBool("redact_token"), "Scrub the GitHub and GitLab tokens from log lines and every output file")
+	fs.StringVar(&o.gitlabToken, "gitlab-token", v.GetString("gitlab_token"), "GitLab access token with the read_api scope (default $GITLAB_TOKEN)")
+	fs.StringVar(&o.token, "token", v.GetString("token"), "GitHub Personal Access Token (default $GITHUB_TOKEN, else the gh CLI's github.com login)")
+	fs.StringVar(&o.cacheFile, "cache", v.GetString("cache_file"), "Path to JSON cache file")
+	fs.BoolVar(&o.cleanCache, "clean-cache", v.GetBool("clean_cache"), "Reset the findings cache")
+	fs.BoolVar(&o.noCache, "no-cache", v.GetBool("no_cache"), "Keep findings in memory only: read and write no cache, journal, or log cache, and write just the requested outputs once the scan ends")
+	fs.StringVar(&o.jsonOutput, "json", v.GetString("json_output"), "Path to final JSON output file")
+	fs.StringVar(&o.csvOutput, "csv", v.GetString("csv_output"), "Path to final CSV output file")
+	fs.StringVar(&o.summaryOutput, "summary", v.GetString("summary_output"), "Path to per-repository IOC summary JSON file")
+	fs.BoolVar(&o.summaryOnly, "summary-only", v.GetBool("summary_only"), "Write only per-repository IOC counts and totals to -summary (default summary.json), skipping the detailed outputs")
+	fs.StringVar(&o.markdownOutput, "markdown", v.GetString("markdown_output"), "Path to Markdown report file for pasting into issues")
+	fs.StringVar(&o.sqliteOutput, "sqlite", v.GetString("sqlite_output"), "Path to SQLite database that findings are appended to")
+	fs.StringVar(&o.stixOutput, "stix", v.GetString("stix_output"), "Path to STIX 2.1 bundle of indicators derived from findings, for threat-intelligence platforms")
+	fs.StringVar(&o.format, "format", strings.Join(v.GetStringSlice("formats"), ","), "Comma-separated output formats (json, csv, summary, markdown, sqlite, stix) written to -output-dir with conventional file names; explicit path flags take precedence")
+	fs.StringVar(&o.outputDir, "output-dir", v.GetString("output_dir"), "Directory under the results directory that -format outputs are written to")
+	fs.StringVar(&o.outputMode, "output-mode", v.GetString("output_mode"), "Octal permission of every output file, e.g. 0640 to make results group-readable; directories get the matching read and search bits")
+	fs.BoolVar(&o.keepLogs, "keep-logs", v.GetBool("keep_logs"), "Write the extracted log of every run with findings to logs/owner__repo/<run ID>.log under the results directory")
+	fs.BoolVar(&o.keepAllLogs, "keep-all-logs", v.GetBool("keep_all_logs"), "Like -keep-logs, but keep the log of every scanned run")
+	fs.BoolVar(&o.uploadSARIF, "upload-sarif", v.GetBool("upload_sarif"), "Upload each scanned repository's findings as SARIF to its GitHub code scanning (needs the security_events scope)")
+	fs.BoolVar(&o.uploadSARIFEmpty, "upload-sarif-empty", v.GetBool("upload_sarif_empty"), "With -upload-sarif, also upload an empty analysis for scanned repositories without findings, closing their stale alerts")
+	fs.BoolVar(&o.githubAnnotations, "github-annotations", v.GetBool("github_annotations") || os.Getenv("GITHUB_ACTIONS") == "true", "Print a GitHub Actions ::error:: or ::warning:: annotation per finding to stdout (default on when GITHUB_ACTIONS=true)")
+	fs.StringVar(&o.baseline, "baseline", v.GetString("baseline"), "Path to a previous cache; exit non-zero only for findings not in it")
+	fs.IntVar(&o.minConfidence, "min-confidence", v.GetInt("min_confidence"), "Omit findings with a confidence score (0-100) below this from the reports and exit code; the cache keeps them")
+	fs.BoolVar(&o.jsonNested, "json-nested", v.GetBool("json_nested"), "Nest the JSON output's findings under repository and workflow, with counts at each level")
+	fs.BoolVar(&o.groupBySeverity, "group-by-severity", v.GetBool("group_by_severity"), "Section the JSON and Markdown outputs by severity (critical first, with counts) and sort CSV rows by severity")
+	fs.BoolVar(&o.perRepoOutput, "per-repo-output", v.GetBool("per_repo_output"), "Also write owner__repo.json and owner__repo.csv for each repository with findings")
+	fs.StringVar(&o.startTime, "start", v.GetString("start_time"), "Start time for workflow run filtering (RFC3339; env GHSCAN_START)")
+	fs.StringVar(&o.endTime, "end", v.GetString("end_time"), "End time for workflow run filtering (RFC3339, or now/latest; env GHSCAN_END)")
+	fs.DurationVar(&o.last, "last", v.GetDuration("last"), "Scan the runs of this long before -end (e.g. 24h) instead of from -start (env GHSCAN_LAST)")
+	fs.StringVar(&o.iocName, "ioc-name", v.GetString("ioc.name"), "IOC Logs to scan for (e.g. tj-actions/changed-files")
+	fs.StringVar(&o.iocContent, "ioc-content", v.GetString("ioc.content"), "Comma-separated string(s) to search for in logs")
+	fs.StringVar(&o.iocContentFile, "ioc-content-file", v.GetString("ioc.content_file"), "Path to a file of newline-delimited strings (e.g. digests) to search for in logs; # starts a comment")
+	fs.StringVar(&o.iocPattern, "ioc-pattern", v.GetString("ioc.pattern"), "Regex pattern to search logs with")
+	fs.StringVar(&o.iocFile, "ioc-file", v.GetString("ioc_file"), "Path to a JSON corpus file overriding the embedded IOC list")
+	fs.BoolVar(&o.scanYAML, "scan-yaml", v.GetBool("scan_yaml"), "Scan workflow YAML for known-bad uses: refs before execution")
+	fs.BoolVar(&o.allowBinaryDecoded, "allow-binary-decoded", v.GetBool("allow_binary_decoded"), "Report base64 blocks that decode to non-UTF-8 bytes, with those bytes \\xNN-escaped, instead of discarding them")
+	fs.IntVar(&o.contextLines, "context-lines", v.GetInt("context_lines"), "Record up to this many log lines before and after each matching line in the finding's context (0 = off, max 50)")
+	fs.BoolVar(&o.explain, "explain", v.GetBool("explain"), "Record in each finding's explanation which detector or rule reported it, what matched, and where")
+	fs.BoolVar(&o.pauseOnRateLimit, "pause-on-rate-limit", v.GetBool("pause_on_rate_limit"), "Hold new repositories, workflows, and runs until the core rate limit resets once it is spent, instead of failing their requests")
+	fs.BoolVar(&o.adaptiveConcurrency, "adaptive-concurrency", v.GetBool("adaptive_concurrency"), "Scale the repositories scanned at once (up to max_concurrency) to the remaining rate-limit budget")
+	fs.BoolVar(&o.bestEffort, "best-effort", v.GetBool("best_effort"), "Skip runs and workflows that fail instead of aborting their repository, and report them when the scan completes")
+	fs.DurationVar(&o.repoTimeout, "repo-timeout", v.GetDuration("repo_timeout"), "Cancel a repository's scan after this long (0 = scale the budget with its workflows and runs)")
+	fs.DurationVar(&o.repoStagger, "repo-stagger", v.GetDuration("repo_stagger"), "Wait a random delay up to this long before scanning each repository (0 = off)")
+	fs.DurationVar(&o.flushInterval, "flush-interval", v.GetDuration("flush_interval"), "Flush finished-workflow results to the incremental outputs at least this often (0 = only when each repository finishes)")
+	fs.IntVar(&o.flushSize, "flush-size", v.GetInt("flush_size"), "Also flush finished-workflow results to the incremental outputs once at least this many are pending (0 = disabled)")
+	fs.StringVar(&o.orgWorkflow, "org-workflow", v.GetString("org_workflow"), "Scan only this workflow file (e.g. publish.yml) in every repository, skipping repositories without it")
+	fs.StringVar(&o.repoLanguage, "repo-language", strings.Join(v.GetStringSlice("repo_languages"), ","), "Comma-separated primary languages; scan only the organization's repositories written in one of them (e.g. JavaScript,TypeScript)")
+	fs.StringVar(&o.repoTopic, "repo-topic", strings.Join(v.GetStringSlice("repo_topics"), ","), "Comma-separated topics; scan only the organization's repositories tagged with one of them (e.g. production)")
+	fs.StringVar(&o.visibility, "visibility", v.GetString("visibility"), "Scan only the organization's public or private repositories: public, private, or all")
+	fs.IntVar(&o.maxRepos, "max-repos", v.GetInt("max_repos"), "Scan at most this many repositories after listing (0 = no limit)")
+	fs.BoolVar(&o.scanLogs, "scan-logs", v.GetBool("scan_logs"), "Scan workflow run logs for behavioral IOCs after execution")
+	fs.BoolVar(&o.scanHistory, "scan-history", v.GetBool("scan_history"), "Scan commits to .github/workflows in the time window for added lines referencing the IOC")
+	fs.BoolVar(&o.modifiedOnly, "modified-only", v.GetBool("modified_only"), "Scan the runs of only the workflows whose file a commit in the time window modified, and report those commits as with -scan-history")
+	fs.BoolVar(&o.scanActions, "scan-actions", v.GetBool("scan_actions"), "Statically scan the action.yml and bundled scripts of actions referenced by workflows, at the pinned ref")
+	fs.BoolVar(&o.resolveTags, "resolve-tags", v.GetBool("resolve_tags"), "Resolve each action tag workflows pin to its current commit and report tags that point at a known-bad commit")
+	fs.IntVar(&o.scanActionsDepth, "scan-actions-depth", v.GetInt("scan_actions_depth"), "Levels of composite actions -scan-actions follows (1 = only actions workflows reference)")
+	fs.BoolVar(&o.scanAttempts, "scan-attempts", v.GetBool("scan_attempts"), "Also scan the logs of every earlier attempt of re-run runs, reporting findings the latest attempt no longer shows")
+	fs.BoolVar(&o.scanCommitMessages, "scan-commit-messages", v.GetBool("scan_commit_messages"), "Scan the head commit message of each run with the log detectors")
+	fs.BoolVar(&o.scanPRBodies, "scan-pr-bodies", v.GetBool("scan_pr_bodies"), "Scan the title and body of each run's pull requests with the log detectors (one API call per pull request)")
+	fs.BoolVar(&o.scanSummaries, "scan-summaries", v.GetBool("scan_summaries"), "Scan each job's check-run summary with the log detectors")
+	fs.BoolVar(&o.correlateSecrets, "correlate-secrets", v.GetBool("correlate_secrets"), "Record the secrets each workflow references on its log and summary findings as candidates for exposure")
+	fs.BoolVar(&o.detectEgress, "detect-egress", v.GetBool("detect_egress"), "Flag curl/wget/nc/Invoke-WebRequest calls in logs to hosts outside -egress-allow")
+	fs.StringVar(&o.egressAllow, "egress-allow", strings.Join(v.GetStringSlice("egress_allowlist"), ","), "Comma-separated hosts (and their subdomains) -detect-egress does not flag")
+	fs.BoolVar(&o.detectCachePoisoning, "detect-cache-poisoning", v.GetBool("detect_cache_poisoning"), "Report actions/cache restore-key fallbacks and suspicious cache keys as low-confidence leads")
+	fs.BoolVar(&o.detectSuspiciousGit, "detect-suspicious-git", v.GetBool("detect_suspicious_git"), "Flag git clone/remote add/push lines in logs whose remote is on a host outside -git-remote-allow")
+	fs.StringVar(&o.gitRemoteAllow, "git-remote-allow", strings.Join(v.GetStringSlice("git_remote_allowlist"), ","), "Comma-separated hosts (and their subdomains) -detect-suspicious-git does not flag")
+	fs.BoolVar(&o.detectBase32, "detect-base32", v.GetBool("detect_base32"), "Flag base32 tokens in logs that decode to printable text (prone to false positives on uppercase IDs)")
+	fs.BoolVar(&o.detectBase85, "detect-base85", v.GetBool("detect_base85"), "Flag Ascii85 (<~ ~>) and RFC 1924 base85 tokens in logs that decode to printable text")
+	fs.BoolVar(&o.detectMaskBypass, "detect-mask-bypass", v.GetBool("detect_mask_bypass"), "Flag steps that pass a masked secret through base64/xxd/rev/character splitting and print output that decodes back to it")
+	fs.StringVar(&o.conclusions, "conclusions", strings.Join(v.GetStringSlice("conclusions"), ","), "Comma-separated run conclusions or statuses to scan logs for; prefix with ! to exclude (e.g. failure or !skipped,!in_progress)")
+	fs.StringVar(&o.apiVersion, "api-version", v.GetString("api_version"), "X-GitHub-Api-Version sent on every API request (default: each client's built-in pin)")
+	fs.StringVar(&o.caCert, "ca-cert", v.GetString("ca_cert"), "PEM bundle of additional CA certificates every HTTP client trusts, e.g. a GitHub Enterprise Server's private CA")
+	fs.StringVar(&o.logCacheDir, "log-cache-dir", v.GetString("log_cache_dir"), "Directory to cache downloaded run logs in, keyed by run ID, so re-scans skip the download")
+	fs.DurationVar(&o.logCacheTTL, "log-cache-ttl", v.GetDuration("log_cache_ttl"), "Re-download cached run logs older than this (0 = keep forever; logs of completed runs never change)")
+	fs.StringVar(&o.enableDetectors, "enable-detectors", strings.Join(v.GetStringSlice("enable_detectors"), ","), "Comma-separated detector names to run instead of all of them (ioc, base64, pem, or an opt-in detector such as network-egress, which this also turns on)")
+	fs.StringVar(&o.disableDetectors, "disable-detectors", strings.Join(v.GetStringSlice("disable_detectors"), ","), "Comma-separated detector names to skip (e.g. ioc to hunt only with the other detectors)")
+	fs.StringVar(&o.jobFilter, "job-filter", strings.Join(v.GetStringSlice("job_filter"), ","), "Comma-separated job name regexps or conclusion:<value> terms; scan only the logs of matching jobs (e.g. deploy or conclusion:failure)")
+	fs.BoolVar(&o.allRuns, "all-runs", v.GetBool("all_runs"), "Scan every run the repository's workflows ever had, ignoring -start, -end, and -last (single repository only; slow and API-heavy)")
+	fs.BoolVar(&o.collapseRuns, "collapse-runs", v.GetBool("collapse_runs"), "Report a finding repeated across a workflow's runs once, listing every run in run_urls")
+	fs.BoolVar(&o.latestOnly, "latest-only", v.GetBool("latest_only"), "Scan only the newest run of each workflow in the time window")
+	fs.StringVar(&o.searchQueryTemplate, "search-query-template", v.GetString("search_query_template"), "Code-search query used to find workflow files; {owner} and {repo} are substituted per repository")
+	fs.BoolVar(&o.selftest, "selftest", false, "Scan a built-in fixture log with the embedded IOC and exit non-zero unless every built-in detector fires; needs no target or token")
+	if err := fs.Parse(args); err != nil {
+		return scanOptions{}, err
+	}
+	return o, nil
+}
+
+// validate reports the first flag, or combination of flags, a scan
+// cannot run with. It checks only the flags themselves, not the files
+// or APIs they name.
+func (o *scanOptions) validate() error {
+	if err := o.validateSource(); err != nil {
+		return err
+	}
+	switch {
+	case !o.scanYAML && !o.scanLogs:
+		return errors.New("at least one of -scan-yaml or -scan-logs must be enabled")
+	case o.scanActions && !o.scanYAML:
+		return errors.New("-scan-actions requires -scan-yaml")
+	case o.resolveTags && !o.scanYAML:
+		return errors.New("-resolve-tags requires -scan-yaml")
+	case o.jsonNested && o.groupBySeverity:
+		return errors.New("only one of -json-nested or -group-by-severity may be provided")
+	case o.minConfidence < 0 || o.minConfidence > 100:
+		return fmt.Errorf("-min-confidence must be between 0 and 100, got %d", o.minConfidence)
+	case !validVisibility(o.visibility):
+		return fmt.Errorf("-visibility must be public, private, or all, got %q", o.visibility)
+	}
+	if err := action.ValidateSearchQueryTemplate(o.searchQueryTemplate); err != nil {
+		return fmt.Errorf("invalid -search-query-template: %w", err)
+	}
+	if o.orgWorkflow != "" {
+		if _, err := action.OrgWorkflowPath(o.orgWorkflow); err != nil {
+			return fmt.Errorf("invalid -org-workflow: %w", err)
+		}
+	}
+	if err := action.ValidateConclusions(splitList(o.conclusions)); err != nil {
+		return fmt.Errorf("invalid -conclusions: %w", err)
+	}
+	return nil
+}
+
+// validateSource reports a scan naming no source, or more than one:
+// a GitHub target or enterprise, a GitLab project, a runs file, or a
+// logs directory.
+func (o *scanOptions) validateSource() error {
+	switch {
+	case o.target != "" && o.enterprise != "":
+		return errors.New("only one of -target or -enterprise may be provided")
+	case o.gitlabProject != "" && (o.target != "" || o.enterprise != ""):
+		return errors.New("-gitlab-project cannot be combined with -target or -enterprise")
+	case o.runsFile != "" && (o.target != "" || o.enterprise != "" || o.gitlabProject != ""):
+		return errors.New("-runs-file cannot be combined with -target, -enterprise, or -gitlab-project")
+	case o.logsDir != "" && (o.target != "" || o.enterprise != "" || o.gitlabProject != "" || o.runsFile != ""):
+		return errors.New("-logs-dir cannot be combined with -target, -enterprise, -gitlab-project, or -runs-file")
+	case o.target == "" && o.enterprise == "" && o.gitlabProject == "" && o.runsFile == "" && o.logsDir == "":
+		return errors.New("target must be provided")
+	case o.uploadSARIF && (o.gitlabProject != "" || o.logsDir != ""):
+		return errors.New("-upload-sarif uploads to GitHub code scanning and cannot be combined with -gitlab-project or -logs-dir")
+	case o.uploadSARIFEmpty && !o.uploadSARIF:
+		return errors.New("-upload-sarif-empty requires -upload-sarif")
+	}
+	return nil
+}
+
+// usesGitHub reports whether the scan calls the GitHub API; GitLab and
+// offline scans do not, so they need no GitHub token.
+func (o *scanOptions) usesGitHub() bool {
+	return o.gitlabProject == "" && o.logsDir == ""
+}
+
+// resolveOutputs fills in the output paths -format and -summary-only
+// imply and creates -output-dir for them.
+func (o *scanOptions) resolveOutputs() error {
+	paths := outputPaths{
+		JSON:     o.jsonOutput,
+		CSV:      o.csvOutput,
+		Summary:  o.summaryOutput,
+		Markdown: o.markdownOutput,
+		SQLite:   o.sqliteOutput,
+		STIX:     o.stixOutput,
+	}
+	formats := splitList(o.format)
+	if err := applyFormats(&paths, formats, o.outputDir); err != nil {
+		return fmt.Errorf("invalid -format: %w", err)
+	}
+	if o.summaryOnly {
+		if conflicts := summaryOnlyConflicts(paths, o.perRepoOutput); len(conflicts) > 0 {
+			return fmt.Errorf("-summary-only writes no detailed outputs; drop %s", strings.Join(conflicts, ", "))
+		}
+		if paths.Summary == "" {
+			paths.Summary = filepath.Join(o.outputDir, formatFiles["summary"])
+		}
+	}
+	o.jsonOutput, o.csvOutput, o.summaryOutput = paths.JSON, paths.CSV, paths.Summary
+	o.markdownOutput, o.sqliteOutput, o.stixOutput = paths.Markdown, paths.SQLite, paths.STIX
+	if len(formats) > 0 && o.outputDir != "" {
+		if err := os.MkdirAll(filepath.Join(ghscan.ResultsDir, o.outputDir), file.DirMode()); err != nil {
+			return fmt.Errorf("creating -output-dir: %w", err)
+		}
+	}
+	return nil
+}
+
+// logOptions returns the settings the log scans run with, registering
+// the opt-in detectors the flags turn on. Naming an opt-in detector in
+// -enable-detectors turns it on as its -detect-* flag would.
+func (o *scanOptions) logOptions(v *viper.Viper) (*workflow.Options, error) {
+	jobFilter, err := workflow.ParseJobFilter(splitList(o.jobFilter))
+	if err != nil {
+		return nil, fmt.Errorf("invalid -job-filter: %w", err)
+	}
+	logCacheDir := o.logCacheDir
+	if o.noCache {
+		// -no-cache leaves nothing behind but the requested outputs,
+		// so downloaded logs are not cached either.
+		logCacheDir = ""
+	}
+	opts := &workflow.Options{
+		MaxDecodeDepth:      v.GetInt("max_decode_depth"),
+		AllowBinaryDecoded:  o.allowBinaryDecoded,
+		EnableDetectors:     splitList(o.enableDetectors),
+		DisableDetectors:    splitList(o.disableDetectors),
+		ContextLines:        o.contextLines,
+		Explain:             o.explain,
+		JobFilter:           jobFilter,
+		LogCache:            &workflow.LogCache{Dir: logCacheDir, TTL: o.logCacheTTL},
+		FallbackConcurrency: v.GetInt("fallback_concurrency"),
+	}
+
+	optIn := []struct {
+		on     bool
+		name   string
+		detect func() workflow.Detector
+	}{
+		{o.detectEgress, workflow.DetectorEgress, func() workflow.Detector { return workflow.NewEgressDetector(splitList(o.egressAllow)) }},
+		{o.detectCachePoisoning, workflow.DetectorCachePoisoning, workflow.NewCachePoisoningDetector},
+		{o.detectSuspiciousGit, workflow.DetectorSuspiciousGit, func() workflow.Detector { return workflow.NewGitRemoteDetector(splitList(o.gitRemoteAllow)) }},
+		{o.detectMaskBypass, workflow.DetectorMaskBypass, workflow.NewMaskBypassDetector},
+		{o.detectBase32, workflow.DetectorBase32, workflow.NewBase32Detector},
+		{o.detectBase85, workflow.DetectorBase85, workflow.NewBase85Detector},
+	}
+	for _, d := range optIn {
+		if !d.on && !slices.Contains(opts.EnableDetectors, d.name) {
+			continue
+		}
+		if err := workflow.RegisterDetector(d.name, d.detect()); err != nil {
+			return nil, fmt.Errorf("enabling %s detection: %w", d.name, err)
+		}
+	}
+	if len(opts.EnableDetectors) > 0 || len(opts.DisableDetectors) > 0 {
+		if err := opts.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid -enable-detectors/-disable-detectors: %w", err)
+		}
+		logger.Infof("Running detectors: %s", strings.Join(opts.Detectors(), ", "))
+	}
+	return opts, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-github/v86/github"
+)
+
+// repoFilter narrows the repositories listed for an organization or
+// enterprise scan to those in one of languages and tagged with one of
+// topics. An empty list does not filter on that attribute; both are
+// compared case-insensitively.
+type repoFilter struct {
+	languages []string
+	topics    []string
+}
+
+func newRepoFilter(languages, topics []string) repoFilter {
+	lower := func(in []string) []string {
+		out := make([]string, 0, len(in))
+		for _, s := range in {
+			out = append(out, strings.ToLower(s))
+		}
+		return out
+	}
+	return repoFilter{languages: lower(languages), topics: lower(topics)}
+}
+
+func (f repoFilter) empty() bool {
+	return len(f.languages) == 0 && len(f.topics) == 0
+}
+
+// filterRepos returns the repositories f admits, in order. The
+// language and topics come from the listing itself; a repository whose
+// listing carried no topics has them fetched, so only the topic filter
+// costs extra API calls.
+func filterRepos(ctx context.Context, gh *github.Client, repos []*github.Repository, f repoFilter) ([]*github.Repository, error) {
+	if f.empty() {
+		return repos, nil
+	}
+	var out []*github.Repository
+	for _, repo := range repos {
+		if len(f.languages) > 0 && !slices.Contains(f.languages, strings.ToLower(repo.GetLanguage())) {
+			continue
+		}
+		if len(f.topics) > 0 {
+			topics := repo.Topics
+			if topics == nil {
+				var err error
+				topics, _, err = gh.Repositories.ListAllTopics(ctx, repo.GetOwner().GetLogin(), repo.GetName(), nil)
+				if err != nil {
+					return nil, fmt.Errorf("listing topics of %s: %w", repo.GetFullName(), err)
+				}
+			}
+			if !slices.ContainsFunc(topics, func(t string) bool {
+				return slices.Contains(f.topics, strings.ToLower(t))
+			}) {
+				continue
+			}
+		}
+		out = append(out, repo)
+	}
+	return out, nil
+}
+
+// Repository visibilities accepted by -visibility. Internal
+// repositories of an enterprise report themselves as private.
+const (
+	visibilityAll     = "all"
+	visibilityPublic  = "public"
+	visibilityPrivate = "private"
+)
+
+// validVisibility reports whether v is a -visibility value.
+func validVisibility(v string) bool {
+	return v == visibilityAll || v == visibilityPublic || v == visibilityPrivate
+}
+
+// filterVisibility returns the repositories of the given visibility,
+// in order, with the number of public and private repositories among
+// all of repos. visibilityAll keeps every repository.
+func filterVisibility(repos []*github.Repository, visibility string) (kept []*github.Repository, public, private int) {
+	for _, repo := range repos {
+		isPrivate := repo.GetPrivate()
+		if isPrivate {
+			private++
+		} else {
+			public++
+		}
+		switch {
+		case visibility == visibilityPublic && isPrivate,
+			visibility == visibilityPrivate && !isPrivate:
+			continue
+		}
+		kept = append(kept, repo)
+	}
+	return kept, public, private
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v86/github"
+)
+
+// TestFilterRepos covers the language and topic filters, alone and
+// combined, and the topics lookup for a listing that carried none.
+func TestFilterRepos(t *testing.T) {
+	t.Parallel()
+
+	repo := func(name, lang string, topics []string) *github.Repository {
+		return &github.Repository{
+			Name:     new(name),
+			FullName: new("octo/" + name),
+			Language: new(lang),
+			Owner:    &github.User{Login: new("octo")},
+			Topics:   topics,
+		}
+	}
+	repos := []*github.Repository{
+		repo("web", "JavaScript", []string{"production"}),
+		repo("tool", "Go", []string{}),
+		repo("ui", "TypeScript", []string{"staging"}),
+		repo("legacy", "JavaScript", nil),
+	}
+
+	cases := []struct {
+		name      string
+		languages []string
+		topics    []string
+		want      []string
+	}{
+		{name: "no filter", want: []string{"web", "tool", "ui", "legacy"}},
+		{name: "language case-insensitive", languages: []string{"javascript"}, want: []string{"web", "legacy"}},
+		{name: "several languages", languages: []string{"JavaScript", "TypeScript"}, want: []string{"web", "ui", "legacy"}},
+		{name: "topic with lookup", topics: []string{"Production"}, want: []string{"web", "legacy"}},
+		{name: "both must pass", languages: []string{"TypeScript"}, topics: []string{"production"}, want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			gh := newEnterpriseTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/repos/octo/legacy/topics" {
+					t.Errorf("unexpected request %s", r.URL.Path)
+					http.NotFound(w, r)
+					return
+				}
+				_, _ = w.Write([]byte(`{"names":["production"]}`))
+			})
+			got, err := filterRepos(t.Context(), gh, repos, newRepoFilter(tc.languages, tc.topics))
+			if err != nil {
+				t.Fatalf("filterRepos() error = %v", err)
+			}
+			var names []string
+			for _, r := range got {
+				names = append(names, r.GetName())
+			}
+			if !slices.Equal(names, tc.want) {
+				t.Fatalf("filterRepos() = %q, want %q", names, tc.want)
+			}
+		})
+	}
+}
+
+// TestFilterVisibility covers each -visibility value and the
+// per-visibility counts, which cover every listed repository whatever
+// is kept.
+func TestFilterVisibility(t *testing.T) {
+	t.Parallel()
+
+	repos := []*github.Repository{
+		{Name: new("web"), Private: new(false)},
+		{Name: new("infra"), Private: new(true)},
+		// A listing without the field counts as public.
+		{Name: new("docs")},
+		{Name: new("secrets"), Private: new(true)},
+	}
+	cases := []struct {
+		visibility string
+		want       []string
+	}{
+		{visibility: visibilityAll, want: []string{"web", "infra", "docs", "secrets"}},
+		{visibility: visibilityPublic, want: []string{"web", "docs"}},
+		{visibility: visibilityPrivate, want: []string{"infra", "secrets"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.visibility, func(t *testing.T) {
+			t.Parallel()
+			got, public, private := filterVisibility(repos, tc.visibility)
+			var names []string
+			for _, r := range got {
+				names = append(names, r.GetName())
+			}
+			if !slices.Equal(names, tc.want) {
+				t.Fatalf("filterVisibility() = %q, want %q", names, tc.want)
+			}
+			if public != 2 || private != 2 {
+				t.Fatalf("filterVisibility() counts = %d public, %d private, want 2, 2", public, private)
+			}
+		})
+	}
+
+	for _, v := range []string{"public", "private", "all"} {
+		if !validVisibility(v) {
+			t.Errorf("validVisibility(%q) = false, want true", v)
+		}
+	}
+	for _, v := range []string{"", "internal", "Public"} {
+		if validVisibility(v) {
+			t.Errorf("validVisibility(%q) = true, want false", v)
+		}
+	}
+}
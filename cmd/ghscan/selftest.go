@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// The -selftest fixture: a log in GitHub's format holding one hit for
+// each built-in log detector. selftestDigest is the compromised
+// tj-actions/changed-files commit from the embedded corpus, and
+// selftestEncoded is selftestPayload base64-encoded.
+const (
+	selftestIOCName = "tj-actions/changed-files"
+	selftestDigest  = "0e58ed8671d6b60d0890c21b07f8835ace038e67"
+	selftestPayload = "ghscan self-test payload: a secret dumped by a compromised action"
+	selftestEncoded = "Z2hzY2FuIHNlbGYtdGVzdCBwYXlsb2FkOiBhIHNlY3JldCBkdW1wZWQgYnkgYSBjb21wcm9taXNlZCBhY3Rpb24="
+	selftestPEMType = "OPENSSH PRIVATE KEY"
+
+	// selftestPattern is the base64 pattern config.yaml documents for
+	// custom IOCs.
+	selftestPattern = `(?:^|\s+)([A-Za-z0-9+/]{40,}={0,3})`
+)
+
+var selftestLog = strings.Join([]string{
+	"2025-03-14T12:00:00.0000000Z ##[group]Run " + selftestIOCName + "@" + selftestDigest,
+	"2025-03-14T12:00:01.0000000Z " + selftestEncoded,
+	"2025-03-14T12:00:02.0000000Z -----BEGIN " + selftestPEMType + "-----",
+	"2025-03-14T12:00:02.0000000Z b3BlbnNzaC1rZXktdjEAAAAA",
+	"2025-03-14T12:00:02.0000000Z -----END " + selftestPEMType + "-----",
+}, "\n") + "\n"
+
+// runSelftest scans the built-in fixture with the embedded IOC corpus
+// and the default detectors, and returns an error naming every
+// detector that did not report its expected finding. It needs no
+// network access or token, so operators can check a deployed binary.
+func runSelftest(logger *clog.Logger) error {
+	predefined, ok := ioc.GetPredefinedIOC(selftestIOCName)
+	if !ok {
+		return fmt.Errorf("predefined IOC %s is missing from the embedded corpus", selftestIOCName)
+	}
+	findIOC, err := ioc.NewIOC(&ioc.Config{
+		Name:    selftestIOCName,
+		Content: predefined.GetContent(),
+		Pattern: selftestPattern,
+	})
+	if err != nil {
+		return fmt.Errorf("building self-test IOC: %w", err)
+	}
+	findings, _ := workflow.ParseLogs(logger, selftestLog, 0, findIOC)
+	return checkSelftest(findings)
+}
+
+// checkSelftest returns an error listing the detectors whose expected
+// fixture finding is absent from findings.
+func checkSelftest(findings []workflow.Finding) error {
+	fired := make(map[string]bool)
+	for _, f := range findings {
+		switch {
+		case f.KeyType == selftestPEMType:
+			fired[workflow.DetectorPEM] = true
+		case f.Decoded == selftestPayload:
+			fired[workflow.DetectorBase64] = true
+		case f.Encoded == "" && f.MatchedPattern == selftestDigest:
+			fired[workflow.DetectorIOC] = true
+		}
+	}
+	var missing []string
+	for _, name := range []string{workflow.DetectorIOC, workflow.DetectorBase64, workflow.DetectorPEM} {
+		if !fired[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("self-test detections missing: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Environment variables a scheduled job can set instead of passing
+// -start, -end, and -last. They override config.yaml; the flags
+// override them.
+const (
+	envStart = "GHSCAN_START"
+	envEnd   = "GHSCAN_END"
+	envLast  = "GHSCAN_LAST"
+)
+
+// bindWindowEnv makes the start_time, end_time, and last keys of v
+// read from their GHSCAN_* environment variables.
+func bindWindowEnv(v *viper.Viper) {
+	_ = v.BindEnv("start_time", envStart)
+	_ = v.BindEnv("end_time", envEnd)
+	_ = v.BindEnv("last", envLast)
+}
+
+// resolveWindow returns the absolute time window to scan. end is an
+// RFC3339 time or one of the keywords now and latest, which resolve to
+// now. A positive last makes the window the last-long span ending at
+// end and start is ignored; otherwise start must be an RFC3339 time.
+// The window must not be empty.
+func resolveWindow(start, end string, last time.Duration, now time.Time) (time.Time, time.Time, error) {
+	var endTime time.Time
+	switch strings.ToLower(strings.TrimSpace(end)) {
+	case "now", "latest":
+		endTime = now
+	default:
+		var err error
+		if endTime, err = time.Parse(time.RFC3339, end); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing end time %q (want RFC3339, now, or latest): %w", end, err)
+		}
+	}
+
+	var startTime time.Time
+	switch {
+	case last < 0:
+		return time.Time{}, time.Time{}, fmt.Errorf("last must not be negative, got %s", last)
+	case last > 0:
+		startTime = endTime.Add(-last)
+	default:
+		var err error
+		if startTime, err = time.Parse(time.RFC3339, start); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("parsing start time %q (want RFC3339, or set -last): %w", start, err)
+		}
+	}
+
+	if !startTime.Before(endTime) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start time %s is not before end time %s", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339))
+	}
+	return startTime, endTime, nil
+}
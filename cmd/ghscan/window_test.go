@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// TestResolveWindow pins how -start, -end, and -last combine into the
+// absolute window: end keywords resolve to now, -last replaces -start,
+// and malformed or empty windows are rejected.
+func TestResolveWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 3, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name               string
+		start, end         string
+		last               time.Duration
+		wantStart, wantEnd time.Time
+		wantErr            string
+	}{
+		{
+			name:      "absolute",
+			start:     "2025-03-14T00:00:00Z",
+			end:       "2025-03-15T00:00:00Z",
+			wantStart: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{name: "end now", start: "2025-03-14T00:00:00Z", end: "now", wantStart: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), wantEnd: now},
+		{name: "end latest", start: "2025-03-14T00:00:00Z", end: " Latest ", wantStart: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC), wantEnd: now},
+		{name: "last before now ignores start", start: "2025-01-01T00:00:00Z", end: "now", last: 24 * time.Hour, wantStart: now.Add(-24 * time.Hour), wantEnd: now},
+		{name: "last without start", end: "2025-03-15T00:00:00Z", last: time.Hour, wantStart: time.Date(2025, 3, 14, 23, 0, 0, 0, time.UTC), wantEnd: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "missing start", end: "now", wantErr: "parsing start time"},
+		{name: "bad end", start: "2025-03-14T00:00:00Z", end: "tomorrow", wantErr: "parsing end time"},
+		{name: "negative last", end: "now", last: -time.Hour, wantErr: "must not be negative"},
+		{name: "start after end", start: "2025-03-15T00:00:00Z", end: "2025-03-14T00:00:00Z", wantErr: "not before"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			start, end, err := resolveWindow(tc.start, tc.end, tc.last, now)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("resolveWindow() error = %v, want substring %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveWindow() error = %v", err)
+			}
+			if !start.Equal(tc.wantStart) || !end.Equal(tc.wantEnd) {
+				t.Fatalf("resolveWindow() = %s, %s, want %s, %s", start, end, tc.wantStart, tc.wantEnd)
+			}
+		})
+	}
+}
+
+// TestSetDefaults_WindowFromEnv asserts the GHSCAN_* variables feed the
+// window keys and take precedence over config.yaml values.
+func TestSetDefaults_WindowFromEnv(t *testing.T) {
+	t.Setenv(envStart, "2025-03-01T00:00:00Z")
+	t.Setenv(envEnd, "now")
+	t.Setenv(envLast, "6h")
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader("start_time: \"2025-03-14T00:00:00Z\"\nend_time: \"2025-03-16T00:00:00Z\"\n")); err != nil {
+		t.Fatal(err)
+	}
+	setDefaults(v)
+
+	if got := v.GetString("start_time"); got != "2025-03-01T00:00:00Z" {
+		t.Fatalf("start_time=%q, want GHSCAN_START", got)
+	}
+	if got := v.GetString("end_time"); got != "now" {
+		t.Fatalf("end_time=%q, want GHSCAN_END", got)
+	}
+	if got := v.GetDuration("last"); got != 6*time.Hour {
+		t.Fatalf("last=%s, want GHSCAN_LAST", got)
+	}
+}
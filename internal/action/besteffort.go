@@ -0,0 +1,94 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	"github.com/spf13/viper"
+)
+
+// bestEffortKey keeps scanning past a run or workflow that fails
+// instead of cancelling its siblings; the failures are reported once
+// the scan completes. Defaults to false (strict).
+const bestEffortKey = "best_effort"
+
+// ErrIncompleteScan is returned by [Scan] in best_effort mode when one
+// or more runs or workflows failed and were skipped; every other
+// result is still recorded.
+var ErrIncompleteScan = errors.New("scan incomplete")
+
+// failures collects the errors best_effort tolerates. A nil *failures,
+// as in strict mode, tolerates nothing. All methods are safe for
+// concurrent use.
+type failures struct {
+	mu        sync.Mutex
+	runs      []error
+	workflows []error
+}
+
+// newFailures returns a collector when best_effort is enabled.
+func newFailures() *failures {
+	if !viper.GetBool(bestEffortKey) {
+		return nil
+	}
+	return &failures{}
+}
+
+// run records a failed run and returns nil so the scan continues, or
+// returns err unchanged when it cannot be tolerated.
+func (f *failures) run(ctx context.Context, err error) error {
+	if !f.tolerates(ctx, err) {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runs = append(f.runs, err)
+	return nil
+}
+
+// workflow is run for a failed workflow.
+func (f *failures) workflow(ctx context.Context, err error) error {
+	if !f.tolerates(ctx, err) {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.workflows = append(f.workflows, err)
+	return nil
+}
+
+// tolerates reports whether err may be recorded rather than returned.
+// A cancelled scan still stops, and an open circuit breaker is left to
+// Scan, which reports the repository as a whole.
+func (f *failures) tolerates(ctx context.Context, err error) bool {
+	return f != nil && err != nil && ctx.Err() == nil && !errors.Is(err, request.ErrCircuitOpen)
+}
+
+// report logs every recorded failure and returns ErrIncompleteScan
+// with their counts, wrapping each failure, or nil when nothing
+// failed.
+func (f *failures) report(logger *clog.Logger) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.runs) == 0 && len(f.workflows) == 0 {
+		return nil
+	}
+	for _, err := range f.workflows {
+		logger.Warnf("Skipped failed workflow: %v", err)
+	}
+	for _, err := range f.runs {
+		logger.Warnf("Skipped failed run: %v", err)
+	}
+	return &failureSummary{
+		msg:  fmt.Sprintf("%v: %d runs and %d workflows failed", ErrIncompleteScan, len(f.runs), len(f.workflows)),
+		errs: slices.Concat([]error{ErrIncompleteScan}, f.workflows, f.runs),
+	}
+}
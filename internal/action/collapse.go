@@ -0,0 +1,83 @@
+package action
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// collapseRunsKey makes scanRuns fold a workflow's identical findings
+// from different runs into one result (see [CollapseRuns]). Defaults to
+// false so every run keeps its own row.
+const collapseRunsKey = "collapse_runs"
+
+// collapseKey identifies a finding independently of the run it was
+// seen in: by its decoded payload, or, for a finding without one, by
+// the pattern and destination it matched. Raw log lines are not used
+// because they carry each run's timestamps. The empty string means the
+// finding cannot be keyed and is kept as is.
+func collapseKey(r ghscan.Result) string {
+	if r.DecodedData == "" && r.MatchedPattern == "" && r.Destination == "" {
+		return ""
+	}
+	return strings.Join([]string{r.Repository, r.WorkflowFileName, r.Source, r.KeyTypes, r.DecodedData, r.MatchedPattern, r.Destination}, "\x00")
+}
+
+// compareRunURLs orders run URLs by run ID. IDs only grow, so a
+// shorter URL of the same workflow is an older run.
+func compareRunURLs(a, b string) int {
+	return cmp.Or(cmp.Compare(len(a), len(b)), strings.Compare(a, b))
+}
+
+// CollapseRuns folds results of one workflow that report the same
+// finding (the same decoded payload, or for findings without one the
+// same matched pattern and destination) in different runs into a
+// single result, so a malicious step that ran on every push is
+// reported once. The kept result is the one from the oldest run; its
+// RunURLs lists every run the finding was seen in, oldest first, and
+// its Confidence is the highest of the folded results. Results that
+// name no run, or match nothing that identifies them across runs, are
+// returned unchanged. Output follows the order in which each finding
+// was first seen.
+func CollapseRuns(in []ghscan.Result) []ghscan.Result {
+	if len(in) < 2 {
+		return in
+	}
+	idx := make(map[string]int, len(in))
+	out := make([]ghscan.Result, 0, len(in))
+	for _, r := range in {
+		key := collapseKey(r)
+		if key == "" || r.WorkflowRunURL == "" {
+			out = append(out, r)
+			continue
+		}
+		i, ok := idx[key]
+		if !ok {
+			idx[key] = len(out)
+			r.RunURLs = []string{r.WorkflowRunURL}
+			out = append(out, r)
+			continue
+		}
+		kept := &out[i]
+		if !slices.Contains(kept.RunURLs, r.WorkflowRunURL) {
+			kept.RunURLs = append(kept.RunURLs, r.WorkflowRunURL)
+		}
+		confidence := max(kept.Confidence, r.Confidence)
+		if compareRunURLs(r.WorkflowRunURL, kept.WorkflowRunURL) < 0 {
+			r.RunURLs = kept.RunURLs
+			*kept = r
+		}
+		kept.Confidence = confidence
+	}
+	for i := range out {
+		if len(out[i].RunURLs) < 2 {
+			// A finding seen in one run needs no run list.
+			out[i].RunURLs = nil
+			continue
+		}
+		slices.SortFunc(out[i].RunURLs, compareRunURLs)
+	}
+	return out
+}
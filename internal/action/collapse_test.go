@@ -0,0 +1,60 @@
+package action_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+func TestCollapseRuns(t *testing.T) {
+	t.Parallel()
+
+	const runs = "https://github.com/o/r/actions/runs/"
+	payload := func(run, line string, confidence int) ghscan.Result {
+		return ghscan.Result{
+			Repository:       "o/r",
+			WorkflowFileName: "ci.yml",
+			WorkflowRunURL:   runs + run,
+			Base64Data:       "cGF5bG9hZA==",
+			DecodedData:      "payload",
+			LineData:         line,
+			Confidence:       confidence,
+		}
+	}
+	in := []ghscan.Result{
+		payload("120", "2025-03-15T00:00:00Z cGF5bG9hZA==", 40),
+		{Repository: "o/r", WorkflowFileName: "ci.yml", WorkflowRunURL: runs + "120", LineData: "curl evil.example", KeyTypes: "network-egress", Destination: "evil.example"},
+		payload("99", "2025-03-14T00:00:00Z cGF5bG9hZA==", 40),
+		payload("1000", "2025-03-16T00:00:00Z cGF5bG9hZA==", 70),
+		{Repository: "o/r", WorkflowFileName: "ci.yml", WorkflowRunURL: runs + "1000", LineData: "curl evil.example", KeyTypes: "network-egress", Destination: "evil.example"},
+		// Nothing but the line identifies this finding, so it is kept.
+		{Repository: "o/r", WorkflowFileName: "ci.yml", WorkflowRunURL: runs + "99", LineData: "odd line"},
+		// The same payload from another workflow is a separate finding.
+		{Repository: "o/r", WorkflowFileName: "release.yml", WorkflowRunURL: runs + "5", DecodedData: "payload"},
+	}
+
+	got := action.CollapseRuns(in)
+	want := []ghscan.Result{
+		{
+			Repository:       "o/r",
+			WorkflowFileName: "ci.yml",
+			WorkflowRunURL:   runs + "99",
+			RunURLs:          []string{runs + "99", runs + "120", runs + "1000"},
+			Base64Data:       "cGF5bG9hZA==",
+			DecodedData:      "payload",
+			LineData:         "2025-03-14T00:00:00Z cGF5bG9hZA==",
+			Confidence:       70,
+		},
+		{Repository: "o/r", WorkflowFileName: "ci.yml", WorkflowRunURL: runs + "120", RunURLs: []string{runs + "120", runs + "1000"}, LineData: "curl evil.example", KeyTypes: "network-egress", Destination: "evil.example"},
+		{Repository: "o/r", WorkflowFileName: "ci.yml", WorkflowRunURL: runs + "99", LineData: "odd line"},
+		{Repository: "o/r", WorkflowFileName: "release.yml", WorkflowRunURL: runs + "5", DecodedData: "payload"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CollapseRuns:\n got %+v\nwant %+v", got, want)
+	}
+	if in[0].RunURLs != nil {
+		t.Fatal("CollapseRuns modified its input")
+	}
+}
@@ -5,18 +5,111 @@
 // Public surface:
 //
 //   - [Scan] is the top-level entry point. It walks every supplied
-//     repository, lists workflow files, lists workflow runs in the
+//     repository, lists workflow files (through code search, or the
+//     Actions workflow listing when search fails with a non-retryable
+//     error or finds none), lists workflow runs in the
 //     caller's time window, and dispatches log scanning across a
 //     bounded errgroup. Each repository runs against a shallow per-repo
 //     clone of the request so result slices never alias across
 //     goroutines; the per-repo result slice is merged back into the
 //     caller's cache under a mutex once the repository finishes.
+//     When scan_history is enabled, Scan also walks the commits that
+//     touched .github/workflows in the time window and reports added
+//     lines referencing the IOC as "workflow-change" findings. When
+//     scan_summaries is enabled, each job's check-run summary is run
+//     through the log detectors and reported as "step-summary".
+//     When scan_attempts is enabled, the earlier attempts of re-run
+//     runs are scanned too, and findings the latest attempt lacks are
+//     reported with their RunAttempt.
+//     When scan_commit_messages or scan_pr_bodies is enabled, each
+//     run's head commit message, or the title and body of its pull
+//     requests, is run through the log detectors and reported as
+//     "commit-message" or "pr-body".
+//     When correlate_secrets is enabled, log and summary findings
+//     list the secrets their workflow references, read at the run's
+//     head commit, in ReachableSecrets.
+//     When scan_actions is enabled, the actions each workflow uses are
+//     fetched at their pinned ref and scanned as "action" findings.
+//     When resolve_tags is enabled, each action tag a workflow pins is
+//     resolved to its current commit, and a tag that points at a
+//     known-bad commit is reported as a "tag-pin" finding.
+//     When org_workflow names a workflow file ([OrgWorkflowPath]),
+//     only that file is scanned in each repository, without a code
+//     search, and repositories lacking it are skipped.
+//     When modified_only is enabled, only the runs of workflows whose
+//     file a commit in the time window touched are scanned, and the
+//     history walk runs as if scan_history were set.
+//     When adaptive_concurrency is enabled, the repositories in flight
+//     are paced between 1 and max_concurrency by the rate-limit budget
+//     the request's HTTP client observes. When pause_on_rate_limit is
+//     enabled, new repositories, workflows, and runs wait for the core
+//     rate limit to reset once that budget is all but spent; so do the
+//     runs of [ScanRunRefs].
+//     Each repository is canceled once its time budget is spent: a
+//     fixed repo_timeout, or by default repo_enum_budget grown by the
+//     workflow and run budgets for the work found. Rate-limit pauses
+//     do not count against it. Scan reports the repositories canceled
+//     this way at the end, keeping their partial results.
+//     When best_effort is enabled, a failed run or workflow is skipped
+//     rather than cancelling its siblings, and Scan reports the
+//     failures with [ErrIncompleteScan] at the end.
+//     A repository GitHub withholds (451, or 403 "Repository access
+//     blocked") is skipped without failing the scan and recorded in
+//     the request's Coverage with the reason.
+//     Scan, [ScanSource], and [ScanRunRefs] report each failed
+//     operation as a [ScanError] naming the repository, workflow, and
+//     run it concerned, with an [ErrorCategory] from [Categorize];
+//     [ScanErrors] collects them from the returned error, and the
+//     cause stays visible to errors.Is and errors.As.
+//     When explain is enabled, the results of the YAML, action,
+//     tag-pin, and history scans carry an Explanation naming the
+//     compromised reference; log results carry the detectors' own.
+//   - [ScanSource] scans the runs of any workflow.LogSource, such as a
+//     GitLab project, with the log detectors only, recording findings
+//     under the repository label it is given.
+//   - [ScanRunRefs] scans exactly the GitHub runs named by a stream
+//     of NDJSON [RunRef] lines, without search or listing, sending
+//     each run's findings to the request's sink as it finishes.
+//   - [FilterRuns] applies the conclusions filter to a workflow's runs
+//     before their logs are fetched; [RunState] is the value it
+//     matches and [ValidateConclusions] rejects unknown entries. A
+//     non-zero [ghscan.Request.RunID] narrows the runs to that single
+//     run. With
+//     all_runs, Scan lists every run of each workflow with
+//     [github.com/chainguard-dev/ghscan/pkg/workflow.ListAllWorkflowRuns]
+//     instead of the runs in the request's time window.
+//   - [CollapseRuns] folds a workflow's identical findings from
+//     different runs into one result listing every run's URL. Scan
+//     applies it per workflow when collapse_runs is enabled.
+//   - [DedupResults] collapses a log finding into the YAML finding for
+//     the same workflow file. Scan applies it per repository; the
+//     merge subcommand reuses it across shards.
+//   - [Reanalyze] filters cached results against a new IOC using only
+//     the text retained in each result; it backs the analyze
+//     subcommand.
 //
 // Persistence:
 //
 //   - The caller is responsible for writing the final cache once Scan
-//     returns (see pkg/file.WriteResults). Scan does not perform any
-//     intermediate flushes.
+//     returns (see internal/file.WriteResults). When the request
+//     carries a ResultSink, each repository's findings are
+//     appended to it as soon as the repository finishes so progress
+//     survives a crash without rewriting the whole cache. Results of
+//     workflows that finish earlier are also appended every
+//     flush_interval (default DefaultFlushInterval; 0 disables) and,
+//     when flush_size is positive, as soon as that many are pending,
+//     so a long-running repository cannot hold hours of findings in
+//     memory.
+//     Each result reaches the sink at most once.
+//   - When the request carries a LogStore, the extracted log of every
+//     scanned run is offered to it with whether the run had findings.
+//
+// Logging:
+//
+//   - Scan logs through its logger parameter, or the logger carried by
+//     its context when that is nil, and installs the chosen logger on
+//     the context it passes down. Embedders can therefore route every
+//     line through their own slog handler with either mechanism.
 //
 // Invariants:
 //
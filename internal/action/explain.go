@@ -0,0 +1,22 @@
+package action
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// explainKey records in each finding's Explanation why it was
+// reported. The log detectors explain their own findings once the
+// request's LogOptions set Explain; the YAML, action, tag-pin, and
+// history scans explain theirs here. Defaults to false.
+const explainKey = "explain"
+
+// explain returns the formatted explanation of a finding, or "" when
+// explain is off.
+func explain(format string, args ...any) string {
+	if !viper.GetBool(explainKey) {
+		return ""
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,89 @@
+package action
+
+import (
+	"context"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// ProgressFlusherForTest wraps the unexported progress flusher so tests
+// can drive staging, periodic, and per-repository flushes directly
+// instead of timing a full Scan.
+type ProgressFlusherForTest struct{ f *progressFlusher }
+
+func NewProgressFlusherForTest(logger *clog.Logger, sink ghscan.ResultSink, size int) ProgressFlusherForTest {
+	return ProgressFlusherForTest{f: newProgressFlusher(logger, sink, size)}
+}
+
+func (p ProgressFlusherForTest) Add(ctx context.Context, results []ghscan.Result) {
+	p.f.add(ctx, results)
+}
+
+func (p ProgressFlusherForTest) Flush(ctx context.Context) { p.f.flush(ctx) }
+
+func (p ProgressFlusherForTest) FinishRepo(ctx context.Context, results []ghscan.Result) {
+	p.f.finishRepo(ctx, results)
+}
+
+// Tracked reports how many repositories' sent results are recorded.
+func (p ProgressFlusherForTest) Tracked() int {
+	p.f.mu.Lock()
+	defer p.f.mu.Unlock()
+	return len(p.f.sent)
+}
+
+func (p ProgressFlusherForTest) Run(ctx context.Context, interval time.Duration) {
+	p.f.run(ctx, interval)
+}
+
+// AdaptiveLimitForTest exposes the adaptive concurrency policy.
+func AdaptiveLimitForTest(b httpclient.Budget, now time.Time, maxConcurrency int) int {
+	return adaptiveLimit(b, now, maxConcurrency)
+}
+
+// AdaptiveLimiterForTest wraps the unexported adaptive limiter so
+// tests can observe it admitting and blocking acquirers.
+type AdaptiveLimiterForTest struct{ l *adaptiveLimiter }
+
+func NewAdaptiveLimiterForTest(logger *clog.Logger, budget *httpclient.RateBudget, maxConcurrency int) AdaptiveLimiterForTest {
+	return AdaptiveLimiterForTest{l: newAdaptiveLimiter(logger, budget, maxConcurrency)}
+}
+
+func (a AdaptiveLimiterForTest) Acquire(ctx context.Context) error { return a.l.acquire(ctx) }
+
+func (a AdaptiveLimiterForTest) Release() { a.l.release() }
+
+// RatePauserForTest wraps the unexported rate-limit pauser with a
+// fake rate-limit endpoint and a recording sleep.
+type RatePauserForTest struct{ p *ratePauser }
+
+func NewRatePauserForTest(logger *clog.Logger, budget *httpclient.RateBudget, now time.Time, limits func(context.Context) (*github.Rate, error), sleep func(context.Context, time.Duration) error) RatePauserForTest {
+	p := newRatePauser(logger, nil, budget)
+	p.limits, p.now, p.sleep = limits, func() time.Time { return now }, sleep
+	return RatePauserForTest{p: p}
+}
+
+func (r RatePauserForTest) Wait(ctx context.Context) error { return r.p.wait(ctx) }
+
+// TagResolverForTest wraps the unexported tag resolver so tests can
+// observe which lookups it memoizes.
+type TagResolverForTest struct{ r *tagResolver }
+
+func NewTagResolverForTest(logger *clog.Logger, maxRetries int) TagResolverForTest {
+	return TagResolverForTest{r: &tagResolver{logger: logger, maxRetries: maxRetries, cache: make(map[string]*tagResolution)}}
+}
+
+func (t TagResolverForTest) Resolve(ctx context.Context, gh *github.Client, a wf.ActionRef) string {
+	return t.r.resolve(ctx, gh, a)
+}
+
+// ResultFromFindingForTest exposes the Finding to Result mapping shared
+// by every log scan.
+func ResultFromFindingForTest(repository, iocName string, f wf.Finding) ghscan.Result {
+	return resultFromFinding(repository, iocName, f)
+}
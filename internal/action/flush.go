@@ -0,0 +1,158 @@
+package action
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/spf13/viper"
+)
+
+// flushIntervalKey bounds how long finished-workflow results may wait
+// in memory before they reach the request's ResultSink. Zero disables
+// the periodic flush, leaving only the per-repository flush.
+const flushIntervalKey = "flush_interval"
+
+// DefaultFlushInterval is used when flush_interval is unset.
+const DefaultFlushInterval = time.Minute
+
+// flushSizeKey flushes staged results as soon as at least this many
+// are pending, in addition to the periodic and per-repository
+// flushes. Zero, the default, disables the size trigger.
+const flushSizeKey = "flush_size"
+
+// resolveFlushInterval returns the configured flush_interval, or
+// DefaultFlushInterval when the key is unset. Unlike the operation
+// budgets, an explicit zero is honored and disables the periodic
+// flush.
+func resolveFlushInterval() time.Duration {
+	if !viper.IsSet(flushIntervalKey) {
+		return DefaultFlushInterval
+	}
+	return viper.GetDuration(flushIntervalKey)
+}
+
+// progressFlusher batches results for a ResultSink. Results are staged
+// with add as each workflow finishes and sent by the periodic ticker
+// in run, by finishRepo, or by add itself once size or more are
+// pending, so a repository with hours of runs does not hold
+// everything in memory until it completes. The size trigger compares
+// with >= rather than testing for a multiple, so a batch that jumps
+// past the threshold still flushes.
+//
+// Each result is sent at most once, matched by its [ghscan.Fingerprint]
+// so the stamped copy finishRepo receives is recognized as the result
+// a periodic flush already sent. The record of what was sent is
+// kept per repository and dropped by finishRepo, so an org-wide scan
+// holds it only for the repositories still being scanned. All methods
+// are safe for concurrent use, and a nil *progressFlusher is a no-op.
+type progressFlusher struct {
+	logger *clog.Logger
+	sink   ghscan.ResultSink
+	size   int
+
+	mu      sync.Mutex
+	pending []ghscan.Result
+	// sent holds, per repository, the fingerprint of each result sent.
+	sent map[string]map[string]struct{}
+}
+
+// newProgressFlusher returns a flusher for sink, or nil when there is
+// no sink. A non-positive size disables the size trigger.
+func newProgressFlusher(logger *clog.Logger, sink ghscan.ResultSink, size int) *progressFlusher {
+	if sink == nil {
+		return nil
+	}
+	return &progressFlusher{logger: logger, sink: sink, size: size, sent: make(map[string]map[string]struct{})}
+}
+
+// add stages results for the next flush, flushing at once when size
+// or more results are pending.
+func (f *progressFlusher) add(ctx context.Context, results []ghscan.Result) {
+	if f == nil || len(results) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(f.pending, results...)
+	if f.size > 0 && len(f.pending) >= f.size {
+		f.flushLocked(ctx)
+	}
+}
+
+// flush sends every staged result that has not been sent before. A
+// failed flush is logged rather than returned: the caller's final
+// WriteResults still persists the full in-memory cache.
+func (f *progressFlusher) flush(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flushLocked(ctx)
+}
+
+// flushLocked is flush with f.mu held.
+func (f *progressFlusher) flushLocked(ctx context.Context) {
+	batch := make([]ghscan.Result, 0, len(f.pending))
+	for _, r := range f.pending {
+		key := ghscan.Fingerprint(r)
+		sent := f.sent[r.Repository]
+		if _, dup := sent[key]; dup {
+			continue
+		}
+		if sent == nil {
+			sent = make(map[string]struct{})
+			f.sent[r.Repository] = sent
+		}
+		sent[key] = struct{}{}
+		batch = append(batch, r)
+	}
+	f.pending = nil
+	if len(batch) == 0 {
+		return
+	}
+	ghscan.AddFingerprints(batch)
+	if err := f.sink.Append(ctx, batch); err != nil {
+		f.logger.Warnf("Failed to flush %d results: %v", len(batch), err)
+	}
+}
+
+// finishRepo stages a repository's final, deduplicated results and
+// flushes immediately. Results already sent by a periodic flush are
+// not sent again; a log result sent before DedupResults folded it into
+// a YAML result remains in the incremental outputs until the final
+// consolidation rewrites them. The repository is then done, so the
+// record of its sent results is dropped.
+func (f *progressFlusher) finishRepo(ctx context.Context, results []ghscan.Result) {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pending = append(f.pending, results...)
+	f.flushLocked(ctx)
+	for _, r := range results {
+		delete(f.sent, r.Repository)
+	}
+}
+
+// run flushes staged results every interval until ctx is done. A
+// non-positive interval disables the periodic flush.
+func (f *progressFlusher) run(ctx context.Context, interval time.Duration) {
+	if f == nil || interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			f.flush(ctx)
+		}
+	}
+}
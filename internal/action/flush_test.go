@@ -0,0 +1,177 @@
+package action_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// TestProgressFlusher_SendsEachResultOnce asserts results staged and
+// flushed mid-repository are not re-sent when the repository finishes.
+func TestProgressFlusher_SendsEachResultOnce(t *testing.T) {
+	t.Parallel()
+
+	a := ghscan.Result{Repository: "o/r", LineData: "a"}
+	b := ghscan.Result{Repository: "o/r", LineData: "b"}
+
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 0)
+	f.Add(t.Context(), []ghscan.Result{a})
+	f.Flush(t.Context())
+	f.Flush(t.Context())
+	f.FinishRepo(t.Context(), []ghscan.Result{a, b})
+
+	if len(sink.batches) != 2 {
+		t.Fatalf("sink batches=%d, want 2: %+v", len(sink.batches), sink.batches)
+	}
+	if len(sink.batches[0]) != 1 || sink.batches[0][0].LineData != "a" {
+		t.Fatalf("first batch=%+v, want [a]", sink.batches[0])
+	}
+	if len(sink.batches[1]) != 1 || sink.batches[1][0].LineData != "b" {
+		t.Fatalf("second batch=%+v, want [b]", sink.batches[1])
+	}
+}
+
+// TestProgressFlusher_StampedFinishNotResent asserts a result sent
+// unstamped by a mid-repository flush is not sent again when
+// finishRepo receives its stamped copy, which carries a ScanID and
+// Fingerprint.
+func TestProgressFlusher_StampedFinishNotResent(t *testing.T) {
+	t.Parallel()
+
+	a := ghscan.Result{Repository: "o/r", WorkflowFileName: "ci.yml", LineData: "a"}
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 0)
+	f.Add(t.Context(), []ghscan.Result{a})
+	f.Flush(t.Context())
+
+	stamped := []ghscan.Result{a}
+	(&ghscan.Request{ScanID: "scan-1"}).Stamp(stamped)
+	if stamped[0].ScanID == "" || stamped[0].Fingerprint == "" {
+		t.Fatalf("Stamp left %+v unstamped", stamped[0])
+	}
+	f.FinishRepo(t.Context(), stamped)
+
+	if len(sink.batches) != 1 {
+		t.Fatalf("sink batches=%d, want 1: %+v", len(sink.batches), sink.batches)
+	}
+}
+
+// TestProgressFlusher_FinishRepoForgetsSent asserts a finished
+// repository's sent results are no longer held, while a repository
+// still being scanned keeps its own.
+func TestProgressFlusher_FinishRepoForgetsSent(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 0)
+	f.Add(t.Context(), []ghscan.Result{{Repository: "o/a", LineData: "a"}, {Repository: "o/b", LineData: "b"}})
+	f.Flush(t.Context())
+	if got := f.Tracked(); got != 2 {
+		t.Fatalf("tracked repositories=%d, want 2", got)
+	}
+
+	f.FinishRepo(t.Context(), []ghscan.Result{{Repository: "o/a", LineData: "a"}})
+	if got := f.Tracked(); got != 1 {
+		t.Fatalf("tracked repositories after finishing o/a=%d, want 1", got)
+	}
+	if len(sink.batches) != 1 {
+		t.Fatalf("sink batches=%d, want 1: %+v", len(sink.batches), sink.batches)
+	}
+}
+
+// TestProgressFlusher_RunFlushesPeriodically asserts staged results
+// reach the sink on the ticker without waiting for the repository to
+// finish.
+func TestProgressFlusher_RunFlushesPeriodically(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 0)
+	f.Add(t.Context(), []ghscan.Result{{Repository: "o/r", LineData: "a"}})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Run(ctx, 10*time.Millisecond)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sink.mu.Lock()
+		n := len(sink.batches)
+		sink.mu.Unlock()
+		if n == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("sink batches=%d after deadline, want 1", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestProgressFlusher_FlushesAtSize asserts add flushes once the
+// pending count reaches the size, including when one batch jumps past
+// it, and holds smaller batches for the next flush.
+func TestProgressFlusher_FlushesAtSize(t *testing.T) {
+	t.Parallel()
+
+	r := func(s string) ghscan.Result { return ghscan.Result{Repository: "o/r", LineData: s} }
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 3)
+
+	f.Add(t.Context(), []ghscan.Result{r("a"), r("b")})
+	if len(sink.batches) != 0 {
+		t.Fatalf("sink batches=%d below size, want 0", len(sink.batches))
+	}
+	f.Add(t.Context(), []ghscan.Result{r("c"), r("d")})
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 4 {
+		t.Fatalf("sink batches=%+v, want one batch of 4", sink.batches)
+	}
+	f.Add(t.Context(), []ghscan.Result{r("e")})
+	if len(sink.batches) != 1 {
+		t.Fatalf("sink batches=%d after a flush reset, want 1", len(sink.batches))
+	}
+}
+
+// TestProgressFlusher_IrregularBatches asserts the size trigger counts
+// results pending since the last flush, so batches whose running total
+// never lands on a multiple of the size still flush at the expected
+// rate.
+func TestProgressFlusher_IrregularBatches(t *testing.T) {
+	t.Parallel()
+
+	sink := &recordingSink{}
+	f := action.NewProgressFlusherForTest(newSilentLogger(), sink, 10)
+	n := 0
+	for _, size := range []int{3, 5, 5, 4, 8, 2} {
+		batch := make([]ghscan.Result, size)
+		for i := range batch {
+			n++
+			batch[i] = ghscan.Result{Repository: "o/r", LineData: strconv.Itoa(n)}
+		}
+		f.Add(t.Context(), batch)
+	}
+
+	// 3+5+5 crosses 10 without landing on it, then 4+8 does again; the
+	// trailing 2 stay pending.
+	want := []int{13, 12}
+	if len(sink.batches) != len(want) {
+		t.Fatalf("sink batches=%d, want %d", len(sink.batches), len(want))
+	}
+	for i, w := range want {
+		if len(sink.batches[i]) != w {
+			t.Fatalf("batch %d has %d results, want %d", i, len(sink.batches[i]), w)
+		}
+	}
+}
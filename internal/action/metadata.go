@@ -0,0 +1,171 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+	"github.com/spf13/viper"
+)
+
+const (
+	// scanCommitMessagesKey runs the log detectors over the message of
+	// each run's head commit, which the run listing already carries.
+	// Defaults to false.
+	scanCommitMessagesKey = "scan_commit_messages"
+	// scanPRBodiesKey runs the log detectors over the title and body of
+	// the pull requests each run belongs to. Defaults to false because
+	// it costs one API call per pull request.
+	scanPRBodiesKey = "scan_pr_bodies"
+
+	// commitMessageSource and prBodySource label the findings of the
+	// metadata scan.
+	commitMessageSource = "commit-message"
+	prBodySource        = "pr-body"
+)
+
+// runMetadata scans the text that comes with one workflow's runs
+// rather than their logs: head commit messages and pull request titles
+// and bodies, where an injected payload can also hide. Each commit and
+// pull request is scanned once however many runs share it.
+type runMetadata struct {
+	logger     *clog.Logger
+	req        *ghscan.Request
+	breaker    *request.Breaker
+	maxRetries int
+	wfFileName string
+	wfPath     string
+	commits    bool
+	prBodies   bool
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// newRunMetadata returns the metadata scanner for one workflow, or nil
+// when scan_commit_messages and scan_pr_bodies are both off.
+func newRunMetadata(logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, wfFileName, wfPath string) *runMetadata {
+	commits, prBodies := viper.GetBool(scanCommitMessagesKey), viper.GetBool(scanPRBodiesKey)
+	if !commits && !prBodies {
+		return nil
+	}
+	return &runMetadata{
+		logger:     logger,
+		req:        req,
+		breaker:    breaker,
+		maxRetries: maxRetries,
+		wfFileName: wfFileName,
+		wfPath:     wfPath,
+		commits:    commits,
+		prBodies:   prBodies,
+		seen:       make(map[string]bool),
+	}
+}
+
+// scan returns the findings in run's head commit message and pull
+// requests that an earlier run of the workflow has not already
+// reported. A nil receiver returns nil.
+func (m *runMetadata) scan(ctx context.Context, run *github.WorkflowRun) []ghscan.Result {
+	if m == nil {
+		return nil
+	}
+	runURL := fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d", m.req.Owner, m.req.RepoName, run.GetID())
+
+	var out []ghscan.Result
+	if commit := run.GetHeadCommit(); m.commits && commit.GetMessage() != "" && m.first("commit:"+run.GetHeadSHA()) {
+		out = append(out, m.results(run, commit.GetMessage(), runURL, commitMessageSource,
+			fmt.Sprintf("head commit message of run %d", run.GetID()))...)
+	}
+	if !m.prBodies {
+		return out
+	}
+	for _, pr := range m.pullRequests(ctx, run) {
+		if !m.first(fmt.Sprintf("pr:%d", pr.GetNumber())) {
+			continue
+		}
+		// The pull requests listed on a run carry only their number
+		// and branches.
+		if pr.Title == nil && pr.Body == nil {
+			if pr = m.fetchPullRequest(ctx, pr.GetNumber()); pr == nil {
+				continue
+			}
+		}
+		text := strings.TrimSpace(pr.GetTitle() + "\n" + pr.GetBody())
+		out = append(out, m.results(run, text, runURL, prBodySource,
+			fmt.Sprintf("title or body of pull request %s", pr.GetHTMLURL()))...)
+	}
+	return out
+}
+
+// results runs the log detectors over text and labels the findings.
+func (m *runMetadata) results(run *github.WorkflowRun, text, runURL, source, where string) []ghscan.Result {
+	findings, found := wf.ParseLogsWithOptions(m.logger, text, run.GetID(), m.req.IOC, m.req.LogOptions)
+	if !found {
+		return nil
+	}
+	results := logResults(m.req, run, findings, m.wfFileName, m.wfPath, runURL, nil)
+	for i := range results {
+		results[i].Source = source
+		results[i].CommitSHA = run.GetHeadSHA()
+		if results[i].Note == "" {
+			results[i].Note = where
+		} else {
+			results[i].Note = where + "; " + results[i].Note
+		}
+	}
+	return results
+}
+
+// first reports whether key is seen for the first time.
+func (m *runMetadata) first(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.seen[key] {
+		return false
+	}
+	m.seen[key] = true
+	return true
+}
+
+// pullRequests returns the pull requests run belongs to. GitHub lists
+// them on the run only when the head branch is in the repository
+// itself, so a pull request run without any is looked up by its head
+// commit, which also finds pull requests from forks.
+func (m *runMetadata) pullRequests(ctx context.Context, run *github.WorkflowRun) []*github.PullRequest {
+	if len(run.PullRequests) > 0 || !strings.HasPrefix(run.GetEvent(), "pull_request") || run.GetHeadSHA() == "" {
+		return run.PullRequests
+	}
+	var prs []*github.PullRequest
+	err := m.breaker.WithRetryN(ctx, m.logger, m.maxRetries, func() error {
+		var err error
+		prs, _, err = m.req.Client().PullRequests.ListPullRequestsWithCommit(ctx, m.req.Owner, m.req.RepoName, run.GetHeadSHA(), nil)
+		return permanentIfNotFound(err)
+	})
+	if err != nil {
+		m.logger.Warnf("Skipping pull requests of run %d in %s/%s: %v", run.GetID(), m.req.Owner, m.req.RepoName, err)
+		return nil
+	}
+	return prs
+}
+
+// fetchPullRequest returns pull request number, or nil when it cannot
+// be fetched. Failures are logged.
+func (m *runMetadata) fetchPullRequest(ctx context.Context, number int) *github.PullRequest {
+	var pr *github.PullRequest
+	err := m.breaker.WithRetryN(ctx, m.logger, m.maxRetries, func() error {
+		var err error
+		pr, _, err = m.req.Client().PullRequests.Get(ctx, m.req.Owner, m.req.RepoName, number)
+		return permanentIfNotFound(err)
+	})
+	if err != nil {
+		m.logger.Warnf("Skipping pull request #%d in %s/%s: %v", number, m.req.Owner, m.req.RepoName, err)
+		return nil
+	}
+	return pr
+}
@@ -0,0 +1,134 @@
+package action
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+	"github.com/spf13/viper"
+)
+
+// pauseOnRateLimitKey holds new repositories, workflows, and runs back
+// until the core rate limit resets once its budget is all but spent,
+// instead of letting their requests fail. Defaults to false.
+const pauseOnRateLimitKey = "pause_on_rate_limit"
+
+const (
+	// ratePauseFloor is the remaining core request count at or below
+	// which new work waits for the reset. It leaves the operations
+	// already in flight a few requests to finish with.
+	ratePauseFloor = 10
+	// ratePauseSkew is added to the reported reset so work resumes
+	// after GitHub has actually refilled the budget.
+	ratePauseSkew = time.Second
+)
+
+// ratePauser holds new work back while the core rate-limit budget is
+// exhausted. The budget observed in response headers is checked on
+// every wait; only when it is at or below ratePauseFloor is GitHub's
+// rate-limit endpoint, which costs no quota, asked for the current
+// budget and reset, and every waiter then sleeps until that reset.
+// Work already in flight is not interrupted.
+type ratePauser struct {
+	logger *clog.Logger
+	budget *httpclient.RateBudget
+	limits func(context.Context) (*github.Rate, error)
+	now    func() time.Time
+	sleep  func(context.Context, time.Duration) error
+
+	mu sync.Mutex
+	// resumeAt is the end of the current pause, if one is under way.
+	resumeAt time.Time
+}
+
+// newRatePauser returns a pauser watching budget and confirming with
+// req's GitHub client, or nil when there is no budget to observe. The
+// nil pauser never waits.
+func newRatePauser(logger *clog.Logger, req *ghscan.Request, budget *httpclient.RateBudget) *ratePauser {
+	if budget == nil {
+		return nil
+	}
+	return &ratePauser{
+		logger: logger,
+		budget: budget,
+		limits: func(ctx context.Context) (*github.Rate, error) {
+			limits, _, err := req.Client().RateLimit.Get(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return limits.GetCore(), nil
+		},
+		now:   time.Now,
+		sleep: sleepCtx,
+	}
+}
+
+// resolveRatePauser returns the pauser Scan and ScanRunRefs share, or
+// nil when pause_on_rate_limit is off.
+func resolveRatePauser(logger *clog.Logger, req *ghscan.Request) *ratePauser {
+	if !viper.GetBool(pauseOnRateLimitKey) {
+		return nil
+	}
+	p := newRatePauser(logger, req, req.HTTPClient().RateBudget())
+	if p == nil {
+		logger.Warnf("pause_on_rate_limit needs an HTTP client with a rate budget; rate-limited requests will be retried instead")
+	}
+	return p
+}
+
+// wait returns once the core budget has room for new work, sleeping
+// until the reset when it does not. It returns ctx's error if ctx ends
+// first.
+func (p *ratePauser) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	b, ok := p.budget.Current()
+	if !ok || b.Remaining > ratePauseFloor || !b.Reset.After(p.now()) {
+		return nil
+	}
+
+	p.mu.Lock()
+	now := p.now()
+	if !p.resumeAt.After(now) {
+		// No pause is under way: this waiter decides whether one
+		// starts, and the others queue on mu until it has.
+		resumeAt := b.Reset
+		rate, err := p.limits(ctx)
+		switch {
+		case err != nil:
+			p.logger.Debugf("Checking the rate limit failed, pausing until the observed reset: %v", err)
+		case rate == nil || rate.Remaining > ratePauseFloor:
+			p.mu.Unlock()
+			return nil
+		default:
+			resumeAt = rate.Reset.Time
+			b.Remaining, b.Limit = rate.Remaining, rate.Limit
+		}
+		if limit := now.Add(rateLimitWindow); resumeAt.After(limit) {
+			resumeAt = limit
+		}
+		p.resumeAt = resumeAt.Add(ratePauseSkew)
+		p.logger.Warnf("Core rate limit exhausted (%d of %d requests left); pausing new work for %s until %s",
+			b.Remaining, b.Limit, p.resumeAt.Sub(now).Round(time.Second), p.resumeAt.Format(time.RFC3339))
+	}
+	d := p.resumeAt.Sub(now)
+	p.mu.Unlock()
+	return p.sleep(ctx, d)
+}
+
+// sleepCtx sleeps for d, returning ctx's error early if ctx ends.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package action_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestRatePauser pins when new work is held back: only once the
+// observed budget is at or below the floor and the rate-limit endpoint
+// confirms it, until the reported reset (capped at an hour), and
+// falling back to the observed reset when the endpoint fails.
+func TestRatePauser(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name      string
+		observed  int
+		reset     time.Duration
+		rate      *github.Rate
+		rateErr   error
+		wantCheck bool
+		wantSleep time.Duration
+	}{
+		{name: "budget left", observed: 11, reset: time.Minute},
+		{name: "observed window already reset", observed: 0, reset: -time.Second},
+		{
+			name: "endpoint reports a fresh budget", observed: 3, reset: time.Minute,
+			rate:      &github.Rate{Limit: 5000, Remaining: 4999, Reset: github.Timestamp{Time: now.Add(time.Hour)}},
+			wantCheck: true,
+		},
+		{
+			name: "exhausted until the reported reset", observed: 10, reset: time.Minute,
+			rate:      &github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: now.Add(2 * time.Minute)}},
+			wantCheck: true, wantSleep: 2*time.Minute + time.Second,
+		},
+		{
+			name: "reset capped at the window", observed: 0, reset: time.Minute,
+			rate:      &github.Rate{Limit: 5000, Remaining: 0, Reset: github.Timestamp{Time: now.Add(3 * time.Hour)}},
+			wantCheck: true, wantSleep: time.Hour + time.Second,
+		},
+		{
+			name: "endpoint fails", observed: 0, reset: 5 * time.Minute, rateErr: errors.New("boom"),
+			wantCheck: true, wantSleep: 5*time.Minute + time.Second,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			budget := new(httpclient.RateBudget)
+			h := http.Header{}
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(tc.observed))
+			h.Set("X-RateLimit-Limit", "5000")
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(tc.reset).Unix(), 10))
+			budget.Observe(h)
+
+			var checked bool
+			var slept time.Duration
+			p := action.NewRatePauserForTest(newSilentLogger(), budget, now,
+				func(context.Context) (*github.Rate, error) {
+					checked = true
+					return tc.rate, tc.rateErr
+				},
+				func(_ context.Context, d time.Duration) error {
+					slept = d
+					return nil
+				})
+			if err := p.Wait(t.Context()); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			if checked != tc.wantCheck {
+				t.Fatalf("rate-limit endpoint checked=%v, want %v", checked, tc.wantCheck)
+			}
+			if slept != tc.wantSleep {
+				t.Fatalf("slept %s, want %s", slept, tc.wantSleep)
+			}
+		})
+	}
+}
+
+// TestRatePauser_SharesOnePause asserts concurrent waiters under an
+// exhausted budget check the rate-limit endpoint once and all sleep
+// until the same reset.
+func TestRatePauser_SharesOnePause(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	budget := new(httpclient.RateBudget)
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "0")
+	h.Set("X-RateLimit-Limit", "5000")
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(now.Add(time.Minute).Unix(), 10))
+	budget.Observe(h)
+
+	var checks atomic.Int32
+	var mu sync.Mutex
+	var sleeps []time.Duration
+	p := action.NewRatePauserForTest(newSilentLogger(), budget, now,
+		func(context.Context) (*github.Rate, error) {
+			checks.Add(1)
+			return &github.Rate{Limit: 5000, Reset: github.Timestamp{Time: now.Add(time.Minute)}}, nil
+		},
+		func(_ context.Context, d time.Duration) error {
+			mu.Lock()
+			sleeps = append(sleeps, d)
+			mu.Unlock()
+			return nil
+		})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if err := p.Wait(t.Context()); err != nil {
+				t.Errorf("Wait: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if got := checks.Load(); got != 1 {
+		t.Fatalf("rate-limit endpoint checked %d times, want 1", got)
+	}
+	for _, d := range sleeps {
+		if d != time.Minute+time.Second {
+			t.Fatalf("sleeps=%v, want every waiter to sleep until the reset", sleeps)
+		}
+	}
+	if len(sleeps) != 8 {
+		t.Fatalf("%d waiters slept, want 8", len(sleeps))
+	}
+}
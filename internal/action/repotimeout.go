@@ -0,0 +1,135 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// repoTimeoutKey fixes how long one repository's scan may run. Zero,
+// the default, sizes the budget to the repository instead: it starts
+// at repo_enum_budget and grows with the workflows and runs found.
+const repoTimeoutKey = "repo_timeout"
+
+// errRepoTimeout is the cause a repository's context is canceled with
+// once its budget is spent.
+var errRepoTimeout = errors.New("repository scan budget exhausted")
+
+// repoBudget bounds the scan of one repository. Unless repo_timeout
+// fixes it, the budget grows as work is found: a repository with
+// thousands of workflows gets the time they need, while a small one
+// gives up its concurrency slot soon after a stalled request. Time
+// spent held, such as waiting out a rate-limit pause, is not counted.
+// A nil *repoBudget never expires.
+type repoBudget struct {
+	cancel context.CancelCauseFunc
+	fixed  bool
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	granted  time.Duration
+	holds    int
+	// held reports the timer was stopped by hold; left is the budget
+	// that remained then.
+	held bool
+	left time.Duration
+}
+
+// newRepoBudget returns a context that is canceled with errRepoTimeout
+// once the repository's budget is spent, and the budget bounding it.
+// The caller must call stop when the repository is done.
+func newRepoBudget(ctx context.Context, opTimeout time.Duration) (context.Context, *repoBudget) {
+	b := &repoBudget{granted: resolveDuration(repoTimeoutKey, 0)}
+	b.fixed = b.granted > 0
+	if !b.fixed {
+		b.granted = resolveDuration(repoEnumBudgetKey, opTimeout*5)
+	}
+	ctx, b.cancel = context.WithCancelCause(ctx)
+	b.deadline = time.Now().Add(b.granted)
+	b.timer = time.AfterFunc(b.granted, func() { b.cancel(errRepoTimeout) })
+	return ctx, b
+}
+
+// stop releases the budget's resources.
+func (b *repoBudget) stop() {
+	if b == nil {
+		return
+	}
+	b.timer.Stop()
+	b.cancel(nil)
+}
+
+// extend grows the budget by d, unless repo_timeout fixed it or it is
+// already spent.
+func (b *repoBudget) extend(d time.Duration) {
+	if b == nil || b.fixed || d <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.held:
+		b.left += d
+	case b.timer.Stop():
+		b.deadline = b.deadline.Add(d)
+		b.timer.Reset(time.Until(b.deadline))
+	default:
+		return
+	}
+	b.granted += d
+}
+
+// total returns the budget granted so far.
+func (b *repoBudget) total() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.granted
+}
+
+// hold stops the clock until every hold is released.
+func (b *repoBudget) hold() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holds++
+	if b.holds == 1 && b.timer.Stop() {
+		b.held = true
+		b.left = time.Until(b.deadline)
+	}
+}
+
+// release undoes a hold, restarting the clock after the last one.
+func (b *repoBudget) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holds--
+	if b.holds == 0 && b.held {
+		b.held = false
+		b.deadline = time.Now().Add(b.left)
+		b.timer.Reset(b.left)
+	}
+}
+
+// wait is pause.wait with the clock held, so a rate-limit pause does
+// not spend the repository's budget.
+func (b *repoBudget) wait(ctx context.Context, pause *ratePauser) error {
+	if pause == nil {
+		return nil
+	}
+	b.hold()
+	defer b.release()
+	return pause.wait(ctx)
+}
+
+// batches returns how many rounds of fanOutLimit concurrent operations
+// n operations take.
+func batches(n int) time.Duration {
+	return time.Duration((n + fanOutLimit - 1) / fanOutLimit)
+}
@@ -0,0 +1,220 @@
+package action
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxRunRefBytes bounds one line of a runs file. A reference is a few
+// dozen bytes; the limit only guards against a stream that is not
+// NDJSON at all.
+const maxRunRefBytes = 64 * 1024
+
+// RunRef names one GitHub Actions run to scan. A runs file holds one
+// JSON-encoded RunRef per line.
+type RunRef struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	RunID int64  `json:"run_id"`
+}
+
+// parseRunRef decodes and validates one runs file line.
+func parseRunRef(line []byte) (RunRef, error) {
+	var ref RunRef
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ref); err != nil {
+		return RunRef{}, fmt.Errorf("decoding run reference: %w", err)
+	}
+	switch {
+	case ref.Owner == "" || ref.Repo == "":
+		return RunRef{}, fmt.Errorf("run reference needs owner and repo")
+	case ref.RunID <= 0:
+		return RunRef{}, fmt.Errorf("run reference needs a positive run_id")
+	}
+	return ref, nil
+}
+
+// ScanRunRefs scans the logs of exactly the runs listed in r, one
+// [RunRef] per line, without searching for workflows or listing runs,
+// so ghscan can work through runs flagged elsewhere. r is read as the
+// scan proceeds, so it may be arbitrarily large or a stream, and each
+// run's findings are sent to req.Sink as soon as that run is scanned.
+// Blank lines are skipped; a malformed line fails the scan like a
+// failed run, so best_effort skips it. Only the log detectors run, as
+// in [ScanSource], and the request's time window, conclusion filter,
+// and cache of processed workflows do not apply.
+func ScanRunRefs(ctx context.Context, logger *clog.Logger, req *ghscan.Request, r io.Reader) error {
+	if req == nil {
+		return fmt.Errorf("req cannot be nil")
+	}
+	if r == nil {
+		return fmt.Errorf("r cannot be nil")
+	}
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+
+	maxRetries := resolveMaxRetries()
+	runBudget := resolveDuration(runScanBudgetKey, cmp.Or(req.Timeout, viper.GetDuration("operation_timeout")))
+	breaker := request.NewBreaker(resolveBreakerThreshold())
+	pause := resolveRatePauser(logger, req)
+	failed := newFailures()
+	progress := newProgressFlusher(logger, req.Sink, 0)
+
+	var resultsMu sync.Mutex
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fanOutLimit)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxRunRefBytes)
+	lineNum, queued := 0, 0
+	for scanner.Scan() && gCtx.Err() == nil {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		ref, err := parseRunRef(line)
+		if err != nil {
+			err := &ScanError{Category: ErrorParse, Op: fmt.Sprintf("reading runs file line %d", lineNum), Err: err}
+			g.Go(func() error { return failed.run(gCtx, err) })
+			continue
+		}
+		queued++
+		g.Go(func() error {
+			if err := pause.wait(gCtx); err != nil {
+				return err
+			}
+			runCtx, runCancel := gCtx, context.CancelFunc(func() {})
+			if runBudget > 0 {
+				runCtx, runCancel = context.WithTimeout(gCtx, runBudget)
+			}
+			defer runCancel()
+
+			results, err := scanRunRef(runCtx, logger, req, breaker, maxRetries, ref)
+			if err != nil {
+				return failed.run(gCtx, err)
+			}
+			if len(results) == 0 {
+				return nil
+			}
+			req.Stamp(results)
+			resultsMu.Lock()
+			req.Cache.Results = append(req.Cache.Results, results...)
+			resultsMu.Unlock()
+			progress.finishRepo(ctx, results)
+			return nil
+		})
+	}
+	err := g.Wait()
+	if serr := scanner.Err(); serr != nil && err == nil {
+		err = fmt.Errorf("reading runs file after line %d: %w", lineNum, serr)
+	}
+	logger.Infof("Scanned %d runs from the runs file", queued)
+	if err != nil {
+		return err
+	}
+	return failed.report(logger)
+}
+
+// scanRunRef downloads and scans one referenced run. A run that is
+// unfinished, has no logs, or whose logs expired or are withheld from
+// forks is logged and yields no results rather than an error.
+func scanRunRef(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, ref RunRef) ([]ghscan.Result, error) {
+	repository := ref.Owner + "/" + ref.Repo
+
+	var run *github.WorkflowRun
+	err := breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var (
+			resp *github.Response
+			err  error
+		)
+		run, resp, err = req.Client().Actions.GetWorkflowRunByID(ctx, ref.Owner, ref.Repo, ref.RunID)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return request.Permanent(err)
+		}
+		return err
+	})
+	var uerr *request.UnavailableError
+	switch {
+	case errors.As(err, &uerr):
+		logger.Warnf("Skipping run %d in %s: %s", ref.RunID, repository, uerr.Reason)
+		req.Coverage.AddSkippedRepo(repository, uerr.Reason)
+		return nil, nil
+	case err != nil:
+		return nil, newScanError(ctx, "retrieving run", repository, "", ref.RunID, err)
+	}
+
+	var rc io.ReadCloser
+	err = breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var err error
+		rc, err = wf.GetLogsWithOptions(ctx, logger, req.HTTPClient(), req.Client(), ref.Owner, ref.Repo, ref.RunID, req.Token, req.LogOptions)
+		if errors.Is(err, wf.ErrRunHasNoLogs) {
+			return request.Permanent(err)
+		}
+		return err
+	})
+	switch {
+	case errors.Is(err, wf.ErrRunHasNoLogs), errors.Is(err, wf.ErrRunNotCompleted), errors.Is(err, wf.ErrForkLogsRestricted):
+		logger.Infof("Skipping run %d in %s: %v", ref.RunID, repository, err)
+		return nil, nil
+	case errors.Is(err, wf.ErrLogsExpired):
+		req.Coverage.AddExpiredRuns(1)
+		logger.Warnf("Skipping run %d in %s: %v", ref.RunID, repository, err)
+		return nil, nil
+	case err != nil:
+		return nil, newScanError(ctx, "downloading logs", repository, "", ref.RunID, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	logText, err := wf.ExtractLogsContext(ctx, rc)
+	if err != nil {
+		return nil, newScanError(ctx, "extracting logs", repository, "", ref.RunID, err)
+	}
+	findings, found := wf.ParseLogsWithOptions(logger, logText, ref.RunID, req.IOC, req.LogOptions)
+	if req.Logs != nil {
+		if err := req.Logs.StoreLog(ctx, repository, ref.RunID, logText, found); err != nil {
+			logger.Warnf("Failed to keep log for run %d in %s: %v", ref.RunID, repository, err)
+		}
+	}
+	if !found || len(findings) == 0 {
+		return nil, nil
+	}
+
+	var wfFileName, workflowUIURL string
+	if wfPath := run.GetPath(); wfPath != "" {
+		wfFileName = filepath.Base(wfPath)
+		workflowUIURL = fmt.Sprintf("https://github.com/%s/actions/workflows/%s", repository, url.PathEscape(wfPath))
+	}
+	results := make([]ghscan.Result, 0, len(findings))
+	for _, finding := range findings {
+		res := resultFromFinding(repository, req.IOC.GetName(), finding)
+		res.WorkflowFileName = wfFileName
+		res.WorkflowURL = workflowUIURL
+		res.WorkflowRunURL = fmt.Sprintf("https://github.com/%s/actions/runs/%d", repository, ref.RunID)
+		res.RunStatus = run.GetStatus()
+		res.RunConclusion = run.GetConclusion()
+		res.FromFork = wf.IsForkRun(run)
+		results = append(results, res)
+	}
+	return results, nil
+}
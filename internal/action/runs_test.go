@@ -0,0 +1,79 @@
+package action_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/spf13/viper"
+)
+
+// TestScanRunRefs asserts the runs named in an NDJSON stream are scanned
+// directly, without code search or run listing, that findings reach
+// the sink per run, and that malformed lines and unknown runs fail the
+// scan unless best_effort skips them.
+func TestScanRunRefs(t *testing.T) {
+	cases := []struct {
+		name        string
+		bestEffort  bool
+		runs        string
+		wantErr     string
+		wantResults int
+	}{
+		{name: "one run", runs: "\n{\"owner\":\"octo\",\"repo\":\"demo\",\"run_id\":99}\n\n", wantResults: 1},
+		{name: "malformed line strict", runs: "{\"owner\":\"octo\",\"repo\":\"demo\",\"run_id\":99}\n{\"owner\":\"octo\"}\n", wantErr: "runs file line 2"},
+		{name: "unknown field strict", runs: "{\"owner\":\"octo\",\"repo\":\"demo\",\"run\":99}\n", wantErr: "unknown field"},
+		{name: "malformed line best effort", bestEffort: true, runs: "not json\n{\"owner\":\"octo\",\"repo\":\"demo\",\"run_id\":99}\n", wantErr: action.ErrIncompleteScan.Error(), wantResults: 1},
+		{name: "unknown run best effort", bestEffort: true, runs: "{\"owner\":\"octo\",\"repo\":\"demo\",\"run_id\":7}\n{\"owner\":\"octo\",\"repo\":\"demo\",\"run_id\":99}\n", wantErr: action.ErrIncompleteScan.Error(), wantResults: 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("max_retries", 1)
+			viper.Set("best_effort", tc.bestEffort)
+			t.Cleanup(viper.Reset)
+
+			srv := fakeGitHub(t, "octo", "demo", ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			gh, hc := newTestClients(t, srv)
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			sink := &recordingSink{}
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				IOC:           customIOC,
+				Sink:          sink,
+				Token:         "tok",
+			})
+
+			err = action.ScanRunRefs(t.Context(), newSilentLogger(), req, strings.NewReader(tc.runs))
+			switch {
+			case tc.wantErr == "" && err != nil:
+				t.Fatalf("ScanRunRefs() error = %v", err)
+			case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+				t.Fatalf("ScanRunRefs() error = %v, want substring %q", err, tc.wantErr)
+			case tc.bestEffort && !errors.Is(err, action.ErrIncompleteScan):
+				t.Fatalf("ScanRunRefs() error = %v, want ErrIncompleteScan", err)
+			}
+			if tc.wantErr != "" && !tc.bestEffort {
+				return
+			}
+			if len(req.Cache.Results) != tc.wantResults {
+				t.Fatalf("results = %+v, want %d", req.Cache.Results, tc.wantResults)
+			}
+			got := req.Cache.Results[0]
+			if got.Repository != "octo/demo" || got.WorkflowRunURL != "https://github.com/octo/demo/actions/runs/99" || got.RunConclusion != "success" {
+				t.Fatalf("result = %+v, want it attributed to run 99 of octo/demo", got)
+			}
+			if len(sink.batches) != 1 || len(sink.batches[0]) != 1 {
+				t.Fatalf("sink batches = %+v, want the finding flushed once", sink.batches)
+			}
+		})
+	}
+}
@@ -1,13 +1,19 @@
 package action
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/url"
+	"path"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chainguard-dev/clog"
@@ -43,8 +49,219 @@ const (
 	scanYAMLKey = "scan_yaml"
 	// scanLogsKey enables the log-scanning path. Defaults to true.
 	scanLogsKey = "scan_logs"
+	// scanHistoryKey enables the workflow commit-history path. Unlike
+	// the YAML and log paths it defaults to false because it costs one
+	// extra API call per commit touching .github/workflows.
+	scanHistoryKey = "scan_history"
+	// modifiedOnlyKey restricts log scanning to workflows whose file a
+	// commit in the request's window touched, and turns on the history
+	// path so the change itself is reported. Defaults to false.
+	modifiedOnlyKey = "modified_only"
+	// scanSummariesKey enables scanning each job's check-run summary.
+	// Defaults to false because it costs one API call per job.
+	scanSummariesKey = "scan_summaries"
+	// repoStaggerKey bounds a random delay taken before each
+	// repository's work begins. Zero (the default) disables it.
+	repoStaggerKey = "repo_stagger"
+	// searchQueryTemplateKey overrides the code-search query used to
+	// discover workflow files. Defaults to DefaultSearchQueryTemplate.
+	searchQueryTemplateKey = "search_query_template"
+	// conclusionsKey restricts which runs have their logs scanned; see
+	// FilterRuns. Empty (the default) scans every run.
+	conclusionsKey = "conclusions"
+	// breakerThresholdKey is the number of consecutive failed
+	// operations after which a repository's remaining operations are
+	// skipped. Defaults to DefaultBreakerThreshold; zero disables it.
+	breakerThresholdKey = "circuit_breaker_threshold"
+	// latestOnlyKey keeps only the newest run of each workflow; see
+	// LatestRun. Defaults to false.
+	latestOnlyKey = "latest_only"
+	// allRunsKey lists every run of each workflow, ignoring the
+	// request's time window; see wf.ListAllWorkflowRuns. Defaults to
+	// false.
+	allRunsKey = "all_runs"
+	// orgWorkflowKey names the one workflow file scanned in every
+	// repository, replacing the workflow file search; see
+	// OrgWorkflowPath. Empty (the default) scans every workflow.
+	orgWorkflowKey = "org_workflow"
 )
 
+// DefaultSearchQueryTemplate is the code-search query used to find
+// workflow files in a repository. {owner} and {repo} are replaced per
+// repository. GitHub's YAML language classification covers both the
+// .yml and .yaml extensions.
+const DefaultSearchQueryTemplate = "repo:{owner}/{repo} path:.github/workflows language:YAML"
+
+// RenderSearchQuery substitutes owner and repo into tmpl.
+func RenderSearchQuery(tmpl, owner, repo string) string {
+	return strings.NewReplacer("{owner}", owner, "{repo}", repo).Replace(tmpl)
+}
+
+// ValidateSearchQueryTemplate reports an error when tmpl would render
+// to an empty query, which GitHub rejects for every repository.
+func ValidateSearchQueryTemplate(tmpl string) error {
+	if strings.TrimSpace(RenderSearchQuery(tmpl, "", "")) == "" {
+		return fmt.Errorf("search query template %q renders an empty query", tmpl)
+	}
+	return nil
+}
+
+// workflowsDir is the only directory Actions loads workflows from.
+const workflowsDir = ".github/workflows"
+
+// OrgWorkflowPath returns the repository path of the workflow named by
+// org_workflow: a bare file name such as publish.yml is looked up in
+// .github/workflows, and a full .github/workflows/ path is kept. Names
+// that do not resolve to a .yml or .yaml file directly in that
+// directory are rejected, as Actions only runs workflows from there.
+func OrgWorkflowPath(name string) (string, error) {
+	p := name
+	if !strings.Contains(p, "/") {
+		p = workflowsDir + "/" + p
+	}
+	if path.Clean(p) != p || path.Dir(p) != workflowsDir {
+		return "", fmt.Errorf("workflow %q is not a file directly in %s/", name, workflowsDir)
+	}
+	if ext := path.Ext(p); ext != ".yml" && ext != ".yaml" {
+		return "", fmt.Errorf("workflow %q is not a .yml or .yaml file", name)
+	}
+	return p, nil
+}
+
+// resolveOrgWorkflow returns the path of the configured org_workflow,
+// or "" when every workflow is scanned. Scan rejects an invalid value
+// before any request, so the error is not consulted here.
+func resolveOrgWorkflow() string {
+	name := viper.GetString(orgWorkflowKey)
+	if name == "" {
+		return ""
+	}
+	p, _ := OrgWorkflowPath(name)
+	return p
+}
+
+// staggerRepo sleeps for a random duration in [0, limit) so the first
+// requests of concurrently dispatched repositories are spread out
+// instead of arriving in one burst. It returns early with ctx's error
+// if ctx is cancelled, and immediately when limit is not positive.
+func staggerRepo(ctx context.Context, limit time.Duration) error {
+	if limit <= 0 {
+		return nil
+	}
+	t := time.NewTimer(rand.N(limit)) // #nosec G404 -- pacing jitter, not security sensitive
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// RunState returns the run's conclusion, or its status while the run
+// has not completed (e.g. "in_progress" or "queued"), so a single
+// value identifies every run for filtering.
+func RunState(run *github.WorkflowRun) string {
+	if c := run.GetConclusion(); c != "" {
+		return c
+	}
+	return run.GetStatus()
+}
+
+// runStates lists every value RunState can return: the completed-run
+// conclusions followed by the statuses of runs that have not finished.
+var runStates = []string{
+	"success", "failure", "neutral", "cancelled", "skipped", "timed_out",
+	"action_required", "stale", "startup_failure",
+	"queued", "in_progress", "requested", "waiting", "pending",
+}
+
+// ValidateConclusions reports an error for any filter entry that is
+// not a known run state, so a typo does not silently filter out every
+// run.
+func ValidateConclusions(filter []string) error {
+	for _, f := range filter {
+		state := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(f)), "!")
+		if state == "" {
+			continue
+		}
+		if !slices.Contains(runStates, state) {
+			return fmt.Errorf("unknown run conclusion or status %q (want one of %s)", f, strings.Join(runStates, ", "))
+		}
+	}
+	return nil
+}
+
+// FilterRuns returns the runs whose RunState passes filter. Entries
+// are run states such as "failure" or "in_progress"; an entry prefixed
+// with "!" excludes that state instead. A run is kept when it matches
+// no exclusion and either there are no inclusions or it matches one.
+// An empty filter keeps every run.
+func FilterRuns(runs []*github.WorkflowRun, filter []string) []*github.WorkflowRun {
+	include := make(map[string]bool)
+	exclude := make(map[string]bool)
+	for _, f := range filter {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch {
+		case f == "" || f == "!":
+		case strings.HasPrefix(f, "!"):
+			exclude[f[1:]] = true
+		default:
+			include[f] = true
+		}
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return runs
+	}
+
+	var out []*github.WorkflowRun
+	for _, run := range runs {
+		state := RunState(run)
+		if exclude[state] || (len(include) > 0 && !include[state]) {
+			continue
+		}
+		out = append(out, run)
+	}
+	return out
+}
+
+// LatestRun returns the newest of runs by creation time as a
+// one-element slice, or nil when there are none. Ties are broken by
+// the higher run ID, which GitHub assigns in increasing order.
+func LatestRun(runs []*github.WorkflowRun) []*github.WorkflowRun {
+	if len(runs) == 0 {
+		return nil
+	}
+	latest := slices.MaxFunc(runs, func(a, b *github.WorkflowRun) int {
+		return cmp.Or(
+			a.GetCreatedAt().Compare(b.GetCreatedAt().Time),
+			cmp.Compare(a.GetID(), b.GetID()),
+		)
+	})
+	return []*github.WorkflowRun{latest}
+}
+
+// countOlderThan returns how many of runs were created more than age
+// before now.
+func countOlderThan(runs []*github.WorkflowRun, age time.Duration, now time.Time) int {
+	n := 0
+	for _, run := range runs {
+		if created := run.GetCreatedAt().Time; !created.IsZero() && now.Sub(created) > age {
+			n++
+		}
+	}
+	return n
+}
+
+// resolveSearchQueryTemplate returns the configured search template,
+// falling back to DefaultSearchQueryTemplate when unset or blank.
+func resolveSearchQueryTemplate() string {
+	if tmpl := viper.GetString(searchQueryTemplateKey); strings.TrimSpace(tmpl) != "" {
+		return tmpl
+	}
+	return DefaultSearchQueryTemplate
+}
+
 // scanPathEnabled returns the configured boolean for key, defaulting
 // to true when the key has not been explicitly set. Both YAML and log
 // paths are on by default so existing users observe no behavior
@@ -68,6 +285,20 @@ func resolveDuration(key string, fallback time.Duration) time.Duration {
 	return fallback
 }
 
+// DefaultBreakerThreshold is used when circuit_breaker_threshold is
+// unset.
+const DefaultBreakerThreshold = 5
+
+// resolveBreakerThreshold returns the configured breaker threshold, or
+// DefaultBreakerThreshold when the key is unset. An explicit zero (or
+// negative) value is honored and disables the breaker.
+func resolveBreakerThreshold() int {
+	if !viper.IsSet(breakerThresholdKey) {
+		return DefaultBreakerThreshold
+	}
+	return viper.GetInt(breakerThresholdKey)
+}
+
 // defaultMaxRetries is the fallback retry budget used when viper has
 // no positive "max_retries" configured. It mirrors the default seeded
 // by the CLI entrypoint so library callers that bypass main get the
@@ -76,7 +307,7 @@ const defaultMaxRetries = 3
 
 // resolveMaxRetries returns the configured retry budget, falling back
 // to defaultMaxRetries when the value is unset or non-positive. Read
-// once per Scan/scanWorkflows/scanRuns invocation and threaded through
+// once per Scan invocation and threaded through, via repoScan,
 // to WithRetryN so the package never reaches into viper from a hot
 // loop.
 func resolveMaxRetries() int {
@@ -95,59 +326,204 @@ func resolveMaxRetries() int {
 // tokens and other credentials never appear in go-github error
 // strings; the SDK strips them before formatting.
 
-func scanWorkflows(ctx context.Context, logger *clog.Logger, req *ghscan.Request) error {
-	if req == nil {
-		return fmt.Errorf("req cannot be nil")
+// repoScan is the state every workflow and run scan of one repository
+// shares. Scan builds one per repository and passes it by pointer.
+type repoScan struct {
+	logger *clog.Logger
+	// req carries the repository, its workflows, and the results
+	// scanned so far.
+	req *ghscan.Request
+	// breaker stops retries once the repository keeps failing.
+	breaker *request.Breaker
+	// budget is the repository's time budget, extended per workflow
+	// and run batch.
+	budget *repoBudget
+	// pause holds new requests while the rate limit recovers.
+	pause *ratePauser
+	// progress flushes results to the incremental outputs.
+	progress *progressFlusher
+	// failed collects the workflows and runs that could not be
+	// scanned under best effort.
+	failed     *failures
+	maxRetries int
+
+	// resultsMu guards req.Cache.Results, which the workflows of the
+	// repository append to concurrently.
+	resultsMu sync.Mutex
+}
+
+// addResults appends a finished workflow's results to the
+// repository's and stages them for the next incremental flush.
+func (s *repoScan) addResults(ctx context.Context, results []ghscan.Result) {
+	s.resultsMu.Lock()
+	s.req.Cache.Results = append(s.req.Cache.Results, results...)
+	s.resultsMu.Unlock()
+	s.progress.add(ctx, results)
+}
+
+// scanPlan is what Scan does to every repository: the scans enabled
+// and the state they share across repositories.
+type scanPlan struct {
+	yaml, history, logs, modifiedOnly bool
+	// orgWorkflow, when set, is the one workflow whose runs are
+	// scanned in every repository.
+	orgWorkflow    string
+	searchTemplate string
+	// actions and tags, when non-nil, scan the actions the workflow
+	// YAML references and resolve the tags it pins.
+	actions *actionScanner
+	tags    *tagResolver
+}
+
+// scanPaths runs the scans plan enables against s.req's repository:
+// the workflow YAML, its history, and the logs of its runs.
+func (s *repoScan) scanPaths(ctx context.Context, plan *scanPlan) error {
+	repoKey := s.req.Owner + "/" + s.req.RepoName
+	if plan.yaml {
+		uses, err := scanYAML(ctx, s.logger, s.req, s.breaker, s.maxRetries, plan.actions != nil || plan.tags != nil)
+		if err != nil {
+			return newScanError(ctx, "scanning workflow YAML", repoKey, "", 0, err)
+		}
+		if plan.actions != nil {
+			if err := plan.actions.scanRepo(ctx, s.req, uses); err != nil {
+				return newScanError(ctx, "scanning actions", repoKey, "", 0, err)
+			}
+		}
+		if plan.tags != nil {
+			if err := plan.tags.scanRepo(ctx, s.req, uses); err != nil {
+				return newScanError(ctx, "resolving tags", repoKey, "", 0, err)
+			}
+		}
 	}
 
-	maxRetries := resolveMaxRetries()
+	if plan.history {
+		if err := scanHistory(ctx, s.logger, s.req, s.breaker, s.maxRetries); err != nil {
+			return newScanError(ctx, "scanning workflow history", repoKey, "", 0, err)
+		}
+	}
+
+	if !plan.logs {
+		return nil
+	}
+	// org_workflow names the file outright, so the code search (and
+	// its tight rate limit) is skipped; a repository without the
+	// workflow is dropped when it fails to resolve.
+	workflowPaths := []string{plan.orgWorkflow}
+	if plan.orgWorkflow == "" {
+		var err error
+		if workflowPaths, err = findWorkflowFiles(ctx, s.logger, s.req, s.breaker, s.maxRetries, plan.searchTemplate); err != nil {
+			return err
+		}
+		s.logger.Infof("Found %d workflow files in %s", len(workflowPaths), repoKey)
+	}
+	if plan.modifiedOnly {
+		var err error
+		if workflowPaths, err = modifiedWorkflows(ctx, s.logger, s.req, s.breaker, s.maxRetries, workflowPaths); err != nil {
+			return err
+		}
+	}
+	s.req.Workflows = workflowPaths
+
+	s.budget.extend(batches(len(workflowPaths)) * resolveDuration(workflowFetchBudgetKey, s.req.Timeout*2))
+	return s.scanWorkflows(ctx)
+}
+
+// scanWorkflows scans the runs of each of s.req's workflows.
+func (s *repoScan) scanWorkflows(ctx context.Context) error {
 
 	// fanOutLimit stays well under GitHub's documented secondary
 	// rate-limit concurrency budget (100).
 	g, gCtx := errgroup.WithContext(ctx)
 	g.SetLimit(fanOutLimit)
 
-	for _, wfPath := range req.Workflows {
+	for _, wfPath := range s.req.Workflows {
 		g.Go(func() error {
 			select {
 			case <-gCtx.Done():
 				return gCtx.Err()
 			default:
 				wfFileName := filepath.Base(wfPath)
-				repoKey := fmt.Sprintf("%s/%s", req.Owner, req.RepoName)
+				repoKey := fmt.Sprintf("%s/%s", s.req.Owner, s.req.RepoName)
 				cacheKey := fmt.Sprintf("%s|%s", repoKey, wfFileName)
 
-				if req.CachedResults[cacheKey] {
-					logger.Infof("Skipping already processed workflow %s in %s", wfFileName, repoKey)
+				if s.req.CachedResults[cacheKey] {
+					s.logger.Infof("Skipping already processed workflow %s in %s", wfFileName, repoKey)
 					return nil
 				}
+				if err := s.budget.wait(gCtx, s.pause); err != nil {
+					return err
+				}
 
-				wfCtx, wfCancel := context.WithTimeout(ctx, resolveDuration(workflowFetchBudgetKey, req.Timeout*2))
+				wfCtx, wfCancel := context.WithTimeout(ctx, resolveDuration(workflowFetchBudgetKey, s.req.Timeout*2))
 				defer wfCancel()
 
 				var workflow *github.Workflow
-				err := request.WithRetryN(wfCtx, logger, maxRetries, func() error {
+				err := s.breaker.WithRetryN(wfCtx, s.logger, s.maxRetries, func() error {
 					var err error
-					workflow, err = wf.GetWorkflowByPath(wfCtx, req.Client(), req.Owner, req.RepoName, wfPath)
+					workflow, err = wf.GetWorkflowByPath(wfCtx, s.req.Client(), s.req.Owner, s.req.RepoName, wfPath)
+					if errors.Is(err, wf.ErrWorkflowNotFound) {
+						return request.Permanent(err)
+					}
 					return err
 				})
+				if errors.Is(err, wf.ErrWorkflowNotFound) {
+					// Code search also returns workflow files Actions has
+					// not registered (never triggered, or added on a
+					// branch); they have no runs to scan. With
+					// org_workflow most repositories are expected to
+					// lack the file.
+					if viper.GetString(orgWorkflowKey) != "" {
+						s.logger.Debugf("Skipping %s: it has no workflow %s", repoKey, wfPath)
+						return nil
+					}
+					s.logger.Warnf("Skipping %s in %s: not registered as an Actions workflow", wfPath, repoKey)
+					return nil
+				}
 				if err != nil {
-					return fmt.Errorf("error retrieving workflow for %s in %s/%s: %v", wfPath, req.Owner, req.RepoName, err)
+					return s.failed.workflow(gCtx, newScanError(ctx, "retrieving workflow", repoKey, wfPath, 0, err))
 				}
 
 				workflowID := workflow.GetID()
 
 				var runs []*github.WorkflowRun
-				err = request.WithRetryN(ctx, logger, maxRetries, func() error {
-					var err error
-					runs, err = wf.ListWorkflowRuns(wfCtx, logger, req.Client(), req.Owner, req.RepoName, workflowID, req.StartTime, req.EndTime, maxRetries)
-					return err
-				})
+				if viper.GetBool(allRunsKey) {
+					// The full listing can take far longer than the
+					// per-workflow budget, so it is bounded by ctx
+					// alone and the repository's clock is held;
+					// ListAllWorkflowRuns retries each page.
+					s.budget.hold()
+					runs, err = wf.ListAllWorkflowRuns(ctx, s.logger, s.req.Client(), s.req.Owner, s.req.RepoName, workflowID, s.maxRetries)
+					s.budget.release()
+					if n := countOlderThan(runs, wf.DefaultLogRetention, time.Now()); n > 0 {
+						s.logger.Warnf("%d of %d runs of %s in %s/%s are older than %s; unless the repository retains logs longer, theirs have expired",
+							n, len(runs), wfFileName, s.req.Owner, s.req.RepoName, wf.DefaultLogRetention)
+					}
+				} else {
+					err = s.breaker.WithRetryN(ctx, s.logger, s.maxRetries, func() error {
+						var err error
+						runs, err = wf.ListWorkflowRuns(wfCtx, s.logger, s.req.Client(), s.req.Owner, s.req.RepoName, workflowID, s.req.StartTime, s.req.EndTime, s.maxRetries)
+						return err
+					})
+				}
 				if err != nil {
-					return fmt.Errorf("error listing runs for workflow %d in %s/%s: %v", workflowID, req.Owner, req.RepoName, err)
+					return s.failed.workflow(gCtx, newScanError(ctx, "listing runs", repoKey, wfPath, 0, err))
+				}
+
+				if filter := viper.GetStringSlice(conclusionsKey); len(filter) > 0 {
+					kept := FilterRuns(runs, filter)
+					s.logger.Debugf("Conclusion filter kept %d of %d runs for workflow %s in %s/%s",
+						len(kept), len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+					runs = kept
+				}
+				// A named run is kept whether or not it is the latest.
+				if viper.GetBool(latestOnlyKey) && s.req.RunID == 0 && len(runs) > 1 {
+					s.logger.Debugf("Keeping only the latest of %d runs for workflow %s in %s/%s",
+						len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+					runs = LatestRun(runs)
 				}
 
-				return scanRuns(ctx, logger, req, runs, wfFileName, wfPath)
+				s.budget.extend(batches(len(runs)) * resolveDuration(runScanBudgetKey, s.req.Timeout))
+				return s.failed.workflow(gCtx, s.scanRuns(ctx, runs, wfFileName, wfPath))
 			}
 		})
 	}
@@ -155,19 +531,41 @@ func scanWorkflows(ctx context.Context, logger *clog.Logger, req *ghscan.Request
 	return g.Wait()
 }
 
-func scanRuns(ctx context.Context, logger *clog.Logger, req *ghscan.Request, runs []*github.WorkflowRun, wfFileName, wfPath string) error {
-	if req == nil {
-		return fmt.Errorf("req cannot be nil")
+// scanRuns scans the logs, and any summaries, attempts, and metadata,
+// of runs of the workflow at wfPath. When the request names a RunID,
+// every other run is dropped first.
+func (s *repoScan) scanRuns(ctx context.Context, runs []*github.WorkflowRun, wfFileName, wfPath string) error {
+	if id := s.req.RunID; id > 0 {
+		runs = slices.DeleteFunc(runs, func(run *github.WorkflowRun) bool { return run.GetID() != id })
 	}
-
-	maxRetries := resolveMaxRetries()
+	summariesEnabled := viper.GetBool(scanSummariesKey)
+	attemptsEnabled := viper.GetBool(scanAttemptsKey)
+	var secrets *secretRefs
+	if viper.GetBool(correlateSecretsKey) {
+		secrets = newSecretRefs(s.logger, s.req, s.breaker, s.maxRetries, wfPath)
+	}
+	metadata := newRunMetadata(s.logger, s.req, s.breaker, s.maxRetries, wfFileName, wfPath)
 
 	var resultsMu sync.Mutex
 
 	g, gCtx := errgroup.WithContext(ctx)
 	g.SetLimit(fanOutLimit)
 
-	logger.Infof("Found %d runs for workflow %s in %s/%s", len(runs), wfFileName, req.Owner, req.RepoName)
+	s.logger.Infof("Found %d runs for workflow %s in %s/%s", len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+
+	// fetched and fellBack count runs whose logs were retrieved and how
+	// many of those needed the per-job fallback, so an expired-archive
+	// window is reported once per workflow rather than once per run.
+	var fetched, fellBack atomic.Int64
+	// notCompleted counts runs skipped because they were still queued
+	// or executing, so the coverage gap is reported once per workflow.
+	var notCompleted atomic.Int64
+	// forkRestricted counts fork runs whose logs this token may not
+	// read, reported once per workflow for the same reason.
+	var forkRestricted atomic.Int64
+	// expired counts runs whose logs aged past the retention window;
+	// they are also added to req.Coverage for the scan-wide total.
+	var expired atomic.Int64
 
 	var runResults []ghscan.Result
 	for _, run := range runs {
@@ -176,87 +574,99 @@ func scanRuns(ctx context.Context, logger *clog.Logger, req *ghscan.Request, run
 			case <-gCtx.Done():
 				return gCtx.Err()
 			default:
+				if err := s.budget.wait(gCtx, s.pause); err != nil {
+					return err
+				}
 				runID := run.GetID()
-				runCtx, runCancel := context.WithTimeout(ctx, resolveDuration(runScanBudgetKey, req.Timeout))
+				runCtx, runCancel := context.WithTimeout(ctx, resolveDuration(runScanBudgetKey, s.req.Timeout))
 				defer runCancel()
 
+				// Summaries are scanned before the logs so a run whose
+				// archive is gone can still surface summary findings.
+				if summariesEnabled {
+					if res := scanRunSummaries(runCtx, s.logger, s.req, s.breaker, run, wfFileName, wfPath, s.maxRetries); len(res) > 0 {
+						reachable := secrets.forRun(runCtx, run)
+						for i := range res {
+							res[i].ReachableSecrets = reachable
+						}
+						resultsMu.Lock()
+						runResults = append(runResults, res...)
+						resultsMu.Unlock()
+					}
+				}
+
+				// Commit messages and pull requests are scanned before
+				// the logs too, since they do not depend on them.
+				if res := metadata.scan(runCtx, run); len(res) > 0 {
+					resultsMu.Lock()
+					runResults = append(runResults, res...)
+					resultsMu.Unlock()
+				}
+
 				// rc is goroutine-local so concurrent runs don't clobber
 				// each other's ReadClosers.
 				var rc io.ReadCloser
-				err := request.WithRetryN(runCtx, logger, maxRetries, func() error {
+				err := s.breaker.WithRetryN(runCtx, s.logger, s.maxRetries, func() error {
 					var err error
-					rc, err = wf.GetLogs(runCtx, logger, req.HTTPClient(), req.Client(), req.Owner, req.RepoName, runID, req.Token)
+					rc, err = wf.GetLogsWithOptions(runCtx, s.logger, s.req.HTTPClient(), s.req.Client(), s.req.Owner, s.req.RepoName, runID, s.req.Token, s.req.LogOptions)
 					if errors.Is(err, wf.ErrRunHasNoLogs) {
 						return request.Permanent(err)
 					}
 					return err
 				})
 				if err != nil {
+					if errors.Is(err, wf.ErrRunNotCompleted) {
+						notCompleted.Add(1)
+					}
+					if errors.Is(err, wf.ErrForkLogsRestricted) {
+						forkRestricted.Add(1)
+					}
+					if errors.Is(err, wf.ErrLogsExpired) {
+						expired.Add(1)
+					}
 					if errors.Is(err, wf.ErrRunHasNoLogs) {
 						return nil
 					}
-					return fmt.Errorf("failed to download logs for run %d after retries: %v", runID, err)
+					return s.failed.run(gCtx, newScanError(ctx, "downloading logs", s.req.Owner+"/"+s.req.RepoName, wfPath, runID, err))
 				}
 				defer func() { _ = rc.Close() }()
+				fetched.Add(1)
+				if wf.IsPerJobFallback(rc) {
+					fellBack.Add(1)
+				}
 
-				logText, err := wf.ExtractLogs(rc)
+				logText, err := wf.ExtractLogsContext(runCtx, rc)
 				if err != nil {
-					return fmt.Errorf("error extracting logs for run %d: %v", runID, err)
-				}
-				wfFindings, found := wf.ParseLogs(logger, logText, runID, req.IOC)
-				if !found || len(wfFindings) == 0 {
-					return nil
+					return s.failed.run(gCtx, newScanError(ctx, "extracting logs", s.req.Owner+"/"+s.req.RepoName, wfPath, runID, err))
 				}
-
-				workflowUIURL := fmt.Sprintf("https://github.com/%s/%s/actions/workflows/%s",
-					req.Owner, req.RepoName, url.PathEscape(wfPath))
-
-				workflowRunUIURL := fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d",
-					req.Owner, req.RepoName, runID)
-
-				// Every finding in wfFindings shares the same
-				// (workflowRunUIURL) key, so collapse to a single
-				// Result accumulator and let later non-empty fields
-				// overwrite earlier ones. This matches the previous
-				// map-based behavior without the round-trip alloc.
-				var (
-					acc      ghscan.Result
-					accDirty bool
-				)
-				for _, finding := range wfFindings {
-					if finding.Encoded == "" && finding.Decoded == "" && finding.LineData == "" {
-						continue
-					}
-					if !accDirty {
-						acc = ghscan.Result{
-							Repository:       fmt.Sprintf("%s/%s", req.Owner, req.RepoName),
-							WorkflowFileName: wfFileName,
-							WorkflowURL:      workflowUIURL,
-							WorkflowRunURL:   workflowRunUIURL,
-							Base64Data:       finding.Encoded,
-							DecodedData:      finding.Decoded,
-							LineData:         finding.LineData,
-						}
-						accDirty = true
-						continue
-					}
-					if finding.LineData != "" {
-						acc.LineData = finding.LineData
+				wfFindings, found := wf.ParseLogsWithOptions(s.logger, logText, runID, s.req.IOC, s.req.LogOptions)
+				if s.req.Logs != nil {
+					// Keeping evidence is best effort; the findings
+					// themselves are still recorded.
+					if err := s.req.Logs.StoreLog(runCtx, fmt.Sprintf("%s/%s", s.req.Owner, s.req.RepoName), runID, logText, found); err != nil {
+						s.logger.Warnf("Failed to keep log for run %d in %s/%s: %v", runID, s.req.Owner, s.req.RepoName, err)
 					}
-					if finding.Encoded != "" {
-						acc.Base64Data = finding.Encoded
-					}
-					if finding.Decoded != "" {
-						acc.DecodedData = finding.Decoded
+				}
+				var results []ghscan.Result
+				if found {
+					// Every step of the workflow can read the secrets it
+					// references, so each is a candidate for exposure.
+					results = logResults(s.req, run, wfFindings, wfFileName, wfPath,
+						fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d", s.req.Owner, s.req.RepoName, runID),
+						secrets.forRun(runCtx, run))
+				}
+				if attemptsEnabled && run.GetRunAttempt() > 1 {
+					for i := range results {
+						results[i].RunAttempt = run.GetRunAttempt()
 					}
+					results = append(results, scanRunAttempts(runCtx, s.logger, s.req, s.breaker, run, wfFileName, wfPath, s.maxRetries, secrets, results)...)
 				}
-
-				if !accDirty {
+				if len(results) == 0 {
 					return nil
 				}
 
 				resultsMu.Lock()
-				runResults = append(runResults, acc)
+				runResults = append(runResults, results...)
 				resultsMu.Unlock()
 
 				return nil
@@ -264,14 +674,161 @@ func scanRuns(ctx context.Context, logger *clog.Logger, req *ghscan.Request, run
 		})
 	}
 	err := g.Wait()
+	if n := notCompleted.Load(); n > 0 {
+		s.logger.Infof("Skipped %d of %d runs of %s in %s/%s that had not completed; rescan once they finish",
+			n, len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+	}
+	if n := forkRestricted.Load(); n > 0 {
+		s.logger.Infof("Skipped %d of %d runs of %s in %s/%s from forks whose logs this token cannot read",
+			n, len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+	}
+	if n := expired.Load(); n > 0 {
+		s.req.Coverage.AddExpiredRuns(n)
+		s.logger.Warnf("Skipped %d of %d runs of %s in %s/%s whose logs have expired",
+			n, len(runs), wfFileName, s.req.Owner, s.req.RepoName)
+	}
+	if n, fb := fetched.Load(), fellBack.Load(); fb > 0 && fb*2 > n {
+		s.logger.Warnf("%d of %d runs of %s in %s/%s used the per-job logs fallback; run-level log archives have likely expired",
+			fb, n, wfFileName, s.req.Owner, s.req.RepoName)
+	}
 	if err != nil {
 		return err
 	}
 
-	req.Cache.Results = append(req.Cache.Results, runResults...)
+	if viper.GetBool(collapseRunsKey) {
+		runResults = CollapseRuns(runResults)
+	}
+	s.req.Stamp(runResults)
+	s.addResults(ctx, runResults)
 	return nil
 }
 
+// resultFromFinding returns the Result of a log finding in repository,
+// matched against the IOC named iocName. Callers fill in where the
+// finding was seen: the workflow, the run, and its status.
+func resultFromFinding(repository, iocName string, f wf.Finding) ghscan.Result {
+	return ghscan.Result{
+		Repository:     repository,
+		Base64Data:     f.Encoded,
+		DecodedData:    f.Decoded,
+		DecodeDepth:    f.DecodeDepth,
+		LineData:       f.LineData,
+		KeyTypes:       f.KeyType,
+		Severity:       f.Severity,
+		Destination:    f.Destination,
+		Note:           f.Note,
+		MatchedPattern: f.MatchedPattern,
+		MatchOffset:    f.MatchOffset,
+		Context:        f.Context,
+		Confidence:     f.Confidence,
+		Explanation:    f.Explanation,
+		IOCName:        iocName,
+	}
+}
+
+// runResult returns the Result of a log finding of run in req's
+// repository, linked to the run (or attempt, or job) at runURL.
+func runResult(req *ghscan.Request, run *github.WorkflowRun, f wf.Finding, wfFileName, wfPath, runURL string) ghscan.Result {
+	res := resultFromFinding(req.Owner+"/"+req.RepoName, req.IOC.GetName(), f)
+	res.WorkflowFileName = wfFileName
+	res.WorkflowURL = fmt.Sprintf("https://github.com/%s/%s/actions/workflows/%s",
+		req.Owner, req.RepoName, url.PathEscape(wfPath))
+	res.WorkflowRunURL = runURL
+	res.RunStatus = run.GetStatus()
+	res.RunConclusion = run.GetConclusion()
+	res.FromFork = wf.IsForkRun(run)
+	return res
+}
+
+// logResults turns the log findings of run into one Result each, linked
+// to the run (or attempt) at runURL. ParseLogs already dropped empty
+// and duplicate matches.
+func logResults(req *ghscan.Request, run *github.WorkflowRun, findings []wf.Finding, wfFileName, wfPath, runURL string, reachable []string) []ghscan.Result {
+	results := make([]ghscan.Result, 0, len(findings))
+	for _, finding := range findings {
+		res := runResult(req, run, finding, wfFileName, wfPath, runURL)
+		res.ReachableSecrets = reachable
+		results = append(results, res)
+	}
+	return results
+}
+
+// findWorkflowFiles returns the workflow files of req's repository
+// from the code search rendered from searchTemplate. When the search
+// fails in a way retrying cannot fix (wf.SearchUnavailable) or finds
+// nothing, as for a repository the search index skips, the workflows
+// registered with Actions are listed instead so the repository is
+// still scanned.
+func findWorkflowFiles(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, searchTemplate string) ([]string, error) {
+	query := RenderSearchQuery(searchTemplate, req.Owner, req.RepoName)
+	var paths []string
+	err := breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var err error
+		paths, err = wf.SearchWorkflowFiles(ctx, req.Client(), query)
+		if wf.SearchUnavailable(err) {
+			return request.Permanent(err)
+		}
+		return err
+	})
+	var uerr *request.UnavailableError
+	switch {
+	case errors.As(err, &uerr):
+		// The repository itself is withheld; listing fares no better.
+		return nil, uerr
+	case wf.SearchUnavailable(err):
+		logger.Warnf("Code search failed in %s/%s (%v); listing Actions workflows instead", req.Owner, req.RepoName, err)
+	case err != nil:
+		return nil, newScanError(ctx, "searching workflows", req.Owner+"/"+req.RepoName, "", 0, err)
+	case len(paths) == 0:
+		logger.Infof("Code search found no workflow files in %s/%s; listing Actions workflows instead", req.Owner, req.RepoName)
+	default:
+		return paths, nil
+	}
+
+	err = breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var err error
+		paths, err = wf.ListWorkflowPaths(ctx, req.Client(), req.Owner, req.RepoName)
+		return err
+	})
+	if err != nil {
+		return nil, newScanError(ctx, "listing workflows", req.Owner+"/"+req.RepoName, "", 0, err)
+	}
+	return paths, nil
+}
+
+// scanRunSummaries runs the log detectors over each job summary of
+// run and returns one "step-summary" result per job with a finding.
+// Summaries are best effort: any retrieval failure is logged and the
+// run's log scan proceeds unaffected.
+func scanRunSummaries(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, run *github.WorkflowRun, wfFileName, wfPath string, maxRetries int) []ghscan.Result {
+	runID := run.GetID()
+	var summaries map[int64]string
+	err := breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var err error
+		summaries, err = wf.GetJobSummaries(ctx, req.Client(), req.Owner, req.RepoName, runID)
+		return err
+	})
+	if err != nil {
+		logger.Warnf("Skipping job summaries for run %d in %s/%s: %v", runID, req.Owner, req.RepoName, err)
+		return nil
+	}
+
+	var out []ghscan.Result
+	for jobID, text := range summaries {
+		findings, found := wf.ParseLogsWithOptions(logger, text, runID, req.IOC, req.LogOptions)
+		if !found {
+			continue
+		}
+		for _, f := range findings {
+			res := runResult(req, run, f, wfFileName, wfPath, fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d/job/%d",
+				req.Owner, req.RepoName, runID, jobID))
+			res.Source = "step-summary"
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
 // scanYAML walks every workflow file under .github/workflows for the
 // repo carried on req, parses uses: edges, and emits a finding for
 // each edge whose (action, ref) matches the embedded IOC corpus.
@@ -281,31 +838,38 @@ func scanRuns(ctx context.Context, logger *clog.Logger, req *ghscan.Request, run
 // known-bad refs before the action ever runs (preventing secret
 // exfiltration), while the log path catches behavioral IOCs that
 // surface only after execution.
-func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, maxRetries int) error {
+//
+// When collectEdges is set, the parsed edges of every workflow are
+// returned for the action scan even if the corpus is empty.
+func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, collectEdges bool) ([]workflowUses, error) {
 	corpus, err := iocCorpusFor(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if corpus == nil || len(corpus.IOCs) == 0 {
-		return nil
+	if (corpus == nil || len(corpus.IOCs) == 0) && !collectEdges {
+		return nil, nil
 	}
 
 	wfCtx, wfCancel := context.WithTimeout(ctx, resolveDuration(workflowFetchBudgetKey, req.Timeout*2))
 	defer wfCancel()
 
 	var paths []string
-	err = request.WithRetryN(wfCtx, logger, maxRetries, func() error {
+	err = breaker.WithRetryN(wfCtx, logger, maxRetries, func() error {
 		var err error
 		paths, err = wf.ListWorkflowFilePaths(wfCtx, req.Client(), req.Owner, req.RepoName, "")
 		return err
 	})
 	if err != nil {
-		return fmt.Errorf("listing workflow files: %w", err)
+		return nil, fmt.Errorf("listing workflow files: %w", err)
+	}
+	if orgWorkflow := resolveOrgWorkflow(); orgWorkflow != "" {
+		paths = slices.DeleteFunc(paths, func(p string) bool { return p != orgWorkflow })
 	}
 
 	var (
 		mu       sync.Mutex
 		findings []ghscan.Result
+		uses     []workflowUses
 	)
 
 	g, gCtx := errgroup.WithContext(ctx)
@@ -326,7 +890,7 @@ func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, max
 				body []byte
 				sha  string
 			)
-			err := request.WithRetryN(fileCtx, logger, maxRetries, func() error {
+			err := breaker.WithRetryN(fileCtx, logger, maxRetries, func() error {
 				var err error
 				body, sha, err = wf.FetchWorkflowYAMLWithSHA(fileCtx, req.Client(), req.Owner, req.RepoName, wfPath, "")
 				return err
@@ -346,8 +910,14 @@ func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, max
 			workflowUIURL := fmt.Sprintf("https://github.com/%s/%s/actions/workflows/%s",
 				req.Owner, req.RepoName, url.PathEscape(wfPath))
 
+			if collectEdges {
+				mu.Lock()
+				uses = append(uses, workflowUses{FileName: wfFileName, URL: workflowUIURL, SHA: sha, Edges: edges})
+				mu.Unlock()
+			}
+
 			for _, e := range edges {
-				if !corpus.MatchActionRef(e.Action, e.Ref) {
+				if corpus == nil || !corpus.MatchActionRef(e.Action, e.Ref) {
 					continue
 				}
 				res := ghscan.Result{
@@ -361,6 +931,9 @@ func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, max
 					StepName:          e.StepName,
 					ReachableSecrets:  e.Secrets,
 					Source:            "yaml",
+					IOCName:           e.Action,
+					Confidence:        wf.ConfidenceWorkflowReference,
+					Explanation:       explain("step references %s at %s, which the IOC corpus lists as compromised", e.Action, e.Ref),
 				}
 				mu.Lock()
 				findings = append(findings, res)
@@ -370,15 +943,97 @@ func scanYAML(ctx context.Context, logger *clog.Logger, req *ghscan.Request, max
 		})
 	}
 	if err := g.Wait(); err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(findings) > 0 {
 		req.Cache.Results = append(req.Cache.Results, findings...)
 	}
+	return uses, nil
+}
+
+// scanHistory lists the commits that touched .github/workflows inside
+// the request's time window and emits a "workflow-change" finding for
+// each added line that references the IOC or a known-bad corpus ref.
+// It catches a malicious workflow that was pushed and later reverted
+// even when the corresponding run logs are gone.
+func scanHistory(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int) error {
+	corpus, err := iocCorpusFor(req)
+	if err != nil {
+		return err
+	}
+
+	histCtx, histCancel := context.WithTimeout(ctx, resolveDuration(workflowFetchBudgetKey, req.Timeout*2))
+	defer histCancel()
+
+	var commits []*github.RepositoryCommit
+	err = breaker.WithRetryN(histCtx, logger, maxRetries, func() error {
+		var err error
+		commits, err = wf.ListWorkflowCommits(histCtx, req.Client(), req.Owner, req.RepoName, req.StartTime, req.EndTime)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("listing workflow commits: %w", err)
+	}
+	logger.Infof("Found %d workflow commits in %s/%s", len(commits), req.Owner, req.RepoName)
+
+	for _, c := range commits {
+		var changes []wf.WorkflowChange
+		err := breaker.WithRetryN(histCtx, logger, maxRetries, func() error {
+			var err error
+			changes, err = wf.FindWorkflowChanges(histCtx, req.Client(), req.Owner, req.RepoName, c.GetSHA(), req.IOC, corpus)
+			return err
+		})
+		if err != nil {
+			logger.Warnf("inspecting commit %s in %s/%s: %v", c.GetSHA(), req.Owner, req.RepoName, err)
+			continue
+		}
+		for _, ch := range changes {
+			logger.Warnf("Workflow change referencing %s in %s/%s commit %s", ch.IOCName, req.Owner, req.RepoName, ch.SHA)
+			req.Cache.Results = append(req.Cache.Results, ghscan.Result{
+				Repository:       fmt.Sprintf("%s/%s", req.Owner, req.RepoName),
+				WorkflowFileName: filepath.Base(ch.Path),
+				WorkflowURL: fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s",
+					req.Owner, req.RepoName, ch.SHA, ch.Path),
+				LineData:     ch.Line,
+				Source:       "workflow-change",
+				IOCName:      ch.IOCName,
+				CommitSHA:    ch.SHA,
+				CommitAuthor: ch.Author,
+				Confidence:   wf.ConfidenceWorkflowChange,
+				Explanation:  explain("commit %s added a workflow line referencing %s", ch.SHA, ch.IOCName),
+			})
+		}
+	}
 	return nil
 }
 
+// modifiedWorkflows returns the paths a commit between req.StartTime
+// and req.EndTime touched, in the order given, so long-stable
+// workflows are not scanned for runs. It costs one request per path.
+func modifiedWorkflows(ctx context.Context, logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, paths []string) ([]string, error) {
+	modCtx, modCancel := context.WithTimeout(ctx, resolveDuration(workflowFetchBudgetKey, req.Timeout*2))
+	defer modCancel()
+
+	var out []string
+	for _, p := range paths {
+		var modified bool
+		err := breaker.WithRetryN(modCtx, logger, maxRetries, func() error {
+			var err error
+			modified, err = wf.WorkflowModified(modCtx, req.Client(), req.Owner, req.RepoName, p, req.StartTime, req.EndTime)
+			return err
+		})
+		if err != nil {
+			return nil, newScanError(ctx, "checking commits", req.Owner+"/"+req.RepoName, p, 0, err)
+		}
+		if modified {
+			out = append(out, p)
+		}
+	}
+	logger.Infof("%d of %d workflow files in %s/%s were modified in the scan window", len(out), len(paths), req.Owner, req.RepoName)
+	return out, nil
+}
+
 // iocCorpusFor returns the corpus the YAML scanner should consult.
 // When the operator supplied --ioc-file the request carries an
 // explicit override; otherwise the embedded corpus is used.
@@ -393,18 +1048,79 @@ func iocCorpusFor(req *ghscan.Request) (*ioc.Corpus, error) {
 	return c, nil
 }
 
+// Scan scans repos as described by req. Every log line goes to logger;
+// a nil logger selects the one carried by ctx (see [clog.FromContext]).
+// The chosen logger is also installed on the context handed to the
+// workflow and HTTP layers, so an embedder's handler and level apply
+// to all of ghscan's output.
+//
+// By default a run or workflow that fails cancels the rest of its
+// repository's scan. With best_effort the failure is logged and
+// skipped, and Scan returns [ErrIncompleteScan] with the number of
+// failed runs and workflows once everything else has been scanned.
 func Scan(ctx context.Context, logger *clog.Logger, req *ghscan.Request, repos []*github.Repository) error {
 	if req == nil {
 		return fmt.Errorf("req cannot be nil")
 	}
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+	ctx = clog.WithLogger(ctx, logger)
 
 	yamlEnabled := scanPathEnabled(scanYAMLKey)
 	logsEnabled := scanPathEnabled(scanLogsKey)
+	modifiedOnly := viper.GetBool(modifiedOnlyKey)
+	historyEnabled := viper.GetBool(scanHistoryKey) || modifiedOnly
 	if !yamlEnabled && !logsEnabled {
 		return fmt.Errorf("at least one of scan_yaml or scan_logs must be enabled")
 	}
+	actionsEnabled := viper.GetBool(scanActionsKey)
+	if actionsEnabled && !yamlEnabled {
+		return fmt.Errorf("scan_actions requires scan_yaml")
+	}
+	tagsEnabled := viper.GetBool(resolveTagsKey)
+	if tagsEnabled && !yamlEnabled {
+		return fmt.Errorf("resolve_tags requires scan_yaml")
+	}
+	if name := viper.GetString(orgWorkflowKey); name != "" {
+		if _, err := OrgWorkflowPath(name); err != nil {
+			return fmt.Errorf("org_workflow: %w", err)
+		}
+	}
+	orgWorkflow := resolveOrgWorkflow()
 
 	maxRetries := resolveMaxRetries()
+	searchTemplate := resolveSearchQueryTemplate()
+	stagger := viper.GetDuration(repoStaggerKey)
+
+	// actions is shared by every repository so an action referenced
+	// across the organization is fetched and scanned once.
+	var actions *actionScanner
+	if actionsEnabled {
+		var err error
+		if actions, err = newActionScanner(logger, req, resolveScanActionsDepth(), maxRetries); err != nil {
+			return err
+		}
+	}
+	// tags is likewise shared so a tag pinned across the organization
+	// is resolved once.
+	var tags *tagResolver
+	if tagsEnabled {
+		var err error
+		if tags, err = newTagResolver(logger, req, maxRetries); err != nil {
+			return err
+		}
+	}
+	plan := &scanPlan{
+		yaml:           yamlEnabled,
+		history:        historyEnabled,
+		logs:           logsEnabled,
+		modifiedOnly:   modifiedOnly,
+		orgWorkflow:    orgWorkflow,
+		searchTemplate: searchTemplate,
+		actions:        actions,
+		tags:           tags,
+	}
 
 	// max_concurrency is honored only when it is a positive value
 	// tighter than fanOutLimit. errgroup.SetLimit(<=0) disables the
@@ -417,23 +1133,77 @@ func Scan(ctx context.Context, logger *clog.Logger, req *ghscan.Request, repos [
 	g, gCtx := errgroup.WithContext(ctx)
 	g.SetLimit(maxConcurrency)
 
+	// adaptive, when enabled, paces the repositories in flight below
+	// maxConcurrency by the rate-limit budget the shared HTTP client
+	// observes.
+	var adaptive *adaptiveLimiter
+	if viper.GetBool(adaptiveConcurrencyKey) {
+		if adaptive = newAdaptiveLimiter(logger, req.HTTPClient().RateBudget(), maxConcurrency); adaptive == nil {
+			logger.Warnf("adaptive_concurrency needs an HTTP client with a rate budget; using max_concurrency %d", maxConcurrency)
+		}
+	}
+
+	// pause, when enabled, holds new repositories, workflows, and runs
+	// back until the core rate limit resets once it is spent.
+	pause := resolveRatePauser(logger, req)
+
 	// cacheMu guards merging per-repo result slices back into the
 	// shared req.Cache.Results once each repository finishes.
 	var cacheMu sync.Mutex
 
+	// erroredRepos collects repositories whose circuit breaker opened;
+	// they are reported once every other repository has been scanned.
+	breakerThreshold := resolveBreakerThreshold()
+	var (
+		erroredMu    sync.Mutex
+		erroredRepos []*ScanError
+	)
+
+	// timedOutRepos collects repositories whose budget ran out before
+	// their scan finished.
+	var (
+		timedOutMu    sync.Mutex
+		timedOutRepos []*ScanError
+	)
+
+	// failed, in best_effort mode, collects the runs and workflows that
+	// failed so the rest of each repository is still scanned.
+	failed := newFailures()
+
+	// progress forwards results to req.Sink as each repository
+	// finishes and, every flush_interval or flush_size results, as each
+	// workflow finishes, bounding the work a crash can lose in a
+	// long-running repository.
+	progress := newProgressFlusher(logger, req.Sink, viper.GetInt(flushSizeKey))
+	flushCtx, stopFlush := context.WithCancel(ctx)
+	defer stopFlush()
+	go progress.run(flushCtx, resolveFlushInterval())
+
 	for _, repo := range repos {
 		g.Go(func() error {
 			select {
 			case <-gCtx.Done():
 				return gCtx.Err()
 			default:
+				if err := staggerRepo(gCtx, stagger); err != nil {
+					return err
+				}
+				if err := pause.wait(gCtx); err != nil {
+					return err
+				}
+				if err := adaptive.acquire(gCtx); err != nil {
+					return err
+				}
+				defer adaptive.release()
+
 				owner := repo.GetOwner().GetLogin()
 				repoName := repo.GetName()
-				logger.Infof("Processing repository: %s/%s", owner, repoName)
+				repoKey := owner + "/" + repoName
+				logger.Infof("Processing repository: %s", repoKey)
 
 				opTimeout := viper.GetDuration("operation_timeout")
-				repoCtx, repoCancel := context.WithTimeout(ctx, resolveDuration(repoEnumBudgetKey, opTimeout*5))
-				defer repoCancel()
+				repoCtx, budget := newRepoBudget(ctx, opTimeout)
+				defer budget.stop()
 
 				repoReq := *req
 				repoReq.Cache = ghscan.Cache{}
@@ -441,54 +1211,89 @@ func Scan(ctx context.Context, logger *clog.Logger, req *ghscan.Request, repos [
 				repoReq.RepoName = repoName
 				repoReq.Timeout = opTimeout
 
-				if yamlEnabled {
-					if err := scanYAML(repoCtx, logger, &repoReq, maxRetries); err != nil {
-						return fmt.Errorf("YAML scan of %s/%s: %w", owner, repoName, err)
-					}
+				// breaker is shared by every operation against this
+				// repository so one that keeps failing (deleted, no
+				// access, outage) stops spending retries on each
+				// remaining workflow and run.
+				breaker := request.NewBreaker(breakerThreshold)
+				rs := &repoScan{
+					logger:     logger,
+					req:        &repoReq,
+					breaker:    breaker,
+					budget:     budget,
+					pause:      pause,
+					progress:   progress,
+					failed:     failed,
+					maxRetries: maxRetries,
 				}
-
-				if logsEnabled {
-					query := fmt.Sprintf("repo:%s/%s path:.github/workflows language:YAML", owner, repoName)
-
-					var workflowPaths []string
-					err := request.WithRetryN(repoCtx, logger, maxRetries, func() error {
-						var err error
-						workflowPaths, err = wf.SearchWorkflowFiles(repoCtx, req.Client(), query)
-						return err
-					})
-					if err != nil {
-						return fmt.Errorf("error searching workflows in %s/%s: %v", owner, repoName, err)
-					}
-
-					logger.Infof("Found %d workflow files in %s/%s", len(workflowPaths), owner, repoName)
-					repoReq.Workflows = workflowPaths
-
-					if err := scanWorkflows(ctx, logger, &repoReq); err != nil {
-						return err
+				err := rs.scanPaths(repoCtx, plan)
+				unavailable := breaker.Unavailable()
+				if unavailable == nil {
+					errors.As(err, &unavailable)
+				}
+				switch {
+				case unavailable != nil:
+					// GitHub withholds the repository (a legal
+					// takedown or block), which no retry or rerun
+					// changes, so it is skipped and reported rather
+					// than failing the scan.
+					logger.Warnf("Skipping %s/%s: %s", owner, repoName, unavailable.Reason)
+					req.Coverage.AddSkippedRepo(repoKey, unavailable.Reason)
+				case errors.Is(context.Cause(repoCtx), errRepoTimeout):
+					// The repository is recorded as timed out and the
+					// scan moves on; its partial results are kept.
+					logger.Warnf("Canceled the scan of %s/%s after its %s budget ran out; raise -repo-timeout to give it longer",
+						owner, repoName, budget.total())
+					timedOutMu.Lock()
+					timedOutRepos = append(timedOutRepos, &ScanError{Category: ErrorTimeout, Op: "scanning repository", Repository: repoKey, Err: errRepoTimeout})
+					timedOutMu.Unlock()
+				case breaker.Open():
+					// The repository is recorded as errored and the
+					// scan moves on; its partial results are kept.
+					logger.Errorf("Skipped remaining operations for %s/%s after %d consecutive failures",
+						owner, repoName, breakerThreshold)
+					erroredMu.Lock()
+					if err == nil {
+						err = request.ErrCircuitOpen
 					}
+					erroredRepos = append(erroredRepos, newScanError(repoCtx, "scanning repository", repoKey, "", 0, err))
+					erroredMu.Unlock()
+				case err != nil:
+					return err
 				}
 
-				merged := dedupResults(repoReq.Cache.Results)
+				merged := DedupResults(repoReq.Cache.Results)
+				req.Stamp(merged)
 				if len(merged) > 0 {
 					cacheMu.Lock()
 					req.Cache.Results = append(req.Cache.Results, merged...)
 					cacheMu.Unlock()
+
+					progress.finishRepo(ctx, merged)
 				}
 				return nil
 			}
 		})
 	}
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return errors.Join(
+		summarizeRepos(request.ErrCircuitOpen, erroredRepos),
+		summarizeRepos(errRepoTimeout, timedOutRepos),
+		failed.report(logger),
+	)
 }
 
-// dedupResults merges results emitted by the YAML and log paths so a
+// DedupResults merges results emitted by the YAML and log paths so a
 // single workflow file produces one record when both paths fire.
 // The YAML record wins because it carries the richer attribution
 // context (job/step name, ref form, reachable secrets) needed to
 // triage the finding. Log records on a workflow file with no YAML
-// finding remain in place.
-func dedupResults(in []ghscan.Result) []ghscan.Result {
+// finding remain in place, and workflow-change records are always
+// kept because each one names a distinct commit.
+func DedupResults(in []ghscan.Result) []ghscan.Result {
 	if len(in) == 0 {
 		return in
 	}
@@ -503,7 +1308,7 @@ func dedupResults(in []ghscan.Result) []ghscan.Result {
 	}
 	out := make([]ghscan.Result, 0, len(in))
 	for _, r := range in {
-		if r.Source != "yaml" {
+		if r.Source == "" {
 			if _, ok := yamlFiles[r.Repository+"|"+r.WorkflowFileName]; ok {
 				continue
 			}
@@ -5,18 +5,27 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/internal/request"
 	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
 	"github.com/chainguard-dev/ghscan/pkg/httpclient"
 	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
 	"github.com/google/go-github/v86/github"
 	"github.com/spf13/viper"
 	"golang.org/x/time/rate"
@@ -194,6 +203,7 @@ func TestScan_HappyPath(t *testing.T) {
 		IOC:           customIOC,
 		StartTime:     start,
 		Token:         "test-token",
+		ScanID:        "scan-1",
 	})
 
 	repos := []*github.Repository{{
@@ -212,6 +222,503 @@ func TestScan_HappyPath(t *testing.T) {
 	if !strings.Contains(got.LineData, "DROP_THIS_TOKEN") {
 		t.Fatalf("LineData=%q, want substring DROP_THIS_TOKEN", got.LineData)
 	}
+	if got.RunStatus != "completed" {
+		t.Fatalf("RunStatus=%q, want completed", got.RunStatus)
+	}
+	if got.ScanID != "scan-1" {
+		t.Fatalf("ScanID=%q, want the request's scan-1", got.ScanID)
+	}
+}
+
+// TestScan_NilLoggerUsesContextLogger asserts an embedder that passes
+// no logger gets ghscan's output through the logger on its context.
+func TestScan_NilLoggerUsesContextLogger(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+	t.Cleanup(srv.Close)
+	gh, hc := newTestClients(t, srv)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now().Add(time.Hour)
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-7 * 24 * time.Hour),
+		Token:         "test-token",
+	})
+	repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+	var buf bytes.Buffer
+	ctx := clog.WithLogger(t.Context(), clog.New(slog.NewTextHandler(&buf, nil)))
+	if err := action.Scan(ctx, nil, req, repos); err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(req.Cache.Results) == 0 {
+		t.Fatal("expected a finding with a nil logger")
+	}
+	if !strings.Contains(buf.String(), "Found 1 runs for workflow ci.yml") {
+		t.Fatalf("context logger did not receive the scan's output:\n%s", buf.String())
+	}
+}
+
+// TestScan_CorrelateSecrets asserts correlate_secrets records the
+// secrets the workflow references on the run's log findings, fetching
+// the workflow file once, and that nothing is fetched when it is off.
+func TestScan_CorrelateSecrets(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("correlate_secrets", enabled)
+			t.Cleanup(viper.Reset)
+
+			owner, repo, wfPath := "octo", "demo", ".github/workflows/ci.yml"
+			srv := fakeGitHub(t, owner, repo, wfPath, "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			var hits atomic.Int32
+			serveContent(t, srv.Config.Handler.(*http.ServeMux), "/repos/octo/demo/contents/"+wfPath, "",
+				"jobs:\n  build:\n    steps:\n      - run: echo ${{ secrets.NPM_TOKEN }}\n        env:\n          KEY: ${{ secrets.DEPLOY_KEY }}\n", &hits)
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+			if len(req.Cache.Results) == 0 {
+				t.Fatal("expected a log finding")
+			}
+			var (
+				want     []string
+				wantHits int32
+			)
+			if enabled {
+				want, wantHits = []string{"NPM_TOKEN", "DEPLOY_KEY"}, 1
+			}
+			for _, r := range req.Cache.Results {
+				if !slices.Equal(r.ReachableSecrets, want) {
+					t.Fatalf("ReachableSecrets=%q, want %q", r.ReachableSecrets, want)
+				}
+			}
+			if got := hits.Load(); got != wantHits {
+				t.Fatalf("workflow file fetched %d times, want %d", got, wantHits)
+			}
+		})
+	}
+}
+
+// TestScan_RunIDScansOnlyThatRun asserts Request.RunID keeps only the named
+// run's logs: the fixture's run 99 is scanned when named and skipped
+// when another run is.
+func TestScan_RunIDScansOnlyThatRun(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+	t.Cleanup(srv.Close)
+	gh, hc := newTestClients(t, srv)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now().Add(time.Hour)
+
+	for _, tc := range []struct {
+		runID        int64
+		wantFindings bool
+	}{
+		{runID: 99, wantFindings: true},
+		{runID: 98, wantFindings: false},
+	} {
+		req := ghscan.NewRequest(ghscan.RequestConfig{
+			CachedResults: map[string]bool{},
+			Client:        gh,
+			HTTPClient:    hc,
+			EndTime:       end,
+			IOC:           customIOC,
+			RunID:         tc.runID,
+			StartTime:     end.Add(-7 * 24 * time.Hour),
+			Token:         "test-token",
+		})
+		repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+		if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+			t.Fatalf("Scan(RunID=%d) error: %v", tc.runID, err)
+		}
+		if got := len(req.Cache.Results) > 0; got != tc.wantFindings {
+			t.Fatalf("RunID=%d: findings=%v, want %v", tc.runID, req.Cache.Results, tc.wantFindings)
+		}
+	}
+}
+
+// TestScan_AllRunsIgnoresWindow asserts all_runs scans a run created
+// outside the request's time window, which the windowed listing drops.
+func TestScan_AllRunsIgnoresWindow(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+	t.Cleanup(srv.Close)
+	gh, hc := newTestClients(t, srv)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	// The fake's only run was created 12 hours ago, after this window.
+	end := time.Now().Add(-10 * 24 * time.Hour)
+
+	for _, allRuns := range []bool{false, true} {
+		viper.Set("all_runs", allRuns)
+		req := ghscan.NewRequest(ghscan.RequestConfig{
+			CachedResults: map[string]bool{},
+			Client:        gh,
+			HTTPClient:    hc,
+			EndTime:       end,
+			IOC:           customIOC,
+			StartTime:     end.Add(-7 * 24 * time.Hour),
+			Token:         "test-token",
+		})
+		repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+		if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+			t.Fatalf("Scan(all_runs=%v) error: %v", allRuns, err)
+		}
+		if got := len(req.Cache.Results) > 0; got != allRuns {
+			t.Fatalf("all_runs=%v: findings=%v, want %v", allRuns, req.Cache.Results, allRuns)
+		}
+	}
+}
+
+// TestScan_UnavailableRepoSkipped asserts a repository GitHub withholds
+// with 451 is skipped after one request and recorded in the request's
+// coverage, while the scan itself succeeds.
+func TestScan_UnavailableRepoSkipped(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 3)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		_, _ = w.Write([]byte(`{"message":"Repository access blocked","block":{"reason":"dmca"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	gh, hc := newTestClients(t, srv)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now()
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-24 * time.Hour),
+		Token:         "test-token",
+	})
+	repos := []*github.Repository{{Name: new("taken-down"), Owner: &github.User{Login: new("octo")}}}
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan() error = %v, want the withheld repository skipped", err)
+	}
+	want := []ghscan.SkippedRepo{{Repository: "octo/taken-down", Reason: "unavailable for legal reasons (dmca)"}}
+	if got := req.Coverage.SkippedRepos(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SkippedRepos() = %+v, want %+v", got, want)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("server saw %d requests, want 1 (no retries, no further calls)", got)
+	}
+}
+
+// TestScan_CircuitBreakerRecordsFailingRepo asserts a repository whose
+// API calls keep failing is skipped and reported via ErrCircuitOpen
+// without aborting the scan of healthy repositories.
+func TestScan_CircuitBreakerRecordsFailingRepo(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("max_concurrency", 4)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	viper.Set("circuit_breaker_threshold", 1)
+	t.Cleanup(viper.Reset)
+
+	owner := "octo"
+	wfPath := ".github/workflows/ci.yml"
+	// Only octo/demo is served; every per-repository call for
+	// octo/gone fails with a 500, which is retried and counted, unlike
+	// a 404.
+	srv := fakeGitHub(t, owner, "demo", wfPath, "DROP_THIS_TOKEN appears here\n")
+	t.Cleanup(srv.Close)
+	mux := srv.Config.Handler
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/repos/"+owner+"/gone") {
+			http.Error(w, "server error", http.StatusInternalServerError)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+
+	gh, hc := newTestClients(t, srv)
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now().Add(time.Hour)
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-7 * 24 * time.Hour),
+		Token:         "test-token",
+	})
+	repos := []*github.Repository{
+		{Name: new("gone"), Owner: &github.User{Login: new(owner)}},
+		{Name: new("demo"), Owner: &github.User{Login: new(owner)}},
+	}
+
+	err = action.Scan(t.Context(), newSilentLogger(), req, repos)
+	if !errors.Is(err, request.ErrCircuitOpen) {
+		t.Fatalf("Scan() error = %v, want ErrCircuitOpen", err)
+	}
+	if !strings.Contains(err.Error(), "octo/gone") || strings.Contains(err.Error(), "octo/demo") {
+		t.Fatalf("Scan() error = %v, want only octo/gone reported", err)
+	}
+	if len(req.Cache.Results) == 0 || req.Cache.Results[0].Repository != "octo/demo" {
+		t.Fatalf("results=%+v, want octo/demo's finding kept", req.Cache.Results)
+	}
+}
+
+// TestScan_BestEffort asserts a run whose logs cannot be extracted
+// fails the scan in strict mode, and in best_effort mode is skipped
+// and reported through ErrIncompleteScan once the scan completes. In
+// both modes the failure is a ScanError naming the run.
+func TestScan_BestEffort(t *testing.T) {
+	for _, bestEffort := range []bool{false, true} {
+		t.Run(fmt.Sprintf("best_effort=%v", bestEffort), func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("best_effort", bestEffort)
+			t.Cleanup(viper.Reset)
+
+			owner, repo := "octo", "demo"
+			srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			mux := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/signed" {
+					_, _ = w.Write([]byte("not a zip archive"))
+					return
+				}
+				mux.ServeHTTP(w, r)
+			})
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+			err = action.Scan(t.Context(), newSilentLogger(), req, repos)
+			if err == nil {
+				t.Fatal("Scan() succeeded despite an unreadable log archive")
+			}
+			if got := errors.Is(err, action.ErrIncompleteScan); got != bestEffort {
+				t.Fatalf("Scan() error = %v, want ErrIncompleteScan=%v", err, bestEffort)
+			}
+			if bestEffort && !strings.Contains(err.Error(), "1 runs and 0 workflows failed") {
+				t.Fatalf("Scan() error = %v, want the failed run counted", err)
+			}
+			scanErrs := action.ScanErrors(err)
+			if len(scanErrs) != 1 {
+				t.Fatalf("ScanErrors(%v) = %v, want the failed run", err, scanErrs)
+			}
+			if got := *scanErrs[0]; got.Category != action.ErrorParse || got.Op != "extracting logs" ||
+				got.Repository != "octo/demo" || got.Workflow != ".github/workflows/ci.yml" || got.RunID != 99 {
+				t.Fatalf("ScanErrors()[0] = %+v, want a parse failure extracting run 99 of ci.yml in octo/demo", got)
+			}
+		})
+	}
+}
+
+// recordingSink is a ghscan.ResultSink that captures every batch it
+// receives.
+type recordingSink struct {
+	mu      sync.Mutex
+	batches [][]ghscan.Result
+}
+
+func (s *recordingSink) Append(_ context.Context, results []ghscan.Result) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, results)
+	return nil
+}
+
+// TestScan_FlushesToSink asserts each repository's findings reach the
+// request's ResultSink as well as the in-memory cache, so the CLI's
+// incremental outputs observe the same results as the final write.
+func TestScan_FlushesToSink(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("max_concurrency", 4)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN here\n")
+	t.Cleanup(srv.Close)
+
+	gh, hc := newTestClients(t, srv)
+	customIOC, err := ioc.NewIOC(&ioc.Config{
+		Name:    "test-only",
+		Content: []string{"DROP_THIS_TOKEN"},
+	})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	end := time.Now().Add(time.Hour)
+	start := end.Add(-24 * time.Hour)
+
+	sink := &recordingSink{}
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		Sink:          sink,
+		StartTime:     start,
+		Token:         "tok",
+	})
+
+	repos := []*github.Repository{{
+		Name:  new(repo),
+		Owner: &github.User{Login: new(owner)},
+	}}
+
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	if len(sink.batches) != 1 {
+		t.Fatalf("sink batches=%d, want 1", len(sink.batches))
+	}
+	if len(sink.batches[0]) != len(req.Cache.Results) {
+		t.Fatalf("sink saw %d results, cache holds %d", len(sink.batches[0]), len(req.Cache.Results))
+	}
+}
+
+// recordingLogStore is a ghscan.LogStore that captures every call.
+type recordingLogStore struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (s *recordingLogStore) StoreLog(_ context.Context, repository string, runID int64, logText string, hasFindings bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf("%s|%d|%t|%t", repository, runID, hasFindings, strings.Contains(logText, "DROP_THIS_TOKEN")))
+	return nil
+}
+
+// TestScan_OffersLogsToStore asserts every scanned run's extracted log
+// reaches the request's LogStore along with whether it had findings.
+func TestScan_OffersLogsToStore(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN here\n")
+	t.Cleanup(srv.Close)
+
+	gh, hc := newTestClients(t, srv)
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	store := &recordingLogStore{}
+	end := time.Now().Add(time.Hour)
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		Logs:          store,
+		StartTime:     end.Add(-24 * time.Hour),
+		Token:         "tok",
+	})
+	repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+	want := []string{"octo/demo|99|true|true"}
+	if strings.Join(store.calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("StoreLog calls=%q, want %q", store.calls, want)
+	}
 }
 
 func TestScan_ContextCancelled(t *testing.T) {
@@ -257,6 +764,51 @@ func TestScan_ContextCancelled(t *testing.T) {
 	}
 }
 
+// TestScan_RepoStaggerHonorsCancellation asserts the per-repository
+// stagger delay is interruptible: a long stagger must not hold Scan past
+// its context deadline.
+func TestScan_RepoStaggerHonorsCancellation(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("max_concurrency", 4)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("repo_stagger", "1h")
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "no IOC here\n")
+	t.Cleanup(srv.Close)
+
+	gh, hc := newTestClients(t, srv)
+	predef, _ := ioc.GetPredefinedIOC("tj-actions/changed-files")
+
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       time.Now(),
+		IOC:           predef,
+		StartTime:     time.Now().Add(-time.Hour),
+		Token:         "tok",
+	})
+	repos := []*github.Repository{{
+		Name:  new(repo),
+		Owner: &github.User{Login: new(owner)},
+	}}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	err := action.Scan(ctx, newSilentLogger(), req, repos)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Scan() err=%v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Fatalf("Scan() took %v; stagger ignored cancellation", elapsed)
+	}
+}
+
 // fakeGitHubNoJobs returns an httptest server that drives a workflow
 // run all the way to log fetch, where the per-job fallback discovers
 // zero jobs. The expected scan outcome is a clean nil error with zero
@@ -384,6 +936,60 @@ func TestScanRuns_NoJobsSkipsCleanly(t *testing.T) {
 	}
 }
 
+// TestScan_UnregisteredWorkflowSkipped asserts a workflow file that
+// code search returns but ListWorkflows does not (never triggered, or
+// disabled and unregistered) is skipped instead of failing the
+// repository.
+func TestScan_UnregisteredWorkflowSkipped(t *testing.T) {
+	chdirTemp(t)
+	viper.Set("max_retries", 1)
+	viper.Set("operation_timeout", "30s")
+	viper.Set("scan_yaml", false)
+	t.Cleanup(viper.Reset)
+
+	owner, repo := "octo", "demo"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/code", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.CodeSearchResult{
+			Total:       new(1),
+			CodeResults: []*github.CodeResult{{Path: new(".github/workflows/never-run.yml")}},
+		})
+	})
+	mux.HandleFunc(fmt.Sprintf("/repos/%s/%s/actions/workflows", owner, repo),
+		func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(github.Workflows{
+				TotalCount: new(1),
+				Workflows:  []*github.Workflow{{ID: new(int64(42)), Path: new(".github/workflows/ci.yml")}},
+			})
+		})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	gh, hc := newTestClients(t, srv)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	end := time.Now().Add(time.Hour)
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		Client:        gh,
+		HTTPClient:    hc,
+		EndTime:       end,
+		IOC:           customIOC,
+		StartTime:     end.Add(-7 * 24 * time.Hour),
+		Token:         "tok",
+	})
+	repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+	if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+		t.Fatalf("Scan() must skip an unregistered workflow; got %v", err)
+	}
+	if len(req.Cache.Results) != 0 {
+		t.Fatalf("expected zero findings; got %+v", req.Cache.Results)
+	}
+}
+
 // TestScanRuns_NoJobsBodyContentIgnored is the regression guard for
 // the attacker-controlled-content skip bypass. A run with real log
 // content whose first 100 bytes happen to spell "had no jobs to scan"
@@ -585,3 +1191,712 @@ func TestScan_MaxConcurrencyClamped(t *testing.T) {
 		t.Fatalf("Scan() error: %v", err)
 	}
 }
+
+func TestRenderSearchQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{name: "default", tmpl: action.DefaultSearchQueryTemplate, want: "repo:octo/demo path:.github/workflows language:YAML"},
+		{name: "custom path", tmpl: "repo:{owner}/{repo} path:ci extension:yaml", want: "repo:octo/demo path:ci extension:yaml"},
+		{name: "repeated placeholders", tmpl: "repo:{owner}/{repo} {repo}", want: "repo:octo/demo demo"},
+		{name: "no placeholders", tmpl: "org:octo path:.github", want: "org:octo path:.github"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := action.RenderSearchQuery(tc.tmpl, "octo", "demo"); got != tc.want {
+				t.Fatalf("RenderSearchQuery(%q) = %q, want %q", tc.tmpl, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateSearchQueryTemplate(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		tmpl    string
+		wantErr bool
+	}{
+		{name: "default", tmpl: action.DefaultSearchQueryTemplate},
+		{name: "custom", tmpl: "repo:{owner}/{repo} path:ci"},
+		{name: "empty", tmpl: "", wantErr: true},
+		{name: "whitespace", tmpl: "   ", wantErr: true},
+		{name: "placeholders only", tmpl: "{owner}{repo}", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := action.ValidateSearchQueryTemplate(tc.tmpl)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateSearchQueryTemplate(%q) err=%v, wantErr=%v", tc.tmpl, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOrgWorkflowPath(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "publish.yml", want: ".github/workflows/publish.yml"},
+		{name: "release.yaml", want: ".github/workflows/release.yaml"},
+		{name: ".github/workflows/publish.yml", want: ".github/workflows/publish.yml"},
+		{name: "publish.json", wantErr: true},
+		{name: ".github/workflows/sub/publish.yml", wantErr: true},
+		{name: ".github/workflows/../ci.yml", wantErr: true},
+		{name: "ci/publish.yml", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := action.OrgWorkflowPath(tc.name)
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Fatalf("OrgWorkflowPath(%q)=%q,%v, want %q (error %v)", tc.name, got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestScan_OrgWorkflow asserts org_workflow scans the named workflow
+// without a code search and quietly skips a repository lacking it.
+func TestScan_OrgWorkflow(t *testing.T) {
+	for _, tc := range []struct {
+		workflow     string
+		wantFindings bool
+	}{
+		{workflow: "ci.yml", wantFindings: true},
+		{workflow: "publish.yml"},
+	} {
+		t.Run(tc.workflow, func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("org_workflow", tc.workflow)
+			t.Cleanup(viper.Reset)
+
+			owner, repo := "octo", "demo"
+			srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			mux := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search/code" {
+					t.Errorf("org_workflow scan searched code: %s", r.URL)
+				}
+				mux.ServeHTTP(w, r)
+			})
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+			if got := len(req.Cache.Results) > 0; got != tc.wantFindings {
+				t.Fatalf("findings=%+v, want findings=%v", req.Cache.Results, tc.wantFindings)
+			}
+		})
+	}
+}
+
+// TestScan_ModifiedOnly asserts modified_only scans the runs of a
+// workflow a commit in the window touched, alongside the change
+// itself, and skips the runs of a workflow left unchanged.
+func TestScan_ModifiedOnly(t *testing.T) {
+	const wfPath = ".github/workflows/ci.yml"
+	for _, tc := range []struct {
+		name     string
+		modified bool
+	}{
+		{name: "modified", modified: true},
+		{name: "stable"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("modified_only", true)
+			t.Cleanup(viper.Reset)
+
+			owner, repo := "octo", "demo"
+			srv := fakeGitHub(t, owner, repo, wfPath, "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			var runsListed atomic.Bool
+			mux := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/octo/demo/commits":
+					var commits []*github.RepositoryCommit
+					if tc.modified {
+						commits = append(commits, &github.RepositoryCommit{SHA: new("abc123")})
+					}
+					_ = json.NewEncoder(w).Encode(commits)
+					return
+				case "/repos/octo/demo/commits/abc123":
+					_ = json.NewEncoder(w).Encode(github.RepositoryCommit{
+						SHA: new("abc123"),
+						Files: []*github.CommitFile{{
+							Filename: new(wfPath),
+							Status:   new("modified"),
+							Patch:    new("+  - run: echo DROP_THIS_TOKEN\n"),
+						}},
+					})
+					return
+				case "/repos/octo/demo/actions/workflows/42/runs":
+					runsListed.Store(true)
+				}
+				mux.ServeHTTP(w, r)
+			})
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+			if runsListed.Load() != tc.modified {
+				t.Errorf("runs listed=%v, want %v", runsListed.Load(), tc.modified)
+			}
+			sources := make(map[string]bool)
+			for _, r := range req.Cache.Results {
+				sources[r.Source] = true
+			}
+			if tc.modified && (!sources["workflow-change"] || !sources[""]) {
+				t.Errorf("results=%+v, want both the workflow change and the run's log finding", req.Cache.Results)
+			}
+			if !tc.modified && len(req.Cache.Results) > 0 {
+				t.Errorf("results=%+v, want none for an unchanged workflow", req.Cache.Results)
+			}
+		})
+	}
+}
+
+// TestScan_SearchFallback asserts a repository is still scanned through
+// the Actions workflow listing when code search rejects the query
+// (without spending retries on it) or finds no workflow files.
+func TestScan_SearchFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		search http.HandlerFunc
+	}{
+		{name: "422", search: func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message":"Validation Failed"}`))
+		}},
+		{name: "empty", search: func(w http.ResponseWriter, _ *http.Request) {
+			_ = json.NewEncoder(w).Encode(github.CodeSearchResult{Total: new(0)})
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 2)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			t.Cleanup(viper.Reset)
+
+			owner, repo := "octo", "demo"
+			srv := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(srv.Close)
+			var searches atomic.Int32
+			mux := srv.Config.Handler
+			srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/search/code" {
+					searches.Add(1)
+					tc.search(w, r)
+					return
+				}
+				mux.ServeHTTP(w, r)
+			})
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+			if len(req.Cache.Results) == 0 {
+				t.Fatal("Scan() found nothing, want the listed workflow's finding")
+			}
+			if n := searches.Load(); n != 1 {
+				t.Fatalf("code search called %d times, want 1", n)
+			}
+		})
+	}
+}
+
+// TestFilterRuns covers inclusion, exclusion, and the status fallback
+// for runs that have not completed.
+func TestFilterRuns(t *testing.T) {
+	t.Parallel()
+
+	runs := []*github.WorkflowRun{
+		{ID: new(int64(1)), Status: new("completed"), Conclusion: new("success")},
+		{ID: new(int64(2)), Status: new("completed"), Conclusion: new("failure")},
+		{ID: new(int64(3)), Status: new("completed"), Conclusion: new("skipped")},
+		{ID: new(int64(4)), Status: new("in_progress")},
+	}
+	cases := []struct {
+		name   string
+		filter []string
+		want   []int64
+	}{
+		{name: "empty keeps all", want: []int64{1, 2, 3, 4}},
+		{name: "include failure", filter: []string{"failure"}, want: []int64{2}},
+		{name: "include is case insensitive", filter: []string{" Failure "}, want: []int64{2}},
+		{name: "exclude skipped and in progress", filter: []string{"!skipped", "!in_progress"}, want: []int64{1, 2}},
+		{name: "include and exclude", filter: []string{"success", "failure", "!failure"}, want: []int64{1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got := action.FilterRuns(runs, tc.filter)
+			if len(got) != len(tc.want) {
+				t.Fatalf("FilterRuns(%q) kept %d runs, want %d", tc.filter, len(got), len(tc.want))
+			}
+			for i, r := range got {
+				if r.GetID() != tc.want[i] {
+					t.Fatalf("got[%d]=%d, want %d", i, r.GetID(), tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLatestRun(t *testing.T) {
+	t.Parallel()
+
+	at := func(day int) *github.Timestamp {
+		return &github.Timestamp{Time: time.Date(2025, 3, day, 0, 0, 0, 0, time.UTC)}
+	}
+	cases := []struct {
+		name string
+		runs []*github.WorkflowRun
+		want []int64
+	}{
+		{name: "no runs", want: nil},
+		{name: "single run", runs: []*github.WorkflowRun{{ID: new(int64(1)), CreatedAt: at(14)}}, want: []int64{1}},
+		{
+			name: "newest by created time, not listing order",
+			runs: []*github.WorkflowRun{
+				{ID: new(int64(1)), CreatedAt: at(14)},
+				{ID: new(int64(3)), CreatedAt: at(16)},
+				{ID: new(int64(2)), CreatedAt: at(15)},
+			},
+			want: []int64{3},
+		},
+		{
+			name: "tie broken by higher ID",
+			runs: []*github.WorkflowRun{
+				{ID: new(int64(5)), CreatedAt: at(14)},
+				{ID: new(int64(4)), CreatedAt: at(14)},
+			},
+			want: []int64{5},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got []int64
+			for _, r := range action.LatestRun(tc.runs) {
+				got = append(got, r.GetID())
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("LatestRun kept %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateConclusions(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		filter  []string
+		wantErr bool
+	}{
+		{name: "empty", filter: nil},
+		{name: "conclusions", filter: []string{"failure", "!skipped"}},
+		{name: "status", filter: []string{"!in_progress"}},
+		{name: "typo", filter: []string{"failed"}, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := action.ValidateConclusions(tc.filter)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ValidateConclusions(%q) err=%v, wantErr=%v", tc.filter, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestScan_ScanAttempts asserts scan_attempts scans the earlier
+// attempts of a re-run run, adding only the findings the latest
+// attempt lacks with their attempt recorded, and that earlier attempts
+// are left alone when it is off.
+func TestScan_ScanAttempts(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("scan_attempts", enabled)
+			t.Cleanup(viper.Reset)
+
+			owner, repo := "octo", "demo"
+			base := fakeGitHub(t, owner, repo, ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(base.Close)
+			attemptZip := buildLogZipBytes(t, "DROP_THIS_TOKEN appears here\nDROP_THIS_TOKEN only in attempt two\n")
+			var attemptHits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/octo/demo/actions/workflows/42/runs":
+					_ = json.NewEncoder(w).Encode(github.WorkflowRuns{
+						TotalCount: new(1),
+						WorkflowRuns: []*github.WorkflowRun{{
+							ID:         new(int64(99)),
+							Status:     new("completed"),
+							RunAttempt: new(3),
+							CreatedAt:  &github.Timestamp{Time: time.Now().Add(-12 * time.Hour)},
+						}},
+					})
+				case "/repos/octo/demo/actions/runs/99/attempts/2/logs":
+					attemptHits.Add(1)
+					w.Header().Set("Location", "http://"+r.Host+"/signed-attempt-2")
+					w.WriteHeader(http.StatusFound)
+				case "/signed-attempt-2":
+					_, _ = w.Write(attemptZip)
+				case "/repos/octo/demo/actions/runs/99/attempts/1/logs":
+					attemptHits.Add(1)
+					w.WriteHeader(http.StatusGone)
+				case "/repos/octo/demo/actions/runs/99/attempts/1/jobs":
+					_, _ = w.Write([]byte(`{"total_count":0,"jobs":[]}`))
+				default:
+					base.Config.Handler.ServeHTTP(w, r)
+				}
+			}))
+			t.Cleanup(srv.Close)
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new(repo), Owner: &github.User{Login: new(owner)}}}
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+
+			if !enabled {
+				if len(req.Cache.Results) != 1 || req.Cache.Results[0].RunAttempt != 0 {
+					t.Fatalf("results = %+v, want only the latest attempt's finding without an attempt", req.Cache.Results)
+				}
+				if n := attemptHits.Load(); n != 0 {
+					t.Fatalf("fetched %d earlier attempts with scan_attempts off", n)
+				}
+				return
+			}
+
+			attempts := make(map[string]int)
+			urls := make(map[string]string)
+			for _, r := range req.Cache.Results {
+				attempts[r.LineData] = r.RunAttempt
+				urls[r.LineData] = r.WorkflowRunURL
+			}
+			if len(req.Cache.Results) != 2 {
+				t.Fatalf("results = %+v, want the latest finding and the one only attempt 2 showed", req.Cache.Results)
+			}
+			if got := attempts["DROP_THIS_TOKEN appears here"]; got != 3 {
+				t.Errorf("latest finding RunAttempt = %d, want 3", got)
+			}
+			line := "DROP_THIS_TOKEN only in attempt two"
+			if got := attempts[line]; got != 2 {
+				t.Errorf("earlier finding RunAttempt = %d, want 2", got)
+			}
+			if want := "https://github.com/octo/demo/actions/runs/99/attempts/2"; urls[line] != want {
+				t.Errorf("earlier finding WorkflowRunURL = %q, want %q", urls[line], want)
+			}
+			if n := attemptHits.Load(); n != 2 {
+				t.Errorf("fetched %d earlier attempts, want 2", n)
+			}
+		})
+	}
+}
+
+// TestScan_RepoTimeout asserts a fixed repo_timeout cancels a
+// repository whose log download stalls, reporting it rather than
+// waiting on it, and that the default budget grows with the workflows
+// and runs found, so a repository whose logs arrive after
+// repo_enum_budget has passed is still scanned.
+func TestScan_RepoTimeout(t *testing.T) {
+	cases := []struct {
+		name    string
+		keys    map[string]string
+		delay   time.Duration
+		wantErr bool
+	}{
+		{name: "fixed", keys: map[string]string{"repo_timeout": "300ms"}, delay: time.Minute, wantErr: true},
+		{name: "scaled", keys: map[string]string{"repo_enum_budget": "300ms", "workflow_fetch_budget": "2s", "run_scan_budget": "2s"}, delay: 800 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 0)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			for k, v := range tc.keys {
+				viper.Set(k, v)
+			}
+			t.Cleanup(viper.Reset)
+
+			base := fakeGitHub(t, "octo", "demo", ".github/workflows/ci.yml", "DROP_THIS_TOKEN appears here\n")
+			t.Cleanup(base.Close)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/signed" {
+					select {
+					case <-r.Context().Done():
+						return
+					case <-time.After(tc.delay):
+					}
+				}
+				base.Config.Handler.ServeHTTP(w, r)
+			}))
+			t.Cleanup(srv.Close)
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new("demo"), Owner: &github.User{Login: new("octo")}}}
+
+			began := time.Now()
+			err = action.Scan(t.Context(), newSilentLogger(), req, repos)
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatalf("Scan() error: %v", err)
+				}
+				if len(req.Cache.Results) != 1 {
+					t.Fatalf("results = %+v, want the run's finding", req.Cache.Results)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "budget exhausted in 1 repositories: octo/demo") {
+				t.Fatalf("Scan() error = %v, want octo/demo reported as out of budget", err)
+			}
+			if elapsed := time.Since(began); elapsed > 10*time.Second {
+				t.Fatalf("Scan() took %s, want it canceled soon after the 300ms budget", elapsed)
+			}
+		})
+	}
+}
+
+// TestScan_ScanMetadata asserts the head commit message and the pull
+// request a run belongs to are scanned when enabled, each finding
+// labeled with where it was found, and left alone by default.
+func TestScan_ScanMetadata(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			chdirTemp(t)
+			viper.Set("max_retries", 1)
+			viper.Set("operation_timeout", "30s")
+			viper.Set("scan_yaml", false)
+			viper.Set("scan_commit_messages", enabled)
+			viper.Set("scan_pr_bodies", enabled)
+			t.Cleanup(viper.Reset)
+
+			base := fakeGitHub(t, "octo", "demo", ".github/workflows/ci.yml", "benign log line\n")
+			t.Cleanup(base.Close)
+			var prHits atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/octo/demo/actions/workflows/42/runs":
+					_ = json.NewEncoder(w).Encode(github.WorkflowRuns{
+						TotalCount: new(1),
+						WorkflowRuns: []*github.WorkflowRun{{
+							ID:           new(int64(99)),
+							Status:       new("completed"),
+							Event:        new("pull_request"),
+							HeadSHA:      new("abc123"),
+							HeadCommit:   &github.HeadCommit{Message: new("fix typo\n\nDROP_THIS_TOKEN in the commit")},
+							PullRequests: []*github.PullRequest{{Number: new(7)}},
+							CreatedAt:    &github.Timestamp{Time: time.Now().Add(-12 * time.Hour)},
+						}},
+					})
+				case "/repos/octo/demo/pulls/7":
+					prHits.Add(1)
+					_ = json.NewEncoder(w).Encode(github.PullRequest{
+						Number:  new(7),
+						Title:   new("Update docs"),
+						Body:    new("DROP_THIS_TOKEN in the description"),
+						HTMLURL: new("https://github.com/octo/demo/pull/7"),
+					})
+				default:
+					base.Config.Handler.ServeHTTP(w, r)
+				}
+			}))
+			t.Cleanup(srv.Close)
+			gh, hc := newTestClients(t, srv)
+
+			customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+			if err != nil {
+				t.Fatalf("build IOC: %v", err)
+			}
+			end := time.Now().Add(time.Hour)
+			req := ghscan.NewRequest(ghscan.RequestConfig{
+				CachedResults: map[string]bool{},
+				Client:        gh,
+				HTTPClient:    hc,
+				EndTime:       end,
+				IOC:           customIOC,
+				StartTime:     end.Add(-7 * 24 * time.Hour),
+				Token:         "test-token",
+			})
+			repos := []*github.Repository{{Name: new("demo"), Owner: &github.User{Login: new("octo")}}}
+			if err := action.Scan(t.Context(), newSilentLogger(), req, repos); err != nil {
+				t.Fatalf("Scan() error: %v", err)
+			}
+
+			if !enabled {
+				if len(req.Cache.Results) != 0 || prHits.Load() != 0 {
+					t.Fatalf("results = %+v after %d pull request fetches, want neither with the metadata scan off", req.Cache.Results, prHits.Load())
+				}
+				return
+			}
+			sources := make(map[string]ghscan.Result)
+			for _, r := range req.Cache.Results {
+				sources[r.Source] = r
+			}
+			if len(req.Cache.Results) != 2 {
+				t.Fatalf("results = %+v, want one commit-message and one pr-body finding", req.Cache.Results)
+			}
+			commit := sources["commit-message"]
+			if commit.LineData != "DROP_THIS_TOKEN in the commit" || commit.CommitSHA != "abc123" ||
+				commit.WorkflowRunURL != "https://github.com/octo/demo/actions/runs/99" {
+				t.Errorf("commit-message finding = %+v", commit)
+			}
+			pr := sources["pr-body"]
+			if pr.LineData != "DROP_THIS_TOKEN in the description" || !strings.Contains(pr.Note, "https://github.com/octo/demo/pull/7") {
+				t.Errorf("pr-body finding = %+v", pr)
+			}
+		})
+	}
+}
+
+// TestResultFromFinding asserts every finding field reported in a
+// Result is carried over by the mapping all log scans share.
+func TestResultFromFinding(t *testing.T) {
+	t.Parallel()
+
+	f := wf.Finding{
+		Encoded:        "ZW5j",
+		Decoded:        "enc",
+		DecodeDepth:    2,
+		LineData:       "line",
+		KeyType:        "RSA PRIVATE KEY",
+		Severity:       "critical",
+		Destination:    "evil.example",
+		Note:           "note",
+		MatchedPattern: "pattern",
+		MatchOffset:    7,
+		Context:        "before\nline",
+		Confidence:     90,
+		Explanation:    "why",
+	}
+	got := action.ResultFromFindingForTest("octo/demo", "shai-hulud", f)
+	want := ghscan.Result{
+		Repository:     "octo/demo",
+		Base64Data:     "ZW5j",
+		DecodedData:    "enc",
+		DecodeDepth:    2,
+		LineData:       "line",
+		KeyTypes:       "RSA PRIVATE KEY",
+		Severity:       "critical",
+		Destination:    "evil.example",
+		Note:           "note",
+		MatchedPattern: "pattern",
+		MatchOffset:    7,
+		Context:        "before\nline",
+		Confidence:     90,
+		Explanation:    "why",
+		IOCName:        "shai-hulud",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("resultFromFinding() = %+v, want %+v", got, want)
+	}
+}
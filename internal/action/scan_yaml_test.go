@@ -82,6 +82,7 @@ func TestScan_YAMLOnly_FindsKnownBadRef(t *testing.T) {
 	viper.Set("operation_timeout", "30s")
 	viper.Set("scan_yaml", true)
 	viper.Set("scan_logs", false)
+	viper.Set("explain", true)
 	t.Cleanup(viper.Reset)
 
 	owner, repo := "octo", "demo"
@@ -150,6 +151,9 @@ jobs:
 	if got.Source != "yaml" {
 		t.Fatalf("Source=%q, want yaml", got.Source)
 	}
+	if want := "step references tj-actions/changed-files at v36, which the IOC corpus lists as compromised"; got.Explanation != want {
+		t.Fatalf("Explanation=%q, want %q", got.Explanation, want)
+	}
 }
 
 func TestScan_YAMLOnly_BenignRefProducesNoFinding(t *testing.T) {
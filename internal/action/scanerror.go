@@ -0,0 +1,211 @@
+package action
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/ghscan/internal/request"
+	"github.com/google/go-github/v86/github"
+)
+
+// ErrorCategory classifies the cause of a [ScanError], so callers can
+// tell a rate-limited scan from one the token may not see.
+type ErrorCategory int
+
+const (
+	// ErrorOther is a failure no other category describes, such as a
+	// network error or a GitHub server error.
+	ErrorOther ErrorCategory = iota
+	// ErrorRateLimit is GitHub's primary or secondary rate limit.
+	ErrorRateLimit
+	// ErrorPermission is a token GitHub rejects (401) or that lacks
+	// access (403 not caused by a rate limit).
+	ErrorPermission
+	// ErrorNotFound is a missing or deleted resource (404, 410).
+	ErrorNotFound
+	// ErrorUnavailable is a repository GitHub withholds (451, or 403
+	// "Repository access blocked").
+	ErrorUnavailable
+	// ErrorParse is a response or log archive that cannot be decoded.
+	ErrorParse
+	// ErrorTimeout is an operation or repository whose time budget ran
+	// out.
+	ErrorTimeout
+	// ErrorCanceled is a scan canceled before the operation finished.
+	ErrorCanceled
+)
+
+var errorCategoryNames = [...]string{
+	ErrorOther:       "other",
+	ErrorRateLimit:   "rate-limit",
+	ErrorPermission:  "permission",
+	ErrorNotFound:    "not-found",
+	ErrorUnavailable: "unavailable",
+	ErrorParse:       "parse",
+	ErrorTimeout:     "timeout",
+	ErrorCanceled:    "canceled",
+}
+
+// String returns the category's name, such as "rate-limit".
+func (c ErrorCategory) String() string {
+	if c < 0 || int(c) >= len(errorCategoryNames) {
+		return fmt.Sprintf("ErrorCategory(%d)", int(c))
+	}
+	return errorCategoryNames[c]
+}
+
+// ScanError is a failed scan operation: what failed, where, and why.
+// [Scan], [ScanSource], and [ScanRunRefs] return their failures as
+// ScanErrors, alone or joined with others; [ScanErrors] collects them
+// from the returned error. Err is the underlying error, so errors.Is
+// and errors.As see through a ScanError.
+type ScanError struct {
+	// Category classifies Err.
+	Category ErrorCategory
+	// Op is the operation that failed, such as "downloading logs".
+	Op string
+	// Repository is the owner/repo, or the label a [ScanSource] was
+	// given, the operation worked on.
+	Repository string
+	// Workflow is the workflow path, if the operation concerned one.
+	Workflow string
+	// RunID is the run, if the operation concerned one.
+	RunID int64
+	Err   error
+}
+
+// Error describes the operation, where it failed, and the cause.
+func (e *ScanError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.RunID != 0 {
+		fmt.Fprintf(&b, " for run %d", e.RunID)
+	}
+	if e.Workflow != "" {
+		fmt.Fprintf(&b, " of %s", e.Workflow)
+	}
+	if e.Repository != "" {
+		fmt.Fprintf(&b, " in %s", e.Repository)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ScanError) Unwrap() error { return e.Err }
+
+// newScanError returns err as a ScanError of op. A cancellation caused
+// by the repository's budget running out, which ctx records, is
+// categorized as a timeout.
+func newScanError(ctx context.Context, op, repository, workflow string, runID int64, err error) *ScanError {
+	category := Categorize(err)
+	if category == ErrorCanceled && errors.Is(context.Cause(ctx), errRepoTimeout) {
+		category = ErrorTimeout
+	}
+	return &ScanError{Category: category, Op: op, Repository: repository, Workflow: workflow, RunID: runID, Err: err}
+}
+
+// Categorize returns the [ErrorCategory] of err. A [ScanError] in err's
+// chain reports its own category.
+func Categorize(err error) ErrorCategory {
+	var scanErr *ScanError
+	if errors.As(err, &scanErr) {
+		return scanErr.Category
+	}
+
+	var (
+		rateErr  *github.RateLimitError
+		abuseErr *github.AbuseRateLimitError
+		uerr     *request.UnavailableError
+		ghErr    *github.ErrorResponse
+		syntax   *json.SyntaxError
+		typeErr  *json.UnmarshalTypeError
+	)
+	switch {
+	case err == nil:
+		return ErrorOther
+	case errors.Is(err, errRepoTimeout), errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorCanceled
+	case errors.As(err, &rateErr), errors.As(err, &abuseErr):
+		return ErrorRateLimit
+	case errors.As(err, &uerr):
+		return ErrorUnavailable
+	case errors.Is(err, zip.ErrFormat), errors.As(err, &syntax), errors.As(err, &typeErr):
+		return ErrorParse
+	case errors.As(err, &ghErr) && ghErr.Response != nil:
+		switch ghErr.Response.StatusCode {
+		case http.StatusTooManyRequests:
+			return ErrorRateLimit
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ErrorPermission
+		case http.StatusNotFound, http.StatusGone:
+			return ErrorNotFound
+		}
+	}
+	return ErrorOther
+}
+
+// ScanErrors returns every [ScanError] in err's tree, following joined
+// and wrapped errors, in order.
+func ScanErrors(err error) []*ScanError {
+	var out []*ScanError
+	var walk func(error)
+	walk = func(err error) {
+		if scanErr, ok := err.(*ScanError); ok {
+			out = append(out, scanErr)
+			return
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walk(e)
+			}
+		case interface{ Unwrap() error }:
+			walk(u.Unwrap())
+		}
+	}
+	walk(err)
+	return out
+}
+
+// failureSummary counts failures in a single line while keeping each
+// one, and the sentinel that names them, for errors.Is, errors.As, and
+// [ScanErrors].
+type failureSummary struct {
+	msg  string
+	errs []error
+}
+
+func (e *failureSummary) Error() string { return e.msg }
+
+func (e *failureSummary) Unwrap() []error { return e.errs }
+
+// summarizeRepos returns the failures of whole repositories as one
+// error, led by sentinel and naming the repositories in order, or nil
+// when there are none.
+func summarizeRepos(sentinel error, failed []*ScanError) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(failed))
+	errs := []error{sentinel}
+	for _, f := range failed {
+		names = append(names, f.Repository)
+		errs = append(errs, f)
+	}
+	slices.Sort(names)
+	return &failureSummary{
+		msg:  fmt.Sprintf("%v in %d repositories: %s", sentinel, len(failed), strings.Join(names, ", ")),
+		errs: errs,
+	}
+}
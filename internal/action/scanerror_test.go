@@ -0,0 +1,81 @@
+package action_test
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestCategorize asserts each kind of failure maps to its category,
+// through any wrapping.
+func TestCategorize(t *testing.T) {
+	t.Parallel()
+
+	status := func(code int) error {
+		return &github.ErrorResponse{Response: &http.Response{StatusCode: code}}
+	}
+	cases := []struct {
+		name string
+		err  error
+		want action.ErrorCategory
+	}{
+		{name: "plain", err: errors.New("boom"), want: action.ErrorOther},
+		{name: "server error", err: status(http.StatusBadGateway), want: action.ErrorOther},
+		{name: "rate limit", err: &github.RateLimitError{}, want: action.ErrorRateLimit},
+		{name: "secondary rate limit", err: fmt.Errorf("listing: %w", &github.AbuseRateLimitError{}), want: action.ErrorRateLimit},
+		{name: "429", err: status(http.StatusTooManyRequests), want: action.ErrorRateLimit},
+		{name: "401", err: status(http.StatusUnauthorized), want: action.ErrorPermission},
+		{name: "403", err: status(http.StatusForbidden), want: action.ErrorPermission},
+		{name: "404", err: status(http.StatusNotFound), want: action.ErrorNotFound},
+		{name: "410", err: status(http.StatusGone), want: action.ErrorNotFound},
+		{name: "withheld", err: &request.UnavailableError{Reason: "dmca"}, want: action.ErrorUnavailable},
+		{name: "bad archive", err: fmt.Errorf("reading: %w", zip.ErrFormat), want: action.ErrorParse},
+		{name: "deadline", err: context.DeadlineExceeded, want: action.ErrorTimeout},
+		{name: "canceled", err: context.Canceled, want: action.ErrorCanceled},
+		{name: "scan error keeps its category", err: &action.ScanError{Category: action.ErrorParse, Err: errors.New("boom")}, want: action.ErrorParse},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := action.Categorize(tc.err); got != tc.want {
+				t.Fatalf("Categorize(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestScanErrors asserts every ScanError is found through joined and
+// wrapped errors, that its message names where it failed, and that it
+// unwraps to its cause.
+func TestScanErrors(t *testing.T) {
+	t.Parallel()
+
+	cause := errors.New("API rate limit exceeded")
+	run := &action.ScanError{Category: action.ErrorRateLimit, Op: "downloading logs", Repository: "octo/demo", Workflow: ".github/workflows/ci.yml", RunID: 99, Err: cause}
+	repo := &action.ScanError{Category: action.ErrorTimeout, Op: "scanning repository", Repository: "octo/slow", Err: context.DeadlineExceeded}
+	err := errors.Join(fmt.Errorf("scan: %w", run), errors.New("unrelated"), repo)
+
+	got := action.ScanErrors(err)
+	if len(got) != 2 || got[0] != run || got[1] != repo {
+		t.Fatalf("ScanErrors() = %v, want both scan errors in order", got)
+	}
+	if want := "downloading logs for run 99 of .github/workflows/ci.yml in octo/demo: API rate limit exceeded"; run.Error() != want {
+		t.Fatalf("Error() = %q, want %q", run.Error(), want)
+	}
+	if !errors.Is(err, cause) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("errors.Is does not see through %v", err)
+	}
+	if got := action.ScanErrors(errors.New("boom")); got != nil {
+		t.Fatalf("ScanErrors() = %v for a plain error, want none", got)
+	}
+	if got := action.ErrorRateLimit.String(); got != "rate-limit" {
+		t.Fatalf("String() = %q, want rate-limit", got)
+	}
+}
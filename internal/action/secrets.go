@@ -0,0 +1,82 @@
+package action
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// correlateSecretsKey attaches the secrets a workflow references to
+// the log and summary findings of its runs. Defaults to false because
+// it costs one contents API call per distinct commit with findings.
+const correlateSecretsKey = "correlate_secrets"
+
+// secretRefs resolves the secrets one workflow file references at the
+// commit each run executed, so a finding in the run's logs can name
+// the secrets that were within the payload's reach. Each commit is
+// fetched at most once, and only for runs that produced findings.
+type secretRefs struct {
+	logger     *clog.Logger
+	req        *ghscan.Request
+	breaker    *request.Breaker
+	maxRetries int
+	wfPath     string
+
+	mu    sync.Mutex
+	bySHA map[string]func() []string
+}
+
+func newSecretRefs(logger *clog.Logger, req *ghscan.Request, breaker *request.Breaker, maxRetries int, wfPath string) *secretRefs {
+	return &secretRefs{
+		logger:     logger,
+		req:        req,
+		breaker:    breaker,
+		maxRetries: maxRetries,
+		wfPath:     wfPath,
+		bySHA:      make(map[string]func() []string),
+	}
+}
+
+// forRun returns the secrets the workflow references at run's head
+// commit, or at the default branch when the run carries none. A nil
+// receiver, as when correlation is disabled, returns nil.
+func (s *secretRefs) forRun(ctx context.Context, run *github.WorkflowRun) []string {
+	if s == nil {
+		return nil
+	}
+	sha := run.GetHeadSHA()
+	s.mu.Lock()
+	load, ok := s.bySHA[sha]
+	if !ok {
+		load = sync.OnceValue(func() []string { return s.load(ctx, sha) })
+		s.bySHA[sha] = load
+	}
+	s.mu.Unlock()
+	return load()
+}
+
+// load fetches and parses the workflow at ref. Correlation is best
+// effort: a failure is logged and leaves the findings without names.
+func (s *secretRefs) load(ctx context.Context, ref string) []string {
+	var body []byte
+	err := s.breaker.WithRetryN(ctx, s.logger, s.maxRetries, func() error {
+		var err error
+		body, err = wf.FetchWorkflowYAML(ctx, s.req.Client(), s.req.Owner, s.req.RepoName, s.wfPath, ref)
+		return err
+	})
+	if err != nil {
+		s.logger.Warnf("Skipping secret correlation for %s in %s/%s at %q: %v", s.wfPath, s.req.Owner, s.req.RepoName, ref, err)
+		return nil
+	}
+	secrets, err := wf.ParseSecretRefs(body)
+	if err != nil {
+		s.logger.Warnf("Skipping secret correlation for %s in %s/%s at %q: %v", s.wfPath, s.req.Owner, s.req.RepoName, ref, err)
+		return nil
+	}
+	return secrets
+}
@@ -0,0 +1,121 @@
+package action
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
+)
+
+// ScanSource scans the logs of every run src lists in the request's
+// time window and appends the findings to req.Cache.Results, recorded
+// under repository. It is the provider-neutral counterpart of Scan's
+// log path: only the log detectors run, and the GitHub-specific
+// enrichments (YAML, history, summaries, secrets) do not apply.
+func ScanSource(ctx context.Context, logger *clog.Logger, req *ghscan.Request, src wf.LogSource, repository string) error {
+	if req == nil {
+		return fmt.Errorf("req cannot be nil")
+	}
+	if src == nil {
+		return fmt.Errorf("src cannot be nil")
+	}
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+
+	maxRetries := resolveMaxRetries()
+	// runBudget bounds each run's log retrieval; zero leaves it to ctx.
+	runBudget := resolveDuration(runScanBudgetKey, cmp.Or(req.Timeout, viper.GetDuration("operation_timeout")))
+	breaker := request.NewBreaker(resolveBreakerThreshold())
+	failed := newFailures()
+
+	var runs []wf.Run
+	err := breaker.WithRetryN(ctx, logger, maxRetries, func() error {
+		var err error
+		runs, err = src.ListRuns(ctx, req.StartTime, req.EndTime)
+		return err
+	})
+	if err != nil {
+		return newScanError(ctx, "listing runs", repository, "", 0, err)
+	}
+	logger.Infof("Found %d runs in %s", len(runs), repository)
+
+	var (
+		resultsMu  sync.Mutex
+		runResults []ghscan.Result
+	)
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fanOutLimit)
+	for _, run := range runs {
+		g.Go(func() error {
+			runCtx, runCancel := gCtx, context.CancelFunc(func() {})
+			if runBudget > 0 {
+				runCtx, runCancel = context.WithTimeout(gCtx, runBudget)
+			}
+			defer runCancel()
+
+			var rc io.ReadCloser
+			err := breaker.WithRetryN(runCtx, logger, maxRetries, func() error {
+				var err error
+				rc, err = src.GetRunLogs(runCtx, run)
+				if errors.Is(err, wf.ErrRunHasNoLogs) {
+					return request.Permanent(err)
+				}
+				return err
+			})
+			if errors.Is(err, wf.ErrRunHasNoLogs) {
+				logger.Debugf("Skipping run %d in %s: %v", run.ID, repository, err)
+				return nil
+			}
+			if err != nil {
+				return failed.run(gCtx, newScanError(ctx, "downloading logs", repository, "", run.ID, err))
+			}
+			defer func() { _ = rc.Close() }()
+
+			logText, err := io.ReadAll(rc)
+			if err != nil {
+				return failed.run(gCtx, newScanError(ctx, "reading logs", repository, "", run.ID, err))
+			}
+			findings, found := wf.ParseLogsWithOptions(logger, string(logText), run.ID, req.IOC, req.LogOptions)
+			if req.Logs != nil {
+				if err := req.Logs.StoreLog(runCtx, repository, run.ID, string(logText), found); err != nil {
+					logger.Warnf("Failed to keep log for run %d in %s: %v", run.ID, repository, err)
+				}
+			}
+			if !found || len(findings) == 0 {
+				return nil
+			}
+
+			results := make([]ghscan.Result, 0, len(findings))
+			for _, finding := range findings {
+				res := resultFromFinding(repository, req.IOC.GetName(), finding)
+				res.WorkflowFileName = run.Name
+				res.WorkflowRunURL = run.URL
+				res.RunStatus = run.Status
+				res.RunConclusion = run.Conclusion
+				results = append(results, res)
+			}
+			resultsMu.Lock()
+			runResults = append(runResults, results...)
+			resultsMu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	req.Stamp(runResults)
+	req.Cache.Results = append(req.Cache.Results, runResults...)
+	newProgressFlusher(logger, req.Sink, 0).finishRepo(ctx, runResults)
+	return failed.report(logger)
+}
@@ -0,0 +1,78 @@
+package action_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/action"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/spf13/viper"
+)
+
+// fakeSource is a workflow.LogSource serving fixed runs and logs.
+type fakeSource struct {
+	runs []workflow.Run
+	logs map[int64]string
+}
+
+func (s *fakeSource) ListRuns(context.Context, time.Time, time.Time) ([]workflow.Run, error) {
+	return s.runs, nil
+}
+
+func (s *fakeSource) GetRunLogs(_ context.Context, run workflow.Run) (io.ReadCloser, error) {
+	logs, ok := s.logs[run.ID]
+	if !ok {
+		return nil, fmt.Errorf("%w: run %d", workflow.ErrRunHasNoLogs, run.ID)
+	}
+	return io.NopCloser(strings.NewReader(logs)), nil
+}
+
+// TestScanSource asserts runs from a non-GitHub source are scanned with
+// the log detectors and recorded under the given repository label,
+// while a run without logs is skipped rather than failing the scan.
+func TestScanSource(t *testing.T) {
+	viper.Set("max_retries", 1)
+	t.Cleanup(viper.Reset)
+
+	customIOC, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	sink := &recordingSink{}
+	req := ghscan.NewRequest(ghscan.RequestConfig{
+		CachedResults: map[string]bool{},
+		IOC:           customIOC,
+		Sink:          sink,
+		ScanID:        "scan-1",
+	})
+	src := &fakeSource{
+		runs: []workflow.Run{
+			{ID: 1, Name: "main", URL: "https://gitlab.example/p/1", Status: "success"},
+			{ID: 2, Name: "main", Status: "success"},
+		},
+		logs: map[int64]string{1: "===== JOB ID: 11 =====\nDROP_THIS_TOKEN appears here\n"},
+	}
+
+	if err := action.ScanSource(t.Context(), newSilentLogger(), req, src, "group/project"); err != nil {
+		t.Fatalf("ScanSource() error = %v", err)
+	}
+	if len(req.Cache.Results) != 1 {
+		t.Fatalf("results = %+v, want one finding", req.Cache.Results)
+	}
+	got := req.Cache.Results[0]
+	if got.Repository != "group/project" || got.WorkflowFileName != "main" || got.WorkflowRunURL != "https://gitlab.example/p/1" {
+		t.Fatalf("result = %+v, want it attributed to the pipeline", got)
+	}
+	if got.RunStatus != "success" || got.ScanID != "scan-1" || got.IOCName != "test-only" {
+		t.Fatalf("result = %+v, want run status, scan ID, and IOC name recorded", got)
+	}
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 1 {
+		t.Fatalf("sink batches = %+v, want the finding flushed once", sink.batches)
+	}
+}
@@ -0,0 +1,195 @@
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/internal/request"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	wf "github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+	"golang.org/x/sync/errgroup"
+)
+
+// resolveTagsKey enables resolving every uses: reference pinned by tag
+// to the commit it points at now, and reporting tags that resolve to a
+// known-bad commit. Defaults to false because it costs at least one
+// request per referenced tag.
+const resolveTagsKey = "resolve_tags"
+
+// tagPinSource labels the findings of the tag resolver.
+const tagPinSource = "tag-pin"
+
+// tagResolution memoizes the commit one action tag resolves to. mu
+// serializes lookups of the tag; done is set once it resolved or is
+// known not to exist.
+type tagResolution struct {
+	mu   sync.Mutex
+	done bool
+	sha  string
+}
+
+// tagResolver resolves the tags workflows pin actions to and matches
+// the commits against the IOC content and the corpus's known-bad refs.
+// This catches a tag force-pushed to a malicious commit, as in the
+// tj-actions/changed-files compromise, before any run has used it.
+// Resolutions are memoized per action repository and tag for the whole
+// Scan, so a tag used across an organization is resolved once. Only
+// successes and missing tags are memoized: a lookup that failed, or
+// was cut short by one repository's timeout, is retried by the next
+// repository that uses the tag.
+type tagResolver struct {
+	logger     *clog.Logger
+	maxRetries int
+	findIOC    *ioc.IOC
+	corpus     *ioc.Corpus
+
+	mu    sync.Mutex
+	cache map[string]*tagResolution
+}
+
+func newTagResolver(logger *clog.Logger, req *ghscan.Request, maxRetries int) (*tagResolver, error) {
+	corpus, err := iocCorpusFor(req)
+	if err != nil {
+		return nil, err
+	}
+	return &tagResolver{
+		logger:     logger,
+		maxRetries: maxRetries,
+		findIOC:    req.IOC,
+		corpus:     corpus,
+		cache:      make(map[string]*tagResolution),
+	}, nil
+}
+
+// scanRepo resolves the tag pins of every workflow in uses and appends
+// a "tag-pin" finding to req.Cache.Results for each tag that points at
+// a known-bad commit. Tags the corpus already flags by name are
+// skipped: scanYAML reports those without resolving them.
+func (r *tagResolver) scanRepo(ctx context.Context, req *ghscan.Request, uses []workflowUses) error {
+	var (
+		mu       sync.Mutex
+		findings []ghscan.Result
+	)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(fanOutLimit)
+
+	for _, u := range uses {
+		for _, e := range u.Edges {
+			if e.RefForm != "tag" || r.corpus.MatchActionRef(e.Action, e.Ref) {
+				continue
+			}
+			a, ok := wf.ParseActionRef(e.Uses)
+			if !ok {
+				continue
+			}
+			g.Go(func() error {
+				sha := r.resolve(gCtx, req.Client(), a)
+				name, bad := r.knownBad(e.Action, a, sha)
+				if !bad {
+					return gCtx.Err()
+				}
+				r.logger.Warnf("Tag %s of %s referenced by %s/%s %s resolves to known-bad commit %s",
+					a.Ref, a.Repository(), req.Owner, req.RepoName, u.FileName, sha)
+				res := ghscan.Result{
+					Repository:        fmt.Sprintf("%s/%s", req.Owner, req.RepoName),
+					WorkflowFileName:  u.FileName,
+					WorkflowURL:       u.URL,
+					WorkflowFileSHA:   u.SHA,
+					OffendingUsesLine: e.Uses,
+					ResolvedRefForm:   e.RefForm,
+					JobName:           e.JobName,
+					StepName:          e.StepName,
+					ReachableSecrets:  e.Secrets,
+					CommitSHA:         sha,
+					Source:            tagPinSource,
+					IOCName:           name,
+					Action:            a.Repository(),
+					Note:              fmt.Sprintf("tag %s of %s resolves to known-bad commit %s", a.Ref, a.Repository(), sha),
+					Confidence:        wf.ConfidenceTagPin,
+					Explanation:       explain("tag %s of %s resolves today to commit %s, which %s lists as compromised", a.Ref, a.Repository(), sha, name),
+				}
+				mu.Lock()
+				findings = append(findings, res)
+				mu.Unlock()
+				return gCtx.Err()
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	if len(findings) > 0 {
+		req.Cache.Results = append(req.Cache.Results, findings...)
+	}
+	return nil
+}
+
+// resolve returns the commit a's tag points at, resolving it on first
+// use, or "" when it cannot be resolved. Failures are logged; only a
+// missing tag is remembered, so other failures are retried on the next
+// call.
+func (r *tagResolver) resolve(ctx context.Context, gh *github.Client, a wf.ActionRef) string {
+	key := a.Repository() + "@" + a.Ref
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	if !ok {
+		entry = &tagResolution{}
+		r.cache[key] = entry
+	}
+	r.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.done {
+		return entry.sha
+	}
+	var sha string
+	err := request.WithRetryN(ctx, r.logger, r.maxRetries, func() error {
+		var err error
+		sha, err = wf.ResolveActionTag(ctx, gh, a)
+		return permanentIfNotFound(err)
+	})
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response != nil && ghErr.Response.StatusCode == http.StatusNotFound {
+			// A missing tag is usually a branch that looks like a
+			// version; it is not an error worth a warning, nor worth
+			// asking again.
+			r.logger.Debugf("resolving %s: %v", key, err)
+			entry.done = true
+		} else {
+			r.logger.Warnf("resolving %s: %v", key, err)
+		}
+		return ""
+	}
+	entry.sha, entry.done = sha, true
+	return sha
+}
+
+// knownBad reports whether sha is a known-bad commit of the action,
+// returning the IOC name to attribute it to: the corpus action whose
+// refs list it, or the IOC whose content contains it.
+func (r *tagResolver) knownBad(action string, a wf.ActionRef, sha string) (string, bool) {
+	if sha == "" {
+		return "", false
+	}
+	for _, name := range []string{action, a.Repository()} {
+		if r.corpus.MatchActionRef(name, sha) {
+			return name, true
+		}
+	}
+	if r.findIOC != nil {
+		if m := r.findIOC.GetMatcher(); m != nil && m.MatchAnyString(sha) {
+			return r.findIOC.GetName(), true
+		}
+	}
+	return "", false
+}
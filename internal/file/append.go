@@ -0,0 +1,239 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// journalSuffix is appended to the cache file name to derive the
+// line-delimited JSON journal that incremental flushes append to.
+const journalSuffix = ".ndjson"
+
+// JournalPath returns the on-disk path of the NDJSON journal paired
+// with cacheFile. Like the cache itself, the journal lives under
+// ghscan.ResultsDir.
+func JournalPath(cacheFile string) string {
+	return filepath.Clean(filepath.Join(filepath.Clean(ghscan.ResultsDir), filepath.Clean(cacheFile)+journalSuffix))
+}
+
+// AppenderConfig is the constructor input for [NewAppender]. An empty
+// CacheFile disables the journal; an empty CSVFile disables CSV
+// appending. Both paths are relative to ghscan.ResultsDir.
+type AppenderConfig struct {
+	CacheFile string
+	CSVFile   string
+	// Seed holds results already present in the loaded cache. They are
+	// written to the CSV up front so the file is complete mid-scan; they
+	// are not re-journaled because LoadCache already recovered them.
+	Seed []ghscan.Result
+	// Clean truncates any journal left behind by a previous run. Set it
+	// alongside -clean-cache so discarded findings do not resurface.
+	Clean bool
+}
+
+// Appender persists findings incrementally as the scan progresses.
+// Every Append call costs O(batch) IO: results are written as NDJSON
+// lines to the journal and as rows to the open CSV file, and both are
+// synced before returning. [WriteResults] remains the consolidation
+// step that rewrites the canonical cache, JSON, and CSV outputs once
+// the scan completes and then removes the journal.
+//
+// All exported methods are safe for concurrent use.
+type Appender struct {
+	mu      sync.Mutex
+	logger  *clog.Logger
+	journal *os.File
+	csvFile *os.File
+	csvW    *csv.Writer
+}
+
+var _ ghscan.ResultSink = (*Appender)(nil)
+
+// NewAppender opens the journal and CSV destinations named in cfg. The
+// journal is opened in append mode so results flushed by a crashed run
+// survive until the next successful consolidation; the CSV is
+// truncated, given a header, and seeded with cfg.Seed.
+func NewAppender(ctx context.Context, logger *clog.Logger, cfg AppenderConfig) (*Appender, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return nil, fmt.Errorf("creating results directory: %w", err)
+	}
+
+	a := &Appender{logger: logger}
+
+	if cfg.CacheFile != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		if cfg.Clean {
+			flags |= os.O_TRUNC
+		}
+		f, err := openFile(JournalPath(cfg.CacheFile), flags)
+		if err != nil {
+			return nil, fmt.Errorf("opening journal: %w", err)
+		}
+		a.journal = f
+	}
+
+	if cfg.CSVFile != "" {
+		clean := filepath.Clean(filepath.Join(ghscan.ResultsDir, cfg.CSVFile))
+		if dir := filepath.Dir(clean); dir != "." && dir != "/" {
+			if err := mkdirAll(dir); err != nil {
+				_ = a.Close()
+				return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+			}
+		}
+		f, err := openFile(clean, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		if err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("failed to open file %s: %w", clean, err)
+		}
+		a.csvFile = f
+		a.csvW = csv.NewWriter(f)
+		if err := a.csvW.Write(csvHeader()); err != nil {
+			_ = a.Close()
+			return nil, fmt.Errorf("writing CSV header: %w", err)
+		}
+		if err := a.writeCSVRows(redactResults(cfg.Seed)); err != nil {
+			_ = a.Close()
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Append writes results to every open destination. Errors from each
+// destination are joined so a CSV failure does not mask a journal
+// failure. A nil Appender or an empty batch is a no-op.
+func (a *Appender) Append(ctx context.Context, results []ghscan.Result) error {
+	if a == nil || len(results) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	results = redactResults(results)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var errs error
+	if a.journal != nil {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, res := range results {
+			if err := enc.Encode(res); err != nil {
+				return fmt.Errorf("encoding journal entry: %w", err)
+			}
+		}
+		if _, err := a.journal.Write(buf.Bytes()); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("appending to journal: %w", err))
+		} else if err := a.journal.Sync(); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("syncing journal: %w", err))
+		}
+	}
+	if a.csvW != nil {
+		errs = errors.Join(errs, a.writeCSVRows(results))
+	}
+
+	if errs == nil {
+		a.logger.Infof("Appended %d results to incremental outputs", len(results))
+	}
+	return errs
+}
+
+func (a *Appender) writeCSVRows(results []ghscan.Result) error {
+	for _, res := range results {
+		if res.IsEmpty() {
+			continue
+		}
+		if err := a.csvW.Write(csvRecord(res)); err != nil {
+			return fmt.Errorf("appending CSV row: %w", err)
+		}
+	}
+	a.csvW.Flush()
+	if err := a.csvW.Error(); err != nil {
+		return fmt.Errorf("flushing CSV: %w", err)
+	}
+	if err := a.csvFile.Sync(); err != nil {
+		return fmt.Errorf("syncing CSV: %w", err)
+	}
+	return nil
+}
+
+// Close releases the journal and CSV file handles. The journal itself
+// is left on disk; [WriteResults] removes it after a successful
+// consolidation.
+func (a *Appender) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var errs error
+	if a.csvW != nil {
+		a.csvW.Flush()
+		errs = errors.Join(errs, a.csvW.Error())
+		a.csvW = nil
+	}
+	if a.csvFile != nil {
+		errs = errors.Join(errs, a.csvFile.Close())
+		a.csvFile = nil
+	}
+	if a.journal != nil {
+		errs = errors.Join(errs, a.journal.Close())
+		a.journal = nil
+	}
+	return errs
+}
+
+// readJournal decodes every complete line of the NDJSON journal at
+// path. A missing journal yields no results. Undecodable lines (most
+// commonly a torn final write from a crashed run) are skipped and
+// counted so the caller can log them.
+func readJournal(path string) ([]ghscan.Result, int, error) {
+	f, err := os.Open(path) // #nosec G304 -- path derived from the operator-supplied cache file
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var (
+		results []ghscan.Result
+		skipped int
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var res ghscan.Result
+		if err := json.Unmarshal(line, &res); err != nil {
+			skipped++
+			continue
+		}
+		results = append(results, res)
+	}
+	if err := sc.Err(); err != nil {
+		return results, skipped, err
+	}
+	return results, skipped, nil
+}
@@ -0,0 +1,172 @@
+package file_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+func readCSVRows(t *testing.T, path string) [][]string {
+	t.Helper()
+	f, err := os.Open(path) // #nosec G304 -- test-controlled path
+	if err != nil {
+		t.Fatalf("open csv: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("parse csv: %v", err)
+	}
+	return rows
+}
+
+// TestAppender_AppendsIncrementally verifies that each Append adds
+// exactly the new batch to both the journal and the CSV, and that the
+// CSV is seeded with previously cached results plus a single header.
+func TestAppender_AppendsIncrementally(t *testing.T) {
+	chdirTemp(t)
+
+	seed := []ghscan.Result{{Repository: "o/seed", LineData: "old hit"}}
+	a, err := file.NewAppender(t.Context(), newSilentLogger(), file.AppenderConfig{
+		CacheFile: "cache.json",
+		CSVFile:   "out.csv",
+		Seed:      seed,
+	})
+	if err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+
+	if err := a.Append(t.Context(), []ghscan.Result{{Repository: "o/a", LineData: "hit a"}}); err != nil {
+		t.Fatalf("Append #1: %v", err)
+	}
+	if err := a.Append(t.Context(), []ghscan.Result{
+		{Repository: "o/b", LineData: "hit b"},
+		{Repository: "o/empty"},
+	}); err != nil {
+		t.Fatalf("Append #2: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	rows := readCSVRows(t, filepath.Join(ghscan.ResultsDir, "out.csv"))
+	// header + seed + two non-empty appended rows; the empty result is
+	// skipped exactly as writeCSV skips it.
+	if len(rows) != 4 {
+		t.Fatalf("csv rows=%d, want 4: %v", len(rows), rows)
+	}
+	if rows[0][0] != "Repository" || rows[1][0] != "o/seed" || rows[3][0] != "o/b" {
+		t.Fatalf("unexpected csv layout: %v", rows)
+	}
+
+	// Seed rows are not re-journaled; only appended batches are.
+	got := file.LoadCache(t.Context(), newSilentLogger(), "cache.json", false)
+	if len(got.Results) != 3 {
+		t.Fatalf("recovered=%d, want 3 (seed must not be journaled)", len(got.Results))
+	}
+}
+
+// TestLoadCache_RecoversJournalAndSkipsTornLine simulates a crash after
+// several flushes: the consolidated cache holds one result, the journal
+// holds two complete lines and a torn final write. LoadCache must merge
+// the complete lines and drop the torn one.
+func TestLoadCache_RecoversJournalAndSkipsTornLine(t *testing.T) {
+	chdirTemp(t)
+
+	if err := file.WriteResults(t.Context(), newSilentLogger(),
+		ghscan.Cache{Results: []ghscan.Result{{Repository: "o/base", LineData: "x"}}},
+		"cache.json", "", ""); err != nil {
+		t.Fatalf("seed cache: %v", err)
+	}
+
+	journal := `{"repository":"o/r1","line_data":"a"}` + "\n" +
+		`{"repository":"o/r2","line_data":"b"}` + "\n" +
+		`{"repository":"o/r3","line_d`
+	if err := os.WriteFile(file.JournalPath("cache.json"), []byte(journal), 0o600); err != nil {
+		t.Fatalf("seed journal: %v", err)
+	}
+
+	got := file.LoadCache(t.Context(), newSilentLogger(), "cache.json", false)
+	if len(got.Results) != 3 {
+		t.Fatalf("results=%d, want 3 (1 cached + 2 journaled)", len(got.Results))
+	}
+	if got.Results[2].Repository != "o/r2" {
+		t.Fatalf("journaled results not appended in order: %+v", got.Results)
+	}
+}
+
+// TestWriteResults_RemovesJournal asserts the consolidation contract:
+// once the cache file is rewritten, the journal is gone so the next
+// LoadCache does not double-count its entries.
+func TestWriteResults_RemovesJournal(t *testing.T) {
+	chdirTemp(t)
+
+	a, err := file.NewAppender(t.Context(), newSilentLogger(), file.AppenderConfig{CacheFile: "cache.json"})
+	if err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+	results := []ghscan.Result{{Repository: "o/r", LineData: "hit"}}
+	if err := a.Append(t.Context(), results); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if err := file.WriteResults(t.Context(), newSilentLogger(), ghscan.Cache{Results: results}, "cache.json", "", ""); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+	if _, err := os.Stat(file.JournalPath("cache.json")); !os.IsNotExist(err) {
+		t.Fatalf("journal still present after consolidation: %v", err)
+	}
+	got := file.LoadCache(t.Context(), newSilentLogger(), "cache.json", false)
+	if len(got.Results) != 1 {
+		t.Fatalf("results=%d after consolidation, want 1", len(got.Results))
+	}
+}
+
+// TestAppender_CleanTruncatesJournal asserts -clean-cache semantics
+// carry over to the journal: stale entries from a previous run must not
+// resurface on the next load.
+func TestAppender_CleanTruncatesJournal(t *testing.T) {
+	chdirTemp(t)
+
+	if err := os.MkdirAll(ghscan.ResultsDir, 0o750); err != nil {
+		t.Fatalf("mkdir results: %v", err)
+	}
+	if err := os.WriteFile(file.JournalPath("cache.json"), []byte(`{"repository":"o/stale"}`+"\n"), 0o600); err != nil {
+		t.Fatalf("seed journal: %v", err)
+	}
+
+	a, err := file.NewAppender(t.Context(), newSilentLogger(), file.AppenderConfig{CacheFile: "cache.json", Clean: true})
+	if err != nil {
+		t.Fatalf("NewAppender: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got := file.LoadCache(t.Context(), newSilentLogger(), "cache.json", false)
+	if len(got.Results) != 0 {
+		t.Fatalf("results=%d, want 0 after clean", len(got.Results))
+	}
+}
+
+// TestAppender_NilIsNoop documents that a nil Appender is safe to
+// Append to and Close, which keeps the caller's error path simple when
+// construction fails.
+func TestAppender_NilIsNoop(t *testing.T) {
+	t.Parallel()
+
+	var a *file.Appender
+	if err := a.Append(t.Context(), []ghscan.Result{{Repository: "o/r"}}); err != nil {
+		t.Fatalf("nil Append: %v", err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatalf("nil Close: %v", err)
+	}
+}
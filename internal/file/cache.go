@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -21,18 +22,106 @@ func LoadCache(ctx context.Context, logger *clog.Logger, cacheFile string, clean
 		return cache
 	}
 
+	if cleanCache {
+		logger.Infof("No existing cache found at %s, starting fresh", cacheFile)
+		return cache
+	}
+
 	cf := filepath.Clean(filepath.Join(filepath.Clean(ghscan.ResultsDir), filepath.Clean(cacheFile)))
 	data, err := os.ReadFile(cf)
-	if err != nil || cleanCache {
+	switch {
+	case err != nil:
 		logger.Infof("No existing cache found at %s, starting fresh", cacheFile)
-		return cache
+	default:
+		if err := json.Unmarshal(data, &cache); err != nil {
+			logger.Warnf("Error parsing existing cache file: %v, starting fresh", err)
+			cache = ghscan.Cache{}
+			break
+		}
+		logger.Infof("Loaded %d existing results from cache", len(cache.Results))
 	}
 
-	if err := json.Unmarshal(data, &cache); err != nil {
-		logger.Warnf("Error parsing existing cache file: %v, starting fresh", err)
-		return ghscan.Cache{}
+	if cacheFile == "" {
+		ghscan.AddFingerprints(cache.Results)
+		return cache
 	}
 
-	logger.Infof("Loaded %d existing results from cache", len(cache.Results))
+	// Results flushed to the journal by a run that never reached its
+	// final consolidation are folded back in so a crash loses nothing
+	// that was already appended.
+	journaled, skipped, err := readJournal(JournalPath(cacheFile))
+	if err != nil {
+		logger.Warnf("Error reading cache journal: %v", err)
+	}
+	if skipped > 0 {
+		logger.Warnf("Skipped %d unreadable cache journal entries", skipped)
+	}
+	if len(journaled) > 0 {
+		logger.Infof("Recovered %d results from cache journal", len(journaled))
+		cache.Results = append(cache.Results, journaled...)
+	}
+	ghscan.AddFingerprints(cache.Results)
 	return cache
 }
+
+// MergeCaches loads every cache file in order and concatenates their
+// results, dropping duplicates that differ at most in ScanID so
+// overlapping shards count each finding once. Paths are resolved
+// under ghscan.ResultsDir exactly as LoadCache resolves its cacheFile.
+// Unlike LoadCache, a missing input is an error: silently merging
+// fewer shards than requested would produce an incomplete report that
+// looks authoritative.
+func MergeCaches(ctx context.Context, logger *clog.Logger, cacheFiles []string) (ghscan.Cache, error) {
+	var merged ghscan.Cache
+	seen := make(map[string]struct{})
+	for _, cacheFile := range cacheFiles {
+		if err := ctx.Err(); err != nil {
+			return ghscan.Cache{}, err
+		}
+		cf := filepath.Clean(filepath.Join(filepath.Clean(ghscan.ResultsDir), filepath.Clean(cacheFile)))
+		if _, err := os.Stat(cf); err != nil {
+			return ghscan.Cache{}, fmt.Errorf("reading cache %s: %w", cacheFile, err)
+		}
+
+		added := 0
+		for _, res := range LoadCache(ctx, logger, cacheFile, false).Results {
+			// The same finding from two invocations differs only by
+			// ScanID, so it is left out of the key.
+			keyed := res
+			keyed.ScanID = ""
+			key, err := json.Marshal(keyed)
+			if err != nil {
+				return ghscan.Cache{}, fmt.Errorf("keying result from %s: %w", cacheFile, err)
+			}
+			if _, dup := seen[string(key)]; dup {
+				continue
+			}
+			seen[string(key)] = struct{}{}
+			merged.Results = append(merged.Results, res)
+			added++
+		}
+		logger.Infof("Merged %d new results from %s", added, cacheFile)
+	}
+	return merged, nil
+}
+
+// LoadBaseline reads a previous scan's cache for comparison against the
+// current results. The path is resolved under ghscan.ResultsDir like
+// any cache, but unlike LoadCache a missing or unparsable file is an
+// error: comparing against an empty baseline would report every known
+// finding as new.
+func LoadBaseline(ctx context.Context, baselineFile string) (ghscan.Cache, error) {
+	if err := ctx.Err(); err != nil {
+		return ghscan.Cache{}, err
+	}
+	bf := filepath.Clean(filepath.Join(filepath.Clean(ghscan.ResultsDir), filepath.Clean(baselineFile)))
+	data, err := os.ReadFile(bf)
+	if err != nil {
+		return ghscan.Cache{}, fmt.Errorf("reading baseline %s: %w", baselineFile, err)
+	}
+	var cache ghscan.Cache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return ghscan.Cache{}, fmt.Errorf("parsing baseline %s: %w", baselineFile, err)
+	}
+	return cache, nil
+}
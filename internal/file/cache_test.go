@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/chainguard-dev/clog"
@@ -112,6 +113,78 @@ func TestLoadCache(t *testing.T) {
 			if len(got.Results) != tc.wantResults {
 				t.Fatalf("results=%d, want %d", len(got.Results), tc.wantResults)
 			}
+			// Caches written before fingerprints existed are filled in.
+			for i, r := range got.Results {
+				if want := ghscan.Fingerprint(r); r.Fingerprint != want {
+					t.Fatalf("results[%d].Fingerprint=%q, want %q", i, r.Fingerprint, want)
+				}
+			}
 		})
 	}
 }
+
+// TestMergeCaches asserts shards are concatenated in argument order,
+// exact duplicates across shards are dropped, and a missing shard is an
+// error rather than a silently smaller report.
+func TestMergeCaches(t *testing.T) {
+	chdirTemp(t)
+
+	// The shared finding was found by both shard invocations, so only
+	// its ScanID differs.
+	shared := ghscan.Result{Repository: "o/shared", LineData: "hit", ScanID: "scan-a"}
+	sharedLater := shared
+	sharedLater.ScanID = "scan-b"
+	for name, results := range map[string][]ghscan.Result{
+		"a.json": {{Repository: "o/a", LineData: "hit"}, shared},
+		"b.json": {sharedLater, {Repository: "o/b", LineData: "hit"}},
+	} {
+		if err := file.WriteResults(t.Context(), newSilentLogger(), ghscan.Cache{Results: results}, name, "", ""); err != nil {
+			t.Fatalf("seed %s: %v", name, err)
+		}
+	}
+
+	got, err := file.MergeCaches(t.Context(), newSilentLogger(), []string{"a.json", "b.json"})
+	if err != nil {
+		t.Fatalf("MergeCaches: %v", err)
+	}
+	var repos []string
+	for _, r := range got.Results {
+		repos = append(repos, r.Repository)
+	}
+	if want := []string{"o/a", "o/shared", "o/b"}; !slices.Equal(repos, want) {
+		t.Fatalf("merged repos=%v, want %v", repos, want)
+	}
+
+	if _, err := file.MergeCaches(t.Context(), newSilentLogger(), []string{"a.json", "missing.json"}); err == nil {
+		t.Fatal("expected error for missing shard")
+	}
+}
+
+// TestLoadBaseline asserts a baseline cache is read from the results
+// directory, and that a missing or corrupt baseline is an error rather
+// than an empty baseline that would make every finding look new.
+func TestLoadBaseline(t *testing.T) {
+	chdirTemp(t)
+
+	seed := ghscan.Cache{Results: []ghscan.Result{{Repository: "o/a", LineData: "hit"}}}
+	if err := file.WriteResults(t.Context(), newSilentLogger(), seed, "baseline.json", "", ""); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	got, err := file.LoadBaseline(t.Context(), "baseline.json")
+	if err != nil {
+		t.Fatalf("LoadBaseline: %v", err)
+	}
+	if len(got.Results) != 1 || got.Results[0].Repository != "o/a" {
+		t.Fatalf("baseline results=%+v, want the seeded result", got.Results)
+	}
+
+	if _, err := file.LoadBaseline(t.Context(), "missing.json"); err == nil {
+		t.Fatal("expected error for missing baseline")
+	}
+	if err := os.WriteFile(filepath.Join(ghscan.ResultsDir, "corrupt.json"), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.LoadBaseline(t.Context(), "corrupt.json"); err == nil {
+		t.Fatal("expected error for corrupt baseline")
+	}
+}
@@ -3,23 +3,66 @@
 //
 // Public surface:
 //
-//   - [LoadCache] decodes the JSON findings cache. Cancelled contexts
-//     and unreadable files yield an empty cache rather than an error so
-//     callers can always proceed with a fresh scan.
+//   - [LoadCache] decodes the JSON findings cache and folds in any
+//     results left in the NDJSON journal by a run that did not reach
+//     consolidation. Cancelled contexts and unreadable files yield an
+//     empty cache rather than an error so callers can always proceed
+//     with a fresh scan.
+//   - [LoadBaseline] reads a previous cache for regression gating;
+//     unlike LoadCache, a missing or corrupt file is an error.
+//   - [MergeCaches] loads several caches, e.g. from sharded scans,
+//     and concatenates their results without exact duplicates.
+//   - [Appender] is the incremental writer. Each Append costs O(batch)
+//     IO: results are appended as NDJSON lines to the journal beside
+//     the cache file and as rows to an open CSV file.
 //   - [WriteCache] is the streaming intermediate writer used by the
 //     Scanner. It writes to a temp file and renames atomically; calls
 //     are serialized via a package-level mutex so concurrent writers
 //     against the same on-disk path never observe a torn file.
 //   - [WriteResults] is the final-output writer that emits the cache,
-//     a JSON output file, and a CSV output file in one pass.
+//     a JSON output file, and a CSV output file in one pass. It is the
+//     consolidation step for the Appender and removes the journal once
+//     the cache has been rewritten.
+//   - [WritePerRepoResults] splits the final cache by repository and
+//     writes an owner__repo.json / owner__repo.csv pair for each.
+//   - [WriteSeverityReport] writes the JSON output as a
+//     [SeverityReport] sectioned by severity and the CSV sorted by
+//     severity; [GroupBySeverity] and [SortBySeverity] build them.
+//   - [WriteNestedReport] writes the JSON output as a [NestedReport]
+//     grouping findings by repository and workflow file, built by
+//     [NestResults].
+//   - [WriteMarkdown] writes a GitHub-flavored Markdown report built
+//     by [RenderMarkdown], escaping data fields for table cells and
+//     adding a Why column when findings carry explanations;
+//     [RenderMarkdownBySeverity] sections it by severity.
+//   - [WriteSTIX] writes a STIX 2.1 bundle built by [BuildSTIX]:
+//     indicators for payloads, IOC content, and destinations, related
+//     to observed-data for the runs they were found in.
+//   - [BuildSARIF] renders findings as a SARIF 2.1.0 log for GitHub
+//     code scanning, one result per finding at its workflow file,
+//     fingerprinted and without the matched content.
+//   - [WriteSummary] writes the per-repository IOC summary as JSON,
+//     and [WriteSummaryReport] writes it with its totals.
+//   - [LogKeeper] stores the extracted log text of scanned runs under
+//     logs/owner__repo/ for later analysis.
+//   - [WriteSQLite] appends results to a findings table in a SQLite
+//     database through database/sql and modernc.org/sqlite.
 //
 // Invariants:
 //
 //   - Every write performs MkdirAll on the parent directory before
-//     opening the file.
+//     opening the file. Files are written with [FileMode] (default
+//     [DefaultFileMode], changed by [SetFileMode] and parsed from
+//     -output-mode by [ParseFileMode]) and directories created with
+//     [DirMode].
 //   - WriteCache uses a tmp+rename pattern so readers either see the
 //     previous full file or the new full file, never a partial write.
 //   - All concurrent WriteCache calls targeting the same path are
 //     serialized; this preserves the rename-atomicity invariant when
 //     multiple per-repo goroutines race to flush intermediate results.
+//   - Final outputs list results in [SortResults] order, so reports
+//     of the same findings are byte-identical regardless of the order
+//     concurrent scans produced them in.
+//   - Journal lines are only ever appended, so a crash mid-write can
+//     tear at most the final line; LoadCache skips it.
 package file
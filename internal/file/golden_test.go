@@ -0,0 +1,155 @@
+package file_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// update rewrites the golden files under testdata/golden from the
+// current writers instead of comparing against them:
+//
+//	go test ./internal/file -run TestGolden -update
+var update = flag.Bool("update", false, "rewrite the golden output files")
+
+// goldenCache is a fixed scan whose results cover the awkward cases an
+// output format must survive: a result with only its required fields
+// set, fields holding CSV and Markdown metacharacters, multi-line and
+// non-ASCII data, and an empty result every writer must drop. Results
+// are listed out of order so the goldens also pin the sort.
+func goldenCache() ghscan.Cache {
+	return ghscan.Cache{
+		ScanID:        "0123456789abcdef0123456789abcdef",
+		ScanStartedAt: time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC),
+		Results: []ghscan.Result{
+			{
+				Repository:       "octo/widgets",
+				WorkflowFileName: "release.yml",
+				WorkflowURL:      "https://github.com/octo/widgets/blob/main/.github/workflows/release.yml",
+				WorkflowRunURL:   "https://github.com/octo/widgets/actions/runs/42",
+				Base64Data:       "c2VjcmV0LCAicXVvdGVkIiB8IHBpcGU=",
+				DecodedData:      "secret, \"quoted\" | pipe",
+				DecodeDepth:      1,
+				LineData:         "echo \"a,b\" | base64 <script>\r\nsecond line",
+				JobName:          "build & push",
+				StepName:         "Run `make`",
+				KeyTypes:         "base64",
+				Severity:         "medium",
+				IOCName:          "tj-actions/changed-files",
+				Confidence:       40,
+				MatchOffset:      12,
+				ScanID:           "0123456789abcdef0123456789abcdef",
+			},
+			{
+				Repository: "octo/widgets",
+				LineData:   "ünïcødé ✓ payload\ttabbed",
+				Severity:   "high",
+				IOCName:    "tj-actions/changed-files",
+			},
+			{
+				Repository:        "acme/api",
+				WorkflowFileName:  "ci.yml",
+				WorkflowURL:       "https://github.com/acme/api/blob/main/.github/workflows/ci.yml",
+				OffendingUsesLine: "uses: tj-actions/changed-files@v35",
+				ResolvedRefForm:   "tag",
+				Source:            "workflow",
+				Severity:          "critical",
+				IOCName:           "tj-actions/changed-files",
+				Confidence:        90,
+			},
+			{
+				Repository:     "acme/api",
+				WorkflowRunURL: "https://github.com/acme/api/actions/runs/7",
+			},
+		},
+	}
+}
+
+// goldenSTIXTime fixes the creation time of the STIX objects.
+var goldenSTIXTime = time.Date(2025, 3, 15, 8, 30, 0, 0, time.UTC)
+
+// TestGolden pins the exact bytes of every final output for
+// goldenCache, so a renamed field, reordered column, or changed escape
+// shows up in review as a diff to the files under testdata/golden.
+func TestGolden(t *testing.T) {
+	goldenDir, err := filepath.Abs(filepath.Join("testdata", "golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chdirTemp(t)
+
+	ctx := t.Context()
+	logger := newSilentLogger()
+	cache := goldenCache()
+	ghscan.AddFingerprints(cache.Results)
+
+	if err := file.WriteResults(ctx, logger, cache, "", "results.json", "results.csv"); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+	if err := file.WriteMarkdown(ctx, logger, cache, "results.md", false); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	if err := file.WriteNestedReport(ctx, logger, cache, "nested.json"); err != nil {
+		t.Fatalf("WriteNestedReport: %v", err)
+	}
+	if err := file.WriteSeverityReport(ctx, logger, cache.Results, "severity.json", "severity.csv"); err != nil {
+		t.Fatalf("WriteSeverityReport: %v", err)
+	}
+	if err := file.WriteSummaryReport(ctx, logger, ghscan.Summarize(cache.Results).Report(), "summary.json"); err != nil {
+		t.Fatalf("WriteSummaryReport: %v", err)
+	}
+
+	// The bundle ID is random per run; every other STIX ID is derived
+	// from the findings.
+	bundle := file.BuildSTIX(cache.Results, cache.ScanStartedAt, goldenSTIXTime)
+	bundle.ID = "bundle--golden"
+	stix, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		t.Fatalf("encoding STIX bundle: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(ghscan.ResultsDir, "results.stix.json"), stix, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		"results.json",
+		"results.csv",
+		"results.md",
+		"nested.json",
+		"severity.json",
+		"severity.csv",
+		"summary.json",
+		"results.stix.json",
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := os.ReadFile(filepath.Join(ghscan.ResultsDir, name))
+			if err != nil {
+				t.Fatalf("reading output: %v", err)
+			}
+			golden := filepath.Join(goldenDir, name)
+			if *update {
+				if err := os.MkdirAll(goldenDir, 0o750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("reading golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs from %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", name, golden, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// LogsDir is the directory under ghscan.ResultsDir that LogKeeper
+// writes run logs to.
+const LogsDir = "logs"
+
+// LogKeeper is the on-disk ghscan.LogStore. Each kept run is written
+// to ghscan.ResultsDir/logs/owner__repo/<runID>.log, overwriting any
+// earlier copy of the same run. By default only runs with findings are
+// kept; All keeps every scanned run. A LogKeeper holds no state and is
+// safe for concurrent use.
+type LogKeeper struct {
+	Logger *clog.Logger
+	// All keeps the logs of runs without findings too.
+	All bool
+}
+
+var _ ghscan.LogStore = (*LogKeeper)(nil)
+
+// StoreLog writes logText for runID in repository when the run has
+// findings or k.All is set. A nil LogKeeper is a no-op.
+func (k *LogKeeper) StoreLog(ctx context.Context, repository string, runID int64, logText string, hasFindings bool) error {
+	if k == nil || (!hasFindings && !k.All) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	base, err := perRepoFileName(repository)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(ghscan.ResultsDir, LogsDir, base)
+	if err := mkdirAll(dir); err != nil {
+		return fmt.Errorf("creating log directory %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, strconv.FormatInt(runID, 10)+".log")
+	if err := writeFile(path, []byte(redactString(logText))); err != nil {
+		return fmt.Errorf("writing log for run %d: %w", runID, err)
+	}
+	if k.Logger != nil {
+		k.Logger.Debugf("Kept log for run %d of %s at %s", runID, repository, path)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+func TestLogKeeper(t *testing.T) {
+	chdirTemp(t)
+
+	cases := []struct {
+		name        string
+		all         bool
+		runID       int64
+		hasFindings bool
+		wantKept    bool
+	}{
+		{name: "findings kept", runID: 1, hasFindings: true, wantKept: true},
+		{name: "clean run skipped", runID: 2},
+		{name: "clean run kept with all", all: true, runID: 3, wantKept: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			k := &file.LogKeeper{Logger: newSilentLogger(), All: tc.all}
+			if err := k.StoreLog(t.Context(), "octo/alpha", tc.runID, "log body\n", tc.hasFindings); err != nil {
+				t.Fatalf("StoreLog: %v", err)
+			}
+			path := filepath.Join(ghscan.ResultsDir, file.LogsDir, "octo__alpha", strconv.FormatInt(tc.runID, 10)+".log")
+			data, err := os.ReadFile(path) // #nosec G304 -- test-controlled path
+			if !tc.wantKept {
+				if !os.IsNotExist(err) {
+					t.Fatalf("log for run %d exists (err=%v), want none", tc.runID, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read kept log: %v", err)
+			}
+			if string(data) != "log body\n" {
+				t.Fatalf("kept log=%q, want original text", data)
+			}
+		})
+	}
+
+	if err := (&file.LogKeeper{All: true}).StoreLog(t.Context(), "../escape", 1, "x", true); err == nil {
+		t.Fatal("StoreLog accepted a repository name that escapes the results directory")
+	}
+}
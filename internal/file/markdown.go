@@ -0,0 +1,201 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// markdownCellLimit caps the runes of matched text shown in a table
+// cell; the full text of decoded payloads goes in a details block.
+const markdownCellLimit = 200
+
+// markdownCellEscaper neutralizes characters that would end a table
+// cell, open a code span, or be interpreted as HTML by GitHub.
+var markdownCellEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"`", "\\`",
+	"<", "&lt;",
+	">", "&gt;",
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+// markdownURLEscaper percent-encodes the characters that would end a
+// link target or a table cell. GitHub URLs never contain them, but
+// results loaded from a cache are not trusted to be well formed.
+var markdownURLEscaper = strings.NewReplacer(
+	"|", "%7C",
+	" ", "%20",
+	"(", "%28",
+	")", "%29",
+	"<", "%3C",
+	">", "%3E",
+	"\n", "%0A",
+)
+
+// escapeMarkdownCell makes s safe to place in a GitHub-flavored
+// Markdown table cell, truncating it to markdownCellLimit runes.
+func escapeMarkdownCell(s string) string {
+	if r := []rune(s); len(r) > markdownCellLimit {
+		s = string(r[:markdownCellLimit]) + "…"
+	}
+	return markdownCellEscaper.Replace(s)
+}
+
+// markdownFence returns a backtick fence longer than any backtick run
+// in s, so a payload containing ``` cannot close its own code block.
+func markdownFence(s string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+			continue
+		}
+		run = 0
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
+
+// RenderMarkdown renders results as a GitHub-flavored Markdown report:
+// a summary line, a table with one row per finding linking its run,
+// and a collapsible details block for every decoded payload. When any
+// finding carries an explanation, the table gains a Why column. Rows are
+// in [SortResults] order, and empty results are skipped, as in the CSV
+// output.
+func RenderMarkdown(results []ghscan.Result) string {
+	var findings []ghscan.Result
+	for _, r := range SortResults(results) {
+		if !r.IsEmpty() {
+			findings = append(findings, r)
+		}
+	}
+	return renderMarkdown(findings, nil)
+}
+
+// RenderMarkdownBySeverity is [RenderMarkdown] with the table split
+// into one section per severity, most severe first, as in
+// [GroupBySeverity]. Rows are numbered across sections.
+func RenderMarkdownBySeverity(results []ghscan.Result) string {
+	report := GroupBySeverity(results)
+	var findings []ghscan.Result
+	for _, section := range report.Sections {
+		findings = append(findings, section.Results...)
+	}
+	return renderMarkdown(findings, report.Sections)
+}
+
+// renderMarkdown renders findings, which hold no empty results, in
+// order. With sections, which partition findings in the same order,
+// each section gets its own heading and table.
+func renderMarkdown(findings []ghscan.Result, sections []SeveritySection) string {
+	repos := make(map[string]struct{})
+	explained := false
+	for _, r := range findings {
+		repos[r.Repository] = struct{}{}
+		explained = explained || r.Explanation != ""
+	}
+
+	var b strings.Builder
+	b.WriteString("# ghscan report\n\n")
+	if len(findings) == 0 {
+		b.WriteString("No findings.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "**%d findings** in **%d repositories**.\n", len(findings), len(repos))
+
+	if len(sections) == 0 {
+		b.WriteString("\n")
+		writeMarkdownTable(&b, findings, 1, explained)
+	}
+	n := 1
+	for _, section := range sections {
+		fmt.Fprintf(&b, "\n## %s (%d)\n\n", section.Severity, section.Count)
+		writeMarkdownTable(&b, section.Results, n, explained)
+		n += len(section.Results)
+	}
+
+	for i, r := range findings {
+		if r.DecodedData == "" {
+			continue
+		}
+		fence := markdownFence(r.DecodedData)
+		fmt.Fprintf(&b, "\n<details>\n<summary>#%d %s %s: decoded payload</summary>\n\n%s\n%s\n%s\n\n</details>\n",
+			i+1,
+			escapeMarkdownCell(r.Repository),
+			escapeMarkdownCell(r.WorkflowFileName),
+			fence, r.DecodedData, fence,
+		)
+	}
+	return b.String()
+}
+
+// writeMarkdownTable writes a findings table to b, numbering its rows
+// from first.
+func writeMarkdownTable(b *strings.Builder, findings []ghscan.Result, first int, explained bool) {
+	if explained {
+		b.WriteString("| # | Repository | Workflow | Severity | Type | Finding | Why | Run |\n")
+		b.WriteString("|---|---|---|---|---|---|---|---|\n")
+	} else {
+		b.WriteString("| # | Repository | Workflow | Severity | Type | Finding | Run |\n")
+		b.WriteString("|---|---|---|---|---|---|---|\n")
+	}
+	for i, r := range findings {
+		text := r.LineData
+		if text == "" {
+			text = r.OffendingUsesLine
+		}
+		link := ""
+		if u := r.WorkflowRunURL; u != "" {
+			link = fmt.Sprintf("[run](%s)", markdownURLEscaper.Replace(u))
+			if n := len(r.RunURLs); n > 1 {
+				link += fmt.Sprintf(" (+%d more)", n-1)
+			}
+		} else if u := r.WorkflowURL; u != "" {
+			link = fmt.Sprintf("[workflow](%s)", markdownURLEscaper.Replace(u))
+		}
+		fmt.Fprintf(b, "| %d | %s | %s | %s | %s | %s |",
+			first+i,
+			escapeMarkdownCell(r.Repository),
+			escapeMarkdownCell(r.WorkflowFileName),
+			escapeMarkdownCell(r.Severity),
+			escapeMarkdownCell(r.KeyTypes),
+			escapeMarkdownCell(text),
+		)
+		if explained {
+			fmt.Fprintf(b, " %s |", escapeMarkdownCell(r.Explanation))
+		}
+		fmt.Fprintf(b, " %s |\n", link)
+	}
+}
+
+// WriteMarkdown writes the cache's results as a Markdown report (see
+// [RenderMarkdown]) to markdownFile under ghscan.ResultsDir, ready to
+// paste into an incident issue. With bySeverity the report is
+// sectioned by severity instead (see [RenderMarkdownBySeverity]).
+func WriteMarkdown(ctx context.Context, logger *clog.Logger, cache ghscan.Cache, markdownFile string, bySeverity bool) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WriteMarkdown: context already cancelled: %v", err)
+		return err
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	render := RenderMarkdown
+	if bySeverity {
+		render = RenderMarkdownBySeverity
+	}
+	if err := writeFile(filepath.Join(ghscan.ResultsDir, markdownFile), []byte(render(redactResults(cache.Results)))); err != nil {
+		logger.Errorf("Error writing Markdown report: %v", err)
+		return fmt.Errorf("writing Markdown report: %w", err)
+	}
+	logger.Infof("Wrote Markdown report with %d results", len(cache.Results))
+	return nil
+}
@@ -0,0 +1,133 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// TestRenderMarkdown asserts the report carries a summary line, one
+// table row per finding with its run linked, and a details block per
+// decoded payload, and that data fields cannot break the table.
+func TestRenderMarkdown(t *testing.T) {
+	t.Parallel()
+
+	got := file.RenderMarkdown([]ghscan.Result{
+		{
+			Repository:       "o/a",
+			WorkflowFileName: "ci.yml",
+			WorkflowRunURL:   "https://github.com/o/a/actions/runs/1",
+			RunURLs:          []string{"https://github.com/o/a/actions/runs/1", "https://github.com/o/a/actions/runs/7"},
+			Severity:         "critical",
+			KeyTypes:         "ssh-private-key",
+			LineData:         "echo `id` | base64 <x>",
+			DecodedData:      "payload with ``` fence",
+		},
+		{
+			Repository:        "o/b",
+			WorkflowFileName:  "release.yml",
+			WorkflowURL:       "https://github.com/o/b/actions/workflows/release.yml",
+			OffendingUsesLine: "uses: x/y@bad",
+		},
+		{Repository: "o/c"},
+	})
+
+	for _, want := range []string{
+		"**2 findings** in **2 repositories**.",
+		"| 1 | o/a | ci.yml | critical | ssh-private-key | echo \\`id\\` \\| base64 &lt;x&gt; | [run](https://github.com/o/a/actions/runs/1) (+1 more) |",
+		"| 2 | o/b | release.yml |  |  | uses: x/y@bad | [workflow](https://github.com/o/b/actions/workflows/release.yml) |",
+		"<summary>#1 o/a ci.yml: decoded payload</summary>",
+		"````\npayload with ``` fence\n````",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "<details>") != 1 {
+		t.Errorf("want exactly one details block (only o/a decoded):\n%s", got)
+	}
+	for _, line := range strings.Split(got, "\n") {
+		if strings.HasPrefix(line, "| 1 ") && strings.Count(strings.ReplaceAll(line, `\|`, ""), "|") != 8 {
+			t.Errorf("row has unescaped pipes: %q", line)
+		}
+	}
+}
+
+// TestRenderMarkdown_Explanation asserts explained findings add a Why
+// column, left blank for findings without an explanation.
+func TestRenderMarkdown_Explanation(t *testing.T) {
+	t.Parallel()
+
+	got := file.RenderMarkdown([]ghscan.Result{
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "hit", Explanation: `matched IOC content "hit" at line 3`},
+		{Repository: "o/b", WorkflowFileName: "ci.yml", LineData: "other"},
+	})
+	for _, want := range []string{
+		"| # | Repository | Workflow | Severity | Type | Finding | Why | Run |",
+		`| 1 | o/a | ci.yml |  |  | hit | matched IOC content "hit" at line 3 |  |`,
+		"| 2 | o/b | ci.yml |  |  | other |  |  |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+}
+
+// TestRenderMarkdownBySeverity asserts the report has one heading and
+// table per severity, most severe first, with rows numbered across
+// sections so the details blocks still refer to the right row.
+func TestRenderMarkdownBySeverity(t *testing.T) {
+	t.Parallel()
+
+	got := file.RenderMarkdownBySeverity([]ghscan.Result{
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "plain"},
+		{Repository: "o/b", WorkflowFileName: "ci.yml", LineData: "key", Severity: "critical", DecodedData: "secret"},
+		{Repository: "o/c"},
+	})
+	critical := strings.Index(got, "## critical (1)")
+	unrated := strings.Index(got, "## unrated (1)")
+	if critical < 0 || unrated < critical {
+		t.Fatalf("want a critical section before an unrated one:\n%s", got)
+	}
+	for _, want := range []string{
+		"**2 findings** in **2 repositories**.",
+		"| 1 | o/b | ci.yml | critical |  | key |  |",
+		"| 2 | o/a | ci.yml |  |  | plain |  |",
+		"<summary>#1 o/b ci.yml: decoded payload</summary>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "| # | Repository |") != 2 {
+		t.Errorf("want one table per section:\n%s", got)
+	}
+}
+
+func TestRenderMarkdown_NoFindings(t *testing.T) {
+	t.Parallel()
+
+	if got := file.RenderMarkdown(nil); !strings.Contains(got, "No findings.") {
+		t.Fatalf("empty report = %q, want a no-findings line", got)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	chdirTemp(t)
+
+	cache := ghscan.Cache{Results: []ghscan.Result{{Repository: "o/a", LineData: "hit"}}}
+	if err := file.WriteMarkdown(t.Context(), newSilentLogger(), cache, "report.md", false); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(ghscan.ResultsDir, "report.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != file.RenderMarkdown(cache.Results) {
+		t.Fatalf("written report differs from RenderMarkdown:\n%s", data)
+	}
+}
@@ -0,0 +1,99 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultFileMode is the permission every output file is written with
+// unless [SetFileMode] chooses another: readable and writable by the
+// owner only, since findings can hold decoded secrets.
+const DefaultFileMode os.FileMode = 0o600
+
+// DefaultDirMode is the permission of directories created for outputs
+// while the file mode grants others no read access.
+const DefaultDirMode os.FileMode = 0o750
+
+// fileMode holds the configured output file mode; zero selects
+// DefaultFileMode.
+var fileMode atomic.Uint32
+
+// SetFileMode sets the permission of every file this package writes,
+// e.g. 0o640 so a later CI step running as another user in the same
+// group can upload the results. Existing files are changed to mode
+// when they are rewritten. A zero mode restores [DefaultFileMode].
+// Like [SetRedactor], it is intended to be called once at
+// program start, before anything is written.
+func SetFileMode(mode os.FileMode) {
+	fileMode.Store(uint32(mode.Perm()))
+}
+
+// FileMode reports the permission output files are written with.
+func FileMode() os.FileMode {
+	if m := fileMode.Load(); m != 0 {
+		return os.FileMode(m)
+	}
+	return DefaultFileMode
+}
+
+// DirMode reports the permission output directories are created with:
+// [DefaultDirMode], widened to 0o755 when [FileMode] lets others read
+// the files so they can reach them. Directories that already exist are
+// left as they are.
+func DirMode() os.FileMode {
+	if FileMode()&0o004 != 0 {
+		return DefaultDirMode | 0o005
+	}
+	return DefaultDirMode
+}
+
+// ParseFileMode parses an -output-mode value: an octal permission such
+// as 600, 0600, or 0o640. The owner must be able to read and write
+// the file, since ghscan rewrites its cache, and execute and special
+// bits are rejected.
+func ParseFileMode(s string) (os.FileMode, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0o"), "0O")
+	n, err := strconv.ParseUint(digits, 8, 32)
+	if err != nil || digits == "" {
+		return 0, fmt.Errorf("output mode %q is not an octal permission", s)
+	}
+	mode := os.FileMode(n)
+	switch {
+	case mode&^0o666 != 0:
+		return 0, fmt.Errorf("output mode %q may only grant read and write permissions", s)
+	case mode&0o600 != 0o600:
+		return 0, fmt.Errorf("output mode %q must let the owner read and write", s)
+	}
+	return mode, nil
+}
+
+// mkdirAll creates dir and any missing parents with [DirMode].
+func mkdirAll(dir string) error {
+	return os.MkdirAll(dir, DirMode())
+}
+
+// writeFile writes data to name with [FileMode], replacing the
+// permission of a file that already exists.
+func writeFile(name string, data []byte) error {
+	if err := os.WriteFile(name, data, FileMode()); err != nil {
+		return err
+	}
+	return os.Chmod(name, FileMode())
+}
+
+// openFile opens name with flag, creating it with [FileMode] and
+// replacing the permission of a file that already exists.
+func openFile(name string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(name, flag, FileMode())
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(FileMode()); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,96 @@
+package file_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+func TestParseFileMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{in: "0600", want: 0o600},
+		{in: "600", want: 0o600},
+		{in: "0o640", want: 0o640},
+		{in: " 0644 ", want: 0o644},
+		{in: "0666", want: 0o666},
+		{in: "", wantErr: true},
+		{in: "0o", wantErr: true},
+		{in: "rw-r-----", wantErr: true},
+		{in: "0680", wantErr: true},
+		{in: "0755", wantErr: true},
+		{in: "4600", wantErr: true},
+		{in: "0400", wantErr: true},
+		{in: "0060", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := file.ParseFileMode(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("ParseFileMode(%q) err=%v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseFileMode(%q)=%o, want %o", tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestSetFileMode asserts every writer uses the configured mode,
+// including on files left by an earlier run, and that directories
+// become searchable by others only when the files are readable by them.
+func TestSetFileMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions")
+	}
+	chdirTemp(t)
+	t.Cleanup(func() { file.SetFileMode(0) })
+
+	if got := file.FileMode(); got != file.DefaultFileMode {
+		t.Fatalf("default FileMode=%o, want %o", got, file.DefaultFileMode)
+	}
+	if got := file.DirMode(); got != file.DefaultDirMode {
+		t.Fatalf("default DirMode=%o, want %o", got, file.DefaultDirMode)
+	}
+
+	// A cache from an earlier run keeps its mode until it is rewritten.
+	if err := os.MkdirAll(ghscan.ResultsDir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ghscan.ResultsDir, "cache.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	file.SetFileMode(0o640)
+	if got := file.DirMode(); got != 0o750 {
+		t.Fatalf("DirMode for 0640=%o, want 750", got)
+	}
+	cache := ghscan.Cache{Results: []ghscan.Result{{Repository: "o/r", LineData: "x"}}}
+	if err := file.WriteResults(t.Context(), newSilentLogger(), cache, "cache.json", "results.json", "out/results.csv"); err != nil {
+		t.Fatalf("WriteResults: %v", err)
+	}
+	if err := file.WriteSummary(t.Context(), newSilentLogger(), ghscan.Summarize(cache.Results), "summary.json"); err != nil {
+		t.Fatalf("WriteSummary: %v", err)
+	}
+	for _, name := range []string{"cache.json", "results.json", "out/results.csv", "summary.json"} {
+		info, err := os.Stat(filepath.Join(ghscan.ResultsDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != 0o640 {
+			t.Fatalf("%s mode=%o, want 640", name, got)
+		}
+	}
+
+	file.SetFileMode(0o644)
+	if got := file.DirMode(); got != 0o755 {
+		t.Fatalf("DirMode for 0644=%o, want 755", got)
+	}
+}
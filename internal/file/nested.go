@@ -0,0 +1,100 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// NoWorkflow keys the results in a [NestedReport] that name no
+// workflow file, such as findings loaded from a hand-edited cache.
+const NoWorkflow = "(no workflow)"
+
+// NestedWorkflow is one workflow file's findings in a [NestedReport].
+type NestedWorkflow struct {
+	Count   int             `json:"count"`
+	Results []ghscan.Result `json:"results"`
+}
+
+// NestedRepository is one repository's findings in a [NestedReport],
+// keyed by workflow file name.
+type NestedRepository struct {
+	Count     int                        `json:"count"`
+	Workflows map[string]*NestedWorkflow `json:"workflows"`
+}
+
+// NestedReport is the JSON shape written by [WriteNestedReport]:
+// findings grouped by repository, then by workflow file, with a count
+// at every level. JSON objects are keyed by name, so the encoded
+// report lists repositories and workflows alphabetically.
+type NestedReport struct {
+	ScanID        string                       `json:"scan_id,omitempty"`
+	ScanStartedAt time.Time                    `json:"scan_started_at,omitzero"`
+	Total         int                          `json:"total"`
+	Repositories  map[string]*NestedRepository `json:"repositories"`
+}
+
+// NestResults builds the nested view of cache. Results within a
+// workflow are in [SortResults] order; empty results are skipped, as
+// in the CSV output.
+func NestResults(cache ghscan.Cache) NestedReport {
+	report := NestedReport{
+		ScanID:        cache.ScanID,
+		ScanStartedAt: cache.ScanStartedAt,
+		Repositories:  make(map[string]*NestedRepository),
+	}
+	for _, r := range SortResults(cache.Results) {
+		if r.IsEmpty() {
+			continue
+		}
+		repo := report.Repositories[r.Repository]
+		if repo == nil {
+			repo = &NestedRepository{Workflows: make(map[string]*NestedWorkflow)}
+			report.Repositories[r.Repository] = repo
+		}
+		name := cmp.Or(r.WorkflowFileName, NoWorkflow)
+		wf := repo.Workflows[name]
+		if wf == nil {
+			wf = &NestedWorkflow{}
+			repo.Workflows[name] = wf
+		}
+		wf.Results = append(wf.Results, r)
+		wf.Count++
+		repo.Count++
+		report.Total++
+	}
+	return report
+}
+
+// WriteNestedReport writes the -json-nested form of the JSON output, a
+// [NestedReport] of cache, to jsonFile under ghscan.ResultsDir. The
+// cache file keeps the flat shape so it stays loadable.
+func WriteNestedReport(ctx context.Context, logger *clog.Logger, cache ghscan.Cache, jsonFile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if jsonFile == "" {
+		return nil
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	cache.Results = redactResults(cache.Results)
+	report := NestResults(cache)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling nested report: %w", err)
+	}
+	if err := writeFile(filepath.Join(ghscan.ResultsDir, jsonFile), data); err != nil {
+		logger.Errorf("Error writing nested report: %v", err)
+		return fmt.Errorf("writing JSON output: %w", err)
+	}
+	logger.Infof("Wrote nested report of %d results in %d repositories", report.Total, len(report.Repositories))
+	return nil
+}
@@ -0,0 +1,61 @@
+package file_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// TestNestResults asserts findings are grouped by repository, then
+// workflow, with counts at each level and empty results skipped.
+func TestNestResults(t *testing.T) {
+	t.Parallel()
+
+	cache := ghscan.Cache{ScanID: "scan-1", Results: []ghscan.Result{
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "second", Severity: "low"},
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "first"},
+		{Repository: "o/a", WorkflowFileName: "release.yml", LineData: "hit"},
+		{Repository: "o/b", LineData: "loose"},
+		{Repository: "o/c", WorkflowFileName: "ci.yml"},
+	}}
+	got := file.NestResults(cache)
+
+	if got.ScanID != "scan-1" || got.Total != 4 || len(got.Repositories) != 2 {
+		t.Fatalf("report scan_id=%q total=%d repositories=%d, want scan-1, 4, 2", got.ScanID, got.Total, len(got.Repositories))
+	}
+	a := got.Repositories["o/a"]
+	if a == nil || a.Count != 3 || len(a.Workflows) != 2 {
+		t.Fatalf("o/a=%+v, want 3 findings in 2 workflows", a)
+	}
+	ci := a.Workflows["ci.yml"]
+	if ci.Count != 2 || ci.Results[0].LineData != "first" || ci.Results[1].LineData != "second" {
+		t.Fatalf("o/a ci.yml=%+v, want 2 findings in SortResults order", ci)
+	}
+	if b := got.Repositories["o/b"]; b == nil || b.Workflows[file.NoWorkflow].Count != 1 {
+		t.Fatalf("o/b=%+v, want its finding under %q", b, file.NoWorkflow)
+	}
+}
+
+func TestWriteNestedReport(t *testing.T) {
+	chdirTemp(t)
+
+	cache := ghscan.Cache{Results: []ghscan.Result{{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "hit"}}}
+	if err := file.WriteNestedReport(t.Context(), newSilentLogger(), cache, "nested.json"); err != nil {
+		t.Fatalf("WriteNestedReport: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(ghscan.ResultsDir, "nested.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report file.NestedReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if repo := report.Repositories["o/a"]; report.Total != 1 || repo == nil || repo.Workflows["ci.yml"].Count != 1 {
+		t.Fatalf("report=%s, want one finding under o/a ci.yml", data)
+	}
+}
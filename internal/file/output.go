@@ -1,6 +1,7 @@
 package file
 
 import (
+	"cmp"
 	"context"
 	"encoding/csv"
 	"encoding/json"
@@ -8,6 +9,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 
 	"github.com/chainguard-dev/clog"
@@ -30,12 +33,12 @@ func writeCSV(filename string, results []ghscan.Result) error {
 
 	dir := filepath.Dir(clean)
 	if dir != "." && dir != "/" {
-		if err := os.MkdirAll(dir, 0o750); err != nil {
+		if err := mkdirAll(dir); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
 
-	file, err := os.OpenFile(clean, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	file, err := openFile(clean, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
 	if err != nil {
 		return fmt.Errorf("failed to open file %s: %w", filename, err)
 	}
@@ -43,15 +46,7 @@ func writeCSV(filename string, results []ghscan.Result) error {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	if err := writer.Write([]string{
-		"Repository",
-		"WorkflowFileName",
-		"WorkflowURL",
-		"WorkflowRunURL",
-		"Base64Data",
-		"DecodedData",
-		"LineData",
-	}); err != nil {
+	if err := writer.Write(csvHeader()); err != nil {
 		return err
 	}
 
@@ -59,22 +54,73 @@ func writeCSV(filename string, results []ghscan.Result) error {
 		if res.IsEmpty() {
 			continue
 		}
-		record := []string{
-			res.Repository,
-			res.WorkflowFileName,
-			res.WorkflowURL,
-			res.WorkflowRunURL,
-			res.Base64Data,
-			res.DecodedData,
-			res.LineData,
-		}
-		if err := writer.Write(record); err != nil {
+		if err := writer.Write(csvRecord(res)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// csvHeader returns the column names shared by writeCSV and the
+// incremental Appender so both emit byte-identical headers.
+func csvHeader() []string {
+	return []string{
+		"Repository",
+		"WorkflowFileName",
+		"WorkflowURL",
+		"WorkflowRunURL",
+		"Base64Data",
+		"DecodedData",
+		"LineData",
+		"Severity",
+		"Fingerprint",
+	}
+}
+
+// csvRecord flattens res into a row matching csvHeader.
+func csvRecord(res ghscan.Result) []string {
+	return []string{
+		res.Repository,
+		res.WorkflowFileName,
+		res.WorkflowURL,
+		res.WorkflowRunURL,
+		res.Base64Data,
+		res.DecodedData,
+		res.LineData,
+		res.Severity,
+		res.Fingerprint,
+	}
+}
+
+// SortResults returns a copy of results in a deterministic order: by
+// repository, workflow file, run URL, and matched line, with the
+// remaining identifying fields breaking ties. Results are gathered
+// from concurrent goroutines, so every final output is sorted to make
+// reports reproducible and diffable across runs.
+func SortResults(results []ghscan.Result) []ghscan.Result {
+	out := slices.Clone(results)
+	slices.SortStableFunc(out, func(a, b ghscan.Result) int {
+		return cmp.Or(
+			strings.Compare(a.Repository, b.Repository),
+			strings.Compare(a.WorkflowFileName, b.WorkflowFileName),
+			strings.Compare(a.WorkflowRunURL, b.WorkflowRunURL),
+			strings.Compare(a.LineData, b.LineData),
+			strings.Compare(a.Source, b.Source),
+			strings.Compare(a.IOCName, b.IOCName),
+			strings.Compare(a.OffendingUsesLine, b.OffendingUsesLine),
+			strings.Compare(a.JobName, b.JobName),
+			strings.Compare(a.StepName, b.StepName),
+			strings.Compare(a.Base64Data, b.Base64Data),
+			strings.Compare(a.DecodedData, b.DecodedData),
+			strings.Compare(a.KeyTypes, b.KeyTypes),
+			strings.Compare(a.Action, b.Action),
+			strings.Compare(a.ActionFile, b.ActionFile),
+			strings.Compare(a.CommitSHA, b.CommitSHA),
+		)
+	})
+	return out
+}
+
 // WriteCache atomically persists the in-memory results slice to disk.
 // ctx is consulted at function entry; long writes don't otherwise
 // interleave system calls so finer-grained checks would not pay off.
@@ -94,12 +140,12 @@ func WriteCache(ctx context.Context, logger *clog.Logger, cacheFile string, resu
 
 	clean := filepath.Clean(cacheFile)
 	dir := filepath.Dir(clean)
-	if err := os.MkdirAll(dir, 0o750); err != nil {
+	if err := mkdirAll(dir); err != nil {
 		logger.Errorf("Error creating directory for intermediate results: %v", err)
 		return
 	}
 
-	cache := ghscan.Cache{Results: results}
+	cache := ghscan.Cache{Results: redactResults(results)}
 	cacheData, err := json.MarshalIndent(cache, "", "  ")
 	if err != nil {
 		logger.Errorf("Error marshaling intermediate results: %v", err)
@@ -107,7 +153,7 @@ func WriteCache(ctx context.Context, logger *clog.Logger, cacheFile string, resu
 	}
 
 	tempFile := clean + ".temp"
-	if err = os.WriteFile(tempFile, cacheData, 0o600); err != nil {
+	if err = writeFile(tempFile, cacheData); err != nil {
 		logger.Errorf("Error writing intermediate results: %v", err)
 		return
 	}
@@ -125,12 +171,22 @@ func WriteCache(ctx context.Context, logger *clog.Logger, cacheFile string, resu
 // prevent the others from being attempted. Pre-condition: ctx must
 // be non-nil; ctx cancellation aborts the write attempt and surfaces
 // ctx.Err() to the caller.
+//
+// WriteResults is also the consolidation step for an [Appender]: once
+// the cache file has been rewritten the NDJSON journal is removed.
+// Every output is written in [SortResults] order, with the secrets of
+// the Redactor installed by [SetRedactor] scrubbed. When every name is
+// empty nothing is written, not even ghscan.ResultsDir.
 func WriteResults(ctx context.Context, logger *clog.Logger, cache ghscan.Cache, cacheFile, jsonFile, csvFile string) error {
 	if err := ctx.Err(); err != nil {
 		logger.Warnf("WriteResults: context already cancelled: %v", err)
 		return err
 	}
-	if err := os.MkdirAll(ghscan.ResultsDir, 0o750); err != nil {
+	if cacheFile == "" && jsonFile == "" && csvFile == "" {
+		return nil
+	}
+	cache.Results = redactResults(SortResults(cache.Results))
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
 		return fmt.Errorf("creating results directory: %w", err)
 	}
 	cacheData, err := json.MarshalIndent(cache, "", "  ")
@@ -140,14 +196,19 @@ func WriteResults(ctx context.Context, logger *clog.Logger, cache ghscan.Cache,
 
 	var errs error
 	if cacheFile != "" {
-		if werr := os.WriteFile(filepath.Join(ghscan.ResultsDir, cacheFile), cacheData, 0o600); werr != nil {
+		if werr := writeFile(filepath.Join(ghscan.ResultsDir, cacheFile), cacheData); werr != nil {
 			logger.Errorf("Error writing cache file: %v", werr)
 			errs = errors.Join(errs, fmt.Errorf("writing cache file: %w", werr))
+		} else if rerr := os.Remove(JournalPath(cacheFile)); rerr != nil && !errors.Is(rerr, os.ErrNotExist) {
+			// The consolidated cache now holds every journaled result;
+			// a stale journal would only duplicate them on next load.
+			logger.Errorf("Error removing cache journal: %v", rerr)
+			errs = errors.Join(errs, fmt.Errorf("removing cache journal: %w", rerr))
 		}
 	}
 
 	if jsonFile != "" {
-		if werr := os.WriteFile(filepath.Join(ghscan.ResultsDir, jsonFile), cacheData, 0o600); werr != nil {
+		if werr := writeFile(filepath.Join(ghscan.ResultsDir, jsonFile), cacheData); werr != nil {
 			logger.Errorf("Error writing JSON output: %v", werr)
 			errs = errors.Join(errs, fmt.Errorf("writing JSON output: %w", werr))
 		}
@@ -165,3 +226,129 @@ func WriteResults(ctx context.Context, logger *clog.Logger, cache ghscan.Cache,
 	}
 	return errs
 }
+
+// perRepoFileName maps an owner/repo pair to the base name used for its
+// per-repository outputs. GitHub logins cannot contain underscores, so
+// the first "__" in the result always marks the owner/repo boundary.
+// Anything that could escape ResultsDir is rejected.
+func perRepoFileName(repository string) (string, error) {
+	owner, repo, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || repo == "" || repo == "." || repo == ".." ||
+		strings.ContainsAny(owner, `.\`) || strings.ContainsAny(repo, `/\`) {
+		return "", fmt.Errorf("invalid repository name %q", repository)
+	}
+	return owner + "__" + repo, nil
+}
+
+// WritePerRepoResults writes one JSON file and one CSV file per
+// repository that has at least one result, named owner__repo.json and
+// owner__repo.csv under ghscan.ResultsDir. Repositories without
+// results produce no files. As with WriteResults, results are written
+// in [SortResults] order, and a failure for one repository does not
+// stop the others; the joined error is returned.
+func WritePerRepoResults(ctx context.Context, logger *clog.Logger, cache ghscan.Cache) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WritePerRepoResults: context already cancelled: %v", err)
+		return err
+	}
+
+	byRepo := make(map[string][]ghscan.Result)
+	for _, res := range redactResults(SortResults(cache.Results)) {
+		if res.Repository == "" {
+			continue
+		}
+		byRepo[res.Repository] = append(byRepo[res.Repository], res)
+	}
+	if len(byRepo) == 0 {
+		return nil
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	repos := make([]string, 0, len(byRepo))
+	for repo := range byRepo {
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+
+	var errs error
+	for _, repo := range repos {
+		base, err := perRepoFileName(repo)
+		if err != nil {
+			errs = errors.Join(errs, err)
+			continue
+		}
+		results := byRepo[repo]
+
+		data, err := json.MarshalIndent(ghscan.Cache{ScanID: cache.ScanID, ScanStartedAt: cache.ScanStartedAt, Results: results}, "", "  ")
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("marshaling results for %s: %w", repo, err))
+			continue
+		}
+		if werr := writeFile(filepath.Join(ghscan.ResultsDir, base+".json"), data); werr != nil {
+			logger.Errorf("Error writing JSON output for %s: %v", repo, werr)
+			errs = errors.Join(errs, fmt.Errorf("writing JSON output for %s: %w", repo, werr))
+		}
+		if werr := writeCSV(filepath.Join(ghscan.ResultsDir, base+".csv"), results); werr != nil {
+			logger.Errorf("Error writing CSV output for %s: %v", repo, werr)
+			errs = errors.Join(errs, fmt.Errorf("writing CSV output for %s: %w", repo, werr))
+		}
+	}
+
+	if errs == nil {
+		logger.Infof("Wrote per-repository outputs for %d repositories", len(repos))
+	}
+	return errs
+}
+
+// WriteSummary writes summary as indented JSON to summaryFile under
+// ghscan.ResultsDir. Map keys are emitted in sorted order, so the file
+// is stable across runs over the same results.
+func WriteSummary(ctx context.Context, logger *clog.Logger, summary ghscan.Summary, summaryFile string) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WriteSummary: context already cancelled: %v", err)
+		return err
+	}
+	if err := writeSummaryJSON(logger, summary, summaryFile); err != nil {
+		return err
+	}
+	logger.Infof("Wrote summary for %d repositories", len(summary))
+	return nil
+}
+
+// WriteSummaryReport writes report, a per-repository summary with its
+// totals, as indented JSON to summaryFile under ghscan.ResultsDir,
+// creating the file's directory if needed. Like [WriteSummary], its
+// output is stable across runs over the same results.
+func WriteSummaryReport(ctx context.Context, logger *clog.Logger, report ghscan.SummaryReport, summaryFile string) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WriteSummaryReport: context already cancelled: %v", err)
+		return err
+	}
+	if err := mkdirAll(filepath.Dir(filepath.Join(ghscan.ResultsDir, summaryFile))); err != nil {
+		return fmt.Errorf("creating summary directory: %w", err)
+	}
+	if err := writeSummaryJSON(logger, report, summaryFile); err != nil {
+		return err
+	}
+	logger.Infof("Wrote summary of %d findings in %d repositories", report.Totals.Findings, report.Totals.Repositories)
+	return nil
+}
+
+// writeSummaryJSON writes v as indented JSON to summaryFile under
+// ghscan.ResultsDir.
+func writeSummaryJSON(logger *clog.Logger, v any, summaryFile string) error {
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling summary: %w", err)
+	}
+	if err := writeFile(filepath.Join(ghscan.ResultsDir, summaryFile), data); err != nil {
+		logger.Errorf("Error writing summary: %v", err)
+		return fmt.Errorf("writing summary: %w", err)
+	}
+	return nil
+}
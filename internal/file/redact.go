@@ -0,0 +1,47 @@
+package file
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/chainguard-dev/ghscan/internal/redact"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// redactor holds the Redactor installed with SetRedactor; nil writes
+// results as they are.
+var redactor atomic.Pointer[redact.Redactor]
+
+// SetRedactor installs r to scrub secrets, such as the tokens the scan
+// authenticated with, from everything this package writes: every
+// result output, the [Appender] journal and CSV, the SARIF of
+// [BuildSARIF], and the logs a [LogKeeper] keeps. A nil r writes
+// results unchanged. Like [SetFileMode], it is intended to be
+// called once at program start, before anything is written.
+func SetRedactor(r *redact.Redactor) {
+	redactor.Store(r)
+}
+
+// redactResults returns results with the installed Redactor's secrets
+// scrubbed, leaving results itself untouched.
+func redactResults(results []ghscan.Result) []ghscan.Result {
+	r := redactor.Load()
+	if r == nil {
+		return results
+	}
+	out := slices.Clone(results)
+	for i := range out {
+		r.Strings(&out[i])
+	}
+	return out
+}
+
+// redactString returns s with the installed Redactor's secrets
+// scrubbed.
+func redactString(s string) string {
+	r := redactor.Load()
+	if r == nil {
+		return s
+	}
+	return r.String(s)
+}
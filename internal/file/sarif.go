@@ -0,0 +1,191 @@
+package file
+
+import (
+	"cmp"
+	"path"
+	"slices"
+	"strings"
+
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+const (
+	// sarifVersion and sarifSchema identify the SARIF 2.1.0 format
+	// GitHub code scanning accepts.
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	// SARIFCategory is the automation ID of every ghscan run, so code
+	// scanning keeps ghscan's alerts apart from other tools' and closes
+	// those a later upload no longer reports.
+	SARIFCategory = "ghscan/"
+
+	// sarifFingerprintKey names the partial fingerprint carrying each
+	// result's [ghscan.Fingerprint], which code scanning uses to track
+	// an alert across uploads.
+	sarifFingerprintKey = "ghscanFingerprint/v1"
+)
+
+// SARIFLog is a SARIF 2.1.0 log holding one run of ghscan. Only the
+// properties GitHub code scanning reads are modeled.
+type SARIFLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+// SARIFRun is the run of a [SARIFLog].
+type SARIFRun struct {
+	Tool              SARIFTool              `json:"tool"`
+	AutomationDetails SARIFAutomationDetails `json:"automationDetails"`
+	Results           []SARIFResult          `json:"results"`
+}
+
+// SARIFTool describes ghscan and the rules its results cite.
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+// SARIFDriver is the tool component that produced the results.
+type SARIFDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is one kind of finding, such as a base64 payload or a
+// compromised uses: reference.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+// SARIFAutomationDetails carries the run's category.
+type SARIFAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+// SARIFMessage is a plain-text SARIF message.
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+// SARIFResult is one finding.
+type SARIFResult struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             SARIFMessage      `json:"message"`
+	Locations           []SARIFLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+// SARIFLocation points a result at a file of the scanned repository.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation `json:"physicalLocation"`
+}
+
+// SARIFPhysicalLocation is the file a [SARIFLocation] names.
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+}
+
+// SARIFArtifactLocation is a path relative to the repository root.
+type SARIFArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// BuildSARIF renders results as a SARIF log for GitHub code scanning,
+// in [SortResults] order; empty results are skipped. Each result is
+// located at the workflow file it was found in, with the repository's
+// workflows directory standing in when it names none. As with workflow
+// annotations, the matched log content is left out of the messages:
+// code scanning alerts may be visible to more people than the logs.
+// What remains has the secrets of the Redactor installed by
+// [SetRedactor] scrubbed.
+func BuildSARIF(results []ghscan.Result) SARIFLog {
+	run := SARIFRun{
+		Tool: SARIFTool{Driver: SARIFDriver{
+			Name:           "ghscan",
+			InformationURI: "https://github.com/chainguard-dev/ghscan",
+			Rules:          []SARIFRule{},
+		}},
+		AutomationDetails: SARIFAutomationDetails{ID: SARIFCategory},
+		Results:           []SARIFResult{},
+	}
+	rules := make(map[string]bool)
+	for _, r := range redactResults(SortResults(results)) {
+		if r.IsEmpty() {
+			continue
+		}
+		id, desc := sarifRule(r)
+		if !rules[id] {
+			rules[id] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: id, ShortDescription: SARIFMessage{Text: desc}})
+		}
+		res := SARIFResult{
+			RuleID:  id,
+			Level:   sarifLevel(r.Severity),
+			Message: SARIFMessage{Text: sarifMessage(r)},
+			Locations: []SARIFLocation{{PhysicalLocation: SARIFPhysicalLocation{
+				ArtifactLocation: SARIFArtifactLocation{URI: sarifURI(r)},
+			}}},
+		}
+		if fp := cmp.Or(r.Fingerprint, ghscan.Fingerprint(r)); fp != "" {
+			res.PartialFingerprints = map[string]string{sarifFingerprintKey: fp}
+		}
+		run.Results = append(run.Results, res)
+	}
+	slices.SortFunc(run.Tool.Driver.Rules, func(a, b SARIFRule) int { return strings.Compare(a.ID, b.ID) })
+	return SARIFLog{Version: sarifVersion, Schema: sarifSchema, Runs: []SARIFRun{run}}
+}
+
+// sarifRule returns the rule ID and description of r's kind: the
+// detector for log findings, otherwise where it was found.
+func sarifRule(r ghscan.Result) (string, string) {
+	kind := cmp.Or(r.KeyTypes, r.Source, "ioc")
+	return "ghscan/" + kind, "ghscan " + kind + " finding"
+}
+
+// sarifLevel maps a finding's severity to a SARIF level the way
+// workflow annotations do: unrated findings (direct IOC, YAML, and
+// history matches) are errors alongside critical and high ones.
+func sarifLevel(severity string) string {
+	switch severity {
+	case workflow.SeverityMedium:
+		return "warning"
+	case workflow.SeverityLow:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+// sarifMessage describes r without its matched content.
+func sarifMessage(r ghscan.Result) string {
+	msg := cmp.Or(r.IOCName, "IOC") + " matched"
+	if r.WorkflowFileName != "" {
+		msg += " in " + r.WorkflowFileName
+	}
+	if kind := strings.TrimSpace(r.Severity + " " + r.KeyTypes); kind != "" {
+		msg += " (" + kind + ")"
+	}
+	if r.Action != "" {
+		msg += " via " + r.Action
+	}
+	if r.Source != "" {
+		msg += "; source: " + r.Source
+	}
+	if url := cmp.Or(r.WorkflowRunURL, r.WorkflowURL); url != "" {
+		msg += " " + url
+	}
+	return msg
+}
+
+// sarifURI returns the repository path a result is located at.
+func sarifURI(r ghscan.Result) string {
+	if r.WorkflowFileName == "" {
+		return ".github/workflows"
+	}
+	return path.Join(".github/workflows", r.WorkflowFileName)
+}
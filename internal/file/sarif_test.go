@@ -0,0 +1,82 @@
+package file_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// TestBuildSARIF asserts severities map to SARIF levels, each kind of
+// finding becomes one rule, results carry their fingerprint, and
+// matched content stays out of the log.
+func TestBuildSARIF(t *testing.T) {
+	t.Parallel()
+
+	results := []ghscan.Result{
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "echo c2VjcmV0", Base64Data: "c2VjcmV0", DecodedData: "secret", KeyTypes: "base64", Severity: "medium", IOCName: "tj"},
+		{Repository: "o/a", WorkflowFileName: "ci.yml", OffendingUsesLine: "uses: tj-actions/changed-files@v35", Source: "workflow", IOCName: "tj-actions/changed-files"},
+		{Repository: "o/a", LineData: "curl evil.example", KeyTypes: "network-egress", Severity: "low"},
+		{Repository: "o/a"},
+	}
+	log := file.BuildSARIF(results)
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("version=%q runs=%d, want one SARIF 2.1.0 run", log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if run.AutomationDetails.ID != file.SARIFCategory {
+		t.Errorf("category=%q, want %q", run.AutomationDetails.ID, file.SARIFCategory)
+	}
+	if len(run.Results) != 3 {
+		t.Fatalf("got %d results, want 3 (the empty result dropped)", len(run.Results))
+	}
+
+	var ruleIDs []string
+	for _, r := range run.Tool.Driver.Rules {
+		ruleIDs = append(ruleIDs, r.ID)
+	}
+	if got, want := strings.Join(ruleIDs, ","), "ghscan/base64,ghscan/network-egress,ghscan/workflow"; got != want {
+		t.Errorf("rules=%s, want %s", got, want)
+	}
+
+	levels := make(map[string]string)
+	for _, r := range run.Results {
+		levels[r.RuleID] = r.Level
+		if r.PartialFingerprints["ghscanFingerprint/v1"] == "" {
+			t.Errorf("%s result has no fingerprint", r.RuleID)
+		}
+	}
+	for rule, want := range map[string]string{
+		"ghscan/base64":         "warning",
+		"ghscan/network-egress": "note",
+		"ghscan/workflow":       "error",
+	} {
+		if levels[rule] != want {
+			t.Errorf("%s level=%q, want %q", rule, levels[rule], want)
+		}
+	}
+
+	for _, r := range run.Results {
+		if r.RuleID != "ghscan/base64" {
+			continue
+		}
+		if got := r.Locations[0].PhysicalLocation.ArtifactLocation.URI; got != ".github/workflows/ci.yml" {
+			t.Errorf("uri=%q, want .github/workflows/ci.yml", got)
+		}
+		if want := ghscan.Fingerprint(results[0]); r.PartialFingerprints["ghscanFingerprint/v1"] != want {
+			t.Errorf("fingerprint=%q, want %q", r.PartialFingerprints["ghscanFingerprint/v1"], want)
+		}
+	}
+
+	data, err := json.Marshal(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"secret", "c2VjcmV0", "evil.example"} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("SARIF log contains matched content %q:\n%s", leak, data)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// UnratedSeverity names the report section for results whose detector
+// assigned no severity, such as plain IOC and YAML findings.
+const UnratedSeverity = "unrated"
+
+// severityOrder lists report sections from most to least severe.
+var severityOrder = []string{
+	workflow.SeverityCritical,
+	workflow.SeverityHigh,
+	workflow.SeverityMedium,
+	workflow.SeverityLow,
+	UnratedSeverity,
+}
+
+// SeveritySection is one severity's slice of a [SeverityReport].
+type SeveritySection struct {
+	Severity string          `json:"severity"`
+	Count    int             `json:"count"`
+	Results  []ghscan.Result `json:"results"`
+}
+
+// SeverityReport is the JSON shape written by [WriteSeverityReport]:
+// one section per severity that has findings, most severe first.
+type SeverityReport struct {
+	Total    int               `json:"total"`
+	Sections []SeveritySection `json:"sections"`
+}
+
+// sectionOf maps a result's severity label to its report section.
+// Labels SeverityRank does not know are reported as unrated.
+func sectionOf(r ghscan.Result) string {
+	if workflow.SeverityRank(r.Severity) == 0 {
+		return UnratedSeverity
+	}
+	return r.Severity
+}
+
+// SortBySeverity returns a copy of results ordered from most to least
+// severe, with unrated results last. Results of equal severity are in
+// [SortResults] order.
+func SortBySeverity(results []ghscan.Result) []ghscan.Result {
+	out := SortResults(results)
+	slices.SortStableFunc(out, func(a, b ghscan.Result) int {
+		return cmp.Compare(workflow.SeverityRank(b.Severity), workflow.SeverityRank(a.Severity))
+	})
+	return out
+}
+
+// GroupBySeverity sections results by severity, most severe first,
+// each section in [SortResults] order. Empty results are skipped, as
+// in the CSV output, and severities with no findings get no section.
+func GroupBySeverity(results []ghscan.Result) SeverityReport {
+	bySection := make(map[string][]ghscan.Result)
+	var report SeverityReport
+	for _, r := range SortResults(results) {
+		if r.IsEmpty() {
+			continue
+		}
+		bySection[sectionOf(r)] = append(bySection[sectionOf(r)], r)
+		report.Total++
+	}
+	for _, sev := range severityOrder {
+		if rs := bySection[sev]; len(rs) > 0 {
+			report.Sections = append(report.Sections, SeveritySection{Severity: sev, Count: len(rs), Results: rs})
+		}
+	}
+	return report
+}
+
+// WriteSeverityReport writes the -group-by-severity form of the final
+// outputs under ghscan.ResultsDir: jsonFile receives a
+// [SeverityReport] and csvFile the usual rows sorted by severity. The
+// cache is left to [WriteResults] so it stays loadable. Empty names
+// skip that output; errors from both are joined.
+func WriteSeverityReport(ctx context.Context, logger *clog.Logger, results []ghscan.Result, jsonFile, csvFile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	results = redactResults(results)
+
+	var errs error
+	if jsonFile != "" {
+		data, err := json.MarshalIndent(GroupBySeverity(results), "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling severity report: %w", err)
+		}
+		if werr := writeFile(filepath.Join(ghscan.ResultsDir, jsonFile), data); werr != nil {
+			errs = errors.Join(errs, fmt.Errorf("writing JSON output: %w", werr))
+		}
+	}
+	if csvFile != "" {
+		if werr := writeCSV(filepath.Join(ghscan.ResultsDir, csvFile), SortBySeverity(results)); werr != nil {
+			errs = errors.Join(errs, fmt.Errorf("writing CSV output: %w", werr))
+		}
+	}
+
+	if errs != nil {
+		logger.Errorf("Error writing severity report: %v", errs)
+		return errs
+	}
+	logger.Infof("Wrote severity-grouped report of %d results", len(results))
+	return nil
+}
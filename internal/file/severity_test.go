@@ -0,0 +1,83 @@
+package file_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/file"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+func severityFixture() []ghscan.Result {
+	return []ghscan.Result{
+		{Repository: "o/a", LineData: "plain ioc hit"},
+		{Repository: "o/a", LineData: "cache key", Severity: "low"},
+		{Repository: "o/b", LineData: "private key", Severity: "critical"},
+		{Repository: "o/b", LineData: "curl evil.example", Severity: "medium"},
+		{Repository: "o/c", LineData: "second key", Severity: "critical"},
+		{Repository: "o/c"},
+	}
+}
+
+// TestGroupBySeverity asserts sections run from critical to unrated,
+// omit severities with no findings, and count only non-empty results.
+func TestGroupBySeverity(t *testing.T) {
+	t.Parallel()
+
+	got := file.GroupBySeverity(severityFixture())
+	if got.Total != 5 {
+		t.Fatalf("Total=%d, want 5", got.Total)
+	}
+	var sections []string
+	for _, s := range got.Sections {
+		sections = append(sections, s.Severity)
+		if s.Count != len(s.Results) {
+			t.Fatalf("section %s Count=%d, but holds %d results", s.Severity, s.Count, len(s.Results))
+		}
+	}
+	if want := []string{"critical", "medium", "low", file.UnratedSeverity}; !slices.Equal(sections, want) {
+		t.Fatalf("sections=%v, want %v", sections, want)
+	}
+	if crit := got.Sections[0].Results; crit[0].Repository != "o/b" || crit[1].Repository != "o/c" {
+		t.Fatalf("critical section out of scan order: %+v", crit)
+	}
+}
+
+// TestWriteSeverityReport asserts the JSON output is the sectioned
+// report and the CSV rows, which carry a Severity column, are sorted
+// most severe first.
+func TestWriteSeverityReport(t *testing.T) {
+	chdirTemp(t)
+
+	if err := file.WriteSeverityReport(t.Context(), newSilentLogger(), severityFixture(), "report.json", "report.csv"); err != nil {
+		t.Fatalf("WriteSeverityReport: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ghscan.ResultsDir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report file.SeverityReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(report.Sections) != 4 || report.Sections[0].Severity != "critical" || report.Sections[0].Count != 2 {
+		t.Fatalf("report sections=%+v, want critical (2) first of 4", report.Sections)
+	}
+
+	rows := readCSVRows(t, filepath.Join(ghscan.ResultsDir, "report.csv"))
+	sevCol := slices.Index(rows[0], "Severity")
+	if sevCol < 0 {
+		t.Fatalf("CSV header %v has no Severity column", rows[0])
+	}
+	var got []string
+	for _, row := range rows[1:] {
+		got = append(got, row[sevCol])
+	}
+	if want := []string{"critical", "critical", "medium", "low", ""}; !slices.Equal(got, want) {
+		t.Fatalf("CSV severities=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package file
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+
+	// The pure-Go driver registers itself as sqliteDriver, so the
+	// binary needs no cgo toolchain.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name modernc.org/sqlite
+// registers.
+const sqliteDriver = "sqlite"
+
+// sqliteSchema creates the findings table and its lookup index. Every
+// statement is idempotent so scheduled runs can append to the same
+// database.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS findings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		scanned_at TEXT NOT NULL,
+		repository TEXT,
+		ioc_name TEXT,
+		source TEXT,
+		workflow_file_name TEXT,
+		workflow_url TEXT,
+		workflow_run_url TEXT,
+		workflow_file_sha TEXT,
+		job_name TEXT,
+		step_name TEXT,
+		line_data TEXT,
+		base64_data TEXT,
+		decoded_data TEXT,
+		decode_depth INTEGER,
+		offending_uses_line TEXT,
+		resolved_ref_form TEXT,
+		reachable_secrets TEXT,
+		key_types TEXT,
+		severity TEXT,
+		commit_sha TEXT,
+		commit_author TEXT,
+		run_status TEXT,
+		run_conclusion TEXT,
+		destination TEXT,
+		note TEXT,
+		scan_id TEXT,
+		matched_pattern TEXT,
+		match_offset INTEGER,
+		context TEXT,
+		confidence INTEGER,
+		run_urls TEXT,
+		fingerprint TEXT,
+		run_attempt INTEGER
+	)`,
+	`CREATE INDEX IF NOT EXISTS findings_repository_ioc_name ON findings (repository, ioc_name)`,
+}
+
+const sqliteInsert = `INSERT INTO findings (
+	scanned_at, repository, ioc_name, source, workflow_file_name,
+	workflow_url, workflow_run_url, workflow_file_sha, job_name, step_name,
+	line_data, base64_data, decoded_data, decode_depth, offending_uses_line,
+	resolved_ref_form, reachable_secrets, key_types, severity, commit_sha,
+	commit_author, run_status, run_conclusion, destination, note,
+	scan_id, matched_pattern, match_offset, context, confidence,
+	run_urls, fingerprint, run_attempt
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// WriteSQLite appends results to the findings table of the SQLite
+// database at dbFile, resolved under ghscan.ResultsDir, creating the
+// table and index on first use. Each row is stamped with scannedAt so
+// findings from successive runs can be told apart. All rows are
+// inserted in one transaction with parameterized statements, in
+// [SortResults] order; empty results are skipped, matching CSV
+// emission.
+func WriteSQLite(ctx context.Context, logger *clog.Logger, results []ghscan.Result, dbFile string, scannedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WriteSQLite: context already cancelled: %v", err)
+		return err
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+
+	dbPath := filepath.Join(ghscan.ResultsDir, filepath.Clean(dbFile))
+	db, err := sql.Open(sqliteDriver, dbPath)
+	if err != nil {
+		return fmt.Errorf("opening sqlite database: %w", err)
+	}
+	defer db.Close()
+
+	for _, stmt := range sqliteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("creating findings schema: %w", err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning sqlite transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert, err := tx.PrepareContext(ctx, sqliteInsert)
+	if err != nil {
+		return fmt.Errorf("preparing findings insert: %w", err)
+	}
+	defer insert.Close()
+
+	ts := scannedAt.UTC().Format(time.RFC3339)
+	written := 0
+	for _, r := range redactResults(SortResults(results)) {
+		if r.IsEmpty() {
+			continue
+		}
+		if _, err := insert.ExecContext(ctx,
+			ts, r.Repository, r.IOCName, r.Source, r.WorkflowFileName,
+			r.WorkflowURL, r.WorkflowRunURL, r.WorkflowFileSHA, r.JobName, r.StepName,
+			r.LineData, r.Base64Data, r.DecodedData, r.DecodeDepth, r.OffendingUsesLine,
+			r.ResolvedRefForm, strings.Join(r.ReachableSecrets, ","), r.KeyTypes, r.Severity, r.CommitSHA,
+			r.CommitAuthor, r.RunStatus, r.RunConclusion, r.Destination, r.Note,
+			r.ScanID, r.MatchedPattern, r.MatchOffset, r.Context, r.Confidence,
+			strings.Join(r.RunURLs, ","), r.Fingerprint, r.RunAttempt,
+		); err != nil {
+			return fmt.Errorf("inserting finding for %s: %w", r.Repository, err)
+		}
+		written++
+	}
+	if err := tx.Commit(); err != nil {
+		logger.Errorf("Error writing sqlite database: %v", err)
+		return fmt.Errorf("committing findings: %w", err)
+	}
+	// The driver creates the database with its own default mode.
+	if err := os.Chmod(dbPath, FileMode()); err != nil {
+		return fmt.Errorf("setting sqlite database mode: %w", err)
+	}
+	logger.Infof("Appended %d findings to %s", written, dbFile)
+	return nil
+}
@@ -0,0 +1,303 @@
+package file
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha1" //nolint:gosec // UUIDv5 is defined over SHA-1.
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
+)
+
+// stixNamespace is the UUIDv5 namespace STIX 2.1 defines for
+// deterministic cyber-observable identifiers. ghscan derives every
+// object ID from it, so re-exporting the same findings yields the same
+// IDs and a threat-intelligence platform can deduplicate them.
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixTimeFormat is the STIX 2.1 timestamp format, in UTC with
+// millisecond precision.
+const stixTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// stixScpRE matches git's scp-like remote syntax, capturing the host.
+var stixScpRE = regexp.MustCompile(`^(?:[^@/\s]+@)?([A-Za-z0-9.-]+):`)
+
+// STIXObject is one object of a [STIXBundle]. It holds the properties
+// of every STIX 2.1 type BuildSTIX emits (url, observed-data,
+// indicator, and relationship); each type sets only its own.
+type STIXObject struct {
+	Type           string   `json:"type"`
+	SpecVersion    string   `json:"spec_version"`
+	ID             string   `json:"id"`
+	Created        string   `json:"created,omitempty"`
+	Modified       string   `json:"modified,omitempty"`
+	Name           string   `json:"name,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	IndicatorTypes []string `json:"indicator_types,omitempty"`
+	Pattern        string   `json:"pattern,omitempty"`
+	PatternType    string   `json:"pattern_type,omitempty"`
+	ValidFrom      string   `json:"valid_from,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+	FirstObserved  string   `json:"first_observed,omitempty"`
+	LastObserved   string   `json:"last_observed,omitempty"`
+	NumberObserved int      `json:"number_observed,omitempty"`
+	ObjectRefs     []string `json:"object_refs,omitempty"`
+	Value          string   `json:"value,omitempty"`
+	RelationType   string   `json:"relationship_type,omitempty"`
+	SourceRef      string   `json:"source_ref,omitempty"`
+	TargetRef      string   `json:"target_ref,omitempty"`
+}
+
+// STIXBundle is a STIX 2.1 bundle.
+type STIXBundle struct {
+	Type    string       `json:"type"`
+	ID      string       `json:"id"`
+	Objects []STIXObject `json:"objects"`
+}
+
+// stixID returns the deterministic identifier of the typ object named
+// by name.
+func stixID(typ, name string) string {
+	h := sha1.New() //nolint:gosec // UUIDv5 is defined over SHA-1.
+	h.Write(stixNamespace[:])
+	h.Write([]byte(name))
+	b := h.Sum(nil)[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%s--%x-%x-%x-%x-%x", typ, b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// stixURLID returns the identifier STIX 2.1 specifies for the url
+// object of value: the UUIDv5 of its canonical {"value": ...} JSON.
+func stixURLID(value string) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(map[string]string{"value": value})
+	return stixID("url", strings.TrimSuffix(b.String(), "\n"))
+}
+
+// stixString quotes s as a STIX pattern string literal.
+func stixString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// stixIndicator is an indicator derived from a finding, before it is
+// assigned timestamps.
+type stixIndicator struct {
+	name, pattern, patternType string
+}
+
+// stixIndicators returns the indicators a finding yields: the encoded
+// payload of a base64 finding, the IOC content string that matched,
+// and the host or URL the finding names as its destination. Findings
+// whose decoded form is a recovered secret or key material (mask
+// bypass and PEM findings) export no payload.
+func stixIndicators(r ghscan.Result) []stixIndicator {
+	var out []stixIndicator
+	if r.Base64Data != "" && r.KeyTypes == "" {
+		out = append(out, stixIndicator{
+			name:        "Encoded payload printed in a workflow log",
+			pattern:     fmt.Sprintf("[artifact:payload_bin = %s]", stixString(r.Base64Data)),
+			patternType: "stix",
+		})
+	}
+	if r.MatchedPattern != "" && r.Base64Data == "" && r.KeyTypes == "" {
+		// A literal IOC content string, such as a compromised commit
+		// digest, has no STIX observable of its own; a PCRE pattern
+		// matches it wherever it is printed.
+		out = append(out, stixIndicator{
+			name:        "IOC content " + r.MatchedPattern,
+			pattern:     regexp.QuoteMeta(r.MatchedPattern),
+			patternType: "pcre",
+		})
+	}
+	if p := stixDestinationPattern(r.Destination); p != "" {
+		out = append(out, stixIndicator{
+			name:        "Destination " + r.Destination,
+			pattern:     p,
+			patternType: "stix",
+		})
+	}
+	return out
+}
+
+// stixDestinationPattern returns a STIX pattern for a finding's
+// destination: a url for a URL, and otherwise a domain-name or IP
+// address for the host, taken from a git scp-like remote when needed.
+func stixDestinationPattern(dest string) string {
+	if dest == "" {
+		return ""
+	}
+	if strings.Contains(dest, "://") {
+		if u, err := url.Parse(dest); err == nil && u.Host != "" {
+			return fmt.Sprintf("[url:value = %s]", stixString(dest))
+		}
+		return ""
+	}
+	host := dest
+	if m := stixScpRE.FindStringSubmatch(dest); m != nil {
+		host = m[1]
+	}
+	host = strings.ToLower(host)
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			return fmt.Sprintf("[ipv4-addr:value = %s]", stixString(host))
+		}
+		return fmt.Sprintf("[ipv6-addr:value = %s]", stixString(host))
+	}
+	return fmt.Sprintf("[domain-name:value = %s]", stixString(host))
+}
+
+// stixObservedURL returns the page a finding was observed at: its run,
+// else its workflow, else its repository.
+func stixObservedURL(r ghscan.Result) string {
+	switch {
+	case r.WorkflowRunURL != "":
+		return r.WorkflowRunURL
+	case r.WorkflowURL != "":
+		return r.WorkflowURL
+	default:
+		return "https://github.com/" + r.Repository
+	}
+}
+
+// BuildSTIX converts results into a STIX 2.1 bundle. Each run (or
+// workflow, for findings without a run) with findings becomes an
+// observed-data object referencing a url object for its page; each
+// distinct indicator (see stixIndicators) becomes an indicator object
+// with a based-on relationship to every observation it came from.
+// observed is when the findings were seen, typically the scan's start;
+// now stamps object creation. Object IDs are derived from their
+// content, and objects are listed in [SortResults] order, so the same
+// findings always yield the same objects.
+func BuildSTIX(results []ghscan.Result, observed, now time.Time) STIXBundle {
+	created := now.UTC().Format(stixTimeFormat)
+	seen := observed.UTC().Format(stixTimeFormat)
+
+	var (
+		urls       []STIXObject
+		observes   []STIXObject
+		indicators []STIXObject
+		relations  []STIXObject
+	)
+	observedIdx := make(map[string]int)
+	emitted := make(map[string]bool)
+	for _, r := range SortResults(results) {
+		if r.IsEmpty() {
+			continue
+		}
+
+		page := stixObservedURL(r)
+		urlID := stixURLID(page)
+		obsID := stixID("observed-data", "observed-data|"+r.Repository+"|"+page)
+		if i, ok := observedIdx[obsID]; ok {
+			observes[i].NumberObserved++
+		} else {
+			if !emitted[urlID] {
+				emitted[urlID] = true
+				urls = append(urls, STIXObject{Type: "url", SpecVersion: "2.1", ID: urlID, Value: page})
+			}
+			observedIdx[obsID] = len(observes)
+			observes = append(observes, STIXObject{
+				Type:           "observed-data",
+				SpecVersion:    "2.1",
+				ID:             obsID,
+				Created:        created,
+				Modified:       created,
+				FirstObserved:  seen,
+				LastObserved:   seen,
+				NumberObserved: 1,
+				ObjectRefs:     []string{urlID},
+			})
+		}
+
+		for _, ind := range stixIndicators(r) {
+			indID := stixID("indicator", "indicator|"+ind.patternType+"|"+ind.pattern)
+			if !emitted[indID] {
+				emitted[indID] = true
+				indicatorType := "malicious-activity"
+				if r.Severity == "low" || r.Severity == "medium" {
+					indicatorType = "anomalous-activity"
+				}
+				var labels []string
+				if r.IOCName != "" {
+					labels = []string{r.IOCName}
+				}
+				indicators = append(indicators, STIXObject{
+					Type:           "indicator",
+					SpecVersion:    "2.1",
+					ID:             indID,
+					Created:        created,
+					Modified:       created,
+					Name:           ind.name,
+					Description:    fmt.Sprintf("Reported by ghscan in %s (%s)", r.Repository, cmp.Or(r.KeyTypes, "log IOC")),
+					IndicatorTypes: []string{indicatorType},
+					Pattern:        ind.pattern,
+					PatternType:    ind.patternType,
+					ValidFrom:      seen,
+					Labels:         labels,
+				})
+			}
+			relID := stixID("relationship", "relationship|"+indID+"|"+obsID)
+			if !emitted[relID] {
+				emitted[relID] = true
+				relations = append(relations, STIXObject{
+					Type:         "relationship",
+					SpecVersion:  "2.1",
+					ID:           relID,
+					Created:      created,
+					Modified:     created,
+					RelationType: "based-on",
+					SourceRef:    indID,
+					TargetRef:    obsID,
+				})
+			}
+		}
+	}
+
+	objects := make([]STIXObject, 0, len(urls)+len(observes)+len(indicators)+len(relations))
+	objects = append(objects, urls...)
+	objects = append(objects, observes...)
+	objects = append(objects, indicators...)
+	objects = append(objects, relations...)
+	return STIXBundle{Type: "bundle", ID: "bundle--" + ghscan.NewScanID(), Objects: objects}
+}
+
+// WriteSTIX writes the cache's results as a STIX 2.1 bundle (see
+// [BuildSTIX]) to stixFile under ghscan.ResultsDir, for a threat
+// intelligence platform to ingest. Findings are dated by the cache's
+// ScanStartedAt, or the current time when it is unset.
+func WriteSTIX(ctx context.Context, logger *clog.Logger, cache ghscan.Cache, stixFile string) error {
+	if err := ctx.Err(); err != nil {
+		logger.Warnf("WriteSTIX: context already cancelled: %v", err)
+		return err
+	}
+	if err := mkdirAll(ghscan.ResultsDir); err != nil {
+		return fmt.Errorf("creating results directory: %w", err)
+	}
+	now := time.Now()
+	observed := cache.ScanStartedAt
+	if observed.IsZero() {
+		observed = now
+	}
+	bundle := BuildSTIX(redactResults(cache.Results), observed, now)
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding STIX bundle: %w", err)
+	}
+	if err := writeFile(filepath.Join(ghscan.ResultsDir, stixFile), data); err != nil {
+		logger.Errorf("Error writing STIX bundle: %v", err)
+		return fmt.Errorf("writing STIX bundle: %w", err)
+	}
+	logger.Infof("Wrote STIX bundle with %d objects", len(bundle.Objects))
+	return nil
+}
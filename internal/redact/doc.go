@@ -0,0 +1,23 @@
+// Package redact scrubs configured secrets, such as the API tokens
+// ghscan authenticates with, from text before it is logged or written
+// to an output file. It is a safety net: no code path is meant to
+// carry a token into an error or URL, but output files get shared, so
+// one that does must not leak it.
+//
+// Public surface:
+//
+//   - [New] builds a [Redactor] for a set of secrets; [Redactor.String]
+//     replaces each of them with [Placeholder].
+//   - [Redactor.Handler] wraps a [log/slog.Handler] so every message
+//     and attribute it handles is scrubbed first.
+//   - [Redactor.Strings] scrubs the string fields of a struct in
+//     place, for records about to be written out.
+//
+// Invariants:
+//
+//   - A nil *Redactor, as returned when there is nothing to scrub, is
+//     valid and changes nothing.
+//   - Secrets shorter than [MinSecretLen] are ignored, so a short
+//     placeholder token in a test or misconfiguration cannot turn
+//     ordinary words into [Placeholder].
+package redact
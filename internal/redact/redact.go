@@ -0,0 +1,166 @@
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Placeholder replaces every scrubbed secret.
+const Placeholder = "[REDACTED]"
+
+// MinSecretLen is the shortest secret [New] scrubs. GitHub and GitLab
+// tokens are far longer.
+const MinSecretLen = 8
+
+// Redactor scrubs a fixed set of secrets from text. It is safe for
+// concurrent use.
+type Redactor struct {
+	replacer *strings.Replacer
+}
+
+// New returns a Redactor for secrets, or nil when none is at least
+// MinSecretLen bytes long. Longer secrets are matched first, so one
+// that contains another is scrubbed whole.
+func New(secrets ...string) *Redactor {
+	var kept []string
+	for _, s := range secrets {
+		if len(s) >= MinSecretLen && !slices.Contains(kept, s) {
+			kept = append(kept, s)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	slices.SortFunc(kept, func(a, b string) int { return len(b) - len(a) })
+	pairs := make([]string, 0, 2*len(kept))
+	for _, s := range kept {
+		pairs = append(pairs, s, Placeholder)
+	}
+	return &Redactor{replacer: strings.NewReplacer(pairs...)}
+}
+
+// String returns s with every secret replaced by Placeholder.
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// Strings scrubs, in place, every string and []string field of the
+// struct v points at, descending into nested structs. Other values are
+// left alone. A []string holding a secret is replaced by a scrubbed
+// copy rather than written through, so scrubbing a copy of a struct
+// leaves the original's slices intact.
+func (r *Redactor) Strings(v any) {
+	if r == nil {
+		return
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return
+	}
+	r.value(rv.Elem())
+}
+
+func (r *Redactor) value(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(r.String(v.String()))
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			r.value(v.Field(i))
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String || !v.CanSet() {
+			return
+		}
+		var out reflect.Value
+		for i := range v.Len() {
+			s := v.Index(i).String()
+			scrubbed := r.String(s)
+			if scrubbed == s {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(out, v)
+			}
+			out.Index(i).SetString(scrubbed)
+		}
+		if out.IsValid() {
+			v.Set(out)
+		}
+	}
+}
+
+// Handler returns h wrapped so the message and attributes of every
+// record are scrubbed before h sees them. An error, Stringer, or other
+// non-scalar value whose formatted text carries a secret is replaced by
+// that text, scrubbed. A nil Redactor returns h unchanged.
+func (r *Redactor) Handler(h slog.Handler) slog.Handler {
+	if r == nil {
+		return h
+	}
+	return &handler{r: r, next: h}
+}
+
+type handler struct {
+	r    *Redactor
+	next slog.Handler
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *handler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.r.String(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = h.attr(a)
+	}
+	return &handler{r: h.r, next: h.next.WithAttrs(scrubbed)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{r: h.r, next: h.next.WithGroup(name)}
+}
+
+// attr scrubs a's value, recursing into groups.
+func (h *handler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.r.String(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, g := range group {
+			scrubbed[i] = h.attr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(scrubbed...)}
+	case slog.KindAny:
+		s := fmt.Sprint(v.Any())
+		if scrubbed := h.r.String(s); scrubbed != s {
+			return slog.String(a.Key, scrubbed)
+		}
+		return slog.Attr{Key: a.Key, Value: v}
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
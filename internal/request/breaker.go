@@ -0,0 +1,98 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/cenkalti/backoff/v5"
+	"github.com/chainguard-dev/clog"
+)
+
+// ErrCircuitOpen is returned by [Breaker.WithRetryN], without running
+// the operation, once the breaker has tripped.
+var ErrCircuitOpen = errors.New("circuit breaker open after consecutive failures")
+
+// Breaker short-circuits retries against a target that keeps failing.
+// It counts operations that exhausted their retry budget; a success
+// resets the count, and reaching the threshold opens the breaker for
+// good so every later operation fails fast with [ErrCircuitOpen]
+// instead of spending its own retry budget. Operations that return a
+// [Permanent] error, one [WithRetryN] does not retry such as a 404, or
+// observe cancellation are not counted. An [UnavailableError] says the
+// whole target is withheld, so once one is seen every later operation
+// returns it without running; [Breaker.Unavailable] reports it.
+//
+// A Breaker is shared by the concurrent operations against one target
+// and is safe for concurrent use. A nil *Breaker is disabled and
+// delegates straight to [WithRetryN].
+type Breaker struct {
+	threshold   int
+	failures    atomic.Int32
+	open        atomic.Bool
+	unavailable atomic.Pointer[UnavailableError]
+}
+
+// NewBreaker returns a Breaker that opens after threshold consecutive
+// failures, or nil (disabled) when threshold is not positive.
+func NewBreaker(threshold int) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &Breaker{threshold: threshold}
+}
+
+// Open reports whether the breaker has tripped.
+func (b *Breaker) Open() bool {
+	return b != nil && b.open.Load()
+}
+
+// Unavailable returns the [UnavailableError] an operation through the
+// breaker failed with, or nil when none has.
+func (b *Breaker) Unavailable() *UnavailableError {
+	if b == nil {
+		return nil
+	}
+	return b.unavailable.Load()
+}
+
+// WithRetryN runs operation under [WithRetryN] unless the breaker is
+// open or its target is unavailable. An operation still retrying when
+// another one trips the breaker stops at its next attempt.
+func (b *Breaker) WithRetryN(ctx context.Context, logger *clog.Logger, maxRetries int, operation func() error) error {
+	if b == nil {
+		return WithRetryN(ctx, logger, maxRetries, operation)
+	}
+	if b.open.Load() {
+		return ErrCircuitOpen
+	}
+	if uerr := b.unavailable.Load(); uerr != nil {
+		return uerr
+	}
+
+	var terminal bool
+	err := WithRetryN(ctx, logger, maxRetries, func() error {
+		if b.open.Load() {
+			terminal = true
+			return Permanent(ErrCircuitOpen)
+		}
+		err := operation()
+		var permErr *backoff.PermanentError
+		terminal = err != nil && (errors.As(err, &permErr) || !retryable(err))
+		return err
+	})
+
+	var uerr *UnavailableError
+	switch {
+	case err == nil:
+		b.failures.Store(0)
+	case errors.As(err, &uerr):
+		b.unavailable.CompareAndSwap(nil, uerr)
+	case terminal || errors.Is(ctx.Err(), context.Canceled):
+	default:
+		if int(b.failures.Add(1)) >= b.threshold && b.open.CompareAndSwap(false, true) {
+			logger.Warnf("Circuit breaker opened after %d consecutive failures: %v", b.threshold, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package request_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/internal/request"
+	"github.com/google/go-github/v86/github"
+)
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	b := request.NewBreaker(2)
+	var calls int32
+	fail := func() error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("repository unavailable")
+	}
+
+	for i := range 2 {
+		if err := b.WithRetryN(t.Context(), newSilentLogger(), 0, fail); err == nil || errors.Is(err, request.ErrCircuitOpen) {
+			t.Fatalf("failure %d: err=%v, want the operation's error", i+1, err)
+		}
+	}
+	if !b.Open() {
+		t.Fatal("breaker should be open after threshold failures")
+	}
+
+	err := b.WithRetryN(t.Context(), newSilentLogger(), 0, fail)
+	if !errors.Is(err, request.ErrCircuitOpen) {
+		t.Fatalf("err=%v, want ErrCircuitOpen", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls=%d, want 2 (open breaker must not run the operation)", calls)
+	}
+}
+
+func TestBreaker_SuccessResetsCount(t *testing.T) {
+	t.Parallel()
+
+	b := request.NewBreaker(2)
+	fail := func() error { return errors.New("transient") }
+	ok := func() error { return nil }
+
+	for _, op := range []func() error{fail, ok, fail, ok, fail} {
+		_ = b.WithRetryN(t.Context(), newSilentLogger(), 0, op)
+	}
+	if b.Open() {
+		t.Fatal("non-consecutive failures must not open the breaker")
+	}
+}
+
+func TestBreaker_IgnoresPermanentAndCancellation(t *testing.T) {
+	t.Parallel()
+
+	b := request.NewBreaker(1)
+	err := b.WithRetryN(t.Context(), newSilentLogger(), 0, func() error {
+		return request.Permanent(errors.New("run has no logs"))
+	})
+	if err == nil {
+		t.Fatal("expected the permanent error")
+	}
+
+	err = b.WithRetryN(t.Context(), newSilentLogger(), 0, func() error {
+		return newErrorResponse(http.StatusNotFound, nil, "Not Found")
+	})
+	if err == nil {
+		t.Fatal("expected the 404 error")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_ = b.WithRetryN(ctx, newSilentLogger(), 0, func() error { return errors.New("boom") })
+
+	if b.Open() {
+		t.Fatal("permanent errors, non-retryable responses, and cancellation must not open the breaker")
+	}
+}
+
+func TestBreaker_NilIsDisabled(t *testing.T) {
+	t.Parallel()
+
+	b := request.NewBreaker(0)
+	if b != nil {
+		t.Fatal("NewBreaker(0) should return a disabled (nil) breaker")
+	}
+	for range 3 {
+		if err := b.WithRetryN(t.Context(), newSilentLogger(), 0, func() error { return errors.New("boom") }); errors.Is(err, request.ErrCircuitOpen) {
+			t.Fatal("nil breaker must never open")
+		}
+	}
+	if b.Open() {
+		t.Fatal("nil breaker reports open")
+	}
+}
+
+// TestBreaker_RemembersUnavailable asserts that once a target is found
+// withheld, later operations return the same error without running
+// and the breaker itself stays closed.
+func TestBreaker_RemembersUnavailable(t *testing.T) {
+	t.Parallel()
+
+	b := request.NewBreaker(5)
+	blocked := &github.ErrorResponse{
+		Response: &http.Response{StatusCode: http.StatusUnavailableForLegalReasons, Request: &http.Request{Method: http.MethodGet}},
+		Message:  "Repository access blocked",
+	}
+	_ = b.WithRetryN(t.Context(), newSilentLogger(), 2, func() error { return blocked })
+	if b.Unavailable() == nil {
+		t.Fatal("Unavailable() = nil after a 451")
+	}
+
+	ran := false
+	err := b.WithRetryN(t.Context(), newSilentLogger(), 2, func() error { ran = true; return nil })
+	var uerr *request.UnavailableError
+	if ran || !errors.As(err, &uerr) {
+		t.Fatalf("later operation ran=%v err=%v, want it skipped with the UnavailableError", ran, err)
+	}
+	if b.Open() {
+		t.Fatal("an unavailable target must not open the breaker")
+	}
+	if (*request.Breaker)(nil).Unavailable() != nil {
+		t.Fatal("nil breaker reports unavailable")
+	}
+}
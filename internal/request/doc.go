@@ -8,6 +8,14 @@
 //     [github.com/cenkalti/backoff/v5] with a 1s initial interval and
 //     a 10s cap. The retry budget is passed explicitly by the caller
 //     so this package depends on no global configuration state.
+//   - [Breaker] wraps WithRetryN with a consecutive-failure circuit
+//     breaker shared by every operation against one target; once it
+//     opens, operations fail fast with [ErrCircuitOpen]. Once an
+//     operation reports the target withheld, later ones return the
+//     same [UnavailableError] without running.
+//   - [UnavailableError] reports a 451 (legal takedown) or 403
+//     "Repository access blocked" response. WithRetryN returns it on
+//     the first attempt, since no retry changes it.
 //
 // Retry layering:
 //
@@ -33,6 +41,11 @@
 //     backoff loop unwinds immediately.
 //   - DeadlineExceeded is treated as permanent: a stuck operation
 //     does not consume the entire retry budget.
+//   - Only errors another attempt could fix are retried. A
+//     [github.com/google/go-github/v86/github.ErrorResponse] with status
+//     400, 401, 404, 410, or 422, and a [context.Canceled] returned by
+//     the operation, fail on the first attempt; 5xx responses, network
+//     timeouts, connection resets, and unclassified errors are retried.
 //   - Rate-limit detection is keyed off concrete error types (and the
 //     Retry-After header on [github.com/google/go-github/v86/github.ErrorResponse]),
 //     never substring matches on the error string.
@@ -6,10 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -455,3 +458,99 @@ func TestWithRetryN_ErrorResponseRetryAfter(t *testing.T) {
 		})
 	}
 }
+
+// TestWithRetryN_UnavailableNotRetried asserts a repository withheld by
+// a legal takedown or a GitHub block fails on the first attempt as an
+// UnavailableError naming the reason.
+func TestWithRetryN_UnavailableNotRetried(t *testing.T) {
+	t.Parallel()
+
+	dmca := newErrorResponse(http.StatusUnavailableForLegalReasons, nil, "Repository access blocked")
+	dmca.Block = &github.ErrorBlock{Reason: "dmca"}
+	cases := []struct {
+		name       string
+		err        error
+		wantReason string
+	}{
+		{name: "451 with block reason", err: dmca, wantReason: "unavailable for legal reasons (dmca)"},
+		{name: "451 without block", err: newErrorResponse(http.StatusUnavailableForLegalReasons, nil, "Unavailable"), wantReason: "unavailable for legal reasons"},
+		{name: "403 access blocked", err: newErrorResponse(http.StatusForbidden, nil, "Repository access blocked"), wantReason: "access blocked by GitHub"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var calls atomic.Int32
+			err := request.WithRetryN(t.Context(), newSilentLogger(), 3, func() error {
+				calls.Add(1)
+				return fmt.Errorf("listing workflows: %w", tc.err)
+			})
+			var uerr *request.UnavailableError
+			if !errors.As(err, &uerr) || uerr.Reason != tc.wantReason {
+				t.Fatalf("WithRetryN() error = %v, want UnavailableError %q", err, tc.wantReason)
+			}
+			if got := calls.Load(); got != 1 {
+				t.Fatalf("operation ran %d times, want 1", got)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error reporting a timeout, as a dial or read
+// deadline does.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+// TestWithRetryN_ErrorClasses asserts WithRetryN retries only errors
+// another attempt could fix: a GitHub response for a missing resource,
+// bad credentials, or an invalid request, and a cancellation returned
+// by the operation, fail on the first attempt with the error intact,
+// while server errors and network failures use the retry budget.
+func TestWithRetryN_ErrorClasses(t *testing.T) {
+	t.Parallel()
+
+	get := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.github.com/repos/o/r", Err: err}
+	}
+	cases := []struct {
+		name      string
+		err       error
+		wantCalls int32
+	}{
+		{name: "400 bad request", err: newErrorResponse(http.StatusBadRequest, nil, "Problems parsing JSON"), wantCalls: 1},
+		{name: "401 bad credentials", err: newErrorResponse(http.StatusUnauthorized, nil, "Bad credentials"), wantCalls: 1},
+		{name: "404 not found", err: fmt.Errorf("listing runs: %w", newErrorResponse(http.StatusNotFound, nil, "Not Found")), wantCalls: 1},
+		{name: "410 gone", err: newErrorResponse(http.StatusGone, nil, "Gone"), wantCalls: 1},
+		{name: "422 invalid request", err: newErrorResponse(http.StatusUnprocessableEntity, nil, "Validation Failed"), wantCalls: 1},
+		{name: "operation cancelled", err: fmt.Errorf("downloading logs: %w", context.Canceled), wantCalls: 1},
+		{name: "500 server error", err: newErrorResponse(http.StatusInternalServerError, nil, "Server Error"), wantCalls: 2},
+		{name: "502 bad gateway", err: newErrorResponse(http.StatusBadGateway, nil, "Bad Gateway"), wantCalls: 2},
+		{name: "network timeout", err: get(timeoutError{}), wantCalls: 2},
+		{name: "connection reset", err: get(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), wantCalls: 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			logger, snapshot := captureLogger(t)
+			var calls atomic.Int32
+			err := request.WithRetryN(t.Context(), logger, 1, func() error {
+				calls.Add(1)
+				return tc.err
+			})
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("WithRetryN() error = %v, want it to wrap %v", err, tc.err)
+			}
+			if got := calls.Load(); got != tc.wantCalls {
+				t.Fatalf("operation ran %d times, want %d", got, tc.wantCalls)
+			}
+			if retried := strings.Contains(snapshot(), "Operation failed"); retried != (tc.wantCalls > 1) {
+				t.Fatalf("retry warning logged=%v, want %v\nlogs:\n%s", retried, tc.wantCalls > 1, snapshot())
+			}
+		})
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff/v5"
@@ -27,9 +29,73 @@ func Permanent(err error) error {
 	return backoff.Permanent(err)
 }
 
+// UnavailableError reports a GitHub response whose status no retry
+// can change because the resource itself is withheld: 451 for a
+// repository disabled by a DMCA or other legal takedown, and 403
+// "Repository access blocked" for one GitHub blocked for a terms of
+// service violation. [WithRetryN] returns it on the first attempt.
+type UnavailableError struct {
+	StatusCode int
+	// Reason describes the block, including GitHub's own reason (such
+	// as "dmca") when the response carries one.
+	Reason string
+	Err    error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d): %v", e.Reason, e.StatusCode, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error { return e.Err }
+
+// unavailable returns err as an *UnavailableError when it is a GitHub
+// response for a withheld resource, and nil otherwise.
+func unavailable(err error) *UnavailableError {
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp == nil || errResp.Response == nil {
+		return nil
+	}
+	switch status := errResp.Response.StatusCode; {
+	case status == http.StatusUnavailableForLegalReasons:
+		reason := "unavailable for legal reasons"
+		if errResp.Block != nil && errResp.Block.Reason != "" {
+			reason += " (" + errResp.Block.Reason + ")"
+		}
+		return &UnavailableError{StatusCode: status, Reason: reason, Err: err}
+	case status == http.StatusForbidden && strings.Contains(strings.ToLower(errResp.Message), "access blocked"):
+		return &UnavailableError{StatusCode: status, Reason: "access blocked by GitHub", Err: err}
+	}
+	return nil
+}
+
+// retryable reports whether another attempt could succeed where err
+// failed. A GitHub response for a missing resource, bad credentials,
+// or an invalid request (400, 401, 404, 410, 422) is permanent, as is
+// a cancellation surfaced by the operation. Everything else is
+// retried: 5xx and rate-limit responses, network timeouts, connection
+// resets, and errors that carry no status at all.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp == nil || errResp.Response == nil {
+		return true
+	}
+	switch errResp.Response.StatusCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound,
+		http.StatusGone, http.StatusUnprocessableEntity:
+		return false
+	}
+	return true
+}
+
 // WithRetryN runs operation under exponential-backoff retry with an
 // explicit maxRetries budget. Setting maxRetries=0 means a single
-// attempt with no retries.
+// attempt with no retries. A response for a withheld resource is
+// returned at once as an [UnavailableError], and other errors no retry
+// can fix, such as a 404 or 401 response, are returned at once as
+// they are.
 //
 // Rate-limit / abuse-rate-limit errors from go-github are honored via
 // [backoff.RetryAfter] so the retry schedule respects the server's
@@ -50,6 +116,13 @@ func WithRetryN(ctx context.Context, logger *clog.Logger, maxRetries int, operat
 			return nil, nil
 		}
 
+		// A withheld resource is terminal whether or not the caller
+		// marked it so; it is checked first so it is always reported
+		// as an UnavailableError.
+		if uerr := unavailable(err); uerr != nil {
+			return nil, backoff.Permanent(uerr)
+		}
+
 		// A pre-wrapped PermanentError signals a terminal condition the
 		// caller already decided is non-retryable. Pass it through to the
 		// backoff library (which unwraps and returns the inner error)
@@ -59,6 +132,12 @@ func WithRetryN(ctx context.Context, logger *clog.Logger, maxRetries int, operat
 			return nil, err
 		}
 
+		// GitHub responses no retry can change, and cancellation, fail
+		// now rather than spending the budget.
+		if !retryable(err) {
+			return nil, backoff.Permanent(err)
+		}
+
 		if attempt > maxRetries {
 			return nil, backoff.Permanent(fmt.Errorf("max retries exceeded: %w", err))
 		}
@@ -7,11 +7,27 @@
 //
 //   - [Request] carries the GitHub clients, IOC matcher, time window,
 //     and per-run cache state. It is constructed once in main and
-//     shallow-cloned per repository inside the scanner.
+//     shallow-cloned per repository inside the scanner. Its
+//     [Coverage] counts the runs whose logs had expired and lists the
+//     repositories skipped as withheld ([SkippedRepo]).
 //   - [Result] is the canonical finding shape. [Result.IsEmpty]
 //     identifies records with no extracted log content so they can be
-//     skipped during CSV emission.
-//   - [Cache] is the on-disk JSON envelope wrapping a slice of Result.
+//     skipped during CSV emission. [Fingerprint] hashes the fields
+//     that identify a finding into an ID that is stable across runs;
+//     [AddFingerprints] and [Request.Stamp] record it as
+//     Result.Fingerprint.
+//   - [Cache] is the on-disk JSON envelope wrapping a slice of Result,
+//     labeled with the scan invocation that wrote it. [NewScanID]
+//     mints the per-invocation ID and [Request.Stamp] records it on
+//     each Result.
+//   - [ResultSink] and [LogStore] are the optional persistence hooks a
+//     Request carries: findings as each repository finishes, and the
+//     extracted log text of each scanned run.
+//   - [Summarize] rolls results up into a [Summary] of per-repository
+//     counts keyed by the IOC name carried on each Result;
+//     [Summary.Report] adds its totals.
+//   - [FilterConfidence] drops results whose Confidence score falls
+//     below a threshold.
 //
 // The package also exposes [ResultsDir] -- the directory under which
 // cache, JSON, and CSV outputs are written.
@@ -1,15 +1,41 @@
 package ghscan
 
 import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	httpclient "github.com/chainguard-dev/ghscan/pkg/httpclient"
 	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
 	"github.com/google/go-github/v86/github"
 )
 
 const ResultsDir string = "results"
 
+// ResultSink receives findings incrementally as the scanner finishes
+// each repository. Implementations must be safe for concurrent use;
+// see internal/file.Appender for the on-disk implementation.
+type ResultSink interface {
+	Append(ctx context.Context, results []Result) error
+}
+
+// LogStore retains the extracted text of scanned workflow run logs so
+// the evidence behind a finding survives without re-downloading it.
+// hasFindings reports whether the run produced any result; the store
+// decides whether to keep runs without findings. Implementations must
+// be safe for concurrent use; see internal/file.LogKeeper.
+type LogStore interface {
+	StoreLog(ctx context.Context, repository string, runID int64, logText string, hasFindings bool) error
+}
+
 // Request carries the per-scan state shared across internal/action and
 // pkg/workflow call sites. The embedded GitHub and raw HTTP clients
 // are unexported so external callers must go through the accessors
@@ -25,15 +51,97 @@ type Request struct {
 	IOC           *ioc.IOC
 	Owner         string
 	RepoName      string
-	StartTime     time.Time
-	Timeout       time.Duration
-	Token         string
-	Workflows     []string
+	// RunID, when non-zero, restricts log scanning to the run with
+	// this ID, for a target naming a single run.
+	RunID int64
+	// Sink, when non-nil, receives each repository's findings as soon
+	// as the repository completes so long scans persist progress
+	// without rewriting the full cache.
+	Sink ResultSink
+	// Logs, when non-nil, is offered the extracted text of every run
+	// whose logs were scanned.
+	Logs LogStore
+	// LogOptions configures how run logs are fetched and scanned; nil
+	// selects the defaults.
+	LogOptions *workflow.Options
+	StartTime  time.Time
+	Timeout    time.Duration
+	Token      string
+	Workflows  []string
+	// Coverage counts the runs the scan could not inspect. NewRequest
+	// allocates it; copies of the Request share it.
+	Coverage *Coverage
+	// ScanID identifies the scan invocation; [Request.Stamp] records
+	// it on every Result. ScanStartedAt is when that invocation began.
+	ScanID        string
+	ScanStartedAt time.Time
 
 	client     *github.Client
 	httpClient *httpclient.Client
 }
 
+// Coverage records the limits of a scan's retrospective coverage. Its
+// methods are safe for concurrent use, and a nil *Coverage ignores
+// updates and reports zero.
+type Coverage struct {
+	expiredRuns atomic.Int64
+
+	mu           sync.Mutex
+	skippedRepos []SkippedRepo
+}
+
+// SkippedRepo is a repository a scan skipped entirely, such as one
+// GitHub withholds after a DMCA takedown, and why.
+type SkippedRepo struct {
+	Repository string
+	Reason     string
+}
+
+// AddExpiredRuns counts n runs skipped because their logs had aged
+// past GitHub's retention window.
+func (c *Coverage) AddExpiredRuns(n int64) {
+	if c == nil {
+		return
+	}
+	c.expiredRuns.Add(n)
+}
+
+// ExpiredRuns returns the number of runs skipped because their logs
+// had expired.
+func (c *Coverage) ExpiredRuns() int64 {
+	if c == nil {
+		return 0
+	}
+	return c.expiredRuns.Load()
+}
+
+// AddSkippedRepo records that repository was not scanned, and why. A
+// repository already recorded keeps its first reason.
+func (c *Coverage) AddSkippedRepo(repository, reason string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.ContainsFunc(c.skippedRepos, func(s SkippedRepo) bool { return s.Repository == repository }) {
+		return
+	}
+	c.skippedRepos = append(c.skippedRepos, SkippedRepo{Repository: repository, Reason: reason})
+}
+
+// SkippedRepos returns the repositories recorded by
+// [Coverage.AddSkippedRepo], sorted by name.
+func (c *Coverage) SkippedRepos() []SkippedRepo {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := slices.Clone(c.skippedRepos)
+	slices.SortFunc(out, func(a, b SkippedRepo) int { return cmp.Compare(a.Repository, b.Repository) })
+	return out
+}
+
 // RequestConfig is the constructor input for [NewRequest]. Every field
 // mirrors its counterpart on Request; scalar fields with a zero value
 // remain zero on the resulting Request.
@@ -48,10 +156,16 @@ type RequestConfig struct {
 	IOC           *ioc.IOC
 	Owner         string
 	RepoName      string
+	RunID         int64
+	Sink          ResultSink
+	Logs          LogStore
+	LogOptions    *workflow.Options
 	StartTime     time.Time
 	Timeout       time.Duration
 	Token         string
 	Workflows     []string
+	ScanID        string
+	ScanStartedAt time.Time
 }
 
 // NewRequest returns a Request populated from cfg. The returned value
@@ -68,15 +182,49 @@ func NewRequest(cfg RequestConfig) *Request {
 		IOC:           cfg.IOC,
 		Owner:         cfg.Owner,
 		RepoName:      cfg.RepoName,
+		RunID:         cfg.RunID,
+		Sink:          cfg.Sink,
+		Logs:          cfg.Logs,
+		LogOptions:    cfg.LogOptions,
 		StartTime:     cfg.StartTime,
 		Timeout:       cfg.Timeout,
 		Token:         cfg.Token,
 		Workflows:     cfg.Workflows,
+		Coverage:      &Coverage{},
+		ScanID:        cfg.ScanID,
+		ScanStartedAt: cfg.ScanStartedAt,
 		client:        cfg.Client,
 		httpClient:    cfg.HTTPClient,
 	}
 }
 
+// Stamp records r's ScanID on each of results that carries none, in
+// place, so findings from successive invocations writing to the same
+// outputs can be told apart. Results loaded from an earlier scan keep
+// their own ID. Every result is also given its [Fingerprint], with or
+// without a ScanID.
+func (r *Request) Stamp(results []Result) {
+	AddFingerprints(results)
+	if r == nil || r.ScanID == "" {
+		return
+	}
+	for i := range results {
+		if results[i].ScanID == "" {
+			results[i].ScanID = r.ScanID
+		}
+	}
+}
+
+// NewScanID returns a random RFC 4122 version 4 UUID identifying one
+// scan invocation.
+func NewScanID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
 // Client returns the GitHub SDK client wired into this Request. It is
 // nil-safe so a zero-value Request is observable without panicking.
 func (r *Request) Client() *github.Client {
@@ -99,10 +247,12 @@ func (r *Request) HTTPClient() *httpclient.Client {
 type Result struct {
 	Base64Data        string   `json:"base64_data,omitempty"`
 	DecodedData       string   `json:"decoded_data,omitempty"`
+	DecodeDepth       int      `json:"decode_depth,omitempty"`
 	LineData          string   `json:"line_data,omitempty"`
 	Repository        string   `json:"repository,omitempty"`
 	WorkflowFileName  string   `json:"workflow_file_name,omitempty"`
 	WorkflowRunURL    string   `json:"workflow_run_url,omitempty"`
+	RunURLs           []string `json:"run_urls,omitempty"`
 	WorkflowURL       string   `json:"workflow_url,omitempty"`
 	WorkflowFileSHA   string   `json:"workflow_file_sha,omitempty"`
 	OffendingUsesLine string   `json:"offending_uses_line,omitempty"`
@@ -111,12 +261,172 @@ type Result struct {
 	StepName          string   `json:"step_name,omitempty"`
 	ReachableSecrets  []string `json:"reachable_secrets,omitempty"`
 	Source            string   `json:"source,omitempty"`
+	KeyTypes          string   `json:"key_types,omitempty"`
+	Severity          string   `json:"severity,omitempty"`
+	IOCName           string   `json:"ioc_name,omitempty"`
+	CommitSHA         string   `json:"commit_sha,omitempty"`
+	CommitAuthor      string   `json:"commit_author,omitempty"`
+	RunStatus         string   `json:"run_status,omitempty"`
+	RunConclusion     string   `json:"run_conclusion,omitempty"`
+	RunAttempt        int      `json:"run_attempt,omitempty"`
+	FromFork          bool     `json:"from_fork,omitempty"`
+	Destination       string   `json:"destination,omitempty"`
+	Note              string   `json:"note,omitempty"`
+	MatchedPattern    string   `json:"matched_pattern,omitempty"`
+	MatchOffset       int      `json:"match_offset,omitempty"`
+	Context           string   `json:"context,omitempty"`
+	Confidence        int      `json:"confidence,omitempty"`
+	Action            string   `json:"action,omitempty"`
+	ActionFile        string   `json:"action_file,omitempty"`
+	ScanID            string   `json:"scan_id,omitempty"`
+	Fingerprint       string   `json:"fingerprint,omitempty"`
+	Explanation       string   `json:"explanation,omitempty"`
 }
 
 func (r *Result) IsEmpty() bool {
 	return r.Base64Data == "" && r.DecodedData == "" && r.LineData == "" && r.OffendingUsesLine == ""
 }
 
+// Cache is the JSON envelope of a scan's results. ScanID and
+// ScanStartedAt describe the invocation that last wrote it; each
+// Result carries the ScanID of the invocation that found it.
 type Cache struct {
-	Results []Result `json:"results,omitempty"`
+	ScanID        string    `json:"scan_id,omitempty"`
+	ScanStartedAt time.Time `json:"scan_started_at,omitzero"`
+	Results       []Result  `json:"results,omitempty"`
+}
+
+// UnknownIOC labels results with no IOCName in a [Summary], such as
+// those loaded from a cache written before the field existed.
+const UnknownIOC = "unknown"
+
+// Summary maps each repository to the number of results per IOC name
+// that fired in it.
+type Summary map[string]map[string]int
+
+// SummaryTotals totals a [Summary] across its repositories.
+type SummaryTotals struct {
+	Repositories int            `json:"repositories"`
+	Findings     int            `json:"findings"`
+	ByIOC        map[string]int `json:"by_ioc"`
+}
+
+// SummaryReport is a [Summary] with its totals, the only output of a
+// summary-only scan.
+type SummaryReport struct {
+	Repositories Summary       `json:"repositories"`
+	Totals       SummaryTotals `json:"totals"`
+}
+
+// Totals sums s: the repositories with findings, their findings, and
+// the findings per IOC name.
+func (s Summary) Totals() SummaryTotals {
+	t := SummaryTotals{Repositories: len(s), ByIOC: make(map[string]int)}
+	for _, counts := range s {
+		for name, n := range counts {
+			t.Findings += n
+			t.ByIOC[name] += n
+		}
+	}
+	return t
+}
+
+// Report pairs s with its [Summary.Totals].
+func (s Summary) Report() SummaryReport {
+	return SummaryReport{Repositories: s, Totals: s.Totals()}
+}
+
+// Summarize rolls results up into a [Summary]. Empty results and
+// results without a repository are skipped, matching CSV emission.
+func Summarize(results []Result) Summary {
+	s := make(Summary)
+	for _, r := range results {
+		if r.Repository == "" || r.IsEmpty() {
+			continue
+		}
+		name := r.IOCName
+		if name == "" {
+			name = UnknownIOC
+		}
+		if s[r.Repository] == nil {
+			s[r.Repository] = make(map[string]int)
+		}
+		s[r.Repository][name]++
+	}
+	return s
+}
+
+// BaselineKey identifies a finding across scans for [NewSince]: the
+// repository, workflow file, and decoded data. Findings that carry no
+// decoded data (plain IOC hits, YAML and history findings) fall back to
+// the matched line, then to the offending uses: line, so distinct hits
+// in one workflow are not collapsed into a single key.
+func BaselineKey(r Result) string {
+	data := cmp.Or(r.DecodedData, r.LineData, r.OffendingUsesLine)
+	return r.Repository + "|" + r.WorkflowFileName + "|" + data
+}
+
+// Fingerprint returns a stable ID for r: a hex SHA-256 prefix over the
+// repository, workflow file, IOC name, and the data [BaselineKey]
+// compares. Unlike ScanID it does not change between runs, so the same
+// finding always gets the same fingerprint and suppression lists or
+// ticket links can be keyed on it.
+func Fingerprint(r Result) string {
+	data := cmp.Or(r.DecodedData, r.LineData, r.OffendingUsesLine)
+	h := sha256.New()
+	for _, field := range []string{r.Repository, r.WorkflowFileName, r.IOCName, data} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// AddFingerprints sets the [Fingerprint] of each of results that
+// carries none, in place, so results loaded from a cache written before
+// the field existed are fingerprinted too. Empty results are skipped.
+func AddFingerprints(results []Result) {
+	for i := range results {
+		if results[i].Fingerprint == "" && !results[i].IsEmpty() {
+			results[i].Fingerprint = Fingerprint(results[i])
+		}
+	}
+}
+
+// NewSince returns the results whose [BaselineKey] does not appear in
+// baseline, in their original order. Empty results are skipped, as in
+// [Summarize], so they never count as new.
+func NewSince(results, baseline []Result) []Result {
+	known := make(map[string]struct{}, len(baseline))
+	for _, r := range baseline {
+		known[BaselineKey(r)] = struct{}{}
+	}
+	var out []Result
+	for _, r := range results {
+		if r.IsEmpty() {
+			continue
+		}
+		if _, ok := known[BaselineKey(r)]; ok {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// FilterConfidence returns the results whose Confidence is at least
+// threshold, in their original order. Results without a score, such as
+// those loaded from a cache written before scores existed, are kept
+// because they cannot be judged. A threshold of zero or less returns
+// results unchanged.
+func FilterConfidence(results []Result, threshold int) []Result {
+	if threshold <= 0 {
+		return results
+	}
+	var out []Result
+	for _, r := range results {
+		if r.Confidence == 0 || r.Confidence >= threshold {
+			out = append(out, r)
+		}
+	}
+	return out
 }
@@ -3,6 +3,7 @@ package ghscan_test
 import (
 	"encoding/json"
 	"reflect"
+	"regexp"
 	"testing"
 
 	ghscan "github.com/chainguard-dev/ghscan/pkg/ghscan"
@@ -140,4 +141,243 @@ func TestRequest_NilSafeAccessors(t *testing.T) {
 	if got := req.HTTPClient(); got != nil {
 		t.Fatalf("HTTPClient() = %v, want nil", got)
 	}
+	req.Coverage.AddExpiredRuns(1)
+	if got := req.Coverage.ExpiredRuns(); got != 0 {
+		t.Fatalf("nil Coverage ExpiredRuns() = %d, want 0", got)
+	}
+	req.Coverage.AddSkippedRepo("o/r", "gone")
+	if got := req.Coverage.SkippedRepos(); got != nil {
+		t.Fatalf("nil Coverage SkippedRepos() = %v, want nil", got)
+	}
+}
+
+// TestCoverage_SkippedRepos asserts skipped repositories are reported
+// once each, with their first reason, sorted by name.
+func TestCoverage_SkippedRepos(t *testing.T) {
+	t.Parallel()
+
+	req := ghscan.NewRequest(ghscan.RequestConfig{})
+	req.Coverage.AddSkippedRepo("o/zeta", "unavailable for legal reasons (dmca)")
+	req.Coverage.AddSkippedRepo("o/alpha", "access blocked by GitHub")
+	req.Coverage.AddSkippedRepo("o/zeta", "recorded twice")
+	want := []ghscan.SkippedRepo{
+		{Repository: "o/alpha", Reason: "access blocked by GitHub"},
+		{Repository: "o/zeta", Reason: "unavailable for legal reasons (dmca)"},
+	}
+	if got := req.Coverage.SkippedRepos(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SkippedRepos() = %+v, want %+v", got, want)
+	}
+}
+
+// TestSummarize asserts the per-repo rollup counts results by IOC name,
+// buckets unnamed results under UnknownIOC, and skips records that
+// carry no finding.
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	got := ghscan.Summarize([]ghscan.Result{
+		{Repository: "o/a", IOCName: "x/y", LineData: "hit"},
+		{Repository: "o/a", IOCName: "x/y", OffendingUsesLine: "uses: x/y@bad"},
+		{Repository: "o/a", IOCName: "z/w", LineData: "hit"},
+		{Repository: "o/b", LineData: "legacy hit"},
+		{Repository: "o/c", IOCName: "x/y"},
+		{IOCName: "x/y", LineData: "no repo"},
+	})
+	want := ghscan.Summary{
+		"o/a": {"x/y": 2, "z/w": 1},
+		"o/b": {ghscan.UnknownIOC: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Summarize = %v, want %v", got, want)
+	}
+}
+
+// TestSummaryReport asserts the totals count repositories with
+// findings, all findings, and findings per IOC name.
+func TestSummaryReport(t *testing.T) {
+	t.Parallel()
+
+	s := ghscan.Summary{
+		"o/a": {"x/y": 2, "z/w": 1},
+		"o/b": {"x/y": 4},
+	}
+	got := s.Report()
+	want := ghscan.SummaryReport{
+		Repositories: s,
+		Totals: ghscan.SummaryTotals{
+			Repositories: 2,
+			Findings:     7,
+			ByIOC:        map[string]int{"x/y": 6, "z/w": 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Report = %+v, want %+v", got, want)
+	}
+	if empty := (ghscan.Summary{}).Totals(); empty.Findings != 0 || empty.ByIOC == nil {
+		t.Fatalf("empty Totals = %+v, want zero findings and a non-nil by_ioc", empty)
+	}
+}
+
+// TestNewSince asserts findings are matched to the baseline on
+// repository, workflow, and decoded data, falling back to the matched
+// line when nothing was decoded.
+func TestNewSince(t *testing.T) {
+	t.Parallel()
+
+	baseline := []ghscan.Result{
+		{Repository: "o/a", WorkflowFileName: "ci.yml", DecodedData: "secret", LineData: "run 1 line"},
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "ioc hit"},
+	}
+	results := []ghscan.Result{
+		// Same decoded data on a different run: known.
+		{Repository: "o/a", WorkflowFileName: "ci.yml", DecodedData: "secret", LineData: "run 2 line"},
+		{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "ioc hit"},
+		{Repository: "o/a", WorkflowFileName: "ci.yml", DecodedData: "other"},
+		{Repository: "o/a", WorkflowFileName: "release.yml", DecodedData: "secret"},
+		{Repository: "o/b", WorkflowFileName: "ci.yml", OffendingUsesLine: "uses: x/y@bad"},
+		{Repository: "o/c", WorkflowFileName: "ci.yml"},
+	}
+
+	got := ghscan.NewSince(results, baseline)
+	want := []ghscan.Result{results[2], results[3], results[4]}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("NewSince = %+v, want %+v", got, want)
+	}
+	if got := ghscan.NewSince(results[:2], baseline); len(got) != 0 {
+		t.Fatalf("NewSince of known findings = %+v, want none", got)
+	}
+}
+
+// TestFilterConfidence asserts results below the threshold are
+// dropped, unscored results are kept, and a zero threshold is a no-op.
+func TestFilterConfidence(t *testing.T) {
+	t.Parallel()
+
+	results := []ghscan.Result{
+		{Repository: "o/a", LineData: "digest", Confidence: 95},
+		{Repository: "o/a", Base64Data: "blob", Confidence: 25},
+		{Repository: "o/a", LineData: "from an old cache"},
+		{Repository: "o/b", LineData: "curl", Confidence: 40},
+	}
+	got := ghscan.FilterConfidence(results, 40)
+	want := []ghscan.Result{results[0], results[2], results[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FilterConfidence = %+v, want %+v", got, want)
+	}
+	if got := ghscan.FilterConfidence(results, 0); !reflect.DeepEqual(got, results) {
+		t.Fatalf("FilterConfidence(0) = %+v, want every result", got)
+	}
+}
+
+// TestNewScanID asserts scan IDs are distinct version 4 UUIDs.
+func TestNewScanID(t *testing.T) {
+	t.Parallel()
+
+	uuidV4 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := ghscan.NewScanID(), ghscan.NewScanID()
+	if !uuidV4.MatchString(a) {
+		t.Fatalf("NewScanID()=%q, want a version 4 UUID", a)
+	}
+	if a == b {
+		t.Fatalf("NewScanID() returned %q twice", a)
+	}
+}
+
+// TestRequest_Stamp asserts Stamp labels results with the request's
+// scan ID but leaves results from an earlier scan labeled as found.
+func TestRequest_Stamp(t *testing.T) {
+	t.Parallel()
+
+	results := []ghscan.Result{{LineData: "new"}, {LineData: "old", ScanID: "earlier"}}
+	ghscan.NewRequest(ghscan.RequestConfig{ScanID: "now"}).Stamp(results)
+	if results[0].ScanID != "now" || results[1].ScanID != "earlier" {
+		t.Fatalf("stamped ScanIDs=%q,%q, want now,earlier", results[0].ScanID, results[1].ScanID)
+	}
+	if results[0].Fingerprint != ghscan.Fingerprint(results[0]) {
+		t.Fatalf("stamped Fingerprint=%q, want %q", results[0].Fingerprint, ghscan.Fingerprint(results[0]))
+	}
+
+	var nilReq *ghscan.Request
+	nilReq.Stamp(results)
+
+	unscanned := []ghscan.Result{{LineData: "no scan ID"}}
+	nilReq.Stamp(unscanned)
+	if unscanned[0].Fingerprint == "" {
+		t.Fatal("Stamp without a scan ID left the result unfingerprinted")
+	}
+}
+
+// TestFingerprint asserts a finding keeps its fingerprint across runs
+// and scans while any of its identifying fields changes it.
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	base := ghscan.Result{
+		Repository:       "o/a",
+		WorkflowFileName: "ci.yml",
+		IOCName:          "tj-actions/changed-files",
+		DecodedData:      "secret",
+		LineData:         "run 1 line",
+		WorkflowRunURL:   "https://github.com/o/a/actions/runs/1",
+		ScanID:           "first",
+	}
+	fp := ghscan.Fingerprint(base)
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(fp) {
+		t.Fatalf("Fingerprint()=%q, want 32 hex digits", fp)
+	}
+
+	rerun := base
+	rerun.LineData = "run 2 line"
+	rerun.WorkflowRunURL = "https://github.com/o/a/actions/runs/2"
+	rerun.ScanID = "second"
+	rerun.Confidence = 70
+	if got := ghscan.Fingerprint(rerun); got != fp {
+		t.Errorf("Fingerprint of the same finding from another run=%q, want %q", got, fp)
+	}
+
+	for name, mutate := range map[string]func(*ghscan.Result){
+		"repository":    func(r *ghscan.Result) { r.Repository = "o/b" },
+		"workflow file": func(r *ghscan.Result) { r.WorkflowFileName = "release.yml" },
+		"ioc name":      func(r *ghscan.Result) { r.IOCName = "other" },
+		"decoded data":  func(r *ghscan.Result) { r.DecodedData = "other" },
+		// Fields are separated, so moving text between them is not
+		// the same finding.
+		"field boundary": func(r *ghscan.Result) { r.Repository, r.WorkflowFileName = "o/aci.yml", "" },
+	} {
+		r := base
+		mutate(&r)
+		if got := ghscan.Fingerprint(r); got == fp {
+			t.Errorf("changing the %s kept fingerprint %q", name, got)
+		}
+	}
+
+	// Without decoded data the matched line identifies the finding.
+	plain := ghscan.Result{Repository: "o/a", WorkflowFileName: "ci.yml", LineData: "ioc hit"}
+	other := plain
+	other.LineData = "another ioc hit"
+	if ghscan.Fingerprint(plain) == ghscan.Fingerprint(other) {
+		t.Error("distinct lines without decoded data share a fingerprint")
+	}
+}
+
+// TestAddFingerprints asserts missing fingerprints are filled, existing
+// ones are kept, and empty results are left alone.
+func TestAddFingerprints(t *testing.T) {
+	t.Parallel()
+
+	results := []ghscan.Result{
+		{Repository: "o/a", LineData: "hit"},
+		{Repository: "o/a", LineData: "hit", Fingerprint: "kept"},
+		{Repository: "o/a"},
+	}
+	ghscan.AddFingerprints(results)
+	if want := ghscan.Fingerprint(results[0]); results[0].Fingerprint != want {
+		t.Errorf("Fingerprint=%q, want %q", results[0].Fingerprint, want)
+	}
+	if results[1].Fingerprint != "kept" {
+		t.Errorf("existing Fingerprint=%q, want kept", results[1].Fingerprint)
+	}
+	if results[2].Fingerprint != "" {
+		t.Errorf("empty result Fingerprint=%q, want none", results[2].Fingerprint)
+	}
 }
@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Budget is one observation of GitHub's primary (core) rate limit.
+type Budget struct {
+	Remaining int
+	Limit     int
+	Reset     time.Time
+}
+
+// RateBudget records the latest core rate-limit budget GitHub reports
+// in response headers, so callers can pace themselves against it. One
+// RateBudget is typically shared by a [Client] (via [WithRateBudget])
+// and the go-github client (via [BudgetTransport]), because both draw
+// on the same token's quota. The zero value is ready to use and all
+// methods are safe for concurrent use.
+type RateBudget struct {
+	mu     sync.Mutex
+	budget Budget
+	seen   bool
+}
+
+// Observe records the budget carried by h. Responses without the
+// X-RateLimit headers, and those for another resource such as search,
+// are ignored. A nil receiver is a no-op.
+func (b *RateBudget) Observe(h http.Header) {
+	if b == nil || h == nil {
+		return
+	}
+	if res := h.Get("X-RateLimit-Resource"); res != "" && res != "core" {
+		return
+	}
+	remaining, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, err := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	if err != nil || limit <= 0 {
+		return
+	}
+	resetUnix, err := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.budget = Budget{Remaining: remaining, Limit: limit, Reset: time.Unix(resetUnix, 0)}
+	b.seen = true
+}
+
+// Current returns the latest observed budget and whether any response
+// has reported one yet. A nil receiver reports none.
+func (b *RateBudget) Current() (Budget, bool) {
+	if b == nil {
+		return Budget{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.budget, b.seen
+}
+
+// WithRateBudget records the rate-limit headers of every response the
+// client receives into b. See [Client.RateBudget].
+func WithRateBudget(b *RateBudget) Option {
+	return func(c *Client) {
+		c.budget = b
+	}
+}
+
+// RateBudget returns the budget installed with [WithRateBudget], or
+// nil.
+func (c *Client) RateBudget() *RateBudget {
+	if c == nil {
+		return nil
+	}
+	return c.budget
+}
+
+// BudgetTransport records the rate-limit headers of every response it
+// carries into Budget. It exists for the go-github client, whose
+// responses never pass through [Client].
+type BudgetTransport struct {
+	// Budget receives the observations; nil disables recording.
+	Budget *RateBudget
+	// Base performs the request; nil means [http.DefaultTransport].
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *BudgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err == nil && resp != nil {
+		t.Budget.Observe(resp.Header)
+	}
+	return resp, err
+}
@@ -0,0 +1,59 @@
+package httpclient_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+)
+
+// TestRateBudget_ObservesCoreHeaders asserts both the Client and the
+// BudgetTransport record the core budget and skip other resources.
+func TestRateBudget_ObservesCoreHeaders(t *testing.T) {
+	t.Parallel()
+
+	reset := time.Now().Add(30 * time.Minute).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		if r.URL.Path == "/search" {
+			w.Header().Set("X-RateLimit-Resource", "search")
+			w.Header().Set("X-RateLimit-Remaining", "3")
+			return
+		}
+		w.Header().Set("X-RateLimit-Resource", "core")
+		w.Header().Set("X-RateLimit-Remaining", "1234")
+	}))
+	t.Cleanup(srv.Close)
+
+	budget := new(httpclient.RateBudget)
+	if _, ok := budget.Current(); ok {
+		t.Fatal("a fresh budget reports an observation")
+	}
+
+	hc := newTestClient(t, srv, httpclient.WithRateBudget(budget))
+	if hc.RateBudget() != budget {
+		t.Fatal("RateBudget() does not return the installed budget")
+	}
+	if _, _, err := hc.Get(t.Context(), srv.URL+"/core"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, ok := budget.Current()
+	if !ok || got.Remaining != 1234 || got.Limit != 5000 || got.Reset.Unix() != reset {
+		t.Fatalf("Current()=%+v,%v, want 1234 of 5000 resetting at %d", got, ok, reset)
+	}
+
+	rt := &httpclient.BudgetTransport{Budget: budget, Base: srv.Client().Transport}
+	t.Cleanup(srv.Client().CloseIdleConnections)
+	resp, err := (&http.Client{Transport: rt}).Get(srv.URL + "/search")
+	if err != nil {
+		t.Fatalf("transport Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if got, _ := budget.Current(); got.Remaining != 1234 {
+		t.Fatalf("search response changed the core budget to %d", got.Remaining)
+	}
+}
@@ -0,0 +1,138 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v86/github"
+)
+
+// maxCategoryPause caps how long a throttled category holds back its
+// requests. It covers the search API's one-minute window; waiting out a
+// longer core reset is left to the caller's retry loop.
+const maxCategoryPause = time.Minute
+
+// CategoryLimiter holds back the requests of one GitHub rate-limit
+// category while GitHub throttles it, leaving the other categories
+// unaffected. Categories are go-github's [github.RateLimitCategory], so
+// a throttled code search (30 requests a minute) does not delay the
+// core calls (5000 an hour) interleaved with it, and vice versa. One
+// CategoryLimiter is shared by every request made with the token,
+// typically through [CategoryTransport], so concurrent operations in a
+// throttled category wait for it to lift instead of each spending a
+// retry on a rejected request. The zero value is ready to use and all
+// methods are safe for concurrent use.
+type CategoryLimiter struct {
+	mu    sync.Mutex
+	until [github.Categories]time.Time
+}
+
+// Pause holds back category's requests for d from now, capped at one
+// minute. A pause never shortens one already in place. A nil receiver
+// is a no-op.
+func (l *CategoryLimiter) Pause(category github.RateLimitCategory, d time.Duration) {
+	if l == nil || d <= 0 || category >= github.Categories {
+		return
+	}
+	until := time.Now().Add(min(d, maxCategoryPause))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.until[category]) {
+		l.until[category] = until
+	}
+}
+
+// PausedUntil returns when category's pause lifts, or the zero time
+// when it is not paused. A nil receiver reports no pause.
+func (l *CategoryLimiter) PausedUntil(category github.RateLimitCategory) time.Time {
+	if l == nil || category >= github.Categories {
+		return time.Time{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := l.until[category]; until.After(time.Now()) {
+		return until
+	}
+	return time.Time{}
+}
+
+// Wait blocks until category's pause lifts or ctx is done, returning
+// ctx's error in the latter case.
+func (l *CategoryLimiter) Wait(ctx context.Context, category github.RateLimitCategory) error {
+	until := l.PausedUntil(category)
+	if until.IsZero() {
+		return nil
+	}
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Observe pauses category when resp is a rate-limit rejection: a 403
+// or 429 carrying a Retry-After header, or reporting no remaining
+// requests with X-RateLimit-Remaining and the window's end with
+// X-RateLimit-Reset.
+func (l *CategoryLimiter) Observe(category github.RateLimitCategory, resp *http.Response) {
+	if l == nil || resp == nil {
+		return
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	if d, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		l.Pause(category, d)
+		return
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	l.Pause(category, time.Until(time.Unix(resetUnix, 0)))
+}
+
+// RequestCategory returns the rate-limit category of req, as go-github
+// classifies it. The /api/v3 prefix of a GitHub Enterprise Server
+// endpoint is ignored.
+func RequestCategory(req *http.Request) github.RateLimitCategory {
+	return github.GetRateLimitCategory(req.Method, strings.TrimPrefix(req.URL.Path, "/api/v3"))
+}
+
+// CategoryTransport waits out the pause of each request's rate-limit
+// category before sending it, and pauses the category when the
+// response is a rate-limit rejection. It exists for the go-github
+// client, whose search and core calls share one transport.
+type CategoryTransport struct {
+	// Limiter tracks the pauses; nil disables them.
+	Limiter *CategoryLimiter
+	// Base performs the request; nil means [http.DefaultTransport].
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (t *CategoryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	category := RequestCategory(req)
+	if err := t.Limiter.Wait(req.Context(), category); err != nil {
+		return nil, err
+	}
+	resp, err := base.RoundTrip(req)
+	if err == nil {
+		t.Limiter.Observe(category, resp)
+	}
+	return resp, err
+}
@@ -0,0 +1,151 @@
+package httpclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+	"golang.org/x/time/rate"
+)
+
+// TestCategoryTransport_SeparatesSearchAndCore asserts a throttled
+// search pauses only later searches: core calls through the same
+// transport go out at once, and a paused search waits without reaching
+// the server.
+func TestCategoryTransport_SeparatesSearchAndCore(t *testing.T) {
+	t.Parallel()
+
+	var searches, cores atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/search/code":
+			searches.Add(1)
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+		default:
+			cores.Add(1)
+			_, _ = w.Write([]byte(`{"name":"r"}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	limiter := new(httpclient.CategoryLimiter)
+	gh := github.NewClient(&http.Client{Transport: &httpclient.CategoryTransport{Limiter: limiter, Base: srv.Client().Transport}})
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gh.BaseURL = u
+
+	ctx := t.Context()
+	if _, _, err := gh.Search.Code(ctx, "uses in:file", nil); err == nil {
+		t.Fatal("throttled search succeeded")
+	}
+	if limiter.PausedUntil(github.CodeSearchCategory).IsZero() {
+		t.Fatal("code search category not paused after a Retry-After rejection")
+	}
+	if !limiter.PausedUntil(github.CoreCategory).IsZero() {
+		t.Fatal("core category paused by a search rejection")
+	}
+
+	start := time.Now()
+	if _, _, err := gh.Repositories.Get(ctx, "o", "r"); err != nil {
+		t.Fatalf("core call: %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("core call took %v behind the paused search", d)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := gh.Search.Code(waitCtx, "uses in:file", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("paused search err = %v, want it held until the deadline", err)
+	}
+	if n := searches.Load(); n != 1 {
+		t.Errorf("server saw %d searches, want 1: the paused one must not be sent", n)
+	}
+	if n := cores.Load(); n != 1 {
+		t.Errorf("server saw %d core calls, want 1", n)
+	}
+}
+
+// TestCategoryLimiter_Observe covers which responses pause a category
+// and that a pause is capped and never shortened.
+func TestCategoryLimiter_Observe(t *testing.T) {
+	t.Parallel()
+
+	resp := func(status int, hdr map[string]string) *http.Response {
+		h := make(http.Header)
+		for k, v := range hdr {
+			h.Set(k, v)
+		}
+		return &http.Response{StatusCode: status, Header: h}
+	}
+	reset := strconv.FormatInt(time.Now().Add(20*time.Second).Unix(), 10)
+
+	for _, tc := range []struct {
+		name   string
+		resp   *http.Response
+		paused bool
+	}{
+		{"retry-after on 429", resp(http.StatusTooManyRequests, map[string]string{"Retry-After": "10"}), true},
+		{"exhausted window on 403", resp(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}), true},
+		{"403 with quota left", resp(http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "7", "X-RateLimit-Reset": reset}), false},
+		{"success with retry-after", resp(http.StatusOK, map[string]string{"Retry-After": "10"}), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			l := new(httpclient.CategoryLimiter)
+			l.Observe(github.SearchCategory, tc.resp)
+			if got := !l.PausedUntil(github.SearchCategory).IsZero(); got != tc.paused {
+				t.Errorf("paused = %v, want %v", got, tc.paused)
+			}
+		})
+	}
+
+	l := new(httpclient.CategoryLimiter)
+	l.Pause(github.SearchCategory, time.Hour)
+	capped := l.PausedUntil(github.SearchCategory)
+	if d := time.Until(capped); d <= 0 || d > time.Minute {
+		t.Errorf("hour-long pause lifts in %v, want it capped at a minute", d)
+	}
+	l.Pause(github.SearchCategory, time.Second)
+	if got := l.PausedUntil(github.SearchCategory); !got.Equal(capped) {
+		t.Errorf("shorter pause moved the lift from %v to %v", capped, got)
+	}
+}
+
+// TestGet_SearchQuotaDoesNotStallCore asserts the Client's limiter
+// ignores an exhausted search window.
+func TestGet_SearchQuotaDoesNotStallCore(t *testing.T) {
+	t.Parallel()
+
+	reset := strconv.FormatInt(time.Now().Add(10*time.Minute).Unix(), 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Resource", "search")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", reset)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+
+	hc := newTestClient(t, srv, httpclient.WithRateLimit(rate.Every(time.Second), 2))
+	if _, _, err := hc.Get(t.Context(), srv.URL+"/a"); err != nil {
+		t.Fatalf("first Get: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), 300*time.Millisecond)
+	defer cancel()
+	if _, _, err := hc.Get(ctx, srv.URL+"/b"); err != nil {
+		t.Fatalf("second Get stalled by the search window: %v", err)
+	}
+}
@@ -2,7 +2,6 @@ package httpclient
 
 import (
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -95,6 +94,9 @@ type Client struct {
 	retryBase     time.Duration
 	retryCap      time.Duration
 
+	// budget, when set, receives every response's rate-limit headers.
+	budget *RateBudget
+
 	// limiterMu guards adjustments derived from response headers so we
 	// never race rate.Limiter SetLimit/SetBurst against an in-flight
 	// Wait.
@@ -104,6 +106,16 @@ type Client struct {
 // Option configures a [Client].
 type Option func(*Client)
 
+// WithAPIVersion overrides the pinned X-GitHub-Api-Version header. An
+// empty value keeps the default.
+func WithAPIVersion(v string) Option {
+	return func(c *Client) {
+		if v != "" {
+			c.apiVersion = v
+		}
+	}
+}
+
 // WithUserAgent overrides the default User-Agent header.
 func WithUserAgent(ua string) Option {
 	return func(c *Client) {
@@ -167,9 +179,7 @@ func New(opts ...Option) *Client {
 		MaxIdleConnsPerHost: 8,
 		MaxConnsPerHost:     32,
 		IdleConnTimeout:     90 * time.Second,
-		TLSClientConfig: &tls.Config{
-			MinVersion: tls.VersionTLS12,
-		},
+		TLSClientConfig:     TLSConfig(nil),
 	}
 
 	c := &Client{
@@ -332,6 +342,7 @@ func (c *Client) executeOnce(ctx context.Context, req *http.Request) ([]byte, *h
 	}
 
 	c.reconcileRateLimit(resp)
+	c.budget.Observe(resp.Header)
 
 	key := canonicalKey(req)
 
@@ -355,7 +366,16 @@ func (c *Client) executeOnce(ctx context.Context, req *http.Request) ([]byte, *h
 		}
 	}
 
-	body, readErr := ReadAllBounded(resp.Body, c.maxBodyBytes)
+	var bodyReader io.Reader = resp.Body
+	var progress *progressReader
+	if cfg, ok := progressFromContext(ctx); ok {
+		progress = newProgressReader(resp.Body, resp.ContentLength, cfg)
+		bodyReader = progress
+	}
+	body, readErr := ReadAllBounded(bodyReader, c.maxBodyBytes)
+	if progress != nil {
+		progress.finish(readErr)
+	}
 	_ = resp.Body.Close()
 	if readErr != nil {
 		return nil, nil, readErr
@@ -379,11 +399,16 @@ func (c *Client) executeOnce(ctx context.Context, req *http.Request) ([]byte, *h
 
 // reconcileRateLimit consults the X-RateLimit-Remaining and
 // X-RateLimit-Reset response headers to back off the local limiter
-// when GitHub reports quota pressure.
+// when GitHub reports quota pressure. Headers for another resource
+// than core, such as search, are ignored: the limiter paces core
+// calls, and an exhausted search window must not stall them.
 func (c *Client) reconcileRateLimit(resp *http.Response) {
 	if resp == nil || resp.Header == nil {
 		return
 	}
+	if res := resp.Header.Get("X-RateLimit-Resource"); res != "" && res != "core" {
+		return
+	}
 	remainingStr := resp.Header.Get("X-RateLimit-Remaining")
 	resetStr := resp.Header.Get("X-RateLimit-Reset")
 	if remainingStr == "" || resetStr == "" {
@@ -76,6 +76,66 @@ func TestGet_FreshFetch_SetsDefaultHeaders(t *testing.T) {
 	}
 }
 
+func TestWithAPIVersion_OverridesPin(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Github-Api-Version")
+	}))
+	t.Cleanup(ts.Close)
+
+	c := newTestClient(t, ts, httpclient.WithAPIVersion("2022-11-28"))
+	_, resp, err := c.Get(t.Context(), ts.URL+"/runs")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	closeBody(t, resp)
+	if got != "2022-11-28" {
+		t.Fatalf("api version: got %q want 2022-11-28", got)
+	}
+}
+
+func TestAPIVersionTransport(t *testing.T) {
+	t.Parallel()
+
+	var got string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Github-Api-Version")
+	}))
+	t.Cleanup(ts.Close)
+
+	cases := []struct {
+		name    string
+		version string
+		want    string
+	}{
+		{name: "replaces caller version", version: "2022-11-28", want: "2022-11-28"},
+		{name: "empty leaves request untouched", version: "", want: "1999-01-01"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hc := &http.Client{Transport: &httpclient.APIVersionTransport{Version: tc.version, Base: ts.Client().Transport}}
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-GitHub-Api-Version", "1999-01-01")
+			resp, err := hc.Do(req)
+			if err != nil {
+				t.Fatalf("Do: %v", err)
+			}
+			closeBody(t, resp)
+			if got != tc.want {
+				t.Fatalf("api version: got %q want %q", got, tc.want)
+			}
+			if req.Header.Get("X-GitHub-Api-Version") != "1999-01-01" {
+				t.Fatal("transport mutated the caller's request")
+			}
+		})
+	}
+}
+
 func TestGet_ETagCache_Returns304AsCachedBody(t *testing.T) {
 	t.Parallel()
 
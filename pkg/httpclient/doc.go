@@ -5,20 +5,35 @@
 // The client centralizes:
 //
 //   - A locked-down [http.Transport] (TLS >= 1.2, capped idle connections).
+//     [LoadCertPool] and [WithTLSConfig] add a private CA's roots, and
+//     [NewTransport] carries the same [TLSConfig] to other clients.
 //   - A scheme/host allowlist enforced via [http.Client.CheckRedirect] so
 //     redirects can never escape api.github.com or its log-serving CDN
 //     hostnames.
 //   - Default request headers (User-Agent, Accept,
 //     X-GitHub-Api-Version) applied if the caller has not already set
-//     them.
+//     them. [WithAPIVersion] changes the pinned version, and
+//     [APIVersionTransport] applies it to clients built elsewhere,
+//     such as go-github.
 //   - Token-bucket rate limiting using [golang.org/x/time/rate],
 //     reconciled from response X-RateLimit-Remaining /
-//     X-RateLimit-Reset headers.
+//     X-RateLimit-Reset headers. A [RateBudget] installed with
+//     [WithRateBudget] or [BudgetTransport] exposes the latest
+//     observed budget to callers that pace their own concurrency.
+//     Headers for another resource than core, such as search, do not
+//     stall the limiter.
+//   - Per-category pauses: a [CategoryLimiter], installed on the
+//     go-github client with [CategoryTransport], holds back requests of
+//     one [github.com/google/go-github/v86/github.RateLimitCategory]
+//     (core, search, code search, ...) while GitHub throttles it, so
+//     a throttled search does not delay core calls and vice versa.
 //   - An ETag cache backed by [github.com/hashicorp/golang-lru/v2]
 //     that transparently returns cached bodies on HTTP 304.
 //   - In-flight request deduplication via
 //     [golang.org/x/sync/singleflight] keyed by the canonical URL.
 //   - Body size capping via [ReadAllBounded].
+//   - Optional body download progress reports, requested per call
+//     through [WithProgress].
 //
 // Retry layering:
 //
@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Progress describes how much of a response body has been read.
+type Progress struct {
+	// Read is the number of body bytes read so far.
+	Read int64
+	// Total is the response's Content-Length, or -1 when the server
+	// did not send one.
+	Total int64
+	// Elapsed is the time since the response headers arrived.
+	Elapsed time.Duration
+	// Done is set on the final report, once the body has been read to
+	// the end or the read failed.
+	Done bool
+	// Err is the read error that ended the body, if any.
+	Err error
+}
+
+// ProgressFunc receives body download progress reports.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+type progressConfig struct {
+	interval time.Duration
+	fn       ProgressFunc
+}
+
+// WithProgress returns a context that makes [Client.Do], and so
+// [Client.DoWithRetry], report response body progress to fn: at most
+// once per interval while bytes arrive, and once more with Done set
+// when the body ends. A download that stalls produces no interim
+// reports, so the gap before the final one tells a hung connection
+// apart from a slow transfer. Bodies served from the ETag cache are
+// not reported. A nil fn or non-positive interval returns ctx as is.
+func WithProgress(ctx context.Context, interval time.Duration, fn ProgressFunc) context.Context {
+	if fn == nil || interval <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, progressConfig{interval: interval, fn: fn})
+}
+
+// progressFromContext returns the progress configuration installed by
+// WithProgress, if any.
+func progressFromContext(ctx context.Context) (progressConfig, bool) {
+	cfg, ok := ctx.Value(progressKey{}).(progressConfig)
+	return cfg, ok
+}
+
+// progressReader counts the bytes read through it and reports them to
+// a ProgressFunc.
+type progressReader struct {
+	r     io.Reader
+	cfg   progressConfig
+	total int64
+	read  int64
+	start time.Time
+	last  time.Time
+	done  bool
+}
+
+func newProgressReader(r io.Reader, total int64, cfg progressConfig) *progressReader {
+	now := time.Now()
+	return &progressReader{r: r, cfg: cfg, total: total, start: now, last: now}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	switch {
+	case err == io.EOF:
+		p.finish(nil)
+	case err != nil:
+		p.finish(err)
+	default:
+		if now := time.Now(); now.Sub(p.last) >= p.cfg.interval {
+			p.last = now
+			p.cfg.fn(p.progress())
+		}
+	}
+	return n, err
+}
+
+// finish sends the final report once. It is also called when the
+// body is closed early, such as by the size cap in ReadAllBounded.
+func (p *progressReader) finish(err error) {
+	if p.done {
+		return
+	}
+	p.done = true
+	pr := p.progress()
+	pr.Done = true
+	pr.Err = err
+	p.cfg.fn(pr)
+}
+
+func (p *progressReader) progress() Progress {
+	return Progress{Read: p.read, Total: p.total, Elapsed: time.Since(p.start)}
+}
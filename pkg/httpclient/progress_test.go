@@ -0,0 +1,153 @@
+package httpclient_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+)
+
+// recordProgress returns a ProgressFunc that records every report and
+// a function returning the reports recorded so far.
+func recordProgress() (httpclient.ProgressFunc, func() []httpclient.Progress) {
+	var mu sync.Mutex
+	var got []httpclient.Progress
+	fn := func(p httpclient.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, p)
+	}
+	return fn, func() []httpclient.Progress {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]httpclient.Progress(nil), got...)
+	}
+}
+
+// TestWithProgress_ReportsAgainstContentLength asserts interim reports
+// count bytes against Content-Length while the body streams and a
+// single final report marks the end.
+func TestWithProgress_ReportsAgainstContentLength(t *testing.T) {
+	t.Parallel()
+
+	const chunk = "0123456789"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(3*len(chunk)))
+		for range 3 {
+			_, _ = io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	fn, reports := recordProgress()
+	ctx := httpclient.WithProgress(t.Context(), time.Millisecond, fn)
+	body, resp, err := newTestClient(t, ts).Get(ctx, ts.URL+"/logs.zip")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	closeBody(t, resp)
+	if len(body) != 3*len(chunk) {
+		t.Fatalf("body length=%d, want %d", len(body), 3*len(chunk))
+	}
+
+	got := reports()
+	if len(got) < 2 {
+		t.Fatalf("got %d reports, want interim reports and a final one: %+v", len(got), got)
+	}
+	for _, p := range got[:len(got)-1] {
+		if p.Done || p.Total != int64(3*len(chunk)) || p.Read <= 0 || p.Read > p.Total {
+			t.Errorf("interim report %+v, want 0 < Read <= Total=%d", p, 3*len(chunk))
+		}
+	}
+	if last := got[len(got)-1]; !last.Done || last.Err != nil || last.Read != int64(3*len(chunk)) {
+		t.Fatalf("final report %+v, want Done with all %d bytes", last, 3*len(chunk))
+	}
+}
+
+// TestWithProgress_ReportsFailure asserts a body cut short, and one
+// over the size cap, end with a final report carrying the error and
+// the bytes read before it.
+func TestWithProgress_ReportsFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		opts    []httpclient.Option
+		read    int64
+		total   int64
+		wantErr error
+	}{{
+		name: "truncated",
+		handler: func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			_, _ = io.WriteString(w, "partial")
+		},
+		read:    7,
+		total:   100,
+		wantErr: io.ErrUnexpectedEOF,
+	}, {
+		name: "too large",
+		handler: func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, strings.Repeat("x", 64))
+		},
+		opts:    []httpclient.Option{httpclient.WithMaxBodyBytes(16)},
+		read:    17,
+		total:   64,
+		wantErr: httpclient.ErrBodyTooLarge,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(tt.handler)
+			t.Cleanup(ts.Close)
+
+			fn, reports := recordProgress()
+			ctx := httpclient.WithProgress(t.Context(), time.Hour, fn)
+			if _, _, err := newTestClient(t, ts, tt.opts...).Get(ctx, ts.URL+"/logs.zip"); err == nil {
+				t.Fatal("Get succeeded, want an error")
+			}
+			got := reports()
+			if len(got) != 1 {
+				t.Fatalf("got %d reports, want only the final one: %+v", len(got), got)
+			}
+			p := got[0]
+			if !p.Done || !errors.Is(p.Err, tt.wantErr) || p.Read != tt.read || p.Total != tt.total {
+				t.Fatalf("final report %+v, want Done, Read=%d, Total=%d, Err=%v", p, tt.read, tt.total, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestWithProgress_UnknownLength asserts a body without Content-Length
+// reports a Total of -1.
+func TestWithProgress_UnknownLength(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "chunked")
+		w.(http.Flusher).Flush()
+	}))
+	t.Cleanup(ts.Close)
+
+	fn, reports := recordProgress()
+	ctx := httpclient.WithProgress(t.Context(), time.Hour, fn)
+	_, resp, err := newTestClient(t, ts).Get(ctx, ts.URL+"/logs.zip")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	closeBody(t, resp)
+	got := reports()
+	if len(got) != 1 || got[0].Total != -1 || got[0].Read != 7 {
+		t.Fatalf("reports %+v, want one final report with Read=7, Total=-1", got)
+	}
+}
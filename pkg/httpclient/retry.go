@@ -219,9 +219,10 @@ func (c *Client) DoWithRetry(ctx context.Context, req *http.Request) ([]byte, *h
 		}
 
 		body, resp, err = c.Do(ctx, req)
-		// Network error path.
+		// Network error path. An oversized body is the same size on
+		// every attempt, so it is not retried.
 		if err != nil {
-			if attempt == maxRetries {
+			if attempt == maxRetries || errors.Is(err, ErrBodyTooLarge) {
 				return body, resp, err
 			}
 			sleep := jitterDelay(attempt, base, capDur)
@@ -252,6 +252,29 @@ func TestDoWithRetry_NoRetryOn200(t *testing.T) {
 	}
 }
 
+// TestDoWithRetry_BodyTooLargeIsNotRetried asserts an oversized body,
+// which every attempt would hit again, is returned after one request.
+func TestDoWithRetry_BodyTooLargeIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		_, _ = io.WriteString(w, "far more than sixteen bytes of body")
+	}))
+	t.Cleanup(ts.Close)
+
+	c, clock := retryTestClient(t, ts, httpclient.WithMaxBodyBytes(16))
+	_, _, err := c.GetWithRetry(t.Context(), ts.URL+"/")
+	if !errors.Is(err, httpclient.ErrBodyTooLarge) {
+		t.Fatalf("err=%v, want ErrBodyTooLarge", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls: got %d want 1", calls.Load())
+	}
+	if len(clock.Sleeps) != 0 {
+		t.Errorf("sleeps: got %v want []", clock.Sleeps)
+	}
+}
+
 func TestDoWithRetry_ContextCanceledMidRetry(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Retry-After", "60")
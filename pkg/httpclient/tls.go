@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSConfig returns the TLS configuration every ghscan client shares:
+// TLS 1.2 or newer, trusting roots, or the system roots when roots is
+// nil.
+func TLSConfig(roots *x509.CertPool) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    roots,
+	}
+}
+
+// LoadCertPool returns the system roots extended with the PEM
+// certificates in path, for a GitHub Enterprise Server signed by a
+// private CA. A file that holds no certificate is an error, so a
+// mistyped path fails at startup rather than as x509 errors mid-scan.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// WithTLSConfig replaces the TLS configuration of the client's default
+// transport, e.g. with one from [TLSConfig] trusting a private CA. It
+// has no effect on a client installed with [WithHTTPClient] whose
+// transport is not an [*http.Transport]; a nil cfg keeps the default.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		if cfg == nil {
+			return
+		}
+		if t, ok := c.httpClient.Transport.(*http.Transport); ok {
+			t.TLSClientConfig = cfg
+		}
+	}
+}
+
+// NewTransport returns a clone of [http.DefaultTransport] using cfg,
+// for clients built outside this package, such as go-github's, that
+// must trust the same roots. A nil cfg keeps the default.
+func NewTransport(cfg *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg != nil {
+		t.TLSClientConfig = cfg
+	}
+	return t
+}
@@ -0,0 +1,62 @@
+package httpclient_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"golang.org/x/time/rate"
+)
+
+// TestWithTLSConfig_TrustsPrivateCA asserts a server certificate from
+// a private CA is rejected by default and accepted once its PEM is
+// loaded with LoadCertPool.
+func TestWithTLSConfig_TrustsPrivateCA(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(ts.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caFile, pemData, 0o600); err != nil {
+		t.Fatalf("write CA: %v", err)
+	}
+
+	untrusted := httpclient.New(httpclient.WithRateLimit(rate.Inf, 10))
+	if _, _, err := untrusted.Get(t.Context(), ts.URL); err == nil {
+		t.Fatal("Get succeeded against an untrusted private CA")
+	}
+
+	roots, err := httpclient.LoadCertPool(caFile)
+	if err != nil {
+		t.Fatalf("LoadCertPool: %v", err)
+	}
+	trusted := httpclient.New(httpclient.WithRateLimit(rate.Inf, 10), httpclient.WithTLSConfig(httpclient.TLSConfig(roots)))
+	body, _, err := trusted.Get(t.Context(), ts.URL)
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("Get=%q,%v, want ok", body, err)
+	}
+}
+
+// TestLoadCertPool_RejectsFileWithoutCertificates asserts a missing or
+// non-PEM bundle is an error.
+func TestLoadCertPool_RejectsFileWithoutCertificates(t *testing.T) {
+	t.Parallel()
+
+	junk := filepath.Join(t.TempDir(), "junk.pem")
+	if err := os.WriteFile(junk, []byte("not a certificate\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, path := range []string{junk, filepath.Join(t.TempDir(), "missing.pem")} {
+		if _, err := httpclient.LoadCertPool(path); err == nil {
+			t.Fatalf("LoadCertPool(%s) succeeded, want error", path)
+		}
+	}
+}
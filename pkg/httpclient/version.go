@@ -0,0 +1,32 @@
+package httpclient
+
+import "net/http"
+
+// APIVersionTransport sets X-GitHub-Api-Version on every request it
+// carries, replacing any value already present. It exists for the
+// go-github client, which stamps its own default version in
+// NewRequest; wrapping that client's transport keeps SDK calls on the
+// same pinned version as [Client] when an operator overrides it.
+type APIVersionTransport struct {
+	// Version is the date stamp to send. Empty leaves requests
+	// untouched.
+	Version string
+	// Base performs the request; nil means [http.DefaultTransport].
+	Base http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper]. The request is cloned
+// before its headers are changed, as the RoundTripper contract
+// requires.
+func (t *APIVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t.Version == "" || req.Header.Get("X-GitHub-Api-Version") == t.Version {
+		return base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set("X-GitHub-Api-Version", t.Version)
+	return base.RoundTrip(r)
+}
@@ -0,0 +1,40 @@
+package ioc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadContentFile reads newline-delimited literal content (typically
+// commit or image digests) for [Config.Content]. Each line is trimmed;
+// blank lines and lines starting with '#' are skipped. A file with no
+// remaining entries is not an error so a freshly created list can be
+// wired up before it is populated.
+func LoadContentFile(path string) ([]string, error) {
+	clean := filepath.Clean(path)
+	// #nosec G304 -- content path is an explicit user-supplied list of
+	// indicators, read the same way as the corpus override.
+	data, err := os.ReadFile(clean)
+	if err != nil {
+		return nil, fmt.Errorf("reading content file %s: %w", clean, err)
+	}
+
+	out := make([]string, 0)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("parsing content file %s: %w", clean, err)
+	}
+	return out, nil
+}
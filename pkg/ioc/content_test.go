@@ -0,0 +1,71 @@
+package ioc_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+)
+
+func TestLoadContentFile(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "one per line",
+			data: "0e58ed8671d6b60d0890c21b07f8835ace038e67\na1b2c3\n",
+			want: []string{"0e58ed8671d6b60d0890c21b07f8835ace038e67", "a1b2c3"},
+		},
+		{
+			name: "trims whitespace and CRLF",
+			data: "  a1b2c3 \r\n\tdeadbeef\r\n",
+			want: []string{"a1b2c3", "deadbeef"},
+		},
+		{
+			name: "skips blanks and comments",
+			data: "# published 2025-03-15\n\na1b2c3\n   # indented comment\ndeadbeef\n",
+			want: []string{"a1b2c3", "deadbeef"},
+		},
+		{
+			name: "no trailing newline",
+			data: "a1b2c3",
+			want: []string{"a1b2c3"},
+		},
+		{
+			name: "only comments",
+			data: "# nothing yet\n",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "digests.txt")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioc.LoadContentFile(path)
+			if err != nil {
+				t.Fatalf("LoadContentFile: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadContentFile_Missing(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ioc.LoadContentFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("expected error for missing file")
+	}
+}
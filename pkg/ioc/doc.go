@@ -10,7 +10,8 @@
 //   - [LoadEmbeddedCorpus] / [LoadCorpusFile] return a parsed [Corpus]
 //     whose [CorpusEntry] values are turned into [IOC] instances via
 //     [CorpusEntry.BuildIOC]. The on-disk schema lives in iocs.json and
-//     pins a single integer version field.
+//     pins a single integer version field. [LoadContentFile] reads a
+//     newline-delimited list of literals for [Config.Content].
 //   - [NewMatcher] builds a [Matcher] over a literal IOC corpus. The
 //     matcher transparently selects between strings.Contains and
 //     Aho-Corasick at construction time and is fronted by a bloom
@@ -18,6 +19,9 @@
 //   - [Matcher.Match] / [Matcher.MatchAny] / [Matcher.MatchAnyString]
 //     are the per-line scan entry points. MatchAnyString avoids the
 //     []byte conversion when callers already hold a string.
+//   - [IOC.MayMatchRegex] pre-filters lines for the IOC's regex,
+//     rejecting those shorter than its shortest match before a cheap
+//     MatchString, so the submatch search runs only on candidates.
 //
 // Invariants:
 //
@@ -26,6 +30,8 @@
 //   - Adding more IOCs to the corpus monotonically widens the set of
 //     admitted log windows -- it never causes a previously matched
 //     pair to be rejected.
+//   - [IOC.MayMatchRegex] is sound in the same way: it never rejects
+//     a line the regex matches.
 //   - The matcher is immutable after construction and safe for
 //     concurrent reads from multiple goroutines.
 package ioc
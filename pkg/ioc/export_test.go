@@ -6,3 +6,8 @@ package ioc
 func NormalizeForTest(s string) string {
 	return normalizeMatchInput(s)
 }
+
+// RegexMinLenForTest exposes the pre-filter's match length bound.
+func RegexMinLenForTest(pattern string) int {
+	return regexMinLen(pattern)
+}
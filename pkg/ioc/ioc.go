@@ -20,7 +20,10 @@ type IOC struct {
 	name    string
 	content []string
 	regex   *regexp.Regexp
-	matcher Matcher
+	// regexMinLen is the shortest line regex can match; see
+	// [IOC.MayMatchRegex].
+	regexMinLen int
+	matcher     Matcher
 }
 
 // embeddedCorpusOnce memoizes the parsed embedded corpus so repeated
@@ -116,10 +119,11 @@ func NewIOC(config *Config) (*IOC, error) {
 	}
 
 	return &IOC{
-		name:    name,
-		content: normalized,
-		regex:   regex,
-		matcher: matcher,
+		name:        name,
+		content:     normalized,
+		regex:       regex,
+		regexMinLen: regexMinLen(config.Pattern),
+		matcher:     matcher,
 	}, nil
 }
 
@@ -0,0 +1,63 @@
+package ioc
+
+import "regexp/syntax"
+
+// MayMatchRegex is a cheap pre-filter for the IOC's regex: false means
+// the regex cannot match line, so running FindAllStringSubmatch on it
+// would find nothing; true means it can, and the caller runs the full
+// submatch search. Lines shorter than the shortest possible match are
+// rejected without touching the regex, and the rest with MatchString,
+// which skips the capture bookkeeping FindAllStringSubmatch pays on
+// every line. Matching lines, rare in real logs, are searched twice.
+// An IOC without a regex matches nothing.
+func (i *IOC) MayMatchRegex(line string) bool {
+	if i.regex == nil {
+		return false
+	}
+	return len(line) >= i.regexMinLen && i.regex.MatchString(line)
+}
+
+// regexMinLen returns a lower bound on the length in bytes of any
+// match of pattern, or 0 when the pattern cannot be parsed as the
+// regexp package would.
+func regexMinLen(pattern string) int {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0
+	}
+	return minLen(re.Simplify())
+}
+
+// minLen is regexMinLen over a parsed expression. Every rune counts as
+// one byte, its shortest encoding, so case folding and multibyte
+// classes keep the result a lower bound.
+func minLen(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return len(re.Rune)
+	case syntax.OpCharClass, syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return 1
+	case syntax.OpCapture, syntax.OpPlus:
+		return minLen(re.Sub[0])
+	case syntax.OpRepeat:
+		return re.Min * minLen(re.Sub[0])
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += minLen(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := -1
+		for _, sub := range re.Sub {
+			if m := minLen(sub); n < 0 || m < n {
+				n = m
+			}
+		}
+		return max(n, 0)
+	default:
+		// Star, Quest, empty-width assertions, and anything newer
+		// may match the empty string.
+		return 0
+	}
+}
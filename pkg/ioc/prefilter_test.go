@@ -0,0 +1,136 @@
+package ioc_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+)
+
+// benchmarkPattern is the permissive base64 pattern from config.yaml.
+const benchmarkPattern = `(?:^|\s+)([A-Za-z0-9+/]{40,}={0,3})`
+
+func TestRegexMinLen(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		want    int
+	}{
+		{pattern: benchmarkPattern, want: 40},
+		{pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`, want: 8},
+		{pattern: `abc|de`, want: 2},
+		{pattern: `(?i)token-[0-9]+`, want: 7},
+		{pattern: `x*`, want: 0},
+		{pattern: `é{3}`, want: 3},
+		{pattern: `(unclosed`, want: 0},
+	}
+	for _, tc := range tests {
+		if got := ioc.RegexMinLenForTest(tc.pattern); got != tc.want {
+			t.Errorf("regexMinLen(%q)=%d, want %d", tc.pattern, got, tc.want)
+		}
+	}
+}
+
+// TestMayMatchRegex_AgreesWithFindAll asserts the pre-filter never
+// drops a line the full submatch search would report.
+func TestMayMatchRegex_AgreesWithFindAll(t *testing.T) {
+	t.Parallel()
+
+	for _, pattern := range []string{benchmarkPattern, `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`, `secret-(\d+)`} {
+		i, err := ioc.NewIOC(&ioc.Config{Name: "t", Pattern: pattern})
+		if err != nil {
+			t.Fatalf("NewIOC(%q): %v", pattern, err)
+		}
+		for _, line := range buildRegexBenchmarkLines(64 << 10) {
+			want := len(i.GetRegex().FindAllStringSubmatch(line, -1)) > 0
+			if got := i.MayMatchRegex(line); got != want {
+				t.Fatalf("pattern %q: MayMatchRegex(%q)=%v, want %v", pattern, line, got, want)
+			}
+		}
+	}
+
+	var none ioc.IOC
+	if none.MayMatchRegex(strings.Repeat("A", 64)) {
+		t.Fatal("IOC without a regex may match")
+	}
+}
+
+// BenchmarkRegexPerLine_FindAll is the baseline: the base64 detector
+// running the permissive pattern's submatch search on every line of a
+// multi-megabyte log.
+func BenchmarkRegexPerLine_FindAll(b *testing.B) {
+	i, lines, size := regexBenchmarkInput(b)
+	regex := i.GetRegex()
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		for _, line := range lines {
+			_ = regex.FindAllStringSubmatch(line, -1)
+		}
+	}
+}
+
+// BenchmarkRegexPerLine_Prefiltered measures the same workload with
+// MayMatchRegex gating the submatch search, as the detector now runs.
+func BenchmarkRegexPerLine_Prefiltered(b *testing.B) {
+	i, lines, size := regexBenchmarkInput(b)
+	regex := i.GetRegex()
+
+	b.SetBytes(size)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		for _, line := range lines {
+			if i.MayMatchRegex(line) {
+				_ = regex.FindAllStringSubmatch(line, -1)
+			}
+		}
+	}
+}
+
+func regexBenchmarkInput(b *testing.B) (*ioc.IOC, []string, int64) {
+	b.Helper()
+	i, err := ioc.NewIOC(&ioc.Config{Name: "bench", Pattern: benchmarkPattern})
+	if err != nil {
+		b.Fatalf("NewIOC: %v", err)
+	}
+	lines := buildRegexBenchmarkLines(4 << 20)
+	var size int64
+	for _, line := range lines {
+		size += int64(len(line))
+	}
+	return i, lines, size
+}
+
+// buildRegexBenchmarkLines returns about size bytes of Actions-style
+// log lines: timestamped step output, group markers, blank lines, and
+// one base64 payload per thousand lines.
+func buildRegexBenchmarkLines(size int) []string {
+	templates := []string{
+		"2025-03-14T12:00:%02d.1234567Z ##[group]Run actions/checkout@v4",
+		"2025-03-14T12:00:%02d.1234567Z   with:",
+		"2025-03-14T12:00:%02d.1234567Z npm warn deprecated inflight@1.0.6: This module is not supported",
+		"2025-03-14T12:00:%02d.1234567Z added 1432 packages, and audited 1433 packages in 21s",
+		"2025-03-14T12:00:%02d.1234567Z ##[endgroup]",
+		"",
+		"2025-03-14T12:00:%02d.1234567Z sha256:4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945",
+	}
+	var lines []string
+	total := 0
+	for n := 0; total < size; n++ {
+		line := templates[n%len(templates)]
+		if strings.Contains(line, "%") {
+			line = fmt.Sprintf(line, n%60)
+		}
+		if n%1000 == 999 {
+			line = "2025-03-14T12:00:00.1234567Z " + strings.Repeat("U0VDUkVUX1RPS0VO", 4)
+		}
+		lines = append(lines, line)
+		total += len(line) + 1
+	}
+	return lines
+}
@@ -0,0 +1,269 @@
+package workflow
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/google/go-github/v86/github"
+	"gopkg.in/yaml.v3"
+)
+
+// maxActionFileBytes caps any action manifest or bundled file fetched
+// for static scanning, matching the contents API's 1 MiB inline limit.
+const maxActionFileBytes = 1 << 20
+
+// maxActionLineBytes caps the text kept from a matched line. Bundled
+// JavaScript is often minified onto a handful of enormous lines.
+const maxActionLineBytes = 512
+
+// maxTagDerefs bounds how many annotated tags [ResolveActionTag]
+// follows before giving up, so a tag object pointing at itself, or a
+// long chain of tags, cannot loop.
+const maxTagDerefs = 4
+
+// ErrActionNotFound is returned by [FetchActionManifest] when neither
+// action.yml nor action.yaml exists at the action's path and ref.
+var ErrActionNotFound = errors.New("action manifest not found")
+
+// actionPathRE captures script paths a composite step reaches through
+// the action's own checkout, e.g. ${{ github.action_path }}/run.sh.
+var actionPathRE = regexp.MustCompile(`(?:\$\{\{\s*github\.action_path\s*\}\}|\$\{?GITHUB_ACTION_PATH\}?)/([A-Za-z0-9._/-]+)`)
+
+// ActionRef identifies a remote action referenced by a uses: value:
+// the repository, an optional subdirectory holding the manifest, and
+// the ref it is pinned to.
+type ActionRef struct {
+	Owner string
+	Repo  string
+	Path  string
+	Ref   string
+}
+
+// Repository returns the action's owner/repo.
+func (a ActionRef) Repository() string {
+	return a.Owner + "/" + a.Repo
+}
+
+// String renders the reference in uses: form, owner/repo[/path]@ref.
+func (a ActionRef) String() string {
+	s := a.Repository()
+	if a.Path != "" {
+		s += "/" + a.Path
+	}
+	return s + "@" + a.Ref
+}
+
+// ParseActionRef parses a uses: value naming a remote action. Local
+// actions (./...), docker:// images, reusable workflow calls, values
+// without a ref, and paths that are not canonical are rejected.
+func ParseActionRef(uses string) (ActionRef, bool) {
+	uses = strings.TrimSpace(uses)
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") {
+		return ActionRef{}, false
+	}
+	action, ref := splitUses(uses)
+	if ref == "" {
+		return ActionRef{}, false
+	}
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ActionRef{}, false
+	}
+	a := ActionRef{Owner: parts[0], Repo: parts[1], Ref: ref}
+	if len(parts) == 3 {
+		if !validActionPath(parts[2]) || strings.HasPrefix(parts[2], workflowsDir+"/") {
+			return ActionRef{}, false
+		}
+		a.Path = parts[2]
+	}
+	return a, true
+}
+
+// validActionPath reports whether p is a canonical path relative to
+// an action repository's root.
+func validActionPath(p string) bool {
+	return p != "" && p != "." && path.Clean(p) == p && !path.IsAbs(p) &&
+		p != ".." && !strings.HasPrefix(p, "../")
+}
+
+// ActionManifest is the part of an action.yml the static scanner
+// needs: how the action runs, the uses: references of composite steps,
+// and the bundled files it executes, relative to the repository root.
+type ActionManifest struct {
+	Using string
+	Uses  []string
+	Files []string
+}
+
+// ParseActionManifest extracts an [ActionManifest] from action.yml
+// data for an action whose manifest lives in dir ("" for the root).
+// Bundled files are runs.main/pre/post for JavaScript actions, a local
+// Dockerfile for Docker actions, and any script a composite step runs
+// through github.action_path.
+func ParseActionManifest(data []byte, dir string) (ActionManifest, error) {
+	if len(data) > maxActionFileBytes {
+		return ActionManifest{}, fmt.Errorf("action manifest exceeds maximum size (%d > %d bytes)", len(data), maxActionFileBytes)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return ActionManifest{}, fmt.Errorf("parsing action manifest: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return ActionManifest{}, nil
+	}
+	runs := mappingValue(root.Content[0], "runs")
+	if runs == nil || runs.Kind != yaml.MappingNode {
+		return ActionManifest{}, nil
+	}
+
+	var m ActionManifest
+	seen := make(map[string]bool)
+	addFile := func(rel string) {
+		p := path.Join(dir, rel)
+		if validActionPath(path.Clean(rel)) && validActionPath(p) && !seen[p] {
+			seen[p] = true
+			m.Files = append(m.Files, p)
+		}
+	}
+	if n := mappingValue(runs, "using"); n != nil && n.Kind == yaml.ScalarNode {
+		m.Using = n.Value
+	}
+	for _, key := range []string{"pre", "main", "post"} {
+		if n := mappingValue(runs, key); n != nil && n.Kind == yaml.ScalarNode && n.Value != "" {
+			addFile(n.Value)
+		}
+	}
+	if n := mappingValue(runs, "image"); n != nil && n.Kind == yaml.ScalarNode && !strings.HasPrefix(n.Value, "docker://") {
+		addFile(n.Value)
+	}
+	if steps := mappingValue(runs, "steps"); steps != nil && steps.Kind == yaml.SequenceNode {
+		for _, step := range steps.Content {
+			if n := mappingValue(step, "uses"); n != nil && n.Kind == yaml.ScalarNode {
+				m.Uses = append(m.Uses, strings.TrimSpace(n.Value))
+			}
+			if n := mappingValue(step, "run"); n != nil && n.Kind == yaml.ScalarNode {
+				for _, sm := range actionPathRE.FindAllStringSubmatch(n.Value, -1) {
+					addFile(sm[1])
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// FetchActionFile returns the content of filePath in an action
+// repository at ref. Files over 1 MiB are rejected.
+func FetchActionFile(ctx context.Context, gh *github.Client, owner, repo, filePath, ref string) ([]byte, error) {
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	if !validActionPath(filePath) {
+		return nil, fmt.Errorf("action file path %q is not a canonical relative path", filePath)
+	}
+	fc, _, _, err := gh.Repositories.GetContents(ctx, owner, repo, filePath, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", filePath, err)
+	}
+	if fc == nil {
+		return nil, fmt.Errorf("fetching %s: not a file", filePath)
+	}
+	if size := fc.GetSize(); size > maxActionFileBytes {
+		return nil, fmt.Errorf("action file %s exceeds maximum size (%d > %d bytes)", filePath, size, maxActionFileBytes)
+	}
+	body, err := fc.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", filePath, err)
+	}
+	return []byte(body), nil
+}
+
+// FetchActionManifest fetches action.yml, falling back to action.yaml,
+// from the action's directory and returns its path and content. It
+// returns [ErrActionNotFound] when neither exists.
+func FetchActionManifest(ctx context.Context, gh *github.Client, a ActionRef) (string, []byte, error) {
+	for _, name := range []string{"action.yml", "action.yaml"} {
+		p := path.Join(a.Path, name)
+		body, err := FetchActionFile(ctx, gh, a.Owner, a.Repo, p, a.Ref)
+		if err == nil {
+			return p, body, nil
+		}
+		var ghErr *github.ErrorResponse
+		if !errors.As(err, &ghErr) || ghErr.Response == nil || ghErr.Response.StatusCode != http.StatusNotFound {
+			return "", nil, err
+		}
+	}
+	return "", nil, fmt.Errorf("%s: %w", a, ErrActionNotFound)
+}
+
+// ResolveActionTag returns the commit SHA the tag named by a's Ref
+// points at now, following annotated tags to the commit they tag. A tag
+// that was force-pushed resolves to its new commit, whatever the
+// workflow author reviewed when pinning it.
+func ResolveActionTag(ctx context.Context, gh *github.Client, a ActionRef) (string, error) {
+	if gh == nil {
+		return "", fmt.Errorf("github client must not be nil")
+	}
+	ref, _, err := gh.Git.GetRef(ctx, a.Owner, a.Repo, "tags/"+a.Ref)
+	if err != nil {
+		return "", fmt.Errorf("resolving tag %s of %s: %w", a.Ref, a.Repository(), err)
+	}
+	obj := ref.GetObject()
+	for range maxTagDerefs {
+		if obj.GetType() != "tag" {
+			break
+		}
+		tag, _, err := gh.Git.GetTag(ctx, a.Owner, a.Repo, obj.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("dereferencing tag %s of %s: %w", a.Ref, a.Repository(), err)
+		}
+		obj = tag.GetObject()
+	}
+	if obj.GetType() != "commit" || obj.GetSHA() == "" {
+		return "", fmt.Errorf("tag %s of %s does not resolve to a commit (%s)", a.Ref, a.Repository(), obj.GetType())
+	}
+	return obj.GetSHA(), nil
+}
+
+// ActionMatch is a line of an action file that matched.
+type ActionMatch struct {
+	Line    string
+	IOCName string
+}
+
+// MatchActionContent returns every line of content that contains
+// findIOC content or a uses: reference corpus flags as known-bad, the
+// same test the history scanner applies to added workflow lines.
+// Either matcher may be nil. Matched lines are trimmed and capped.
+func MatchActionContent(content []byte, findIOC *ioc.IOC, corpus *ioc.Corpus) []ActionMatch {
+	var matcher ioc.Matcher
+	if findIOC != nil {
+		matcher = findIOC.GetMatcher()
+	}
+
+	var out []ActionMatch
+	sc := bufio.NewScanner(bytes.NewReader(content))
+	sc.Buffer(make([]byte, 0, 64*1024), maxActionFileBytes+1)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		name, ok := matchLine(line, matcher, findIOC, corpus)
+		if !ok {
+			continue
+		}
+		if len(line) > maxActionLineBytes {
+			line = strings.ToValidUTF8(line[:maxActionLineBytes], "") + "…"
+		}
+		out = append(out, ActionMatch{Line: line, IOCName: name})
+	}
+	return out
+}
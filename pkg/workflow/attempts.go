@@ -0,0 +1,127 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+)
+
+// GetAttemptLogs fetches the logs of one attempt of a re-run run
+// through [github.ActionsService.GetWorkflowRunAttemptLogs]. [GetLogs]
+// returns only the latest attempt's logs, so a payload that ran in an
+// earlier attempt and was re-run away is visible only here. When the
+// attempt's archive is gone (404/410) or too large, the attempt's jobs,
+// which keep their own IDs across re-runs, are downloaded through the
+// per-job endpoint instead.
+//
+// The JobFilter and FallbackConcurrency of opts apply as in GetLogs:
+// only the matching jobs of the attempt are downloaded, and an attempt
+// with none returns ErrNoMatchingJobs. The [LogCache], which is keyed
+// by run, is not consulted. An attempt without jobs returns
+// ErrRunHasNoLogs.
+func GetAttemptLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID, attempt int64, token string, opts *Options) (io.ReadCloser, error) {
+	if hc == nil {
+		return nil, fmt.Errorf("httpclient must not be nil")
+	}
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+
+	if filter := opts.jobFilter(); filter != nil {
+		return attemptJobLogs(ctx, logger, hc, gh, owner, repo, runID, attempt, token, filter, opts.fallbackConcurrency())
+	}
+
+	logURL, resp, err := gh.Actions.GetWorkflowRunAttemptLogs(ctx, owner, repo, runID, int(attempt), runLogsMaxRedirects)
+	switch {
+	case err == nil && logURL != nil:
+		body, err := fetchRawLogs(ctx, hc, logURL.String(), token)
+		if errors.Is(err, httpclient.ErrBodyTooLarge) {
+			logger.Warnf("Log archive of attempt %d of run %d exceeds the download limit; downloading each job's logs instead", attempt, runID)
+			return attemptJobLogs(ctx, logger, hc, gh, owner, repo, runID, attempt, token, nil, opts.fallbackConcurrency())
+		}
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(body)), nil
+
+	case resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone):
+		logger.Debugf("Logs API returned %d for attempt %d of run %d; falling back to per-job logs API", resp.StatusCode, attempt, runID)
+		rc, err := attemptJobLogs(ctx, logger, hc, gh, owner, repo, runID, attempt, token, nil, opts.fallbackConcurrency())
+		if err != nil {
+			return nil, err
+		}
+		return perJobFallbackLogs{rc}, nil
+
+	case err != nil:
+		return nil, fmt.Errorf("executing logs request: %w", err)
+
+	default:
+		return nil, fmt.Errorf("logs API returned no URL and no error")
+	}
+}
+
+// attemptJobLogs downloads the logs of attempt's jobs through the
+// per-job endpoint, only those matching filter when it is non-nil, and
+// combines them with combineLogs.
+func attemptJobLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID, attempt int64, token string, filter *JobFilter, limit int) (io.ReadCloser, error) {
+	jobs, err := listAttemptJobs(ctx, gh, owner, repo, runID, attempt, maxWorkflowListPages)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs of attempt %d: %w", attempt, err)
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("run %d attempt %d: %w", runID, attempt, ErrRunHasNoLogs)
+	}
+	if filter != nil {
+		jobs = slices.DeleteFunc(jobs, func(job *github.WorkflowJob) bool { return !filter.Match(job) })
+		if len(jobs) == 0 {
+			logger.Infof("No job of attempt %d of run %d matches the job filter; skipping", attempt, runID)
+			return nil, fmt.Errorf("run %d attempt %d: %w", runID, attempt, ErrNoMatchingJobs)
+		}
+	}
+
+	logs, err := downloadJobLogs(ctx, logger, hc, gh, owner, repo, jobs, token, limit)
+	if err != nil {
+		return nil, fmt.Errorf("fetching per-job logs: %w", err)
+	}
+	combined, err := combineLogs(ctx, logs)
+	if err != nil {
+		return nil, fmt.Errorf("combining logs: %w", err)
+	}
+	return perJobLogs{combined}, nil
+}
+
+// listAttemptJobs returns every job of one attempt of runID, at most
+// maxPages pages of them.
+func listAttemptJobs(ctx context.Context, gh *github.Client, owner, repo string, runID, attempt int64, maxPages int) ([]*github.WorkflowJob, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var all []*github.WorkflowJob
+	err := paginate(maxPages, "workflow attempt jobs", func(page int) (int, error) {
+		opts.Page = page
+		out, resp, err := gh.Actions.ListWorkflowJobsAttempt(ctx, owner, repo, runID, attempt, opts)
+		if err != nil {
+			return 0, err
+		}
+		if out != nil {
+			all = append(all, out.Jobs...)
+		}
+		if resp == nil {
+			return 0, nil
+		}
+		return resp.NextPage, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
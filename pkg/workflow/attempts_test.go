@@ -0,0 +1,81 @@
+package workflow_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestGetAttemptLogs asserts an earlier attempt's archive is read
+// through the per-attempt endpoint, an attempt whose archive is gone
+// falls back to that attempt's jobs, and an attempt without jobs has
+// no logs.
+func TestGetAttemptLogs(t *testing.T) {
+	t.Parallel()
+
+	archive := runArchive(t)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/actions/runs/7/attempts/1/logs":
+			w.Header().Set("Location", server.URL+"/raw/attempt-1.zip")
+			w.WriteHeader(http.StatusFound)
+		case "/repos/o/r/actions/runs/7/attempts/2/logs", "/repos/o/r/actions/runs/7/attempts/3/logs":
+			w.WriteHeader(http.StatusGone)
+		case "/repos/o/r/actions/runs/7/attempts/2/jobs":
+			_, _ = io.WriteString(w, `{"total_count":1,"jobs":[{"id":33,"name":"build"}]}`)
+		case "/repos/o/r/actions/runs/7/attempts/3/jobs":
+			_, _ = io.WriteString(w, `{"total_count":0,"jobs":[]}`)
+		case "/repos/o/r/actions/jobs/33/logs":
+			w.Header().Set("Location", server.URL+"/raw/job-33.txt")
+			w.WriteHeader(http.StatusFound)
+		case "/raw/attempt-1.zip":
+			_, _ = w.Write(archive)
+		case "/raw/job-33.txt":
+			_, _ = io.WriteString(w, "attempt-2-log-line")
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	gh, hc := newTestClients(t, server)
+
+	rc, err := workflow.GetAttemptLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, 1, "tok", nil)
+	if err != nil {
+		t.Fatalf("GetAttemptLogs(attempt 1): %v", err)
+	}
+	text, err := workflow.ExtractLogs(rc)
+	_ = rc.Close()
+	if err != nil {
+		t.Fatalf("ExtractLogs: %v", err)
+	}
+	if !strings.Contains(text, "deploy-full-log") {
+		t.Errorf("attempt 1 logs lack the archive's content:\n%s", text)
+	}
+
+	rc, err = workflow.GetAttemptLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, 2, "tok", nil)
+	if err != nil {
+		t.Fatalf("GetAttemptLogs(attempt 2): %v", err)
+	}
+	if !workflow.IsPerJobFallback(rc) {
+		t.Error("attempt 2 logs lack the fallback marker")
+	}
+	text, err = workflow.ExtractLogs(rc)
+	_ = rc.Close()
+	if err != nil {
+		t.Fatalf("ExtractLogs: %v", err)
+	}
+	if !strings.Contains(text, "===== JOB ID: 33 =====") || !strings.Contains(text, "attempt-2-log-line") {
+		t.Errorf("attempt 2 logs lack its job:\n%s", text)
+	}
+
+	if _, err := workflow.GetAttemptLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, 3, "tok", nil); !errors.Is(err, workflow.ErrRunHasNoLogs) {
+		t.Errorf("GetAttemptLogs(attempt 3) err = %v, want ErrRunHasNoLogs", err)
+	}
+}
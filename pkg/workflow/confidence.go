@@ -0,0 +1,93 @@
+package workflow
+
+// Confidence scores range from 0 to 100 and estimate how likely a
+// finding is to be a real compromise rather than noise, so findings can
+// be triaged, or filtered, independently of their severity. Each
+// detector scores its findings as follows:
+//
+//   - ioc: 95. The line contains a literal IOC content string, such as
+//     a compromised commit digest, which benign output almost never
+//     reproduces.
+//   - pem: 90. A complete private key block was printed.
+//   - mask-bypass: 75. A masked value was re-encoded or split to slip
+//     past GitHub's secret masking; build tooling rarely does this.
+//   - cache-poisoning: 50. Cache manipulation that is also consistent
+//     with unusual but legitimate cache use.
+//   - network-egress and suspicious-git: 40. Traffic or pushes to
+//     destinations outside the allowlist, which are often just hosts
+//     the allowlist has not caught up with.
+//   - base64: 40 for one decoded layer, 70 when the payload was
+//     encoded more than once (deliberate obfuscation, as in the
+//     tj-actions/changed-files payload), 15 lower when the decoded
+//     bytes are not text, and 15 lower again for a blob longer than
+//     longBase64Bytes, which is usually a bundled artifact or
+//     certificate rather than dumped secrets.
+//   - base85: 45, and base32: 35. The payload decoded to printable
+//     text, but base32's alphabet also matches uppercase identifiers.
+//   - any other registered detector: 50.
+//
+// Findings from outside the logs are scored by where they were found:
+// a workflow's uses: of a compromised action ref is 90, the same
+// reference inside a composite action it calls is 85, and a commit in
+// the workflow history that introduced one, which may since have been
+// reverted, is 80, and a tag pin that resolves today to a known-bad
+// commit is 95, like a literal IOC match.
+const (
+	ConfidenceIOC            = 95
+	ConfidencePEM            = 90
+	ConfidenceMaskBypass     = 75
+	ConfidenceCachePoisoning = 50
+	ConfidenceEgress         = 40
+	ConfidenceSuspiciousGit  = 40
+	ConfidenceBase64         = 40
+	ConfidenceNestedBase64   = 70
+	ConfidenceBase85         = 45
+	ConfidenceBase32         = 35
+	ConfidenceCustom         = 50
+
+	ConfidenceWorkflowReference = 90
+	ConfidenceActionReference   = 85
+	ConfidenceWorkflowChange    = 80
+	ConfidenceTagPin            = 95
+)
+
+// longBase64Bytes is the encoded length above which a base64 finding
+// loses confidence.
+const longBase64Bytes = 1024
+
+// findingConfidence returns the confidence score of f, reported by the
+// detector named detector.
+func findingConfidence(detector string, f Finding) int {
+	switch detector {
+	case DetectorIOC:
+		return ConfidenceIOC
+	case DetectorPEM:
+		return ConfidencePEM
+	case DetectorMaskBypass:
+		return ConfidenceMaskBypass
+	case DetectorCachePoisoning:
+		return ConfidenceCachePoisoning
+	case DetectorEgress:
+		return ConfidenceEgress
+	case DetectorSuspiciousGit:
+		return ConfidenceSuspiciousGit
+	case DetectorBase64:
+		score := ConfidenceBase64
+		if f.DecodeDepth > 1 {
+			score = ConfidenceNestedBase64
+		}
+		if f.Note == binaryDecodedNote {
+			score -= 15
+		}
+		if len(f.Encoded) > longBase64Bytes {
+			score -= 15
+		}
+		return score
+	case DetectorBase85:
+		return ConfidenceBase85
+	case DetectorBase32:
+		return ConfidenceBase32
+	default:
+		return ConfidenceCustom
+	}
+}
@@ -0,0 +1,62 @@
+package workflow_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestParseLogs_Confidence asserts each finding is scored by the
+// detector that reported it: literal IOC content above any base64
+// payload, nested encodings above a single layer, and long blobs below
+// short ones.
+func TestParseLogs_Confidence(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"0e58ed8671d6b60d0890c21b07f8835ace038e67"}, Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	enc := base64.StdEncoding.EncodeToString
+	single := enc([]byte("AWS_SECRET=abc123"))
+	nested := enc([]byte(enc([]byte("GITHUB_TOKEN=ghp_abc123"))))
+	long := enc([]byte(strings.Repeat("bundle ", 200)))
+	logs := strings.Join([]string{
+		"uses tj-actions/changed-files@0e58ed8671d6b60d0890c21b07f8835ace038e67",
+		"payload " + single,
+		"payload " + nested,
+		"payload " + long,
+	}, "\n")
+
+	findings, found := workflow.ParseLogs(newTestLogger(), logs, 1, custom)
+	if !found {
+		t.Fatal("no findings")
+	}
+	got := make(map[string]int)
+	for _, f := range findings {
+		switch {
+		case f.Encoded == "":
+			got["ioc"] = f.Confidence
+		case f.Encoded == single:
+			got["single"] = f.Confidence
+		case f.Encoded == nested:
+			got["nested"] = f.Confidence
+		case f.Encoded == long:
+			got["long"] = f.Confidence
+		}
+	}
+	want := map[string]int{
+		"ioc":    workflow.ConfidenceIOC,
+		"single": workflow.ConfidenceBase64,
+		"nested": workflow.ConfidenceNestedBase64,
+		"long":   workflow.ConfidenceBase64 - 15,
+	}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s confidence=%d, want %d (all: %v)", k, got[k], w, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package workflow
+
+import (
+	"strings"
+)
+
+const (
+	// MaxContextLines caps [Options.ContextLines] so a typo cannot
+	// make every finding carry thousands of lines.
+	MaxContextLines = 50
+	// maxContextLineBytes truncates each captured context line; the
+	// matching line itself is kept whole in LineData.
+	maxContextLineBytes = 4096
+)
+
+// contextWindow is the sliding window ParseReader keeps to fill in
+// findings' Context: the last n lines seen, and the findings still
+// owed lines that follow their match.
+type contextWindow struct {
+	n      int
+	before []string
+	// pending maps the index of a finding to the number of following
+	// lines it still needs.
+	pending map[int]int
+}
+
+func newContextWindow(n int) *contextWindow {
+	if n <= 0 {
+		return nil
+	}
+	return &contextWindow{n: n, before: make([]string, 0, n+1), pending: make(map[int]int)}
+}
+
+// line returns line as it appears in a Context: without its timestamp
+// and truncated to maxContextLineBytes. A nil window returns "" without
+// doing the work.
+func (w *contextWindow) line(line string) string {
+	if w == nil {
+		return ""
+	}
+	line = timestampRE.ReplaceAllString(line, "")
+	if len(line) > maxContextLineBytes {
+		line = line[:maxContextLineBytes] + "..."
+	}
+	return line
+}
+
+// follow appends line to the Context of every finding still owed
+// following lines. It is called for each line before that line's own
+// findings are recorded.
+func (w *contextWindow) follow(findings []Finding, line string) {
+	if w == nil {
+		return
+	}
+	for i, left := range w.pending {
+		findings[i].Context += "\n" + line
+		if left <= 1 {
+			delete(w.pending, i)
+		} else {
+			w.pending[i] = left - 1
+		}
+	}
+}
+
+// start sets the Context of the new finding at index i to the lines
+// before line and line itself, and marks it as owed the lines after.
+func (w *contextWindow) start(findings []Finding, i int, line string) {
+	if w == nil {
+		return
+	}
+	findings[i].Context = strings.Join(append(w.before[:len(w.before):len(w.before)], line), "\n")
+	w.pending[i] = w.n
+}
+
+// push records line as seen, keeping the last n.
+func (w *contextWindow) push(line string) {
+	if w == nil {
+		return
+	}
+	if len(w.before) == w.n {
+		copy(w.before, w.before[1:])
+		w.before = w.before[:w.n-1]
+	}
+	w.before = append(w.before, line)
+}
@@ -0,0 +1,86 @@
+package workflow_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestParseLogs_ContextLines asserts each finding records the lines
+// around its match, timestamps stripped, with fewer lines kept at the
+// edges of the log and overlapping windows filled independently.
+func TestParseLogs_ContextLines(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"HIT_ONE", "HIT_TWO", "HIT_END"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	logs := strings.Join([]string{
+		"2025-03-14T00:00:00.0000000Z HIT_ONE first",
+		"2025-03-14T00:00:01.0000000Z between",
+		"2025-03-14T00:00:02.0000000Z HIT_TWO second",
+		"2025-03-14T00:00:03.0000000Z before end",
+		"2025-03-14T00:00:04.0000000Z HIT_END last",
+	}, "\n")
+
+	findings, found := workflow.ParseLogsWithOptions(newTestLogger(), logs, 1, custom, &workflow.Options{ContextLines: 1})
+	if !found || len(findings) != 3 {
+		t.Fatalf("findings=%+v, want three", findings)
+	}
+	want := []string{
+		"HIT_ONE first\nbetween",
+		"between\nHIT_TWO second\nbefore end",
+		"before end\nHIT_END last",
+	}
+	for i, f := range findings {
+		if f.Context != want[i] {
+			t.Errorf("finding %d Context=%q, want %q", i, f.Context, want[i])
+		}
+	}
+}
+
+// TestOptions_ContextLines asserts the setting is clamped to
+// [0, MaxContextLines] and capture is off by default.
+func TestOptions_ContextLines(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"DROP_THIS"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	lines := make([]string, 0, 2*workflow.MaxContextLines+21)
+	for range workflow.MaxContextLines + 10 {
+		lines = append(lines, "filler")
+	}
+	lines = append(lines, "DROP_THIS here")
+	for range workflow.MaxContextLines + 10 {
+		lines = append(lines, "filler")
+	}
+	logs := strings.Join(lines, "\n")
+
+	for _, tc := range []struct {
+		name string
+		opts *workflow.Options
+		want int
+	}{
+		{name: "default", want: 0},
+		{name: "negative", opts: &workflow.Options{ContextLines: -3}, want: 0},
+		{name: "in range", opts: &workflow.Options{ContextLines: 5}, want: 11},
+		{name: "capped", opts: &workflow.Options{ContextLines: 1000}, want: 2*workflow.MaxContextLines + 1},
+	} {
+		findings, _ := workflow.ParseLogsWithOptions(newTestLogger(), logs, 1, custom, tc.opts)
+		if len(findings) != 1 {
+			t.Fatalf("%s: findings=%+v, want one", tc.name, findings)
+		}
+		got := 0
+		if findings[0].Context != "" {
+			got = strings.Count(findings[0].Context, "\n") + 1
+		}
+		if got != tc.want {
+			t.Errorf("%s: Context holds %d lines, want %d", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package workflow
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+)
+
+// Built-in detector names. They are reserved: RegisterDetector rejects
+// a custom detector that reuses one.
+const (
+	// DetectorIOC reports lines containing any literal IOC content.
+	DetectorIOC = "ioc"
+	// DetectorBase64 reports regex-captured base64 blocks that decode
+	// to valid UTF-8 (or any bytes, with Options.AllowBinaryDecoded),
+	// unwrapping nested layers up to Options.MaxDecodeDepth.
+	DetectorBase64 = "base64"
+	// DetectorPEM reports complete PEM private key and certificate
+	// blocks, which span many log lines.
+	DetectorPEM = "pem"
+)
+
+// DefaultMaxDecodeDepth is the number of nested base64 layers the
+// built-in base64 detector unwraps when [Options] set no other limit.
+const DefaultMaxDecodeDepth = 3
+
+// binaryDecodedNote is set as the Note of findings whose decoded
+// content was escaped by escapeBinary.
+const binaryDecodedNote = `decoded content is not valid UTF-8; bytes outside printable ASCII are shown as \xNN escapes`
+
+// escapeBinary renders b as printable ASCII: printable bytes are kept,
+// a backslash is doubled, tab, newline and carriage return become \t,
+// \n and \r, and every other byte becomes \xNN.
+func escapeBinary(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		switch {
+		case c == '\\':
+			sb.WriteString(`\\`)
+		case c == '\t':
+			sb.WriteString(`\t`)
+		case c == '\n':
+			sb.WriteString(`\n`)
+		case c == '\r':
+			sb.WriteString(`\r`)
+		case c >= 0x20 && c < 0x7f:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, `\x%02x`, c)
+		}
+	}
+	return sb.String()
+}
+
+// LineContext carries the per-line state a [Detector] may consult. It
+// is passed by value so detectors cannot mutate the scan loop.
+type LineContext struct {
+	// RunID identifies the workflow run the line came from.
+	RunID int64
+	// LineNum is the 1-based line number within the extracted log.
+	LineNum int
+	// IOC is the indicator configured for this scan. Never nil.
+	IOC *ioc.IOC
+	// Logger is the scan logger, for detectors that report progress.
+	Logger *clog.Logger
+	// Options are the options the log is scanned with; nil selects
+	// the defaults.
+	Options *Options
+}
+
+// Detector inspects a single log line and returns any findings. Only
+// the Encoded, Decoded, DecodeDepth, LineData, KeyType, Severity,
+// Destination, and Note fields of each returned [Finding] are
+// aggregated by [ParseLogs]; Note is kept from the first occurrence.
+// Implementations must be safe for concurrent use because runs are
+// scanned in parallel, unless they also implement [ScopedDetector].
+type Detector interface {
+	Detect(line string, lc LineContext) []Finding
+}
+
+// ScopedDetector is implemented by detectors that carry state from one
+// line to the next, such as multi-line block matchers. ParseLogs calls
+// NewScan once per log and feeds every line of that log, in order, to
+// the returned Detector, which therefore needs no locking.
+type ScopedDetector interface {
+	Detector
+	NewScan() Detector
+}
+
+// DetectorFunc adapts an ordinary function to the [Detector] interface.
+type DetectorFunc func(line string, lc LineContext) []Finding
+
+// Detect calls f(line, lc).
+func (f DetectorFunc) Detect(line string, lc LineContext) []Finding {
+	return f(line, lc)
+}
+
+type namedDetector struct {
+	name     string
+	detector Detector
+}
+
+// detectorsMu guards registered. The built-in detectors are not stored
+// here so they can never be removed or reordered.
+var (
+	detectorsMu sync.RWMutex
+	registered  []namedDetector
+)
+
+// builtinDetectors runs ahead of every registered detector, in this
+// order, so the default ParseLogs output is unchanged by the registry.
+var builtinDetectors = []namedDetector{
+	{name: DetectorIOC, detector: DetectorFunc(detectIOC)},
+	{name: DetectorBase64, detector: DetectorFunc(detectBase64)},
+	{name: DetectorPEM, detector: &pemDetector{}},
+}
+
+// RegisterDetector adds d to the detectors run by [ParseLogs] on every
+// line, after the built-ins and in registration order. name must be
+// non-empty and unique; it appears in diagnostics and lets callers
+// refer to the detector later. Registration is global and is intended
+// to happen once at program start, before scanning begins.
+func RegisterDetector(name string, d Detector) error {
+	if name == "" {
+		return fmt.Errorf("detector name must not be empty")
+	}
+	if d == nil {
+		return fmt.Errorf("detector %q must not be nil", name)
+	}
+
+	detectorsMu.Lock()
+	defer detectorsMu.Unlock()
+	for _, nd := range builtinDetectors {
+		if nd.name == name {
+			return fmt.Errorf("detector %q is built in", name)
+		}
+	}
+	for _, nd := range registered {
+		if nd.name == name {
+			return fmt.Errorf("detector %q is already registered", name)
+		}
+	}
+	registered = append(registered, namedDetector{name: name, detector: d})
+	return nil
+}
+
+// detectorNames returns the names of the built-in and registered
+// detectors, in the order they run.
+func detectorNames() []string {
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+	names := make([]string, 0, len(builtinDetectors)+len(registered))
+	for _, nd := range slices.Concat(builtinDetectors, registered) {
+		names = append(names, nd.name)
+	}
+	return names
+}
+
+// scanDetectors returns the detectors active with o, with every
+// ScopedDetector replaced by a fresh per-scan instance.
+func (o *Options) scanDetectors() []namedDetector {
+	active := o.activeDetectors()
+	for i, nd := range active {
+		if sd, ok := nd.detector.(ScopedDetector); ok {
+			active[i].detector = sd.NewScan()
+		}
+	}
+	return active
+}
+
+// activeDetectors snapshots the built-in and registered detectors that
+// pass o's detector filter, so a ParseLogs call observes a stable list
+// even if registration races it.
+func (o *Options) activeDetectors() []namedDetector {
+	detectorsMu.RLock()
+	defer detectorsMu.RUnlock()
+	out := make([]namedDetector, 0, len(builtinDetectors)+len(registered))
+	for _, nd := range slices.Concat(builtinDetectors, registered) {
+		if o.detectorAllowed(nd.name) {
+			out = append(out, nd)
+		}
+	}
+	return out
+}
+
+// detectIOC is the built-in literal-content detector. It reports the
+// matched line with GitHub's timestamp prefix stripped, and the first
+// content string found in it with its offset.
+func detectIOC(line string, lc LineContext) []Finding {
+	if len(lc.IOC.GetContent()) == 0 {
+		return nil
+	}
+
+	// The IOC carries a precomputed bloom-prefiltered Matcher built once
+	// at construction. The bloom prefilter rejects lines that contain no
+	// n-gram from any IOC -- the common case for log scanning at
+	// internet scale -- without invoking the deterministic backend. The
+	// string-input variant skips the []byte(line) conversion that the
+	// generic Match() entrypoint would otherwise force.
+	matcher := lc.IOC.GetMatcher()
+	if matcher == nil || !matcher.MatchAnyString(line) {
+		return nil
+	}
+
+	lc.Logger.Warnf("IOC log entry found in Run ID: %d", lc.RunID)
+	clean := timestampRE.ReplaceAllString(line, "")
+	f := Finding{LineData: clean}
+	first := -1
+	for _, c := range lc.IOC.GetContent() {
+		if c == "" {
+			continue
+		}
+		if i := strings.Index(clean, c); i >= 0 && (first < 0 || i < first) {
+			first, f.MatchedPattern = i, c
+		}
+	}
+	f.MatchOffset = max(first, 0)
+	return []Finding{f}
+}
+
+// detectBase64 is the built-in encoded-payload detector. It applies the
+// IOC's regex and reports every capture group that decodes as base64.
+// Lines the IOC's cheap pre-filter rules out, the bulk of any log,
+// never reach the submatch search.
+func detectBase64(line string, lc LineContext) []Finding {
+	regex := lc.IOC.GetRegex()
+	if regex == nil || !lc.IOC.MayMatchRegex(line) {
+		return nil
+	}
+	return processMatch(line, regex, lc)
+}
+
+func processMatch(line string, regex *regexp.Regexp, lc LineContext) []Finding {
+	var out []Finding
+	limit := lc.Options.maxDecodeDepth()
+	allowBinary := lc.Options.allowBinaryDecoded()
+	clean := timestampRE.ReplaceAllString(line, "")
+	// prefix is the length of the stripped timestamp, so offsets in
+	// line translate to offsets in the reported LineData.
+	prefix := len(line) - len(clean)
+	for _, loc := range regex.FindAllStringSubmatchIndex(line, -1) {
+		if len(loc) <= 2 {
+			continue
+		}
+
+		// An unmatched group reports -1 and, as before, an empty
+		// encoded string; its offset falls back to the whole match.
+		encoded, start := "", loc[0]
+		if loc[2] >= 0 {
+			encoded, start = line[loc[2]:loc[3]], loc[2]
+		}
+		decoded, depth, binary := decodeLayers(encoded, limit, allowBinary)
+		if depth == 0 {
+			continue
+		}
+		note := ""
+		if binary {
+			note = binaryDecodedNote
+		}
+
+		if depth > 1 {
+			lc.Logger.Warnf("Found %d-layer base64-encoded content at log line %d in Run ID: %d", depth, lc.LineNum, lc.RunID)
+		} else {
+			lc.Logger.Infof("Found valid base64-encoded content at log line %d in Run ID: %d", lc.LineNum, lc.RunID)
+		}
+		out = append(out, Finding{
+			Encoded:        encoded,
+			Decoded:        decoded,
+			DecodeDepth:    depth,
+			LineData:       clean,
+			Note:           note,
+			MatchedPattern: regex.String(),
+			MatchOffset:    max(start-prefix, 0),
+		})
+	}
+	return out
+}
+
+// decodeLayers repeatedly base64-decodes s while each layer yields
+// valid UTF-8, stopping after limit layers. It returns the innermost
+// plaintext reached and the number of layers removed; a depth of zero
+// means s itself was not valid base64.
+//
+// With allowBinary, a first layer that is not valid UTF-8 is returned
+// escaped by escapeBinary with binary set. Deeper layers stay
+// UTF-8 only: plaintext such as "password1" is itself valid base64,
+// and decoding it once more would report noise.
+func decodeLayers(s string, limit int, allowBinary bool) (decoded string, depth int, binary bool) {
+	for depth < limit {
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			break
+		}
+		if !utf8.Valid(raw) {
+			if depth == 0 && allowBinary {
+				return escapeBinary(raw), 1, true
+			}
+			break
+		}
+		s = string(raw)
+		depth++
+	}
+	return s, depth, false
+}
@@ -0,0 +1,285 @@
+package workflow_test
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+func TestDetectors_BuiltinsFirst(t *testing.T) {
+	t.Parallel()
+
+	got := new(workflow.Options).Detectors()
+	if len(got) < 2 || got[0] != workflow.DetectorIOC || got[1] != workflow.DetectorBase64 {
+		t.Fatalf("Detectors()=%v, want built-ins [ioc base64] first", got)
+	}
+}
+
+func TestRegisterDetector_Validation(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	noop := workflow.DetectorFunc(func(string, workflow.LineContext) []workflow.Finding { return nil })
+
+	cases := []struct {
+		name    string
+		detName string
+		det     workflow.Detector
+		wantErr string
+	}{
+		{name: "empty name", detName: "", det: noop, wantErr: "must not be empty"},
+		{name: "nil detector", detName: "x", det: nil, wantErr: "must not be nil"},
+		{name: "built-in name", detName: workflow.DetectorIOC, det: noop, wantErr: "built in"},
+		{name: "first registration", detName: "custom", det: noop},
+		{name: "duplicate registration", detName: "custom", det: noop, wantErr: "already registered"},
+	}
+	for _, tc := range cases {
+		err := workflow.RegisterDetector(tc.detName, tc.det)
+		switch {
+		case tc.wantErr == "" && err != nil:
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		case tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)):
+			t.Fatalf("%s: err=%v, want substring %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+// TestParseLogs_RunsRegisteredDetector asserts a registered detector
+// sees every line with its 1-based line number and that its findings
+// are reported alongside the built-in IOC hit.
+func TestParseLogs_RunsRegisteredDetector(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	var seen []int
+	err := workflow.RegisterDetector("keyword", workflow.DetectorFunc(func(line string, lc workflow.LineContext) []workflow.Finding {
+		seen = append(seen, lc.LineNum)
+		if strings.Contains(line, "SUSPICIOUS") {
+			return []workflow.Finding{{LineData: "custom:" + line}}
+		}
+		return nil
+	}))
+	if err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	if got := new(workflow.Options).Detectors(); !slices.Contains(got, "keyword") {
+		t.Fatalf("Detectors()=%v, want keyword registered", got)
+	}
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	findings, _ := workflow.ParseLogs(newTestLogger(), "DROP_THIS_TOKEN\nbenign\nSUSPICIOUS\n", 1, custom)
+	if !slices.Equal(seen, []int{1, 2, 3}) {
+		t.Fatalf("detector saw lines %v, want [1 2 3]", seen)
+	}
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, f.LineData)
+	}
+	if !slices.Equal(lines, []string{"DROP_THIS_TOKEN", "custom:SUSPICIOUS"}) {
+		t.Fatalf("LineData=%q, want built-in hit then custom hit", lines)
+	}
+}
+
+// TestOptions_DetectorFilter asserts the enable and disable lists
+// narrow the detectors ParseLogs runs, and that Validate rejects
+// unknown names or a filter leaving nothing to run.
+func TestOptions_DetectorFilter(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	if err := workflow.RegisterDetector(workflow.DetectorEgress, workflow.NewEgressDetector(nil)); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	all := new(workflow.Options).Detectors()
+
+	cases := []struct {
+		name    string
+		enable  []string
+		disable []string
+		want    []string
+		wantErr string
+	}{
+		{name: "disable built-in", disable: []string{workflow.DetectorIOC}, want: []string{workflow.DetectorBase64, workflow.DetectorPEM, workflow.DetectorEgress}},
+		{name: "enable only", enable: []string{workflow.DetectorEgress, workflow.DetectorBase64}, want: []string{workflow.DetectorBase64, workflow.DetectorEgress}},
+		{name: "enable less disable", enable: []string{workflow.DetectorIOC, workflow.DetectorPEM}, disable: []string{workflow.DetectorPEM}, want: []string{workflow.DetectorIOC}},
+		{name: "unknown name", disable: []string{"entropy"}, wantErr: `unknown detector "entropy"`},
+		{name: "unregistered opt-in", enable: []string{workflow.DetectorMaskBypass}, wantErr: "unknown detector"},
+		{name: "nothing left", enable: []string{workflow.DetectorIOC}, disable: []string{workflow.DetectorIOC}, wantErr: "no detector"},
+		{name: "empty restores all", want: all},
+	}
+	for _, tc := range cases {
+		opts := &workflow.Options{EnableDetectors: tc.enable, DisableDetectors: tc.disable}
+		err := opts.Validate()
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("%s: err=%v, want substring %q", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if got := opts.Detectors(); !slices.Equal(got, tc.want) {
+			t.Fatalf("%s: Detectors()=%v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestParseLogs_DisabledDetectorSkipped asserts a disabled built-in
+// reports nothing through ParseLogs while the others still run.
+func TestParseLogs_DisabledDetectorSkipped(t *testing.T) {
+	t.Parallel()
+
+	opts := &workflow.Options{DisableDetectors: []string{workflow.DetectorIOC}}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"DROP_THIS_TOKEN"}, Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	findings, _ := workflow.ParseLogsWithOptions(newTestLogger(), "DROP_THIS_TOKEN\nrun cGFzc3dvcmQ=\n", 1, custom, opts)
+	if len(findings) != 1 || findings[0].Encoded != "cGFzc3dvcmQ=" {
+		t.Fatalf("findings=%+v, want only the base64 finding", findings)
+	}
+}
+
+// TestParseLogs_Base64DetectorUnwrapsDoubleEncoding pins the built-in
+// base64 detector's behavior now that it runs behind the Detector
+// interface: the encoded block is reported verbatim and the decoded
+// value is unwrapped through the second layer.
+func TestParseLogs_Base64DetectorUnwrapsDoubleEncoding(t *testing.T) {
+	t.Parallel()
+
+	// base64(base64("secret-value")).
+	const encoded = "YzJWamNtVjBMWFpoYkhWbA=="
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	findings, _ := workflow.ParseLogs(newTestLogger(), "leak "+encoded+"\n", 1, custom)
+	if findings[0].Encoded != encoded {
+		t.Fatalf("Encoded=%q, want %q", findings[0].Encoded, encoded)
+	}
+	if findings[0].Decoded != "secret-value" {
+		t.Fatalf("Decoded=%q, want secret-value", findings[0].Decoded)
+	}
+}
+
+// TestParseLogs_Base64DecodeDepth pins the iterative unwrap: each
+// layer is removed until the limit is hit or a layer stops decoding,
+// and the deepest layer reached is recorded on the finding.
+func TestParseLogs_Base64DecodeDepth(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	// base64^3("secret-value").
+	const triple = "WXpKV2FtTnRWakJNV0Zwb1lraFdiQT09"
+	cases := []struct {
+		name        string
+		limit       int
+		wantDecoded string
+		wantDepth   int
+	}{
+		{name: "default unwraps three layers", limit: 0, wantDecoded: "secret-value", wantDepth: 3},
+		{name: "limit two stops early", limit: 2, wantDecoded: "c2VjcmV0LXZhbHVl", wantDepth: 2},
+		{name: "limit above nesting stops at plaintext", limit: 5, wantDecoded: "secret-value", wantDepth: 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			findings, _ := workflow.ParseLogsWithOptions(newTestLogger(), "leak "+triple+"\n", 1, custom, &workflow.Options{MaxDecodeDepth: tc.limit})
+			if findings[0].Decoded != tc.wantDecoded {
+				t.Fatalf("Decoded=%q, want %q", findings[0].Decoded, tc.wantDecoded)
+			}
+			if findings[0].DecodeDepth != tc.wantDepth {
+				t.Fatalf("DecodeDepth=%d, want %d", findings[0].DecodeDepth, tc.wantDepth)
+			}
+		})
+	}
+}
+
+// TestParseLogs_AllowBinaryDecoded pins the non-UTF-8 handling: such
+// a block is dropped by default, and with the option it is reported
+// with its bytes escaped and a Note saying so.
+func TestParseLogs_AllowBinaryDecoded(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	// base64("caf\xe9\x00MZ\\\n"): Latin-1, a NUL, and a backslash.
+	encoded := base64.StdEncoding.EncodeToString([]byte("caf\xe9\x00MZ\\\n"))
+	line := "leak " + encoded + "\n"
+
+	if findings, found := workflow.ParseLogs(newTestLogger(), line, 1, custom); found {
+		t.Fatalf("findings=%+v, want none without the option", findings)
+	}
+
+	opts := &workflow.Options{AllowBinaryDecoded: true}
+	findings, _ := workflow.ParseLogsWithOptions(newTestLogger(), line, 1, custom, opts)
+	if len(findings) != 1 {
+		t.Fatalf("findings=%+v, want one", findings)
+	}
+	if want := `caf\xe9\x00MZ\\\n`; findings[0].Decoded != want {
+		t.Fatalf("Decoded=%q, want %q", findings[0].Decoded, want)
+	}
+	if findings[0].DecodeDepth != 1 || !strings.Contains(findings[0].Note, "not valid UTF-8") {
+		t.Fatalf("finding=%+v, want depth 1 and a Note about the escaping", findings[0])
+	}
+
+	// A UTF-8 layer whose plaintext is itself valid base64 stops there.
+	// base64("password") -> "password", which decodes to binary.
+	findings, _ = workflow.ParseLogsWithOptions(newTestLogger(), "leak cGFzc3dvcmQ=\n", 1, custom, opts)
+	if len(findings) != 1 || findings[0].Decoded != "password" || findings[0].Note != "" {
+		t.Fatalf("findings=%+v, want plain password at depth 1", findings)
+	}
+}
+
+// TestParseLogs_MatchPatternAndOffset asserts the IOC detectors record
+// which content string or regex matched and the byte offset of the
+// match in the timestamp-stripped LineData.
+func TestParseLogs_MatchPatternAndOffset(t *testing.T) {
+	t.Parallel()
+
+	const pattern = `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"NEVER_PRESENT", "DROP_THIS"}, Pattern: pattern})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	logs := "2025-03-14T00:00:00.0000000Z step: DROP_THIS here\n" +
+		"2025-03-14T00:00:00.0000000Z a b cGFzc3dvcmQ= and cGFzc3dvcmQy\n"
+	findings, _ := workflow.ParseLogs(newTestLogger(), logs, 1, custom)
+
+	type match struct {
+		pattern string
+		offset  int
+	}
+	got := make(map[string]match, len(findings))
+	for _, f := range findings {
+		got[f.Encoded+"|"+f.LineData] = match{f.MatchedPattern, f.MatchOffset}
+	}
+	want := map[string]match{
+		"|step: DROP_THIS here":                          {"DROP_THIS", 6},
+		"cGFzc3dvcmQ=|a b cGFzc3dvcmQ= and cGFzc3dvcmQy": {pattern, 4},
+		"cGFzc3dvcmQy|a b cGFzc3dvcmQ= and cGFzc3dvcmQy": {pattern, 21},
+	}
+	for k, w := range want {
+		if g, ok := got[k]; !ok || g != w {
+			t.Errorf("finding %q = %+v (present=%v), want %+v; all: %+v", k, g, ok, w, findings)
+		}
+	}
+}
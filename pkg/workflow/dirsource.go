@@ -0,0 +1,154 @@
+package workflow
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultLogExtensions are the file extensions [DirSource] scans when
+// none are configured: plain-text logs and GitHub's log archives.
+var DefaultLogExtensions = []string{".txt", ".log", ".zip"}
+
+// binarySniffLen is how much of a file [DirSource] inspects to decide
+// whether it is binary, the same window git uses.
+const binarySniffLen = 8000
+
+// DirSource is the [LogSource] for logs already on disk, such as run
+// archives downloaded from the GitHub UI or extracted from them. Every
+// file under Dir with one of Extensions is one run, identified by its
+// position in path order and named by its path relative to Dir. A .zip
+// file is read as a run log archive with [ExtractLogs]; any other file
+// is scanned as text. Binary files are skipped with [ErrRunHasNoLogs].
+type DirSource struct {
+	// Dir is the directory holding the logs.
+	Dir string
+	// Extensions are the file extensions to scan, with or without the
+	// leading dot and in any case; empty means [DefaultLogExtensions].
+	Extensions []string
+	// Recursive descends into subdirectories, where extracted GitHub
+	// logs keep each job's step files.
+	Recursive bool
+}
+
+var _ LogSource = (*DirSource)(nil)
+
+// ListRuns lists the matching files under Dir. The files carry no run
+// times, so every file is listed whatever the window; CreatedAt is the
+// file's modification time.
+func (s *DirSource) ListRuns(ctx context.Context, _, _ time.Time) ([]Run, error) {
+	exts := s.extensions()
+	var runs []Run
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != s.Dir && !s.Recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !slices.Contains(exts, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.Dir, path)
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, Run{
+			ID:        int64(len(runs) + 1),
+			Name:      filepath.ToSlash(rel),
+			URL:       "file://" + filepath.ToSlash(abs),
+			Status:    "completed",
+			CreatedAt: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing logs in %s: %w", s.Dir, err)
+	}
+	return runs, nil
+}
+
+// GetRunLogs returns the text of run's file, extracting it first when
+// it is a log archive. A binary file, or a .zip that is not an
+// archive, is reported with [ErrRunHasNoLogs].
+func (s *DirSource) GetRunLogs(ctx context.Context, run Run) (io.ReadCloser, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(run.Name))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening log %s: %w", run.Name, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		defer func() { _ = f.Close() }()
+		text, err := ExtractLogsContext(ctx, f)
+		if errors.Is(err, zip.ErrFormat) {
+			return nil, fmt.Errorf("%w: %s is not a log archive", ErrRunHasNoLogs, run.Name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("extracting logs from %s: %w", run.Name, err)
+		}
+		return io.NopCloser(strings.NewReader(text)), nil
+	}
+
+	head := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		_ = f.Close()
+		return nil, fmt.Errorf("reading log %s: %w", run.Name, err)
+	}
+	if bytes.IndexByte(head[:n], 0) >= 0 {
+		_ = f.Close()
+		return nil, fmt.Errorf("%w: %s is a binary file", ErrRunHasNoLogs, run.Name)
+	}
+	return readCloser{Reader: io.MultiReader(bytes.NewReader(head[:n]), f), Closer: f}, nil
+}
+
+// extensions returns the configured extensions, lower-cased and
+// dotted.
+func (s *DirSource) extensions() []string {
+	configured := s.Extensions
+	if len(configured) == 0 {
+		configured = DefaultLogExtensions
+	}
+	exts := make([]string, 0, len(configured))
+	for _, e := range configured {
+		e = strings.ToLower(strings.TrimSpace(e))
+		if e == "" {
+			continue
+		}
+		if !strings.HasPrefix(e, ".") {
+			e = "." + e
+		}
+		exts = append(exts, e)
+	}
+	return exts
+}
+
+// readCloser pairs a reader with the file it drains.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,95 @@
+package workflow_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestDirSource lists the logs of an extracted GitHub run, with its
+// numeric-prefixed step files in nested job folders, beside a run
+// archive, and checks the extension and recursion settings and that
+// binary files are skipped rather than scanned.
+func TestDirSource(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, body := range map[string][]byte{
+		"0_build.txt":          []byte("build-full-log\n"),
+		"build/1_Set up.txt":   []byte("build-step-log\n"),
+		"build/2_Upload.LOG":   []byte("upload-step-log\n"),
+		"run.zip":              runArchive(t),
+		"event.json":           []byte(`{"action":"completed"}`),
+		"artifact/blob.txt":    {'E', 'L', 'F', 0, 1, 2},
+		"notes/not-a-zip.zip":  []byte("plain text"),
+		"notes/readme.md":      []byte("skipped"),
+		"nested/deep/3_x.txt":  []byte("deep-step-log\n"),
+		"nested/deep/4_y.json": []byte("{}"),
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, body, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(src *workflow.DirSource) []string {
+		t.Helper()
+		runs, err := src.ListRuns(t.Context(), time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("ListRuns() error = %v", err)
+		}
+		var out []string
+		for i, r := range runs {
+			if r.ID != int64(i+1) {
+				t.Errorf("run %s has ID %d, want %d", r.Name, r.ID, i+1)
+			}
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	recursive := &workflow.DirSource{Dir: dir, Recursive: true}
+	want := []string{"0_build.txt", "artifact/blob.txt", "build/1_Set up.txt", "build/2_Upload.LOG", "nested/deep/3_x.txt", "notes/not-a-zip.zip", "run.zip"}
+	if got := names(recursive); !slices.Equal(got, want) {
+		t.Errorf("recursive ListRuns() = %q, want %q", got, want)
+	}
+	if got, want := names(&workflow.DirSource{Dir: dir}), []string{"0_build.txt", "run.zip"}; !slices.Equal(got, want) {
+		t.Errorf("non-recursive ListRuns() = %q, want %q", got, want)
+	}
+	if got, want := names(&workflow.DirSource{Dir: dir, Extensions: []string{"JSON", " .log"}, Recursive: true}),
+		[]string{"build/2_Upload.LOG", "event.json", "nested/deep/4_y.json"}; !slices.Equal(got, want) {
+		t.Errorf("ListRuns() with json and log extensions = %q, want %q", got, want)
+	}
+
+	read := func(name string) (string, error) {
+		t.Helper()
+		rc, err := recursive.GetRunLogs(t.Context(), workflow.Run{Name: name})
+		if err != nil {
+			return "", err
+		}
+		defer func() { _ = rc.Close() }()
+		b, err := io.ReadAll(rc)
+		return string(b), err
+	}
+	if got, err := read("build/1_Set up.txt"); err != nil || got != "build-step-log\n" {
+		t.Errorf("GetRunLogs(step file) = %q, %v", got, err)
+	}
+	if got, err := read("run.zip"); err != nil || !strings.Contains(got, "build-full-log") || !strings.Contains(got, "deploy-step-log") {
+		t.Errorf("GetRunLogs(archive) = %q, %v, want the archive's logs", got, err)
+	}
+	for _, name := range []string{"artifact/blob.txt", "notes/not-a-zip.zip"} {
+		if _, err := read(name); !errors.Is(err, workflow.ErrRunHasNoLogs) {
+			t.Errorf("GetRunLogs(%s) error = %v, want ErrRunHasNoLogs", name, err)
+		}
+	}
+}
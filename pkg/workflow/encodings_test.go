@@ -0,0 +1,113 @@
+package workflow_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// encodedSecret is the plaintext every positive case below encodes.
+const encodedSecret = "AWS_SECRET_ACCESS_KEY=wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+
+func TestBase32Detector(t *testing.T) {
+	t.Parallel()
+
+	const padded = "IFLVGX2TIVBVERKUL5AUGQ2FKNJV6S2FLE6XOSTBNRZFQVLUNZDEKTKJF5FTOTKEIVHEOL3CKB4FEZTJINMUKWCBJVIEYRKLIVMQ===="
+	cases := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{name: "padded", line: "2025-03-14T00:00:00.0000000Z " + padded, want: []string{padded}},
+		{name: "unpadded in quotes", line: `echo "` + padded[:len(padded)-4] + `"`, want: []string{padded[:len(padded)-4]}},
+		{name: "uppercase hex", line: "digest DEADBEEFCAFEBABEDEADBEEFCAFEBABEDEADBEEF", want: nil},
+		{name: "uppercase identifier", line: "export AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=1", want: nil},
+		{name: "part of a longer word", line: "x" + padded, want: nil},
+		{name: "too short", line: "JBSWY3DPEBLW64TMMQ======", want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			for _, f := range workflow.NewBase32Detector().Detect(tc.line, workflow.LineContext{Logger: newTestLogger()}) {
+				if f.KeyType != workflow.DetectorBase32 || f.Severity != workflow.SeverityMedium || f.Decoded != encodedSecret {
+					t.Fatalf("finding=%+v, want base32 at medium decoding to the secret", f)
+				}
+				got = append(got, f.Encoded)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("encoded=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBase85Detector(t *testing.T) {
+	t.Parallel()
+
+	const (
+		adobe   = "<~6!$ul;aj&O79!V[6UO:@;dW0d=Zpt$@;Ksd<HN+J786?,9/fR578??+@Sh;IAnaV879DiM:eX;N79K~>"
+		rfc1924 = "L03~>Q$<5kMO0rwLqkPVQ(sF(Sv_}3VQg|(RdjAfMNLUBOE*nKMNUUAVo-QeW@$rNMOZ;iP)tQjMOg"
+	)
+	cases := []struct {
+		name string
+		line string
+		want []string
+	}{
+		{name: "ascii85", line: "2025-03-14T00:00:00.0000000Z " + adobe, want: []string{adobe}},
+		{name: "rfc 1924", line: "payload: " + rfc1924, want: []string{rfc1924}},
+		{name: "commit digest", line: "HEAD is now at 0e58ed8671d6b60d0890c21b07f8835ace038e67", want: nil},
+		{name: "long identifier", line: "run TestVeryLongCamelCaseIdentifierNameForSomething", want: nil},
+		{name: "too short", line: "<~87cURD]i~>", want: nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var got []string
+			for _, f := range workflow.NewBase85Detector().Detect(tc.line, workflow.LineContext{Logger: newTestLogger()}) {
+				if f.KeyType != workflow.DetectorBase85 || f.Severity != workflow.SeverityMedium || f.Decoded != encodedSecret {
+					t.Fatalf("finding=%+v, want base85 at medium decoding to the secret", f)
+				}
+				got = append(got, f.Encoded)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("encoded=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestParseLogs_EncodingDetectorsRegistered asserts each encoding runs
+// only when its detector is registered, and is scored by it.
+func TestParseLogs_EncodingDetectorsRegistered(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"NEVER_PRESENT"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	const log = "IFLVGX2TIVBVERKUL5AUGQ2FKNJV6S2FLE6XOSTBNRZFQVLUNZDEKTKJF5FTOTKEIVHEOL3CKB4FEZTJINMUKWCBJVIEYRKLIVMQ====\n" +
+		"<~6!$ul;aj&O79!V[6UO:@;dW0d=Zpt$@;Ksd<HN+J786?,9/fR578??+@Sh;IAnaV879DiM:eX;N79K~>\n"
+
+	if _, found := workflow.ParseLogs(newTestLogger(), log, 1, custom); found {
+		t.Fatal("encodings reported without their detectors registered")
+	}
+
+	if err := workflow.RegisterDetector(workflow.DetectorBase85, workflow.NewBase85Detector()); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	findings, _ := workflow.ParseLogs(newTestLogger(), log, 1, custom)
+	if len(findings) != 1 || findings[0].KeyType != workflow.DetectorBase85 || findings[0].Confidence != workflow.ConfidenceBase85 {
+		t.Fatalf("findings=%+v, want one base85 finding", findings)
+	}
+
+	if err := workflow.RegisterDetector(workflow.DetectorBase32, workflow.NewBase32Detector()); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	findings, _ = workflow.ParseLogs(newTestLogger(), log, 1, custom)
+	if len(findings) != 2 || findings[0].KeyType != workflow.DetectorBase32 || findings[0].Confidence != workflow.ConfidenceBase32 {
+		t.Fatalf("findings=%+v, want base32 and base85 findings", findings)
+	}
+}
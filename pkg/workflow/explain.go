@@ -0,0 +1,36 @@
+package workflow
+
+import (
+	"fmt"
+)
+
+// explainFinding returns the explanation of f, reported by the
+// detector named detector at line lineNum.
+func explainFinding(detector string, f Finding, lineNum int) string {
+	switch detector {
+	case DetectorIOC:
+		if f.MatchedPattern == "" {
+			return fmt.Sprintf("matched IOC content at line %d", lineNum)
+		}
+		return fmt.Sprintf("matched IOC content %q at line %d", f.MatchedPattern, lineNum)
+	case DetectorBase64:
+		switch {
+		case f.Note == binaryDecodedNote:
+			return fmt.Sprintf("IOC pattern %s captured base64 that decoded to non-UTF-8 bytes at line %d", f.MatchedPattern, lineNum)
+		case f.DecodeDepth > 1:
+			return fmt.Sprintf("IOC pattern %s captured base64 that decoded through %d nested layers to valid UTF-8 at line %d", f.MatchedPattern, f.DecodeDepth, lineNum)
+		}
+		return fmt.Sprintf("IOC pattern %s captured base64 that decoded as valid UTF-8 at line %d", f.MatchedPattern, lineNum)
+	case DetectorPEM:
+		return fmt.Sprintf("complete PEM %s block ending at line %d", f.KeyType, lineNum)
+	case DetectorBase32, DetectorBase85:
+		return fmt.Sprintf("%s token decoded as printable text at line %d", detector, lineNum)
+	case DetectorEgress:
+		return fmt.Sprintf("network request to %s, outside the egress allowlist, at line %d", f.Destination, lineNum)
+	case DetectorSuspiciousGit, DetectorMaskBypass, DetectorCachePoisoning:
+		// These detectors already say in the note what they saw.
+		return fmt.Sprintf("%s detector at line %d: %s", detector, lineNum, f.Note)
+	default:
+		return fmt.Sprintf("reported by the %s detector at line %d", detector, lineNum)
+	}
+}
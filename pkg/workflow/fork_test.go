@@ -0,0 +1,89 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+func TestIsForkRun(t *testing.T) {
+	t.Parallel()
+
+	repo := func(name string) *github.Repository {
+		if name == "" {
+			return nil
+		}
+		return &github.Repository{FullName: new(name)}
+	}
+	tests := []struct {
+		name       string
+		head, base string
+		want       bool
+	}{
+		{name: "fork pull request", head: "mallory/demo", base: "octo/demo", want: true},
+		{name: "same repository", head: "octo/demo", base: "octo/demo"},
+		{name: "case differs only", head: "Octo/Demo", base: "octo/demo"},
+		{name: "no head repository", base: "octo/demo"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			run := &github.WorkflowRun{HeadRepository: repo(tc.head), Repository: repo(tc.base)}
+			if got := workflow.IsForkRun(run); got != tc.want {
+				t.Fatalf("IsForkRun(head=%q, base=%q)=%v, want %v", tc.head, tc.base, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestGetLogs_ForkLogsRestricted asserts a refused log request for a
+// fork run is a skip wrapping ErrRunHasNoLogs, while the same refusal
+// for a run of the repository itself stays a fetch failure.
+func TestGetLogs_ForkLogsRestricted(t *testing.T) {
+	t.Parallel()
+
+	for _, fork := range []bool{true, false} {
+		head := "octo/demo"
+		if fork {
+			head = "mallory/demo"
+		}
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/actions/runs/5/logs"):
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"Must have admin rights to Repository."}`))
+			case strings.HasSuffix(r.URL.Path, "/actions/runs/5"):
+				_ = json.NewEncoder(w).Encode(github.WorkflowRun{
+					ID:             new(int64(5)),
+					Status:         new("completed"),
+					Conclusion:     new("success"),
+					Event:          new("pull_request_target"),
+					HeadRepository: &github.Repository{FullName: new(head)},
+					Repository:     &github.Repository{FullName: new("octo/demo")},
+				})
+			default:
+				t.Errorf("unexpected path: %s", r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		gh, hc := newTestClients(t, ts)
+		_, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "octo", "demo", 5, "tok")
+		if got := errors.Is(err, workflow.ErrForkLogsRestricted); got != fork {
+			t.Fatalf("fork=%v: GetLogs error %v, want ErrForkLogsRestricted=%v", fork, err, fork)
+		}
+		if fork && !errors.Is(err, workflow.ErrRunHasNoLogs) {
+			t.Fatalf("ErrForkLogsRestricted does not wrap ErrRunHasNoLogs: %v", err)
+		}
+		if !fork && err == nil {
+			t.Fatal("a refused log request for a non-fork run succeeded")
+		}
+	}
+}
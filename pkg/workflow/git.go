@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DetectorSuspiciousGit names the git remote detector returned by
+// [NewGitRemoteDetector]. Like [DetectorEgress] it is opt-in.
+const DetectorSuspiciousGit = "suspicious-git"
+
+// DefaultGitRemoteAllowlist holds the git hosts a workflow is expected
+// to clone from or push to. A host matches an entry when it equals the
+// entry or is a subdomain of it.
+var DefaultGitRemoteAllowlist = []string{
+	"github.com",
+}
+
+// gitCommandRE finds a git invocation that names a remote, skipping
+// any global -C/-c options, and captures the subcommand and the rest
+// of the command line. git must start a word so that e.g. "legit" or
+// "git-lfs" do not match.
+var gitCommandRE = regexp.MustCompile(`(?:^|[\s;&|(` + "`" + `$])git(?:\.exe)?(?:\s+-[Cc]\s+\S+)*\s+(clone|remote\s+add|remote\s+set-url|push)\s+(.*)$`)
+
+// gitSCPRE matches git's scp-like remote syntax, [user@]host:path.
+var gitSCPRE = regexp.MustCompile(`^(?:[A-Za-z0-9._-]+@)?([A-Za-z0-9.-]+):\S`)
+
+// gitRemoteDetector flags git clones of, and pushes or remotes added
+// to, hosts outside its allowlist: fetching attacker code to run, or
+// pushing stolen material to an attacker's repository, leaves no
+// encoded payload for the base64 detector to find.
+type gitRemoteDetector struct {
+	allow []string
+}
+
+// NewGitRemoteDetector returns a [Detector] that reports log lines
+// running git clone, git remote add or set-url, or git push against a
+// remote whose host is not covered by allow. An empty allow selects
+// [DefaultGitRemoteAllowlist]. Remote names such as origin and local
+// paths are not reported. Each finding carries the stripped line, the
+// remote URL with any password redacted in Destination, KeyType
+// DetectorSuspiciousGit, and SeverityMedium.
+func NewGitRemoteDetector(allow []string) Detector {
+	if len(allow) == 0 {
+		allow = DefaultGitRemoteAllowlist
+	}
+	return &gitRemoteDetector{allow: normalizeHosts(allow)}
+}
+
+// Detect reports one finding per distinct unexpected remote on line.
+func (d *gitRemoteDetector) Detect(line string, lc LineContext) []Finding {
+	clean := timestampRE.ReplaceAllString(line, "")
+	m := gitCommandRE.FindStringSubmatch(clean)
+	if m == nil {
+		return nil
+	}
+	op := strings.Join(strings.Fields(m[1]), " ")
+
+	var out []Finding
+	var seen []string
+	for tok := range strings.FieldsSeq(m[2]) {
+		tok = strings.Trim(tok, `'"`)
+		if strings.ContainsAny(tok, "|;&>") {
+			break
+		}
+		remote, host := gitRemote(tok)
+		if host == "" || slices.Contains(seen, remote) || hostAllowed(d.allow, host) {
+			continue
+		}
+		seen = append(seen, remote)
+		lc.Logger.Warnf("Suspicious git %s to %s at log line %d in Run ID: %d", op, remote, lc.LineNum, lc.RunID)
+		out = append(out, Finding{
+			LineData:    strings.TrimSpace(clean),
+			Destination: remote,
+			KeyType:     DetectorSuspiciousGit,
+			Severity:    SeverityMedium,
+			Note:        "git " + op + " against a remote outside the allowlist",
+		})
+	}
+	return out
+}
+
+// gitRemote returns tok, with any password redacted, and its lower-cased
+// host when tok is a remote URL (scheme://host/path or
+// [user@]host:path), or "" and "" otherwise.
+func gitRemote(tok string) (string, string) {
+	if strings.Contains(tok, "://") {
+		u, err := url.Parse(tok)
+		if err != nil || u.Hostname() == "" || u.Scheme == "file" {
+			return "", ""
+		}
+		return u.Redacted(), strings.ToLower(u.Hostname())
+	}
+	if m := gitSCPRE.FindStringSubmatch(tok); m != nil {
+		if host := hostOf(m[1]); host != "" {
+			return tok, host
+		}
+	}
+	return "", ""
+}
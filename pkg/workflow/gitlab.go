@@ -0,0 +1,201 @@
+package workflow
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+)
+
+// DefaultGitLabURL is the base URL of gitlab.com.
+const DefaultGitLabURL = "https://gitlab.com"
+
+// gitlabPageSize is the largest per_page the GitLab API accepts.
+const gitlabPageSize = 100
+
+// gitlabActiveStatuses are the pipeline states whose job logs are not
+// yet final; such pipelines are skipped like in-progress GitHub runs.
+var gitlabActiveStatuses = []string{"created", "waiting_for_resource", "preparing", "pending", "running", "scheduled"}
+
+// GitLabSource is the [LogSource] for the CI pipelines of one GitLab
+// project. It reads the REST API (v4): the project's pipelines, the
+// jobs of each pipeline, and each job's trace.
+type GitLabSource struct {
+	Logger *clog.Logger
+	// HTTP performs the requests; nil means [http.DefaultClient].
+	HTTP *http.Client
+	// BaseURL is the GitLab instance, [DefaultGitLabURL] when empty.
+	BaseURL string
+	// Project is the project's full path (group/project) or ID.
+	Project string
+	// Token is sent as a PRIVATE-TOKEN and needs the read_api scope.
+	Token string
+}
+
+var _ LogSource = (*GitLabSource)(nil)
+
+type gitlabPipeline struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Ref       string    `json:"ref"`
+	Status    string    `json:"status"`
+	WebURL    string    `json:"web_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type gitlabJob struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// ListRuns lists the project's pipelines created between start and
+// end. GitLab filters pipelines by update time only, so the window is
+// applied to their creation time here, matching [ListWorkflowRuns].
+func (s *GitLabSource) ListRuns(ctx context.Context, start, end time.Time) ([]Run, error) {
+	q := url.Values{}
+	q.Set("updated_after", start.UTC().Format(time.RFC3339))
+	q.Set("order_by", "id")
+	q.Set("sort", "asc")
+
+	var runs []Run
+	err := s.paginate(ctx, "pipelines", q, func(body []byte) error {
+		var page []gitlabPipeline
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("decoding pipelines: %w", err)
+		}
+		for _, p := range page {
+			if p.CreatedAt.Before(start) || p.CreatedAt.After(end) {
+				continue
+			}
+			runs = append(runs, Run{
+				ID:        p.ID,
+				Name:      cmp.Or(p.Name, p.Ref),
+				URL:       p.WebURL,
+				Status:    p.Status,
+				CreatedAt: p.CreatedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetRunLogs concatenates the traces of every job in the pipeline,
+// each under the same job header the per-job GitHub fallback writes.
+// Jobs whose trace has expired or was never produced are skipped; a
+// pipeline left with none is reported with [ErrRunHasNoLogs].
+func (s *GitLabSource) GetRunLogs(ctx context.Context, run Run) (io.ReadCloser, error) {
+	if slices.Contains(gitlabActiveStatuses, run.Status) {
+		return nil, fmt.Errorf("%w: pipeline %d is %s", ErrRunNotCompleted, run.ID, run.Status)
+	}
+
+	var jobs []gitlabJob
+	err := s.paginate(ctx, fmt.Sprintf("pipelines/%d/jobs", run.ID), url.Values{}, func(body []byte) error {
+		var page []gitlabJob
+		if err := json.Unmarshal(body, &page); err != nil {
+			return fmt.Errorf("decoding jobs of pipeline %d: %w", run.ID, err)
+		}
+		jobs = append(jobs, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(jobs, func(a, b gitlabJob) int { return cmp.Compare(a.ID, b.ID) })
+
+	var b strings.Builder
+	var traced int
+	for _, job := range jobs {
+		trace, status, _, err := s.do(ctx, fmt.Sprintf("jobs/%d/trace", job.ID), nil)
+		if err != nil {
+			return nil, err
+		}
+		if status == http.StatusNotFound || status == http.StatusForbidden || len(trace) == 0 {
+			s.logger(ctx).Debugf("No trace for job %d (%s) of pipeline %d: HTTP %d", job.ID, job.Name, run.ID, status)
+			continue
+		}
+		if status != http.StatusOK {
+			return nil, fmt.Errorf("gitlab: trace of job %d: unexpected status %d", job.ID, status)
+		}
+		fmt.Fprintf(&b, "===== JOB ID: %d =====\n", job.ID)
+		b.Write(trace)
+		b.WriteString("\n\n")
+		traced++
+	}
+	if traced == 0 {
+		return nil, fmt.Errorf("%w: pipeline %d has no job traces", ErrRunHasNoLogs, run.ID)
+	}
+	return io.NopCloser(strings.NewReader(b.String())), nil
+}
+
+// paginate calls fn with each page of the project-scoped list at path,
+// following GitLab's X-Next-Page header.
+func (s *GitLabSource) paginate(ctx context.Context, path string, q url.Values, fn func([]byte) error) error {
+	q.Set("per_page", strconv.Itoa(gitlabPageSize))
+	for page := "1"; page != ""; {
+		q.Set("page", page)
+		body, status, next, err := s.do(ctx, path, q)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("gitlab: listing %s of %s: unexpected status %d", path, s.Project, status)
+		}
+		if err := fn(body); err != nil {
+			return err
+		}
+		page = next
+	}
+	return nil
+}
+
+// do performs a GET on path under the project and returns the body,
+// the status code, and the next page number, if any.
+func (s *GitLabSource) do(ctx context.Context, path string, q url.Values) ([]byte, int, string, error) {
+	u := fmt.Sprintf("%s/api/v4/projects/%s/%s",
+		strings.TrimSuffix(cmp.Or(s.BaseURL, DefaultGitLabURL), "/"), url.PathEscape(s.Project), path)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("gitlab: building request: %w", err)
+	}
+	if s.Token != "" {
+		req.Header.Set("PRIVATE-TOKEN", s.Token)
+	}
+	hc := s.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("gitlab: GET %s: %w", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := httpclient.ReadAllBounded(resp.Body, 0)
+	if err != nil {
+		return nil, resp.StatusCode, "", fmt.Errorf("gitlab: reading %s: %w", path, err)
+	}
+	return body, resp.StatusCode, resp.Header.Get("X-Next-Page"), nil
+}
+
+func (s *GitLabSource) logger(ctx context.Context) *clog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return clog.FromContext(ctx)
+}
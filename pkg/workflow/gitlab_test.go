@@ -0,0 +1,83 @@
+package workflow_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// TestGitLabSource lists a project's pipelines across pages, keeps
+// only those created in the window, and concatenates the traces of a
+// pipeline's jobs while skipping jobs whose trace is gone.
+func TestGitLabSource(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("PRIVATE-TOKEN"); got != "glpat-test" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/api/v4/projects/group%2Fproject/pipelines":
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				_, _ = io.WriteString(w, `[{"id":1,"ref":"main","status":"success","web_url":"https://gitlab.example/p/1","created_at":"2025-03-01T10:00:00Z"},
+					{"id":2,"ref":"main","status":"success","created_at":"2025-02-20T10:00:00Z"}]`)
+				return
+			}
+			_, _ = io.WriteString(w, `[{"id":3,"name":"release","status":"running","created_at":"2025-03-01T11:00:00Z"}]`)
+		case "/api/v4/projects/group%2Fproject/pipelines/1/jobs":
+			_, _ = io.WriteString(w, `[{"id":12,"name":"test"},{"id":11,"name":"build"}]`)
+		case "/api/v4/projects/group%2Fproject/jobs/11/trace":
+			_, _ = io.WriteString(w, "building\nDROP_THIS_TOKEN\n")
+		case "/api/v4/projects/group%2Fproject/jobs/12/trace":
+			http.NotFound(w, r)
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	src := &workflow.GitLabSource{HTTP: srv.Client(), BaseURL: srv.URL + "/", Project: "group/project", Token: "glpat-test"}
+
+	runs, err := src.ListRuns(t.Context(), start, end)
+	if err != nil {
+		t.Fatalf("ListRuns() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != 1 || runs[1].ID != 3 {
+		t.Fatalf("ListRuns() = %+v, want pipelines 1 and 3", runs)
+	}
+	if runs[0].Name != "main" || runs[0].URL != "https://gitlab.example/p/1" || runs[1].Name != "release" {
+		t.Fatalf("ListRuns() = %+v, want name falling back to the ref and the web URL kept", runs)
+	}
+
+	rc, err := src.GetRunLogs(t.Context(), runs[0])
+	if err != nil {
+		t.Fatalf("GetRunLogs() error = %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	logs, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read logs: %v", err)
+	}
+	if !strings.Contains(string(logs), "===== JOB ID: 11 =====\nbuilding\nDROP_THIS_TOKEN") {
+		t.Fatalf("GetRunLogs() = %q, want job 11's trace under its header", logs)
+	}
+	if strings.Contains(string(logs), "JOB ID: 12") {
+		t.Fatalf("GetRunLogs() = %q, want job 12 without a trace skipped", logs)
+	}
+
+	if _, err := src.GetRunLogs(t.Context(), runs[1]); !errors.Is(err, workflow.ErrRunNotCompleted) {
+		t.Fatalf("GetRunLogs(running) error = %v, want ErrRunNotCompleted", err)
+	}
+}
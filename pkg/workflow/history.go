@@ -0,0 +1,173 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/google/go-github/v86/github"
+)
+
+// usesLineRE captures the value of a uses: key on a single YAML line,
+// including the list-item form ("- uses: ..."). It is deliberately
+// looser than ParseUsesEdges because a diff hunk is not a complete
+// document.
+var usesLineRE = regexp.MustCompile(`^\s*(?:-\s+)?uses:\s*["']?([^"'\s#]+)`)
+
+// WorkflowChange is a line added to a workflow file by a commit that
+// references a configured IOC. It survives after the run logs for the
+// malicious revision have expired or been deleted.
+type WorkflowChange struct {
+	// SHA is the commit that introduced the line.
+	SHA string
+	// Author is the GitHub login of the commit author, or the git
+	// author name when the commit is not linked to an account.
+	Author string
+	// Date is the git author date.
+	Date time.Time
+	// Path is the workflow file path within the repository.
+	Path string
+	// Line is the added line with the diff marker and surrounding
+	// whitespace removed.
+	Line string
+	// IOCName names the indicator that matched: the IOC's name for a
+	// content match, or the corpus action for a uses: match.
+	IOCName string
+}
+
+// ListWorkflowCommits returns the commits on the default branch that
+// touched .github/workflows between since and until.
+func ListWorkflowCommits(ctx context.Context, gh *github.Client, owner, repo string, since, until time.Time) ([]*github.RepositoryCommit, error) {
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	opts := &github.CommitsListOptions{
+		Path:        workflowsDir,
+		Since:       since,
+		Until:       until,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var commits []*github.RepositoryCommit
+	err := paginate(maxWorkflowListPages, "workflow commit listing", func(page int) (int, error) {
+		opts.Page = page
+		res, resp, err := gh.Repositories.ListCommits(ctx, owner, repo, opts)
+		if err != nil {
+			return 0, err
+		}
+		commits = append(commits, res...)
+		if resp == nil {
+			return 0, nil
+		}
+		return resp.NextPage, nil
+	})
+	return commits, err
+}
+
+// WorkflowModified reports whether a commit on the default branch
+// between since and until touched the workflow file at path. It costs
+// one request, however many commits there were.
+func WorkflowModified(ctx context.Context, gh *github.Client, owner, repo, path string, since, until time.Time) (bool, error) {
+	if gh == nil {
+		return false, fmt.Errorf("github client must not be nil")
+	}
+	commits, _, err := gh.Repositories.ListCommits(ctx, owner, repo, &github.CommitsListOptions{
+		Path:        path,
+		Since:       since,
+		Until:       until,
+		ListOptions: github.ListOptions{PerPage: 1},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(commits) > 0, nil
+}
+
+// FindWorkflowChanges fetches commit sha and returns every line it added
+// to a workflow file that contains findIOC content or a uses: reference
+// corpus flags as known-bad. Either matcher may be nil.
+func FindWorkflowChanges(ctx context.Context, gh *github.Client, owner, repo, sha string, findIOC *ioc.IOC, corpus *ioc.Corpus) ([]WorkflowChange, error) {
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	commit, _, err := gh.Repositories.GetCommit(ctx, owner, repo, sha, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching commit %s: %w", sha, err)
+	}
+
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+	date := commit.GetCommit().GetAuthor().GetDate().Time
+
+	var out []WorkflowChange
+	for _, f := range commit.Files {
+		name := f.GetFilename()
+		if f.GetStatus() == "removed" || path.Dir(name) != workflowsDir ||
+			(!strings.HasSuffix(name, ".yml") && !strings.HasSuffix(name, ".yaml")) {
+			continue
+		}
+		for _, m := range matchAddedLines(f.GetPatch(), findIOC, corpus) {
+			out = append(out, WorkflowChange{
+				SHA:     commit.GetSHA(),
+				Author:  author,
+				Date:    date,
+				Path:    name,
+				Line:    m.line,
+				IOCName: m.iocName,
+			})
+		}
+	}
+	return out, nil
+}
+
+type addedLineMatch struct {
+	line    string
+	iocName string
+}
+
+// matchAddedLines scans the "+" lines of a unified diff patch. A line
+// is reported at most once, preferring the IOC content match.
+func matchAddedLines(patch string, findIOC *ioc.IOC, corpus *ioc.Corpus) []addedLineMatch {
+	var matcher ioc.Matcher
+	if findIOC != nil {
+		matcher = findIOC.GetMatcher()
+	}
+
+	var out []addedLineMatch
+	for raw := range strings.SplitSeq(patch, "\n") {
+		if !strings.HasPrefix(raw, "+") || strings.HasPrefix(raw, "+++") {
+			continue
+		}
+		line := strings.TrimSpace(raw[1:])
+		if line == "" {
+			continue
+		}
+
+		if name, ok := matchLine(line, matcher, findIOC, corpus); ok {
+			out = append(out, addedLineMatch{line: line, iocName: name})
+		}
+	}
+	return out
+}
+
+// matchLine reports whether line contains findIOC content (via
+// matcher) or a uses: reference corpus flags as known-bad, returning
+// the IOC name to attribute it to. The content match wins when both
+// apply.
+func matchLine(line string, matcher ioc.Matcher, findIOC *ioc.IOC, corpus *ioc.Corpus) (string, bool) {
+	if matcher != nil && matcher.MatchAnyString(line) {
+		return findIOC.GetName(), true
+	}
+	if m := usesLineRE.FindStringSubmatch(line); m != nil {
+		action, ref := splitUses(m[1])
+		if corpus.MatchActionRef(action, ref) {
+			return action, true
+		}
+	}
+	return "", false
+}
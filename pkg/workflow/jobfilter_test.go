@@ -0,0 +1,205 @@
+package workflow_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+func TestParseJobFilter(t *testing.T) {
+	t.Parallel()
+
+	jobs := []*github.WorkflowJob{
+		{Name: new("build (ubuntu)"), Conclusion: new("success")},
+		{Name: new("deploy"), Conclusion: new("success")},
+		{Name: new("test"), Conclusion: new("failure")},
+	}
+	tests := []struct {
+		name    string
+		terms   []string
+		want    []bool
+		wantErr bool
+	}{
+		{name: "no terms match every job", want: []bool{true, true, true}},
+		{name: "name regexp", terms: []string{"^build"}, want: []bool{true, false, false}},
+		{name: "conclusion", terms: []string{"conclusion:failure"}, want: []bool{false, false, true}},
+		{name: "either term", terms: []string{"deploy", "conclusion:FAILURE"}, want: []bool{false, true, true}},
+		{name: "bad regexp", terms: []string{"("}, wantErr: true},
+		{name: "empty conclusion", terms: []string{"conclusion:"}, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := workflow.ParseJobFilter(tc.terms)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("ParseJobFilter(%q) succeeded, want an error", tc.terms)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJobFilter(%q): %v", tc.terms, err)
+			}
+			for i, job := range jobs {
+				if got := f.Match(job); got != tc.want[i] {
+					t.Errorf("Match(%q)=%v, want %v", job.GetName(), got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+// runArchive builds a run-level log archive laid out the way GitHub
+// serves it: a top-level file and a step directory per job.
+func runArchive(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range map[string]string{
+		"0_build.txt":              "build-full-log",
+		"build/1_Set up job.txt":   "build-step-log",
+		"1_deploy prod.txt":        "deploy-full-log",
+		"deploy prod/1_Deploy.txt": "deploy-step-log",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		_, _ = io.WriteString(w, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestGetLogs_JobFilter asserts the filter downloads only the matching
+// jobs' logs, narrows the run-level archive and per-job fallback logs
+// to them when a log cache needs whole runs, and skips a run with no
+// matching job.
+func TestGetLogs_JobFilter(t *testing.T) {
+	t.Parallel()
+
+	f, err := workflow.ParseJobFilter([]string{"conclusion:failure"})
+	if err != nil {
+		t.Fatalf("ParseJobFilter: %v", err)
+	}
+	opts := &workflow.Options{JobFilter: f}
+
+	archive := runArchive(t)
+	const jobs = `{"total_count":2,"jobs":[` +
+		`{"id":11,"name":"build","conclusion":"success"},` +
+		`{"id":22,"name":"deploy: prod","conclusion":"failure"}]}`
+	var (
+		server    *httptest.Server
+		requested sync.Map
+	)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested.Store(r.URL.Path, true)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"),
+			strings.HasSuffix(r.URL.Path, "/actions/runs/9/logs"):
+			w.Header().Set("Location", server.URL+"/raw/run-7.zip")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/8/logs"):
+			w.WriteHeader(http.StatusGone)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/9/jobs"):
+			_, _ = io.WriteString(w, `{"total_count":1,"jobs":[{"id":11,"name":"build","conclusion":"success"}]}`)
+		case strings.HasSuffix(r.URL.Path, "/jobs"):
+			_, _ = io.WriteString(w, jobs)
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/11/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-11.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/22/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-22.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.Contains(r.URL.Path, "/actions/runs/"):
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		case strings.HasSuffix(r.URL.Path, "/raw/run-7.zip"):
+			_, _ = w.Write(archive)
+		case strings.HasSuffix(r.URL.Path, "/raw/job-11.txt"):
+			_, _ = io.WriteString(w, "build-log-line")
+		case strings.HasSuffix(r.URL.Path, "/raw/job-22.txt"):
+			_, _ = io.WriteString(w, "deploy-log-line")
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	gh, hc := newTestClients(t, server)
+
+	t.Run("per-job download", func(t *testing.T) {
+		rc, err := workflow.GetLogsWithOptions(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, "tok", opts)
+		if err != nil {
+			t.Fatalf("GetLogs: %v", err)
+		}
+		defer func() { _ = rc.Close() }()
+		if workflow.IsPerJobFallback(rc) {
+			t.Error("logs chosen for the filter carry the fallback marker")
+		}
+		text, err := workflow.ExtractLogs(rc)
+		if err != nil {
+			t.Fatalf("ExtractLogs: %v", err)
+		}
+		if !strings.Contains(text, "===== JOB ID: 22 =====") || !strings.Contains(text, "deploy-log-line") {
+			t.Errorf("filtered logs lack the failed job:\n%s", text)
+		}
+		for _, path := range []string{"/repos/o/r/actions/runs/7/logs", "/repos/o/r/actions/jobs/11/logs"} {
+			if _, ok := requested.Load(path); ok {
+				t.Errorf("requested %s, want only the matching job's logs", path)
+			}
+		}
+	})
+
+	// A log cache keeps whole runs, so with one set the full logs are
+	// downloaded and filtered afterwards.
+	cached := &workflow.Options{JobFilter: f, LogCache: &workflow.LogCache{Dir: t.TempDir()}}
+
+	t.Run("archive", func(t *testing.T) {
+		rc, err := workflow.GetLogsWithOptions(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, "tok", cached)
+		if err != nil {
+			t.Fatalf("GetLogs: %v", err)
+		}
+		defer func() { _ = rc.Close() }()
+		text, err := workflow.ExtractLogs(rc)
+		if err != nil {
+			t.Fatalf("ExtractLogs: %v", err)
+		}
+		if !strings.Contains(text, "deploy-full-log") || !strings.Contains(text, "deploy-step-log") {
+			t.Errorf("filtered archive lacks the failed job's entries:\n%s", text)
+		}
+		if strings.Contains(text, "build-") {
+			t.Errorf("filtered archive kept the successful job's entries:\n%s", text)
+		}
+	})
+
+	t.Run("per-job fallback", func(t *testing.T) {
+		body, fallback := readLogs(t, gh, server, 8, cached)
+		if !fallback {
+			t.Fatal("filtered per-job logs lost the fallback marker")
+		}
+		if !strings.Contains(body, "===== JOB ID: 22 =====") || !strings.Contains(body, "deploy-log-line") {
+			t.Errorf("filtered logs lack the failed job:\n%s", body)
+		}
+		if strings.Contains(body, "build-log-line") {
+			t.Errorf("filtered logs kept the successful job:\n%s", body)
+		}
+	})
+
+	t.Run("no matching job", func(t *testing.T) {
+		_, err := workflow.GetLogsWithOptions(t.Context(), newTestLogger(), hc, gh, "o", "r", 9, "tok", cached)
+		if !errors.Is(err, workflow.ErrNoMatchingJobs) || !errors.Is(err, workflow.ErrRunHasNoLogs) {
+			t.Fatalf("GetLogs err=%v, want ErrNoMatchingJobs wrapping ErrRunHasNoLogs", err)
+		}
+	})
+}
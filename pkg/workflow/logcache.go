@@ -0,0 +1,152 @@
+package workflow
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+)
+
+// Cached logs keep the form GetLogs returned them in: the run-level
+// zip archive, the per-job plain text assembled by the fallback, or
+// per-job text downloaded by choice, for an archive too large to
+// download.
+const (
+	logCacheArchiveExt  = ".zip"
+	logCacheFallbackExt = ".txt"
+	logCacheJobsExt     = ".jobs.txt"
+)
+
+// LogCache is an on-disk cache of downloaded run logs, consulted by
+// [GetLogs] before any request is made. Entries are stored as
+// Dir/owner__repo/<run ID>.zip, .txt for per-job fallback logs, or
+// .jobs.txt for other per-job logs.
+// Only completed runs reach the cache, and their logs are immutable,
+// so a TTL of zero keeps entries forever; a positive TTL treats
+// entries older than it as missing. A LogCache holds no state and is
+// safe for concurrent use.
+type LogCache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// path returns the cache file for runID in owner/repo with ext, or
+// false when the names could escape Dir.
+func (c *LogCache) path(owner, repo string, runID int64, ext string) (string, bool) {
+	for _, name := range []string{owner, repo} {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return "", false
+		}
+	}
+	return filepath.Join(c.Dir, owner+"__"+repo, strconv.FormatInt(runID, 10)+ext), true
+}
+
+// load returns the cached logs for runID, wrapped as GetLogs would
+// have returned them. A nil cache, a miss, an expired entry, and an
+// unreadable file all report false so the caller downloads afresh.
+func (c *LogCache) load(logger *clog.Logger, owner, repo string, runID int64) (io.ReadCloser, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for _, ext := range []string{logCacheArchiveExt, logCacheFallbackExt, logCacheJobsExt} {
+		p, ok := c.path(owner, repo, runID, ext)
+		if !ok {
+			return nil, false
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if c.TTL > 0 && time.Since(info.ModTime()) > c.TTL {
+			logger.Debugf("Cached log for run %d in %s/%s is older than %v; downloading", runID, owner, repo, c.TTL)
+			return nil, false
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			logger.Warnf("Reading cached log for run %d in %s/%s: %v", runID, owner, repo, err)
+			return nil, false
+		}
+		logger.Debugf("Using cached log for run %d in %s/%s", runID, owner, repo)
+		rc := io.NopCloser(bytes.NewReader(data))
+		switch ext {
+		case logCacheFallbackExt:
+			return perJobFallbackLogs{rc}, true
+		case logCacheJobsExt:
+			return perJobLogs{rc}, true
+		}
+		return rc, true
+	}
+	return nil, false
+}
+
+// store writes the logs read from rc to the cache and returns a
+// replacement reader over the same bytes, preserving the per-job
+// markers, which load restores from the entry's extension. rc is
+// always closed. A failed write is logged and the logs are still
+// returned, since caching is best effort.
+func (c *LogCache) store(logger *clog.Logger, owner, repo string, runID int64, rc io.ReadCloser) (io.ReadCloser, error) {
+	fallback := IsPerJobFallback(rc)
+	text := isPerJobText(rc)
+	data, err := io.ReadAll(rc)
+	_ = rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading logs for run %d: %w", runID, err)
+	}
+
+	ext := logCacheArchiveExt
+	switch {
+	case fallback:
+		ext = logCacheFallbackExt
+	case text:
+		ext = logCacheJobsExt
+	}
+	if err := c.write(owner, repo, runID, ext, data); err != nil {
+		logger.Warnf("Caching log for run %d in %s/%s: %v", runID, owner, repo, err)
+	}
+
+	out := io.NopCloser(bytes.NewReader(data))
+	switch {
+	case fallback:
+		return perJobFallbackLogs{out}, nil
+	case text:
+		return perJobLogs{out}, nil
+	}
+	return out, nil
+}
+
+// write stores data atomically through a temp file and rename, so a
+// concurrent reader or an interrupted scan never sees a torn entry.
+func (c *LogCache) write(owner, repo string, runID int64, ext string, data []byte) error {
+	p, ok := c.path(owner, repo, runID, ext)
+	if !ok {
+		return fmt.Errorf("invalid repository name %s/%s", owner, repo)
+	}
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("creating log cache directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating log cache file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("writing log cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("closing log cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("renaming log cache file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package workflow_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// readLogs drains and closes the logs GetLogs returns for runID with
+// opts.
+func readLogs(t *testing.T, gh *github.Client, srv *httptest.Server, runID int64, opts *workflow.Options) (string, bool) {
+	t.Helper()
+	_, hc := newTestClients(t, srv)
+	rc, err := workflow.GetLogsWithOptions(t.Context(), newTestLogger(), hc, gh, "o", "r", runID, "tok", opts)
+	if err != nil {
+		t.Fatalf("GetLogs(%d): %v", runID, err)
+	}
+	defer func() { _ = rc.Close() }()
+	fallback := workflow.IsPerJobFallback(rc)
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read logs: %v", err)
+	}
+	return string(body), fallback
+}
+
+// TestGetLogs_LogCache asserts a cached run is served without any
+// request, per-job fallback logs keep their marker across the cache,
+// and an entry older than the TTL is downloaded again.
+func TestGetLogs_LogCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	opts := &workflow.Options{LogCache: &workflow.LogCache{Dir: dir, TTL: time.Hour}}
+
+	var requests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"):
+			w.Header().Set("Location", server.URL+"/raw/run-7.zip")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/8/logs"):
+			w.WriteHeader(http.StatusGone)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/8/jobs"):
+			_, _ = io.WriteString(w, `{"total_count":1,"jobs":[{"id":11}]}`)
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/11/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-11.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.Contains(r.URL.Path, "/actions/runs/"):
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		case r.URL.Path == "/raw/run-7.zip":
+			_, _ = io.WriteString(w, "ARCHIVE-7")
+		case r.URL.Path == "/raw/job-11.txt":
+			_, _ = io.WriteString(w, "job-11-line\n")
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	gh, _ := newTestClients(t, server)
+
+	if body, fallback := readLogs(t, gh, server, 7, opts); body != "ARCHIVE-7" || fallback {
+		t.Fatalf("first fetch=(%q, %v), want (ARCHIVE-7, false)", body, fallback)
+	}
+	archive := filepath.Join(dir, "o__r", "7.zip")
+	if data, err := os.ReadFile(archive); err != nil || string(data) != "ARCHIVE-7" {
+		t.Fatalf("cached archive=(%q, %v), want ARCHIVE-7", data, err)
+	}
+
+	before := requests.Load()
+	if body, fallback := readLogs(t, gh, server, 7, opts); body != "ARCHIVE-7" || fallback {
+		t.Fatalf("cached fetch=(%q, %v), want (ARCHIVE-7, false)", body, fallback)
+	}
+	if got := requests.Load(); got != before {
+		t.Fatalf("cache hit made %d requests, want 0", got-before)
+	}
+
+	first, fallback := readLogs(t, gh, server, 8, opts)
+	if !fallback || !strings.Contains(first, "job-11-line") {
+		t.Fatalf("fallback fetch=(%q, %v), want per-job logs", first, fallback)
+	}
+	before = requests.Load()
+	if body, fallback := readLogs(t, gh, server, 8, opts); body != first || !fallback {
+		t.Fatalf("cached fallback=(%q, %v), want (%q, true)", body, fallback, first)
+	}
+	if got := requests.Load(); got != before {
+		t.Fatalf("cached fallback made %d requests, want 0", got-before)
+	}
+
+	stale := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(archive, stale, stale); err != nil {
+		t.Fatalf("age cache entry: %v", err)
+	}
+	before = requests.Load()
+	readLogs(t, gh, server, 7, opts)
+	if got := requests.Load(); got == before {
+		t.Fatal("expired entry was served from the cache")
+	}
+}
+
+// TestLogCache_PerJobMarkers asserts per-job logs keep their marker
+// across the cache: fallback logs still report an expired archive, and
+// logs downloaded job by job for another reason do not.
+func TestLogCache_PerJobMarkers(t *testing.T) {
+	t.Parallel()
+
+	c := &workflow.LogCache{Dir: t.TempDir()}
+	for _, tc := range []struct {
+		runID    int64
+		fallback bool
+	}{
+		{runID: 1, fallback: true},
+		{runID: 2, fallback: false},
+	} {
+		text, fallback, err := workflow.LogCacheRoundTripForTest(c, tc.runID, "job-line\n", tc.fallback)
+		if err != nil {
+			t.Fatalf("run %d: %v", tc.runID, err)
+		}
+		if !text || fallback != tc.fallback {
+			t.Errorf("run %d loaded as (text=%v, fallback=%v), want (true, %v)", tc.runID, text, fallback, tc.fallback)
+		}
+	}
+}
+
+// TestOptions_LogCacheEmptyDirDisables asserts a cache without a
+// directory is treated as no cache rather than writing to the working
+// directory.
+func TestOptions_LogCacheEmptyDirDisables(t *testing.T) {
+	t.Chdir(t.TempDir())
+	opts := &workflow.Options{LogCache: &workflow.LogCache{}}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"):
+			w.Header().Set("Location", server.URL+"/raw/run-7.zip")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7"):
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		default:
+			_, _ = io.WriteString(w, "ARCHIVE-7")
+		}
+	}))
+	t.Cleanup(server.Close)
+	gh, _ := newTestClients(t, server)
+
+	readLogs(t, gh, server, 7, opts)
+	if entries, _ := os.ReadDir("."); len(entries) != 0 {
+		t.Fatalf("empty Dir cache wrote %d entries to the working directory", len(entries))
+	}
+}
@@ -4,16 +4,19 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/chainguard-dev/clog"
@@ -38,6 +41,23 @@ var ErrNoJobsForRun = errors.New("workflow: run has no jobs")
 // inadvertent skip.
 var ErrRunHasNoLogs = errors.New("workflow: run has no logs to scan")
 
+// ErrRunNotCompleted marks a run that is still queued or executing.
+// Its logs are partial or not yet published, so GetLogs skips it
+// rather than reporting a spurious fetch failure. It wraps
+// ErrRunHasNoLogs, so callers that only handle the general skip keep
+// working.
+var ErrRunNotCompleted = fmt.Errorf("%w: run has not completed", ErrRunHasNoLogs)
+
+// unfinishedStatuses are the run statuses GitHub reports before a run
+// completes.
+var unfinishedStatuses = map[string]bool{
+	"queued":      true,
+	"in_progress": true,
+	"requested":   true,
+	"waiting":     true,
+	"pending":     true,
+}
+
 // timestampRE strips the leading RFC3339-like prefix that GitHub
 // prepends to every log line. Compiled once at init so per-line scans
 // pay zero regex build cost.
@@ -60,11 +80,51 @@ const (
 	// Mirrors internal/action.fanOutLimit; chosen well below GitHub's
 	// documented 100-request secondary concurrency limit.
 	perJobFanOutLimit = 32
+	// logProgressInterval is how often a run-level log download in
+	// flight reports its progress at debug level.
+	logProgressInterval = 5 * time.Second
 )
 
+// DefaultFallbackConcurrency is the per-job fallback download limit
+// used when [Options] set no lower limit.
+const DefaultFallbackConcurrency = perJobFanOutLimit
+
+// perJobFallbackLogs marks a ReadCloser assembled from per-job logs so
+// callers can tell, via [IsPerJobFallback], that the run-level archive
+// was unavailable.
+type perJobFallbackLogs struct {
+	io.ReadCloser
+}
+
+// IsPerJobFallback reports whether rc, as returned by [GetLogs], was
+// assembled from the per-job logs API because the run-level archive
+// returned 404 or 410, which usually means it has expired.
+func IsPerJobFallback(rc io.ReadCloser) bool {
+	_, ok := rc.(perJobFallbackLogs)
+	return ok
+}
+
+// perJobLogs marks a ReadCloser assembled from per-job logs by choice
+// rather than as a fallback: for a job filter, or a run archive too
+// large to download.
+type perJobLogs struct {
+	io.ReadCloser
+}
+
+// isPerJobText reports whether rc holds per-job logs combined as text
+// rather than a run-level zip archive.
+func isPerJobText(rc io.Reader) bool {
+	switch rc.(type) {
+	case perJobFallbackLogs, perJobLogs:
+		return true
+	}
+	return false
+}
+
 type Finding struct {
 	Encoded           string   `json:"encoded,omitempty"`
 	Decoded           string   `json:"decoded,omitempty"`
+	DecodeDepth       int      `json:"decode_depth,omitempty"`
 	LineData          string   `json:"line_data,omitempty"`
 	WorkflowFileSHA   string   `json:"workflow_file_sha,omitempty"`
 	OffendingUsesLine string   `json:"offending_uses_line,omitempty"`
@@ -72,13 +132,49 @@ type Finding struct {
 	JobName           string   `json:"job_name,omitempty"`
 	StepName          string   `json:"step_name,omitempty"`
 	ReachableSecrets  []string `json:"reachable_secrets,omitempty"`
+	KeyType           string   `json:"key_type,omitempty"`
+	Severity          string   `json:"severity,omitempty"`
+	Destination       string   `json:"destination,omitempty"`
+	Note              string   `json:"note,omitempty"`
+	// MatchedPattern is the IOC content string or regex that matched,
+	// and MatchOffset the byte offset in LineData where the match (the
+	// regex's capture group, if any) starts. Only the IOC detectors
+	// set them; an offset of zero is omitted from JSON.
+	MatchedPattern string `json:"matched_pattern,omitempty"`
+	MatchOffset    int    `json:"match_offset,omitempty"`
+	// Context holds the matching line with up to
+	// [Options.ContextLines] lines on each side, newline-separated,
+	// when context capture is on.
+	Context string `json:"context,omitempty"`
+	// Confidence is the 0-100 likelihood that the finding is a real
+	// compromise, scored by [ParseReader] from the detector that
+	// reported it (see [ConfidenceIOC] and the scores listed with it).
+	Confidence int `json:"confidence,omitempty"`
+	// Explanation says, with [Options.Explain], which detector reported the
+	// finding, what it matched, and on which line.
+	Explanation string `json:"explanation,omitempty"`
 }
 
+// ExtractLogs reads a run log archive and returns the text of every
+// member, each followed by a newline: every job's log, including long
+// image build steps, and the per-step files GitHub adds beside them.
+// Nothing is filtered or truncated, so the detectors see the whole run.
+// Logs [GetLogs] assembled from the per-job endpoint are already text
+// and are returned as they are.
 func ExtractLogs(rc io.Reader) (string, error) {
-	data, err := io.ReadAll(rc)
+	return ExtractLogsContext(context.Background(), rc)
+}
+
+// ExtractLogsContext is [ExtractLogs], stopping with ctx's error once
+// ctx ends rather than decompressing the rest of a large archive.
+func ExtractLogsContext(ctx context.Context, rc io.Reader) (string, error) {
+	data, err := io.ReadAll(ctxReader{ctx: ctx, r: rc})
 	if err != nil {
 		return "", fmt.Errorf("read logs: %w", err)
 	}
+	if isPerJobText(rc) {
+		return string(data), nil
+	}
 	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
 		return "", fmt.Errorf("open zip: %w", err)
@@ -91,7 +187,7 @@ func ExtractLogs(rc io.Reader) (string, error) {
 				return fmt.Errorf("open zip member: %w", err)
 			}
 			defer func() { _ = f.Close() }()
-			b, err := io.ReadAll(f)
+			b, err := io.ReadAll(ctxReader{ctx: ctx, r: f})
 			if err != nil {
 				return fmt.Errorf("read zip member: %w", err)
 			}
@@ -116,18 +212,89 @@ func ExtractLogs(rc io.Reader) (string, error) {
 // allowlist for the log payload itself; gh handles all REST envelopes
 // (status, listing, redirect resolution).
 //
+// Both paths authenticate through gh, so private repositories are
+// handled identically to public ones; there is no unauthenticated
+// github.com UI request that could be served a login page instead of
+// log content.
+//
+// Runs that are still queued or in progress return ErrRunNotCompleted
+// before any log request is made.
+//
+// Runs from forks (see [IsForkRun]) are read through the same logs
+// API. When GitHub refuses this token their logs, GetLogs returns
+// ErrForkLogsRestricted instead of a fetch failure.
+//
+// A run-level archive with (almost) no log text, which the logs API
+// sometimes serves near the retention boundary, is supplemented with
+// the per-job logs of the jobs it lacks, and the supplementation is
+// logged.
+//
+// A download that turns out to be an HTML page, such as a login or
+// anti-bot interstitial served in place of the logs, is returned as an
+// ordinary (retryable) error rather than scanned as an empty run.
+//
 // token is used on raw log download requests (signed
 // objects.githubusercontent.com URLs may not embed credentials). It
 // is not consulted on REST envelope calls because gh is expected to
 // carry its own authentication.
+//
+// A nil logger selects the one carried by ctx (see
+// [clog.FromContext]), so embedders can route ghscan's logging through
+// their own handler either way.
+//
+// A run archive larger than the HTTP client's body limit is replaced
+// by the run's per-job logs, each of which is downloaded on its own.
+//
+// GetLogs uses the default [Options]; see [GetLogsWithOptions].
 func GetLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string) (io.ReadCloser, error) {
+	return GetLogsWithOptions(ctx, logger, hc, gh, owner, repo, runID, token, nil)
+}
+
+// GetLogsWithOptions is [GetLogs] configured by opts. A nil opts
+// selects the defaults.
+//
+// When opts carry a [LogCache], a cached copy of the run's logs is
+// returned without any request, and downloaded logs are written to it.
+//
+// When opts carry a [JobFilter], the returned logs hold only the
+// matching jobs, and a run with none returns ErrNoMatchingJobs. Unless
+// a [LogCache], which keeps whole runs, is set too, only the matching
+// jobs' logs are downloaded, through [GetJobLogs], instead of the
+// run's archive.
+func GetLogsWithOptions(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string, opts *Options) (io.ReadCloser, error) {
 	if hc == nil {
 		return nil, fmt.Errorf("httpclient must not be nil")
 	}
 	if gh == nil {
 		return nil, fmt.Errorf("github client must not be nil")
 	}
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+
+	filter := opts.jobFilter()
+	cache := opts.logCache()
+	limit := opts.fallbackConcurrency()
+	if rc, ok := cache.load(logger, owner, repo, runID); ok {
+		return filter.filter(ctx, logger, gh, owner, repo, runID, rc)
+	}
+	if filter != nil && cache == nil {
+		return fetchLogs(ctx, logger, hc, gh, owner, repo, runID, token, filter, limit)
+	}
+	rc, err := fetchLogs(ctx, logger, hc, gh, owner, repo, runID, token, nil, limit)
+	if err == nil && cache != nil {
+		rc, err = cache.store(logger, owner, repo, runID, rc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return filter.filter(ctx, logger, gh, owner, repo, runID, rc)
+}
 
+// fetchLogs is GetLogsWithOptions without the cache. A non-nil filter downloads
+// only the matching jobs' logs instead of the run's archive. Per-job
+// logs are downloaded at most limit at a time.
+func fetchLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string, filter *JobFilter, limit int) (io.ReadCloser, error) {
 	run, _, err := gh.Actions.GetWorkflowRunByID(ctx, owner, repo, runID)
 	if err != nil {
 		return nil, fmt.Errorf("fetching run status: %w", err)
@@ -135,6 +302,10 @@ func GetLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh
 
 	status := run.GetStatus()
 	conclusion := run.GetConclusion()
+	if unfinishedStatuses[status] {
+		logger.Infof("Run %d is %s; skipping log retrieval until it completes", runID, status)
+		return nil, fmt.Errorf("run %d: %w", runID, ErrRunNotCompleted)
+	}
 	if status == cancelled || conclusion == cancelled {
 		// For cancelled runs we proactively check job count; if there
 		// are no jobs the per-job fallback can't help us either.
@@ -145,18 +316,49 @@ func GetLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh
 		}
 	}
 
+	if filter != nil {
+		rc, err := filter.jobLogs(ctx, logger, hc, gh, owner, repo, runID, token, limit)
+		if err != nil && IsForkRun(run) && isInaccessible(nil, err) {
+			return nil, forkLogsRestricted(logger, run, err)
+		}
+		return rc, err
+	}
+
 	logURL, resp, err := gh.Actions.GetWorkflowRunLogs(ctx, owner, repo, runID, runLogsMaxRedirects)
 	switch {
 	case err == nil && logURL != nil:
-		body, err := fetchRawLogs(ctx, hc, logURL.String(), token)
+		progressCtx := httpclient.WithProgress(ctx, logProgressInterval, logDownloadProgress(logger, runID))
+		body, err := fetchRawLogs(progressCtx, hc, logURL.String(), token)
+		if errors.Is(err, httpclient.ErrBodyTooLarge) {
+			// Each job's log is far smaller than the archive of all of
+			// them, so the run can still be scanned job by job.
+			logger.Warnf("Log archive of run %d exceeds the download limit; downloading each job's logs instead", runID)
+			rc, err := runJobLogs(ctx, logger, hc, gh, owner, repo, runID, token, status, conclusion, limit)
+			if err != nil {
+				return nil, err
+			}
+			return perJobLogs{rc}, nil
+		}
 		if err != nil {
 			return nil, err
 		}
+		if reason := archiveLooksTruncated(body); reason != "" {
+			body = supplementArchive(ctx, logger, hc, gh, owner, repo, runID, token, body, reason, limit)
+		}
 		return io.NopCloser(bytes.NewReader(body)), nil
 
 	case resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone):
 		logger.Warnf("Logs API returned %d for run %d; falling back to per-job logs API", resp.StatusCode, runID)
-		return fallbackPerJobLogs(ctx, logger, hc, gh, owner, repo, runID, token, status, conclusion)
+		rc, err := fallbackPerJobLogs(ctx, logger, hc, gh, owner, repo, runID, token, status, conclusion, limit)
+		if err != nil && !errors.Is(err, ErrRunHasNoLogs) {
+			if logsExpiredStatus(run, resp.StatusCode, time.Now()) {
+				return nil, logsExpired(logger, run, err)
+			}
+			if IsForkRun(run) && isInaccessible(nil, err) {
+				return nil, forkLogsRestricted(logger, run, err)
+			}
+		}
+		return rc, err
 
 	default:
 		if resp != nil && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusFound {
@@ -164,6 +366,9 @@ func GetLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh
 				logger.Infof("Run %d was canceled, no job logs found in API response", runID)
 				return nil, fmt.Errorf("run %d: %w", runID, ErrRunHasNoLogs)
 			}
+			if IsForkRun(run) && isInaccessible(resp, err) {
+				return nil, forkLogsRestricted(logger, run, fmt.Errorf("status %d", resp.StatusCode))
+			}
 			return nil, fmt.Errorf("failed to download logs: status %d", resp.StatusCode)
 		}
 		if err != nil {
@@ -173,6 +378,29 @@ func GetLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh
 	}
 }
 
+// logDownloadProgress logs the download of runID's log archive at
+// debug level against its Content-Length, so a timeout can be told
+// apart as a slow transfer (steady progress lines) or a stalled
+// connection (no lines until the failure).
+func logDownloadProgress(logger *clog.Logger, runID int64) httpclient.ProgressFunc {
+	return func(p httpclient.Progress) {
+		total := "unknown"
+		if p.Total >= 0 {
+			total = fmt.Sprintf("%d", p.Total)
+		}
+		switch {
+		case p.Err != nil:
+			logger.Debugf("Log download for run %d failed after %d of %s bytes in %v: %v", runID, p.Read, total, p.Elapsed, p.Err)
+		case p.Done:
+			logger.Debugf("Downloaded %d bytes of logs for run %d in %v", p.Read, runID, p.Elapsed)
+		default:
+			logger.Debugf("Downloading logs for run %d: %d of %s bytes after %v", runID, p.Read, total, p.Elapsed)
+		}
+	}
+}
+
+// fallbackPerJobLogs returns the logs of every job of runID, marked as
+// the fallback for a run archive that is gone.
 func fallbackPerJobLogs(
 	ctx context.Context,
 	logger *clog.Logger,
@@ -181,8 +409,28 @@ func fallbackPerJobLogs(
 	owner, repo string,
 	runID int64,
 	token, status, conclusion string,
+	limit int,
 ) (io.ReadCloser, error) {
-	jobLogs, err := getPerJobLogs(ctx, hc, gh, owner, repo, runID, token)
+	rc, err := runJobLogs(ctx, logger, hc, gh, owner, repo, runID, token, status, conclusion, limit)
+	if err != nil {
+		return nil, err
+	}
+	return perJobFallbackLogs{rc}, nil
+}
+
+// runJobLogs downloads the logs of every job of runID through the
+// per-job endpoint and combines them with combineLogs.
+func runJobLogs(
+	ctx context.Context,
+	logger *clog.Logger,
+	hc *httpclient.Client,
+	gh *github.Client,
+	owner, repo string,
+	runID int64,
+	token, status, conclusion string,
+	limit int,
+) (io.ReadCloser, error) {
+	jobLogs, err := getPerJobLogs(ctx, logger, hc, gh, owner, repo, runID, token, limit)
 	if err != nil {
 		if errors.Is(err, ErrNoJobsForRun) {
 			if status == cancelled || conclusion == cancelled {
@@ -203,115 +451,116 @@ func fallbackPerJobLogs(
 		return nil, fmt.Errorf("no per-job logs returned")
 	}
 
-	combinedLogs, err := combineLogs(jobLogs)
+	combinedLogs, err := combineLogs(ctx, jobLogs)
 	if err != nil {
 		return nil, fmt.Errorf("combining logs: %w", err)
 	}
 	return combinedLogs, nil
 }
 
+// maxLogLineBytes bounds a single log line read by [ParseReader]; a
+// longer line ends the scan with a warning rather than buffering
+// without limit.
+const maxLogLineBytes = 16 * 1024 * 1024
+
+// ParseLogs runs every active [Detector] over each line of logData and
+// returns one [Finding] per distinct match, in the order each was first
+// seen. Two matches are the same when their Encoded, Decoded, LineData,
+// KeyType, and Destination fields all agree, so a payload repeated
+// across many lines is reported once. The bool reports whether
+// anything matched. It is [ParseReader] over an in-memory string with
+// the default [Options]; see [ParseLogsWithOptions].
 func ParseLogs(logger *clog.Logger, logData string, runID int64, findIOC *ioc.IOC) ([]Finding, bool) {
+	return ParseLogsWithOptions(logger, logData, runID, findIOC, nil)
+}
+
+// ParseLogsWithOptions is [ParseLogs] configured by opts. A nil opts
+// selects the defaults.
+func ParseLogsWithOptions(logger *clog.Logger, logData string, runID int64, findIOC *ioc.IOC, opts *Options) ([]Finding, bool) {
+	return ParseReader(logger, strings.NewReader(logData), runID, findIOC, opts)
+}
+
+// ParseReader is [ParseLogs] over a stream: it reads r line by line
+// through the full detector stack without buffering the whole log, so
+// any text (a saved log file, a pipe, a test fixture) can be scanned
+// without GitHub or zip extraction. Each finding is scored with the
+// Confidence of the detector that reported it, and with
+// [Options.ContextLines] also records the lines around its match in
+// Context; with [Options.Explain] it records why it was reported in
+// Explanation. A nil opts runs every detector with its defaults. A
+// read error, or a line longer than maxLogLineBytes, ends the scan with
+// a warning and returns the findings gathered up to that point.
+func ParseReader(logger *clog.Logger, r io.Reader, runID int64, findIOC *ioc.IOC, opts *Options) ([]Finding, bool) {
 	if findIOC == nil {
 		logger.Errorf("provided IOC is nil, unable to scan logs")
 		return nil, false
 	}
 
-	scanner := bufio.NewScanner(strings.NewReader(logData))
-	regex := findIOC.GetRegex()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineBytes)
+	detectors := opts.scanDetectors()
+	explain := opts.explain()
 
-	lineMap := make(map[string]struct{}, 16)
-	encodedMap := make(map[string]struct{}, 16)
-	decodedMap := make(map[string]struct{}, 16)
+	var findings []Finding
+	seen := make(map[findingKey]int, 16)
 
-	lineNum := 0
+	// window is nil, and costs nothing, unless ContextLines is set.
+	window := newContextWindow(opts.contextLines())
+
+	lc := LineContext{RunID: runID, IOC: findIOC, Logger: logger, Options: opts}
 	for scanner.Scan() {
 		line := scanner.Text()
-		lineNum++
-
-		lineMap = findMatch(line, findIOC, timestampRE, lineMap, logger, runID)
-
-		if regex == nil {
-			continue
+		lc.LineNum++
+		cl := window.line(line)
+		window.follow(findings, cl)
+
+		for _, nd := range detectors {
+			for _, f := range nd.detector.Detect(line, lc) {
+				if f.Encoded == "" && f.Decoded == "" && f.LineData == "" {
+					continue
+				}
+				k := findingKey{f.Encoded, f.Decoded, f.LineData, f.KeyType, f.Destination}
+				confidence := findingConfidence(nd.name, f)
+				if i, ok := seen[k]; ok {
+					findings[i].Severity = MaxSeverity(findings[i].Severity, f.Severity)
+					findings[i].DecodeDepth = max(findings[i].DecodeDepth, f.DecodeDepth)
+					findings[i].Confidence = max(findings[i].Confidence, confidence)
+					continue
+				}
+				seen[k] = len(findings)
+				explanation := ""
+				if explain {
+					explanation = cmp.Or(f.Explanation, explainFinding(nd.name, f, lc.LineNum))
+				}
+				findings = append(findings, Finding{
+					Encoded:        f.Encoded,
+					Decoded:        f.Decoded,
+					DecodeDepth:    f.DecodeDepth,
+					LineData:       f.LineData,
+					KeyType:        f.KeyType,
+					Severity:       f.Severity,
+					Destination:    f.Destination,
+					Note:           f.Note,
+					MatchedPattern: f.MatchedPattern,
+					MatchOffset:    f.MatchOffset,
+					Confidence:     confidence,
+					Explanation:    explanation,
+				})
+				window.start(findings, len(findings)-1, cl)
+			}
 		}
-
-		encodedMap, decodedMap = processMatch(line, regex, lineNum, encodedMap, decodedMap, logger, runID)
-	}
-
-	finding := Finding{
-		Encoded:  strings.Join(setToSlice(encodedMap), ","),
-		Decoded:  strings.Join(setToSlice(decodedMap), ","),
-		LineData: strings.Join(setToSlice(lineMap), ","),
-	}
-
-	findings := []Finding{finding}
-	foundIssues := len(findings) > 0
-	return findings, foundIssues
-}
-
-// setToSlice flattens a set into a slice via a single pass with the
-// final capacity known up-front. Avoids the two-pass collect/copy that
-// slices.Collect(maps.Keys(...)) performs.
-func setToSlice(m map[string]struct{}) []string {
-	out := make([]string, 0, len(m))
-	for k := range m {
-		out = append(out, k)
-	}
-	return out
-}
-
-func findMatch(line string, findIOC *ioc.IOC, timestamp *regexp.Regexp, lineMap map[string]struct{}, logger *clog.Logger, runID int64) map[string]struct{} {
-	if len(findIOC.GetContent()) == 0 {
-		return lineMap
-	}
-
-	// The IOC carries a precomputed bloom-prefiltered Matcher built once
-	// at construction. The bloom prefilter rejects lines that contain no
-	// n-gram from any IOC -- the common case for log scanning at
-	// internet scale -- without invoking the deterministic backend. The
-	// string-input variant skips the []byte(line) conversion that the
-	// generic Match() entrypoint would otherwise force.
-	matcher := findIOC.GetMatcher()
-	if matcher == nil || !matcher.MatchAnyString(line) {
-		return lineMap
+		window.push(cl)
 	}
-
-	clean := timestamp.ReplaceAllString(line, "")
-	lineMap[clean] = struct{}{}
-	logger.Warnf("IOC log entry found in Run ID: %d", runID)
-
-	return lineMap
-}
-
-func processMatch(line string, regex *regexp.Regexp, lineNum int, encodedMap, decodedMap map[string]struct{}, logger *clog.Logger, runID int64) (map[string]struct{}, map[string]struct{}) {
-	matches := regex.FindAllStringSubmatch(line, -1)
-	for _, match := range matches {
-		if len(match) <= 1 {
-			continue
-		}
-
-		encoded := match[1]
-		decoded, err := tryBase64Decode(encoded)
-		if err != nil {
-			continue
-		}
-
-		encodedMap[encoded] = struct{}{}
-		decodedMap = handleDecoded(decoded, lineNum, decodedMap, logger, runID)
+	if err := scanner.Err(); err != nil {
+		logger.Warnf("Stopped scanning logs for Run ID %d after line %d: %v", runID, lc.LineNum, err)
 	}
 
-	return encodedMap, decodedMap
+	return findings, len(findings) > 0
 }
 
-func handleDecoded(decoded string, lineNum int, decodedMap map[string]struct{}, logger *clog.Logger, runID int64) map[string]struct{} {
-	secondDecoded, err := tryBase64Decode(decoded)
-	if err == nil {
-		decodedMap[secondDecoded] = struct{}{}
-		logger.Warnf("Found valid double base64-encoded content at log line %d in Run ID: %d", lineNum, runID)
-	} else {
-		decodedMap[decoded] = struct{}{}
-		logger.Infof("Found valid base64-encoded content at log line %d in Run ID: %d", lineNum, runID)
-	}
-	return decodedMap
+// findingKey identifies a distinct match for ParseLogs deduplication.
+type findingKey struct {
+	encoded, decoded, lineData, keyType, destination string
 }
 
 // countJobs returns the total number of jobs in a workflow run. It is
@@ -335,10 +584,11 @@ func countJobs(ctx context.Context, gh *github.Client, owner, repo string, runID
 // by job ID so combineLogs produces deterministic output ordered by
 // numeric job ID.
 //
-// Per-job downloads run concurrently capped at perJobFanOutLimit so
-// runs with many jobs amortize GitHub's API round-trip latency without
+// Per-job downloads run concurrently capped at limit, the
+// [Options.FallbackConcurrency] (at most perJobFanOutLimit), so runs
+// with many jobs amortize GitHub's API round-trip latency without
 // exceeding the documented secondary rate-limit budget.
-func getPerJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string) (map[int64]io.ReadCloser, error) {
+func getPerJobLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string, limit int) (map[int64]io.ReadCloser, error) {
 	jobs, err := listAllJobs(ctx, gh, owner, repo, runID)
 	if err != nil {
 		return nil, fmt.Errorf("listing jobs: %w", err)
@@ -346,7 +596,14 @@ func getPerJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client
 	if len(jobs) == 0 {
 		return nil, fmt.Errorf("run %d: %w", runID, ErrNoJobsForRun)
 	}
+	return downloadJobLogs(ctx, logger, hc, gh, owner, repo, jobs, token, limit)
+}
 
+// downloadJobLogs downloads the plain-text logs of jobs through the
+// per-job logs endpoint, keyed by job ID, at most limit at a time.
+// Individual failures are tolerated as long as at least one job's logs
+// arrive.
+func downloadJobLogs(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, jobs []*github.WorkflowJob, token string, limit int) (map[int64]io.ReadCloser, error) {
 	var (
 		mu           sync.Mutex
 		results      = make(map[int64]io.ReadCloser, len(jobs))
@@ -364,7 +621,7 @@ func getPerJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client
 	)
 
 	g, gCtx := errgroup.WithContext(ctx)
-	g.SetLimit(perJobFanOutLimit)
+	g.SetLimit(limit)
 
 	for _, job := range jobs {
 		jobID := job.GetID()
@@ -384,19 +641,9 @@ func getPerJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client
 			default:
 			}
 
-			logURL, _, err := gh.Actions.GetWorkflowJobLogs(gCtx, owner, repo, jobID, jobLogsMaxRedirects)
+			body, err := GetJobLogs(gCtx, hc, gh, owner, repo, jobID, token)
 			if err != nil {
-				recordError("job %s (ID: %d) get-url: %v", jobName, jobID, err)
-				return nil
-			}
-			if logURL == nil {
-				recordError("job %s (ID: %d): empty log URL", jobName, jobID)
-				return nil
-			}
-
-			body, err := fetchRawLogs(gCtx, hc, logURL.String(), token)
-			if err != nil {
-				recordError("job %s (ID: %d) fetch: %v", jobName, jobID, err)
+				recordError("job %s (ID: %d) %v", jobName, jobID, err)
 				return nil
 			}
 			recordResult(jobID, body)
@@ -412,12 +659,38 @@ func getPerJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client
 		return nil, fmt.Errorf("failed to fetch any job logs: %s", strings.Join(fetchErrors, "; "))
 	}
 	if len(fetchErrors) > 0 {
-		clog.WarnContextf(ctx, "failed to fetch some job logs: %s", strings.Join(fetchErrors, "; "))
+		logger.Warnf("Failed to fetch some job logs: %s", strings.Join(fetchErrors, "; "))
 	}
 
 	return results, nil
 }
 
+// GetJobLogs downloads the plain-text log of one job through the
+// per-job logs endpoint, [github.ActionsService.GetWorkflowJobLogs].
+// A job's log is a fraction of its run's archive, so it suits runs
+// whose archive is too large and scans that need only some jobs. token
+// is used on the raw download as in [GetLogs].
+func GetJobLogs(ctx context.Context, hc *httpclient.Client, gh *github.Client, owner, repo string, jobID int64, token string) ([]byte, error) {
+	if hc == nil {
+		return nil, fmt.Errorf("httpclient must not be nil")
+	}
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	logURL, _, err := gh.Actions.GetWorkflowJobLogs(ctx, owner, repo, jobID, jobLogsMaxRedirects)
+	if err != nil {
+		return nil, fmt.Errorf("get-url: %w", err)
+	}
+	if logURL == nil {
+		return nil, fmt.Errorf("empty log URL")
+	}
+	body, err := fetchRawLogs(ctx, hc, logURL.String(), token)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+	return body, nil
+}
+
 // listAllJobs iterates ListWorkflowJobs across every page so the caller
 // observes every job for the run. PerPage is set to the API maximum of
 // 100 to minimize round trips on large fan-out workflows. The page
@@ -456,10 +729,45 @@ func fetchRawLogs(ctx context.Context, hc *httpclient.Client, rawLogURL, token s
 		return nil, fmt.Errorf("empty raw logs")
 	}
 
+	// A login, rate-limit, or anti-bot interstitial can be served with
+	// a 200 in place of the logs; scanning it would record the run as
+	// clean. Failing instead lets the caller's backoff retry.
+	if isHTMLPage(rawResp.Header.Get("Content-Type"), body) {
+		return nil, fmt.Errorf("fetching raw logs: got an HTML page instead of logs")
+	}
+
 	return body, nil
 }
 
-func combineLogs(logsMap map[int64]io.ReadCloser) (io.ReadCloser, error) {
+// isHTMLPage reports whether a log download is an HTML document, by
+// its Content-Type or, when that is missing or generic, its content.
+// Log archives are zip files and per-job logs start with a timestamp,
+// so neither sniffs as HTML.
+func isHTMLPage(contentType string, body []byte) bool {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil && mt == "text/html" {
+		return true
+	}
+	return strings.HasPrefix(http.DetectContentType(body), "text/html")
+}
+
+// ctxReader reads from r until ctx ends, then fails with ctx's error,
+// so reading a large log stops promptly when the scan is cancelled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// combineLogs concatenates the logs of each job in logsMap, in job ID
+// order, under a per-job header. Every reader is closed, including
+// those left unread when a read fails or ctx ends first.
+func combineLogs(ctx context.Context, logsMap map[int64]io.ReadCloser) (io.ReadCloser, error) {
 	var combinedBuilder strings.Builder
 
 	jobIDs := make([]int64, 0, len(logsMap))
@@ -468,20 +776,34 @@ func combineLogs(logsMap map[int64]io.ReadCloser) (io.ReadCloser, error) {
 	}
 	slices.Sort(jobIDs)
 
+	// next indexes the first job whose reader has not been closed.
+	next := 0
+	defer func() {
+		for _, jobID := range jobIDs[next:] {
+			if logs := logsMap[jobID]; logs != nil {
+				_ = logs.Close()
+			}
+		}
+	}()
+
 	for _, jobID := range jobIDs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		logs := logsMap[jobID]
+		next++
 		if logs == nil {
 			continue
 		}
 		fmt.Fprintf(&combinedBuilder, "===== JOB ID: %d =====\n", jobID)
 
-		logContent, err := io.ReadAll(logs)
+		logContent, err := io.ReadAll(ctxReader{ctx: ctx, r: logs})
+		closeErr := logs.Close()
 		if err != nil {
 			return nil, fmt.Errorf("reading logs for job %d: %w", jobID, err)
 		}
-		err = logs.Close()
-		if err != nil {
-			return nil, fmt.Errorf("closing logs for job %d: %w", jobID, err)
+		if closeErr != nil {
+			return nil, fmt.Errorf("closing logs for job %d: %w", jobID, closeErr)
 		}
 
 		combinedBuilder.Write(logContent)
@@ -1,6 +1,7 @@
 package workflow_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -131,6 +133,38 @@ func TestGetLogs_CancelledRunNoJobs(t *testing.T) {
 	}
 }
 
+// TestGetLogs_RunNotCompleted asserts queued and in-progress runs are
+// skipped before any log request, with a sentinel that still satisfies
+// the general no-logs check.
+func TestGetLogs_RunNotCompleted(t *testing.T) {
+	t.Parallel()
+
+	for _, status := range []string{"queued", "in_progress"} {
+		t.Run(status, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/actions/runs/42") {
+					t.Errorf("unexpected path: %s", r.URL.Path)
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = io.WriteString(w, runStatusBody(status, ""))
+			}))
+			t.Cleanup(ts.Close)
+
+			gh, hc := newTestClients(t, ts)
+			rc, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 42, "tok")
+			if rc != nil {
+				t.Fatalf("expected nil ReadCloser for %s run; got %T", status, rc)
+			}
+			if !errors.Is(err, workflow.ErrRunNotCompleted) || !errors.Is(err, workflow.ErrRunHasNoLogs) {
+				t.Fatalf("expected ErrRunNotCompleted wrapping ErrRunHasNoLogs; got %v", err)
+			}
+		})
+	}
+}
+
 // TestGetLogs_ArchiveSuccess covers the happy path: GitHub returns a
 // 302 from the run-level logs endpoint pointing at a signed URL, and
 // httpclient fetches the archive bytes.
@@ -167,6 +201,9 @@ func TestGetLogs_ArchiveSuccess(t *testing.T) {
 		t.Fatalf("GetLogs: %v", err)
 	}
 	t.Cleanup(func() { _ = rc.Close() })
+	if workflow.IsPerJobFallback(rc) {
+		t.Fatal("run-level archive must not be reported as a per-job fallback")
+	}
 
 	body, _ := io.ReadAll(rc)
 	if string(body) != archive {
@@ -174,6 +211,81 @@ func TestGetLogs_ArchiveSuccess(t *testing.T) {
 	}
 }
 
+// TestGetLogs_HTMLPageRejected asserts an HTML page served with a 200
+// in place of the log archive, such as an anti-bot interstitial, fails
+// the download so it is retried rather than scanned as a clean run.
+func TestGetLogs_HTMLPageRejected(t *testing.T) {
+	t.Parallel()
+
+	for name, contentType := range map[string]string{
+		"declared": "text/html; charset=utf-8",
+		"sniffed":  "application/octet-stream",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"):
+					w.Header().Set("Location", server.URL+"/raw/run-archive.zip")
+					w.WriteHeader(http.StatusFound)
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/7"):
+					w.Header().Set("Content-Type", "application/json")
+					_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+				default:
+					w.Header().Set("Content-Type", contentType)
+					_, _ = io.WriteString(w, "<!DOCTYPE html><html><body>Checking your browser</body></html>")
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			gh, hc := newTestClients(t, server)
+			rc, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 7, "tok")
+			if rc != nil {
+				t.Fatalf("expected nil ReadCloser for an HTML page; got %T", rc)
+			}
+			if err == nil || !strings.Contains(err.Error(), "HTML page") {
+				t.Fatalf("expected HTML page error, got %v", err)
+			}
+			if errors.Is(err, workflow.ErrRunHasNoLogs) {
+				t.Fatalf("HTML page reported as a run without logs: %v", err)
+			}
+		})
+	}
+}
+
+// TestGetLogs_LogsDownloadProgress asserts the run-level archive
+// download is reported at debug level against its Content-Length.
+func TestGetLogs_LogsDownloadProgress(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"):
+			w.Header().Set("Location", server.URL+"/raw/run-archive.zip")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/7"):
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		default:
+			_, _ = io.WriteString(w, "ARCHIVE")
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	var buf bytes.Buffer
+	logger := clog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	gh, hc := newTestClients(t, server)
+	rc, err := workflow.GetLogs(t.Context(), logger, hc, gh, "o", "r", 7, "tok")
+	if err != nil {
+		t.Fatalf("GetLogs: %v", err)
+	}
+	_ = rc.Close()
+	if !strings.Contains(buf.String(), "Downloaded 7 bytes of logs for run 7") {
+		t.Fatalf("debug log lacks the download report:\n%s", buf.String())
+	}
+}
+
 // TestGetLogs_FallbackPerJobLogs exercises the new go-github-driven
 // fallback. The run-level archive endpoint returns 410 Gone (the
 // observed production behavior after the 30-day retention window),
@@ -242,9 +354,16 @@ func TestGetLogs_FallbackPerJobLogs(t *testing.T) {
 		t.Fatalf("GetLogs: %v", err)
 	}
 	t.Cleanup(func() { _ = rc.Close() })
+	if !workflow.IsPerJobFallback(rc) {
+		t.Fatal("per-job logs must be reported as a fallback")
+	}
 
-	body, _ := io.ReadAll(rc)
-	got := string(body)
+	// The combined logs are text, which ExtractLogs must pass through
+	// rather than reject as a malformed archive.
+	got, err := workflow.ExtractLogs(rc)
+	if err != nil {
+		t.Fatalf("ExtractLogs: %v", err)
+	}
 	for _, want := range []string{
 		"===== JOB ID: 11 =====",
 		"build-log-line",
@@ -273,6 +392,106 @@ func TestGetLogs_FallbackPerJobLogs(t *testing.T) {
 	}
 }
 
+// TestGetLogs_OversizedArchive asserts a run archive larger than the
+// HTTP client's body limit is downloaded once and replaced by the
+// run's per-job logs, without the fallback marker that signals an
+// expired archive.
+func TestGetLogs_OversizedArchive(t *testing.T) {
+	t.Parallel()
+
+	var (
+		server         *httptest.Server
+		archiveFetches atomic.Int32
+	)
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/100/jobs"):
+			_ = json.NewEncoder(w).Encode(github.Jobs{
+				TotalCount: new(1),
+				Jobs:       []*github.WorkflowJob{{ID: new(int64(11)), Name: new("build")}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/100/logs"):
+			w.Header().Set("Location", server.URL+"/raw/run-100.zip")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/100"):
+			_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+		case strings.HasSuffix(r.URL.Path, "/actions/jobs/11/logs"):
+			w.Header().Set("Location", server.URL+"/raw/job-11.txt")
+			w.WriteHeader(http.StatusFound)
+		case strings.HasSuffix(r.URL.Path, "/raw/run-100.zip"):
+			archiveFetches.Add(1)
+			_, _ = w.Write(bytes.Repeat([]byte("x"), 4096))
+		case strings.HasSuffix(r.URL.Path, "/raw/job-11.txt"):
+			_, _ = io.WriteString(w, "build-log-line\n")
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	gh, _ := newTestClients(t, server)
+	hc := httpclient.New(
+		httpclient.WithHTTPClient(&http.Client{Timeout: 5 * time.Second, Transport: server.Client().Transport}),
+		httpclient.WithRateLimit(rate.Inf, 10),
+		httpclient.WithMaxBodyBytes(1024),
+	)
+	rc, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "o", "r", 100, "tok")
+	if err != nil {
+		t.Fatalf("GetLogs: %v", err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	if workflow.IsPerJobFallback(rc) {
+		t.Error("per-job logs of an oversized archive carry the expired-archive fallback marker")
+	}
+	got, err := workflow.ExtractLogs(rc)
+	if err != nil {
+		t.Fatalf("ExtractLogs: %v", err)
+	}
+	if !strings.Contains(got, "build-log-line") {
+		t.Errorf("logs lack the job's log:\n%s", got)
+	}
+	if n := archiveFetches.Load(); n != 1 {
+		t.Errorf("oversized archive fetched %d times, want 1", n)
+	}
+}
+
+// TestGetJobLogs asserts one job's log is downloaded through the
+// per-job endpoint's redirect with the token, and a failed lookup is
+// returned as an error.
+func TestGetJobLogs(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/r/actions/jobs/11/logs":
+			w.Header().Set("Location", server.URL+"/raw/job-11.txt")
+			w.WriteHeader(http.StatusFound)
+		case "/raw/job-11.txt":
+			if got := r.Header.Get("Authorization"); got != "token tok" {
+				t.Errorf("Authorization=%q, want the token", got)
+			}
+			_, _ = io.WriteString(w, "2025-03-14T12:00:00.0000000Z build-log-line\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	gh, hc := newTestClients(t, server)
+
+	body, err := workflow.GetJobLogs(t.Context(), hc, gh, "o", "r", 11, "tok")
+	if err != nil {
+		t.Fatalf("GetJobLogs: %v", err)
+	}
+	if !strings.Contains(string(body), "build-log-line") {
+		t.Errorf("body=%q, want the job's log", body)
+	}
+	if _, err := workflow.GetJobLogs(t.Context(), hc, gh, "o", "r", 12, "tok"); err == nil {
+		t.Error("GetJobLogs of a missing job succeeded")
+	}
+}
+
 // TestGetLogs_FallbackPaginatedJobs covers the multi-page jobs listing
 // branch. The server emits a Link header pointing to ?page=2; the
 // client must follow it and download logs for jobs from both pages.
@@ -503,3 +722,25 @@ func TestGetLogs_CancelledFallbackEmpty(t *testing.T) {
 		t.Fatalf("expected errors.Is(err, ErrRunHasNoLogs); got %v", err)
 	}
 }
+
+// TestOptions_FallbackConcurrency pins the knob's clamping: it can
+// lower the per-job fan-out but never raise it above the built-in
+// limit.
+func TestOptions_FallbackConcurrency(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		n    int
+		want int
+	}{
+		{n: 0, want: workflow.DefaultFallbackConcurrency},
+		{n: -3, want: workflow.DefaultFallbackConcurrency},
+		{n: 4, want: 4},
+		{n: 1000, want: workflow.DefaultFallbackConcurrency},
+	}
+	for _, tc := range cases {
+		if got := workflow.FallbackConcurrencyForTest(&workflow.Options{FallbackConcurrency: tc.n}); got != tc.want {
+			t.Fatalf("FallbackConcurrency %d: limit=%d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package workflow
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DetectorMaskBypass names the masked-secret bypass heuristics returned
+// by [NewMaskBypassDetector]. Like [DetectorEgress] it is opt-in.
+const DetectorMaskBypass = "mask-bypass"
+
+// Masked secrets shorter or longer than these bounds are not worth
+// reporting: shorter recoveries are mostly coincidental tokens, and no
+// realistic credential is longer.
+const (
+	minMaskedSecretLen = 8
+	maxMaskedSecretLen = 256
+)
+
+var (
+	// maskStepRE marks the start of a step's log group and captures the
+	// first line of its script.
+	maskStepRE = regexp.MustCompile(`^##\[group\]Run (.*)$`)
+	// maskEnvRE captures an env entry echoed in a step's group whose
+	// value GitHub masked.
+	maskEnvRE = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*):\s*\*\*\*$`)
+	// maskTransformRE matches the commands that turn a secret into a
+	// form GitHub's exact-match masking no longer recognizes.
+	maskTransformRE = regexp.MustCompile(`(?:^|[\s|;&(])(base64|base32|xxd|od|hexdump|rev|fold|sed|tr|openssl|python3?|perl)(?:\s|$)`)
+	// maskBase64RE and maskHexRE match an output line that is one
+	// encoded token.
+	maskBase64RE = regexp.MustCompile(`^[A-Za-z0-9+/]{12,}={0,2}$`)
+	maskHexRE    = regexp.MustCompile(`^(?:[0-9a-fA-F]{2}){8,}$`)
+)
+
+// maskBypassDetector flags the technique of printing a secret after
+// transforming it (base64, hex, reversing, or splitting it into single
+// characters) so the runner's masking, which only replaces the exact
+// secret value with ***, lets it through. A step is armed when its
+// script pipes a masked value (*** in the script, or a variable whose
+// env entry shows ***) into a transforming command; the step's output
+// is then searched for a token that decodes back to plausible secret
+// text, between minMaskedSecretLen and maxMaskedSecretLen characters.
+type maskBypassDetector struct {
+	inGroup bool
+	// script holds the step's script lines that run a transforming
+	// command; they are judged once the env block has been seen.
+	script []string
+	masked []string
+	// tool is the transforming command of the armed step, or "".
+	tool string
+	// chars accumulates consecutive single-character output lines, as
+	// printed by e.g. fold -w1.
+	chars     []string
+	charsLine string
+}
+
+var _ ScopedDetector = (*maskBypassDetector)(nil)
+
+// NewMaskBypassDetector returns the opt-in masked-secret bypass
+// detector. It is a [ScopedDetector] because the masked value, the
+// transforming command, and the leaked output sit on different lines
+// of the same step. Findings carry KeyType DetectorMaskBypass,
+// SeverityHigh, the transformed token in Encoded, and the recovered
+// text in Decoded.
+func NewMaskBypassDetector() Detector {
+	return &maskBypassDetector{}
+}
+
+// NewScan returns a detector with no step in progress.
+func (*maskBypassDetector) NewScan() Detector {
+	return &maskBypassDetector{}
+}
+
+// Detect follows step boundaries, arms on a masked value fed to a
+// transforming command, and reports recovered output in armed steps.
+func (d *maskBypassDetector) Detect(line string, lc LineContext) []Finding {
+	clean := strings.TrimSpace(timestampRE.ReplaceAllString(line, ""))
+
+	if m := maskStepRE.FindStringSubmatch(clean); m != nil {
+		out := d.flushChars(lc)
+		*d = maskBypassDetector{inGroup: true}
+		d.scriptLine(m[1])
+		return out
+	}
+	if d.inGroup {
+		switch {
+		case clean == "##[endgroup]":
+			d.inGroup = false
+			d.arm()
+		case maskEnvRE.MatchString(clean):
+			d.masked = append(d.masked, maskEnvRE.FindStringSubmatch(clean)[1])
+		default:
+			d.scriptLine(clean)
+		}
+		return nil
+	}
+	if d.tool == "" {
+		return nil
+	}
+
+	if len([]rune(clean)) == 1 && clean != "*" {
+		if len(d.chars) == 0 {
+			d.charsLine = clean
+		}
+		d.chars = append(d.chars, clean)
+		return nil
+	}
+	out := d.flushChars(lc)
+	if f, ok := d.recover(clean); ok {
+		out = append(out, d.finding(lc, f))
+	}
+	return out
+}
+
+// scriptLine records s when it runs a transforming command.
+func (d *maskBypassDetector) scriptLine(s string) {
+	if maskTransformRE.MatchString(s) {
+		d.script = append(d.script, s)
+	}
+}
+
+// arm picks the transforming command of the first script line that
+// consumes a masked value, directly or through a masked env variable.
+func (d *maskBypassDetector) arm() {
+	for _, s := range d.script {
+		uses := strings.Contains(s, "***")
+		for _, name := range d.masked {
+			if strings.Contains(s, "$"+name) || strings.Contains(s, "${"+name) || strings.Contains(s, "$env:"+name) {
+				uses = true
+			}
+		}
+		if uses {
+			d.tool = maskTransformRE.FindStringSubmatch(s)[1]
+			return
+		}
+	}
+}
+
+// recover returns a finding for an output line that decodes back to
+// plausible secret text, trying each form the transforming commands
+// produce. A hex token is also valid base64, so hex, the narrower
+// alphabet, is tried first and every form is tried until one yields
+// plausible text.
+func (d *maskBypassDetector) recover(clean string) (Finding, bool) {
+	if clean == "" || strings.Contains(clean, "***") {
+		return Finding{}, false
+	}
+	var candidates []string
+	if maskHexRE.MatchString(clean) {
+		if b, err := hex.DecodeString(clean); err == nil {
+			candidates = append(candidates, string(b))
+		}
+	}
+	if maskBase64RE.MatchString(clean) {
+		if s, err := tryBase64Decode(clean); err == nil {
+			candidates = append(candidates, s)
+		}
+	}
+	if d.tool == "rev" && !strings.ContainsAny(clean, " \t") {
+		r := []rune(clean)
+		slices.Reverse(r)
+		candidates = append(candidates, string(r))
+	}
+	if joined, ok := splitChars(clean); ok {
+		candidates = append(candidates, joined)
+	}
+	for _, decoded := range candidates {
+		decoded = strings.TrimRight(decoded, "\r\n")
+		if plausibleSecret(decoded) {
+			return Finding{Encoded: clean, Decoded: decoded, LineData: clean}, true
+		}
+	}
+	return Finding{}, false
+}
+
+// flushChars reports the single-character output lines collected so
+// far when they spell plausible secret text, and resets them.
+func (d *maskBypassDetector) flushChars(lc LineContext) []Finding {
+	chars := d.chars
+	d.chars = nil
+	joined := strings.Join(chars, "")
+	if d.tool == "" || !plausibleSecret(joined) {
+		return nil
+	}
+	return []Finding{d.finding(lc, Finding{
+		Encoded:  strings.Join(chars, "\n"),
+		Decoded:  joined,
+		LineData: d.charsLine,
+	})}
+}
+
+func (d *maskBypassDetector) finding(lc LineContext, f Finding) Finding {
+	lc.Logger.Warnf("Possible masked secret bypass via %s at log line %d in Run ID: %d", d.tool, lc.LineNum, lc.RunID)
+	f.KeyType = DetectorMaskBypass
+	f.Severity = SeverityHigh
+	f.Note = fmt.Sprintf("likely mask bypass: a masked value was passed to %s and the step printed %d characters that decode back from it", d.tool, len([]rune(f.Decoded)))
+	return f
+}
+
+// splitChars rejoins a line of single characters separated by one
+// repeated delimiter, such as "s e c r e t" or "s,e,c,r,e,t".
+func splitChars(s string) (string, bool) {
+	r := []rune(s)
+	if len(r) < 2*minMaskedSecretLen-1 || len(r)%2 == 0 {
+		return "", false
+	}
+	delim := r[1]
+	if unicode.IsLetter(delim) || unicode.IsDigit(delim) {
+		return "", false
+	}
+	var b strings.Builder
+	for i, c := range r {
+		if i%2 == 1 {
+			if c != delim {
+				return "", false
+			}
+			continue
+		}
+		if c == delim || unicode.IsSpace(c) {
+			return "", false
+		}
+		b.WriteRune(c)
+	}
+	return b.String(), true
+}
+
+// plausibleSecret reports whether s has a secret's length and is one
+// printable token without spaces.
+func plausibleSecret(s string) bool {
+	n := len([]rune(s))
+	if n < minMaskedSecretLen || n > maxMaskedSecretLen {
+		return false
+	}
+	for _, c := range s {
+		if !unicode.IsPrint(c) || unicode.IsSpace(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package workflow_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/ioc"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+func TestParseLogs_MaskBypassDetector(t *testing.T) {
+	t.Cleanup(workflow.SnapshotDetectorsForTest())
+
+	if err := workflow.RegisterDetector(workflow.DetectorMaskBypass, workflow.NewMaskBypassDetector()); err != nil {
+		t.Fatalf("RegisterDetector: %v", err)
+	}
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"NEVER_PRESENT"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	const secret = "hunter2-s3cr3t-value"
+	cases := []struct {
+		name        string
+		log         string
+		wantDecoded string
+	}{
+		{
+			name: "base64 of an inline masked secret",
+			log: "2025-03-14T00:00:00.0000000Z ##[group]Run echo *** | base64\n" +
+				"2025-03-14T00:00:00.0000000Z shell: /usr/bin/bash -e {0}\n2025-03-14T00:00:00.0000000Z ##[endgroup]\n" +
+				"2025-03-14T00:00:01.0000000Z " + base64.StdEncoding.EncodeToString([]byte(secret+"\n")) + "\n",
+			wantDecoded: secret,
+		},
+		{
+			name: "hex of a masked env variable",
+			log: "##[group]Run echo \"$TOKEN\" | xxd -p\nshell: /usr/bin/bash -e {0}\nenv:\n  TOKEN: ***\n##[endgroup]\n" +
+				hex.EncodeToString([]byte(secret)) + "\n",
+			wantDecoded: secret,
+		},
+		{
+			name:        "reversed",
+			log:         "##[group]Run echo *** | rev\n##[endgroup]\neulav-t3rc3s-2retnuh\n",
+			wantDecoded: secret,
+		},
+		{
+			name:        "split with spaces",
+			log:         "##[group]Run echo *** | sed 's/./& /g'\n##[endgroup]\nh u n t e r 2 - s 3 c r 3 t\n",
+			wantDecoded: "hunter2-s3cr3t",
+		},
+		{
+			name:        "one character per line",
+			log:         "##[group]Run echo *** | fold -w1\n##[endgroup]\nh\nu\nn\nt\ne\nr\n2\n!\n##[group]Run next\n##[endgroup]\n",
+			wantDecoded: "hunter2!",
+		},
+		{
+			name: "masking held",
+			log:  "##[group]Run echo *** | base64 -d > key.pem\n##[endgroup]\n***\n",
+		},
+		{
+			name: "transform without a masked value",
+			log: "##[group]Run echo hello-world-value | base64\n##[endgroup]\n" +
+				base64.StdEncoding.EncodeToString([]byte("hello-world-value")) + "\n",
+		},
+		{
+			name: "output of a later step",
+			log:  "##[group]Run echo *** | rev\n##[endgroup]\n##[group]Run make\n##[endgroup]\neulav-t3rc3s-2retnuh\n",
+		},
+		{
+			name: "recovered text too short",
+			log:  "##[group]Run echo *** | rev\n##[endgroup]\nterces\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, _ := workflow.ParseLogs(newTestLogger(), tc.log, 1, custom)
+			var got []workflow.Finding
+			for _, f := range findings {
+				if f.KeyType == workflow.DetectorMaskBypass {
+					got = append(got, f)
+				}
+			}
+			if tc.wantDecoded == "" {
+				if len(got) != 0 {
+					t.Fatalf("findings=%+v, want no mask-bypass finding", got)
+				}
+				return
+			}
+			if len(got) != 1 {
+				t.Fatalf("findings=%+v, want exactly one mask-bypass finding", findings)
+			}
+			f := got[0]
+			if f.Decoded != tc.wantDecoded || f.Severity != workflow.SeverityHigh {
+				t.Fatalf("finding=%+v, want Decoded %q at high severity", f, tc.wantDecoded)
+			}
+			if f.Note == "" {
+				t.Fatal("Note is empty, want the transforming command and recovered length")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package workflow
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Options configures how [GetLogsWithOptions] fetches run logs and how
+// [ParseReader] scans them. A nil *Options, like the zero value,
+// downloads each run's whole archive without a cache and runs every
+// detector with its defaults. An Options is read, never written, by
+// the functions it is passed to, so one value may be shared by
+// concurrent scans.
+type Options struct {
+	// MaxDecodeDepth bounds how many nested base64 layers the
+	// built-in base64 detector unwraps. A non-positive value selects
+	// [DefaultMaxDecodeDepth].
+	MaxDecodeDepth int
+	// AllowBinaryDecoded makes the built-in base64 detector report a
+	// block whose first decoded layer is not valid UTF-8, such as a
+	// compiled dropper or Latin-1 text, instead of discarding it. The
+	// finding's Decoded keeps printable ASCII and escapes other bytes
+	// as \xNN, and its Note says so.
+	AllowBinaryDecoded bool
+	// EnableDetectors, when non-empty, limits the detectors run to
+	// those it names; DisableDetectors are never run. Both accept
+	// built-in and registered detector names, so opt-in detectors
+	// must be registered first. [Options.Validate] checks them.
+	EnableDetectors  []string
+	DisableDetectors []string
+	// ContextLines records up to this many log lines before and after
+	// each matching line in the finding's Context. A non-positive
+	// value disables capture, and values above [MaxContextLines] are
+	// capped.
+	ContextLines int
+	// Explain records in each finding's Explanation why it was
+	// reported: the detector, what it matched, and the log line, such
+	// as "matched IOC content \"0e58ed8\" at line 412". A detector may
+	// set Explanation itself, which is kept.
+	Explain bool
+	// JobFilter, when non-nil, narrows the logs GetLogs and
+	// [GetAttemptLogs] return to the matching jobs.
+	JobFilter *JobFilter
+	// LogCache, when non-nil with a Dir, is the cache GetLogs reads
+	// from and writes to.
+	LogCache *LogCache
+	// FallbackConcurrency bounds concurrent per-job log downloads when
+	// GetLogs falls back from an expired run-level archive.
+	// Non-positive values select [DefaultFallbackConcurrency]; values
+	// above it are clamped so the knob can only lower the fan-out.
+	FallbackConcurrency int
+}
+
+// Validate reports an unknown detector name in EnableDetectors or
+// DisableDetectors, or a filter that leaves no detector to run.
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+	known := detectorNames()
+	for _, name := range slices.Concat(o.EnableDetectors, o.DisableDetectors) {
+		if !slices.Contains(known, name) {
+			return fmt.Errorf("unknown detector %q (known: %s)", name, strings.Join(known, ", "))
+		}
+	}
+	if !slices.ContainsFunc(known, o.detectorAllowed) {
+		return fmt.Errorf("detector filter leaves no detector enabled")
+	}
+	return nil
+}
+
+// Detectors returns the names of every detector [ParseReader] runs
+// with o, in order.
+func (o *Options) Detectors() []string {
+	active := o.activeDetectors()
+	out := make([]string, 0, len(active))
+	for _, nd := range active {
+		out = append(out, nd.name)
+	}
+	return out
+}
+
+// detectorAllowed reports whether name passes o's detector filter.
+func (o *Options) detectorAllowed(name string) bool {
+	if o == nil {
+		return true
+	}
+	if len(o.EnableDetectors) > 0 && !slices.Contains(o.EnableDetectors, name) {
+		return false
+	}
+	return !slices.Contains(o.DisableDetectors, name)
+}
+
+func (o *Options) maxDecodeDepth() int {
+	if o == nil || o.MaxDecodeDepth <= 0 {
+		return DefaultMaxDecodeDepth
+	}
+	return o.MaxDecodeDepth
+}
+
+func (o *Options) allowBinaryDecoded() bool {
+	return o != nil && o.AllowBinaryDecoded
+}
+
+func (o *Options) contextLines() int {
+	if o == nil {
+		return 0
+	}
+	return min(max(o.ContextLines, 0), MaxContextLines)
+}
+
+func (o *Options) explain() bool {
+	return o != nil && o.Explain
+}
+
+func (o *Options) jobFilter() *JobFilter {
+	if o == nil {
+		return nil
+	}
+	return o.JobFilter
+}
+
+func (o *Options) logCache() *LogCache {
+	if o == nil || o.LogCache == nil || o.LogCache.Dir == "" {
+		return nil
+	}
+	return o.LogCache
+}
+
+func (o *Options) fallbackConcurrency() int {
+	if o == nil || o.FallbackConcurrency <= 0 {
+		return DefaultFallbackConcurrency
+	}
+	return min(o.FallbackConcurrency, DefaultFallbackConcurrency)
+}
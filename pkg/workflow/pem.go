@@ -0,0 +1,80 @@
+package workflow
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pemBoundaryRE matches the BEGIN/END armor lines of PEM-encoded
+// private keys and certificates. The label capture covers every
+// OpenSSL/OpenSSH variant ("RSA PRIVATE KEY", "OPENSSH PRIVATE KEY",
+// "ENCRYPTED PRIVATE KEY", "CERTIFICATE", ...).
+var pemBoundaryRE = regexp.MustCompile(`-----(BEGIN|END) ((?:[A-Z0-9]+ )*(?:PRIVATE KEY|CERTIFICATE))-----`)
+
+// pemDetector recognizes PEM blocks, which GitHub logs print one armor
+// or base64 line at a time and which the base64 regex therefore sees
+// only in fragments. A block is reported once its END line arrives with
+// a matching label and at least some body between the markers, so a
+// script that merely echoes a BEGIN marker is not flagged.
+//
+// The reported LineData is the BEGIN armor line only; the key material
+// itself is never copied into findings.
+type pemDetector struct {
+	open      string
+	beginLine string
+	hasBody   bool
+}
+
+var _ ScopedDetector = (*pemDetector)(nil)
+
+// NewScan returns a detector with no open block so concurrent runs
+// never share state.
+func (*pemDetector) NewScan() Detector {
+	return &pemDetector{}
+}
+
+// Detect advances the block state machine by one line. BEGIN and END
+// markers on the same line (e.g. a key printed with escaped newlines)
+// are handled in order.
+func (d *pemDetector) Detect(line string, lc LineContext) []Finding {
+	clean := timestampRE.ReplaceAllString(line, "")
+	locs := pemBoundaryRE.FindAllStringSubmatchIndex(clean, -1)
+
+	var out []Finding
+	prev := 0
+	for _, loc := range locs {
+		if d.open != "" && strings.TrimSpace(clean[prev:loc[0]]) != "" {
+			d.hasBody = true
+		}
+		label := clean[loc[4]:loc[5]]
+		switch clean[loc[2]:loc[3]] {
+		case "BEGIN":
+			d.open = label
+			d.beginLine = strings.TrimSpace(clean[:loc[1]])
+			d.hasBody = false
+		case "END":
+			if d.open == label && d.hasBody {
+				out = append(out, d.finding(lc))
+			}
+			d.open = ""
+		}
+		prev = loc[1]
+	}
+	if d.open != "" && strings.TrimSpace(clean[prev:]) != "" {
+		d.hasBody = true
+	}
+	return out
+}
+
+// finding reports the block just closed. Every PEM block is critical,
+// certificates included: a certificate printed by a compromised step
+// usually travels with its private key, and KeyType tells the two
+// apart for triage.
+func (d *pemDetector) finding(lc LineContext) Finding {
+	lc.Logger.Warnf("PEM %s block found ending at log line %d in Run ID: %d", d.open, lc.LineNum, lc.RunID)
+	return Finding{
+		LineData: d.beginLine,
+		KeyType:  d.open,
+		Severity: SeverityCritical,
+	}
+}
@@ -0,0 +1,44 @@
+package workflow
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/google/go-github/v86/github"
+)
+
+// ErrLogsExpired marks a run whose logs GitHub no longer serves
+// because they aged past the repository's log retention window. It
+// wraps ErrRunHasNoLogs, so callers skip such runs like any other run
+// without logs, and can count them to report the limits of a
+// retrospective scan.
+var ErrLogsExpired = fmt.Errorf("%w: logs have expired", ErrRunHasNoLogs)
+
+// DefaultLogRetention is GitHub's default retention period for
+// workflow run logs. A repository can configure up to 400 days; a run
+// older than the default whose logs are gone is treated as expired.
+const DefaultLogRetention = 90 * 24 * time.Hour
+
+// logsExpiredStatus reports whether the logs API answering status for
+// run means its logs have expired: 410 Gone says so outright, and a
+// 404 for a run older than DefaultLogRetention almost always does.
+func logsExpiredStatus(run *github.WorkflowRun, status int, now time.Time) bool {
+	switch status {
+	case http.StatusGone:
+		return true
+	case http.StatusNotFound:
+		created := run.GetCreatedAt().Time
+		return !created.IsZero() && now.Sub(created) > DefaultLogRetention
+	}
+	return false
+}
+
+// logsExpired logs and returns the skip for a run whose logs expired,
+// once the per-job fallback also failed with cause.
+func logsExpired(logger *clog.Logger, run *github.WorkflowRun, cause error) error {
+	logger.Infof("Logs of run %d from %s have expired; skipping: %v",
+		run.GetID(), run.GetCreatedAt().Format(time.DateOnly), cause)
+	return fmt.Errorf("run %d: %w", run.GetID(), ErrLogsExpired)
+}
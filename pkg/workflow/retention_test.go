@@ -0,0 +1,72 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestGetLogs_LogsExpired asserts a run whose run-level and per-job
+// logs are both gone is classified as expired on 410 Gone, and on 404
+// only once the run is older than the default retention window.
+func TestGetLogs_LogsExpired(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		age    time.Duration
+		want   bool
+	}{
+		{name: "gone", status: http.StatusGone, age: time.Hour, want: true},
+		{name: "not found past retention", status: http.StatusNotFound, age: 200 * 24 * time.Hour, want: true},
+		{name: "not found within retention", status: http.StatusNotFound, age: 24 * time.Hour},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/5/logs"), strings.HasSuffix(r.URL.Path, "/actions/jobs/1/logs"):
+					w.WriteHeader(tc.status)
+					_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/5/jobs"):
+					_ = json.NewEncoder(w).Encode(github.Jobs{
+						TotalCount: new(1),
+						Jobs:       []*github.WorkflowJob{{ID: new(int64(1)), Name: new("build")}},
+					})
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/5"):
+					_ = json.NewEncoder(w).Encode(github.WorkflowRun{
+						ID:         new(int64(5)),
+						Status:     new("completed"),
+						Conclusion: new("success"),
+						CreatedAt:  &github.Timestamp{Time: time.Now().Add(-tc.age)},
+					})
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(ts.Close)
+
+			gh, hc := newTestClients(t, ts)
+			_, err := workflow.GetLogs(t.Context(), newTestLogger(), hc, gh, "octo", "demo", 5, "tok")
+			if got := errors.Is(err, workflow.ErrLogsExpired); got != tc.want {
+				t.Fatalf("GetLogs error %v, want ErrLogsExpired=%v", err, tc.want)
+			}
+			if tc.want && !errors.Is(err, workflow.ErrRunHasNoLogs) {
+				t.Fatalf("ErrLogsExpired does not wrap ErrRunHasNoLogs: %v", err)
+			}
+			if err == nil {
+				t.Fatal("GetLogs succeeded without any logs")
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package workflow
+
+// Severity labels attached to findings. The empty string means the
+// detector did not assign one, which is the case for the built-in IOC
+// and base64 detectors.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank orders severity labels so callers can compare them.
+// Unknown and empty labels rank below SeverityLow.
+func SeverityRank(s string) int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// MaxSeverity returns whichever of a and b ranks higher.
+func MaxSeverity(a, b string) string {
+	if SeverityRank(b) > SeverityRank(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,91 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+)
+
+// Run is one execution of a CI pipeline, described in the terms every
+// [LogSource] shares.
+type Run struct {
+	ID int64
+	// Name is the workflow file or pipeline the run executed.
+	Name string
+	// URL is the run's page in the provider's web UI.
+	URL string
+	// Status and Conclusion are the provider's own values; providers
+	// with a single state report it as Status.
+	Status     string
+	Conclusion string
+	CreatedAt  time.Time
+}
+
+// LogSource lists the runs of one CI pipeline and retrieves their logs,
+// so the detectors ([ParseReader]) can scan any provider's output.
+type LogSource interface {
+	// ListRuns returns the runs created between start and end.
+	ListRuns(ctx context.Context, start, end time.Time) ([]Run, error)
+	// GetRunLogs returns the plain-text logs of every job of run.
+	// Runs without logs to scan are reported with an error wrapping
+	// [ErrRunHasNoLogs].
+	GetRunLogs(ctx context.Context, run Run) (io.ReadCloser, error)
+}
+
+// GitHubSource is the [LogSource] for one GitHub Actions workflow. It
+// combines [ListWorkflowRuns], [GetLogs], and [ExtractLogs], so the
+// fallbacks, and the log cache and job filter of Options, apply as
+// they do to a scan.
+type GitHubSource struct {
+	Logger     *clog.Logger
+	HTTP       *httpclient.Client
+	Client     *github.Client
+	Owner      string
+	Repo       string
+	WorkflowID int64
+	Token      string
+	MaxRetries int
+	Options    *Options
+}
+
+var _ LogSource = (*GitHubSource)(nil)
+
+// ListRuns lists the workflow's runs in the window.
+func (s *GitHubSource) ListRuns(ctx context.Context, start, end time.Time) ([]Run, error) {
+	runs, err := ListWorkflowRuns(ctx, s.Logger, s.Client, s.Owner, s.Repo, s.WorkflowID, start, end, s.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Run, 0, len(runs))
+	for _, r := range runs {
+		out = append(out, Run{
+			ID:         r.GetID(),
+			Name:       r.GetPath(),
+			URL:        fmt.Sprintf("https://github.com/%s/%s/actions/runs/%d", s.Owner, s.Repo, r.GetID()),
+			Status:     r.GetStatus(),
+			Conclusion: r.GetConclusion(),
+			CreatedAt:  r.GetCreatedAt().Time,
+		})
+	}
+	return out, nil
+}
+
+// GetRunLogs downloads and extracts the run's log archive.
+func (s *GitHubSource) GetRunLogs(ctx context.Context, run Run) (io.ReadCloser, error) {
+	rc, err := GetLogsWithOptions(ctx, s.Logger, s.HTTP, s.Client, s.Owner, s.Repo, run.ID, s.Token, s.Options)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	text, err := ExtractLogsContext(ctx, rc)
+	if err != nil {
+		return nil, fmt.Errorf("extracting logs for run %d: %w", run.ID, err)
+	}
+	return io.NopCloser(strings.NewReader(text)), nil
+}
@@ -0,0 +1,70 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v86/github"
+)
+
+// GetJobSummaries returns the check-run output of every job in runID,
+// keyed by job ID. Each Actions job is backed by a check run with the
+// same ID, whose output carries the summary text published for that
+// job. The REST API does not expose the raw $GITHUB_STEP_SUMMARY file,
+// so this is the summary content that can be retrieved at all.
+//
+// Jobs whose check run is forbidden or missing, or whose output is
+// empty, are omitted rather than treated as errors.
+func GetJobSummaries(ctx context.Context, gh *github.Client, owner, repo string, runID int64) (map[int64]string, error) {
+	if gh == nil {
+		return nil, fmt.Errorf("github client must not be nil")
+	}
+	jobs, err := listAllJobs(ctx, gh, owner, repo, runID)
+	if err != nil {
+		return nil, fmt.Errorf("listing jobs: %w", err)
+	}
+
+	out := make(map[int64]string, len(jobs))
+	for _, job := range jobs {
+		jobID := job.GetID()
+		if jobID == 0 {
+			continue
+		}
+		cr, resp, err := gh.Checks.GetCheckRun(ctx, owner, repo, jobID)
+		if err != nil {
+			if isInaccessible(resp, err) {
+				continue
+			}
+			return nil, fmt.Errorf("fetching check run for job %d: %w", jobID, err)
+		}
+
+		output := cr.GetOutput()
+		var parts []string
+		for _, s := range []string{output.GetTitle(), output.GetSummary(), output.GetText()} {
+			if strings.TrimSpace(s) != "" {
+				parts = append(parts, s)
+			}
+		}
+		if len(parts) > 0 {
+			out[jobID] = strings.Join(parts, "\n")
+		}
+	}
+	return out, nil
+}
+
+// isInaccessible reports whether a failed API call means the resource
+// is not available to this token, as opposed to a transient failure.
+func isInaccessible(resp *github.Response, err error) bool {
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	var ghErr *github.ErrorResponse
+	if errors.As(err, &ghErr) && ghErr.Response != nil {
+		status = ghErr.Response.StatusCode
+	}
+	return status == http.StatusForbidden || status == http.StatusNotFound || status == http.StatusGone
+}
@@ -0,0 +1,53 @@
+package workflow_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+	"github.com/google/go-github/v86/github"
+)
+
+// TestGetJobSummaries asserts summary text is collected per job, empty
+// outputs are omitted, and an inaccessible check run is skipped rather
+// than failing the whole run.
+func TestGetJobSummaries(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/o/r/actions/runs/5/jobs", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.Jobs{
+			TotalCount: new(3),
+			Jobs: []*github.WorkflowJob{
+				{ID: new(int64(1))},
+				{ID: new(int64(2))},
+				{ID: new(int64(3))},
+			},
+		})
+	})
+	mux.HandleFunc("/repos/o/r/check-runs/1", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.CheckRun{
+			ID:     new(int64(1)),
+			Output: &github.CheckRunOutput{Title: new("build"), Summary: new("DROP_THIS_TOKEN")},
+		})
+	})
+	mux.HandleFunc("/repos/o/r/check-runs/2", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(github.CheckRun{ID: new(int64(2)), Output: &github.CheckRunOutput{}})
+	})
+	mux.HandleFunc("/repos/o/r/check-runs/3", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	gh, _ := newTestClients(t, ts)
+
+	got, err := workflow.GetJobSummaries(t.Context(), gh, "o", "r", 5)
+	if err != nil {
+		t.Fatalf("GetJobSummaries: %v", err)
+	}
+	if len(got) != 1 || got[1] != "build\nDROP_THIS_TOKEN" {
+		t.Fatalf("summaries=%v, want only job 1 with title and summary", got)
+	}
+}
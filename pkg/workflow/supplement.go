@@ -0,0 +1,129 @@
+package workflow
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/chainguard-dev/clog"
+	httpclient "github.com/chainguard-dev/ghscan/pkg/httpclient"
+	"github.com/google/go-github/v86/github"
+)
+
+// minArchiveLogBytes is the least log text a run-level archive is
+// expected to hold. Every job log opens with the runner's "Set up job"
+// lines, so an archive below it has lost its content, as the logs API
+// sometimes serves for runs near the retention boundary.
+const minArchiveLogBytes = 16
+
+// archiveLooksTruncated reports why a run-level archive appears to be
+// missing log content, or "" when it looks complete. An archive that
+// is not a zip at all is left to ExtractLogs to reject.
+func archiveLooksTruncated(data []byte) string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return ""
+	}
+	if len(zr.File) == 0 {
+		return "no entries"
+	}
+	var total uint64
+	for _, file := range zr.File {
+		total += file.UncompressedSize64
+	}
+	if total < minArchiveLogBytes {
+		return fmt.Sprintf("%d bytes of log text in %d entries", total, len(zr.File))
+	}
+	return ""
+}
+
+// supplementArchive adds per-job logs to a run-level archive that
+// archiveLooksTruncated flagged. Jobs without a non-empty entry in the
+// archive are downloaded through the per-job logs endpoint and
+// appended as <job name>/per-job-<job ID>.txt, so ExtractLogs and a
+// [JobFilter] treat them like the archive's own entries. When nothing
+// can be added, the archive is returned unchanged: a partial archive
+// still beats none.
+func supplementArchive(ctx context.Context, logger *clog.Logger, hc *httpclient.Client, gh *github.Client, owner, repo string, runID int64, token string, data []byte, reason string, limit int) []byte {
+	logger.Warnf("Log archive for run %d looks truncated (%s); supplementing with per-job logs", runID, reason)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return data
+	}
+	present := make(map[string]bool, len(zr.File))
+	for _, file := range zr.File {
+		if file.UncompressedSize64 > 0 {
+			present[archiveEntryJob(file.Name)] = true
+		}
+	}
+
+	jobs, err := listAllJobs(ctx, gh, owner, repo, runID)
+	if err != nil {
+		logger.Warnf("Listing jobs to supplement run %d: %v", runID, err)
+		return data
+	}
+	var missing []*github.WorkflowJob
+	for _, job := range jobs {
+		if job.GetID() != 0 && !present[archiveJobName(job.GetName())] {
+			missing = append(missing, job)
+		}
+	}
+	if len(missing) == 0 {
+		logger.Debugf("Every job of run %d has log content in the archive; nothing to supplement", runID)
+		return data
+	}
+
+	jobLogs, err := downloadJobLogs(ctx, logger, hc, gh, owner, repo, missing, token, limit)
+	if err != nil {
+		logger.Warnf("Downloading per-job logs to supplement run %d: %v", runID, err)
+		return data
+	}
+	merged, err := mergeArchive(zr, missing, jobLogs)
+	if err != nil {
+		logger.Warnf("Merging per-job logs into the archive of run %d: %v", runID, err)
+		return data
+	}
+	logger.Infof("Supplemented the log archive of run %d with per-job logs of %d of %d jobs", runID, len(jobLogs), len(jobs))
+	return merged
+}
+
+// mergeArchive copies every entry of zr and appends the logs in
+// jobLogs under the names of their jobs. The job log readers are
+// closed.
+func mergeArchive(zr *zip.Reader, jobs []*github.WorkflowJob, jobLogs map[int64]io.ReadCloser) ([]byte, error) {
+	defer func() {
+		for _, rc := range jobLogs {
+			_ = rc.Close()
+		}
+	}()
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, file := range zr.File {
+		if err := zw.Copy(file); err != nil {
+			return nil, fmt.Errorf("copy zip member: %w", err)
+		}
+	}
+	for _, job := range jobs {
+		rc, ok := jobLogs[job.GetID()]
+		if !ok {
+			continue
+		}
+		dir := cmp.Or(archiveJobName(job.GetName()), fmt.Sprintf("Job-%d", job.GetID()))
+		w, err := zw.Create(fmt.Sprintf("%s/per-job-%d.txt", dir, job.GetID()))
+		if err != nil {
+			return nil, fmt.Errorf("create zip member: %w", err)
+		}
+		if _, err := io.Copy(w, rc); err != nil {
+			return nil, fmt.Errorf("write logs for job %d: %w", job.GetID(), err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close zip: %w", err)
+	}
+	return out.Bytes(), nil
+}
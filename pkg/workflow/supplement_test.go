@@ -0,0 +1,127 @@
+package workflow_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/ghscan/pkg/workflow"
+)
+
+// zipOf builds an archive holding entries in order.
+func zipOf(t *testing.T, entries ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e[0])
+		if err != nil {
+			t.Fatalf("create %s: %v", e[0], err)
+		}
+		_, _ = io.WriteString(w, e[1])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestGetLogs_SupplementsTruncatedArchive asserts an archive whose
+// entries hold no log text is merged with the per-job logs of the jobs
+// it lacks, that the supplementation is logged, and that a complete
+// archive never lists jobs.
+func TestGetLogs_SupplementsTruncatedArchive(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		archive    []byte
+		wantLogs   []string
+		absent     []string
+		supplement bool
+	}{{
+		name:       "empty entries",
+		archive:    zipOf(t, [2]string{"0_build.txt", ""}, [2]string{"build/1_Set up job.txt", ""}),
+		wantLogs:   []string{"build-log-line", "test-log-line"},
+		supplement: true,
+	}, {
+		name:       "only one job present",
+		archive:    zipOf(t, [2]string{"1_test.txt", "tiny"}),
+		wantLogs:   []string{"tiny", "build-log-line"},
+		absent:     []string{"test-log-line"},
+		supplement: true,
+	}, {
+		name:     "complete archive",
+		archive:  zipOf(t, [2]string{"0_build.txt", "2025-01-01T00:00:00.0Z Current runner version: '2.300.0'"}),
+		wantLogs: []string{"Current runner version"},
+	}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/7/logs"):
+					w.Header().Set("Location", server.URL+"/raw/run-7.zip")
+					w.WriteHeader(http.StatusFound)
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/7/jobs"):
+					if !tc.supplement {
+						t.Errorf("a complete archive must not list jobs")
+					}
+					_, _ = io.WriteString(w, `{"total_count":2,"jobs":[{"id":11,"name":"build"},{"id":22,"name":"test"}]}`)
+				case strings.HasSuffix(r.URL.Path, "/actions/runs/7"):
+					_, _ = io.WriteString(w, runStatusBody("completed", "success"))
+				case strings.HasSuffix(r.URL.Path, "/actions/jobs/11/logs"):
+					w.Header().Set("Location", server.URL+"/raw/job-11.txt")
+					w.WriteHeader(http.StatusFound)
+				case strings.HasSuffix(r.URL.Path, "/actions/jobs/22/logs"):
+					w.Header().Set("Location", server.URL+"/raw/job-22.txt")
+					w.WriteHeader(http.StatusFound)
+				case strings.HasSuffix(r.URL.Path, "/raw/run-7.zip"):
+					_, _ = w.Write(tc.archive)
+				case strings.HasSuffix(r.URL.Path, "/raw/job-11.txt"):
+					_, _ = io.WriteString(w, "build-log-line")
+				case strings.HasSuffix(r.URL.Path, "/raw/job-22.txt"):
+					_, _ = io.WriteString(w, "test-log-line")
+				default:
+					t.Errorf("unexpected path: %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			t.Cleanup(server.Close)
+
+			var buf bytes.Buffer
+			logger := clog.New(slog.NewTextHandler(&buf, nil))
+			gh, hc := newTestClients(t, server)
+			rc, err := workflow.GetLogs(t.Context(), logger, hc, gh, "o", "r", 7, "tok")
+			if err != nil {
+				t.Fatalf("GetLogs: %v", err)
+			}
+			defer func() { _ = rc.Close() }()
+			text, err := workflow.ExtractLogs(rc)
+			if err != nil {
+				t.Fatalf("ExtractLogs: %v", err)
+			}
+			for _, want := range tc.wantLogs {
+				if !strings.Contains(text, want) {
+					t.Errorf("logs lack %q:\n%s", want, text)
+				}
+			}
+			for _, absent := range tc.absent {
+				if strings.Contains(text, absent) {
+					t.Errorf("logs hold %q from a job the archive already had:\n%s", absent, text)
+				}
+			}
+			if got := strings.Contains(buf.String(), "supplementing with per-job logs"); got != tc.supplement {
+				t.Errorf("supplementation logged=%v, want %v:\n%s", got, tc.supplement, buf.String())
+			}
+		})
+	}
+}
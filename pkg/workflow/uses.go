@@ -129,6 +129,26 @@ func ParseUsesEdges(data []byte) ([]UsesEdge, error) {
 	return edges, nil
 }
 
+// ParseSecretRefs returns every secrets.NAME a workflow YAML document
+// references anywhere (env, with, run scripts, reusable workflow
+// calls), deduplicated in first-seen order. Any step of the workflow
+// can read these, so they are the secrets a compromised run may have
+// exposed. Input larger than 1 MiB is rejected.
+func ParseSecretRefs(data []byte) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	if len(data) > maxYAMLBytes {
+		return nil, fmt.Errorf("workflow YAML exceeds maximum size (%d > %d bytes)", len(data), maxYAMLBytes)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing workflow YAML: %w", err)
+	}
+	return collectSecretsFromNode(&root), nil
+}
+
 func buildEdge(usesRaw, jobName, stepName string, line int, secrets []string) UsesEdge {
 	uses := strings.TrimSpace(usesRaw)
 	action, ref := splitUses(uses)
@@ -346,3 +346,38 @@ func TestParseUsesEdges_EmptyInput(t *testing.T) {
 		t.Fatalf("expected zero edges, got %d", len(edges))
 	}
 }
+
+func TestParseSecretRefs(t *testing.T) {
+	t.Parallel()
+
+	const doc = `
+on: push
+env:
+  GLOBAL: ${{ secrets.GLOBAL_TOKEN }}
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo "${{ secrets.NPM_TOKEN }}" > ~/.npmrc
+      - uses: actions/checkout@v4
+        with:
+          token: ${{secrets.GITHUB_TOKEN}}
+  deploy:
+    uses: org/repo/.github/workflows/deploy.yml@main
+    secrets:
+      key: ${{ secrets.DEPLOY_KEY }}
+      again: ${{ secrets.NPM_TOKEN }}
+`
+	got, err := workflow.ParseSecretRefs([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseSecretRefs: %v", err)
+	}
+	want := []string{"GLOBAL_TOKEN", "NPM_TOKEN", "GITHUB_TOKEN", "DEPLOY_KEY"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ParseSecretRefs=%q, want %q", got, want)
+	}
+
+	if _, err := workflow.ParseSecretRefs([]byte("jobs: [unterminated")); err == nil {
+		t.Fatal("expected error for malformed YAML")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/chainguard-dev/clog"
@@ -62,6 +64,60 @@ func SearchWorkflowFiles(ctx context.Context, client *github.Client, query strin
 	return paths, err
 }
 
+// SearchUnavailable reports whether err, returned by
+// [SearchWorkflowFiles], is a failure that retrying cannot fix, such as
+// 422 for a query the search index rejects. Rate limits, primary or
+// secondary, are not: they clear once the window resets. Callers fall
+// back to [ListWorkflowPaths] when it reports true.
+func SearchUnavailable(err error) bool {
+	var rateLimitErr *github.RateLimitError
+	var abuseLimitErr *github.AbuseRateLimitError
+	if errors.As(err, &rateLimitErr) || errors.As(err, &abuseLimitErr) {
+		return false
+	}
+	var errResp *github.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return false
+	}
+	code := errResp.Response.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusTooManyRequests
+}
+
+// ListWorkflowPaths returns the path of every workflow file Actions has
+// registered in the repository. It enumerates workflows without code
+// search, so it still works for repositories the search index skips or
+// when search is failing; unlike search it omits workflow files that
+// have never been triggered. Workflows GitHub runs without a file in
+// the repository (dynamic/..., e.g. default CodeQL setup) are skipped.
+func ListWorkflowPaths(ctx context.Context, client *github.Client, owner, repo string) ([]string, error) {
+	var paths []string
+	opts := &github.ListOptions{PerPage: 100}
+	err := paginate(maxWorkflowListPages, "workflow listing", func(page int) (int, error) {
+		opts.Page = page
+		wfs, resp, err := client.Actions.ListWorkflows(ctx, owner, repo, opts)
+		if err != nil {
+			return 0, err
+		}
+		for _, wf := range wfs.Workflows {
+			if path := wf.GetPath(); strings.HasPrefix(path, ".github/workflows/") {
+				paths = append(paths, path)
+			}
+		}
+		if resp == nil {
+			return 0, nil
+		}
+		return resp.NextPage, nil
+	})
+	return paths, err
+}
+
+// ErrWorkflowNotFound marks a workflow file that Actions has not
+// registered, such as one that was never triggered. Code search still
+// returns such files, so callers should skip them rather than fail.
+var ErrWorkflowNotFound = errors.New("workflow: path not registered with Actions")
+
+// GetWorkflowByPath returns the workflow registered for wfPath, or an
+// error wrapping ErrWorkflowNotFound when no workflow has that path.
 func GetWorkflowByPath(ctx context.Context, client *github.Client, owner, repo, wfPath string) (*github.Workflow, error) {
 	return getWorkflowByPathPaginated(ctx, client, owner, repo, wfPath, maxWorkflowListPages)
 }
@@ -90,12 +146,15 @@ func getWorkflowByPathPaginated(ctx context.Context, client *github.Client, owne
 		return nil, perr
 	}
 	if found == nil {
-		return nil, fmt.Errorf("workflow with path %s not found", wfPath)
+		return nil, fmt.Errorf("workflow with path %s not found: %w", wfPath, ErrWorkflowNotFound)
 	}
 	return found, nil
 }
 
 func ListWorkflowRuns(ctx context.Context, logger *clog.Logger, client *github.Client, owner, repo string, workflowID int64, start, end time.Time, maxRetries int) ([]*github.WorkflowRun, error) {
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
 	var allRuns []*github.WorkflowRun
 
 	chunkDuration := 48 * time.Hour
@@ -188,6 +247,58 @@ func ListWorkflowRuns(ctx context.Context, logger *clog.Logger, client *github.C
 	return allRuns, chunkErrs
 }
 
+// maxAllRunsPages caps [ListAllWorkflowRuns]. At 100 runs per page it
+// covers 100,000 runs of one workflow, far beyond what GitHub still
+// holds logs for.
+const maxAllRunsPages = 1000
+
+// ListAllWorkflowRuns returns every run of the workflow, newest first,
+// ignoring any time window. It walks the unfiltered run listing, which
+// GitHub does not cap at 1,000 results the way it caps a created-date
+// query, so it needs no time chunking. Each page is retried
+// independently, so a transient failure deep into a long listing does
+// not restart it; one page failing after maxRetries retries fails the
+// listing. It costs one API request per 100 runs.
+func ListAllWorkflowRuns(ctx context.Context, logger *clog.Logger, client *github.Client, owner, repo string, workflowID int64, maxRetries int) ([]*github.WorkflowRun, error) {
+	if logger == nil {
+		logger = clog.FromContext(ctx)
+	}
+	var allRuns []*github.WorkflowRun
+	opts := &github.ListWorkflowRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	err := paginate(maxAllRunsPages, "workflow runs", func(page int) (int, error) {
+		opts.Page = page
+		var (
+			wr   *github.WorkflowRuns
+			resp *github.Response
+		)
+		err := request.WithRetryN(ctx, logger, maxRetries, func() error {
+			var err error
+			wr, resp, err = client.Actions.ListWorkflowRunsByID(ctx, owner, repo, workflowID, opts)
+			return err
+		})
+		if err != nil {
+			return 0, fmt.Errorf("listing page %d of runs: %w", max(page, 1), err)
+		}
+		allRuns = append(allRuns, wr.WorkflowRuns...)
+		if resp == nil || resp.NextPage == 0 {
+			return 0, nil
+		}
+		logger.Debugf("Listed %d of %d runs for workflow %d in %s/%s",
+			len(allRuns), wr.GetTotalCount(), workflowID, owner, repo)
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+		return resp.NextPage, nil
+	})
+	if err != nil {
+		return allRuns, err
+	}
+	logger.Infof("Found total of %d runs for workflow %d in %s/%s", len(allRuns), workflowID, owner, repo)
+	return allRuns, nil
+}
+
 // listAllJobsPaginated is the internal helper exposed for tests that
 // exercise the page-cap branch of listAllJobs.
 func listAllJobsPaginated(ctx context.Context, gh *github.Client, owner, repo string, runID int64, maxPages int) ([]*github.WorkflowJob, error) {
@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -82,6 +85,98 @@ func TestExtractLogs(t *testing.T) {
 	}
 }
 
+// TestExtractLogsContext asserts extraction stops with the context's
+// error once it is cancelled.
+func TestExtractLogsContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := workflow.ExtractLogsContext(ctx, bytes.NewReader(buildLogZip(t, "hello world"))); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExtractLogsContext err=%v, want context.Canceled", err)
+	}
+}
+
+// trackedLog is a job log reader that records being closed and can
+// fail its reads.
+type trackedLog struct {
+	r      *strings.Reader
+	fail   bool
+	closed bool
+}
+
+func (l *trackedLog) Read(p []byte) (int, error) {
+	if l.fail {
+		return 0, errors.New("connection reset")
+	}
+	return l.r.Read(p)
+}
+
+func (l *trackedLog) Close() error {
+	l.closed = true
+	return nil
+}
+
+// TestCombineLogs_ClosesEveryReader asserts the job logs are combined
+// in job ID order, and that every reader is closed when combining
+// succeeds, when a read fails partway, and when the context is
+// cancelled before any is read.
+func TestCombineLogs_ClosesEveryReader(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		failJob int64
+		cancel  bool
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "read error mid-loop", failJob: 2},
+		{name: "cancelled", cancel: true, wantErr: context.Canceled},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			tracked := map[int64]*trackedLog{}
+			logs := map[int64]io.ReadCloser{}
+			for _, id := range []int64{3, 1, 2, 4} {
+				l := &trackedLog{r: strings.NewReader(fmt.Sprintf("log of job %d", id)), fail: id == tc.failJob}
+				tracked[id] = l
+				logs[id] = l
+			}
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			if tc.cancel {
+				cancel()
+			}
+
+			got, err := workflow.CombineLogsForTest(ctx, logs)
+			switch {
+			case tc.wantErr != nil:
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("err=%v, want %v", err, tc.wantErr)
+				}
+			case tc.failJob != 0:
+				if err == nil {
+					t.Fatal("combining a failing reader succeeded")
+				}
+			default:
+				if err != nil {
+					t.Fatalf("CombineLogsForTest: %v", err)
+				}
+				if i, j := strings.Index(got, "log of job 1"), strings.Index(got, "log of job 4"); i < 0 || j < i {
+					t.Fatalf("combined logs out of job order:\n%s", got)
+				}
+			}
+			for id, l := range tracked {
+				if !l.closed {
+					t.Errorf("reader of job %d was not closed", id)
+				}
+			}
+		})
+	}
+}
+
 func TestParseLogs(t *testing.T) {
 	t.Parallel()
 
@@ -132,25 +227,65 @@ func TestParseLogs(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			findings, found := workflow.ParseLogs(newTestLogger(), tc.log, 12345, tc.ioc)
-			if !found {
-				t.Fatal("ParseLogs always reports found=true for nil-checked IOC")
+			if found != tc.wantHit {
+				t.Fatalf("found=%v, want %v (findings=%+v)", found, tc.wantHit, findings)
 			}
-			if len(findings) != 1 {
-				t.Fatalf("expected exactly 1 finding envelope, got %d", len(findings))
+			if !tc.wantHit {
+				if len(findings) != 0 {
+					t.Fatalf("expected no findings, got %+v", findings)
+				}
+				return
 			}
-			f := findings[0]
-			gotHit := f.LineData != "" || f.Encoded != "" || f.Decoded != ""
-			if gotHit != tc.wantHit {
-				t.Fatalf("hit=%v, want %v (line=%q encoded=%q decoded=%q)",
-					gotHit, tc.wantHit, f.LineData, f.Encoded, f.Decoded)
+			if len(findings) != 1 {
+				t.Fatalf("expected exactly 1 finding, got %d", len(findings))
 			}
-			if tc.wantLineSub != "" && !strings.Contains(f.LineData, tc.wantLineSub) {
+			if f := findings[0]; tc.wantLineSub != "" && !strings.Contains(f.LineData, tc.wantLineSub) {
 				t.Fatalf("LineData=%q, want substring %q", f.LineData, tc.wantLineSub)
 			}
 		})
 	}
 }
 
+// TestParseLogs_OneFindingPerDistinctMatch asserts each distinct match
+// keeps its own Finding, in first-seen order, and that a repeated
+// payload is reported once.
+func TestParseLogs_OneFindingPerDistinctMatch(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{
+		Name:    "t",
+		Content: []string{"DROP_THIS_TOKEN"},
+		Pattern: `(?:^|\s+)([A-Za-z0-9+/]{8,}={0,3})`,
+	})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	logText := "2025-01-01T00:00:00.000Z leak c2VjcmV0LW9uZQ==\n" +
+		"2025-01-01T00:00:01.000Z DROP_THIS_TOKEN\n" +
+		"2025-01-01T00:00:02.000Z leak c2VjcmV0LXR3bw==\n" +
+		"2025-01-01T00:00:03.000Z leak c2VjcmV0LW9uZQ==\n"
+	findings, found := workflow.ParseLogs(newTestLogger(), logText, 1, custom)
+	if !found {
+		t.Fatal("found=false, want true")
+	}
+
+	want := []workflow.Finding{
+		{Encoded: "c2VjcmV0LW9uZQ==", Decoded: "secret-one", DecodeDepth: 1, LineData: "leak c2VjcmV0LW9uZQ=="},
+		{LineData: "DROP_THIS_TOKEN"},
+		{Encoded: "c2VjcmV0LXR3bw==", Decoded: "secret-two", DecodeDepth: 1, LineData: "leak c2VjcmV0LXR3bw=="},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("findings=%+v, want %d entries", findings, len(want))
+	}
+	for i := range want {
+		if findings[i].Encoded != want[i].Encoded || findings[i].Decoded != want[i].Decoded ||
+			findings[i].LineData != want[i].LineData || findings[i].DecodeDepth != want[i].DecodeDepth {
+			t.Fatalf("findings[%d]=%+v, want %+v", i, findings[i], want[i])
+		}
+	}
+}
+
 func TestParseLogs_NilIOCReturnsNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -163,6 +298,30 @@ func TestParseLogs_NilIOCReturnsNotFound(t *testing.T) {
 	}
 }
 
+// TestParseReader asserts the reader entrypoint finds a match in a
+// stream, including one after a line longer than bufio's default
+// token size, and reports the same findings as ParseLogs.
+func TestParseReader(t *testing.T) {
+	t.Parallel()
+
+	custom, err := ioc.NewIOC(&ioc.Config{Name: "t", Content: []string{"DROP_THIS_TOKEN"}})
+	if err != nil {
+		t.Fatalf("build IOC: %v", err)
+	}
+
+	logText := "2025-01-01T00:00:00.000Z " + strings.Repeat("x", 128*1024) + "\n" +
+		"2025-01-01T00:00:01.000Z DROP_THIS_TOKEN\n"
+	findings, found := workflow.ParseReader(newTestLogger(), strings.NewReader(logText), 1, custom, nil)
+	if !found || len(findings) != 1 || findings[0].LineData != "DROP_THIS_TOKEN" {
+		t.Fatalf("ParseReader findings=%+v found=%v, want the IOC line", findings, found)
+	}
+
+	fromString, _ := workflow.ParseLogs(newTestLogger(), logText, 1, custom)
+	if len(fromString) != len(findings) || fromString[0].LineData != findings[0].LineData {
+		t.Fatalf("ParseLogs findings=%+v, want %+v", fromString, findings)
+	}
+}
+
 // makeWorkflowPage materializes one page of /actions/workflows as the
 // envelope go-github expects: {"total_count": N, "workflows": [...]}.
 // Each workflow carries a distinct ID and path so the test can assert
@@ -265,6 +424,9 @@ func TestGetWorkflowByPath_PaginatesUntilMatch(t *testing.T) {
 				if tc.errSubstr != "" && !strings.Contains(err.Error(), tc.errSubstr) {
 					t.Fatalf("err=%v, want substring %q", err, tc.errSubstr)
 				}
+				if !errors.Is(err, workflow.ErrWorkflowNotFound) {
+					t.Fatalf("err=%v, want ErrWorkflowNotFound", err)
+				}
 				return
 			}
 			if err != nil {
@@ -499,6 +661,130 @@ func TestSearchWorkflowFiles_ExceedsPagesReturnsError(t *testing.T) {
 	}
 }
 
+// TestSearchUnavailable classifies the errors go-github returns for
+// search responses: a rejected query is not worth retrying, while rate
+// limits and server errors are.
+func TestSearchUnavailable(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		want    bool
+	}{
+		{name: "validation failed", status: http.StatusUnprocessableEntity, want: true},
+		{name: "forbidden", status: http.StatusForbidden, want: true},
+		{name: "primary rate limit", status: http.StatusForbidden, headers: map[string]string{
+			"X-RateLimit-Limit": "30", "X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1893456000",
+		}},
+		{name: "too many requests", status: http.StatusTooManyRequests},
+		{name: "server error", status: http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				for k, v := range tc.headers {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(`{"message":"failed"}`))
+			}))
+			t.Cleanup(ts.Close)
+
+			gh, _ := newTestClients(t, ts)
+			_, err := workflow.SearchWorkflowFiles(t.Context(), gh, "repo:o/r path:.github/workflows")
+			if err == nil {
+				t.Fatal("SearchWorkflowFiles() succeeded, want an error")
+			}
+			if got := workflow.SearchUnavailable(err); got != tc.want {
+				t.Fatalf("SearchUnavailable(%v) = %v, want %v", err, got, tc.want)
+			}
+		})
+	}
+	if workflow.SearchUnavailable(nil) {
+		t.Fatal("SearchUnavailable(nil) = true, want false")
+	}
+}
+
+// TestListWorkflowPaths pages through the registered workflows and
+// drops the dynamic ones that have no file in the repository.
+func TestListWorkflowPaths(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/actions/workflows" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/actions/workflows?page=2>; rel="next"`, server.URL))
+			_ = json.NewEncoder(w).Encode(github.Workflows{Workflows: []*github.Workflow{
+				{ID: new(int64(1)), Path: new(".github/workflows/ci.yml")},
+				{ID: new(int64(2)), Path: new("dynamic/github-code-scanning/codeql")},
+			}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(github.Workflows{Workflows: []*github.Workflow{
+			{ID: new(int64(3)), Path: new(".github/workflows/release.yml")},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	gh, _ := newTestClients(t, server)
+	got, err := workflow.ListWorkflowPaths(t.Context(), gh, "o", "r")
+	if err != nil {
+		t.Fatalf("ListWorkflowPaths() error = %v", err)
+	}
+	want := []string{".github/workflows/ci.yml", ".github/workflows/release.yml"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("ListWorkflowPaths() = %q, want %q", got, want)
+	}
+}
+
+// TestListAllWorkflowRuns pages through every run without a created
+// filter, so runs of any age are returned.
+func TestListAllWorkflowRuns(t *testing.T) {
+	t.Parallel()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/actions/workflows/42/runs" || r.URL.Query().Has("created") {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		old := &github.Timestamp{Time: time.Now().Add(-3 * 365 * 24 * time.Hour)}
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/o/r/actions/workflows/42/runs?page=2>; rel="next"`, server.URL))
+			_ = json.NewEncoder(w).Encode(github.WorkflowRuns{TotalCount: new(3), WorkflowRuns: []*github.WorkflowRun{
+				{ID: new(int64(3))}, {ID: new(int64(2))},
+			}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(github.WorkflowRuns{TotalCount: new(3), WorkflowRuns: []*github.WorkflowRun{
+			{ID: new(int64(1)), CreatedAt: old},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	gh, _ := newTestClients(t, server)
+	runs, err := workflow.ListAllWorkflowRuns(t.Context(), newTestLogger(), gh, "o", "r", 42, 1)
+	if err != nil {
+		t.Fatalf("ListAllWorkflowRuns() error = %v", err)
+	}
+	var ids []int64
+	for _, run := range runs {
+		ids = append(ids, run.GetID())
+	}
+	if !slices.Equal(ids, []int64{3, 2, 1}) {
+		t.Fatalf("ListAllWorkflowRuns() IDs = %v, want [3 2 1]", ids)
+	}
+}
+
 // TestListWorkflowRuns_CancelledContextStops verifies that a
 // cancellation while iterating chunks/pages propagates to the
 // returned error and does not waste cycles in 100ms sleeps.